@@ -23,22 +23,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"slices"
+	"strings"
 	"sync"
 )
 
 // Account represents a Swift account. Instances are usually obtained by
 // connecting to a backend (see package-level documentation), or by traversing
 // upwards from a container with Container.Account().
+//
+// Methods like WithNewest() or ReadOnly() return a new Account instance that
+// refers to the same account, but behaves differently. Such instances do not
+// share any caches with the original instance.
 type Account struct {
 	backend Backend
 	// URL parts
 	baseURL string
 	name    string
+	// see WithPublicEndpoint()
+	publicEndpointURL string
 	// cache
-	headers   *AccountHeaders
-	caps      *Capabilities
-	capsMutex sync.Mutex
+	headers    *AccountHeaders
+	rawHeaders http.Header // multi-valued headers from the response that filled `headers`
+	caps       *Capabilities
+	capsMutex  sync.Mutex
 }
 
 // IsEqualTo returns true if both Account instances refer to the same account.
@@ -63,6 +74,18 @@ func InitializeAccount(backend Backend) (*Account, error) {
 	}, nil
 }
 
+// withBackend returns a new Account instance for the same account that uses
+// the given backend. This is the common implementation of WithNewest(),
+// ReadOnly() etc.
+func (a *Account) withBackend(backend Backend) *Account {
+	return &Account{
+		backend:           backend,
+		baseURL:           a.baseURL,
+		name:              a.name,
+		publicEndpointURL: a.publicEndpointURL,
+	}
+}
+
 // SwitchAccount returns a handle to a different account on the same server. Note
 // that you need reseller permissions to access accounts other than that where
 // you originally authenticated. This method does not check whether the account
@@ -79,6 +102,116 @@ func (a *Account) SwitchAccount(accountName string) *Account {
 	}
 }
 
+// WithNewest returns a handle to the same account that sends "X-Newest: true"
+// on all GET and HEAD requests, including those for containers and objects
+// below it. This causes Swift to query all replicas and return the most recent
+// version of the requested resource, instead of the first one that it finds.
+// Use this when read-after-write consistency is required, e.g. when reading
+// an object that was just written by another process.
+//
+// This comes at a significant cost: Requests will take as long as the slowest
+// replica takes to respond, and will put more load on the cluster. To apply
+// the same behavior to only some requests, set RequestOptions.Newest instead.
+func (a *Account) WithNewest() *Account {
+	backend := a.backend
+	if _, ok := backend.(newestBackend); !ok {
+		backend = newestBackend{backend}
+	}
+	return a.withBackend(backend)
+}
+
+// ReadOnly returns a handle to the same account that refuses to modify any
+// data. All operations on it (and on containers and objects below it) that
+// would send a request other than GET or HEAD fail with ErrReadOnly without
+// contacting the server. This is useful for reporting and auditing jobs that
+// must not be able to modify production data, even by accident.
+//
+// The read-only property carries over to accounts obtained from the returned
+// Account via SwitchAccount() or WithNewest().
+func (a *Account) ReadOnly() *Account {
+	backend := a.backend
+	if _, ok := backend.(readOnlyBackend); !ok {
+		backend = readOnlyBackend{backend}
+	}
+	return a.withBackend(backend)
+}
+
+// WithPolicy returns a handle to the same account on which only those requests
+// are permitted that match the given AccessPolicy. See documentation on type
+// AccessPolicy for details. An error is returned if the policy contains a
+// malformed container pattern.
+//
+// When called on an Account that already has a policy, both policies apply.
+// The policy carries over to accounts obtained from the returned Account via
+// SwitchAccount(), WithNewest() or ReadOnly().
+func (a *Account) WithPolicy(policy AccessPolicy) (*Account, error) {
+	for _, rule := range policy {
+		_, err := path.Match(rule.ContainerPattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid container pattern %q: %w", rule.ContainerPattern, err)
+		}
+	}
+	return a.withBackend(policyBackend{a.backend, slices.Clone(policy)}), nil
+}
+
+// WithPublicEndpoint returns a handle to the same account that uses the given
+// URL instead of the Swift endpoint URL in Container.PublicURL() and
+// Object.PublicURL(). This is useful when publicly readable content is served
+// through a different domain, e.g. by a CDN that forwards to this account. The
+// URL must refer to the account itself, i.e. the container name is appended to
+// it directly. For example:
+//
+//	cdnAccount := account.WithPublicEndpoint("https://cdn.example.com/")
+//	url, err := cdnAccount.Container("assets").Object("logo.png").PublicURL()
+//	//url == "https://cdn.example.com/assets/logo.png"
+//
+// All other requests still use the Swift endpoint URL.
+func (a *Account) WithPublicEndpoint(endpointURL string) *Account {
+	result := a.withBackend(a.backend)
+	result.publicEndpointURL = endpointURL
+	return result
+}
+
+// publicURL implements Container.PublicURL() and Object.PublicURL().
+func (a *Account) publicURL(containerName, objectName string) (string, error) {
+	endpointURL := a.publicEndpointURL
+	if endpointURL == "" {
+		endpointURL = a.backend.EndpointURL()
+	}
+	uri, err := url.Parse(endpointURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(containerName, "/") {
+		return "", ErrMalformedContainerName
+	}
+
+	// escape each path element separately, so that the slashes in the object
+	// name are kept as they are
+	segments := []string{escapePathSegment(containerName)}
+	if objectName != "" {
+		for _, segment := range strings.Split(objectName, "/") {
+			segments = append(segments, escapePathSegment(segment))
+		}
+	}
+	uri.RawPath = strings.TrimSuffix(uri.EscapedPath(), "/") + "/" + strings.Join(segments, "/")
+	uri.Path, err = url.PathUnescape(uri.RawPath)
+	if err != nil {
+		return "", err
+	}
+	return uri.String(), nil
+}
+
+// escapePathSegment is like url.PathEscape, but also escapes the dot segments
+// "." and "..", which would otherwise be removed when the URL is normalized by
+// a client or proxy (e.g. "a/../b" would refer to "b" instead).
+func escapePathSegment(segment string) string {
+	if segment == "." || segment == ".." {
+		return strings.Repeat("%2E", len(segment))
+	}
+	return url.PathEscape(segment)
+}
+
 // Name returns the name of the account (usually the prefix "AUTH_" followed by
 // the Keystone project ID).
 func (a *Account) Name() string {
@@ -112,15 +245,31 @@ func (a *Account) Headers(ctx context.Context) (AccountHeaders, error) {
 	}
 	defer resp.Body.Close()
 
-	headers := AccountHeaders{headersFromHTTP(resp.Header)}
+	headers := newAccountHeadersFromHTTP(resp.Header)
 	err = headers.Validate()
 	if err != nil {
 		return headers, err
 	}
 	a.headers = &headers
+	a.rawHeaders = multiValuedHeaders(resp.Header)
 	return *a.headers, nil
 }
 
+// RawAll returns all values of the given header in the response that the
+// cached AccountHeaders were obtained from. The AccountHeaders only contain the
+// first value of each header, so this is useful for headers that can appear
+// multiple times in a response. If the header has been changed in the cached
+// AccountHeaders since then, only its current value is returned.
+//
+// This does not issue any requests. If no AccountHeaders are cached (see
+// Headers() and Invalidate()), nil is returned.
+func (a *Account) RawAll(key string) []string {
+	if a.headers == nil {
+		return nil
+	}
+	return rawAll(a.headers.Headers, a.rawHeaders, key)
+}
+
 // Invalidate clears the internal cache of this Account instance. The next call
 // to Headers() on this instance will issue a HEAD request on the account.
 //
@@ -128,6 +277,7 @@ func (a *Account) Headers(ctx context.Context) (AccountHeaders, error) {
 // object results in undefined behavior.
 func (a *Account) Invalidate() {
 	a.headers = nil
+	a.rawHeaders = nil
 }
 
 // Update updates the account using a POST request. The headers in the headers
@@ -165,6 +315,12 @@ func (a *Account) Create(ctx context.Context, opts *RequestOptions) error {
 	return err
 }
 
+// NewRequest prepares a request on this account for use with Request.Do().
+// See documentation on type Request for details. The opts argument may be nil.
+func (a *Account) NewRequest(method string, opts *RequestOptions) Request {
+	return Request{Method: method, Options: opts}
+}
+
 // Containers returns a ContainerIterator that lists the containers in this
 // account. The most common use case is:
 //
@@ -216,8 +372,8 @@ func (a *Account) Capabilities(ctx context.Context) (Capabilities, error) {
 // this account, and returns the response body. Unlike Account.Capabilities,
 // this method does not employ any caching.
 func (a *Account) RawCapabilities(ctx context.Context) ([]byte, error) {
-	// This method is the only one in Schwift that bypasses struct Request since
-	// the request URL is not below the endpoint URL.
+	// This method (like Account.Healthcheck) bypasses struct Request since the
+	// request URL is not below the endpoint URL.
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"info", http.NoBody)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,318 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AppendOptions invokes advanced behavior in the Object.Append() method.
+type AppendOptions struct {
+	// Segmenting is used when the object is not a large object yet and needs to
+	// be converted into one. If Segmenting.SegmentContainer is nil, segments are
+	// stored in the object's own container. For existing large objects, the
+	// segmenting options are taken from the existing manifest instead.
+	Segmenting SegmentingOptions
+	// SegmentSizeBytes is passed on to LargeObject.Append().
+	SegmentSizeBytes int64
+	// SegmentOptions is passed on to LargeObject.Append(), so it applies to each
+	// segment upload, e.g. to set a Content-Type or metadata on the segments.
+	// When a plain object is converted into a large object, it also applies to
+	// the copy of its current content. (The ropts argument of Append() only
+	// applies to the manifest.)
+	SegmentOptions *RequestOptions
+	// If SegmentsExpireWithManifest is true, the X-Delete-At header of the
+	// manifest (either given in ropts or retained from the existing object) is
+	// also set on each new segment, unless SegmentOptions contains X-Delete-At
+	// or X-Delete-After already. Since segments are uploaded before the
+	// manifest, a relative X-Delete-After in ropts is converted into an absolute
+	// X-Delete-At before any upload, so that the segments and the manifest
+	// expire at the same time.
+	SegmentsExpireWithManifest bool
+	// OnConflict selects what happens when the object is modified concurrently
+	// while Append() uploads segments. The default is AppendConflictMerge.
+	OnConflict AppendConflictResolution
+}
+
+// AppendConflictResolution is an enum for AppendOptions.OnConflict.
+//
+// Right before writing the new manifest, Object.Append() checks whether the
+// object still has the same Etag as when the append started. If not, another
+// writer has modified the object in the meantime (most likely another
+// Append() on the same object), and writing the manifest as planned would
+// discard the other writer's changes. This check is only performed when
+// converting a plain object and for static large objects, since the Etag of a
+// dynamic large object changes whenever segments are uploaded.
+//
+// To make merging possible, each Append() on an existing static large object
+// uploads its segments below a fresh segment prefix, so that concurrent
+// appenders do not overwrite each other's segments.
+//
+// Since Swift cannot replace an object conditionally, this check narrows the
+// window for conflicting writes considerably, but cannot close it entirely.
+type AppendConflictResolution int
+
+const (
+	// AppendConflictMerge is the default. If the object was a static large
+	// object already and the other writer has only appended segments to the
+	// manifest, the new segments are added after those. For any other
+	// modification, and for any modification while a plain object is converted
+	// into a large object, the newly uploaded segments are deleted and
+	// ErrConcurrentModification is returned.
+	AppendConflictMerge AppendConflictResolution = iota
+	// AppendConflictFail deletes the newly uploaded segments and returns
+	// ErrConcurrentModification whenever the object was modified concurrently.
+	AppendConflictFail
+	// AppendConflictOverwrite skips the check and writes the manifest as
+	// planned, discarding all concurrent modifications.
+	AppendConflictOverwrite
+)
+
+// Append adds the given content to the end of this object. This is intended for
+// log-style workloads that append to an object repeatedly, without having to
+// deal with large objects directly:
+//
+//	err := obj.Append(ctx, strings.NewReader("another log line\n"), nil, nil)
+//
+// The object is stored as a large object. If the object does not exist yet, a
+// new large object is created. If it is a plain object, it is converted into a
+// large object by copying its current content into the first segment on the
+// server side. Then the new content is uploaded as additional segments, and the
+// manifest is rewritten. Metadata and content headers of the object are
+// retained unless ropts overrides them. The ropts argument applies to the
+// manifest upload only.
+//
+// Each call adds at least one segment, so very frequent appends of small
+// amounts of data will eventually run into the maximum number of segments per
+// manifest. For how concurrent appends to the same object are handled, see
+// documentation on type AppendConflictResolution.
+func (o *Object) Append(ctx context.Context, content io.Reader, opts *AppendOptions, ropts *RequestOptions) error {
+	if opts == nil {
+		opts = &AppendOptions{}
+	}
+
+	// remember how the object looked before, to detect concurrent modifications
+	var baseEtag string
+	hdr, err := o.Headers(ctx)
+	switch {
+	case err == nil:
+		baseEtag = hdr.Etag().Get()
+	case Is(err, http.StatusNotFound):
+		// okay, object will be created
+	default:
+		return err
+	}
+
+	manifestOpts, err := o.manifestRewriteOptions(ctx, ropts)
+	if err != nil {
+		return err
+	}
+	segmentOpts := opts.SegmentOptions
+	if opts.SegmentsExpireWithManifest {
+		manifestHdr := ObjectHeaders{manifestOpts.Headers}
+		if manifestHdr.ExpiresAfter().Exists() {
+			err := manifestHdr.ExpiresAfter().validate()
+			if err != nil {
+				return err
+			}
+			// like in Swift itself, X-Delete-After takes precedence over X-Delete-At
+			manifestHdr.ExpiresAt().Set(time.Now().Add(manifestHdr.ExpiresAfter().Get()))
+			manifestHdr.ExpiresAfter().Del()
+		}
+
+		segmentOpts = cloneRequestOptions(segmentOpts, nil)
+		segmentHdr := ObjectHeaders{segmentOpts.Headers}
+		if manifestHdr.ExpiresAt().Exists() && !segmentHdr.ExpiresAt().Exists() && !segmentHdr.ExpiresAfter().Exists() {
+			segmentHdr.Set("X-Delete-At", manifestHdr.Get("X-Delete-At"))
+		}
+	}
+
+	var (
+		baseSegmentCount int
+		converted        bool
+	)
+	lo, err := o.AsLargeObject(ctx)
+	switch {
+	case err == nil:
+		// okay, append to existing large object
+		baseSegmentCount = len(lo.segments)
+		if lo.strategy == StaticLargeObject {
+			// upload new segments below a fresh prefix, so that concurrent appenders
+			// do not overwrite each other's segments
+			lo.segmentPrefix = defaultSegmentPrefix(o, StaticLargeObject)
+		}
+	case errors.Is(err, ErrNotLarge):
+		lo, err = o.convertToLargeObject(ctx, opts.Segmenting, segmentOpts)
+		if err != nil {
+			return err
+		}
+		converted = true
+	default:
+		return err
+	}
+
+	err = lo.Append(ctx, content, opts.SegmentSizeBytes, segmentOpts)
+	if err != nil {
+		return err
+	}
+
+	if (converted || lo.strategy == StaticLargeObject) && opts.OnConflict != AppendConflictOverwrite {
+		// when converting a plain object, merging is not possible: the first
+		// segment is a copy of content that another writer may have replaced
+		mergeable := !converted && baseSegmentCount > 0
+		err = o.resolveAppendConflict(ctx, lo, baseEtag, baseSegmentCount, mergeable, opts.OnConflict)
+		if err != nil {
+			return err
+		}
+	}
+	return lo.WriteManifest(ctx, manifestOpts)
+}
+
+// resolveAppendConflict is called by Append() right before writing the
+// manifest. The first baseSegmentCount segments of lo are those that were
+// already present in the manifest before the append. If mergeable is false,
+// any concurrent modification is a conflict.
+func (o *Object) resolveAppendConflict(ctx context.Context, lo *LargeObject, baseEtag string, baseSegmentCount int, mergeable bool, onConflict AppendConflictResolution) error {
+	o.Invalidate()
+	var currentEtag string
+	hdr, err := o.Headers(ctx)
+	switch {
+	case err == nil:
+		currentEtag = hdr.Etag().Get()
+	case Is(err, http.StatusNotFound):
+		// object was deleted concurrently (or never existed)
+	default:
+		return err
+	}
+	if currentEtag == baseEtag {
+		return nil
+	}
+
+	baseSegments := lo.segments[:baseSegmentCount]
+	newSegments := lo.segments[baseSegmentCount:]
+
+	if onConflict == AppendConflictMerge && mergeable && baseEtag != "" && hdr.IsStaticLargeObject() {
+		current, err := o.asSLO(ctx, true, SegmentLocationOptions{})
+		if err != nil {
+			return err
+		}
+		if segmentsHavePrefix(current.segments, baseSegments) {
+			lo.segments = append(current.segments, newSegments...)
+			return nil
+		}
+	}
+
+	// cannot merge -> clean up the segments that will not be referenced
+	var newSegmentObjects []*Object
+	for _, s := range newSegments {
+		if s.Object != nil {
+			newSegmentObjects = append(newSegmentObjects, s.Object)
+		}
+	}
+	_, _, err = o.c.a.BulkDelete(ctx, newSegmentObjects, nil, nil)
+	return errors.Join(ErrConcurrentModification, err)
+}
+
+func segmentsHavePrefix(segments, prefix []SegmentInfo) bool {
+	if len(segments) < len(prefix) {
+		return false
+	}
+	for idx, p := range prefix {
+		s := segments[idx]
+		if (s.Object == nil) != (p.Object == nil) {
+			return false
+		}
+		if s.Object != nil && !s.Object.IsEqualTo(p.Object) {
+			return false
+		}
+		if s.Etag != p.Etag || s.RangeOffset != p.RangeOffset || s.RangeLength != p.RangeLength || !bytes.Equal(s.Data, p.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// convertToLargeObject prepares a LargeObject for Append() when the object is
+// not a large object yet. If the object exists, its content is copied into the
+// first segment.
+func (o *Object) convertToLargeObject(ctx context.Context, sopts SegmentingOptions, segmentOpts *RequestOptions) (*LargeObject, error) {
+	if sopts.SegmentContainer == nil {
+		sopts.SegmentContainer = o.c
+	}
+	lo, err := o.AsNewLargeObject(ctx, sopts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hdr, err := o.Headers(ctx)
+	if Is(err, http.StatusNotFound) {
+		return lo, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if hdr.SizeBytes().Get() == 0 {
+		// Swift does not accept empty segments
+		return lo, nil
+	}
+
+	firstSegment := lo.NextSegmentObject()
+	err = o.CopyTo(ctx, firstSegment, nil, segmentOpts)
+	if err != nil {
+		return nil, err
+	}
+	err = lo.AddSegment(SegmentInfo{
+		Object:    firstSegment,
+		SizeBytes: hdr.SizeBytes().Get(),
+		Etag:      hdr.Etag().Get(),
+	})
+	return lo, err
+}
+
+// manifestRewriteOptions returns the RequestOptions for rewriting the manifest
+// of an existing object in Append() or LargeObject.Repack(). Since the
+// manifest upload replaces all metadata, the existing metadata needs to be
+// included.
+func (o *Object) manifestRewriteOptions(ctx context.Context, ropts *RequestOptions) (*RequestOptions, error) {
+	ropts = cloneRequestOptions(ropts, nil)
+	hdr, err := o.Headers(ctx)
+	if Is(err, http.StatusNotFound) {
+		return ropts, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for k, v := range hdr.Headers {
+		if k == "X-Object-Manifest" {
+			continue // this is managed by LargeObject.WriteManifest()
+		}
+		if !strings.HasPrefix(k, "X-Object-Meta-") && !contains(postResetObjectHeaders, k) {
+			continue
+		}
+		if _, exists := ropts.Headers[k]; !exists {
+			ropts.Headers[k] = v
+		}
+	}
+	return ropts, nil
+}
@@ -0,0 +1,231 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a single mutating request (i.e. any request other
+// than GET or HEAD) that was made through an Account obtained from
+// Account.WithAudit(), or through containers and objects below it.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor,omitempty"`
+	Account string    `json:"account"`
+	Method  string    `json:"method"` // e.g. http.MethodPut
+	Target  string    `json:"target"` // either "<account>" or "$CONTAINER_NAME" or "$CONTAINER_NAME/$OBJECT_NAME"
+	// RequestID is the value of RequestOptions.RequestID, if any.
+	RequestID string `json:"request_id,omitempty"`
+	// TransactionID is the X-Trans-Id returned by Swift. It identifies the
+	// request in the server-side logs.
+	TransactionID string `json:"transaction_id,omitempty"`
+	// StatusCode is 0 if no response was received. In this case, Error
+	// contains the error message.
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// PreviousHash is filled by AuditLogWriter. See documentation over there.
+	PreviousHash string `json:"previous_hash,omitempty"`
+}
+
+// AuditSink receives the AuditRecords from an Account obtained from
+// Account.WithAudit(). Since requests can be made concurrently, RecordAudit()
+// may be called concurrently as well.
+type AuditSink interface {
+	RecordAudit(record AuditRecord)
+}
+
+// AuditFunc is an AuditSink that calls itself for each record.
+type AuditFunc func(record AuditRecord)
+
+// RecordAudit implements the AuditSink interface.
+func (f AuditFunc) RecordAudit(record AuditRecord) {
+	f(record)
+}
+
+// WithAudit returns a handle to the same account that reports every mutating
+// request (i.e. every request other than GET or HEAD) to the given sink, once
+// the response has been received or the request has failed. The actor is
+// recorded in each AuditRecord, and should identify the user or process on
+// whose behalf the requests are made.
+//
+// When called on an Account obtained from ReadOnly() or WithPolicy(), requests
+// that are rejected before reaching the server are recorded as well, with a
+// StatusCode of 0. The audit carries over to accounts obtained from the
+// returned Account via SwitchAccount(), WithNewest(), ReadOnly() or
+// WithPolicy().
+func (a *Account) WithAudit(actor string, sink AuditSink) *Account {
+	return a.withBackend(auditBackend{a.backend, actor, sink})
+}
+
+// auditBackend wraps a Backend to report mutating requests to an AuditSink.
+// It is used by Account.WithAudit().
+type auditBackend struct {
+	inner Backend
+	actor string
+	sink  AuditSink
+}
+
+func (b auditBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b auditBackend) Clone(newEndpointURL string) Backend {
+	return auditBackend{b.inner.Clone(newEndpointURL), b.actor, b.sink}
+}
+
+func (b auditBackend) Do(req *http.Request) (*http.Response, error) {
+	if isReadMethod(req.Method) {
+		return b.inner.Do(req)
+	}
+
+	record := AuditRecord{
+		Time:      time.Now(),
+		Actor:     b.actor,
+		Method:    req.Method,
+		Target:    req.URL.Path,
+		RequestID: req.Header.Get("X-Openstack-Request-Id"),
+	}
+	endpointURL, err := url.Parse(b.inner.EndpointURL())
+	if err == nil {
+		record.Account = path.Base(endpointURL.Path)
+		if rest, ok := strings.CutPrefix(req.URL.Path, endpointURL.Path); ok {
+			containerName, objectName, _ := strings.Cut(rest, "/")
+			record.Target = describeTarget(containerName, objectName)
+		}
+	}
+
+	resp, err := b.inner.Do(req)
+	if err == nil {
+		record.StatusCode = resp.StatusCode
+		record.TransactionID = resp.Header.Get("X-Trans-Id")
+	} else {
+		record.Error = err.Error()
+	}
+	b.sink.RecordAudit(record)
+	return resp, err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// AuditLogWriter is an AuditSink that writes AuditRecords into an io.Writer
+// in the JSON Lines format, i.e. one JSON object per line.
+//
+// To make the log tamper-evident, each record contains the HMAC-SHA256 of the
+// previous line in its PreviousHash field, so that modifying, removing or
+// reordering records breaks the hash chain. Use VerifyAuditLog() to check the
+// chain. The HMAC key must be kept secret from whoever can write to the log;
+// otherwise, they can just recompute the chain after tampering with it. Note
+// that removing records from the end of the log cannot be detected this way;
+// to guard against that, store LastHash() elsewhere periodically.
+type AuditLogWriter struct {
+	mutex    sync.Mutex
+	writer   io.Writer
+	key      []byte
+	lastHash string
+	err      error
+}
+
+// NewAuditLogWriter returns an AuditLogWriter that writes into the given
+// writer, using the given key for the HMAC of the hash chain. When appending
+// to an existing log, pass the hash returned by VerifyAuditLog() for the
+// existing log to continue its hash chain. Otherwise, pass an empty string.
+func NewAuditLogWriter(w io.Writer, key []byte, lastHash string) *AuditLogWriter {
+	return &AuditLogWriter{writer: w, key: bytes.Clone(key), lastHash: lastHash}
+}
+
+// RecordAudit implements the AuditSink interface. Records are not written
+// after a write error has occurred; use Err() to check for that.
+func (l *AuditLogWriter) RecordAudit(record AuditRecord) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.err != nil {
+		return
+	}
+
+	record.PreviousHash = l.lastHash
+	buf, err := json.Marshal(record)
+	if err != nil {
+		l.err = err
+		return
+	}
+	_, err = l.writer.Write(append(buf, '\n'))
+	if err != nil {
+		l.err = err
+		return
+	}
+	l.lastHash = hashAuditLogLine(l.key, buf)
+}
+
+// LastHash returns the hash of the last record written by this writer.
+func (l *AuditLogWriter) LastHash() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.lastHash
+}
+
+// Err returns the first error that occurred while writing records, if any.
+func (l *AuditLogWriter) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.err
+}
+
+// VerifyAuditLog reads an audit log written by AuditLogWriter and checks its
+// hash chain using the same key that was given to NewAuditLogWriter(). If the
+// chain is broken, an error wrapping ErrAuditLogTampered is returned. On
+// success, the hash of the last record is returned, for use with
+// NewAuditLogWriter() or for comparison with a previously stored LastHash().
+func VerifyAuditLog(r io.Reader, key []byte) (lastHash string, err error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		var record AuditRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return "", fmt.Errorf("cannot parse line %d of audit log: %w", lineNumber, err)
+		}
+		if !hmac.Equal([]byte(record.PreviousHash), []byte(lastHash)) {
+			return "", fmt.Errorf("in line %d of audit log: %w", lineNumber, ErrAuditLogTampered)
+		}
+		lastHash = hashAuditLogLine(key, scanner.Bytes())
+	}
+	return lastHash, scanner.Err()
+}
+
+func hashAuditLogLine(key, line []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(line)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,87 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	ctx := context.Background()
+	b := &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	a, err := InitializeAccount(b)
+	must(t, err)
+
+	var buf bytes.Buffer
+	key := []byte("secret")
+	w := NewAuditLogWriter(&buf, key, "")
+	audited := a.WithAudit("alice", w)
+	obj := audited.Container("foo").Object("bar")
+
+	// reads are not recorded, writes are
+	_, err = obj.Download(ctx, nil).AsByteSlice()
+	must(t, err)
+	resp, err := obj.NewRequest("PUT", &RequestOptions{RequestID: "req-1"}).Do(ctx, obj.Container().Account().Backend())
+	must(t, err)
+	must(t, resp.Body.Close())
+
+	// writes are also recorded when they are rejected before reaching the server
+	err = a.ReadOnly().WithAudit("alice", w).Container("foo").Delete(ctx, nil)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	must(t, w.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit records, got %d: %q", len(lines), buf.String())
+	}
+	expectString(t, `"actor":"alice","account":"AUTH_example","method":"PUT","target":"foo/bar","request_id":"req-1","status_code":200}`,
+		lines[0][strings.Index(lines[0], `"actor"`):])
+	if !strings.Contains(lines[1], `"target":"foo","error":"operation not permitted on a read-only account","previous_hash":"`) {
+		t.Errorf("unexpected second audit record: %s", lines[1])
+	}
+
+	// the hash chain verifies, and can be continued
+	lastHash, err := VerifyAuditLog(bytes.NewReader(buf.Bytes()), key)
+	must(t, err)
+	expectString(t, w.LastHash(), lastHash)
+	w = NewAuditLogWriter(&buf, key, lastHash)
+	w.RecordAudit(AuditRecord{Method: "POST", Target: "<account>"})
+	_, err = VerifyAuditLog(bytes.NewReader(buf.Bytes()), key)
+	must(t, err)
+
+	// the chain cannot be verified without the right key
+	_, err = VerifyAuditLog(bytes.NewReader(buf.Bytes()), []byte("wrong"))
+	if !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("expected ErrAuditLogTampered, got %v", err)
+	}
+
+	// removing a record breaks the chain
+	tampered := strings.Join(lines[1:], "\n")
+	_, err = VerifyAuditLog(strings.NewReader(tampered), key)
+	if !errors.Is(err, ErrAuditLogTampered) {
+		t.Errorf("expected ErrAuditLogTampered, got %v", err)
+	}
+}
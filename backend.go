@@ -46,3 +46,53 @@ type Backend interface {
 // DefaultUserAgent is the User-Agent string that Backend implementations should
 // use if the user does not provide their own User-Agent string.
 const DefaultUserAgent = "schwift/" + Version
+
+// newestBackend wraps a Backend to add "X-Newest: true" to all GET and HEAD
+// requests. It is used by Account.WithNewest().
+type newestBackend struct {
+	inner Backend
+}
+
+func (b newestBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b newestBackend) Clone(newEndpointURL string) Backend {
+	return newestBackend{b.inner.Clone(newEndpointURL)}
+}
+
+func (b newestBackend) Do(req *http.Request) (*http.Response, error) {
+	if isReadMethod(req.Method) && req.Header.Get("X-Newest") == "" {
+		req.Header.Set("X-Newest", "true")
+	}
+	return b.inner.Do(req)
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// readOnlyBackend wraps a Backend to reject all requests other than GET and
+// HEAD with ErrReadOnly. It is used by Account.ReadOnly().
+type readOnlyBackend struct {
+	inner Backend
+}
+
+func (b readOnlyBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b readOnlyBackend) Clone(newEndpointURL string) Backend {
+	return readOnlyBackend{b.inner.Clone(newEndpointURL)}
+}
+
+func (b readOnlyBackend) Do(req *http.Request) (*http.Response, error) {
+	if !isReadMethod(req.Method) {
+		// like http.Client.Do(), we are responsible for closing the request body
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrReadOnly
+	}
+	return b.inner.Do(req)
+}
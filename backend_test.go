@@ -0,0 +1,81 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// testBackend is the Backend used by the unit tests in this package. Each
+// request is answered by calling `handle`, much like fakeswift.Backend.Hook
+// (which cannot be used here because package fakeswift imports this package).
+// The backend also remembers the number of requests and the last request.
+type testBackend struct {
+	endpointURL string
+	handle      func(req *http.Request) (*http.Response, error)
+
+	mutex    sync.Mutex
+	requests int
+	lastReq  *http.Request
+}
+
+func (b *testBackend) EndpointURL() string {
+	if b.endpointURL == "" {
+		return "https://example.com/v1/AUTH_example/"
+	}
+	return b.endpointURL
+}
+
+func (b *testBackend) Clone(newEndpointURL string) Backend {
+	return &testBackend{endpointURL: newEndpointURL, handle: b.handle}
+}
+
+func (b *testBackend) Do(req *http.Request) (*http.Response, error) {
+	b.mutex.Lock()
+	b.requests++
+	b.lastReq = req
+	b.mutex.Unlock()
+	return b.handle(req)
+}
+
+// respondWith returns a handler for testBackend that answers all requests
+// with the given status code and body.
+func respondWith(statusCode int, body string) func(*http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		return testResponse(statusCode, body), nil
+	}
+}
+
+// hangUntilCanceled is a handler for testBackend that does not respond until
+// the request's context expires.
+func hangUntilCanceled(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, req.Context().Err()
+}
+
+func testResponse(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -0,0 +1,325 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package backup creates incremental backups of Swift containers as tar
+streams, and restores them. For example:
+
+	import "github.com/majewsky/schwift/v2/backup"
+
+	// full backup
+	manifest, err := backup.Create(ctx, fullFile, container, nil)
+
+	// later: incremental backup of everything that changed since then
+	manifest, err = backup.Create(ctx, incrementalFile, container, &backup.Options{
+		Since: manifest.CreatedAt,
+	})
+
+	// restore: apply the full backup and all incremental backups in order
+	for _, file := range []io.Reader{fullFile, incrementalFile} {
+		_, err := backup.Restore(ctx, file, container, &backup.RestoreOptions{
+			DeleteMissing: true,
+		})
+	}
+
+Each backup set is a tar stream. The content of each object that was created
+or changed since the given time is stored below "objects/" (e.g. the object
+"foo/bar" is stored as "objects/foo/bar"). Its Content-Type,
+Content-Encoding, Content-Disposition and metadata are stored in PAX records
+with the prefix "SCHWIFT.header.". The last entry of the stream is a JSON
+document named "manifest.json" that describes the backup set (see type
+Manifest). Since the manifest lists all objects that existed when the backup
+was created, Restore() can also reproduce deletions.
+
+Whether an object has changed is determined from the last-modified timestamps
+in the object listing, which are set by the Swift servers. Since the start
+time of a backup (Manifest.CreatedAt) is measured by the client, callers
+should account for clock skew by choosing Options.Since slightly earlier than
+the CreatedAt of the previous backup. Objects that are included needlessly in
+an incremental backup do no harm.
+
+Large objects are backed up with their full content, and restored as regular
+objects. Symlinks are backed up with the content of their target.
+*/
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+const (
+	manifestName     = "manifest.json"
+	objectPrefix     = "objects/"
+	paxHeaderPrefix  = "SCHWIFT.header."
+	metaHeaderPrefix = "X-Object-Meta-"
+)
+
+// The headers (besides X-Object-Meta-*) that are stored in the backup.
+var backedUpHeaders = []string{
+	"Content-Type",
+	"Content-Encoding",
+	"Content-Disposition",
+}
+
+// Options contains optional settings for Create().
+type Options struct {
+	// When Since is set, only objects that were modified at or after this time
+	// are included in the backup (usually the CreatedAt of the previous backup
+	// minus a safety margin). Otherwise, all objects are included.
+	Since time.Time
+	// When Prefix is set, only objects whose name starts with this string are
+	// backed up.
+	Prefix string
+}
+
+// Manifest describes a backup set. It is stored at the end of the tar stream
+// that is written by Create(), and returned by Create() and Restore().
+type Manifest struct {
+	// The name of the container that was backed up.
+	Container string `json:"container"`
+	// The Options.Prefix that was used to create this backup.
+	Prefix string `json:"prefix,omitempty"`
+	// The Options.Since that was used to create this backup (zero for a full
+	// backup).
+	Since time.Time `json:"since"`
+	// When the backup was started, as measured by the client.
+	CreatedAt time.Time `json:"created_at"`
+	// The objects whose content is included in this backup.
+	Changed []Entry `json:"changed"`
+	// The names of all objects below the Prefix that existed when the backup
+	// was created, including those that were not changed.
+	Objects []string `json:"objects"`
+}
+
+// Entry appears in type Manifest.
+type Entry struct {
+	Name         string    `json:"name"`
+	SizeBytes    uint64    `json:"bytes"`
+	Etag         string    `json:"hash"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// Create writes a backup set for the given container into the given writer.
+// The opts argument may be nil.
+//
+// If an object is deleted while the backup is running, it is left out of the
+// backup. If an object is modified while the backup is running, an error may
+// be returned.
+func Create(ctx context.Context, w io.Writer, c *schwift.Container, opts *Options) (Manifest, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	manifest := Manifest{
+		Container: c.Name(),
+		Prefix:    opts.Prefix,
+		Since:     opts.Since,
+		CreatedAt: time.Now().UTC(),
+		Changed:   []Entry{},
+		Objects:   []string{},
+	}
+
+	tw := tar.NewWriter(w)
+	iter := c.Objects()
+	iter.Prefix = opts.Prefix
+	err := iter.ForeachDetailed(ctx, func(info schwift.ObjectInfo) error {
+		if info.Object == nil {
+			return nil // pseudo-directory (cannot happen without a Delimiter)
+		}
+		if info.LastModified.Before(opts.Since) {
+			manifest.Objects = append(manifest.Objects, info.Object.Name())
+			return nil
+		}
+
+		entry, err := writeObject(ctx, tw, info.Object)
+		if schwift.Is(err, 404) {
+			return nil // object was deleted concurrently
+		}
+		if err != nil {
+			return fmt.Errorf("cannot back up %s: %w", info.Object.FullName(), err)
+		}
+		manifest.Objects = append(manifest.Objects, info.Object.Name())
+		manifest.Changed = append(manifest.Changed, entry)
+		return nil
+	})
+	if err != nil {
+		return manifest, err
+	}
+
+	buf, err := json.Marshal(manifest)
+	if err != nil {
+		return manifest, err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     manifestName,
+		Size:     int64(len(buf)),
+		Mode:     0o644,
+		ModTime:  manifest.CreatedAt,
+		Format:   tar.FormatPAX,
+	})
+	if err == nil {
+		_, err = tw.Write(buf)
+	}
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, tw.Close()
+}
+
+func writeObject(ctx context.Context, tw *tar.Writer, obj *schwift.Object) (Entry, error) {
+	obj.Invalidate()
+	hdr, err := obj.Headers(ctx)
+	if err != nil {
+		return Entry{}, err
+	}
+	reader, err := obj.Download(ctx, nil).AsReadCloser()
+	if err != nil {
+		return Entry{}, err
+	}
+	defer reader.Close()
+
+	th := &tar.Header{
+		Typeflag:   tar.TypeReg,
+		Name:       objectPrefix + obj.Name(),
+		Size:       int64(hdr.SizeBytes().Get()),
+		Mode:       0o644,
+		ModTime:    hdr.UpdatedAt().Get(),
+		Format:     tar.FormatPAX,
+		PAXRecords: make(map[string]string),
+	}
+	for key, value := range hdr.Headers {
+		if strings.HasPrefix(key, metaHeaderPrefix) || slices.Contains(backedUpHeaders, key) {
+			th.PAXRecords[paxHeaderPrefix+key] = value
+		}
+	}
+	err = tw.WriteHeader(th)
+	if err != nil {
+		return Entry{}, err
+	}
+	n, err := io.Copy(tw, reader)
+	if err != nil {
+		return Entry{}, err
+	}
+	if n != th.Size {
+		return Entry{}, fmt.Errorf("expected %d bytes, but downloaded %d bytes (object was modified during backup?)", th.Size, n)
+	}
+
+	return Entry{
+		Name:         obj.Name(),
+		SizeBytes:    uint64(n),
+		Etag:         hdr.Etag().Get(),
+		LastModified: hdr.UpdatedAt().Get(),
+	}, nil
+}
+
+// RestoreOptions contains optional settings for Restore().
+type RestoreOptions struct {
+	// If true, objects below the backup's Prefix that are not listed in
+	// Manifest.Objects are deleted after all objects have been restored.
+	DeleteMissing bool
+}
+
+// Restore reads a backup set that was written by Create(), and uploads the
+// objects contained therein into the given container. The opts argument may
+// be nil. The container may be different from the one that was backed up.
+//
+// To restore an incremental backup, the preceding full backup and all
+// incremental backups in between must be restored first, in order.
+func Restore(ctx context.Context, r io.Reader, c *schwift.Container, opts *RestoreOptions) (Manifest, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+
+	var (
+		manifest      Manifest
+		foundManifest bool
+	)
+	tr := tar.NewReader(r)
+	for {
+		th, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, err
+		}
+		if th.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if th.Name == manifestName {
+			err := json.NewDecoder(tr).Decode(&manifest)
+			if err != nil {
+				return manifest, fmt.Errorf("cannot decode %s: %w", manifestName, err)
+			}
+			foundManifest = true
+			continue
+		}
+		name, ok := strings.CutPrefix(th.Name, objectPrefix)
+		if !ok {
+			continue
+		}
+
+		hdr := schwift.NewObjectHeaders()
+		for key, value := range th.PAXRecords {
+			if headerName, ok := strings.CutPrefix(key, paxHeaderPrefix); ok {
+				hdr.Set(headerName, value)
+			}
+		}
+		err = c.Object(name).Upload(ctx, tr, nil, hdr.ToOpts())
+		if err != nil {
+			return manifest, fmt.Errorf("cannot restore %s: %w", name, err)
+		}
+	}
+	if !foundManifest {
+		return manifest, fmt.Errorf("invalid backup: %s not found", manifestName)
+	}
+
+	if opts.DeleteMissing {
+		expected := make(map[string]bool, len(manifest.Objects))
+		for _, name := range manifest.Objects {
+			expected[name] = true
+		}
+		iter := c.Objects()
+		iter.Prefix = manifest.Prefix
+		err := iter.Foreach(ctx, func(obj *schwift.Object) error {
+			if expected[obj.Name()] {
+				return nil
+			}
+			err := obj.Delete(ctx, nil, nil)
+			if schwift.Is(err, 404) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
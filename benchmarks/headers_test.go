@@ -0,0 +1,81 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func BenchmarkObjectHeaders(b *testing.B) {
+	withContainer(b, func(c *schwift.Container) {
+		ctx := context.Background()
+		obj := c.Object("headers")
+		hdr := schwift.NewObjectHeaders()
+		for idx := range 20 {
+			hdr.Metadata().Set(fmt.Sprintf("Key%d", idx), fmt.Sprintf("value%d", idx))
+		}
+		hdr.ContentType().Set("text/plain")
+		err := obj.Upload(ctx, bytes.NewReader([]byte("hello")), nil, hdr.ToOpts())
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+
+		// this measures the HEAD request as well as the parsing of its response
+		b.Run("HEAD", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				obj.Invalidate()
+				_, err := obj.Headers(ctx)
+				if err != nil {
+					b.Fatal(err.Error())
+				}
+			}
+		})
+
+		serverHdr, err := obj.Headers(ctx)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+
+		b.Run("Validate", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				err := serverHdr.Validate()
+				if err != nil {
+					b.Fatal(err.Error())
+				}
+			}
+		})
+
+		b.Run("Accessors", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = serverHdr.SizeBytes().Get()
+				_ = serverHdr.UpdatedAt().Get()
+				_ = serverHdr.Etag().Get()
+				_ = serverHdr.Metadata().Get("Key10")
+			}
+		})
+	})
+}
@@ -0,0 +1,91 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package benchmarks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func BenchmarkObjectIterator(b *testing.B) {
+	const objectCount = 1000
+	withContainer(b, func(c *schwift.Container) {
+		ctx := context.Background()
+		populateContainer(b, c, objectCount, []byte("hello"))
+
+		b.Run("Collect", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				objects, err := c.Objects().Collect(ctx)
+				if err != nil {
+					b.Fatal(err.Error())
+				}
+				if len(objects) != objectCount {
+					b.Fatalf("expected %d objects, got %d", objectCount, len(objects))
+				}
+			}
+		})
+
+		b.Run("CollectDetailed", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				infos, err := c.Objects().CollectDetailed(ctx)
+				if err != nil {
+					b.Fatal(err.Error())
+				}
+				if len(infos) != objectCount {
+					b.Fatalf("expected %d objects, got %d", objectCount, len(infos))
+				}
+			}
+		})
+
+		b.Run("ForeachDetailedPaged", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				iter := c.Objects()
+				for {
+					infos, err := iter.NextPageDetailed(ctx, 100)
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+					if len(infos) == 0 {
+						break
+					}
+				}
+			}
+		})
+
+		b.Run("Delimiter", func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				iter := c.Objects()
+				iter.Delimiter = "/"
+				infos, err := iter.CollectDetailed(ctx)
+				if err != nil {
+					b.Fatal(err.Error())
+				}
+				if len(infos) != 10 {
+					b.Fatalf("expected 10 pseudo-directories, got %d", len(infos))
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,74 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func BenchmarkLargeObjectWrite(b *testing.B) {
+	const (
+		totalSize   = 16 << 20
+		segmentSize = 1 << 20
+	)
+	strategies := []struct {
+		Name     string
+		Strategy schwift.LargeObjectStrategy
+	}{
+		{"SLO", schwift.StaticLargeObject},
+		{"DLO", schwift.DynamicLargeObject},
+	}
+
+	for _, s := range strategies {
+		b.Run(s.Name, func(b *testing.B) {
+			withContainer(b, func(c *schwift.Container) {
+				ctx := context.Background()
+				payload := getRandomContent(totalSize)
+				obj := c.Object("largeobject")
+				sopts := schwift.SegmentingOptions{
+					SegmentContainer: c,
+					SegmentPrefix:    "segments/",
+					Strategy:         s.Strategy,
+				}
+
+				b.ReportAllocs()
+				b.SetBytes(totalSize)
+				b.ResetTimer()
+				for range b.N {
+					lo, err := obj.AsNewLargeObject(ctx, sopts, &schwift.TruncateOptions{DeleteSegments: true})
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+					err = lo.Append(ctx, bytes.NewReader(payload), segmentSize, nil)
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+					err = lo.WriteManifest(ctx, nil)
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+				}
+			})
+		})
+	}
+}
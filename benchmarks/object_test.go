@@ -0,0 +1,138 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func BenchmarkUpload(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		b.Run(bs.Name, func(b *testing.B) {
+			withContainer(b, func(c *schwift.Container) {
+				ctx := context.Background()
+				payload := getRandomContent(bs.Size)
+				obj := c.Object("upload")
+
+				b.ReportAllocs()
+				b.SetBytes(int64(bs.Size))
+				b.ResetTimer()
+				for range b.N {
+					err := obj.Upload(ctx, bytes.NewReader(payload), nil, nil)
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkUploadParallel(b *testing.B) {
+	const size = 1 << 20
+	withContainer(b, func(c *schwift.Container) {
+		ctx := context.Background()
+		payload := getRandomContent(size)
+		var counter atomic.Int64
+
+		b.ReportAllocs()
+		b.SetBytes(size)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				// use distinct objects to avoid measuring the server's handling of
+				// concurrent writes to the same object
+				obj := c.Object(fmt.Sprintf("upload-%d", counter.Add(1)%64))
+				err := obj.Upload(ctx, bytes.NewReader(payload), nil, nil)
+				if err != nil {
+					b.Error(err.Error())
+					return
+				}
+			}
+		})
+	})
+}
+
+func BenchmarkDownload(b *testing.B) {
+	for _, bs := range benchmarkSizes {
+		withContainer(b, func(c *schwift.Container) {
+			ctx := context.Background()
+			obj := c.Object("download")
+			err := obj.Upload(ctx, bytes.NewReader(getRandomContent(bs.Size)), nil, nil)
+			if err != nil {
+				b.Fatal(err.Error())
+			}
+
+			b.Run(bs.Name+"/AsByteSlice", func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(bs.Size))
+				for range b.N {
+					_, err := obj.Download(ctx, nil).AsByteSlice()
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+				}
+			})
+
+			b.Run(bs.Name+"/WriteTo", func(b *testing.B) {
+				b.ReportAllocs()
+				b.SetBytes(int64(bs.Size))
+				for range b.N {
+					_, err := obj.Download(ctx, nil).WriteTo(io.Discard)
+					if err != nil {
+						b.Fatal(err.Error())
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkDownloadParallel(b *testing.B) {
+	const size = 1 << 20
+	withContainer(b, func(c *schwift.Container) {
+		ctx := context.Background()
+		err := c.Object("download").Upload(ctx, bytes.NewReader(getRandomContent(size)), nil, nil)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+
+		b.ReportAllocs()
+		b.SetBytes(size)
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			// Object instances are not thread-safe, so each goroutine needs its own
+			obj := c.Object("download")
+			for pb.Next() {
+				_, err := obj.Download(ctx, nil).WriteTo(io.Discard)
+				if err != nil {
+					b.Error(err.Error())
+					return
+				}
+			}
+		})
+	})
+}
@@ -0,0 +1,34 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+// Package benchmarks contains benchmarks for Schwift. Run them with:
+//
+//	go test -run=XXX -bench=. ./benchmarks
+//
+// By default, the benchmarks run against an in-memory fake Swift, so they
+// measure the overhead of Schwift itself (request construction, header
+// parsing, buffering, etc.). To benchmark against a real Swift cluster, supply
+// credentials in the same way as for the integration tests in the "tests"
+// package (either ST_AUTH, ST_USER and ST_KEY, or the usual OS_* variables).
+//
+// When changing a performance-sensitive code path, compare the results before
+// and after the change with benchstat (golang.org/x/perf/cmd/benchstat).
+package benchmarks
+
+// This source file only contains the package documentation. The benchmarks
+// themselves are in the _test.go files.
@@ -0,0 +1,124 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package benchmarks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/schwifttest/testaccount"
+)
+
+var (
+	accountOnce sync.Once
+	account     *schwift.Account
+	accountErr  error
+)
+
+// getAccount returns the account that the benchmarks run against. If Swift
+// credentials are present in the environment, a real Swift is used. Otherwise
+// we fall back to an in-memory fake.
+func getAccount(b *testing.B) *schwift.Account {
+	b.Helper()
+	accountOnce.Do(func() {
+		account, accountErr = testaccount.Connect(context.Background())
+	})
+	if accountErr != nil {
+		b.Fatal(accountErr.Error())
+	}
+	return account
+}
+
+// withContainer runs the benchmark body with a fresh container that is
+// deleted (including all its objects) afterwards.
+func withContainer(b *testing.B, body func(c *schwift.Container)) {
+	b.Helper()
+	ctx := context.Background()
+	c, err := getAccount(b).Container("schwift-bench-" + getRandomName()).EnsureExists(ctx)
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	b.Cleanup(func() {
+		err := c.Objects().Foreach(ctx, func(o *schwift.Object) error {
+			return o.Delete(ctx, nil, nil)
+		})
+		if err == nil {
+			err = c.Delete(ctx, nil)
+		}
+		if err != nil {
+			b.Error(err.Error())
+		}
+	})
+	body(c)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// load generation utilities
+
+// benchmarkSizes are the object sizes used for throughput benchmarks.
+var benchmarkSizes = []struct {
+	Name string
+	Size int
+}{
+	{"1KiB", 1 << 10},
+	{"64KiB", 64 << 10},
+	{"1MiB", 1 << 20},
+	{"16MiB", 16 << 20},
+}
+
+func getRandomName() string {
+	var buf [8]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		panic(err.Error())
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// getRandomContent generates a payload of the given size. The payload is
+// random to defeat any compression or deduplication on the server side.
+func getRandomContent(size int) []byte {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err.Error())
+	}
+	return buf
+}
+
+// populateContainer uploads `count` objects with the given payload into the
+// container. Object names are spread across a few pseudo-directories, so that
+// listings with delimiter have something to do.
+func populateContainer(b *testing.B, c *schwift.Container, count int, payload []byte) {
+	b.Helper()
+	ctx := context.Background()
+	for idx := range count {
+		name := fmt.Sprintf("dir%d/object%06d", idx%10, idx)
+		err := c.Object(name).Upload(ctx, bytes.NewReader(payload), nil, nil)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
@@ -42,6 +42,9 @@ const (
 	BulkUploadTarGzip BulkUploadFormat = "tar.gz"
 	// BulkUploadTarBzip2 is a BZip2-compressed tar archive.
 	BulkUploadTarBzip2 BulkUploadFormat = "tar.bz2"
+	// BulkUploadZip is a zip archive. Swift does not support this format, so
+	// zip archives are always extracted on the client side.
+	BulkUploadZip BulkUploadFormat = "zip"
 )
 
 // BulkUpload extracts an archive (which may contain multiple files) into a
@@ -64,15 +67,21 @@ const (
 //
 // If not nil, the error return value is *usually* an instance of BulkError.
 //
-// This operation returns (0, ErrNotSupported) if the server does not support
-// bulk-uploading.
+// If the server does not support bulk-uploading, or if the archive is in a
+// format that the server does not support (i.e. BulkUploadZip), this function
+// falls back to extracting the archive on the client side, and uploads the
+// files individually (several at once). The result is aggregated in the same
+// way as the server would do it. For zip archives, the central directory at
+// the end of the archive needs to be read first, so if the contents are not an
+// io.ReaderAt and io.Seeker (e.g. *os.File or *bytes.Reader), they are
+// spooled into a temporary file.
 func (a *Account) BulkUpload(ctx context.Context, uploadPath string, format BulkUploadFormat, contents io.Reader, opts *RequestOptions) (int, error) {
 	caps, err := a.Capabilities(ctx)
 	if err != nil {
 		return 0, err
 	}
-	if caps.BulkUpload == nil {
-		return 0, ErrNotSupported
+	if caps.BulkUpload == nil || !capabilities.AllowBulkUpload || format == BulkUploadZip {
+		return a.bulkUploadSingle(ctx, uploadPath, format, contents, opts)
 	}
 
 	req := Request{
@@ -106,7 +115,7 @@ func parseResponseStatus(status string) (int, error) {
 }
 
 func makeBulkObjectError(fullName string, statusCode int) BulkObjectError {
-	nameFields := strings.SplitN(fullName, "/", 2)
+	nameFields := strings.SplitN(strings.TrimPrefix(fullName, "/"), "/", 2)
 	for len(nameFields) < 2 {
 		nameFields = append(nameFields, "")
 	}
@@ -294,8 +303,14 @@ type bulkResponse struct {
 	NumberDeleted int `json:"Number Deleted"`
 	// NumberNotFound is included in the BulkDelete result only.
 	NumberNotFound int `json:"Number Not Found"`
+	// Etag is included in the result of SLO manifest uploads with
+	// "heartbeat=on" only.
+	Etag string `json:"Etag"`
 }
 
+// parseBulkResponse parses the response body of a bulk operation, or of a
+// long-running operation with "heartbeat=on". Leading whitespace (as sent by
+// Swift to keep the connection alive) is skipped.
 func parseBulkResponse(body io.ReadCloser) (bulkResponse, error) {
 	var resp bulkResponse
 	err := json.NewDecoder(body).Decode(&resp)
@@ -321,7 +336,11 @@ func parseBulkResponse(body io.ReadCloser) (bulkResponse, error) {
 		}
 		statusCode, err := parseResponseStatus(suberr[1])
 		if err != nil {
-			return resp, err
+			// not a status (e.g. "Etag Mismatch" for SLO segments) -> report as message
+			objErr := makeBulkObjectError(suberr[0], bulkErr.StatusCode)
+			objErr.Message = suberr[1]
+			bulkErr.ObjectErrors = append(bulkErr.ObjectErrors, objErr)
+			continue
 		}
 		bulkErr.ObjectErrors = append(bulkErr.ObjectErrors,
 			makeBulkObjectError(suberr[0], statusCode),
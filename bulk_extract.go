@@ -0,0 +1,272 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/majewsky/schwift/v2/internal/errext"
+)
+
+const (
+	// The number of files that bulkUploadSingle() uploads at the same time.
+	bulkUploadConcurrency = 4
+	// Files up to this size are buffered in memory by bulkUploadSingle(), so
+	// that they can be uploaded concurrently while the archive is being read.
+	// Larger files are uploaded directly from the archive stream.
+	bulkUploadBufferSize = 4 << 20
+)
+
+// Implementation of BulkUpload() for servers that *do not* support bulk
+// uploading, and for archive formats that Swift does not support. Like Swift's
+// extract-archive, containers are only created when uploading to the account
+// level.
+func (a *Account) bulkUploadSingle(ctx context.Context, uploadPath string, format BulkUploadFormat, contents io.Reader, opts *RequestOptions) (int, error) {
+	u := &bulkUploader{
+		a:                 a,
+		ctx:               ctx,
+		opts:              opts,
+		semaphore:         make(chan struct{}, bulkUploadConcurrency),
+		createdContainers: make(map[string]bool),
+	}
+	u.containerName, u.objectPrefix, _ = strings.Cut(strings.Trim(uploadPath, "/"), "/")
+	if u.objectPrefix != "" {
+		u.objectPrefix += "/"
+	}
+
+	var readErr error
+	switch format {
+	case BulkUploadTar:
+		readErr = u.extractTar(contents)
+	case BulkUploadTarGzip:
+		gzr, err := gzip.NewReader(contents)
+		if err != nil {
+			return 0, invalidArchiveError("Tar", err)
+		}
+		defer gzr.Close()
+		readErr = u.extractTar(gzr)
+	case BulkUploadTarBzip2:
+		readErr = u.extractTar(bzip2.NewReader(contents))
+	case BulkUploadZip:
+		readErr = u.extractZip(contents)
+	default:
+		return 0, BulkError{StatusCode: http.StatusBadRequest, OverallError: "Unsupported archive format"}
+	}
+	u.wg.Wait()
+
+	switch {
+	case u.fatalErr != nil:
+		return u.numCreated, u.fatalErr
+	case readErr != nil:
+		return u.numCreated, readErr
+	case len(u.errs) > 0:
+		// report errors in a deterministic order
+		slices.SortFunc(u.errs, func(lhs, rhs BulkObjectError) int {
+			return strings.Compare(lhs.ContainerName+"/"+lhs.ObjectName, rhs.ContainerName+"/"+rhs.ObjectName)
+		})
+		return u.numCreated, BulkError{
+			StatusCode:   http.StatusBadRequest,
+			ObjectErrors: u.errs,
+		}
+	default:
+		return u.numCreated, nil
+	}
+}
+
+// bulkUploader holds the state of bulkUploadSingle().
+type bulkUploader struct {
+	a                 *Account
+	ctx               context.Context //nolint:containedctx // only lives for the duration of one BulkUpload()
+	opts              *RequestOptions
+	containerName     string
+	objectPrefix      string
+	createdContainers map[string]bool
+
+	mutex      sync.Mutex
+	wg         sync.WaitGroup
+	semaphore  chan struct{}
+	numCreated int
+	errs       []BulkObjectError
+	fatalErr   error
+}
+
+func (u *bulkUploader) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+	for !u.hasFatalError() {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return invalidArchiveError("Tar", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		err = u.addFile(hdr.Name, hdr.Size, tr)
+		if err != nil {
+			return invalidArchiveError("Tar", err)
+		}
+	}
+	return nil
+}
+
+func (u *bulkUploader) extractZip(r io.Reader) error {
+	// zip.NewReader() needs random access to the archive
+	ra, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	})
+	if !ok {
+		file, err := os.CreateTemp("", "schwift-bulkupload-*.zip")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		_, err = io.Copy(file, r)
+		if err != nil {
+			return err
+		}
+		ra = file
+	}
+	size, err := ra.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return invalidArchiveError("Zip", err)
+	}
+	for _, f := range zr.File {
+		if u.hasFatalError() {
+			return nil
+		}
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		body, err := f.Open()
+		if err != nil {
+			return invalidArchiveError("Zip", err)
+		}
+		err = u.addFile(f.Name, int64(f.UncompressedSize64), body)
+		body.Close()
+		if err != nil {
+			return invalidArchiveError("Zip", err)
+		}
+	}
+	return nil
+}
+
+func (u *bulkUploader) hasFatalError() bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.fatalErr != nil
+}
+
+// addFile uploads a file from the archive. Only errors while reading the file
+// contents are returned; all other errors are recorded in the bulkUploader.
+func (u *bulkUploader) addFile(name string, size int64, body io.Reader) error {
+	// determine target location of this file
+	path := strings.TrimPrefix(strings.TrimPrefix(name, "./"), "/")
+	targetContainer, targetObject := u.containerName, u.objectPrefix+path
+	if u.containerName == "" {
+		var ok bool
+		targetContainer, targetObject, ok = strings.Cut(path, "/")
+		if !ok {
+			u.mutex.Lock()
+			u.errs = append(u.errs, makeBulkObjectError(path, http.StatusBadRequest))
+			u.mutex.Unlock()
+			return nil
+		}
+		if !u.createdContainers[targetContainer] {
+			_, err := u.a.Container(targetContainer).EnsureExists(u.ctx)
+			if err != nil {
+				u.mutex.Lock()
+				u.fatalErr = err
+				u.mutex.Unlock()
+				return nil
+			}
+			u.createdContainers[targetContainer] = true
+		}
+	}
+	obj := u.a.Container(targetContainer).Object(targetObject)
+
+	// small files are buffered and uploaded in the background, large files
+	// are uploaded directly (while the background uploads continue)
+	u.semaphore <- struct{}{}
+	if size > bulkUploadBufferSize {
+		u.upload(obj, body)
+		<-u.semaphore
+		return nil
+	}
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		<-u.semaphore
+		return err
+	}
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		u.upload(obj, bytes.NewReader(buf))
+		<-u.semaphore
+	}()
+	return nil
+}
+
+func (u *bulkUploader) upload(obj *Object, body io.Reader) {
+	err := obj.Upload(u.ctx, body, nil, u.opts)
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if err == nil {
+		u.numCreated++
+	} else if statusErr, ok := errext.As[UnexpectedStatusCodeError](err); ok {
+		u.errs = append(u.errs, makeBulkObjectError(obj.FullName(), statusErr.ActualResponse.StatusCode))
+	} else if u.fatalErr == nil {
+		// unexpected error type -> stop early
+		u.fatalErr = err
+	}
+}
+
+// invalidArchiveError builds the same BulkError that Swift reports for
+// archives that cannot be read.
+func invalidArchiveError(kind string, err error) BulkError {
+	msg := err.Error()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// match the error message from Python's tarfile module
+		msg = "truncated header"
+	}
+	return BulkError{
+		StatusCode:   http.StatusBadRequest,
+		OverallError: "Invalid " + kind + " File: " + msg,
+	}
+}
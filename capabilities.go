@@ -18,6 +18,8 @@
 
 package schwift
 
+import "strings"
+
 // Capabilities describes a subset of the capabilities that Swift can report
 // under its /info endpoint. This struct is obtained through the
 // Account.Capabilities() method. To query capabilities not represented in this
@@ -35,6 +37,10 @@ type Capabilities struct {
 		MaximumContainersPerExtraction uint `json:"max_containers_per_extraction"`
 		MaximumFailedExtractions       uint `json:"max_failed_extractions"`
 	} `json:"bulk_upload"`
+	Encryption *struct{} `json:"encryption"`
+	FormPost   *struct {
+		AllowedDigests []string `json:"allowed_digests"`
+	} `json:"formpost"`
 	StaticLargeObject *struct {
 		MaximumManifestSegments uint `json:"max_manifest_segments"`
 		MaximumManifestSize     uint `json:"max_manifest_size"`
@@ -89,3 +95,28 @@ type StoragePolicySpec struct {
 	Aliases string `json:"aliases"`
 	Default bool   `json:"default"`
 }
+
+// StoragePolicy returns the storage policy with the given name or alias, as
+// reported in ContainerInfo.StoragePolicy or ContainerStat.StoragePolicy.
+// Names are compared case-insensitively, like Swift does. If the name is
+// empty, the default policy is returned. The second return value is false if
+// no such policy is advertised by the server.
+func (c Capabilities) StoragePolicy(name string) (StoragePolicySpec, bool) {
+	for _, policy := range c.Swift.Policies {
+		if name == "" {
+			if policy.Default {
+				return policy, true
+			}
+			continue
+		}
+		if strings.EqualFold(policy.Name, name) {
+			return policy, true
+		}
+		for _, alias := range strings.Split(policy.Aliases, ",") {
+			if strings.EqualFold(strings.TrimSpace(alias), name) {
+				return policy, true
+			}
+		}
+	}
+	return StoragePolicySpec{}, false
+}
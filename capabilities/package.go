@@ -27,3 +27,7 @@ package capabilities
 // AllowBulkDelete can be set to false to force Schwift to act as if the server
 // does not support bulk deletion.
 var AllowBulkDelete = true
+
+// AllowBulkUpload can be set to false to force Schwift to act as if the server
+// does not support bulk uploading.
+var AllowBulkUpload = true
@@ -0,0 +1,48 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCapabilitiesStoragePolicy(t *testing.T) {
+	var caps Capabilities
+	must(t, json.Unmarshal([]byte(`{"swift":{"policies":[
+		{"name":"gold","aliases":"gold, fast"},
+		{"name":"silver","default":true}
+	]}}`), &caps))
+
+	testCases := map[string]string{
+		"gold":   "gold",
+		"GOLD":   "gold",
+		"fast":   "gold",
+		"silver": "silver",
+		"":       "silver",
+		"bronze": "",
+	}
+	for name, expected := range testCases {
+		policy, ok := caps.StoragePolicy(name)
+		if ok != (expected != "") {
+			t.Errorf("expected ok = %t for %q, got %t", expected != "", name, ok)
+		}
+		expectString(t, expected, policy.Name)
+	}
+}
@@ -0,0 +1,261 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed is the normal state where all requests are sent.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen is the state where all requests fail with ErrCircuitOpen
+	// without being sent.
+	CircuitOpen
+	// CircuitHalfOpen is the state after the cool-down where a limited number of
+	// probe requests is sent to find out if the server has recovered.
+	CircuitHalfOpen
+)
+
+// String implements the fmt.Stringer interface.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker protects against pile-ups of blocked requests when Swift is
+// failing. It is attached to an account with Account.WithCircuitBreaker().
+//
+// A request counts as failed if it fails without a response (except when its
+// context was canceled), or if the response has a 5xx status code.
+// When at least MinimumRequests requests were made within the current Window,
+// and the fraction of failed requests among them reaches FailureThreshold, the
+// breaker opens. While it is open, all requests fail with ErrCircuitOpen
+// without being sent. After CoolDown has passed, the breaker becomes half-open
+// and lets up to HalfOpenProbes requests through. If all of them succeed, the
+// breaker closes again. If one of them fails, the breaker opens again for
+// another CoolDown.
+//
+// The configuration fields must not be changed after the CircuitBreaker has
+// been attached to an account. All methods are safe for concurrent use.
+type CircuitBreaker struct {
+	// Defaults to 0.5.
+	FailureThreshold float64
+	// Defaults to 10.
+	MinimumRequests int
+	// Defaults to 1 minute.
+	Window time.Duration
+	// Defaults to 30 seconds.
+	CoolDown time.Duration
+	// Defaults to 1.
+	HalfOpenProbes int
+	// If not nil, this is called whenever the state of the breaker changes. This
+	// can be used to report the state as a metric. The callback is invoked while
+	// the breaker's internal lock is held, so it must not call State().
+	OnStateChange func(from, to CircuitState)
+
+	mutex       sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int // number of probes sent in the half-open state
+	successes   int // number of successful probes in the half-open state
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.advance(time.Now())
+	return cb.state
+}
+
+// WithCircuitBreaker returns a handle to the same account that sends all
+// requests through the given CircuitBreaker. The breaker carries over to
+// accounts obtained from the returned Account via SwitchAccount() etc., and
+// the same breaker may be attached to multiple accounts, in which case their
+// requests are counted together. This is usually appropriate since they are
+// served by the same Swift cluster.
+func (a *Account) WithCircuitBreaker(cb *CircuitBreaker) *Account {
+	return a.withBackend(circuitBreakerBackend{a.backend, cb})
+}
+
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	cb.requests = 0
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(from, state)
+	}
+}
+
+// advance performs time-based state transitions. The caller must hold the lock.
+func (cb *CircuitBreaker) advance(now time.Time) {
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) >= durationOrDefault(cb.CoolDown, 30*time.Second) {
+			cb.setState(CircuitHalfOpen)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) >= durationOrDefault(cb.Window, time.Minute) {
+			cb.windowStart = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+	}
+}
+
+// admit decides whether a request may be sent.
+func (cb *CircuitBreaker) admit() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.advance(time.Now())
+	switch cb.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if cb.probes >= max(cb.HalfOpenProbes, 1) {
+			return false
+		}
+		cb.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+// report records the outcome of a request that was admitted.
+func (cb *CircuitBreaker) report(failed bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	now := time.Now()
+	cb.advance(now)
+
+	switch cb.state {
+	case CircuitHalfOpen:
+		if failed {
+			cb.openedAt = now
+			cb.setState(CircuitOpen)
+			return
+		}
+		cb.successes++
+		if cb.successes >= max(cb.HalfOpenProbes, 1) {
+			cb.windowStart = now
+			cb.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		cb.requests++
+		if failed {
+			cb.failures++
+		}
+		minimumRequests := cb.MinimumRequests
+		if minimumRequests <= 0 {
+			minimumRequests = 10
+		}
+		threshold := cb.FailureThreshold
+		if threshold <= 0 {
+			threshold = 0.5
+		}
+		if cb.requests >= minimumRequests && float64(cb.failures) >= threshold*float64(cb.requests) {
+			cb.openedAt = now
+			cb.setState(CircuitOpen)
+		}
+	default:
+		// outcome of a request that was admitted before the breaker opened
+	}
+}
+
+// release is called instead of report() for an admitted request whose
+// outcome says nothing about the server's health. If the request was a probe,
+// its slot is freed up for another probe.
+func (cb *CircuitBreaker) release() {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+func durationOrDefault(d, defaultValue time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultValue
+	}
+	return d
+}
+
+// circuitBreakerBackend wraps a Backend to send all requests through a
+// CircuitBreaker. It is used by Account.WithCircuitBreaker().
+type circuitBreakerBackend struct {
+	inner   Backend
+	breaker *CircuitBreaker
+}
+
+func (b circuitBreakerBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b circuitBreakerBackend) Clone(newEndpointURL string) Backend {
+	return circuitBreakerBackend{b.inner.Clone(newEndpointURL), b.breaker}
+}
+
+func (b circuitBreakerBackend) Do(req *http.Request) (*http.Response, error) {
+	if !b.breaker.admit() {
+		// like http.Client.Do(), we are responsible for closing the request body
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := b.inner.Do(req)
+	switch {
+	case errors.Is(err, context.Canceled):
+		// a canceled request says nothing about the server's health (but a
+		// timeout does)
+		b.breaker.release()
+	case err != nil:
+		b.breaker.report(true)
+	default:
+		b.breaker.report(resp.StatusCode >= 500)
+	}
+	return resp, err
+}
@@ -0,0 +1,136 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	statusCode := http.StatusServiceUnavailable
+	b := &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		return testResponse(statusCode, ""), nil
+	}}
+	var transitions []string
+	cb := &CircuitBreaker{
+		MinimumRequests: 4,
+		CoolDown:        50 * time.Millisecond,
+		HalfOpenProbes:  2,
+		OnStateChange: func(from, to CircuitState) {
+			transitions = append(transitions, from.String()+" -> "+to.String())
+		},
+	}
+	a, err := InitializeAccount(b)
+	must(t, err)
+	c := a.WithCircuitBreaker(cb).Container("foo")
+
+	// the breaker opens once enough requests have failed
+	for range 4 {
+		_, err := c.Headers(ctx)
+		if !Is(err, http.StatusServiceUnavailable) {
+			t.Errorf("expected 503 error, got %v", err)
+		}
+	}
+	expectString(t, "open", cb.State().String())
+
+	// while open, requests are rejected without being sent
+	_, err = c.Headers(ctx)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if b.requests != 4 {
+		t.Errorf("expected 4 requests to be sent, but got %d", b.requests)
+	}
+
+	// after the cool-down, a failing probe opens the breaker again
+	time.Sleep(60 * time.Millisecond)
+	expectString(t, "half-open", cb.State().String())
+	_, err = c.Headers(ctx)
+	if !Is(err, http.StatusServiceUnavailable) {
+		t.Errorf("expected 503 error, got %v", err)
+	}
+	expectString(t, "open", cb.State().String())
+
+	// after the next cool-down, successful probes close the breaker again
+	time.Sleep(60 * time.Millisecond)
+	statusCode = http.StatusNoContent
+	for range 2 {
+		c.Invalidate()
+		_, err = c.Headers(ctx)
+		must(t, err)
+	}
+	expectString(t, "closed", cb.State().String())
+
+	// the breaker carries over to other accounts
+	_, err = c.Account().SwitchAccount("AUTH_other").Headers(ctx)
+	must(t, err)
+
+	expectString(t, strings.Join([]string{
+		"closed -> open",
+		"open -> half-open",
+		"half-open -> open",
+		"open -> half-open",
+		"half-open -> closed",
+	}, "\n"), strings.Join(transitions, "\n"))
+}
+
+func TestCircuitBreakerCanceledProbe(t *testing.T) {
+	ctx := context.Background()
+	var (
+		statusCode = http.StatusServiceUnavailable
+		failure    error
+	)
+	b := &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		if failure != nil {
+			return nil, failure
+		}
+		return testResponse(statusCode, ""), nil
+	}}
+	cb := &CircuitBreaker{MinimumRequests: 1, CoolDown: 10 * time.Millisecond}
+	a, err := InitializeAccount(b)
+	must(t, err)
+	c := a.WithCircuitBreaker(cb).Container("foo")
+
+	_, err = c.Headers(ctx)
+	if !Is(err, http.StatusServiceUnavailable) {
+		t.Errorf("expected 503 error, got %v", err)
+	}
+	expectString(t, "open", cb.State().String())
+
+	// a canceled probe neither closes the breaker nor uses up the probe slot
+	time.Sleep(20 * time.Millisecond)
+	failure = context.Canceled
+	_, err = c.Headers(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	expectString(t, "half-open", cb.State().String())
+
+	failure = nil
+	statusCode = http.StatusNoContent
+	_, err = c.Headers(ctx)
+	must(t, err)
+	expectString(t, "closed", cb.State().String())
+}
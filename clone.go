@@ -0,0 +1,266 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// CloneOptions contains options for Container.CloneShallow().
+type CloneOptions struct {
+	// If not empty, only objects whose name starts with this prefix are cloned.
+	Prefix string
+	// The maximum number of symlinks that are created at the same time.
+	// Defaults to 4.
+	Concurrency int
+	// If not nil, these options are used for each PUT request.
+	RequestOptions *RequestOptions
+}
+
+// CloneShallow creates a copy-on-write clone of this container in the
+// destination container: For each object in this container, a symlink with the
+// same name is created in the destination container that points to the
+// original object. No object data is copied, so this is cheap even for large
+// containers. This is useful for creating "branches" of a dataset:
+//
+//	branch := account.Container("dataset-experiment")
+//	_, err := branch.EnsureExists(ctx)
+//	count, err := account.Container("dataset").CloneShallow(ctx, branch, nil)
+//
+// Objects in the branch can be overwritten or deleted without affecting the
+// original. Conversely, changes to the original objects are visible through the
+// symlinks in the branch until the respective objects are materialized with
+// Object.Materialize() or Container.Materialize(). Symlinks in this container
+// are cloned by pointing to their target directly, so that no symlink chains
+// are created (this requires the JSON listing format, which is the default).
+//
+// The source container is listed page by page while the symlinks are being
+// created. Returns the number of symlinks created. If listing or creating a
+// symlink fails, no further symlinks are created, and the first error is
+// returned after all requests in progress have completed.
+func (c *Container) CloneShallow(ctx context.Context, dest *Container, opts *CloneOptions) (int, error) {
+	if c.IsEqualTo(dest) {
+		return 0, errors.New("cannot clone a container into itself")
+	}
+	if opts == nil {
+		opts = &CloneOptions{}
+	}
+
+	iter := c.Objects()
+	iter.Prefix = opts.Prefix
+	var (
+		mutex sync.Mutex
+		count int
+	)
+	err := forEachListedConcurrently(ctx, opts.Concurrency, iter, func(ctx context.Context, info ObjectInfo) error {
+		if info.SubDirectory != "" {
+			return nil
+		}
+		target := info.Object
+		if info.SymlinkTarget != nil {
+			target = info.SymlinkTarget
+		}
+		err := dest.Object(info.Object.Name()).SymlinkTo(ctx, target, nil, opts.RequestOptions)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// MaterializeOptions contains options for Object.Materialize() and
+// Container.Materialize().
+type MaterializeOptions struct {
+	// If set, large objects are materialized by copying their manifest, so the
+	// result refers to the same segments as the original. By default, their
+	// content is copied into a plain object, which fails for objects that are
+	// larger than the maximum object size of the cluster.
+	ShallowCopyLargeObjects bool
+	// The maximum number of objects that are copied at the same time by
+	// Container.Materialize(). Defaults to 4.
+	Concurrency int
+	// If not nil, these options are used for each COPY request.
+	RequestOptions *RequestOptions
+}
+
+// Materialize replaces this object, if it is a symlink (e.g. one created by
+// Container.CloneShallow()), with a server-side copy of its target, so that it
+// no longer reflects changes to the target. Metadata is taken from the target.
+// If this object is not a symlink, nothing is done and false is returned.
+//
+// This operation fails with http.StatusNotFound if this object or its target
+// does not exist.
+func (o *Object) Materialize(ctx context.Context, opts *MaterializeOptions) (bool, error) {
+	if opts == nil {
+		opts = &MaterializeOptions{}
+	}
+	_, target, err := o.SymlinkHeaders(ctx)
+	if err != nil || target == nil {
+		return false, err
+	}
+	copyOpts := &CopyOptions{ShallowCopyLargeObjects: opts.ShallowCopyLargeObjects}
+	err = target.CopyTo(ctx, o, copyOpts, opts.RequestOptions)
+	return err == nil, err
+}
+
+// MaterializeResult is returned by Container.Materialize().
+type MaterializeResult struct {
+	// The number of symlinks that were replaced by copies of their targets.
+	ObjectCount int
+	// The names of symlinks whose target does not exist (or that were deleted
+	// while Materialize() was running). These are left unchanged.
+	DanglingSymlinks []string
+}
+
+// Materialize calls Object.Materialize() on all symlinks in this container
+// whose name starts with the given prefix. This turns a shallow clone created
+// by Container.CloneShallow() into a full copy.
+//
+// If a copy fails, no further copies are started, and the first error is
+// returned after all copies in progress have completed.
+func (c *Container) Materialize(ctx context.Context, prefix string, opts *MaterializeOptions) (MaterializeResult, error) {
+	if opts == nil {
+		opts = &MaterializeOptions{}
+	}
+	iter := c.Objects()
+	iter.Prefix = prefix
+	var (
+		mutex  sync.Mutex
+		result MaterializeResult
+	)
+	err := forEachListedConcurrently(ctx, opts.Concurrency, iter, func(ctx context.Context, info ObjectInfo) error {
+		if info.SubDirectory != "" || !info.IsSymlink() {
+			return nil
+		}
+		ok, err := info.Object.Materialize(ctx, opts)
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch {
+		case Is(err, http.StatusNotFound):
+			result.DanglingSymlinks = append(result.DanglingSymlinks, info.Object.Name())
+			return nil
+		case err != nil:
+			return err
+		case ok:
+			result.ObjectCount++
+		}
+		return nil
+	})
+	slices.Sort(result.DanglingSymlinks)
+	return result, err
+}
+
+// forEachConcurrently calls `action` on each of the items, with up to
+// `concurrency` calls running at the same time (defaults to 4). When an action
+// fails, no further actions are started, and the first error is returned after
+// all actions in progress have completed.
+func forEachConcurrently[T any](ctx context.Context, concurrency int, items []T, action func(context.Context, T) error) error {
+	return streamConcurrently(ctx, concurrency, func(_ context.Context, submit func(T) bool) error {
+		for _, item := range items {
+			if !submit(item) {
+				break
+			}
+		}
+		return nil
+	}, action)
+}
+
+// streamConcurrently is like forEachConcurrently, but the items are not known
+// upfront. Instead, `produce` is called once and shall call `submit` for each
+// item. The context given to `produce` is canceled when an action fails. When
+// `submit` returns false (because an action failed or the context expired),
+// `produce` shall stop submitting items and return. If `produce` returns an
+// error, that error is returned after all actions in progress have completed
+// (unless an action failed first).
+func streamConcurrently[T any](ctx context.Context, concurrency int, produce func(context.Context, func(T) bool) error, action func(context.Context, T) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		firstErr  error
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+	submit := func(item T) bool {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			err := action(ctx, item)
+			if err != nil {
+				mutex.Lock()
+				defer mutex.Unlock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+			}
+		}()
+		return true
+	}
+	produceErr := produce(ctx, submit)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if produceErr != nil {
+		return produceErr
+	}
+	return ctx.Err()
+}
+
+// errStopListing is used by forEachListedConcurrently to abort a listing.
+var errStopListing = errors.New("stop listing")
+
+// forEachListedConcurrently is like forEachConcurrently, but the items are
+// taken from the given listing. Pages are fetched as needed while the actions
+// are running, so the listing does not need to be held in memory at once.
+func forEachListedConcurrently(ctx context.Context, concurrency int, iter *ObjectIterator, action func(context.Context, ObjectInfo) error) error {
+	return streamConcurrently(ctx, concurrency, func(ctx context.Context, submit func(ObjectInfo) bool) error {
+		err := iter.ForeachDetailed(ctx, func(info ObjectInfo) error {
+			if !submit(info) {
+				return errStopListing
+			}
+			return nil
+		})
+		if errors.Is(err, errStopListing) {
+			return nil
+		}
+		return err
+	}, action)
+}
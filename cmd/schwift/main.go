@@ -0,0 +1,304 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Command schwift is a minimal command-line client for Swift. It is built
+entirely on the public API of Schwift, and is therefore also meant as
+executable documentation for that API. Install it with:
+
+	go install github.com/majewsky/schwift/v2/cmd/schwift@latest
+
+Credentials are taken from the environment: Either the usual OS_* variables
+for Keystone authentication (as understood by the openstack CLI), or ST_AUTH,
+ST_USER and ST_KEY for Swift v1 authentication (as understood by the swift
+CLI). Run "schwift help" for a list of commands.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/config"
+	"github.com/majewsky/schwift/v2/gopherschwift"
+)
+
+const usage = `Usage: schwift <command> [<args>...]
+
+Commands:
+  ls [<container>[/<prefix>]]         List containers, or objects in a container.
+  stat [<container>[/<object>]]       Show headers of the account, a container or an object.
+  get <container>/<object> [<file>]   Download an object into a file (or to stdout).
+  put <container>/<object> [<file>]   Upload an object from a file (or from stdin).
+  rm <container>[/<object>]           Delete an object, or an empty container.
+  sync [--delete] <dir> <container>[/<prefix>]
+                                      Upload all files in a directory that have changed.
+  tempurl <method> <container>/<object> <seconds> <key>
+                                      Generate a temporary URL.
+  cap                                 Show the capabilities of the Swift cluster.
+`
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err := run(ctx, os.Args[1:], cli{stdin: os.Stdin, stdout: os.Stdout, connect: connect})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "schwift: "+err.Error())
+		cancel()
+		os.Exit(1) //nolint:gocritic // cancel() was called explicitly
+	}
+}
+
+var errUsage = errors.New("invalid arguments (run \"schwift help\" for usage)")
+
+// cli contains the connections of the command to the outside world. Tests
+// replace them to run the command against a fake Swift.
+type cli struct {
+	stdin   io.Reader
+	stdout  io.Writer
+	connect func(context.Context) (*schwift.Account, error)
+}
+
+func run(ctx context.Context, args []string, c cli) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		_, err := io.WriteString(c.stdout, usage)
+		return err
+	}
+	command, args := args[0], args[1:]
+
+	account, err := c.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "ls":
+		return c.cmdList(ctx, account, args)
+	case "stat":
+		return c.cmdStat(ctx, account, args)
+	case "get":
+		return c.cmdGet(ctx, account, args)
+	case "put":
+		return c.cmdPut(ctx, account, args)
+	case "rm":
+		return c.cmdRemove(ctx, account, args)
+	case "sync":
+		return c.cmdSync(ctx, account, args)
+	case "tempurl":
+		return c.cmdTempURL(ctx, account, args)
+	case "cap":
+		return c.cmdCapabilities(ctx, account, args)
+	default:
+		return fmt.Errorf("unknown command: %q (run \"schwift help\" for usage)", command)
+	}
+}
+
+// connect obtains an Account using the credentials from the environment.
+func connect(ctx context.Context) (*schwift.Account, error) {
+	cfg := config.FromEnv()
+	cfg.Options = &gopherschwift.Options{
+		UserAgent: "schwift-cli/" + schwift.Version,
+	}
+	return cfg.Connect(ctx)
+}
+
+// splitPath splits an argument like "container/object/name" into container
+// name and object name.
+func splitPath(arg string) (containerName, objectName string) {
+	containerName, objectName, _ = strings.Cut(arg, "/")
+	return containerName, objectName
+}
+
+func (c cli) cmdList(ctx context.Context, account *schwift.Account, args []string) error {
+	switch len(args) {
+	case 0:
+		return account.Containers().ForeachDetailed(ctx, func(info schwift.ContainerInfo) error {
+			_, err := fmt.Fprintf(c.stdout, "%12d %8d %s\n", info.BytesUsed, info.ObjectCount, info.Container.Name())
+			return err
+		})
+	case 1:
+		containerName, prefix := splitPath(args[0])
+		iter := account.Container(containerName).Objects()
+		iter.Prefix = prefix
+		return iter.ForeachDetailed(ctx, func(info schwift.ObjectInfo) error {
+			_, err := fmt.Fprintf(c.stdout, "%12d %s %s\n", info.SizeBytes, info.LastModified.Format(time.DateTime), info.Object.Name())
+			return err
+		})
+	default:
+		return errUsage
+	}
+}
+
+func (c cli) cmdStat(ctx context.Context, account *schwift.Account, args []string) error {
+	var hdr schwift.Headers
+	switch len(args) {
+	case 0:
+		h, err := account.Headers(ctx)
+		if err != nil {
+			return err
+		}
+		hdr = h.Headers
+	case 1:
+		containerName, objectName := splitPath(args[0])
+		if objectName == "" {
+			h, err := account.Container(containerName).Headers(ctx)
+			if err != nil {
+				return err
+			}
+			hdr = h.Headers
+		} else {
+			h, err := account.Container(containerName).Object(objectName).Headers(ctx)
+			if err != nil {
+				return err
+			}
+			hdr = h.Headers
+		}
+	default:
+		return errUsage
+	}
+
+	keys := make([]string, 0, len(hdr))
+	for key := range hdr {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		fmt.Fprintf(c.stdout, "%s: %s\n", key, hdr[key])
+	}
+	return nil
+}
+
+func (c cli) cmdGet(ctx context.Context, account *schwift.Account, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	containerName, objectName := splitPath(args[0])
+	if objectName == "" {
+		return errUsage
+	}
+	obj := account.Container(containerName).Object(objectName)
+
+	if len(args) == 2 && args[1] != "-" {
+		return obj.DownloadVerified(ctx, args[1], nil)
+	}
+	_, err := obj.Download(ctx, nil).WriteTo(c.stdout)
+	return err
+}
+
+func (c cli) cmdPut(ctx context.Context, account *schwift.Account, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errUsage
+	}
+	containerName, objectName := splitPath(args[0])
+	if objectName == "" {
+		return errUsage
+	}
+
+	content := c.stdin
+	if len(args) == 2 && args[1] != "-" {
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		content = file
+	}
+	return account.Container(containerName).Object(objectName).Upload(ctx, content, nil, nil)
+}
+
+func (c cli) cmdRemove(ctx context.Context, account *schwift.Account, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	containerName, objectName := splitPath(args[0])
+	if objectName == "" {
+		return account.Container(containerName).Delete(ctx, nil)
+	}
+	return account.Container(containerName).Object(objectName).Delete(ctx, nil, nil)
+}
+
+func (c cli) cmdSync(ctx context.Context, account *schwift.Account, args []string) error {
+	opts := &schwift.UploadFSOptions{SkipUnchanged: true}
+	if len(args) > 0 && args[0] == "--delete" {
+		opts.DeleteMissing = true
+		args = args[1:]
+	}
+	if len(args) != 2 {
+		return errUsage
+	}
+	containerName, prefix := splitPath(args[1])
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	container, err := account.Container(containerName).EnsureExists(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := container.UploadFS(ctx, os.DirFS(args[0]), prefix, opts)
+	fmt.Fprintf(c.stdout, "%d files uploaded (%d bytes), %d files unchanged, %d objects deleted\n",
+		result.FilesUploaded, result.BytesUploaded, result.FilesSkipped, result.ObjectsDeleted)
+	return err
+}
+
+func (c cli) cmdTempURL(ctx context.Context, account *schwift.Account, args []string) error {
+	if len(args) != 4 {
+		return errUsage
+	}
+	method := strings.ToUpper(args[0])
+	containerName, objectName := splitPath(args[1])
+	if objectName == "" {
+		return errUsage
+	}
+	seconds, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid number of seconds: %w", err)
+	}
+	expires := time.Now().Add(time.Duration(seconds) * time.Second)
+
+	url, err := account.Container(containerName).Object(objectName).TempURL(ctx, args[3], method, expires)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, url)
+	return nil
+}
+
+func (c cli) cmdCapabilities(ctx context.Context, account *schwift.Account, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	caps, err := account.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(caps)
+}
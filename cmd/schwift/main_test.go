@@ -0,0 +1,171 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/internal/fakeswift"
+)
+
+// testCLI runs commands against a fake Swift. All commands share the same
+// fake account.
+type testCLI struct {
+	t       *testing.T
+	account *schwift.Account
+}
+
+// Run runs the command with the given arguments and stdin, and returns its
+// stdout and error.
+func (tc testCLI) Run(stdin string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := run(context.Background(), args, cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		connect: func(context.Context) (*schwift.Account, error) {
+			return tc.account, nil
+		},
+	})
+	return stdout.String(), err
+}
+
+// Expect runs the command and checks that it succeeds with the given output.
+func (tc testCLI) Expect(expectedOutput string, args ...string) {
+	tc.t.Helper()
+	output, err := tc.Run("", args...)
+	if err != nil {
+		tc.t.Errorf("%q failed: %s", args, err.Error())
+	}
+	if output != expectedOutput {
+		tc.t.Errorf("%q: expected output %q, got %q", args, expectedOutput, output)
+	}
+}
+
+// ExpectError runs the command and checks that it fails with the given error.
+func (tc testCLI) ExpectError(expectedError string, args ...string) {
+	tc.t.Helper()
+	_, err := tc.Run("", args...)
+	if err == nil || err.Error() != expectedError {
+		tc.t.Errorf("%q: expected error %q, got %v", args, expectedError, err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tc := testCLI{t, fakeswift.NewAccount()}
+	ctx := context.Background()
+	_, err := tc.account.Container("foo").EnsureExists(ctx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// put and get through stdin/stdout
+	output, err := tc.Run("hello world", "put", "foo/greeting.txt")
+	if err != nil || output != "" {
+		t.Errorf("put failed: output = %q, err = %v", output, err)
+	}
+	tc.Expect("hello world", "get", "foo/greeting.txt")
+	tc.Expect("hello world", "get", "foo/greeting.txt", "-")
+
+	// put and get through files
+	dir := t.TempDir()
+	must(t, os.WriteFile(filepath.Join(dir, "input.txt"), []byte("from file"), 0o600))
+	tc.Expect("", "put", "foo/file.txt", filepath.Join(dir, "input.txt"))
+	tc.Expect("", "get", "foo/file.txt", filepath.Join(dir, "output.txt"))
+	buf, err := os.ReadFile(filepath.Join(dir, "output.txt"))
+	must(t, err)
+	if string(buf) != "from file" {
+		t.Errorf("expected downloaded file to contain %q, got %q", "from file", string(buf))
+	}
+
+	// stat shows headers
+	output, err = tc.Run("", "stat", "foo/greeting.txt")
+	must(t, err)
+	if !strings.Contains(output, "Content-Length: 11\n") {
+		t.Errorf("expected Content-Length in stat output, got %q", output)
+	}
+	output, err = tc.Run("", "stat", "foo")
+	must(t, err)
+	if !strings.Contains(output, "X-Container-Object-Count: 2\n") {
+		t.Errorf("expected object count in stat output, got %q", output)
+	}
+
+	// sync uploads changed files, and deletes missing ones if requested
+	syncDir := t.TempDir()
+	must(t, os.WriteFile(filepath.Join(syncDir, "a.txt"), []byte("aaa"), 0o600))
+	must(t, os.WriteFile(filepath.Join(syncDir, "b.txt"), []byte("bbb"), 0o600))
+	tc.Expect("2 files uploaded (6 bytes), 0 files unchanged, 0 objects deleted\n", "sync", syncDir, "foo/synced")
+	must(t, os.Remove(filepath.Join(syncDir, "b.txt")))
+	tc.Expect("0 files uploaded (0 bytes), 1 files unchanged, 1 objects deleted\n", "sync", "--delete", syncDir, "foo/synced/")
+
+	// ls lists containers and objects (with size, timestamp and name)
+	output, err = tc.Run("", "ls")
+	must(t, err)
+	if !strings.HasSuffix(output, " 3 foo\n") {
+		t.Errorf("unexpected container listing: %q", output)
+	}
+	output, err = tc.Run("", "ls", "foo/synced/")
+	must(t, err)
+	if !strings.HasPrefix(output, "           3 ") || !strings.HasSuffix(output, " synced/a.txt\n") {
+		t.Errorf("unexpected object listing: %q", output)
+	}
+
+	// rm deletes objects and containers
+	for _, name := range []string{"greeting.txt", "file.txt", "synced/a.txt"} {
+		tc.Expect("", "rm", "foo/"+name)
+	}
+	tc.Expect("", "rm", "foo")
+	tc.Expect("", "ls")
+
+	// tempurl and cap report on the capabilities of the cluster
+	output, err = tc.Run("", "tempurl", "get", "foo/bar", "60", "secret")
+	must(t, err)
+	if !strings.HasPrefix(output, fakeswift.BaseURL+"v1/AUTH_test/foo/bar?temp_url_sig=") {
+		t.Errorf("unexpected temp URL: %q", output)
+	}
+	output, err = tc.Run("", "cap")
+	must(t, err)
+	if !strings.Contains(output, `"tempurl"`) {
+		t.Errorf("unexpected capabilities: %q", output)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	tc := testCLI{t, fakeswift.NewAccount()}
+	tc.Expect(usage, "help")
+	tc.Expect(usage)
+	tc.ExpectError(`unknown command: "frobnicate" (run "schwift help" for usage)`, "frobnicate")
+	tc.ExpectError(errUsage.Error(), "get", "foo")
+	tc.ExpectError(errUsage.Error(), "rm")
+	tc.ExpectError(errUsage.Error(), "sync", "--delete", "dir")
+	tc.ExpectError(errUsage.Error(), "cap", "extra")
+	tc.ExpectError(`invalid number of seconds: strconv.ParseUint: parsing "soon": invalid syntax`, "tempurl", "get", "foo/bar", "soon", "secret")
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+}
@@ -0,0 +1,139 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package config builds a schwift.Account from credentials that are discovered in
+the usual places, so that applications do not need to reimplement credential
+discovery. Credentials can be given in environment variables, in clouds.yaml,
+or in an explicit Config struct. The most common usage is:
+
+	import "github.com/majewsky/schwift/v2/config"
+
+	account, err := config.FromEnv().Connect(ctx)
+
+FromEnv() understands the same environment variables as the swift and openstack
+CLIs: Either ST_AUTH, ST_USER and ST_KEY for Swift v1 authentication, or the
+usual OS_* variables for Keystone authentication (including OS_CLOUD to select
+an entry from clouds.yaml). Authentication is performed through Gophercloud, and
+the resulting Account is created with gopherschwift.WrapContext().
+*/
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/swauth"
+	"github.com/gophercloud/utils/v2/openstack/clientconfig"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/gopherschwift"
+)
+
+// Config describes how to authenticate to Swift. If SwiftAuthURL is set, Swift
+// v1 authentication is used. Otherwise, Keystone authentication is used.
+type Config struct {
+	// Swift v1 authentication, as with the ST_AUTH, ST_USER and ST_KEY
+	// environment variables understood by the swift CLI.
+	SwiftAuthURL string
+	SwiftUser    string
+	SwiftKey     string
+
+	// Keystone authentication. If Cloud is set, this entry is read from
+	// clouds.yaml. Otherwise, if Keystone is not nil, these credentials are
+	// used. Otherwise, credentials are read from the OS_* environment variables
+	// (including OS_CLOUD) by Gophercloud.
+	Cloud    string
+	Keystone *clientconfig.AuthInfo
+	// If set, the object-store endpoint is selected from this region and with
+	// this interface (e.g. "public" or "internal") from the Keystone catalog.
+	// Otherwise, OS_REGION_NAME and OS_INTERFACE or the clouds.yaml entry are
+	// used.
+	RegionName string
+	Interface  string
+
+	// Options for gopherschwift.WrapContext(), e.g. a custom User-Agent.
+	Options *gopherschwift.Options
+}
+
+// FromEnv returns a Config that takes Swift v1 credentials from the ST_AUTH,
+// ST_USER and ST_KEY environment variables. If none of these are set, the
+// Config leaves it to Gophercloud to discover Keystone credentials from the
+// OS_* environment variables and from clouds.yaml.
+func FromEnv() Config {
+	return Config{
+		SwiftAuthURL: os.Getenv("ST_AUTH"),
+		SwiftUser:    os.Getenv("ST_USER"),
+		SwiftKey:     os.Getenv("ST_KEY"),
+	}
+}
+
+// Validate checks that the Config does not mix different authentication
+// methods, and that Swift v1 credentials are complete.
+func (cfg Config) Validate() error {
+	hasSwiftAuth := cfg.SwiftAuthURL != "" || cfg.SwiftUser != "" || cfg.SwiftKey != ""
+	if !hasSwiftAuth {
+		return nil
+	}
+	if cfg.Cloud != "" || cfg.Keystone != nil {
+		return errors.New("cannot use Swift v1 credentials and Keystone credentials at the same time")
+	}
+	if cfg.SwiftAuthURL == "" || cfg.SwiftUser == "" || cfg.SwiftKey == "" {
+		return errors.New("incomplete Swift v1 credentials (need all of ST_AUTH, ST_USER and ST_KEY)")
+	}
+	return nil
+}
+
+// Connect authenticates with the credentials described by this Config and
+// returns the resulting Account.
+func (cfg Config) Connect(ctx context.Context) (*schwift.Account, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var client *gophercloud.ServiceClient
+	if cfg.SwiftAuthURL == "" {
+		client, err = clientconfig.NewServiceClient(ctx, "object-store", &clientconfig.ClientOpts{
+			Cloud:        cfg.Cloud,
+			AuthInfo:     cfg.Keystone,
+			RegionName:   cfg.RegionName,
+			EndpointType: cfg.Interface,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot authenticate with Keystone: %w", err)
+		}
+	} else {
+		provider, err := openstack.NewClient(cfg.SwiftAuthURL)
+		if err != nil {
+			return nil, err
+		}
+		client, err = swauth.NewObjectStorageV1(ctx, provider, swauth.AuthOpts{
+			User: cfg.SwiftUser,
+			Key:  cfg.SwiftKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("cannot authenticate with Swift: %w", err)
+		}
+	}
+	return gopherschwift.WrapContext(ctx, client, cfg.Options)
+}
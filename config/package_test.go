@@ -0,0 +1,60 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/gophercloud/utils/v2/openstack/clientconfig"
+)
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("ST_AUTH", "https://swift.example.com/auth/v1.0")
+	t.Setenv("ST_USER", "test:tester")
+	t.Setenv("ST_KEY", "testing")
+	cfg := FromEnv()
+	if cfg.SwiftAuthURL != "https://swift.example.com/auth/v1.0" || cfg.SwiftUser != "test:tester" || cfg.SwiftKey != "testing" {
+		t.Errorf("unexpected Config: %#v", cfg)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		Config Config
+		Error  string
+	}{
+		{Config{}, ""},
+		{Config{Cloud: "mycloud"}, ""},
+		{Config{SwiftAuthURL: "https://swift.example.com/auth/v1.0", SwiftUser: "test:tester", SwiftKey: "testing"}, ""},
+		{Config{SwiftAuthURL: "https://swift.example.com/auth/v1.0", SwiftUser: "test:tester"}, "incomplete Swift v1 credentials (need all of ST_AUTH, ST_USER and ST_KEY)"},
+		{Config{SwiftUser: "test:tester", SwiftKey: "testing"}, "incomplete Swift v1 credentials (need all of ST_AUTH, ST_USER and ST_KEY)"},
+		{Config{SwiftKey: "testing", Keystone: &clientconfig.AuthInfo{}}, "cannot use Swift v1 credentials and Keystone credentials at the same time"},
+	}
+
+	for idx, tc := range testCases {
+		err := tc.Config.Validate()
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if actual != tc.Error {
+			t.Errorf("test case %d: expected error %q, got %q", idx, tc.Error, actual)
+		}
+	}
+}
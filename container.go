@@ -21,6 +21,7 @@ package schwift
 import (
 	"context"
 	"net/http"
+	"net/textproto"
 )
 
 // Container represents a Swift container. Instances are usually obtained by
@@ -29,8 +30,11 @@ import (
 type Container struct {
 	a    *Account
 	name string
+	// set by WithDefaultObjectHeaders()
+	defaultObjectHeaders Headers
 	// cache
-	headers *ContainerHeaders
+	headers    *ContainerHeaders
+	rawHeaders http.Header // multi-valued headers from the response that filled `headers`
 }
 
 // IsEqualTo returns true if both Container instances refer to the same container.
@@ -59,6 +63,47 @@ func (c *Container) Name() string {
 	return c.name
 }
 
+// WithDefaultObjectHeaders returns a handle to the same container that adds
+// the given headers to every Object.Upload() and Object.UploadMultiReader()
+// call on objects obtained through it, unless the respective header is already
+// given in the RequestOptions of that call. This is useful for headers that
+// shall be applied consistently throughout an application, e.g.
+//
+//	hdr := schwift.NewObjectHeaders()
+//	hdr.Set("Cache-Control", "max-age=3600")
+//	hdr.ExpiresAfter().Set(30 * 24 * time.Hour)
+//	container := account.Container("logs").WithDefaultObjectHeaders(hdr)
+//
+// The default headers are a client-side convenience only. They are not stored
+// on the server, and do not apply to other handles for the same container.
+// Calling this method again replaces the previous set of default headers.
+//
+// The returned Container does not share any caches with this Container.
+func (c *Container) WithDefaultObjectHeaders(hdr ObjectHeaders) *Container {
+	defaults := make(Headers, len(hdr.Headers))
+	for k, v := range hdr.Headers {
+		defaults.Set(k, v)
+	}
+	return &Container{a: c.a, name: c.name, defaultObjectHeaders: defaults}
+}
+
+// applyDefaultObjectHeaders adds the headers from WithDefaultObjectHeaders()
+// to the given headers, except for those that are already present.
+func (c *Container) applyDefaultObjectHeaders(hdr Headers) {
+	if len(c.defaultObjectHeaders) == 0 {
+		return
+	}
+	present := make(map[string]bool, len(hdr))
+	for k := range hdr {
+		present[textproto.CanonicalMIMEHeaderKey(k)] = true
+	}
+	for k, v := range c.defaultObjectHeaders {
+		if !present[k] {
+			hdr[k] = v
+		}
+	}
+}
+
 // Exists checks if this container exists, potentially by issuing a HEAD request
 // if no Headers() have been cached yet.
 func (c *Container) Exists(ctx context.Context) (bool, error) {
@@ -93,15 +138,25 @@ func (c *Container) Headers(ctx context.Context) (ContainerHeaders, error) {
 	}
 	defer resp.Body.Close()
 
-	headers := ContainerHeaders{headersFromHTTP(resp.Header)}
+	headers := newContainerHeadersFromHTTP(resp.Header)
 	err = headers.Validate()
 	if err != nil {
 		return headers, err
 	}
 	c.headers = &headers
+	c.rawHeaders = multiValuedHeaders(resp.Header)
 	return *c.headers, nil
 }
 
+// RawAll returns all values of the given header in the response that the
+// cached ContainerHeaders were obtained from. This works like Account.RawAll().
+func (c *Container) RawAll(key string) []string {
+	if c.headers == nil {
+		return nil
+	}
+	return rawAll(c.headers.Headers, c.rawHeaders, key)
+}
+
 // Update updates the container using a POST request. To add URL parameters, pass
 // a non-nil *RequestOptions.
 //
@@ -172,6 +227,7 @@ func (c *Container) Delete(ctx context.Context, opts *RequestOptions) error {
 // object results in undefined behavior.
 func (c *Container) Invalidate() {
 	c.headers = nil
+	c.rawHeaders = nil
 }
 
 // EnsureExists issues a PUT request on this container.
@@ -181,6 +237,9 @@ func (c *Container) Invalidate() {
 // with freshly constructed Container instances like so:
 //
 //	container, err := account.Container("documents").EnsureExists()
+//
+// To also ensure that the container has certain headers, use
+// CreateIfNotExists() instead.
 func (c *Container) EnsureExists(ctx context.Context) (*Container, error) {
 	resp, err := Request{
 		Method:            "PUT",
@@ -194,6 +253,43 @@ func (c *Container) EnsureExists(ctx context.Context) (*Container, error) {
 	return c, err
 }
 
+// CreateIfNotExists is like EnsureExists, but additionally reconciles the
+// container's headers with the given ones. If the container does not exist,
+// it is created with these headers using a PUT request. If it exists, its
+// current headers are compared with the given ones, and all headers that
+// differ are sent to the server in a POST request. (No request is sent if
+// nothing differs.) As with Update(), a header with an empty value in the
+// given headers will cause this header to be removed on the server.
+//
+// The return value contains those headers that were sent to the server, so
+// the caller can find out what was changed. For freshly created containers,
+// this is the entire set of given headers (minus those with empty values).
+//
+// A successful PUT or POST request implies Invalidate() since it may change
+// metadata.
+func (c *Container) CreateIfNotExists(ctx context.Context, headers ContainerHeaders, opts *RequestOptions) (ContainerHeaders, error) {
+	changed := NewContainerHeaders()
+
+	exists, err := c.Exists(ctx)
+	if err != nil {
+		return changed, err
+	}
+	if !exists {
+		changed.Headers = diffHeaders(make(Headers), headers.Headers)
+		return changed, c.Create(ctx, cloneRequestOptions(opts, changed.Headers))
+	}
+
+	current, err := c.Headers(ctx)
+	if err != nil {
+		return changed, err
+	}
+	changed.Headers = diffHeaders(current.Headers, headers.Headers)
+	if len(changed.Headers) == 0 {
+		return changed, nil
+	}
+	return changed, c.Update(ctx, changed, opts)
+}
+
 // Objects returns an ObjectIterator that lists the objects in this
 // container. The most common use case is:
 //
@@ -215,6 +311,12 @@ func (c *Container) Objects() *ObjectIterator {
 	return &ObjectIterator{Container: c}
 }
 
+// NewRequest prepares a request on this container for use with Request.Do().
+// See documentation on type Request for details. The opts argument may be nil.
+func (c *Container) NewRequest(method string, opts *RequestOptions) Request {
+	return Request{Method: method, ContainerName: c.name, Options: opts}
+}
+
 // URL returns the canonical URL for this container on the server. This is
 // particularly useful when the ReadACL on the account or container is set to
 // allow anonymous read access.
@@ -223,3 +325,11 @@ func (c *Container) URL() (string, error) {
 		ContainerName: c.name,
 	}.URL(c.a.backend, nil)
 }
+
+// PublicURL returns the URL for this container that unauthenticated clients
+// can use when the ReadACL on the container permits anonymous access. Unlike
+// URL(), this honors the public endpoint configured with
+// Account.WithPublicEndpoint().
+func (c *Container) PublicURL() (string, error) {
+	return c.a.publicURL(c.name, "")
+}
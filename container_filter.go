@@ -0,0 +1,156 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ContainerHeaderCache caches the results of the HEAD requests issued by
+// ContainerIterator.CollectWhere(). When the same cache is passed to multiple
+// calls, each container is only HEADed once, so repeated scans of the same
+// account with different predicates (e.g. within one run of a cleanup job) do
+// not multiply the number of requests. Entries never expire; create a new
+// cache or call Clear() to pick up metadata changes.
+//
+// A ContainerHeaderCache is safe for concurrent use.
+type ContainerHeaderCache struct {
+	mutex   sync.Mutex
+	entries map[string]ContainerHeaders
+}
+
+// NewContainerHeaderCache creates a new, empty ContainerHeaderCache.
+func NewContainerHeaderCache() *ContainerHeaderCache {
+	return &ContainerHeaderCache{entries: make(map[string]ContainerHeaders)}
+}
+
+// Clear removes all entries from this cache.
+func (hc *ContainerHeaderCache) Clear() {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	clear(hc.entries)
+}
+
+func (hc *ContainerHeaderCache) cacheKey(c *Container) string {
+	return c.a.backend.EndpointURL() + c.name
+}
+
+func (hc *ContainerHeaderCache) get(c *Container) (ContainerHeaders, bool) {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	hdr, ok := hc.entries[hc.cacheKey(c)]
+	return hdr, ok
+}
+
+func (hc *ContainerHeaderCache) put(c *Container, hdr ContainerHeaders) {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	hc.entries[hc.cacheKey(c)] = hdr
+}
+
+// ContainerFilterOptions contains options for ContainerIterator.CollectWhere().
+type ContainerFilterOptions struct {
+	// Concurrency is the maximum number of HEAD requests that are in flight at
+	// the same time. The default is 8.
+	Concurrency int
+	// If Cache is not nil, HEAD results are looked up in and stored in this
+	// cache. See documentation on type ContainerHeaderCache for details.
+	Cache *ContainerHeaderCache
+}
+
+// MetadataEquals returns a predicate for ContainerIterator.CollectWhere() that
+// matches containers whose metadata field with the given key (without the
+// "X-Container-Meta-" prefix) has exactly the given value. For example:
+//
+//	containers, err := account.Containers().CollectWhere(ctx,
+//		schwift.MetadataEquals("Managed-By", "myapp"), nil)
+func MetadataEquals(key, value string) func(ContainerHeaders) bool {
+	return func(hdr ContainerHeaders) bool {
+		return hdr.Metadata().Get(key) == value
+	}
+}
+
+// CollectWhere lists all containers matching this iterator, HEADs them
+// concurrently, and returns those containers for which the predicate returns
+// true, in listing order. Since container listings do not include metadata,
+// this is the only way to filter containers by metadata, but it issues one
+// HEAD request per listed container; use Prefix, Match and Exclude to narrow
+// down the listing as far as possible.
+//
+// The returned containers already have their headers loaded, so
+// Container.Headers() does not issue another request. Containers that are
+// deleted while this method is running are skipped. The predicate may be
+// called concurrently from multiple goroutines.
+func (i *ContainerIterator) CollectWhere(ctx context.Context, predicate func(ContainerHeaders) bool, opts *ContainerFilterOptions) ([]*Container, error) {
+	if opts == nil {
+		opts = &ContainerFilterOptions{}
+	}
+	containers, err := i.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 8
+	}
+	matched := make([]bool, len(containers))
+	var uncached []int
+	for idx, c := range containers {
+		if opts.Cache != nil {
+			if hdr, ok := opts.Cache.get(c); ok {
+				c.headers = &hdr
+				c.rawHeaders = nil
+				matched[idx] = predicate(hdr)
+				continue
+			}
+		}
+		uncached = append(uncached, idx)
+	}
+
+	err = forEachConcurrently(ctx, concurrency, uncached, func(ctx context.Context, idx int) error {
+		c := containers[idx]
+		hdr, err := c.Headers(ctx)
+		if Is(err, http.StatusNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if opts.Cache != nil {
+			opts.Cache.put(c, hdr)
+		}
+		// each action writes only its own slot, so no locking is needed
+		matched[idx] = predicate(hdr)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Container
+	for idx, c := range containers {
+		if matched[idx] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
@@ -21,6 +21,7 @@ package schwift
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -33,6 +34,11 @@ type ContainerInfo struct {
 	ObjectCount  uint64
 	BytesUsed    uint64
 	LastModified time.Time
+	// StoragePolicy is the name of the container's storage policy. It is only
+	// reported by newer Swift versions, and is empty otherwise (or for
+	// ListingFormatPlain). Use Capabilities.StoragePolicy() to find the
+	// corresponding StoragePolicySpec.
+	StoragePolicy string
 }
 
 // ContainerIterator iterates over the accounts in a container. It is typically
@@ -65,8 +71,20 @@ type ContainerIterator struct {
 	// When Prefix is set, only containers whose name starts with this string are
 	// returned.
 	Prefix string
+	// When Match is set, only containers whose name matches this regular
+	// expression are returned. When Exclude is set, containers whose name
+	// matches that regular expression are not returned. Unlike Prefix, these
+	// filters are applied on the client side, so the server still returns (and
+	// the client still downloads) the entire listing below the Prefix.
+	Match   *regexp.Regexp
+	Exclude *regexp.Regexp
 	// Options may contain additional headers and query parameters for the GET request.
+	// If Options contains a "marker" query parameter, the first page starts
+	// after the container with that name. This can be used to resume a listing.
 	Options *RequestOptions
+	// Format selects the response format for the "Detailed" methods. See
+	// documentation on type ListingFormat for details.
+	Format ListingFormat
 
 	base *iteratorBase
 }
@@ -78,6 +96,13 @@ func (i *ContainerIterator) getBase() *iteratorBase {
 	return i.base
 }
 
+// Stats returns pagination metadata for all pages that have been fetched
+// through this iterator so far. See documentation on type ListingStats for
+// details.
+func (i *ContainerIterator) Stats() ListingStats {
+	return i.getBase().stats()
+}
+
 // NextPage queries Swift for the next page of container names. If limit is
 // >= 0, not more than that many container names will be returned at once. Note
 // that the server also has a limit for how many containers to list in one
@@ -88,7 +113,7 @@ func (i *ContainerIterator) getBase() *iteratorBase {
 // This method offers maximal flexibility, but most users will prefer the
 // simpler interfaces offered by Collect() and Foreach().
 func (i *ContainerIterator) NextPage(ctx context.Context, limit int) ([]*Container, error) {
-	names, err := i.getBase().nextPage(ctx, limit)
+	names, err := i.getBase().nextFilteredPage(ctx, limit, i.Match, i.Exclude)
 	if err != nil {
 		return nil, err
 	}
@@ -102,33 +127,68 @@ func (i *ContainerIterator) NextPage(ctx context.Context, limit int) ([]*Contain
 
 // NextPageDetailed is like NextPage, but includes basic metadata.
 func (i *ContainerIterator) NextPageDetailed(ctx context.Context, limit int) ([]ContainerInfo, error) {
-	b := i.getBase()
+	for {
+		infos, err := i.nextPageDetailedUnfiltered(ctx, limit)
+		if err != nil || len(infos) == 0 {
+			return infos, err
+		}
+		infos = filterByName(infos, i.Match, i.Exclude, func(info ContainerInfo) string {
+			return info.Container.Name()
+		})
+		if len(infos) > 0 {
+			return infos, nil
+		}
+		// all entries on this page were filtered out, but the listing is not
+		// finished yet
+	}
+}
 
-	var document []struct {
-		BytesUsed       uint64 `json:"bytes"`
-		ObjectCount     uint64 `json:"count"`
-		LastModifiedStr string `json:"last_modified"`
-		Name            string `json:"name"`
+func (i *ContainerIterator) nextPageDetailedUnfiltered(ctx context.Context, limit int) ([]ContainerInfo, error) {
+	if i.Format == ListingFormatPlain {
+		containers, err := i.NextPage(ctx, limit)
+		if err != nil || len(containers) == 0 {
+			return nil, err
+		}
+		result := make([]ContainerInfo, len(containers))
+		for idx, c := range containers {
+			result[idx].Container = c
+		}
+		return result, nil
 	}
-	err := b.nextPageDetailed(ctx, limit, &document)
+
+	b := i.getBase()
+	body, err := b.nextPageDetailed(ctx, limit)
 	if err != nil {
 		return nil, err
 	}
-	if len(document) == 0 {
-		b.setMarker("") // indicate EOF to iteratorBase
-		return nil, nil
-	}
-
-	result := make([]ContainerInfo, len(document))
-	for idx, data := range document {
-		result[idx].Container = i.Account.Container(data.Name)
-		result[idx].BytesUsed = data.BytesUsed
-		result[idx].ObjectCount = data.ObjectCount
-		result[idx].LastModified, err = time.Parse(time.RFC3339Nano, data.LastModifiedStr+"Z")
+	var result []ContainerInfo
+	err = decodeListing(body, i.Format, func(data struct {
+		BytesUsed       uint64 `json:"bytes" xml:"bytes"`
+		ObjectCount     uint64 `json:"count" xml:"count"`
+		LastModifiedStr string `json:"last_modified" xml:"last_modified"`
+		Name            string `json:"name" xml:"name"`
+		StoragePolicy   string `json:"storage_policy" xml:"storage_policy"`
+	}) error {
+		lastModified, err := time.Parse(time.RFC3339Nano, data.LastModifiedStr+"Z")
 		if err != nil {
 			// this error is sufficiently obscure that we don't need to expose a type for it
-			return nil, fmt.Errorf("bad field containers[%d].last_modified: %s", idx, err.Error())
+			return fmt.Errorf("bad field containers[%d].last_modified: %s", len(result), err.Error())
 		}
+		result = append(result, ContainerInfo{
+			Container:     i.Account.Container(data.Name),
+			BytesUsed:     data.BytesUsed,
+			ObjectCount:   data.ObjectCount,
+			StoragePolicy: data.StoragePolicy,
+			LastModified:  lastModified,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		b.setMarker("") // indicate EOF to iteratorBase
+		return nil, nil
 	}
 
 	b.setMarker(result[len(result)-1].Container.Name())
@@ -0,0 +1,135 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ConfigureCORS sets up Cross-Origin Resource Sharing (CORS) for this
+// container using a POST request. The arguments correspond to the
+// X-Container-Meta-Access-Control-* headers that are also available as
+// ContainerHeaders.CORSAllowedOrigins() etc.:
+//
+//   - allowedOrigins lists the origins (e.g. "https://example.com") that may
+//     access objects in this container from a browser. The special origin "*"
+//     allows all origins. If empty, CORS is disabled for this container
+//     (unless the cluster is configured to allow some origins everywhere).
+//   - maxAge is how long browsers may cache the result of a preflight request.
+//     If zero, no maximum age is configured.
+//   - exposeHeaders lists response headers (in addition to the default ones)
+//     that browsers shall make available to scripts.
+//
+// Settings that are passed as empty values are removed from the container.
+// All other metadata is left unchanged.
+//
+// A successful POST request implies Invalidate() since it may change metadata.
+func (c *Container) ConfigureCORS(ctx context.Context, allowedOrigins []string, maxAge time.Duration, exposeHeaders []string, opts *RequestOptions) error {
+	hdr := NewContainerHeaders()
+	if len(allowedOrigins) == 0 {
+		hdr.CORSAllowedOrigins().Remove()
+	} else {
+		hdr.CORSAllowedOrigins().Set(strings.Join(allowedOrigins, " "))
+	}
+	if maxAge == 0 {
+		hdr.CORSMaxAge().Remove()
+	} else {
+		hdr.CORSMaxAge().Set(maxAge)
+	}
+	if len(exposeHeaders) == 0 {
+		hdr.CORSExposedHeaders().Remove()
+	} else {
+		hdr.CORSExposedHeaders().Set(strings.Join(exposeHeaders, " "))
+	}
+	return c.Update(ctx, hdr, opts)
+}
+
+// CORSPreflightResult describes the response of Swift to a CORS preflight
+// request (i.e. an OPTIONS request with an Origin header). It is returned by
+// ContainerHeaders.SimulateCORSPreflight().
+type CORSPreflightResult struct {
+	// Allowed is false if Swift would reject the preflight request with 401
+	// (Unauthorized). All other fields are only filled if Allowed is true.
+	Allowed bool
+	// Values for the respective Access-Control-Allow-* and
+	// Access-Control-Max-Age response headers. MaxAge is zero if the container
+	// does not configure a maximum age.
+	AllowOrigin  string
+	AllowMethods []string
+	AllowHeaders []string
+	MaxAge       time.Duration
+}
+
+// These are the methods that Swift allows on objects. Preflight requests are
+// almost always sent for objects, so we only simulate those.
+var corsAllowedObjectMethods = []string{
+	"COPY",
+	http.MethodDelete,
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodPost,
+	http.MethodPut,
+}
+
+// SimulateCORSPreflight computes how Swift would answer a CORS preflight
+// request for an object in the container with these headers. The arguments
+// correspond to the Origin, Access-Control-Request-Method and
+// Access-Control-Request-Headers headers of the preflight request. For
+// example:
+//
+//	hdr, err := container.Headers(ctx)
+//	result := hdr.SimulateCORSPreflight("https://example.com", "GET", nil)
+//	if !result.Allowed {
+//		log.Println("browsers on example.com cannot load assets from this container")
+//	}
+//
+// This takes only the container's metadata into account. Origins that the
+// cluster operator has allowed for all containers (the cors_allow_origin
+// setting of the Swift proxy) are unknown to the client and thus not
+// considered.
+func (h ContainerHeaders) SimulateCORSPreflight(origin, method string, requestHeaders []string) CORSPreflightResult {
+	allowedOrigins := strings.Fields(h.CORSAllowedOrigins().Get())
+	isAllowed := slices.Contains(allowedOrigins, origin) || slices.Contains(allowedOrigins, "*")
+	if origin == "" || !isAllowed || !slices.Contains(corsAllowedObjectMethods, method) {
+		return CORSPreflightResult{Allowed: false}
+	}
+
+	result := CORSPreflightResult{
+		Allowed:      true,
+		AllowOrigin:  origin,
+		AllowMethods: slices.Clone(corsAllowedObjectMethods),
+		MaxAge:       h.CORSMaxAge().Get(),
+	}
+	if strings.TrimSpace(h.CORSAllowedOrigins().Get()) == "*" {
+		result.AllowOrigin = "*"
+	}
+	// Swift allows all requested headers
+	for _, header := range requestHeaders {
+		header = strings.TrimSpace(header)
+		if header != "" && !slices.Contains(result.AllowHeaders, header) {
+			result.AllowHeaders = append(result.AllowHeaders, header)
+		}
+	}
+	return result
+}
@@ -0,0 +1,345 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package crawler visits every object in a Swift account (or in a subset of its
+containers) and hands each object to a Visitor. For example:
+
+	import "github.com/majewsky/schwift/v2/crawler"
+
+	counter := &crawler.Counter{}
+	result, err := crawler.AccountCrawler{
+		Account:     account,
+		Visitor:     counter,
+		Concurrency: 8,
+		Checkpoint:  &replicate.FileCheckpoint{Path: "/var/lib/myapp/crawl.json"},
+		MaxDuration: time.Hour,
+	}.Run(ctx)
+
+This package contains Visitors for common tasks (Counter, Verifier, Exporter
+and Deleter) that can be combined with Chain(), but any type implementing the
+Visitor interface can be used. For checking the integrity of a single
+container, Scrub() offers more control than the Verifier.
+
+Containers are visited one after the other in the order of the account
+listing, and objects within a container are visited in the order of the
+container listing. Within each page of the container listing, up to
+AccountCrawler.Concurrency objects are visited concurrently, so visitors must
+be safe for concurrent use.
+
+A crawl can be bounded in time with AccountCrawler.MaxDuration, and its
+progress can be persisted in a replicate.Checkpoint, so that a long crawl can be split
+into multiple runs. Progress is recorded after each page of the container
+listing, once all objects on that page have been visited.
+*/
+package crawler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/replicate"
+)
+
+// Visitor is the interface for the operations that AccountCrawler performs
+// on each object. VisitObject() is not called for pseudo-directories. If it
+// returns an error, the crawl is aborted and the error is returned by
+// AccountCrawler.Run().
+//
+// Implementations must be safe for concurrent use.
+type Visitor interface {
+	VisitObject(ctx context.Context, info schwift.ObjectInfo) error
+}
+
+// VisitorFunc is a Visitor that calls itself for each object.
+type VisitorFunc func(ctx context.Context, info schwift.ObjectInfo) error
+
+// VisitObject implements the Visitor interface.
+func (f VisitorFunc) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	return f(ctx, info)
+}
+
+// AccountCrawler visits objects in an account. See package documentation for
+// details. All fields except for Account and Visitor are optional.
+type AccountCrawler struct {
+	Account *schwift.Account
+	Visitor Visitor
+	// If ContainerPrefix is set, only containers whose name starts with this
+	// string are visited.
+	ContainerPrefix string
+	// If ObjectPrefix is set, only objects whose name starts with this string
+	// are visited.
+	ObjectPrefix string
+	// Concurrency is the maximum number of objects that are visited at the same
+	// time. Values below 1 are treated as 1.
+	Concurrency int
+	// If ObjectsPerSecond is positive, visits are spaced out so that this rate
+	// is not exceeded.
+	ObjectsPerSecond float64
+	// If MaxDuration is positive, the crawl stops after this duration has
+	// elapsed. No further objects are visited after this point, but objects
+	// that are already being visited are finished (which can take arbitrarily
+	// long, depending on the Visitor). Then Run() returns with
+	// Result.Complete = false.
+	MaxDuration time.Duration
+	// If Checkpoint is set, progress is recorded there as a marker per
+	// container: All objects in that container whose name sorts before or equal
+	// to the marker have been visited. When the crawl is resumed, the listing of
+	// each container starts after its marker. Use replicate.FileCheckpoint to
+	// persist progress into a file. To start a new crawl from the beginning, use
+	// a new checkpoint (e.g. by deleting the file).
+	Checkpoint replicate.Checkpoint
+}
+
+// Result contains statistics about a crawl.
+type Result struct {
+	ContainersVisited uint64
+	ObjectsVisited    uint64
+	// BytesVisited is the sum of ObjectInfo.SizeBytes over all visited objects.
+	BytesVisited uint64
+	// Complete is false if the crawl was stopped because MaxDuration elapsed.
+	Complete bool
+}
+
+// Run performs the crawl. If listing a container fails, or if the Visitor
+// returns an error, the crawl stops and the error is returned together with
+// the statistics collected up to that point.
+func (ac AccountCrawler) Run(ctx context.Context) (Result, error) {
+	if ac.Account == nil || ac.Visitor == nil {
+		return Result{}, errors.New("crawler: Account and Visitor must be set")
+	}
+	cr := crawl{
+		AccountCrawler: ac,
+		limiter:        newRateLimiter(ac.ObjectsPerSecond),
+	}
+	if cr.Concurrency < 1 {
+		cr.Concurrency = 1
+	}
+	if cr.MaxDuration > 0 {
+		cr.deadline = time.Now().Add(cr.MaxDuration)
+	}
+
+	iter := ac.Account.Containers()
+	iter.Prefix = ac.ContainerPrefix
+	err := iter.Foreach(ctx, func(c *schwift.Container) error {
+		if cr.isPastDeadline() {
+			return errDeadlineReached
+		}
+		cr.result.ContainersVisited++
+		return cr.crawlContainer(ctx, c)
+	})
+	switch {
+	case errors.Is(err, errDeadlineReached):
+		return cr.result, nil
+	case err != nil:
+		return cr.result, err
+	default:
+		cr.result.Complete = true
+		return cr.result, nil
+	}
+}
+
+// errDeadlineReached is used internally to abort the container listing.
+var errDeadlineReached = errors.New("MaxDuration elapsed")
+
+// crawl holds the state of AccountCrawler.Run().
+type crawl struct {
+	AccountCrawler
+	limiter  *rateLimiter
+	deadline time.Time
+	result   Result
+}
+
+func (cr *crawl) isPastDeadline() bool {
+	return !cr.deadline.IsZero() && time.Now().After(cr.deadline)
+}
+
+func (cr *crawl) crawlContainer(ctx context.Context, c *schwift.Container) error {
+	var startAfter string
+	if cr.Checkpoint != nil {
+		var err error
+		startAfter, err = cr.Checkpoint.Load(c.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	iter := c.Objects()
+	iter.Prefix = cr.ObjectPrefix
+	if startAfter != "" {
+		// resume on the server side instead of listing all visited objects again
+		iter.Options = (*schwift.RequestOptions)(nil).WithValue("marker", startAfter)
+	}
+	for {
+		if cr.isPastDeadline() {
+			return errDeadlineReached
+		}
+
+		infos, err := iter.NextPageDetailed(ctx, -1)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return nil // EOF
+		}
+
+		var toVisit []schwift.ObjectInfo
+		for _, info := range infos {
+			if info.SubDirectory == "" {
+				toVisit = append(toVisit, info)
+			}
+		}
+		if len(toVisit) == 0 {
+			continue
+		}
+
+		numVisited, err := cr.visitAll(ctx, toVisit)
+		if cr.Checkpoint != nil && numVisited > 0 {
+			saveErr := cr.Checkpoint.Save(c.Name(), toVisit[numVisited-1].Object.Name())
+			if err == nil {
+				err = saveErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// visitAll visits the given objects with bounded concurrency. If MaxDuration
+// elapses in the meantime, no further visits are started, and
+// errDeadlineReached is returned once the running visits are finished.
+// Otherwise, the first error returned by the Visitor is returned, if any.
+//
+// The first return value counts the objects at the start of the list that
+// have all been visited (for recording in the checkpoint). If the Visitor
+// returned an error, this is 0.
+func (cr *crawl) visitAll(ctx context.Context, infos []schwift.ObjectInfo) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg           sync.WaitGroup
+		mutex        sync.Mutex
+		firstErr     error
+		semaphore    = make(chan struct{}, cr.Concurrency)
+		numStarted   int
+		pastDeadline bool
+	)
+	for _, info := range infos {
+		err := cr.limiter.Wait(ctx)
+		if err == nil {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		if err != nil {
+			mutex.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mutex.Unlock()
+			break
+		}
+		if cr.isPastDeadline() {
+			<-semaphore
+			pastDeadline = true
+			break
+		}
+
+		wg.Add(1)
+		numStarted++
+		go func(info schwift.ObjectInfo) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			err := cr.Visitor.VisitObject(ctx, info)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err == nil {
+				cr.result.ObjectsVisited++
+				cr.result.BytesVisited += info.SizeBytes
+			} else if firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}(info)
+	}
+	wg.Wait()
+
+	switch {
+	case firstErr != nil:
+		return 0, firstErr
+	case pastDeadline:
+		return numStarted, errDeadlineReached
+	default:
+		return numStarted, nil
+	}
+}
+
+// rateLimiter spaces out events so that a given rate is not exceeded.
+type rateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     time.Time
+}
+
+// newRateLimiter returns nil (i.e. no limit) if the rate is not positive.
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next event is allowed, or until the context expires.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN is like Wait, but accounts for n events at once.
+func (l *rateLimiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(n) * l.interval)
+	l.mutex.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,279 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package crawler
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/replicate"
+)
+
+// ScrubOptions contains optional settings for Scrub().
+type ScrubOptions struct {
+	// Concurrency is the maximum number of objects that are checked at the same
+	// time. Values below 1 are treated as 1.
+	Concurrency int
+	// If ObjectsPerSecond is positive, checks are spaced out so that this rate
+	// is not exceeded.
+	ObjectsPerSecond float64
+	// If BytesPerSecond is positive, downloads are throttled so that this rate
+	// is not exceeded across all concurrent checks.
+	BytesPerSecond float64
+	// If SampleBytes is positive, objects larger than this are not downloaded
+	// completely. Instead, a range of this many bytes is downloaded from a
+	// random offset. Since the Etag can only be verified for complete
+	// downloads, sampled objects are only checked for readability. If the
+	// server ignores the Range header, the complete object is checked instead.
+	SampleBytes uint64
+	// If MaxDuration is positive, the scrub stops after this duration has
+	// elapsed, and Scrub() returns with Result.Complete = false. Checks that
+	// are already running at this point are finished first (see
+	// AccountCrawler.MaxDuration).
+	MaxDuration time.Duration
+	// If Checkpoint is set, progress is recorded there, and objects that have
+	// been recorded as checked are skipped. This can be combined with
+	// MaxDuration to scrub a large container in multiple runs.
+	Checkpoint replicate.Checkpoint
+}
+
+// ScrubResult is returned by Scrub().
+type ScrubResult struct {
+	// Result contains statistics about the underlying crawl.
+	Result
+	// BytesRead is the number of bytes that were downloaded for checking.
+	BytesRead uint64
+	// ObjectsSampled counts objects that were only checked by downloading a
+	// range (see ScrubOptions.SampleBytes).
+	ObjectsSampled uint64
+	// Findings contains all objects that failed their check, in no particular
+	// order.
+	Findings []ScrubFinding
+}
+
+// ScrubFinding describes an object that failed its check in Scrub().
+type ScrubFinding struct {
+	Object schwift.ObjectInfo
+	// TransactionID is the X-Trans-Id of the failed download (if a response
+	// was received), for correlating the finding with Swift's logs.
+	TransactionID string
+	// If the content could be read completely, but did not match the Etag from
+	// the listing, ExpectedEtag and ActualEtag are set.
+	ExpectedEtag string
+	ActualEtag   string
+	// If the download failed, Err contains the error.
+	Err error
+}
+
+// String returns a human-readable description of this finding.
+func (f ScrubFinding) String() string {
+	target := f.Object.Object.FullName()
+	if f.Err != nil {
+		return fmt.Sprintf("%s: %s (transaction ID %q)", target, f.Err.Error(), f.TransactionID)
+	}
+	return fmt.Sprintf("%s: expected Etag %q, but computed %q (transaction ID %q)", target, f.ExpectedEtag, f.ActualEtag, f.TransactionID)
+}
+
+// Scrub downloads all objects in the given container whose name starts with
+// the given prefix, and checks that their content matches the Etag reported
+// in the container listing. The opts argument may be nil. This complements
+// the auditors of Swift itself by checking the whole stack from the client's
+// perspective, e.g. after a hardware incident:
+//
+//	result, err := crawler.Scrub(ctx, container, "", &crawler.ScrubOptions{
+//		BytesPerSecond: 10 << 20, //do not impact production traffic
+//		SampleBytes:    1 << 20,  //only read a random 1 MiB range of large objects
+//	})
+//	for _, finding := range result.Findings {
+//		log.Println(finding.String())
+//	}
+//
+// Objects that fail their check (including objects that cannot be downloaded
+// because of server errors) are reported in ScrubResult.Findings, and do not
+// cause the scrub to stop. Objects that are deleted during the scrub are
+// skipped. An error is only returned if the container listing fails or if the
+// context expires.
+//
+// For dynamic large objects, only the manifest is checked, since that is what
+// the Etag in the listing refers to. Static large objects and symlinks are
+// skipped, since the Etag in the listing cannot be reproduced by downloading
+// them. The segments of large objects are regular objects that can be
+// scrubbed separately.
+func Scrub(ctx context.Context, c *schwift.Container, prefix string, opts *ScrubOptions) (ScrubResult, error) {
+	if opts == nil {
+		opts = &ScrubOptions{}
+	}
+	s := &scrubber{
+		limiter:     newRateLimiter(opts.BytesPerSecond),
+		sampleBytes: opts.SampleBytes,
+	}
+	cr := crawl{
+		AccountCrawler: AccountCrawler{
+			Account:      c.Account(),
+			Visitor:      s,
+			ObjectPrefix: prefix,
+			Concurrency:  max(opts.Concurrency, 1),
+			Checkpoint:   opts.Checkpoint,
+		},
+		limiter: newRateLimiter(opts.ObjectsPerSecond),
+	}
+	if opts.MaxDuration > 0 {
+		cr.deadline = time.Now().Add(opts.MaxDuration)
+	}
+
+	cr.result.ContainersVisited = 1
+	err := cr.crawlContainer(ctx, c)
+	switch {
+	case errors.Is(err, errDeadlineReached):
+		err = nil
+	case err == nil:
+		cr.result.Complete = true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return ScrubResult{
+		Result:         cr.result,
+		BytesRead:      s.bytesRead,
+		ObjectsSampled: s.objectsSampled,
+		Findings:       s.findings,
+	}, err
+}
+
+// scrubber is the Visitor used by Scrub().
+type scrubber struct {
+	limiter     *rateLimiter
+	sampleBytes uint64
+
+	mutex          sync.Mutex
+	bytesRead      uint64
+	objectsSampled uint64
+	findings       []ScrubFinding
+}
+
+// VisitObject implements the Visitor interface.
+func (s *scrubber) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	if info.IsSymlink() || info.IsStaticLargeObject() {
+		return nil
+	}
+
+	// for dynamic large objects, this downloads the manifest, which is what the
+	// Etag in the listing refers to
+	opts := (*schwift.RequestOptions)(nil).WithValue("multipart-manifest", "get")
+	sampled := s.sampleBytes > 0 && info.SizeBytes > s.sampleBytes
+	var expectedBytes uint64
+	if sampled {
+		offset := rand.N(info.SizeBytes - s.sampleBytes + 1) //nolint:gosec // does not need to be cryptographically secure
+		opts = opts.WithHeader("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+s.sampleBytes-1))
+		expectedBytes = s.sampleBytes
+	}
+
+	req := info.Object.NewRequest(http.MethodGet, opts)
+	req.ExpectStatusCodes = []int{http.StatusOK, http.StatusPartialContent}
+	resp, err := req.Do(ctx, info.Object.Container().Account().Backend())
+	if schwift.Is(err, http.StatusNotFound) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		finding := ScrubFinding{Object: info, Err: err}
+		var usce schwift.UnexpectedStatusCodeError
+		if errors.As(err, &usce) && usce.ActualResponse != nil {
+			finding.TransactionID = usce.ActualResponse.Header.Get("X-Trans-Id")
+		}
+		s.report(&finding, 0, sampled)
+		return nil
+	}
+	defer resp.Body.Close()
+	finding := ScrubFinding{Object: info, TransactionID: resp.Header.Get("X-Trans-Id")}
+	if sampled && resp.StatusCode != http.StatusPartialContent {
+		// the server ignored the Range header and sent the entire object, so the
+		// Etag can be verified after all
+		sampled = false
+	}
+
+	// read the content, throttled to the configured rate
+	hasher := md5.New() //nolint:gosec // Etag uses md5
+	var bytesRead uint64
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			hasher.Write(buf[:n])
+			bytesRead += uint64(n)
+			waitErr := s.limiter.WaitN(ctx, n)
+			if waitErr != nil {
+				return waitErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			finding.Err = err
+			s.report(&finding, bytesRead, sampled)
+			return nil
+		}
+	}
+
+	switch {
+	case sampled && bytesRead != expectedBytes:
+		finding.Err = fmt.Errorf("expected %d bytes, but got %d: %w", expectedBytes, bytesRead, io.ErrUnexpectedEOF)
+	case !sampled:
+		actualEtag := hex.EncodeToString(hasher.Sum(nil))
+		if actualEtag != info.Etag {
+			finding.ExpectedEtag = info.Etag
+			finding.ActualEtag = actualEtag
+		}
+	}
+	if finding.Err == nil && finding.ActualEtag == "" {
+		s.report(nil, bytesRead, sampled)
+	} else {
+		s.report(&finding, bytesRead, sampled)
+	}
+	return nil
+}
+
+// report records the outcome of a check. The finding is nil if the check
+// succeeded.
+func (s *scrubber) report(finding *ScrubFinding, bytesRead uint64, sampled bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bytesRead += bytesRead
+	if sampled {
+		s.objectsSampled++
+	}
+	if finding != nil {
+		s.findings = append(s.findings, *finding)
+	}
+}
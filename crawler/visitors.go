@@ -0,0 +1,203 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package crawler
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// Chain returns a Visitor that calls each of the given visitors in order. If
+// one of them returns an error, the subsequent ones are not called.
+func Chain(visitors ...Visitor) Visitor {
+	return VisitorFunc(func(ctx context.Context, info schwift.ObjectInfo) error {
+		for _, v := range visitors {
+			err := v.VisitObject(ctx, info)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Counter is a Visitor that counts objects and bytes per container. The zero
+// value is ready to use.
+type Counter struct {
+	mutex sync.Mutex
+	stats map[string]ContainerUsage
+}
+
+// ContainerUsage appears in the result of Counter.Usage().
+type ContainerUsage struct {
+	ObjectCount uint64
+	BytesUsed   uint64
+}
+
+// VisitObject implements the Visitor interface.
+func (c *Counter) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stats == nil {
+		c.stats = make(map[string]ContainerUsage)
+	}
+	name := info.Object.Container().Name()
+	usage := c.stats[name]
+	usage.ObjectCount++
+	usage.BytesUsed += info.SizeBytes
+	c.stats[name] = usage
+	return nil
+}
+
+// Usage returns the statistics collected so far, with container names as keys.
+func (c *Counter) Usage() map[string]ContainerUsage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return maps.Clone(c.stats)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Verifier is a Visitor that downloads each object and checks that its
+// content matches the Etag reported in the listing. Objects that fail this
+// check are collected, and can be retrieved with Corrupted(). The zero value
+// is ready to use.
+//
+// Static large objects and symlinks are skipped since their Etag in the
+// listing does not refer to their content. Objects that are deleted during
+// the crawl are skipped as well.
+type Verifier struct {
+	mutex     sync.Mutex
+	corrupted []schwift.ObjectInfo
+}
+
+// VisitObject implements the Visitor interface.
+func (v *Verifier) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	if info.IsSymlink() || info.IsStaticLargeObject() {
+		return nil
+	}
+
+	// for dynamic large objects, this downloads the (empty) manifest, which is
+	// what the Etag in the listing refers to
+	opts := (*schwift.RequestOptions)(nil).WithValue("multipart-manifest", "get")
+	reader, err := info.Object.Download(ctx, opts).AsReadCloser()
+	if schwift.Is(err, http.StatusNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	hasher := md5.New() //nolint:gosec // Etag uses md5
+	_, err = io.Copy(hasher, reader)
+	if err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != info.Etag {
+		v.mutex.Lock()
+		defer v.mutex.Unlock()
+		v.corrupted = append(v.corrupted, info)
+	}
+	return nil
+}
+
+// Corrupted returns all objects whose content did not match their Etag.
+func (v *Verifier) Corrupted() []schwift.ObjectInfo {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return append([]schwift.ObjectInfo(nil), v.corrupted...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Exporter is a Visitor that writes one JSON object per visited object into
+// the given writer, with the keys "container", "name", "bytes", "hash",
+// "content_type" and "last_modified" (like the JSON-lines format of package
+// inventory). When objects are visited concurrently, the order of the output
+// is not deterministic.
+type Exporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewExporter returns an Exporter that writes into the given writer.
+func NewExporter(w io.Writer) *Exporter {
+	return &Exporter{encoder: json.NewEncoder(w)}
+}
+
+// VisitObject implements the Visitor interface.
+func (e *Exporter) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.encoder.Encode(struct {
+		Container    string `json:"container"`
+		Name         string `json:"name"`
+		SizeBytes    uint64 `json:"bytes"`
+		Etag         string `json:"hash"`
+		ContentType  string `json:"content_type"`
+		LastModified string `json:"last_modified"`
+	}{
+		Container:    info.Object.Container().Name(),
+		Name:         info.Object.Name(),
+		SizeBytes:    info.SizeBytes,
+		Etag:         info.Etag,
+		ContentType:  info.ContentType,
+		LastModified: info.LastModified.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Deleter is a Visitor that deletes each visited object. Objects that do not
+// exist anymore are ignored. Use the prefix fields of AccountCrawler to
+// restrict which objects are deleted, or wrap the Deleter in a VisitorFunc
+// that filters objects, e.g. by age:
+//
+//	deleter := crawler.Deleter{}
+//	visitor := crawler.VisitorFunc(func(ctx context.Context, info schwift.ObjectInfo) error {
+//		if time.Since(info.LastModified) < 30*24*time.Hour {
+//			return nil
+//		}
+//		return deleter.VisitObject(ctx, info)
+//	})
+type Deleter struct {
+	// Options is passed to Object.Delete().
+	Options *schwift.DeleteOptions
+}
+
+// VisitObject implements the Visitor interface.
+func (d Deleter) VisitObject(ctx context.Context, info schwift.ObjectInfo) error {
+	err := info.Object.Delete(ctx, d.Options, nil)
+	if schwift.Is(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"net/textproto"
+	"sync"
+)
+
+// FieldType identifies the data type of a CustomField. Each value corresponds
+// to one of the Field types in this package.
+type FieldType int
+
+const (
+	// FieldTypeString is the data type of FieldString.
+	FieldTypeString FieldType = iota
+	// FieldTypeUint64 is the data type of FieldUint64.
+	FieldTypeUint64
+	// FieldTypeInt64 is the data type of FieldInt64.
+	FieldTypeInt64
+	// FieldTypeBool is the data type of FieldBool.
+	FieldTypeBool
+	// FieldTypeDuration is the data type of FieldDuration.
+	FieldTypeDuration
+	// FieldTypeUnixTime is the data type of FieldUnixTime.
+	FieldTypeUnixTime
+	// FieldTypeStringList is the data type of FieldStringList.
+	FieldTypeStringList
+)
+
+// CustomField describes a header that is not known to Schwift, e.g. because it
+// is used by a proprietary middleware. Once registered with
+// RegisterAccountField(), RegisterContainerField() or RegisterObjectField(),
+// the header is checked by the respective Validate() method, and thus also when
+// Schwift reads headers from a GET or HEAD response. For example:
+//
+//	func init() {
+//		err := schwift.RegisterObjectField(schwift.CustomField{
+//			Key:  "X-Object-Meta-Revision",
+//			Type: schwift.FieldTypeInt64,
+//		})
+//		if err != nil {
+//			panic(err.Error())
+//		}
+//	}
+//
+//	//later on...
+//	hdr, err := obj.Headers(ctx) //fails if X-Object-Meta-Revision is malformed
+//	revision := hdr.Int64Field("X-Object-Meta-Revision").Get()
+//
+// Typed access to the header is available through the generic accessors on
+// type Headers (StringField(), Uint64Field() etc.), which can also be used
+// without registering the header first.
+type CustomField struct {
+	Key  string
+	Type FieldType
+	// If Validate is not nil, it is called on non-empty values that conform to
+	// the Type, and may reject them by returning an error. This error will be
+	// wrapped in a MalformedHeaderError.
+	Validate func(value string) error
+}
+
+func (f CustomField) validate(h Headers) error {
+	var err error
+	switch f.Type {
+	case FieldTypeString:
+		err = h.StringField(f.Key).validate()
+	case FieldTypeUint64:
+		err = h.Uint64Field(f.Key).validate()
+	case FieldTypeInt64:
+		err = h.Int64Field(f.Key).validate()
+	case FieldTypeBool:
+		err = h.BoolField(f.Key).validate()
+	case FieldTypeDuration:
+		err = h.DurationField(f.Key).validate()
+	case FieldTypeUnixTime:
+		err = h.UnixTimeField(f.Key).validate()
+	case FieldTypeStringList:
+		err = h.StringListField(f.Key).validate()
+	default:
+		//unreachable because registerCustomField() rejects unknown types
+		err = fmt.Errorf("cannot validate %s: %w", f.Key, ErrUnknownFieldType)
+	}
+	if err != nil {
+		return err
+	}
+
+	value := h.Get(f.Key)
+	if f.Validate == nil || value == "" {
+		return nil
+	}
+	err = f.Validate(value)
+	if err != nil {
+		return MalformedHeaderError{textproto.CanonicalMIMEHeaderKey(f.Key), err}
+	}
+	return nil
+}
+
+var (
+	customFieldsMutex sync.RWMutex
+	customFields      = make(map[string][]CustomField) // key = "Account", "Container" or "Object"
+)
+
+// RegisterAccountField registers a custom header field for AccountHeaders.
+// See documentation on type CustomField for details.
+//
+// This is typically called during init(). Registering a field with the same
+// key again replaces the previous registration. ErrUnknownFieldType is returned
+// if f.Type is not one of the FieldType constants.
+func RegisterAccountField(f CustomField) error {
+	return registerCustomField("Account", f)
+}
+
+// RegisterContainerField registers a custom header field for ContainerHeaders.
+// See documentation on type CustomField for details.
+//
+// This is typically called during init(). Registering a field with the same
+// key again replaces the previous registration. ErrUnknownFieldType is returned
+// if f.Type is not one of the FieldType constants.
+func RegisterContainerField(f CustomField) error {
+	return registerCustomField("Container", f)
+}
+
+// RegisterObjectField registers a custom header field for ObjectHeaders.
+// See documentation on type CustomField for details.
+//
+// This is typically called during init(). Registering a field with the same
+// key again replaces the previous registration. ErrUnknownFieldType is returned
+// if f.Type is not one of the FieldType constants.
+func RegisterObjectField(f CustomField) error {
+	return registerCustomField("Object", f)
+}
+
+func registerCustomField(headersType string, f CustomField) error {
+	if f.Type < FieldTypeString || f.Type > FieldTypeStringList {
+		return fmt.Errorf("cannot register %s: %w", f.Key, ErrUnknownFieldType)
+	}
+	f.Key = textproto.CanonicalMIMEHeaderKey(f.Key)
+
+	customFieldsMutex.Lock()
+	defer customFieldsMutex.Unlock()
+	fields := customFields[headersType]
+	for idx, existing := range fields {
+		if existing.Key == f.Key {
+			fields[idx] = f
+			return nil
+		}
+	}
+	customFields[headersType] = append(fields, f)
+	return nil
+}
+
+func validateCustomFields(h Headers, headersType string) error {
+	customFieldsMutex.RLock()
+	defer customFieldsMutex.RUnlock()
+	for _, f := range customFields[headersType] {
+		err := f.validate(h)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// generic accessors
+
+// StringField provides type-safe access to the given header as a string. This
+// is mostly useful for headers that are not known to Schwift; see
+// documentation on type CustomField.
+func (h Headers) StringField(key string) FieldString {
+	return FieldString{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// Uint64Field provides type-safe access to the given header as an unsigned
+// integer. See documentation on Headers.StringField() for details.
+func (h Headers) Uint64Field(key string) FieldUint64 {
+	return FieldUint64{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// Int64Field provides type-safe access to the given header as a signed
+// integer. See documentation on Headers.StringField() for details.
+func (h Headers) Int64Field(key string) FieldInt64 {
+	return FieldInt64{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// BoolField provides type-safe access to the given header as a boolean. See
+// documentation on Headers.StringField() for details.
+func (h Headers) BoolField(key string) FieldBool {
+	return FieldBool{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// DurationField provides type-safe access to the given header as a duration in
+// seconds. See documentation on Headers.StringField() for details.
+func (h Headers) DurationField(key string) FieldDuration {
+	return FieldDuration{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// UnixTimeField provides type-safe access to the given header as a UNIX
+// timestamp. See documentation on Headers.StringField() for details.
+func (h Headers) UnixTimeField(key string) FieldUnixTime {
+	return FieldUnixTime{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
+
+// StringListField provides type-safe access to the given header as a
+// comma-separated list of strings. See documentation on Headers.StringField()
+// for details.
+func (h Headers) StringListField(key string) FieldStringList {
+	return FieldStringList{h, textproto.CanonicalMIMEHeaderKey(key)}
+}
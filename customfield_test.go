@@ -0,0 +1,100 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// isolateCustomFields makes sure that fields registered by the calling test
+// do not leak into other tests.
+func isolateCustomFields(t *testing.T) {
+	t.Helper()
+	customFieldsMutex.Lock()
+	saved := customFields
+	customFields = make(map[string][]CustomField)
+	customFieldsMutex.Unlock()
+
+	t.Cleanup(func() {
+		customFieldsMutex.Lock()
+		customFields = saved
+		customFieldsMutex.Unlock()
+	})
+}
+
+func TestCustomField(t *testing.T) {
+	isolateCustomFields(t)
+	must(t, RegisterObjectField(CustomField{
+		Key:  "x-object-meta-test-revision",
+		Type: FieldTypeInt64,
+		Validate: func(value string) error {
+			if strings.HasPrefix(value, "-") {
+				return errors.New("must not be negative")
+			}
+			return nil
+		},
+	}))
+
+	hdr := NewObjectHeaders()
+	must(t, hdr.Validate())
+
+	hdr.Int64Field("X-Object-Meta-Test-Revision").Set(42)
+	expectString(t, "42", hdr.Get("X-Object-Meta-Test-Revision"))
+	if actual := hdr.Int64Field("X-Object-Meta-Test-Revision").Get(); actual != 42 {
+		t.Errorf("expected 42, got %d", actual)
+	}
+	must(t, hdr.Validate())
+
+	hdr.Metadata().Set("Test-Revision", "many")
+	expectError(t, hdr.Validate(), `Bad header X-Object-Meta-Test-Revision: strconv.ParseInt: parsing "many": invalid syntax`)
+	hdr.Metadata().Set("Test-Revision", "-1")
+	expectError(t, hdr.Validate(), `Bad header X-Object-Meta-Test-Revision: must not be negative`)
+
+	// the registration only applies to ObjectHeaders
+	chdr := NewContainerHeaders()
+	chdr.Set("X-Object-Meta-Test-Revision", "many")
+	must(t, chdr.Validate())
+}
+
+func TestCustomFieldUnknownType(t *testing.T) {
+	isolateCustomFields(t)
+	err := RegisterContainerField(CustomField{
+		Key:  "X-Container-Meta-Test",
+		Type: FieldTypeStringList + 1,
+	})
+	if !errors.Is(err, ErrUnknownFieldType) {
+		t.Errorf("expected ErrUnknownFieldType, got %v", err)
+	}
+
+	// the field was not registered
+	hdr := NewContainerHeaders()
+	hdr.Set("X-Container-Meta-Test", "foo")
+	must(t, hdr.Validate())
+}
+
+func expectError(t *testing.T, err error, expected string) {
+	t.Helper()
+	if err == nil {
+		t.Errorf("expected error %q, got success", expected)
+		return
+	}
+	expectString(t, expected, err.Error())
+}
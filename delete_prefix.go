@@ -0,0 +1,114 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"path"
+
+	"github.com/majewsky/schwift/v2/internal/pathmatch"
+)
+
+// DeleteByPrefixOptions contains options for Account.DeleteByPrefix().
+type DeleteByPrefixOptions struct {
+	// If true, DeleteByPrefix() only enumerates the matching objects without
+	// deleting them. The returned DeleteByPrefixResult reports them as
+	// ObjectsMatched.
+	DryRun bool
+	// If not nil, Progress is called after each batch of objects has been
+	// processed, with the cumulative result up to that point.
+	Progress func(DeleteByPrefixResult)
+}
+
+// DeleteByPrefixResult is returned by Account.DeleteByPrefix().
+type DeleteByPrefixResult struct {
+	// The number of containers whose names matched the pattern.
+	ContainersMatched int
+	// The number of objects that were found in the matching containers.
+	ObjectsMatched int
+	// The number of objects that were deleted. (Always 0 for dry runs.)
+	ObjectsDeleted int
+	// The number of objects that were listed, but had already been deleted
+	// when the delete request was processed. (Always 0 for dry runs.)
+	ObjectsNotFound int
+}
+
+// DeleteByPrefix deletes all objects whose name starts with the given
+// objectPrefix in all containers whose name matches the given
+// containerPattern. The containers themselves are not deleted. This is
+// useful for cleaning up namespaces that are spread over many containers,
+// for example:
+//
+//	//delete all objects below "tenant-42/" in the containers "logs-2024-01", "logs-2024-02" etc.
+//	result, err := account.DeleteByPrefix(ctx, "logs-*", "tenant-42/", nil)
+//
+// The containerPattern uses the syntax of path.Match(), so it may be a plain
+// container name. If the pattern is malformed, path.ErrBadPattern is returned.
+// An empty objectPrefix matches all objects.
+//
+// Objects are deleted one listing page at a time using BulkDelete(), so a
+// BulkError may be returned. When an error occurs, DeleteByPrefix stops and
+// returns the result up to that point.
+func (a *Account) DeleteByPrefix(ctx context.Context, containerPattern, objectPrefix string, opts *DeleteByPrefixOptions) (DeleteByPrefixResult, error) {
+	if opts == nil {
+		opts = &DeleteByPrefixOptions{}
+	}
+	var result DeleteByPrefixResult
+	_, err := path.Match(containerPattern, "")
+	if err != nil {
+		return result, err
+	}
+
+	// restrict the container listing to the literal prefix of the pattern
+	containerIter := a.Containers()
+	containerIter.Prefix = pathmatch.LiteralPrefix(containerPattern)
+
+	err = containerIter.Foreach(ctx, func(c *Container) error {
+		if ok, _ := path.Match(containerPattern, c.Name()); !ok {
+			return nil
+		}
+		result.ContainersMatched++
+
+		objectIter := c.Objects()
+		objectIter.Prefix = objectPrefix
+		for {
+			objects, err := objectIter.NextPage(ctx, -1)
+			if err != nil {
+				return err
+			}
+			if len(objects) == 0 {
+				return nil
+			}
+			result.ObjectsMatched += len(objects)
+
+			if !opts.DryRun {
+				numDeleted, numNotFound, err := a.BulkDelete(ctx, objects, nil, nil)
+				result.ObjectsDeleted += numDeleted
+				result.ObjectsNotFound += numNotFound
+				if err != nil {
+					return err
+				}
+			}
+			if opts.Progress != nil {
+				opts.Progress(result)
+			}
+		}
+	})
+	return result, err
+}
@@ -0,0 +1,164 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"strings"
+)
+
+// Directory is a handle for a pseudo-directory within a container, i.e. the
+// set of all objects whose names start with a certain prefix ending in a slash.
+// Swift does not have real directories, but many applications (and Swift
+// middlewares like staticweb) follow the convention of using slashes in object
+// names to build a directory-like hierarchy. Instances are obtained with
+// Container.Dir(). For example:
+//
+//	dir := container.Dir("2018-02-10")
+//	dir.Prefix()                      //returns "2018-02-10/"
+//	dir.Object("invoice.pdf").Name()  //returns "2018-02-10/invoice.pdf"
+//	dir.Dir("scans").Prefix()         //returns "2018-02-10/scans/"
+//	dir.Parent().Prefix()             //returns ""
+//
+// Like Container.Object(), none of these methods issue any HTTP requests.
+type Directory struct {
+	c      *Container
+	prefix string
+}
+
+// Dir returns a handle to the pseudo-directory with the given path within this
+// container. A trailing slash is added to the path if necessary. Leading
+// slashes are removed. The empty path refers to the root of the container.
+func (c *Container) Dir(path string) *Directory {
+	return &Directory{c: c, prefix: normalizeDirPrefix(path)}
+}
+
+func normalizeDirPrefix(path string) string {
+	path = strings.TrimLeft(path, "/")
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	return path
+}
+
+// Container returns a handle to the container this directory is located in.
+func (d *Directory) Container() *Container {
+	return d.c
+}
+
+// Prefix returns the common prefix of all object names within this directory.
+// This is the directory path including the trailing slash, or the empty string
+// for the container root.
+func (d *Directory) Prefix() string {
+	return d.prefix
+}
+
+// Base returns the last element of the directory path, without slashes. For the
+// container root, the empty string is returned.
+func (d *Directory) Base() string {
+	trimmed := strings.TrimSuffix(d.prefix, "/")
+	return trimmed[strings.LastIndex(trimmed, "/")+1:]
+}
+
+// Parent returns the directory containing this directory. The parent of the
+// container root is the container root itself.
+func (d *Directory) Parent() *Directory {
+	trimmed := strings.TrimSuffix(d.prefix, "/")
+	return &Directory{c: d.c, prefix: trimmed[:strings.LastIndex(trimmed, "/")+1]}
+}
+
+// Join returns the full object name for the given path relative to this
+// directory. The path is not normalized in any way.
+func (d *Directory) Join(relName string) string {
+	return d.prefix + relName
+}
+
+// Object returns a handle to the object with the given path relative to this
+// directory.
+func (d *Directory) Object(relName string) *Object {
+	return d.c.Object(d.Join(relName))
+}
+
+// Dir returns a handle to the subdirectory with the given path relative to
+// this directory.
+func (d *Directory) Dir(relPath string) *Directory {
+	return d.c.Dir(d.Join(relPath))
+}
+
+// Objects returns an ObjectIterator that lists the direct children of this
+// directory. Objects in subdirectories are condensed into a single
+// ObjectInfo.SubDirectory entry per subdirectory when using the "Detailed"
+// methods of the iterator.
+func (d *Directory) Objects() *ObjectIterator {
+	return &ObjectIterator{
+		Container: d.c,
+		Prefix:    d.prefix,
+		Delimiter: "/",
+	}
+}
+
+// List returns the direct children of this directory. This is a shorthand for
+// d.Objects().CollectDetailed(). Subdirectories are reported with their full
+// path in ObjectInfo.SubDirectory.
+func (d *Directory) List(ctx context.Context) ([]ObjectInfo, error) {
+	return d.Objects().CollectDetailed(ctx)
+}
+
+// Walk calls the callback once for every object in this directory and all its
+// subdirectories, in the lexical order of object names. Iteration is aborted
+// when a GET request fails, or when the callback returns a non-nil error.
+func (d *Directory) Walk(ctx context.Context, callback func(ObjectInfo) error) error {
+	iter := d.c.Objects()
+	iter.Prefix = d.prefix
+	return iter.ForeachDetailed(ctx, callback)
+}
+
+// DirectoryMarkerContentType is the Content-Type of directory marker objects
+// created by Directory.EnsureMarker().
+const DirectoryMarkerContentType = "application/directory"
+
+// MarkerObject returns the object that may serve as a directory marker for this
+// directory. By convention (e.g. in the staticweb middleware), this object has
+// the same name as the directory, minus the trailing slash. For the container
+// root, nil is returned.
+func (d *Directory) MarkerObject() *Object {
+	if d.prefix == "" {
+		return nil
+	}
+	return d.c.Object(strings.TrimSuffix(d.prefix, "/"))
+}
+
+// EnsureMarker creates a zero-byte directory marker object with Content-Type
+// "application/directory" for this directory, unless it exists already. This
+// is not needed for Swift itself, but some clients and middlewares (e.g.
+// staticweb) use these markers to detect directories. For the container root,
+// this method does nothing.
+func (d *Directory) EnsureMarker(ctx context.Context) error {
+	marker := d.MarkerObject()
+	if marker == nil {
+		return nil
+	}
+	exists, err := marker.Exists(ctx)
+	if err != nil || exists {
+		return err
+	}
+	hdr := NewObjectHeaders()
+	hdr.ContentType().Set(DirectoryMarkerContentType)
+	return marker.Upload(ctx, nil, nil, hdr.ToOpts())
+}
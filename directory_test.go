@@ -0,0 +1,65 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"testing"
+)
+
+func TestDirectoryPaths(t *testing.T) {
+	c := (&Account{}).Container("foo")
+
+	testCases := []struct {
+		input  string
+		prefix string
+		base   string
+		parent string
+	}{
+		{"", "", "", ""},
+		{"/", "", "", ""},
+		{"a", "a/", "a", ""},
+		{"a/", "a/", "a", ""},
+		{"/a/b", "a/b/", "b", "a/"},
+		{"a/b/c/", "a/b/c/", "c", "a/b/"},
+	}
+
+	for _, tc := range testCases {
+		d := c.Dir(tc.input)
+		if d.Prefix() != tc.prefix {
+			t.Errorf("expected Dir(%q).Prefix() = %q, got %q", tc.input, tc.prefix, d.Prefix())
+		}
+		if d.Base() != tc.base {
+			t.Errorf("expected Dir(%q).Base() = %q, got %q", tc.input, tc.base, d.Base())
+		}
+		if d.Parent().Prefix() != tc.parent {
+			t.Errorf("expected Dir(%q).Parent().Prefix() = %q, got %q", tc.input, tc.parent, d.Parent().Prefix())
+		}
+	}
+
+	d := c.Dir("a/b")
+	if name := d.Object("c.txt").Name(); name != "a/b/c.txt" {
+		t.Errorf("expected object name %q, got %q", "a/b/c.txt", name)
+	}
+	if prefix := d.Dir("c").Prefix(); prefix != "a/b/c/" {
+		t.Errorf("expected subdirectory prefix %q, got %q", "a/b/c/", prefix)
+	}
+	if name := d.MarkerObject().Name(); name != "a/b" {
+		t.Errorf("expected marker object name %q, got %q", "a/b", name)
+	}
+}
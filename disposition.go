@@ -0,0 +1,138 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FormatContentDisposition produces a value for the Content-Disposition header
+// that tells browsers to save a download under the given filename. The
+// dispositionType is usually "attachment" (to download the file) or "inline"
+// (to display it in the browser). For example:
+//
+//	hdr := schwift.NewObjectHeaders()
+//	hdr.ContentDisposition().Set(schwift.FormatContentDisposition("attachment", "Résumé.pdf"))
+//	//hdr.ContentDisposition().Get() == `attachment; filename="R_sum_.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9.pdf`
+//
+// Non-ASCII filenames are encoded as described in RFC 6266 and RFC 5987: The
+// "filename" parameter contains an ASCII-only fallback for old clients, and the
+// "filename*" parameter contains the full filename.
+func FormatContentDisposition(dispositionType, filename string) string {
+	if filename == "" {
+		return dispositionType
+	}
+
+	var (
+		fallback strings.Builder
+		encoded  strings.Builder
+		isASCII  = true
+	)
+	for _, r := range filename {
+		switch {
+		case r < 0x20 || r >= 0x7F:
+			isASCII = false
+			fallback.WriteByte('_')
+		case r == '"' || r == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteRune(r)
+		default:
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(filename) {
+		if isRFC5987AttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+
+	result := fmt.Sprintf(`%s; filename="%s"`, dispositionType, fallback.String())
+	if !isASCII {
+		result += "; filename*=UTF-8''" + encoded.String()
+	}
+	return result
+}
+
+// isRFC5987AttrChar checks whether the byte is in the "attr-char" production
+// of RFC 5987, section 3.2.1, i.e. whether it can appear unencoded in an
+// extended parameter value.
+func isRFC5987AttrChar(b byte) bool {
+	switch {
+	case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9':
+		return true
+	default:
+		return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+	}
+}
+
+// DownloadURLOptions contains options for Object.DownloadURL().
+type DownloadURLOptions struct {
+	// If TempURLKey is set, a temp URL with this key and expiry time is
+	// generated (see Object.TempURL()). Otherwise, the plain object URL is
+	// returned, which only works if the object is publicly readable.
+	TempURLKey string
+	Expires    time.Time
+	// If Filename is set, browsers will save the download under this filename
+	// instead of the last part of the object name. This requires TempURLKey to be
+	// set. (For plain object URLs, set the object's Content-Disposition header
+	// using FormatContentDisposition() instead.)
+	Filename string
+	// If Inline is set, browsers are asked to display the object instead of
+	// downloading it. This requires TempURLKey to be set.
+	Inline bool
+}
+
+// DownloadURL produces a URL that can be given to a browser to download the
+// object. With a temp URL key, the Content-Disposition of the download can be
+// overridden through the filename and inline parameters supported by Swift's
+// tempurl middleware, which also takes care of encoding non-ASCII filenames.
+// For example:
+//
+//	url, err := obj.DownloadURL(ctx, schwift.DownloadURLOptions{
+//		TempURLKey: key,
+//		Expires:    time.Now().Add(10 * time.Minute),
+//		Filename:   "Quarterly Report.pdf",
+//	})
+func (o *Object) DownloadURL(ctx context.Context, opts DownloadURLOptions) (string, error) {
+	if opts.TempURLKey == "" {
+		if opts.Filename != "" || opts.Inline {
+			return "", errors.New("DownloadURLOptions.Filename and .Inline require a TempURLKey")
+		}
+		return o.URL()
+	}
+
+	urlStr, err := o.TempURL(ctx, opts.TempURLKey, "GET", opts.Expires)
+	if err != nil {
+		return "", err
+	}
+	if opts.Filename != "" {
+		urlStr += "&filename=" + url.QueryEscape(opts.Filename)
+	}
+	if opts.Inline {
+		urlStr += "&inline"
+	}
+	return urlStr, nil
+}
@@ -0,0 +1,60 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFormatContentDisposition(t *testing.T) {
+	expectString(t, "inline", FormatContentDisposition("inline", ""))
+	expectString(t, `attachment; filename="report.pdf"`, FormatContentDisposition("attachment", "report.pdf"))
+	expectString(t, `attachment; filename="say \"hi\".txt"`, FormatContentDisposition("attachment", `say "hi".txt`))
+	expectString(t, `attachment; filename="R_sum_ 2024.pdf"; filename*=UTF-8''R%C3%A9sum%C3%A9%202024.pdf`,
+		FormatContentDisposition("attachment", "Résumé 2024.pdf"))
+}
+
+func TestObjectDownloadURL(t *testing.T) {
+	account, err := InitializeAccount(tempurlBogusBackend{
+		mockInfoText: `{ "tempurl": { "allowed_digests": [ "sha256" ]}}`,
+	})
+	must(t, err)
+	obj := account.Container("foo").Object("bar")
+
+	actualURL, err := obj.DownloadURL(context.TODO(), DownloadURLOptions{})
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/foo/bar", actualURL)
+
+	_, err = obj.DownloadURL(context.TODO(), DownloadURLOptions{Inline: true})
+	if err == nil {
+		t.Error("expected error when requesting disposition override without temp URL key")
+	}
+
+	actualURL, err = obj.DownloadURL(context.TODO(), DownloadURLOptions{
+		TempURLKey: "supersecretkey",
+		Expires:    time.Unix(1e9, 0),
+		Filename:   "Résumé.pdf",
+		Inline:     true,
+	})
+	must(t, err)
+	expectedURL := "https://example.com/v1/AUTH_example/foo/bar?temp_url_sig=5fc94a988b502d83e88863774812636ef0133b8aae04b20366fd906bff41189f&temp_url_expires=1000000000&filename=R%C3%A9sum%C3%A9.pdf&inline"
+	expectString(t, expectedURL, actualURL)
+}
@@ -68,6 +68,30 @@ the client object that it provides and wrap it into something that implements
 the schwift.Backend interface. Then use schwift.InitializeAccount() to obtain a
 schwift.Account.
 
+# Request options
+
+Most methods that make HTTP requests accept a *RequestOptions argument as their
+last argument. It may be nil. Otherwise, it can carry additional request
+headers and URL query parameters, as well as the cross-cutting settings
+RequestID, IdempotencyKey and Timeout. The Timeout applies to each individual
+request; to cancel or limit an entire operation, use the context.Context
+argument instead.
+
+Headers are best prepared through the type-safe API on AccountHeaders,
+ContainerHeaders and ObjectHeaders, then converted with ToOpts(). Options can
+be extended with WithHeader(), WithHeaders() and WithValue(), which return
+modified copies:
+
+	hdr := schwift.NewObjectHeaders()
+	hdr.ContentType().Set("text/plain")
+	opts := hdr.ToOpts().WithValue("symlink", "get")
+	opts.RequestID = "my-request-id"
+
+Methods that update metadata, like Container.Update(), take the headers as a
+separate argument because they are essential to the request rather than
+optional. Those headers are merged into the RequestOptions, taking precedence
+over headers with the same name.
+
 # Caching
 
 When a GET or HEAD request is sent by an Account, Container or Object instance,
@@ -19,7 +19,12 @@
 package schwift
 
 import (
+	"fmt"
 	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
 // DownloadedObject is returned by Object.Download(). It wraps the io.ReadCloser
@@ -52,6 +57,62 @@ import (
 type DownloadedObject struct {
 	r   io.ReadCloser
 	err error
+	// the Content-Length of the response, if known
+	sizeBytes int64
+	sizeKnown bool
+	// metadata of the response
+	partial bool
+	header  http.Header
+}
+
+// IsPartial returns true if the server responded with 206 Partial Content,
+// i.e. if it honored the Range header of the request. If a Range header was
+// sent, but this returns false, the download contains the entire object.
+func (o DownloadedObject) IsPartial() bool {
+	return o.partial
+}
+
+// AcceptsRanges returns true if the server indicated (with the Accept-Ranges
+// header) that it supports Range requests for this object. This can be used
+// to decide whether an interrupted download can be resumed.
+func (o DownloadedObject) AcceptsRanges() bool {
+	return o.header.Get("Accept-Ranges") == "bytes"
+}
+
+// ContentRange returns which range of the object is contained in this
+// download, and the total size of the object. For partial downloads, this is
+// taken from the Content-Range header. Otherwise, the download contains the
+// entire object, whose size is taken from the Content-Length header.
+//
+// If the range or the total size is not known (e.g. because the server did
+// not report it, or because multiple ranges were returned in a
+// multipart/byteranges response), false is returned.
+func (o DownloadedObject) ContentRange() (r ByteRange, totalBytes uint64, ok bool) {
+	if !o.partial {
+		if !o.sizeKnown || o.err != nil {
+			return ByteRange{}, 0, false
+		}
+		return ByteRange{Offset: 0, Length: uint64(o.sizeBytes)}, uint64(o.sizeBytes), true
+	}
+
+	// parse e.g. "bytes 0-499/1234"
+	value, ok := strings.CutPrefix(o.header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return ByteRange{}, 0, false
+	}
+	rangeStr, totalStr, ok := strings.Cut(value, "/")
+	if !ok {
+		return ByteRange{}, 0, false
+	}
+	r, err := ParseByteRange(rangeStr)
+	if err != nil || r.Offset < 0 || r.Length == 0 {
+		return ByteRange{}, 0, false
+	}
+	totalBytes, err = strconv.ParseUint(totalStr, 10, 64)
+	if err != nil {
+		return ByteRange{}, 0, false
+	}
+	return r, totalBytes, true
 }
 
 // AsReadCloser returns an io.ReadCloser containing the contents of the
@@ -78,3 +139,82 @@ func (o DownloadedObject) AsString() (string, error) {
 	slice, err := o.AsByteSlice()
 	return string(slice), err
 }
+
+// AsByteSliceWithLimit is like AsByteSlice, but fails with ErrTooLarge if the
+// downloaded object is larger than maxBytes. If Swift reports the size of the
+// object upfront, the download is aborted without reading any data. Otherwise,
+// at most maxBytes+1 bytes are read before the download is aborted. This is
+// useful for downloading objects of unknown provenance into memory without
+// risking exhaustion of memory. A negative maxBytes is rejected with an error.
+func (o DownloadedObject) AsByteSliceWithLimit(maxBytes int64) ([]byte, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	if maxBytes < 0 {
+		o.r.Close()
+		return nil, fmt.Errorf("invalid size limit for download: %d bytes", maxBytes)
+	}
+	if o.sizeKnown && o.sizeBytes > maxBytes {
+		o.r.Close()
+		return nil, ErrTooLarge
+	}
+	readLimit := maxBytes
+	if readLimit < math.MaxInt64 {
+		readLimit++ //read one more byte to detect objects exceeding the limit
+	}
+	slice, err := io.ReadAll(io.LimitReader(o.r, readLimit))
+	closeErr := o.r.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil && int64(len(slice)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return slice, err
+}
+
+// AsStringWithLimit is like AsString, but fails with ErrTooLarge if the
+// downloaded object is larger than maxBytes. See AsByteSliceWithLimit() for
+// details.
+func (o DownloadedObject) AsStringWithLimit(maxBytes int64) (string, error) {
+	slice, err := o.AsByteSliceWithLimit(maxBytes)
+	return string(slice), err
+}
+
+// downloadBufferSize is the buffer size used by DownloadedObject.WriteTo(). It
+// is larger than the 32 KiB used by io.Copy() to reduce the number of syscalls
+// for large downloads.
+const downloadBufferSize = 1 << 20
+
+// WriteTo copies the contents of this downloaded object into the given writer,
+// and returns the number of bytes written. This implements the io.WriterTo
+// interface, and is a more efficient alternative to:
+//
+//	reader, err := obj.Download(nil).AsReadCloser()
+//	n, err := io.Copy(w, reader)
+//	err := reader.Close()
+//
+// If the writer implements io.ReaderFrom (e.g. *os.File or *net.TCPConn), the
+// copy is delegated to it, which may allow the kernel to avoid copying data
+// through userspace.
+func (o DownloadedObject) WriteTo(w io.Writer) (int64, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+	var (
+		n   int64
+		err error
+	)
+	if rf, ok := w.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(o.r)
+	} else {
+		buf := getDownloadBuffer()
+		n, err = io.CopyBuffer(w, o.r, *buf)
+		putDownloadBuffer(buf)
+	}
+	closeErr := o.r.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return n, err
+}
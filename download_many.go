@@ -0,0 +1,123 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// DownloadManyOptions contains options for Container.DownloadMany().
+type DownloadManyOptions struct {
+	// The maximum number of objects that are downloaded at the same time.
+	// Defaults to 4.
+	Concurrency int
+	// If true, the sink is called for the objects in the order in which they
+	// were given. Downloads still run concurrently, but a download that is
+	// ready before its predecessors have been processed waits for its turn
+	// (while holding on to its connection). If false, the sink is called as
+	// soon as each download is ready, so it may be called concurrently.
+	Ordered bool
+	// If not nil, these options are used for each GET request.
+	RequestOptions *RequestOptions
+}
+
+// DownloadMany downloads the objects with the given names from this
+// container concurrently, and passes the content of each object to the given
+// sink. The reader is only valid until the sink returns. For example:
+//
+//	err := container.DownloadMany(ctx, names, func(name string, r io.Reader) error {
+//		return processImage(name, r)
+//	}, &schwift.DownloadManyOptions{Concurrency: 16})
+//
+// Unless DownloadManyOptions.Ordered is set, the sink must be safe for
+// concurrent use.
+//
+// When some objects cannot be downloaded, or if the sink returns an error for
+// them, the remaining objects are still processed, and a DownloadManyError is
+// returned that contains the error for each failed object. Only when the
+// context expires are the remaining objects skipped (and reported as failed
+// with the context's error).
+func (c *Container) DownloadMany(ctx context.Context, names []string, sink func(name string, r io.Reader) error, opts *DownloadManyOptions) error {
+	if opts == nil {
+		opts = &DownloadManyOptions{}
+	}
+	var (
+		mutex   sync.Mutex
+		errs    = make(map[string]error)
+		started = make([]bool, len(names))
+		indexes = make([]int, len(names))
+		// in ordered mode, turns[idx] is closed when it is the turn of names[idx]
+		turns []chan struct{}
+	)
+	for idx := range indexes {
+		indexes[idx] = idx
+	}
+	if opts.Ordered {
+		turns = make([]chan struct{}, len(names)+1)
+		for idx := range turns {
+			turns[idx] = make(chan struct{})
+		}
+		close(turns[0])
+	}
+	setError := func(name string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs[name] = err
+	}
+
+	// Since items are started in order, the objects whose turn is awaited in
+	// ordered mode have always been started before.
+	err := forEachConcurrently(ctx, opts.Concurrency, indexes, func(ctx context.Context, idx int) error {
+		started[idx] = true
+		name := names[idx]
+		if opts.Ordered {
+			defer close(turns[idx+1])
+		}
+
+		body, err := c.Object(name).Download(ctx, opts.RequestOptions).AsReadCloser()
+		if opts.Ordered {
+			<-turns[idx]
+		}
+		if err != nil {
+			setError(name, err)
+			return nil
+		}
+		defer body.Close()
+		err = sink(name, body)
+		if err != nil {
+			setError(name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		// the context expired before all downloads could be started
+		for idx, name := range names {
+			if !started[idx] {
+				errs[name] = err
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return DownloadManyError{Errors: errs}
+	}
+	return nil
+}
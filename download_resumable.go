@@ -0,0 +1,226 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// downloadResumeToken is persisted next to the partial file by
+// DownloadResumable() whenever a segment has been verified.
+type downloadResumeToken struct {
+	// the Etag of the static large object that was being downloaded
+	Etag string `json:"etag"`
+	// the number of segments that have been verified
+	Segments int `json:"segments"`
+	// the number of bytes contained in those segments
+	Offset uint64 `json:"offset"`
+}
+
+// DownloadResumable is like DownloadVerified, but downloads of static large
+// objects can be resumed after an interruption. While downloading, the data is
+// written into the file "$PATH.partial". Each time a segment has been
+// verified, the file is synced to disk and a resume token (containing the
+// Etag of the large object and the number of verified bytes) is written into
+// the file "$PATH.partial.resume".
+//
+// When DownloadResumable() is called again with the same path after an
+// interrupted download, and the Etag of the large object has not changed in
+// the meantime, the download resumes at the last verified segment boundary.
+// Otherwise, the download starts from the beginning. When the download has
+// completed and the data has been verified, the partial file is renamed to the
+// given path and the resume token is removed.
+//
+// If the verification fails, a DownloadChecksumError is returned and the
+// partial file and resume token are removed. If the download fails for any
+// other reason, they are retained for the next attempt.
+//
+// For all objects other than static large objects, this behaves exactly like
+// DownloadVerified(). The opts argument may be nil. It must not request a
+// range of the object.
+func (o *Object) DownloadResumable(ctx context.Context, path string, opts *RequestOptions) (returnedErr error) {
+	partialPath := path + ".partial"
+	tokenPath := partialPath + ".resume"
+
+	o.Invalidate()
+	hdr, err := o.fetchHeaders(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if !hdr.IsStaticLargeObject() {
+		removeDownloadState(partialPath, tokenPath)
+		return o.DownloadVerified(ctx, path, opts)
+	}
+
+	verifier, err := o.newDownloadVerifier(ctx, *hdr)
+	if err != nil {
+		return err
+	}
+	v, ok := verifier.(*sloDownloadVerifier)
+	if !ok {
+		return fmt.Errorf("could not GET %q in Swift: unexpected verifier type %T", o.FullName(), verifier)
+	}
+	etag := hdr.Etag().Get()
+	totalSize := hdr.SizeBytes().Get()
+
+	// resume from the last verified segment boundary if possible
+	file, err := os.OpenFile(partialPath, os.O_WRONLY|os.O_CREATE, 0o666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		var checksumErr DownloadChecksumError
+		if errors.As(returnedErr, &checksumErr) {
+			removeDownloadState(partialPath, tokenPath)
+		}
+	}()
+	fi, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	token := readDownloadResumeToken(tokenPath)
+	if token.Etag == etag && isValidResumePoint(v.segments, token) && uint64(fi.Size()) >= token.Offset {
+		v.index = token.Segments
+		v.offset = token.Offset
+	}
+	err = file.Truncate(int64(v.offset))
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(int64(v.offset), io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	v.onSegmentVerified = func(index int, offset uint64) error {
+		err := file.Sync()
+		if err != nil {
+			return err
+		}
+		return writeDownloadResumeToken(tokenPath, downloadResumeToken{etag, index, offset})
+	}
+
+	if v.offset < totalSize {
+		ropts := cloneRequestOptions(opts, nil)
+		ropts.Headers.Set("If-Match", etag)
+		expectedStatus := http.StatusOK
+		if v.offset > 0 {
+			ropts.Headers.Set("Range", "bytes="+strconv.FormatUint(v.offset, 10)+"-")
+			expectedStatus = http.StatusPartialContent
+		}
+		resp, err := Request{
+			Method:            http.MethodGet,
+			ContainerName:     o.c.name,
+			ObjectName:        o.name,
+			Options:           ropts,
+			ExpectStatusCodes: []int{expectedStatus},
+		}.Do(ctx, o.c.a.backend)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		startOffset := v.offset
+		buf := getDownloadBuffer()
+		n, err := io.CopyBuffer(io.MultiWriter(file, v), resp.Body, *buf)
+		putDownloadBuffer(buf)
+		if err != nil {
+			return err
+		}
+		if startOffset+uint64(n) != totalSize {
+			return fmt.Errorf("could not GET %q in Swift: expected %d bytes, but got %d: %w",
+				o.FullName(), totalSize-startOffset, n, io.ErrUnexpectedEOF)
+		}
+	}
+	err = v.Finish()
+	if err != nil {
+		return err
+	}
+
+	// move into place
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(partialPath, path)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(tokenPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// isValidResumePoint checks whether the given token refers to a segment
+// boundary of the given manifest.
+func isValidResumePoint(segments []SegmentInfo, token downloadResumeToken) bool {
+	if token.Segments <= 0 || token.Segments > len(segments) {
+		return false
+	}
+	var offset uint64
+	for _, s := range segments[:token.Segments] {
+		offset += segmentLength(s)
+	}
+	return offset == token.Offset
+}
+
+func readDownloadResumeToken(path string) downloadResumeToken {
+	var token downloadResumeToken
+	buf, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(buf, &token)
+	}
+	if err != nil {
+		// a missing or corrupted token just means that we start from zero
+		return downloadResumeToken{}
+	}
+	return token
+}
+
+func writeDownloadResumeToken(path string, token downloadResumeToken) error {
+	buf, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	// write atomically, so that an interruption cannot leave a corrupted token
+	err = os.WriteFile(path+".new", buf, 0o666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path+".new", path)
+}
+
+func removeDownloadState(partialPath, tokenPath string) {
+	os.Remove(partialPath)
+	os.Remove(tokenPath)
+}
@@ -0,0 +1,236 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// plainWriter hides the io.ReaderFrom implementation of its inner writer.
+type plainWriter struct {
+	w io.Writer
+}
+
+func (p plainWriter) Write(buf []byte) (int, error) {
+	return p.w.Write(buf)
+}
+
+func TestDownloadedObjectWriteTo(t *testing.T) {
+	content := strings.Repeat("Hello World\n", 1<<17) // larger than downloadBufferSize
+
+	for _, wrap := range []bool{false, true} {
+		var buf bytes.Buffer
+		var w io.Writer = &buf
+		if wrap {
+			w = plainWriter{w}
+		}
+
+		o := DownloadedObject{r: io.NopCloser(strings.NewReader(content))}
+		n, err := o.WriteTo(w)
+		must(t, err)
+		if n != int64(len(content)) {
+			t.Errorf("expected %d bytes to be written, but got %d", len(content), n)
+		}
+		if buf.String() != content {
+			t.Error("written content does not match downloaded content")
+		}
+	}
+}
+
+func BenchmarkDownloadedObjectWriteTo(b *testing.B) {
+	content := []byte(strings.Repeat("Hello World\n", 1<<17))
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+
+	for range b.N {
+		// wrap the reader to hide its io.WriterTo implementation
+		r := struct{ io.Reader }{bytes.NewReader(content)}
+		o := DownloadedObject{r: io.NopCloser(r)}
+		_, err := o.WriteTo(plainWriter{io.Discard})
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
+
+func BenchmarkComputeEtag(b *testing.B) {
+	content := []byte(strings.Repeat("Hello World\n", 1<<10))
+	b.ReportAllocs()
+	b.SetBytes(int64(len(content)))
+
+	for range b.N {
+		hdr := NewObjectHeaders()
+		err := tryComputeEtag(bytes.NewReader(content), hdr)
+		if err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
+
+// tricklingReader yields one chunk at a time, waiting the given delay before
+// each chunk, and then stalls until the request's context expires.
+type tricklingReader struct {
+	ctx    context.Context //nolint:containedctx // test helper
+	chunks int
+	delay  time.Duration
+}
+
+func (r *tricklingReader) Read(buf []byte) (int, error) {
+	if r.chunks == 0 {
+		<-r.ctx.Done()
+		return 0, r.ctx.Err()
+	}
+	select {
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	case <-time.After(r.delay):
+		r.chunks--
+		return copy(buf, "x"), nil
+	}
+}
+
+func TestDownloadIdleTimeout(t *testing.T) {
+	// the download trickles along for longer than the idle timeout (but with
+	// shorter pauses), and then stalls
+	b := &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		resp := testResponse(http.StatusOK, "")
+		resp.Body = io.NopCloser(&tricklingReader{req.Context(), 10, 10 * time.Millisecond})
+		return resp, nil
+	}}
+	a, err := InitializeAccount(b)
+	must(t, err)
+	opts := &RequestOptions{IdleTimeout: 50 * time.Millisecond}
+	buf, err := a.Container("foo").Object("bar").Download(context.Background(), opts).AsByteSlice()
+	expectString(t, strings.Repeat("x", 10), string(buf))
+
+	var idleErr IdleTimeoutError
+	if !errors.As(err, &idleErr) || !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("expected IdleTimeoutError, got %v", err)
+	}
+	expectString(t, `could not GET "foo/bar" in Swift: no data received for 50ms`, idleErr.Error())
+}
+
+func TestDownloadedObjectWithLimit(t *testing.T) {
+	// size known upfront
+	o := DownloadedObject{r: io.NopCloser(strings.NewReader("hello")), sizeBytes: 5, sizeKnown: true}
+	_, err := o.AsByteSliceWithLimit(4)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+
+	// size not known upfront
+	o = DownloadedObject{r: io.NopCloser(strings.NewReader("hello"))}
+	_, err = o.AsStringWithLimit(4)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+
+	// within limit
+	o = DownloadedObject{r: io.NopCloser(strings.NewReader("hello"))}
+	str, err := o.AsStringWithLimit(5)
+	must(t, err)
+	expectString(t, "hello", str)
+
+	// the largest possible limit does not overflow
+	o = DownloadedObject{r: io.NopCloser(strings.NewReader("hello"))}
+	str, err = o.AsStringWithLimit(math.MaxInt64)
+	must(t, err)
+	expectString(t, "hello", str)
+
+	// negative limits are rejected
+	o = DownloadedObject{r: io.NopCloser(strings.NewReader("hello"))}
+	_, err = o.AsByteSliceWithLimit(-1)
+	if err == nil {
+		t.Error("expected error for negative limit, got success")
+	}
+}
+
+// echoTestHandler responds to each GET request with the object name as
+// content, except for objects named "missing", which yield 404. Responses for
+// objects named "slow" are delayed.
+func echoTestHandler(req *http.Request) (*http.Response, error) {
+	name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	switch name {
+	case "missing":
+		return testResponse(http.StatusNotFound, ""), nil
+	case "slow":
+		time.Sleep(20 * time.Millisecond)
+	}
+	return testResponse(http.StatusOK, name), nil
+}
+
+func TestDownloadMany(t *testing.T) {
+	ctx := context.Background()
+	a, err := InitializeAccount(&testBackend{handle: echoTestHandler})
+	must(t, err)
+	c := a.Container("foo")
+	names := []string{"slow", "one", "missing", "two", "three"}
+
+	// in ordered mode, the sink sees the objects in the given order, even though
+	// the first one takes longest to download
+	var seen []string
+	sink := func(name string, r io.Reader) error {
+		buf, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if name == "three" {
+			return errors.New("sink failed")
+		}
+		seen = append(seen, string(buf))
+		return nil
+	}
+	err = c.DownloadMany(ctx, names, sink, &DownloadManyOptions{Ordered: true})
+	expectString(t, "slow one two", strings.Join(seen, " "))
+
+	// errors are collected per object
+	var dme DownloadManyError
+	if !errors.As(err, &dme) {
+		t.Fatalf("expected DownloadManyError, got %v", err)
+	}
+	expectString(t, `could not download "missing": could not GET "foo/missing" in Swift: expected 200/206 response, got 404 instead (+1 more errors)`, err.Error())
+	expectString(t, "sink failed", dme.Errors["three"].Error())
+	if !Is(err, http.StatusNotFound) {
+		t.Error("expected 404 error to be matched by Is()")
+	}
+
+	// in unordered mode, the sink may be called concurrently
+	var mutex sync.Mutex
+	seen = nil
+	err = c.DownloadMany(ctx, names, func(name string, r io.Reader) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return sink(name, r)
+	}, &DownloadManyOptions{Concurrency: 5})
+	if !errors.As(err, &dme) || len(dme.Errors) != 2 {
+		t.Errorf("expected DownloadManyError with 2 errors, got %v", err)
+	}
+	slices.Sort(seen)
+	expectString(t, "one slow two", strings.Join(seen, " "))
+}
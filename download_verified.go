@@ -0,0 +1,271 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DownloadVerified downloads this object into the file at the given path. The
+// data is first written into a temporary file in the same directory. Only when
+// the download has completed and the data has been verified, the temporary
+// file is synced to disk and renamed to the given path, replacing any existing
+// file there. If anything fails, the temporary file is removed and the file at
+// the given path is not touched.
+//
+// The data is verified as follows:
+//
+//   - For regular objects, the MD5 checksum of the data must match the Etag.
+//   - For static large objects, the manifest is downloaded in addition to the
+//     object. The Etag computed from the manifest (see ComputeSLOEtag()) must
+//     match the Etag of the object, and the data of each segment must match
+//     the segment's Etag from the manifest. For segments that only use a range
+//     of their backing object, only the length of the data can be verified.
+//     Nested static large objects are not supported.
+//   - For dynamic large objects, the data cannot be verified since Swift does
+//     not report reliable checksums for them. Only the Content-Length is
+//     checked.
+//
+// If the verification fails, a DownloadChecksumError is returned. The opts
+// argument may be nil. It must not request a range of the object.
+func (o *Object) DownloadVerified(ctx context.Context, path string, opts *RequestOptions) (returnedErr error) {
+	resp, err := Request{
+		Method:            http.MethodGet,
+		ContainerName:     o.c.name,
+		ObjectName:        o.name,
+		Options:           opts,
+		ExpectStatusCodes: []int{http.StatusOK},
+	}.Do(ctx, o.c.a.backend)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	hdr := newObjectHeadersFromHTTP(resp.Header)
+	err = hdr.Validate()
+	if err != nil {
+		return err
+	}
+	o.headers = &hdr
+	o.rawHeaders = multiValuedHeaders(resp.Header)
+
+	v, err := o.newDownloadVerifier(ctx, hdr)
+	if err != nil {
+		return err
+	}
+
+	// write into temporary file
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".download-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if returnedErr != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	buf := getDownloadBuffer()
+	n, err := io.CopyBuffer(io.MultiWriter(file, v), resp.Body, *buf)
+	putDownloadBuffer(buf)
+	if err != nil {
+		return err
+	}
+	if hdr.SizeBytes().Exists() && uint64(n) != hdr.SizeBytes().Get() {
+		return fmt.Errorf("could not GET %q in Swift: expected %d bytes, but got %d: %w",
+			o.FullName(), hdr.SizeBytes().Get(), n, io.ErrUnexpectedEOF)
+	}
+	err = v.Finish()
+	if err != nil {
+		return err
+	}
+
+	// move into place
+	err = file.Sync()
+	if err != nil {
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// downloadVerifier is an io.Writer that receives the downloaded data.
+type downloadVerifier interface {
+	io.Writer
+	Finish() error
+}
+
+func (o *Object) newDownloadVerifier(ctx context.Context, hdr ObjectHeaders) (downloadVerifier, error) {
+	expectedEtag := strings.Trim(hdr.Etag().Get(), `"`)
+	switch {
+	case hdr.IsDynamicLargeObject():
+		return nopDownloadVerifier{}, nil
+	case hdr.IsStaticLargeObject():
+		var segments []SegmentInfo
+		err := o.foreachSLOSegment(ctx, func(s SegmentInfo) error {
+			segments = append(segments, s)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		manifestEtag, err := ComputeSLOEtag(segments)
+		if err != nil {
+			return nil, err
+		}
+		if manifestEtag != expectedEtag {
+			return nil, DownloadChecksumError{o.FullName(), "manifest", expectedEtag, manifestEtag}
+		}
+		return &sloDownloadVerifier{target: o.FullName(), segments: segments, hash: md5.New()}, nil //nolint:gosec // Etag uses md5
+	default:
+		return &plainDownloadVerifier{o.FullName(), expectedEtag, md5.New()}, nil //nolint:gosec // Etag uses md5
+	}
+}
+
+type nopDownloadVerifier struct{}
+
+func (nopDownloadVerifier) Write(buf []byte) (int, error) {
+	return len(buf), nil
+}
+
+func (nopDownloadVerifier) Finish() error {
+	return nil
+}
+
+type plainDownloadVerifier struct {
+	target       string
+	expectedEtag string
+	hash         hash.Hash
+}
+
+func (v *plainDownloadVerifier) Write(buf []byte) (int, error) {
+	return v.hash.Write(buf)
+}
+
+func (v *plainDownloadVerifier) Finish() error {
+	actualEtag := hex.EncodeToString(v.hash.Sum(nil))
+	if v.expectedEtag != "" && actualEtag != v.expectedEtag {
+		return DownloadChecksumError{v.target, "", v.expectedEtag, actualEtag}
+	}
+	return nil
+}
+
+// sloDownloadVerifier splits the downloaded data along the segment boundaries
+// from the manifest, and checks each segment against its Etag.
+type sloDownloadVerifier struct {
+	target   string
+	segments []SegmentInfo
+	index    int    // index of current segment
+	consumed uint64 // bytes consumed of current segment
+	offset   uint64 // bytes consumed of all previous segments
+	hash     hash.Hash
+	// if not nil, called after each segment has been verified
+	onSegmentVerified func(index int, offset uint64) error
+}
+
+func (v *sloDownloadVerifier) Write(buf []byte) (int, error) {
+	written := len(buf)
+	for len(buf) > 0 {
+		if v.index >= len(v.segments) {
+			return 0, fmt.Errorf("could not GET %q in Swift: received more data than declared in the manifest", v.target)
+		}
+		remaining := segmentLength(v.segments[v.index]) - v.consumed
+		chunk := buf[:min(uint64(len(buf)), remaining)]
+		v.hash.Write(chunk)
+		v.consumed += uint64(len(chunk))
+		buf = buf[len(chunk):]
+		if uint64(len(chunk)) == remaining {
+			err := v.finishSegment()
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (v *sloDownloadVerifier) finishSegment() error {
+	s := v.segments[v.index]
+	expectedEtag := s.Etag
+	if len(s.Data) > 0 {
+		sum := md5.Sum(s.Data) //nolint:gosec // Etag uses md5
+		expectedEtag = hex.EncodeToString(sum[:])
+	}
+	actualEtag := hex.EncodeToString(v.hash.Sum(nil))
+	v.hash.Reset()
+	v.offset += v.consumed
+	v.consumed = 0
+	v.index++
+
+	// for range segments, the Etag refers to the entire backing object
+	isRangeSegment := segmentLength(s) != segmentLengthWithoutRange(s)
+	if !isRangeSegment && expectedEtag != actualEtag {
+		return DownloadChecksumError{v.target, fmt.Sprintf("segment %d", v.index-1), expectedEtag, actualEtag}
+	}
+	if v.onSegmentVerified != nil {
+		return v.onSegmentVerified(v.index, v.offset)
+	}
+	return nil
+}
+
+func (v *sloDownloadVerifier) Finish() error {
+	// skip over empty segments at the end
+	for v.index < len(v.segments) && segmentLength(v.segments[v.index]) == 0 {
+		err := v.finishSegment()
+		if err != nil {
+			return err
+		}
+	}
+	if v.index < len(v.segments) {
+		return fmt.Errorf("could not GET %q in Swift: received less data than declared in the manifest: %w", v.target, io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// segmentLength returns the number of bytes that this segment contributes to
+// the large object.
+func segmentLength(s SegmentInfo) uint64 {
+	if len(s.Data) > 0 {
+		return uint64(len(s.Data))
+	}
+	start, end, ok := s.ByteRange().Resolve(s.SizeBytes)
+	if !ok {
+		return 0
+	}
+	return end - start
+}
+
+func segmentLengthWithoutRange(s SegmentInfo) uint64 {
+	if len(s.Data) > 0 {
+		return uint64(len(s.Data))
+	}
+	return s.SizeBytes
+}
@@ -22,8 +22,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/majewsky/schwift/v2/internal/errext"
 )
@@ -56,6 +58,65 @@ var (
 	// provided is malformed or uses features not supported by the LargeObject's
 	// strategy. See documentation for LargeObject.AddSegment() for details.
 	ErrSegmentInvalid = errors.New("segment invalid or incompatible with large object strategy")
+	// ErrAlreadyExists is returned by Object.Create() if the object exists
+	// already.
+	ErrAlreadyExists = errors.New("object exists already")
+	// ErrReadOnly is returned by all operations that would modify data when
+	// invoked on an Account obtained from Account.ReadOnly(), or on containers
+	// and objects below it. No request is sent to the server in this case.
+	ErrReadOnly = errors.New("operation not permitted on a read-only account")
+	// ErrConcurrentModification is returned by Object.Append() if the object was
+	// modified by someone else during the append, and the modification could not
+	// be merged. See documentation on type AppendConflictResolution for details.
+	ErrConcurrentModification = errors.New("object was modified concurrently")
+	// ErrAuditLogTampered is returned by VerifyAuditLog() if the hash chain in
+	// the audit log is broken, i.e. if records were modified, removed or
+	// reordered after they were written.
+	ErrAuditLogTampered = errors.New("audit log hash chain is broken")
+	// ErrQuotaExceeded is matched by QuotaExceededError when checked with
+	// errors.Is().
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrNotManaged is matched by ContainerOwnershipError when checked with
+	// errors.Is().
+	ErrNotManaged = errors.New("container is not managed by this application")
+	// ErrCircuitOpen is returned by all operations on an Account obtained from
+	// Account.WithCircuitBreaker(), or on containers and objects below it, while
+	// the circuit breaker is open. No request is sent to the server in this case.
+	ErrCircuitOpen = errors.New("circuit breaker is open after repeated failures")
+	// ErrIdleTimeout is matched by IdleTimeoutError when checked with
+	// errors.Is().
+	ErrIdleTimeout = errors.New("idle timeout expired")
+	// ErrTooLarge is returned by DownloadedObject.AsByteSliceWithLimit() and
+	// AsStringWithLimit() if the downloaded object exceeds the given limit.
+	ErrTooLarge = errors.New("object is too large to be downloaded into memory")
+	// ErrQueueFull is returned by the Enqueue methods of WriteQueue when the
+	// queue has reached its configured bounds.
+	ErrQueueFull = errors.New("write queue is full")
+	// ErrQueueClosed is returned by the Enqueue methods of WriteQueue after
+	// Drain() or Close() has been called. It is also reported to
+	// WriteQueueOptions.OnComplete for writes that were discarded by Close().
+	ErrQueueClosed = errors.New("write queue is closed")
+	// ErrObjectOnHold is matched by ObjectOnHoldError when checked with
+	// errors.Is().
+	ErrObjectOnHold = errors.New("object is on hold")
+	// ErrNotVersioned is returned by Container.RestoreToTime() and
+	// Object.Undelete() when the respective kind of object versioning is not
+	// enabled on the container.
+	ErrNotVersioned = errors.New("object versioning is not enabled on this container")
+	// ErrNoPreviousVersion is returned by Object.Undelete() when no archived
+	// version of the object could be found.
+	ErrNoPreviousVersion = errors.New("no previous version of this object was found")
+	// ErrUnhealthy is returned by Account.Healthcheck() when the Swift cluster
+	// does not report itself as healthy.
+	ErrUnhealthy = errors.New("cluster is not healthy")
+	// ErrUnknownFieldType is returned by RegisterAccountField() and its siblings
+	// when CustomField.Type is not one of the FieldType constants.
+	ErrUnknownFieldType = errors.New("unknown field type")
+	// ErrSegmentsNotLoaded is returned by LargeObject.Segments() and
+	// LargeObject.AddSegment() when the large object was opened with
+	// AsLargeObjectOptions.Lazy and LargeObject.LoadSegments() has not been
+	// called yet.
+	ErrSegmentsNotLoaded = errors.New("segments of large object have not been loaded yet")
 )
 
 // UnexpectedStatusCodeError is generated when a request to Swift does not yield
@@ -96,20 +157,92 @@ type BulkObjectError struct {
 	ContainerName string
 	ObjectName    string
 	StatusCode    int
+	// Message is only set when Swift reported a reason other than a HTTP status
+	// for this object (e.g. "Etag Mismatch" for a segment in an SLO manifest).
+	Message string
 }
 
 // Error implements the builtin/error interface.
 func (e BulkObjectError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s/%s: %s", e.ContainerName, e.ObjectName, e.Message)
+	}
 	return fmt.Sprintf("%s/%s: %d %s",
 		e.ContainerName, e.ObjectName,
 		e.StatusCode, http.StatusText(e.StatusCode),
 	)
 }
 
+// Object returns the object that this error refers to, as located in the given
+// account. If this error refers to a container, nil is returned.
+func (e BulkObjectError) Object(a *Account) *Object {
+	if e.ObjectName == "" {
+		return nil
+	}
+	return a.Container(e.ContainerName).Object(e.ObjectName)
+}
+
+// IsRetryable returns whether this error is likely to be temporary, such that
+// retrying the operation on this object may succeed. This is the case for
+// request timeouts, rate limiting (including Swift's nonstandard status 498),
+// and server-side errors like 503 Service Unavailable.
+func (e BulkObjectError) IsRetryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, 498,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// DownloadManyError is returned by Container.DownloadMany() when some objects
+// could not be downloaded, or when the sink returned an error for them.
+type DownloadManyError struct {
+	// Errors contains the error for each failed object, keyed by object name.
+	Errors map[string]error
+}
+
+// Error implements the builtin/error interface. To fit into one line, it only
+// shows the error for the first failed object (in alphabetical order).
+func (e DownloadManyError) Error() string {
+	names := e.names()
+	if len(names) == 0 {
+		return "no errors"
+	}
+	msg := fmt.Sprintf("could not download %q: %s", names[0], e.Errors[names[0]].Error())
+	if len(names) > 1 {
+		msg += fmt.Sprintf(" (+%d more errors)", len(names)-1)
+	}
+	return msg
+}
+
+// Unwrap returns the errors for all failed objects, so that errors.Is() and
+// errors.As() can be used to check for specific failures.
+func (e DownloadManyError) Unwrap() []error {
+	names := e.names()
+	result := make([]error, len(names))
+	for idx, name := range names {
+		result[idx] = e.Errors[name]
+	}
+	return result
+}
+
+func (e DownloadManyError) names() []string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
 // BulkError is returned by Account.BulkUpload() when the archive was
 // uploaded and unpacked successfully, but some (or all) objects could not be
-// saved in Swift; and by Account.BulkDelete() when not all requested objects
-// could be deleted.
+// saved in Swift; by Account.BulkDelete() when not all requested objects
+// could be deleted; and by LargeObject.WriteManifest() when an SLO manifest
+// written with "heartbeat=on" was rejected.
 type BulkError struct {
 	// StatusCode contains the overall HTTP status code of the operation.
 	StatusCode int
@@ -134,6 +267,64 @@ func (e BulkError) Error() string {
 	return result
 }
 
+// PartitionByStatusCode groups the ObjectErrors by their StatusCode. Errors
+// that do not have a status code (only a Message) are grouped under 0.
+func (e BulkError) PartitionByStatusCode() map[int][]BulkObjectError {
+	result := make(map[int][]BulkObjectError)
+	for _, oe := range e.ObjectErrors {
+		result[oe.StatusCode] = append(result[oe.StatusCode], oe)
+	}
+	return result
+}
+
+// Retryable returns a copy of this BulkError that only contains those
+// ObjectErrors for which IsRetryable() is true. This can be combined with
+// Objects() and Containers() to retry a bulk operation:
+//
+//	_, _, err := account.BulkDelete(ctx, objects, nil, nil)
+//	var bulkErr schwift.BulkError
+//	if errors.As(err, &bulkErr) {
+//	    retry := bulkErr.Retryable()
+//	    _, _, err = account.BulkDelete(ctx, retry.Objects(account), retry.Containers(account), nil)
+//	}
+func (e BulkError) Retryable() BulkError {
+	result := BulkError{
+		StatusCode:   e.StatusCode,
+		OverallError: e.OverallError,
+	}
+	for _, oe := range e.ObjectErrors {
+		if oe.IsRetryable() {
+			result.ObjectErrors = append(result.ObjectErrors, oe)
+		}
+	}
+	return result
+}
+
+// Objects returns the objects referenced by the ObjectErrors, as located in
+// the given account. ObjectErrors referring to containers are skipped.
+func (e BulkError) Objects(a *Account) []*Object {
+	var result []*Object
+	for _, oe := range e.ObjectErrors {
+		if o := oe.Object(a); o != nil {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// Containers returns the containers referenced by those ObjectErrors that
+// refer to a container (instead of an object), as located in the given
+// account.
+func (e BulkError) Containers(a *Account) []*Container {
+	var result []*Container
+	for _, oe := range e.ObjectErrors {
+		if oe.ObjectName == "" {
+			result = append(result, a.Container(oe.ContainerName))
+		}
+	}
+	return result
+}
+
 // Is checks if the given error is an UnexpectedStatusCodeError for that status
 // code. For example:
 //
@@ -167,3 +358,168 @@ type MalformedHeaderError struct {
 func (e MalformedHeaderError) Error() string {
 	return "Bad header " + e.Key + ": " + e.ParseError.Error()
 }
+
+// SLOEtagMismatchError is returned by LargeObject.WriteManifest() when the
+// Etag reported by Swift for a static large object does not match the Etag
+// computed from the segments in the manifest with ComputeSLOEtag(). This
+// error matches ErrChecksumMismatch when checked with errors.Is().
+type SLOEtagMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+// Error implements the builtin/error interface.
+func (e SLOEtagMismatchError) Error() string {
+	return fmt.Sprintf("expected Etag %q for static large object, but Swift reported %q", e.Expected, e.Actual)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e SLOEtagMismatchError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// QuotaExceededError is returned by Object.Upload() if UploadOptions.QuotaCheck
+// is enabled and the upload would exceed the quota of the account or
+// container. No data is uploaded in this case. This error matches
+// ErrQuotaExceeded when checked with errors.Is().
+type QuotaExceededError struct {
+	Target string // either "<account>" or "$CONTAINER_NAME"
+	// MissingBytes is the amount of space that would need to be freed to make
+	// the upload fit within the byte quota.
+	MissingBytes uint64
+	// MissingObjects is the number of objects that would need to be deleted to
+	// make the upload fit within the object count quota.
+	MissingObjects uint64
+}
+
+// Error implements the builtin/error interface.
+func (e QuotaExceededError) Error() string {
+	if e.MissingObjects > 0 {
+		return fmt.Sprintf("upload would exceed object count quota of %q by %d objects", e.Target, e.MissingObjects)
+	}
+	return fmt.Sprintf("upload would exceed byte quota of %q by %d bytes", e.Target, e.MissingBytes)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// SegmentLocationError is returned by Object.AsLargeObjectWithOptions() in
+// strict mode when a segment of the large object is located outside of its
+// SegmentContainer and SegmentPrefix. This error matches ErrSegmentInvalid
+// when checked with errors.Is().
+type SegmentLocationError struct {
+	SegmentName      string // e.g. "$CONTAINER_NAME/$OBJECT_NAME"
+	SegmentContainer string
+	SegmentPrefix    string
+}
+
+// Error implements the builtin/error interface.
+func (e SegmentLocationError) Error() string {
+	return fmt.Sprintf("segment %q is located outside of the segment location %q",
+		e.SegmentName, e.SegmentContainer+"/"+e.SegmentPrefix)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e SegmentLocationError) Unwrap() error {
+	return ErrSegmentInvalid
+}
+
+// ContainerOwnershipError is returned by Container.CheckManagedBy() and
+// Container.EnsureManagedBy() if the container is managed by a different
+// application. This error matches ErrNotManaged when checked with
+// errors.Is().
+type ContainerOwnershipError struct {
+	ContainerName string
+	// ExpectedManagedBy is the application that was supposed to manage the
+	// container.
+	ExpectedManagedBy string
+	// ActualManagedBy is empty if the container is not managed by any
+	// application.
+	ActualManagedBy string
+}
+
+// Error implements the builtin/error interface.
+func (e ContainerOwnershipError) Error() string {
+	if e.ActualManagedBy == "" {
+		return fmt.Sprintf("container %q is not managed by %q", e.ContainerName, e.ExpectedManagedBy)
+	}
+	return fmt.Sprintf("container %q is managed by %q, not by %q", e.ContainerName, e.ActualManagedBy, e.ExpectedManagedBy)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e ContainerOwnershipError) Unwrap() error {
+	return ErrNotManaged
+}
+
+// IdleTimeoutError is returned when a request with RequestOptions.IdleTimeout
+// is aborted because no data was received for that long. When reading a
+// response body (e.g. from Object.Download()), this error is returned by the
+// Read() call that was interrupted. This error matches ErrIdleTimeout when
+// checked with errors.Is().
+type IdleTimeoutError struct {
+	Method      string // e.g. http.MethodGet
+	Target      string // either "<account>" or "$CONTAINER_NAME" or "$CONTAINER_NAME/$OBJECT_NAME"
+	IdleTimeout time.Duration
+}
+
+// Error implements the builtin/error interface.
+func (e IdleTimeoutError) Error() string {
+	return fmt.Sprintf("could not %s %q in Swift: no data received for %s", e.Method, e.Target, e.IdleTimeout)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e IdleTimeoutError) Unwrap() error {
+	return ErrIdleTimeout
+}
+
+// DownloadChecksumError is returned by Object.DownloadVerified() when the
+// downloaded data does not match the Etag reported by Swift. This error matches
+// ErrChecksumMismatch when checked with errors.Is().
+type DownloadChecksumError struct {
+	Target string // "$CONTAINER_NAME/$OBJECT_NAME"
+	// For static large objects, Segment identifies the segment whose data did
+	// not match the Etag from the manifest, e.g. "segment 3". If the segments
+	// in the manifest do not match the Etag of the large object itself, Segment
+	// is "manifest". For all other objects, Segment is empty.
+	Segment  string
+	Expected string
+	Actual   string
+}
+
+// Error implements the builtin/error interface.
+func (e DownloadChecksumError) Error() string {
+	target := e.Target
+	if e.Segment != "" {
+		target += " (" + e.Segment + ")"
+	}
+	return fmt.Sprintf("downloaded data of %s does not match: expected Etag %q, but computed %q", target, e.Expected, e.Actual)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e DownloadChecksumError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// ObjectOnHoldError is returned by all operations on an Account obtained from
+// Account.WithHoldEnforcement(), or on containers and objects below it, that
+// would modify or delete an object that is on hold. No request is sent to the
+// server in this case. This error matches ErrObjectOnHold when checked with
+// errors.Is().
+type ObjectOnHoldError struct {
+	Method string // e.g. http.MethodDelete
+	Target string // "$CONTAINER_NAME/$OBJECT_NAME"
+	// Reason is the value of the X-Object-Meta-Legal-Hold header.
+	Reason string
+}
+
+// Error implements the builtin/error interface.
+func (e ObjectOnHoldError) Error() string {
+	return fmt.Sprintf("could not %s %q in Swift: object is on hold (reason: %q)", e.Method, e.Target, e.Reason)
+}
+
+// Unwrap implements the interface implied by errors.Is().
+func (e ObjectOnHoldError) Unwrap() error {
+	return ErrObjectOnHold
+}
@@ -0,0 +1,70 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestBulkErrorHelpers(t *testing.T) {
+	a, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusOK, "hello")})
+	must(t, err)
+
+	bulkErr := BulkError{
+		StatusCode:   400,
+		OverallError: "some failed",
+		ObjectErrors: []BulkObjectError{
+			{ContainerName: "foo", ObjectName: "a", StatusCode: 503},
+			{ContainerName: "foo", ObjectName: "b", StatusCode: 404},
+			{ContainerName: "bar", StatusCode: 409},
+			{ContainerName: "bar", ObjectName: "c/d", StatusCode: 498},
+			{ContainerName: "baz", StatusCode: 500},
+			{ContainerName: "foo", ObjectName: "e", Message: "Etag Mismatch"},
+		},
+	}
+
+	partitions := bulkErr.PartitionByStatusCode()
+	expectString(t, "6", fmt.Sprint(len(partitions)))
+	expectString(t, "[foo/b: 404 Not Found]", fmt.Sprint(partitions[404]))
+	expectString(t, "[foo/e: Etag Mismatch]", fmt.Sprint(partitions[0]))
+
+	fullNames := func(objects []*Object) string {
+		names := make([]string, len(objects))
+		for idx, o := range objects {
+			names[idx] = o.FullName()
+		}
+		return fmt.Sprint(names)
+	}
+	containerNames := func(containers []*Container) string {
+		names := make([]string, len(containers))
+		for idx, c := range containers {
+			names[idx] = c.Name()
+		}
+		return fmt.Sprint(names)
+	}
+	expectString(t, "[foo/a foo/b bar/c/d foo/e]", fullNames(bulkErr.Objects(a)))
+	expectString(t, "[bar baz]", containerNames(bulkErr.Containers(a)))
+
+	retryable := bulkErr.Retryable()
+	expectString(t, "400 Bad Request: some failed (+3 object errors)", retryable.Error())
+	expectString(t, "[foo/a bar/c/d]", fullNames(retryable.Objects(a)))
+	expectString(t, "[baz]", containerNames(retryable.Containers(a)))
+}
@@ -0,0 +1,84 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"strings"
+)
+
+// EtagCheckPolicy is an enum that appears in type UploadOptions. It controls
+// how Object.Upload() verifies the Etag reported by Swift when the Etag could
+// not be computed in advance (see documentation on Object.Upload()).
+//
+// On clusters with at-rest encryption (as reported by Capabilities.Encryption),
+// the object servers store the Etag of the ciphertext. The proxy normally
+// translates it back into the Etag of the plaintext, but intermediaries that
+// bypass the decrypter (or that do not implement Swift's Etag semantics at
+// all) can report an opaque value instead, so Etag comparisons are not
+// reliable there. The encryption-related sysmeta headers
+// (X-Object-Sysmeta-Crypto-*) are never visible to clients, so the
+// capabilities are the only way to detect this situation.
+type EtagCheckPolicy int
+
+const (
+	// EtagCheckAuto verifies the Etag, except when the cluster reports
+	// encryption and the Etag reported by Swift is not an MD5 digest at all. In
+	// that case, the upload succeeds, but UploadResult.EtagUnverified is set.
+	// An Etag that looks like an MD5 digest but does not match still yields
+	// ErrChecksumMismatch. This is the default.
+	EtagCheckAuto EtagCheckPolicy = iota
+	// EtagCheckStrict always returns ErrChecksumMismatch if the Etag reported by
+	// Swift does not match.
+	EtagCheckStrict
+	// EtagCheckOff skips the verification entirely, and thus also the
+	// computation of the MD5 digest during the upload. UploadResult.MD5 is
+	// then taken from the Etag reported by Swift, and EtagUnverified is set.
+	EtagCheckOff
+)
+
+// checkUploadEtag implements UploadOptions.EtagCheck. It returns whether the
+// Etag could be verified.
+func (o *Object) checkUploadEtag(ctx context.Context, expected, actual string, policy EtagCheckPolicy) (bool, error) {
+	if expected == actual {
+		return true, nil
+	}
+	if policy == EtagCheckAuto && !isMD5Digest(actual) {
+		caps, err := o.c.a.Capabilities(ctx)
+		if err == nil && caps.Encryption != nil {
+			return false, nil
+		}
+	}
+	return false, ErrChecksumMismatch
+}
+
+// isMD5Digest returns whether the given Etag looks like a hex-encoded MD5
+// digest (with or without surrounding quotes).
+func isMD5Digest(etag string) bool {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 {
+		return false
+	}
+	for _, c := range etag {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIsMD5Digest(t *testing.T) {
+	testCases := map[string]bool{
+		"d41d8cd98f00b204e9800998ecf8427e":   true,
+		`"D41D8CD98F00B204E9800998ECF8427E"`: true,
+		"d41d8cd98f00b204e9800998ecf8427":    false,
+		"d41d8cd98f00b204e9800998ecf8427g":   false,
+		"d41d8cd98f00b204e9800998ecf8427e-3": false,
+		"":                                   false,
+	}
+	for etag, expected := range testCases {
+		if isMD5Digest(etag) != expected {
+			t.Errorf("expected isMD5Digest(%q) = %t", etag, expected)
+		}
+	}
+}
+
+func TestCheckUploadEtag(t *testing.T) {
+	const (
+		md5A   = "0cc175b9c0f1b6a831c399e269772661"
+		md5B   = "92eb5ffee6ae2fec3ad71c777531578f"
+		opaque = "HyWQqpLF3TRQfaqdLWvXEh/bK+bv4HNQT07u0OA0UqM="
+	)
+	plain, err := InitializeAccount(tempurlBogusBackend{mockInfoText: `{}`})
+	must(t, err)
+	encrypted, err := InitializeAccount(tempurlBogusBackend{mockInfoText: `{"encryption":{}}`})
+	must(t, err)
+
+	testCases := []struct {
+		Account  *Account
+		Actual   string
+		Policy   EtagCheckPolicy
+		Verified bool
+		Mismatch bool
+	}{
+		{plain, md5A, EtagCheckAuto, true, false},
+		{plain, md5B, EtagCheckAuto, false, true},
+		{plain, opaque, EtagCheckAuto, false, true},
+		{encrypted, md5A, EtagCheckAuto, true, false},
+		{encrypted, md5B, EtagCheckAuto, false, true},
+		{encrypted, opaque, EtagCheckAuto, false, false},
+		{encrypted, opaque, EtagCheckStrict, false, true},
+	}
+
+	for idx, tc := range testCases {
+		obj := tc.Account.Container("foo").Object("bar")
+		verified, err := obj.checkUploadEtag(context.TODO(), md5A, tc.Actual, tc.Policy)
+		if verified != tc.Verified {
+			t.Errorf("test case %d: expected verified = %t", idx, tc.Verified)
+		}
+		if errors.Is(err, ErrChecksumMismatch) != tc.Mismatch {
+			t.Errorf("test case %d: expected mismatch = %t, got err = %v", idx, tc.Mismatch, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"strconv"
+)
+
+// FieldBool is a helper type that provides type-safe access to a Swift header
+// whose value is a boolean. It cannot be directly constructed, but methods on
+// the Headers types return this type. For example:
+//
+//	hdr := NewContainerHeaders()
+//	//the following two statements are equivalent:
+//	hdr["X-Versions-Enabled"] = "true"
+//	hdr.VersionsEnabled().Set(true)
+//
+// When reading, all values accepted by strconv.ParseBool() are understood,
+// including the "True" and "False" that Swift reports.
+type FieldBool struct {
+	h Headers
+	k string
+}
+
+// Exists checks whether there is a value for this header.
+func (f FieldBool) Exists() bool {
+	return f.h.Get(f.k) != ""
+}
+
+// Get returns the value for this header, or false if there is no value (or if
+// it is not a valid boolean).
+func (f FieldBool) Get() bool {
+	v, err := strconv.ParseBool(f.h.Get(f.k))
+	if err != nil {
+		return false
+	}
+	return v
+}
+
+// Set writes a new value for this header into the corresponding headers
+// instance.
+func (f FieldBool) Set(value bool) {
+	f.h.Set(f.k, strconv.FormatBool(value))
+}
+
+// Del removes this key from the original headers instance, so that the key will
+// remain unchanged on the server during Update().
+func (f FieldBool) Del() {
+	f.h.Del(f.k)
+}
+
+// Clear sets this key to an empty string in the original headers instance, so
+// that the key will be removed on the server during Update().
+func (f FieldBool) Clear() {
+	f.h.Clear(f.k)
+}
+
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldBool) Remove() {
+	f.h.Remove(f.k)
+}
+
+func (f FieldBool) validate() error {
+	val := f.h.Get(f.k)
+	if val == "" {
+		return nil
+	}
+	_, err := strconv.ParseBool(val)
+	if err == nil {
+		return nil
+	}
+	return MalformedHeaderError{f.k, err}
+}
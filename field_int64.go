@@ -0,0 +1,83 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"strconv"
+)
+
+// FieldInt64 is a helper type that provides type-safe access to a Swift header
+// whose value is a signed integer. It cannot be directly constructed, but
+// methods on the Headers types return this type. It works exactly like
+// FieldUint64, except that negative values are permitted.
+type FieldInt64 struct {
+	h Headers
+	k string
+}
+
+// Exists checks whether there is a value for this header.
+func (f FieldInt64) Exists() bool {
+	return f.h.Get(f.k) != ""
+}
+
+// Get returns the value for this header, or 0 if there is no value (or if it is
+// not a valid int64).
+func (f FieldInt64) Get() int64 {
+	v, err := strconv.ParseInt(f.h.Get(f.k), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// Set writes a new value for this header into the corresponding headers
+// instance.
+func (f FieldInt64) Set(value int64) {
+	f.h.Set(f.k, strconv.FormatInt(value, 10))
+}
+
+// Del removes this key from the original headers instance, so that the key will
+// remain unchanged on the server during Update().
+func (f FieldInt64) Del() {
+	f.h.Del(f.k)
+}
+
+// Clear sets this key to an empty string in the original headers instance, so
+// that the key will be removed on the server during Update().
+func (f FieldInt64) Clear() {
+	f.h.Clear(f.k)
+}
+
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldInt64) Remove() {
+	f.h.Remove(f.k)
+}
+
+func (f FieldInt64) validate() error {
+	val := f.h.Get(f.k)
+	if val == "" {
+		return nil
+	}
+	_, err := strconv.ParseInt(val, 10, 64)
+	if err == nil {
+		return nil
+	}
+	return MalformedHeaderError{f.k, err}
+}
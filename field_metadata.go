@@ -41,6 +41,11 @@ func (m FieldMetadata) Del(key string) {
 	m.h.Del(m.k + key)
 }
 
+// Remove works like Headers.Remove(), but prepends the metadata prefix to the key.
+func (m FieldMetadata) Remove(key string) {
+	m.h.Remove(m.k + key)
+}
+
 // Get works like Headers.Get(), but prepends the metadata prefix to the key.
 func (m FieldMetadata) Get(key string) string {
 	return m.h.Get(m.k + key)
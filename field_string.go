@@ -59,6 +59,12 @@ func (f FieldString) Clear() {
 	f.h.Clear(f.k)
 }
 
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldString) Remove() {
+	f.h.Remove(f.k)
+}
+
 func (f FieldString) validate() error {
 	return nil
 }
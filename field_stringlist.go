@@ -0,0 +1,88 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FieldStringList is a helper type that provides type-safe access to a Swift
+// header whose value is a comma-separated list of strings, such as an ACL. It
+// cannot be directly constructed, but methods on the Headers types return this
+// type.
+//
+// When reading, whitespace around each entry is removed, and empty entries are
+// skipped. For example, the value "a, b,,c" is read as []string{"a", "b", "c"}.
+// Validate() rejects entries containing control characters.
+type FieldStringList struct {
+	h Headers
+	k string
+}
+
+// Exists checks whether there is a value for this header.
+func (f FieldStringList) Exists() bool {
+	return f.h.Get(f.k) != ""
+}
+
+// Get returns the entries in this header, or nil if there is no value.
+func (f FieldStringList) Get() []string {
+	var result []string
+	for _, entry := range strings.Split(f.h.Get(f.k), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// Set writes a new value for this header into the corresponding headers
+// instance.
+func (f FieldStringList) Set(value []string) {
+	f.h.Set(f.k, strings.Join(value, ","))
+}
+
+// Del removes this key from the original headers instance, so that the key will
+// remain unchanged on the server during Update().
+func (f FieldStringList) Del() {
+	f.h.Del(f.k)
+}
+
+// Clear sets this key to an empty string in the original headers instance, so
+// that the key will be removed on the server during Update().
+func (f FieldStringList) Clear() {
+	f.h.Clear(f.k)
+}
+
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldStringList) Remove() {
+	f.h.Remove(f.k)
+}
+
+func (f FieldStringList) validate() error {
+	for _, entry := range f.Get() {
+		if strings.IndexFunc(entry, unicode.IsControl) >= 0 {
+			return MalformedHeaderError{f.k, fmt.Errorf("entry %q contains control characters", entry)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"reflect"
+	"testing"
+)
+
+// FieldInt64 is not used by any of the well-known headers, so it is tested
+// directly. FieldStringList is tested through ContainerHeaders.ReadACLEntries().
+
+func TestFieldInt64(t *testing.T) {
+	h := make(Headers)
+	f := FieldInt64{h, "X-Object-Meta-Offset"}
+	if f.Exists() || f.Get() != 0 {
+		t.Errorf("expected empty field, got %q", h.Get("X-Object-Meta-Offset"))
+	}
+	must(t, f.validate())
+
+	f.Set(-42)
+	expectString(t, "-42", h.Get("X-Object-Meta-Offset"))
+	if f.Get() != -42 {
+		t.Errorf("expected -42, got %d", f.Get())
+	}
+	must(t, f.validate())
+
+	h.Set("X-Object-Meta-Offset", "forty-two")
+	if f.Get() != 0 {
+		t.Errorf("expected 0 for malformed value, got %d", f.Get())
+	}
+	if f.validate() == nil {
+		t.Error("expected validation error for malformed value")
+	}
+}
+
+func TestFieldStringList(t *testing.T) {
+	h := make(Headers)
+	hdr := ContainerHeaders{h}
+	f := hdr.ReadACLEntries()
+	if f.Exists() || f.Get() != nil {
+		t.Errorf("expected nil, got %#v", f.Get())
+	}
+	must(t, hdr.Validate())
+
+	h.Set("X-Container-Read", ".r:*, .rlistings,,AUTH_foo")
+	expected := []string{".r:*", ".rlistings", "AUTH_foo"}
+	if actual := f.Get(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %#v, got %#v", expected, actual)
+	}
+
+	f.Set([]string{"AUTH_foo", "AUTH_bar"})
+	expectString(t, "AUTH_foo,AUTH_bar", h.Get("X-Container-Read"))
+	expectString(t, "AUTH_foo,AUTH_bar", hdr.ReadACL().Get())
+	must(t, hdr.Validate())
+
+	hdr.WriteACLEntries().Set([]string{"AUTH_foo", "AUTH_\x7fbar"})
+	if hdr.Validate() == nil {
+		t.Error("expected validation error for entry with control character")
+	}
+}
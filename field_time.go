@@ -133,6 +133,12 @@ func (f FieldUnixTime) Clear() {
 	f.h.Clear(f.k)
 }
 
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldUnixTime) Remove() {
+	f.h.Remove(f.k)
+}
+
 func (f FieldUnixTime) validate() error {
 	val := f.h.Get(f.k)
 	if val == "" {
@@ -168,3 +174,69 @@ func (f FieldUnixTimeReadonly) Get() time.Time {
 func (f FieldUnixTimeReadonly) validate() error {
 	return FieldUnixTime(f).validate()
 }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FieldDuration is a helper type that provides type-safe access to a Swift
+// header whose value is a duration in whole seconds. It cannot be directly
+// constructed, but methods on the Headers types return this type. For example:
+//
+//	hdr := NewObjectHeaders()
+//	//the following two statements are equivalent:
+//	hdr["X-Delete-After"] = "3600"
+//	hdr.ExpiresAfter().Set(time.Hour)
+type FieldDuration struct {
+	h Headers
+	k string
+}
+
+// Exists checks whether there is a value for this header.
+func (f FieldDuration) Exists() bool {
+	return f.h.Get(f.k) != ""
+}
+
+// Get returns the value for this header, or 0 if there is no value (or if it is
+// not a valid number of seconds).
+func (f FieldDuration) Get() time.Duration {
+	v, err := strconv.ParseInt(f.h.Get(f.k), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(v) * time.Second
+}
+
+// Set writes a new value for this header into the corresponding headers
+// instance. Fractional seconds are truncated.
+func (f FieldDuration) Set(value time.Duration) {
+	f.h.Set(f.k, strconv.FormatInt(int64(value/time.Second), 10))
+}
+
+// Del removes this key from the original headers instance, so that the key will
+// remain unchanged on the server during Update().
+func (f FieldDuration) Del() {
+	f.h.Del(f.k)
+}
+
+// Clear sets this key to an empty string in the original headers instance, so
+// that the key will be removed on the server during Update().
+func (f FieldDuration) Clear() {
+	f.h.Clear(f.k)
+}
+
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldDuration) Remove() {
+	f.h.Remove(f.k)
+}
+
+func (f FieldDuration) validate() error {
+	val := f.h.Get(f.k)
+	if val == "" {
+		return nil
+	}
+	_, err := strconv.ParseInt(val, 10, 64)
+	if err == nil {
+		return nil
+	}
+	return MalformedHeaderError{f.k, err}
+}
@@ -68,6 +68,12 @@ func (f FieldUint64) Clear() {
 	f.h.Clear(f.k)
 }
 
+// Remove marks this key for removal on the server during Update(). See
+// Headers.Remove() for how this differs from Clear().
+func (f FieldUint64) Remove() {
+	f.h.Remove(f.k)
+}
+
 func (f FieldUint64) validate() error {
 	val := f.h.Get(f.k)
 	if val == "" {
@@ -0,0 +1,427 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DirectoryFS is an implementation of fs.FS (and also fs.ReadDirFS and
+// fs.StatFS) that presents the contents of a pseudo-directory as a read-only
+// file system. Instances are obtained with Directory.FS(). For example, to
+// serve a container's contents via HTTP:
+//
+//	fsys := container.Dir("").FS(ctx)
+//	mux.Handle("/", http.FileServer(http.FS(fsys)))
+//
+// Object names are split into path elements at slashes. Objects whose names
+// cannot be represented as a valid path in terms of fs.ValidPath() (e.g.
+// because they contain empty path elements like "a//b") are not visible
+// through this interface.
+//
+// Files are opened without downloading their contents. Contents are retrieved
+// with ranged GET requests as needed, so the files returned by Open()
+// implement io.Seeker and io.ReaderAt in addition to fs.File.
+//
+// Pseudo-directories (see type Directory) are presented as directories.
+// Directory marker objects (see Directory.EnsureMarker()) are also presented
+// as directories, so that empty directories can exist.
+type DirectoryFS struct {
+	// fs.FS does not take a context argument in its methods, so we need to
+	// provide one from the outside
+	ctx context.Context //nolint:containedctx // see above
+	d   *Directory
+}
+
+// FS returns an fs.FS instance that presents the contents of this directory.
+// The given context will be used for all requests made through the FS
+// instance. See documentation on type DirectoryFS for details.
+func (d *Directory) FS(ctx context.Context) *DirectoryFS {
+	return &DirectoryFS{ctx, d}
+}
+
+var (
+	_ fs.FS          = &DirectoryFS{}
+	_ fs.ReadDirFS   = &DirectoryFS{}
+	_ fs.StatFS      = &DirectoryFS{}
+	_ fs.ReadDirFile = &fsDirectory{}
+	_ io.ReadSeeker  = &fsFile{}
+	_ io.ReaderAt    = &fsFile{}
+)
+
+// Open implements the fs.FS interface.
+func (fsys *DirectoryFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fsDirectory{fsys: fsys, name: name, d: fsys.d}, nil
+	}
+
+	// is this an object?
+	obj := fsys.d.Object(name)
+	hdr, err := obj.Headers(fsys.ctx)
+	switch {
+	case err == nil:
+		if hdr.ContentType().Get() == DirectoryMarkerContentType {
+			return &fsDirectory{fsys: fsys, name: name, d: fsys.d.Dir(name), modTime: hdr.UpdatedAt().Get()}, nil
+		}
+		return &fsFile{fsys: fsys, name: name, obj: obj, hdr: hdr}, nil
+	case Is(err, http.StatusNotFound):
+		// continue below
+	default:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	// is this a pseudo-directory?
+	iter := fsys.d.Dir(name).Objects()
+	names, err := iter.NextPage(fsys.ctx, 1)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if len(names) == 0 {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return &fsDirectory{fsys: fsys, name: name, d: fsys.d.Dir(name)}, nil
+}
+
+// ReadDir implements the fs.ReadDirFS interface.
+func (fsys *DirectoryFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir, ok := f.(*fsDirectory)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return dir.ReadDir(-1)
+}
+
+// Stat implements the fs.StatFS interface.
+func (fsys *DirectoryFS) Stat(name string) (fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// type fsFileInfo
+
+// fsFileInfo implements fs.FileInfo and fs.DirEntry.
+type fsFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+	sys     any
+}
+
+func (i fsFileInfo) Name() string       { return i.name }
+func (i fsFileInfo) Size() int64        { return i.size }
+func (i fsFileInfo) ModTime() time.Time { return i.modTime }
+func (i fsFileInfo) IsDir() bool        { return i.isDir }
+func (i fsFileInfo) Sys() any           { return i.sys }
+
+func (i fsFileInfo) Mode() fs.FileMode {
+	if i.isDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+func (i fsFileInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i fsFileInfo) Info() (fs.FileInfo, error) { return i, nil }
+func (i fsFileInfo) String() string             { return fs.FormatFileInfo(i) }
+
+// ceilToSecond converts a timestamp from an object listing into the same
+// precision as the Last-Modified header that Swift reports for the same
+// object. Otherwise the same file would report different ModTime() values
+// depending on whether it was obtained from Stat() or ReadDir().
+func ceilToSecond(t time.Time) time.Time {
+	truncated := t.Truncate(time.Second)
+	if truncated.Equal(t) {
+		return t
+	}
+	return truncated.Add(time.Second)
+}
+
+func clampToInt64(val uint64) int64 {
+	if val > 1<<63-1 {
+		return 1<<63 - 1
+	}
+	return int64(val)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// type fsFile
+
+// fsFile implements fs.File for objects.
+type fsFile struct {
+	fsys   *DirectoryFS
+	name   string
+	obj    *Object
+	hdr    ObjectHeaders
+	offset int64
+	body   io.ReadCloser // nil until first Read(), and after Seek()
+}
+
+func (f *fsFile) size() int64 {
+	return clampToInt64(f.hdr.SizeBytes().Get())
+}
+
+// Stat implements the fs.File interface.
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return fsFileInfo{
+		name:    path.Base(f.name),
+		size:    f.size(),
+		modTime: f.hdr.UpdatedAt().Get(),
+		sys:     f.hdr,
+	}, nil
+}
+
+// Read implements the fs.File interface.
+func (f *fsFile) Read(buf []byte) (int, error) {
+	if f.offset >= f.size() {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		var err error
+		f.body, err = f.download(f.offset, -1)
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+		}
+	}
+	n, err := f.body.Read(buf)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Seek implements the io.Seeker interface.
+func (f *fsFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// nothing to add
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.size()
+	default:
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	if offset != f.offset && f.body != nil {
+		// the next Read() needs to start a new GET request at the new offset
+		err := f.body.Close()
+		f.body = nil
+		if err != nil {
+			return 0, err
+		}
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// ReadAt implements the io.ReaderAt interface.
+func (f *fsFile) ReadAt(buf []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	size := f.size()
+	if offset >= size {
+		return 0, io.EOF
+	}
+	if len(buf) == 0 {
+		return 0, nil
+	}
+
+	body, err := f.download(offset, int64(len(buf)))
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+	n, err := io.ReadFull(body, buf)
+	closeErr := body.Close()
+	if errors.Is(err, io.ErrUnexpectedEOF) && offset+int64(n) == size {
+		err = io.EOF
+	}
+	if err == nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// Downloads `length` bytes starting at `offset`, or the rest of the object if
+// `length < 0`.
+func (f *fsFile) download(offset, length int64) (io.ReadCloser, error) {
+	var opts *RequestOptions
+	if offset > 0 || length >= 0 {
+		r := ByteRange{Offset: offset}
+		if length >= 0 {
+			r.Length = uint64(length)
+		}
+		opts = opts.WithRange(r)
+	}
+	downloaded := f.obj.Download(f.fsys.ctx, opts)
+	body, err := downloaded.AsReadCloser()
+	if err != nil || opts == nil || downloaded.IsPartial() {
+		return body, err
+	}
+
+	// the server ignored the Range header and sent the entire object, so we
+	// need to cut out the requested range ourselves
+	_, err = io.CopyN(io.Discard, body, offset)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return body, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, length), body}, nil
+}
+
+// Close implements the fs.File interface.
+func (f *fsFile) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// type fsDirectory
+
+// fsDirectory implements fs.ReadDirFile for pseudo-directories.
+type fsDirectory struct {
+	fsys    *DirectoryFS
+	name    string
+	d       *Directory
+	modTime time.Time     // only set for directory markers
+	entries []fs.DirEntry // remaining entries for ReadDir()
+	loaded  bool
+}
+
+// Stat implements the fs.File interface.
+func (d *fsDirectory) Stat() (fs.FileInfo, error) {
+	return fsFileInfo{name: path.Base(d.name), isDir: true, modTime: d.modTime, sys: d.d}, nil
+}
+
+// Read implements the fs.File interface.
+func (d *fsDirectory) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+// Close implements the fs.File interface.
+func (d *fsDirectory) Close() error {
+	return nil
+}
+
+// ReadDir implements the fs.ReadDirFile interface.
+func (d *fsDirectory) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.loaded {
+		err := d.load()
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+	}
+
+	if n <= 0 {
+		result := d.entries
+		d.entries = nil
+		return result, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	result := d.entries[:n]
+	d.entries = d.entries[n:]
+	return result, nil
+}
+
+func (d *fsDirectory) load() error {
+	infos, err := d.d.List(d.fsys.ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make(map[string]fsFileInfo, len(infos))
+	for _, info := range infos {
+		var fi fsFileInfo
+		if info.SubDirectory == "" {
+			fi = fsFileInfo{
+				name:    strings.TrimPrefix(info.Object.Name(), d.d.Prefix()),
+				size:    clampToInt64(info.SizeBytes),
+				modTime: ceilToSecond(info.LastModified),
+				isDir:   info.ContentType == DirectoryMarkerContentType,
+				sys:     info,
+			}
+			if fi.isDir {
+				fi.size = 0
+			}
+		} else {
+			fi = fsFileInfo{
+				name:  strings.TrimSuffix(strings.TrimPrefix(info.SubDirectory, d.d.Prefix()), "/"),
+				isDir: true,
+				sys:   info,
+			}
+		}
+
+		// skip names that cannot be represented in an fs.FS
+		if fi.name == "." || strings.Contains(fi.name, "/") || !fs.ValidPath(fi.name) {
+			continue
+		}
+		// if we have both a directory marker and a pseudo-directory, we prefer the
+		// directory marker since it carries more metadata
+		if existing, exists := entries[fi.name]; exists && existing.isDir && info.SubDirectory != "" {
+			continue
+		}
+		entries[fi.name] = fi
+	}
+
+	d.entries = make([]fs.DirEntry, 0, len(entries))
+	for _, fi := range entries {
+		d.entries = append(d.entries, fi)
+	}
+	sort.Slice(d.entries, func(i, j int) bool {
+		return d.entries[i].Name() < d.entries[j].Name()
+	})
+	d.loaded = true
+	return nil
+}
@@ -0,0 +1,31 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCeilToSecond(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	expectString(t, "2024-01-01T12:00:00Z", ceilToSecond(base).Format(time.RFC3339Nano))
+	expectString(t, "2024-01-01T12:00:01Z", ceilToSecond(base.Add(10*time.Microsecond)).Format(time.RFC3339Nano))
+	expectString(t, "2024-01-01T12:00:01Z", ceilToSecond(base.Add(999*time.Millisecond)).Format(time.RFC3339Nano))
+}
@@ -8,6 +8,8 @@
 
 package schwift
 
+import "net/http"
+
 // AccountHeaders contains the headers for a schwift.Account instance.
 //
 // To read and write well-known headers, use the methods on this type.
@@ -22,12 +24,19 @@ func NewAccountHeaders() AccountHeaders {
 	return AccountHeaders{make(Headers)}
 }
 
+func newAccountHeadersFromHTTP(src http.Header) AccountHeaders {
+	return AccountHeaders{headersFromHTTP(src)}
+}
+
 // Validate returns MalformedHeaderError if the value of any well-known header
 // does not conform to its data type. This is called automatically by Schwift
 // when preparing an AccountHeaders instance from a GET/HEAD response, so you
 // usually do not need to do it yourself. You will get the validation error from
 // the Account method doing the request, e.g. Headers().
 func (h AccountHeaders) Validate() error {
+	if err := h.UpdatedAt().validate(); err != nil {
+		return err
+	}
 	if err := h.BytesUsed().validate(); err != nil {
 		return err
 	}
@@ -52,7 +61,12 @@ func (h AccountHeaders) Validate() error {
 	if err := h.CreatedAt().validate(); err != nil {
 		return err
 	}
-	return evadeGolintComplaint1()
+	return validateCustomFields(h.Headers, "Account")
+}
+
+// UpdatedAt provides type-safe access to Last-Modified headers.
+func (h AccountHeaders) UpdatedAt() FieldHTTPTimeReadonly {
+	return FieldHTTPTimeReadonly{h.Headers, "Last-Modified"}
 }
 
 // BytesUsed provides type-safe access to X-Account-Bytes-Used headers.
@@ -109,18 +123,43 @@ func NewContainerHeaders() ContainerHeaders {
 	return ContainerHeaders{make(Headers)}
 }
 
+func newContainerHeadersFromHTTP(src http.Header) ContainerHeaders {
+	return ContainerHeaders{headersFromHTTP(src)}
+}
+
 // Validate returns MalformedHeaderError if the value of any well-known header
 // does not conform to its data type. This is called automatically by Schwift
 // when preparing an ContainerHeaders instance from a GET/HEAD response, so you
 // usually do not need to do it yourself. You will get the validation error from
 // the Container method doing the request, e.g. Headers().
 func (h ContainerHeaders) Validate() error {
+	if err := h.UpdatedAt().validate(); err != nil {
+		return err
+	}
 	if err := h.BytesUsed().validate(); err != nil {
 		return err
 	}
 	if err := h.Metadata().validate(); err != nil {
 		return err
 	}
+	if err := h.CORSAllowedOrigins().validate(); err != nil {
+		return err
+	}
+	if err := h.CORSExposedHeaders().validate(); err != nil {
+		return err
+	}
+	if err := h.CORSMaxAge().validate(); err != nil {
+		return err
+	}
+	if err := h.CreatedBy().validate(); err != nil {
+		return err
+	}
+	if err := h.ManagedBy().validate(); err != nil {
+		return err
+	}
+	if err := h.Owner().validate(); err != nil {
+		return err
+	}
 	if err := h.BytesUsedQuota().validate(); err != nil {
 		return err
 	}
@@ -139,6 +178,9 @@ func (h ContainerHeaders) Validate() error {
 	if err := h.ReadACL().validate(); err != nil {
 		return err
 	}
+	if err := h.ReadACLEntries().validate(); err != nil {
+		return err
+	}
 	if err := h.SyncKey().validate(); err != nil {
 		return err
 	}
@@ -148,6 +190,9 @@ func (h ContainerHeaders) Validate() error {
 	if err := h.WriteACL().validate(); err != nil {
 		return err
 	}
+	if err := h.WriteACLEntries().validate(); err != nil {
+		return err
+	}
 	if err := h.HistoryLocation().validate(); err != nil {
 		return err
 	}
@@ -157,10 +202,18 @@ func (h ContainerHeaders) Validate() error {
 	if err := h.CreatedAt().validate(); err != nil {
 		return err
 	}
+	if err := h.VersionsEnabled().validate(); err != nil {
+		return err
+	}
 	if err := h.VersionsLocation().validate(); err != nil {
 		return err
 	}
-	return evadeGolintComplaint1()
+	return validateCustomFields(h.Headers, "Container")
+}
+
+// UpdatedAt provides type-safe access to Last-Modified headers.
+func (h ContainerHeaders) UpdatedAt() FieldHTTPTimeReadonly {
+	return FieldHTTPTimeReadonly{h.Headers, "Last-Modified"}
 }
 
 // BytesUsed provides type-safe access to X-Container-Bytes-Used headers.
@@ -173,6 +226,36 @@ func (h ContainerHeaders) Metadata() FieldMetadata {
 	return FieldMetadata{h.Headers, "X-Container-Meta-"}
 }
 
+// CORSAllowedOrigins provides type-safe access to X-Container-Meta-Access-Control-Allow-Origin headers.
+func (h ContainerHeaders) CORSAllowedOrigins() FieldString {
+	return FieldString{h.Headers, "X-Container-Meta-Access-Control-Allow-Origin"}
+}
+
+// CORSExposedHeaders provides type-safe access to X-Container-Meta-Access-Control-Expose-Headers headers.
+func (h ContainerHeaders) CORSExposedHeaders() FieldString {
+	return FieldString{h.Headers, "X-Container-Meta-Access-Control-Expose-Headers"}
+}
+
+// CORSMaxAge provides type-safe access to X-Container-Meta-Access-Control-Max-Age headers.
+func (h ContainerHeaders) CORSMaxAge() FieldDuration {
+	return FieldDuration{h.Headers, "X-Container-Meta-Access-Control-Max-Age"}
+}
+
+// CreatedBy provides type-safe access to X-Container-Meta-Created-By headers.
+func (h ContainerHeaders) CreatedBy() FieldString {
+	return FieldString{h.Headers, "X-Container-Meta-Created-By"}
+}
+
+// ManagedBy provides type-safe access to X-Container-Meta-Managed-By headers.
+func (h ContainerHeaders) ManagedBy() FieldString {
+	return FieldString{h.Headers, "X-Container-Meta-Managed-By"}
+}
+
+// Owner provides type-safe access to X-Container-Meta-Owner headers.
+func (h ContainerHeaders) Owner() FieldString {
+	return FieldString{h.Headers, "X-Container-Meta-Owner"}
+}
+
 // BytesUsedQuota provides type-safe access to X-Container-Meta-Quota-Bytes headers.
 func (h ContainerHeaders) BytesUsedQuota() FieldUint64 {
 	return FieldUint64{h.Headers, "X-Container-Meta-Quota-Bytes"}
@@ -203,6 +286,11 @@ func (h ContainerHeaders) ReadACL() FieldString {
 	return FieldString{h.Headers, "X-Container-Read"}
 }
 
+// ReadACLEntries provides type-safe access to X-Container-Read headers.
+func (h ContainerHeaders) ReadACLEntries() FieldStringList {
+	return FieldStringList{h.Headers, "X-Container-Read"}
+}
+
 // SyncKey provides type-safe access to X-Container-Sync-Key headers.
 func (h ContainerHeaders) SyncKey() FieldString {
 	return FieldString{h.Headers, "X-Container-Sync-Key"}
@@ -218,6 +306,11 @@ func (h ContainerHeaders) WriteACL() FieldString {
 	return FieldString{h.Headers, "X-Container-Write"}
 }
 
+// WriteACLEntries provides type-safe access to X-Container-Write headers.
+func (h ContainerHeaders) WriteACLEntries() FieldStringList {
+	return FieldStringList{h.Headers, "X-Container-Write"}
+}
+
 // HistoryLocation provides type-safe access to X-History-Location headers.
 func (h ContainerHeaders) HistoryLocation() FieldString {
 	return FieldString{h.Headers, "X-History-Location"}
@@ -233,6 +326,11 @@ func (h ContainerHeaders) CreatedAt() FieldUnixTimeReadonly {
 	return FieldUnixTimeReadonly{h.Headers, "X-Timestamp"}
 }
 
+// VersionsEnabled provides type-safe access to X-Versions-Enabled headers.
+func (h ContainerHeaders) VersionsEnabled() FieldBool {
+	return FieldBool{h.Headers, "X-Versions-Enabled"}
+}
+
 // VersionsLocation provides type-safe access to X-Versions-Location headers.
 func (h ContainerHeaders) VersionsLocation() FieldString {
 	return FieldString{h.Headers, "X-Versions-Location"}
@@ -252,6 +350,10 @@ func NewObjectHeaders() ObjectHeaders {
 	return ObjectHeaders{make(Headers)}
 }
 
+func newObjectHeadersFromHTTP(src http.Header) ObjectHeaders {
+	return ObjectHeaders{headersFromHTTP(src)}
+}
+
 // Validate returns MalformedHeaderError if the value of any well-known header
 // does not conform to its data type. This is called automatically by Schwift
 // when preparing an ObjectHeaders instance from a GET/HEAD response, so you
@@ -276,12 +378,21 @@ func (h ObjectHeaders) Validate() error {
 	if err := h.UpdatedAt().validate(); err != nil {
 		return err
 	}
+	if err := h.ExpiresAfter().validate(); err != nil {
+		return err
+	}
 	if err := h.ExpiresAt().validate(); err != nil {
 		return err
 	}
 	if err := h.Metadata().validate(); err != nil {
 		return err
 	}
+	if err := h.IdempotencyKey().validate(); err != nil {
+		return err
+	}
+	if err := h.LegalHold().validate(); err != nil {
+		return err
+	}
 	if err := h.SymlinkTargetAccount().validate(); err != nil {
 		return err
 	}
@@ -291,7 +402,7 @@ func (h ObjectHeaders) Validate() error {
 	if err := h.CreatedAt().validate(); err != nil {
 		return err
 	}
-	return evadeGolintComplaint1()
+	return validateCustomFields(h.Headers, "Object")
 }
 
 // ContentDisposition provides type-safe access to Content-Disposition headers.
@@ -324,6 +435,11 @@ func (h ObjectHeaders) UpdatedAt() FieldHTTPTimeReadonly {
 	return FieldHTTPTimeReadonly{h.Headers, "Last-Modified"}
 }
 
+// ExpiresAfter provides type-safe access to X-Delete-After headers.
+func (h ObjectHeaders) ExpiresAfter() FieldDuration {
+	return FieldDuration{h.Headers, "X-Delete-After"}
+}
+
 // ExpiresAt provides type-safe access to X-Delete-At headers.
 func (h ObjectHeaders) ExpiresAt() FieldUnixTime {
 	return FieldUnixTime{h.Headers, "X-Delete-At"}
@@ -334,6 +450,16 @@ func (h ObjectHeaders) Metadata() FieldMetadata {
 	return FieldMetadata{h.Headers, "X-Object-Meta-"}
 }
 
+// IdempotencyKey provides type-safe access to X-Object-Meta-Idempotency-Key headers.
+func (h ObjectHeaders) IdempotencyKey() FieldString {
+	return FieldString{h.Headers, "X-Object-Meta-Idempotency-Key"}
+}
+
+// LegalHold provides type-safe access to X-Object-Meta-Legal-Hold headers.
+func (h ObjectHeaders) LegalHold() FieldString {
+	return FieldString{h.Headers, "X-Object-Meta-Legal-Hold"}
+}
+
 // SymlinkTargetAccount provides type-safe access to X-Symlink-Target-Account headers.
 func (h ObjectHeaders) SymlinkTargetAccount() FieldString {
 	return FieldString{h.Headers, "X-Symlink-Target-Account"}
@@ -348,7 +474,3 @@ func (h ObjectHeaders) SymlinkTarget() FieldString {
 func (h ObjectHeaders) CreatedAt() FieldUnixTimeReadonly {
 	return FieldUnixTimeReadonly{h.Headers, "X-Timestamp"}
 }
-
-func evadeGolintComplaint1() error {
-	return nil
-}
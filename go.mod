@@ -5,7 +5,6 @@ go 1.22
 require (
 	github.com/gophercloud/gophercloud/v2 v2.0.0
 	github.com/gophercloud/utils/v2 v2.0.0-20240701101423-2401526caee5
-	github.com/jpillora/longestcommon v0.0.0-20161227235612-adb9d91ee629
 )
 
 require (
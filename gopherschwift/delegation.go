@@ -0,0 +1,277 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package gopherschwift
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/identity/v3/tokens"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// TrustOptions contains the credentials for NewAccountFromTrust(). The user
+// given here is the trustee, i.e. the service user that acts on behalf of the
+// trustor. Either UserID or Username plus DomainID or DomainName must be given.
+type TrustOptions struct {
+	// The Keystone v3 endpoint, e.g. "https://keystone.example.com/v3/".
+	IdentityEndpoint string
+	UserID           string
+	Username         string
+	DomainID         string
+	DomainName       string
+	Password         string
+	// The ID of the trust that the trustor has created for the trustee.
+	TrustID string
+}
+
+// ApplicationCredentialOptions contains the credentials for
+// NewAccountFromApplicationCredential(). Either ID must be given, or Name plus
+// enough information to identify the owning user (UserID, or Username plus
+// DomainID or DomainName).
+type ApplicationCredentialOptions struct {
+	// The Keystone v3 endpoint, e.g. "https://keystone.example.com/v3/".
+	IdentityEndpoint string
+	ID               string
+	Name             string
+	UserID           string
+	Username         string
+	DomainID         string
+	DomainName       string
+	Secret           string
+}
+
+// ScopeLimits describes least-privilege requirements that
+// NewAccountFromTrust() and NewAccountFromApplicationCredential() enforce on
+// the token obtained from Keystone. Regardless of these limits, the token must
+// always be scoped to a project.
+type ScopeLimits struct {
+	// If not empty, the token must be scoped to this project.
+	ProjectID string
+	// If not empty, the token must not carry any roles other than these. Use
+	// this to detect trusts and application credentials that were created with
+	// more roles than the service needs (e.g. "admin" in addition to "member").
+	AllowedRoles []string
+	// If not nil, the returned Account is restricted to this policy. See
+	// documentation on schwift.Account.WithPolicy() for details.
+	Policy schwift.AccessPolicy
+}
+
+// ScopeError is returned by NewAccountFromTrust() and
+// NewAccountFromApplicationCredential() when the token obtained from Keystone
+// is broader than allowed by the ScopeLimits. It matches ErrScopeTooBroad when
+// checked with errors.Is().
+type ScopeError struct {
+	Reason string
+}
+
+// ErrScopeTooBroad is matched by ScopeError when checked with errors.Is().
+var ErrScopeTooBroad = errors.New("token scope exceeds the requested limits")
+
+// Error implements the builtin/error interface.
+func (e ScopeError) Error() string {
+	return "refusing to use token: " + e.Reason
+}
+
+// Unwrap returns ErrScopeTooBroad.
+func (e ScopeError) Unwrap() error {
+	return ErrScopeTooBroad
+}
+
+// NewAccountFromTrust authenticates as the trustee of a Keystone trust and
+// returns an Account for the trustor's project. The token is trust-scoped, so
+// it only carries the roles that were delegated in the trust. Reauthentication
+// on token expiry is handled transparently.
+//
+// To act on behalf of a user with least privilege, the trustor should create
+// the trust with only the roles required for object storage (usually just
+// "member" or an operator-specific role like "swiftoperator"), and the service
+// should pass those roles in ScopeLimits.AllowedRoles, so that a trust with
+// additional roles is refused instead of being used silently:
+//
+//	account, err := gopherschwift.NewAccountFromTrust(ctx, gopherschwift.TrustOptions{
+//		IdentityEndpoint: "https://keystone.example.com/v3/",
+//		UserID:           serviceUserID,
+//		Password:         servicePassword,
+//		TrustID:          trustID,
+//	}, &gopherschwift.ScopeLimits{AllowedRoles: []string{"member"}}, nil)
+//
+// The limits and wrap options may be nil.
+func NewAccountFromTrust(ctx context.Context, opts TrustOptions, limits *ScopeLimits, wrapOpts *Options) (*schwift.Account, error) {
+	if opts.TrustID == "" {
+		return nil, errors.New("cannot authenticate with trust: no trust ID given")
+	}
+	authOpts := gophercloud.AuthOptions{
+		IdentityEndpoint: opts.IdentityEndpoint,
+		UserID:           opts.UserID,
+		Username:         opts.Username,
+		DomainID:         opts.DomainID,
+		DomainName:       opts.DomainName,
+		Password:         opts.Password,
+		Scope:            &gophercloud.AuthScope{TrustID: opts.TrustID},
+	}
+	return newDelegatedAccount(ctx, authOpts, limits, wrapOpts, "trust "+opts.TrustID)
+}
+
+// NewAccountFromApplicationCredential authenticates with a Keystone
+// application credential and returns an Account for the project that the
+// credential belongs to. Reauthentication on token expiry is handled
+// transparently.
+//
+// For least privilege, the application credential should be created as
+// restricted (i.e. unable to create further credentials or trusts), with only
+// the roles required for object storage, and with access rules that only
+// permit the "object-store" service type, for example:
+//
+//	openstack application credential create --role member \
+//	  --access-rules '[{"service": "object-store", "method": "*", "path": "/v1/**"}]' \
+//	  my-service
+//
+// Access rules are enforced by Keystone and Swift and cannot be inspected from
+// the token, but the project and roles can be checked via ScopeLimits. The
+// limits and wrap options may be nil.
+func NewAccountFromApplicationCredential(ctx context.Context, opts ApplicationCredentialOptions, limits *ScopeLimits, wrapOpts *Options) (*schwift.Account, error) {
+	if opts.ID == "" && opts.Name == "" {
+		return nil, errors.New("cannot authenticate with application credential: neither ID nor name given")
+	}
+	authOpts := gophercloud.AuthOptions{
+		IdentityEndpoint:            opts.IdentityEndpoint,
+		UserID:                      opts.UserID,
+		Username:                    opts.Username,
+		DomainID:                    opts.DomainID,
+		DomainName:                  opts.DomainName,
+		ApplicationCredentialID:     opts.ID,
+		ApplicationCredentialName:   opts.Name,
+		ApplicationCredentialSecret: opts.Secret,
+	}
+	desc := "application credential " + opts.ID
+	if opts.ID == "" {
+		desc = "application credential " + opts.Name
+	}
+	return newDelegatedAccount(ctx, authOpts, limits, wrapOpts, desc)
+}
+
+func newDelegatedAccount(ctx context.Context, authOpts gophercloud.AuthOptions, limits *ScopeLimits, wrapOpts *Options, desc string) (*schwift.Account, error) {
+	authOpts.AllowReauth = true
+	provider, err := openstack.NewClient(authOpts.IdentityEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("cannot authenticate with %s: %w", desc, err)
+	}
+	err = openstack.AuthenticateV3(ctx, provider, &authOpts, gophercloud.EndpointOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot authenticate with %s: %w", desc, err)
+	}
+
+	info, err := extractTokenScope(provider.GetAuthResult())
+	if err != nil {
+		return nil, fmt.Errorf("cannot inspect token for %s: %w", desc, err)
+	}
+	var trustID string
+	if authOpts.Scope != nil {
+		trustID = authOpts.Scope.TrustID
+	}
+	err = info.check(trustID, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := openstack.NewObjectStorageV1(provider, gophercloud.EndpointOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find object-store endpoint for %s: %w", desc, err)
+	}
+	account, err := Wrap(client, wrapOpts)
+	if err != nil {
+		return nil, err
+	}
+	if limits != nil && limits.Policy != nil {
+		return account.WithPolicy(limits.Policy)
+	}
+	return account, nil
+}
+
+// tokenScope contains the parts of a Keystone token that are relevant for
+// enforcing ScopeLimits.
+type tokenScope struct {
+	ProjectID string
+	TrustID   string
+	Roles     []string
+}
+
+func extractTokenScope(result gophercloud.AuthResult) (tokenScope, error) {
+	r, ok := result.(tokens.CreateResult)
+	if !ok {
+		return tokenScope{}, fmt.Errorf("unexpected auth result of type %T", result)
+	}
+	var s tokenScope
+	project, err := r.ExtractProject()
+	if err != nil {
+		return tokenScope{}, err
+	}
+	if project != nil {
+		s.ProjectID = project.ID
+	}
+	trust, err := r.ExtractTrust()
+	if err != nil {
+		return tokenScope{}, err
+	}
+	if trust != nil {
+		s.TrustID = trust.ID
+	}
+	roles, err := r.ExtractRoles()
+	if err != nil {
+		return tokenScope{}, err
+	}
+	for _, role := range roles {
+		s.Roles = append(s.Roles, role.Name)
+	}
+	return s, nil
+}
+
+func (s tokenScope) check(trustID string, limits *ScopeLimits) error {
+	if s.ProjectID == "" {
+		return ScopeError{Reason: "token is not scoped to a project"}
+	}
+	if trustID != "" && s.TrustID != trustID {
+		return ScopeError{Reason: fmt.Sprintf("token is not scoped to trust %s", trustID)}
+	}
+	if limits == nil {
+		return nil
+	}
+	if limits.ProjectID != "" && s.ProjectID != limits.ProjectID {
+		return ScopeError{Reason: fmt.Sprintf("token is scoped to project %s instead of %s", s.ProjectID, limits.ProjectID)}
+	}
+	if len(limits.AllowedRoles) > 0 {
+		var excess []string
+		for _, role := range s.Roles {
+			if !slices.Contains(limits.AllowedRoles, role) {
+				excess = append(excess, role)
+			}
+		}
+		if len(excess) > 0 {
+			return ScopeError{Reason: "token carries additional roles: " + strings.Join(excess, ", ")}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package gopherschwift
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenScopeCheck(t *testing.T) {
+	s := tokenScope{ProjectID: "p1", TrustID: "t1", Roles: []string{"member", "reader"}}
+
+	testCases := []struct {
+		Scope   tokenScope
+		TrustID string
+		Limits  *ScopeLimits
+		Error   string
+	}{
+		{s, "", nil, ""},
+		{s, "t1", nil, ""},
+		{s, "t2", nil, "refusing to use token: token is not scoped to trust t2"},
+		{tokenScope{Roles: s.Roles}, "", nil, "refusing to use token: token is not scoped to a project"},
+		{s, "", &ScopeLimits{ProjectID: "p1"}, ""},
+		{s, "", &ScopeLimits{ProjectID: "p2"}, "refusing to use token: token is scoped to project p1 instead of p2"},
+		{s, "", &ScopeLimits{AllowedRoles: []string{"member", "reader", "admin"}}, ""},
+		{s, "", &ScopeLimits{AllowedRoles: []string{"member"}}, "refusing to use token: token carries additional roles: reader"},
+	}
+
+	for idx, tc := range testCases {
+		err := tc.Scope.check(tc.TrustID, tc.Limits)
+		switch {
+		case tc.Error == "" && err != nil:
+			t.Errorf("test case %d: unexpected error: %s", idx, err.Error())
+		case tc.Error != "" && err == nil:
+			t.Errorf("test case %d: expected error %q, got none", idx, tc.Error)
+		case tc.Error != "" && err.Error() != tc.Error:
+			t.Errorf("test case %d: expected error %q, got %q", idx, tc.Error, err.Error())
+		case tc.Error != "" && !errors.Is(err, ErrScopeTooBroad):
+			t.Errorf("test case %d: expected error to match ErrScopeTooBroad", idx)
+		}
+	}
+}
@@ -30,9 +30,14 @@ so you only need to obtain a client token once using Gophercloud. For example:
 		"github.com/majewsky/schwift/v2/gopherschwift"
 	)
 
-	provider, err := clientconfig.AuthenticatedClient(nil)
+	provider, err := clientconfig.AuthenticatedClient(ctx, nil)
 	client, err := openstack.NewObjectStorageV1(provider, gophercloud.EndpointOpts{})
-	account, err := gopherschwift.Wrap(client)
+	account, err := gopherschwift.WrapContext(ctx, client, nil)
+
+When the token expires, the backend reauthenticates transparently using the
+ReauthFunc of the ProviderClient. The reauthentication runs with the context of
+the request that encountered the expired token, so cancellation and deadlines
+of that request also apply to the reauthentication.
 
 Using this schwift.Account instance, you have access to all of schwift's API.
 Refer to the documentation in the parent package for details.
@@ -40,6 +45,9 @@ Refer to the documentation in the parent package for details.
 package gopherschwift
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
@@ -53,6 +61,21 @@ type Options struct {
 	// If set, this User-Agent will be reported in HTTP requests instead of
 	// schwift.DefaultUserAgent.
 	UserAgent string
+	// If ReadRegions is not empty, the object-store endpoints of these regions
+	// are looked up in the Keystone catalog, and GET and HEAD requests are
+	// directed to one of them as selected by ReadPreference. All other requests
+	// still go to the endpoint of the service client. See documentation on
+	// schwift.NewMultiRegionBackend() for details.
+	ReadRegions    []string
+	ReadPreference schwift.ReadPreference
+	// If set, requests to Swift are sent through an HTTP client with these TLS
+	// settings instead of through the HTTPClient of the ProviderClient.
+	// Requests to Keystone (i.e. reauthentication) are not affected.
+	TLS *schwift.TLSOptions
+	// If set, connections to Swift are established with this function, e.g.
+	// schwift.UnixSocketDialer() to reach a proxy on the local host. As with TLS,
+	// requests to Keystone are not affected.
+	Dial schwift.DialFunc
 }
 
 // Wrap creates a schwift.Account that uses the given service client as its
@@ -66,12 +89,56 @@ func Wrap(client *gophercloud.ServiceClient, opts *Options) (*schwift.Account, e
 	if opts != nil && opts.UserAgent != "" {
 		b.userAgent = opts.UserAgent
 	}
-	return schwift.InitializeAccount(b)
+	if opts != nil && (opts.TLS != nil || opts.Dial != nil) {
+		httpClient, err := schwift.NewHTTPClient(opts.TLS, opts.Dial)
+		if err != nil {
+			return nil, err
+		}
+		b.httpClient = httpClient
+	}
+	if opts == nil || len(opts.ReadRegions) == 0 {
+		return schwift.InitializeAccount(b)
+	}
+
+	endpoints := make([]schwift.RegionEndpoint, len(opts.ReadRegions))
+	for idx, region := range opts.ReadRegions {
+		eo := gophercloud.EndpointOpts{Region: region}
+		eo.ApplyDefaults("object-store")
+		url, err := client.ProviderClient.EndpointLocator(eo)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find object-store endpoint for region %q: %w", region, err)
+		}
+		endpoints[idx] = schwift.RegionEndpoint{Region: region, URL: url}
+	}
+	mrb, err := schwift.NewMultiRegionBackend(b, endpoints, opts.ReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	return schwift.InitializeAccount(mrb)
+}
+
+// WrapContext is like Wrap, but additionally ensures that the service client
+// is authenticated before returning. If the ProviderClient does not have a
+// token yet, its ReauthFunc is called with the given context. This is useful
+// for surfacing authentication errors early instead of on the first request.
+func WrapContext(ctx context.Context, client *gophercloud.ServiceClient, opts *Options) (*schwift.Account, error) {
+	provider := client.ProviderClient
+	if provider.Token() == "" {
+		err := provider.Reauthenticate(ctx, "")
+		if err != nil {
+			return nil, fmt.Errorf("cannot authenticate: %w", err)
+		}
+		if provider.Token() == "" {
+			return nil, errors.New("cannot authenticate: provider client has no token and no ReauthFunc")
+		}
+	}
+	return Wrap(client, opts)
 }
 
 type backend struct {
-	c         *gophercloud.ServiceClient
-	userAgent string
+	c          *gophercloud.ServiceClient
+	userAgent  string
+	httpClient *http.Client // if nil, the ProviderClient's HTTPClient is used
 }
 
 func (g *backend) EndpointURL() string {
@@ -82,8 +149,9 @@ func (g *backend) Clone(newEndpointURL string) schwift.Backend {
 	clonedClient := *g.c
 	clonedClient.Endpoint = newEndpointURL
 	return &backend{
-		c:         &clonedClient,
-		userAgent: g.userAgent,
+		c:          &clonedClient,
+		userAgent:  g.userAgent,
+		httpClient: g.httpClient,
 	}
 }
 
@@ -99,7 +167,11 @@ func (g *backend) do(req *http.Request, afterReauth bool) (*http.Response, error
 	}
 	req.Header.Set("User-Agent", g.userAgent)
 
-	resp, err := provider.HTTPClient.Do(req)
+	httpClient := g.httpClient
+	if httpClient == nil {
+		httpClient = &provider.HTTPClient
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -21,6 +21,8 @@ package schwift
 import (
 	"net/http"
 	"net/textproto"
+	"slices"
+	"strings"
 )
 
 // Headers represents a set of request headers or response headers.
@@ -39,10 +41,52 @@ type Headers map[string]string
 // Clear sets the value for the specified header to the empty string. When the
 // Headers instance is then sent to the server with Update(), the server will
 // delete the value for that header; cf. Del().
+//
+// Most callers will want to use Remove() instead, which chooses the most
+// reliable way of removing the header on the server.
 func (h Headers) Clear(key string) {
 	h[textproto.CanonicalMIMEHeaderKey(key)] = ""
 }
 
+// Remove marks the specified header for removal, so that the server will
+// delete the value for that header when the Headers instance is sent with
+// Update(). Unlike Clear() and Del(), this works the same for all headers:
+//
+//   - For account and container headers that Swift can remove with the special
+//     "X-Remove-" form (X-Account-Meta-*, X-Container-Meta-*, X-Container-Read,
+//     X-Container-Write, X-Container-Sync-To, X-Container-Sync-Key,
+//     X-Versions-Location and X-History-Location), the header is replaced by
+//     its "X-Remove-" form, e.g. "X-Remove-Container-Meta-Foo" instead of
+//     "X-Container-Meta-Foo".
+//   - For all other headers, this is equivalent to Clear().
+//
+// After Remove(), Get() returns the empty string for the specified header.
+func (h Headers) Remove(key string) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	removeKey, ok := removeFormOf(key)
+	if !ok {
+		h.Clear(key)
+		return
+	}
+	delete(h, key)
+	h[removeKey] = "1"
+}
+
+// removeFormOf returns the "X-Remove-" form of the given canonical header key,
+// if Swift supports one for that header. (Swift ignores "X-Remove-" headers
+// for all other keys, e.g. "X-Remove-Account-Access-Control".)
+func removeFormOf(key string) (string, bool) {
+	switch {
+	case strings.HasPrefix(key, "X-Account-Meta-"), strings.HasPrefix(key, "X-Container-Meta-"),
+		key == "X-Container-Read", key == "X-Container-Write",
+		key == "X-Container-Sync-To", key == "X-Container-Sync-Key",
+		key == "X-Versions-Location", key == "X-History-Location":
+		return "X-Remove-" + strings.TrimPrefix(key, "X-"), true
+	default:
+		return "", false
+	}
+}
+
 // Del deletes a key from the Headers instance. When the Headers instance is
 // then sent to the server with Update(), a key which has been deleted with
 // Del() will remain unchanged on the server.
@@ -54,8 +98,15 @@ func (h Headers) Clear(key string) {
 // For object metadata (but not other object attributes), deleting a key will
 // cause that key to be deleted on the server. Del() is identical to Clear() in
 // this case.
+//
+// To remove a key on the server regardless of these differences, use Remove()
+// instead.
 func (h Headers) Del(key string) {
-	delete(h, textproto.CanonicalMIMEHeaderKey(key))
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	delete(h, key)
+	if removeKey, ok := removeFormOf(key); ok {
+		delete(h, removeKey)
+	}
 }
 
 // Get returns the value for the specified header.
@@ -64,9 +115,13 @@ func (h Headers) Get(key string) string {
 }
 
 // Set sets a new value for the specified header. Any existing value will be
-// overwritten.
+// overwritten, and a previous Remove() on the same header is undone.
 func (h Headers) Set(key, value string) {
-	h[textproto.CanonicalMIMEHeaderKey(key)] = value
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	h[key] = value
+	if removeKey, ok := removeFormOf(key); ok {
+		delete(h, removeKey)
+	}
 }
 
 // ToHTTP converts this Headers instance into the equivalent http.Header
@@ -100,6 +155,55 @@ func headersFromHTTP(src http.Header) Headers {
 	return h
 }
 
+// multiValuedHeaders returns those headers from `src` that have more than one
+// value, or nil if there are none.
+func multiValuedHeaders(src http.Header) http.Header {
+	var result http.Header
+	for k, v := range src {
+		if len(v) > 1 {
+			if result == nil {
+				result = make(http.Header)
+			}
+			result[textproto.CanonicalMIMEHeaderKey(k)] = slices.Clone(v)
+		}
+	}
+	return result
+}
+
+// rawAll implements the RawAll() methods of Account, Container and Object.
+// `h` is the cached header set, and `raw` contains the multi-valued headers
+// from the response that `h` was obtained from.
+func rawAll(h Headers, raw http.Header, key string) []string {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	value, exists := h[key]
+	if !exists || value == "" {
+		return nil
+	}
+	if values := raw[key]; len(values) > 0 && values[0] == value {
+		return slices.Clone(values)
+	}
+	return []string{value}
+}
+
+// diffHeaders returns those headers from `desired` whose value differs from
+// that in `current`. A header in the "X-Remove-" form (see Headers.Remove())
+// is only returned if the header that it removes is present in `current`.
+func diffHeaders(current, desired Headers) Headers {
+	result := make(Headers)
+	for k, v := range desired {
+		if strings.HasPrefix(k, "X-Remove-") {
+			if current.Get("X-"+strings.TrimPrefix(k, "X-Remove-")) != "" {
+				result[k] = v
+			}
+			continue
+		}
+		if current.Get(k) != v {
+			result.Set(k, v)
+		}
+	}
+	return result
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // specialized accessors on Headers subtypes that are not autogenerated
 
@@ -0,0 +1,106 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHeadersRawAll(t *testing.T) {
+	src := make(http.Header)
+	src.Add("Content-Type", "text/plain")
+	src.Add("X-Object-Meta-Tag", "foo")
+	src.Add("X-Object-Meta-Tag", "bar")
+	hdr := newObjectHeadersFromHTTP(src)
+	obj := &Object{}
+
+	expectRawAll := func(key string, expected ...string) {
+		t.Helper()
+		actual := obj.RawAll(key)
+		if len(actual) == 0 && len(expected) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected RawAll(%q) = %#v, got %#v", key, expected, actual)
+		}
+	}
+
+	// without cached headers, nothing is returned
+	expectRawAll("Content-Type")
+	obj.headers = &hdr
+	obj.rawHeaders = multiValuedHeaders(src)
+
+	// the simple API only sees the first value
+	expectString(t, "foo", hdr.Metadata().Get("Tag"))
+	expectRawAll("x-object-meta-tag", "foo", "bar")
+	expectRawAll("Content-Type", "text/plain")
+	expectRawAll("X-Object-Meta-Missing")
+
+	// multiple values are discarded when the value is changed
+	hdr.Metadata().Set("Tag", "qux")
+	expectRawAll("X-Object-Meta-Tag", "qux")
+	hdr.Metadata().Del("Tag")
+	expectRawAll("X-Object-Meta-Tag")
+
+	// Invalidate() discards the multiple values along with the cached headers
+	hdr.Metadata().Set("Tag", "foo")
+	expectRawAll("X-Object-Meta-Tag", "foo", "bar")
+	obj.Invalidate()
+	expectRawAll("X-Object-Meta-Tag")
+}
+
+func TestHeadersRemove(t *testing.T) {
+	h := make(Headers)
+	h.Remove("X-Container-Meta-Foo")
+	h.Remove("X-Container-Read")
+	h.Remove("X-Account-Access-Control") // Swift does not honor X-Remove-Account-Access-Control
+	h.Remove("X-Container-Meta-Quota-Bytes")
+	expectString(t, "1", h["X-Remove-Container-Meta-Foo"])
+	expectString(t, "1", h["X-Remove-Container-Read"])
+	expectString(t, "1", h["X-Remove-Container-Meta-Quota-Bytes"])
+	if value, exists := h["X-Account-Access-Control"]; !exists || value != "" {
+		t.Errorf("expected X-Account-Access-Control to be cleared, got %#v", h)
+	}
+	if _, exists := h["X-Remove-Account-Access-Control"]; exists {
+		t.Errorf("unexpected X-Remove-Account-Access-Control in %#v", h)
+	}
+}
+
+func TestDiffHeaders(t *testing.T) {
+	desired := make(Headers)
+	desired.Set("X-Container-Meta-Color", "blue")
+	desired.Remove("X-Container-Meta-Foo")
+	desired.Remove("X-Container-Read")
+
+	// removals are satisfied when the header is absent
+	current := Headers{"X-Container-Meta-Color": "blue"}
+	if diff := diffHeaders(current, desired); len(diff) != 0 {
+		t.Errorf("expected no difference, got %#v", diff)
+	}
+
+	// ...but not when it is present
+	current["X-Container-Read"] = ".r:*"
+	current["X-Container-Meta-Color"] = "red"
+	diff := diffHeaders(current, desired)
+	if len(diff) != 2 || diff["X-Remove-Container-Read"] != "1" || diff["X-Container-Meta-Color"] != "blue" {
+		t.Errorf("unexpected difference: %#v", diff)
+	}
+}
@@ -0,0 +1,127 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HealthStatus is returned by Account.Healthcheck().
+type HealthStatus struct {
+	// Result of GET /healthcheck. HealthcheckStatusCode is 0 if no response was
+	// received. HealthcheckMessage is the response body, usually "OK" or
+	// "DISABLED BY FILE".
+	HealthcheckStatusCode int
+	HealthcheckMessage    string
+	HealthcheckLatency    time.Duration
+	// Result of GET /info. InfoStatusCode is 0 if no response was received.
+	// Version is the Swift version reported in the capabilities, if any.
+	InfoStatusCode int
+	InfoLatency    time.Duration
+	Version        string
+}
+
+// Healthy returns whether both endpoints responded successfully. A 404
+// response from /healthcheck is tolerated since it only indicates that the
+// healthcheck middleware is not enabled on the proxy.
+func (s HealthStatus) Healthy() bool {
+	healthcheckOK := s.HealthcheckStatusCode == http.StatusOK || s.HealthcheckStatusCode == http.StatusNotFound
+	return healthcheckOK && s.InfoStatusCode == http.StatusOK
+}
+
+// Healthcheck probes the GET /healthcheck and GET /info endpoints of the Swift
+// server providing this account. This is intended for readiness probes of
+// services that depend on Swift. Neither request touches the account itself,
+// so they are cheap for the cluster. (Swift does not require authentication
+// for these endpoints, but like all other requests, they are sent through the
+// account's Backend, which usually adds its token anyway.) For example:
+//
+//	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+//		_, err := account.Healthcheck(r.Context(), 2*time.Second)
+//		if err != nil {
+//			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+//			return
+//		}
+//		w.Write([]byte("ok"))
+//	})
+//
+// Both requests together must complete within the given timeout. If the
+// timeout is zero, a default of 5 seconds is used. If the cluster is not
+// healthy according to HealthStatus.Healthy(), an error wrapping ErrUnhealthy
+// is returned. The HealthStatus is filled in as far as possible in any case.
+func (a *Account) Healthcheck(ctx context.Context, timeout time.Duration) (HealthStatus, error) {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var status HealthStatus
+	code, body, latency, err := a.probe(ctx, "healthcheck")
+	status.HealthcheckStatusCode = code
+	status.HealthcheckMessage = strings.TrimSpace(string(body))
+	status.HealthcheckLatency = latency
+	if err != nil {
+		return status, fmt.Errorf("%w: GET /healthcheck failed: %w", ErrUnhealthy, err)
+	}
+
+	code, body, latency, err = a.probe(ctx, "info")
+	status.InfoStatusCode = code
+	status.InfoLatency = latency
+	if err != nil {
+		return status, fmt.Errorf("%w: GET /info failed: %w", ErrUnhealthy, err)
+	}
+	if code == http.StatusOK {
+		var caps Capabilities
+		if json.Unmarshal(body, &caps) == nil {
+			status.Version = caps.Swift.Version
+		}
+	}
+
+	switch {
+	case status.HealthcheckStatusCode != http.StatusOK && status.HealthcheckStatusCode != http.StatusNotFound:
+		return status, fmt.Errorf("%w: GET /healthcheck returned %d %s",
+			ErrUnhealthy, status.HealthcheckStatusCode, status.HealthcheckMessage)
+	case status.InfoStatusCode != http.StatusOK:
+		return status, fmt.Errorf("%w: GET /info returned %d", ErrUnhealthy, status.InfoStatusCode)
+	}
+	return status, nil
+}
+
+// probe issues a GET request for a path next to the /info endpoint (see
+// Account.RawCapabilities), and returns status code, response body and
+// latency.
+func (a *Account) probe(ctx context.Context, path string) (int, []byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+path, http.NoBody)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	start := time.Now()
+	resp, err := a.backend.Do(req)
+	if err != nil {
+		return 0, nil, time.Since(start), err
+	}
+	body, err := collectResponseBody(resp)
+	return resp.StatusCode, body, time.Since(start), err
+}
@@ -0,0 +1,39 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthcheckTimeout(t *testing.T) {
+	account, err := InitializeAccount(&testBackend{handle: hangUntilCanceled})
+	must(t, err)
+
+	status, err := account.Healthcheck(context.TODO(), 10*time.Millisecond)
+	if !errors.Is(err, ErrUnhealthy) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected ErrUnhealthy and context.DeadlineExceeded, got %v", err)
+	}
+	if status.Healthy() || status.HealthcheckStatusCode != 0 {
+		t.Errorf("unexpected status: %#v", status)
+	}
+}
@@ -0,0 +1,184 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SetHold places a legal hold on this object by setting the
+// X-Object-Meta-Legal-Hold header to the given reason (e.g. a case number),
+// using a metadata-preserving POST request. The reason must not be empty.
+//
+// Swift does not have a native object lock, so the hold is purely a
+// convention: Only operations on an Account obtained from
+// Account.WithHoldEnforcement() (and on containers and objects below it)
+// refuse to modify or delete held objects. Other clients can still modify or
+// delete them. To restrict those as well, combine this with access control on
+// the server side (e.g. by only granting write access to a service user that
+// always uses WithHoldEnforcement()).
+//
+// Since the hold is stored in the object metadata, it is inherited by copies
+// made with Object.CopyTo() (unless CopyOptions.FreshMetadata is set), and
+// removed when the object is overwritten with OverrideHold.
+//
+// This operation fails with http.StatusNotFound if the object does not exist.
+//
+// A successful POST request implies Invalidate() since it may change metadata.
+func (o *Object) SetHold(ctx context.Context, reason string, opts *RequestOptions) error {
+	hdr := NewObjectHeaders()
+	hdr.LegalHold().Set(reason)
+	return o.UpdatePreservingMetadata(ctx, hdr, opts)
+}
+
+// ClearHold removes the legal hold that was placed on this object by
+// SetHold(), using a metadata-preserving POST request. Since this request
+// would otherwise be refused on an Account obtained from
+// Account.WithHoldEnforcement(), it is always sent with
+// RequestOptions.OverrideHold.
+//
+// This operation fails with http.StatusNotFound if the object does not exist.
+//
+// A successful POST request implies Invalidate() since it may change metadata.
+func (o *Object) ClearHold(ctx context.Context, opts *RequestOptions) error {
+	hdr := NewObjectHeaders()
+	hdr.LegalHold().Clear()
+	opts = cloneRequestOptions(opts, nil)
+	opts.OverrideHold = true
+	return o.UpdatePreservingMetadata(ctx, hdr, opts)
+}
+
+// WithHoldEnforcement returns a handle to the same account that refuses to
+// modify or delete objects that are on hold (see Object.SetHold()). Before
+// each PUT, POST, DELETE or COPY request on an object, a HEAD request is sent
+// to check whether the target object is on hold. If so, the request fails with
+// an ObjectOnHoldError without being sent. The following requests are
+// permitted on held objects nevertheless:
+//
+//   - POST requests that keep the hold in place by including the
+//     X-Object-Meta-Legal-Hold header (e.g. from Object.SetHold() or
+//     Object.UpdatePreservingMetadata()),
+//   - requests with RequestOptions.OverrideHold.
+//
+// The check is subject to a race condition: A hold that is placed concurrently
+// with a modification may not be observed. Also, bulk deletes (as in
+// Account.BulkDelete()) are not checked since the names of the deleted objects
+// are only contained in the request body.
+//
+// Hold enforcement carries over to accounts obtained from the returned Account
+// via SwitchAccount(), WithNewest(), ReadOnly() etc.
+func (a *Account) WithHoldEnforcement() *Account {
+	backend := a.backend
+	if _, ok := backend.(holdBackend); !ok {
+		backend = holdBackend{backend}
+	}
+	return a.withBackend(backend)
+}
+
+// overrideHoldKey is the context key that Request.Do() uses to forward
+// RequestOptions.OverrideHold to the holdBackend.
+type overrideHoldKey struct{}
+
+// holdBackend wraps a Backend to reject requests that would modify held
+// objects. It is used by Account.WithHoldEnforcement().
+type holdBackend struct {
+	inner Backend
+}
+
+func (b holdBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b holdBackend) Clone(newEndpointURL string) Backend {
+	return holdBackend{b.inner.Clone(newEndpointURL)}
+}
+
+func (b holdBackend) Do(req *http.Request) (*http.Response, error) {
+	err := b.check(req)
+	if err != nil {
+		// like http.Client.Do(), we are responsible for closing the request body
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return b.inner.Do(req)
+}
+
+func (b holdBackend) check(req *http.Request) error {
+	switch req.Method {
+	case http.MethodPut, http.MethodPost, http.MethodDelete, "COPY":
+	default:
+		return nil
+	}
+	if override, _ := req.Context().Value(overrideHoldKey{}).(bool); override {
+		return nil
+	}
+
+	endpointURL, err := url.Parse(b.inner.EndpointURL())
+	if err != nil {
+		return err
+	}
+	rest, ok := strings.CutPrefix(req.URL.Path, endpointURL.Path)
+	if !ok {
+		return nil
+	}
+	containerName, objectName, _ := strings.Cut(rest, "/")
+	if objectName == "" {
+		return nil // not an object request
+	}
+
+	accountName := ""
+	if req.Method == "COPY" {
+		// the source is not modified, but the destination is
+		fullName := strings.TrimPrefix(req.Header.Get("Destination"), "/")
+		containerName, objectName, _ = strings.Cut(fullName, "/")
+		accountName = req.Header.Get("Destination-Account")
+	}
+	if req.Method == http.MethodPost && req.Header.Get("X-Object-Meta-Legal-Hold") != "" {
+		return nil // request keeps the hold in place
+	}
+
+	a, err := InitializeAccount(b.inner)
+	if err != nil {
+		return err
+	}
+	if accountName != "" && accountName != a.name {
+		a = a.SwitchAccount(accountName)
+	}
+	hdr, err := a.Container(containerName).Object(objectName).fetchHeaders(req.Context(), &RequestOptions{Newest: true})
+	if Is(err, http.StatusNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	reason := hdr.LegalHold().Get()
+	if reason == "" {
+		return nil
+	}
+	return ObjectOnHoldError{
+		Method: req.Method,
+		Target: describeTarget(containerName, objectName),
+		Reason: reason,
+	}
+}
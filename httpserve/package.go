@@ -0,0 +1,172 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package httpserve contains an http.Handler that serves objects from a Swift
+container. For example:
+
+	import (
+		"net/http"
+		"github.com/majewsky/schwift/v2/httpserve"
+	)
+
+	container := account.Container("assets")
+	mux := http.NewServeMux()
+	mux.Handle("/assets/", http.StripPrefix("/assets/", &httpserve.Handler{
+		Container: container,
+	}))
+
+Conditional requests (If-None-Match, If-Modified-Since etc.) and range requests
+are forwarded to Swift, so that the response status (e.g. 304 or 206) and body
+are produced by Swift itself. Object contents are streamed to the client
+without buffering.
+*/
+package httpserve
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// Handler is an http.Handler that serves objects from a Swift container. The
+// request path (without leading slash) is used as object name. Only GET and
+// HEAD requests are supported.
+type Handler struct {
+	Container *schwift.Container
+	// If Prefix is set, it is prepended to the request path to form the object
+	// name. For example, with Prefix = "public/", a request for "/index.html"
+	// serves the object "public/index.html".
+	Prefix string
+	// If TempURLKey is set, objects whose size is at least TempURLThreshold
+	// bytes are not served directly. Instead, the client is redirected to a
+	// temporary URL (see schwift.Object.TempURL) that is signed with this key
+	// and valid for TempURLLifetime (default: 10 minutes). This requires one
+	// additional HEAD request per client request, but takes the load of
+	// streaming large objects off this process.
+	TempURLKey       string
+	TempURLThreshold uint64
+	TempURLLifetime  time.Duration
+}
+
+// These request headers are forwarded to Swift.
+var forwardedRequestHeaders = []string{
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"If-Unmodified-Since",
+	"Range",
+}
+
+// These response headers are forwarded from Swift to the client.
+var forwardedResponseHeaders = []string{
+	"Accept-Ranges",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"Etag",
+	"Expires",
+	"Last-Modified",
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	objectName := h.Prefix + strings.TrimPrefix(r.URL.Path, "/")
+	if objectName == "" {
+		http.NotFound(w, r)
+		return
+	}
+	obj := h.Container.Object(objectName)
+
+	// redirect to temp URL for large objects, if desired
+	if h.TempURLKey != "" {
+		hdr, err := obj.Headers(r.Context())
+		switch {
+		case schwift.Is(err, http.StatusNotFound):
+			http.NotFound(w, r)
+			return
+		case err != nil:
+			http.Error(w, "cannot read object from Swift", http.StatusBadGateway)
+			return
+		case hdr.SizeBytes().Get() >= h.TempURLThreshold:
+			lifetime := h.TempURLLifetime
+			if lifetime == 0 {
+				lifetime = 10 * time.Minute
+			}
+			tempURL, err := obj.TempURL(r.Context(), h.TempURLKey, r.Method, time.Now().Add(lifetime))
+			if err != nil {
+				http.Error(w, "cannot generate temporary URL", http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, tempURL, http.StatusTemporaryRedirect)
+			return
+		}
+	}
+
+	// forward request to Swift
+	hdr := make(schwift.Headers)
+	for _, key := range forwardedRequestHeaders {
+		if value := r.Header.Get(key); value != "" {
+			hdr.Set(key, value)
+		}
+	}
+	resp, err := schwift.Request{
+		Method:        r.Method,
+		ContainerName: h.Container.Name(),
+		ObjectName:    objectName,
+		Options:       hdr.ToOpts(),
+	}.Do(r.Context(), h.Container.Account().Backend())
+	if err != nil {
+		http.Error(w, "cannot read object from Swift", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified,
+		http.StatusPreconditionFailed, http.StatusRequestedRangeNotSatisfiable:
+		// forward response below
+	case http.StatusNotFound:
+		http.NotFound(w, r)
+		return
+	default:
+		http.Error(w, "unexpected response from Swift", http.StatusBadGateway)
+		return
+	}
+
+	for _, key := range forwardedResponseHeaders {
+		if value := resp.Header.Get(key); value != "" {
+			w.Header().Set(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodGet {
+		_, _ = io.Copy(w, resp.Body) //nolint:errcheck // nothing we can do at this point
+	}
+}
@@ -0,0 +1,148 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package fakeswift
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func isSettableAccountHeader(key string) bool {
+	return strings.HasPrefix(key, "X-Account-Meta-") || key == "X-Account-Access-Control"
+}
+
+func (a *account) counts() (objectCount, bytesUsed uint64) {
+	for _, c := range a.containers {
+		oc, bu := c.counts()
+		objectCount += oc
+		bytesUsed += bu
+	}
+	return
+}
+
+func (c *cluster) serveAccount(w http.ResponseWriter, r *http.Request, a *account) {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		hdr := w.Header()
+		for key, values := range a.headers {
+			hdr[key] = values
+		}
+		objectCount, bytesUsed := a.counts()
+		hdr.Set("X-Account-Container-Count", strconv.Itoa(len(a.containers)))
+		hdr.Set("X-Account-Object-Count", strconv.FormatUint(objectCount, 10))
+		hdr.Set("X-Account-Bytes-Used", strconv.FormatUint(bytesUsed, 10))
+		hdr.Set("X-Timestamp", formatTimestamp(a.createdAt))
+		hdr.Set("Last-Modified", formatLastModified(a.createdAt))
+		if len(a.containers) > 0 {
+			// all containers use the "default" policy
+			hdr.Set("X-Account-Storage-Policy-Default-Container-Count", strconv.Itoa(len(a.containers)))
+			hdr.Set("X-Account-Storage-Policy-Default-Object-Count", strconv.FormatUint(objectCount, 10))
+			hdr.Set("X-Account-Storage-Policy-Default-Bytes-Used", strconv.FormatUint(bytesUsed, 10))
+		}
+		if r.Method == http.MethodHead {
+			hdr.Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.serveContainerListing(w, r, a)
+
+	case http.MethodPost:
+		applyHeaders(a.headers, r.Header, isSettableAccountHeader)
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodPut:
+		if query.Has("extract-archive") {
+			c.serveExtractArchive(w, r, a, "", "")
+			return
+		}
+		applyHeaders(a.headers, r.Header, isSettableAccountHeader)
+		w.WriteHeader(http.StatusAccepted)
+
+	case http.MethodDelete:
+		if query.Has("bulk-delete") {
+			c.serveBulkDelete(w, r, a)
+			return
+		}
+		writeError(w, http.StatusMethodNotAllowed)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *cluster) serveContainerListing(w http.ResponseWriter, r *http.Request, a *account) {
+	names := make([]string, 0, len(a.containers))
+	for name := range a.containers {
+		names = append(names, name)
+	}
+	entries := listNames(names, r)
+
+	format := listingFormat(r)
+	if format == "plain" {
+		writePlainListing(w, entries)
+		return
+	}
+	type containerInfo struct {
+		Name          string `json:"name,omitempty"`
+		Count         uint64 `json:"count"`
+		Bytes         uint64 `json:"bytes"`
+		LastModified  string `json:"last_modified,omitempty"`
+		StoragePolicy string `json:"storage_policy,omitempty"`
+		Subdir        string `json:"subdir,omitempty"`
+	}
+	result := make([]containerInfo, len(entries))
+	for idx, e := range entries {
+		if e.isSubdir {
+			result[idx] = containerInfo{Subdir: e.name}
+			continue
+		}
+		cont := a.containers[e.name]
+		objectCount, bytesUsed := cont.counts()
+		result[idx] = containerInfo{
+			Name:         e.name,
+			Count:        objectCount,
+			Bytes:        bytesUsed,
+			LastModified: formatListingTimestamp(cont.updatedAt),
+			// like the X-Storage-Policy header, see container.go
+			StoragePolicy: "default",
+		}
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	xmlEntries := make([]xmlListingEntry, len(result))
+	for idx, info := range result {
+		if info.Subdir != "" {
+			xmlEntries[idx] = xmlSubdirEntry(info.Subdir)
+			continue
+		}
+		xmlEntries[idx] = xmlListingEntry{kind: "container", fields: [][2]string{
+			{"name", info.Name},
+			{"count", strconv.FormatUint(info.Count, 10)},
+			{"bytes", strconv.FormatUint(info.Bytes, 10)},
+			{"last_modified", info.LastModified},
+			{"storage_policy", info.StoragePolicy},
+		}}
+	}
+	writeXMLListing(w, "account", a.name, xmlEntries)
+}
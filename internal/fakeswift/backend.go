@@ -0,0 +1,120 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+// Package fakeswift contains an in-memory implementation of the Swift API that
+// can be used as a schwift.Backend. It is used by Schwift's own tests and
+// benchmarks when no real Swift cluster is available.
+//
+// Only the parts of the Swift API that are used by Schwift are implemented,
+// and authentication is not checked at all. Behavior follows real Swift as
+// closely as reasonably possible, but the fake is not a reference
+// implementation; when in doubt, test against a real Swift.
+package fakeswift
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// BaseURL is the URL of the fake Swift server. Endpoint URLs for accounts are
+// below BaseURL + "v1/".
+const BaseURL = "https://swift.example.com/"
+
+// Backend implements schwift.Backend for one account in a fake Swift cluster.
+// All backends obtained from the same New() call (through Clone()) share the
+// same cluster state.
+type Backend struct {
+	c           *cluster
+	endpointURL string
+	// If not nil, Hook is called for every request before it is executed.
+	// If it returns a non-nil response or error, that is returned from Do()
+	// instead of executing the request.
+	Hook func(*http.Request) (*http.Response, error)
+}
+
+// New creates a new fake Swift cluster, and returns a Backend for the account
+// "AUTH_test" in it.
+func New() *Backend {
+	return &Backend{
+		c:           newCluster(),
+		endpointURL: BaseURL + "v1/AUTH_test/",
+	}
+}
+
+// NewAccount is a shorthand for New() followed by schwift.InitializeAccount().
+func NewAccount() *schwift.Account {
+	a, err := schwift.InitializeAccount(New())
+	if err != nil {
+		// cannot happen since we control the endpoint URL
+		panic(err.Error())
+	}
+	return a
+}
+
+// EndpointURL implements the schwift.Backend interface.
+func (b *Backend) EndpointURL() string {
+	return b.endpointURL
+}
+
+// Clone implements the schwift.Backend interface.
+func (b *Backend) Clone(newEndpointURL string) schwift.Backend {
+	return &Backend{c: b.c, endpointURL: newEndpointURL, Hook: b.Hook}
+}
+
+// Do implements the schwift.Backend interface.
+func (b *Backend) Do(req *http.Request) (*http.Response, error) {
+	if b.Hook != nil {
+		resp, err := b.Hook(req)
+		if resp != nil || err != nil {
+			return resp, err
+		}
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	// like a real server, the handler shall always see a non-nil request body
+	if req.Body == nil {
+		req = req.Clone(req.Context())
+		req.Body = http.NoBody
+	} else if req.Body != http.NoBody {
+		// read the request body before locking the cluster, since producing the
+		// body may involve further requests (e.g. in LargeObject.Repack())
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	rec := httptest.NewRecorder()
+	b.c.ServeHTTP(rec, req)
+
+	resp := rec.Result()
+	resp.Request = req
+	if req.Method == http.MethodHead {
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
@@ -0,0 +1,195 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package fakeswift
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// bulkResponse is the response body for bulk deletes and bulk uploads.
+type bulkResponse struct {
+	ResponseStatus     string     `json:"Response Status"`
+	ResponseBody       string     `json:"Response Body"`
+	Errors             [][]string `json:"Errors"`
+	NumberFilesCreated int        `json:"Number Files Created,omitempty"`
+	NumberDeleted      int        `json:"Number Deleted,omitempty"`
+	NumberNotFound     int        `json:"Number Not Found,omitempty"`
+}
+
+func (c *cluster) serveBulkDelete(w http.ResponseWriter, r *http.Request, a *account) {
+	result := bulkResponse{Errors: [][]string{}}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		path, err := url.PathUnescape(line)
+		if err != nil {
+			result.Errors = append(result.Errors, []string{line, formatStatus(http.StatusBadRequest)})
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+
+		if len(fields) == 2 {
+			// delete object
+			if c.findObject(a, fields[0], fields[1]) == nil {
+				result.NumberNotFound++
+				continue
+			}
+			cont := a.containers[fields[0]]
+			delete(cont.objects, fields[1])
+			cont.updatedAt = c.now()
+			result.NumberDeleted++
+			continue
+		}
+
+		// delete container
+		cont := a.containers[fields[0]]
+		switch {
+		case cont == nil:
+			result.NumberNotFound++
+		case len(cont.objects) > 0:
+			result.Errors = append(result.Errors, []string{fields[0], formatStatus(http.StatusConflict)})
+		default:
+			delete(a.containers, fields[0])
+			result.NumberDeleted++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		result.ResponseStatus = formatStatus(http.StatusBadRequest)
+	} else {
+		result.ResponseStatus = formatStatus(http.StatusOK)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (c *cluster) serveExtractArchive(w http.ResponseWriter, r *http.Request, a *account, containerName, objectPrefix string) {
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch r.URL.Query().Get("extract-archive") {
+	case "tar":
+		reader = r.Body
+	case "tar.gz":
+		reader, err = gzip.NewReader(r.Body)
+	case "tar.bz2":
+		reader = bzip2.NewReader(r.Body)
+	default:
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+
+	result := bulkResponse{Errors: [][]string{}}
+	if err == nil {
+		err = c.extractTar(a, tar.NewReader(reader), containerName, objectPrefix, &result)
+	}
+	switch {
+	case err != nil:
+		result.ResponseStatus = formatStatus(http.StatusBadRequest)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// match the error message from Python's tarfile module
+			result.ResponseBody = "Invalid Tar File: truncated header"
+		} else {
+			result.ResponseBody = "Invalid Tar File: " + err.Error()
+		}
+	case len(result.Errors) > 0:
+		result.ResponseStatus = formatStatus(http.StatusBadRequest)
+	default:
+		result.ResponseStatus = formatStatus(http.StatusCreated)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (c *cluster) extractTar(a *account, tr *tar.Reader, containerName, objectPrefix string, result *bulkResponse) error {
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// determine target location of this file
+		path := strings.TrimPrefix(strings.TrimPrefix(hdr.Name, "./"), "/")
+		targetContainer, targetObject := containerName, objectPrefix+path
+		if containerName == "" {
+			fields := strings.SplitN(path, "/", 2)
+			if len(fields) < 2 {
+				result.Errors = append(result.Errors, []string{path, formatStatus(http.StatusBadRequest)})
+				continue
+			}
+			targetContainer, targetObject = fields[0], fields[1]
+		}
+		fullName := targetContainer + "/" + targetObject
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if len(targetObject) > maxObjectNameLength {
+			result.Errors = append(result.Errors, []string{fullName, formatStatus(http.StatusBadRequest)})
+			continue
+		}
+		cont := a.containers[targetContainer]
+		if cont == nil {
+			if containerName != "" {
+				result.Errors = append(result.Errors, []string{fullName, formatStatus(http.StatusNotFound)})
+				continue
+			}
+			cont = c.newContainer(a, targetContainer)
+		}
+
+		o := &object{
+			name:      targetObject,
+			headers:   make(http.Header),
+			content:   content,
+			createdAt: c.now(),
+		}
+		o.headers.Set("Content-Type", "application/octet-stream")
+		o.headers.Set("Etag", etagOf(content))
+		cont.objects[targetObject] = o
+		cont.updatedAt = o.createdAt
+		result.NumberFilesCreated++
+	}
+}
+
+// formatStatus formats a status code like "201 Created".
+func formatStatus(code int) string {
+	return strconv.Itoa(code) + " " + http.StatusText(code)
+}
@@ -0,0 +1,412 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package fakeswift
+
+import (
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type cluster struct {
+	mutex    sync.Mutex
+	accounts map[string]*account
+	// lastTimestamp is used to ensure that timestamps are strictly increasing
+	lastTimestamp time.Time
+	// requestCount is used to generate transaction IDs
+	requestCount uint64
+}
+
+type account struct {
+	name       string
+	headers    http.Header
+	containers map[string]*container
+	createdAt  time.Time
+}
+
+type container struct {
+	account   *account
+	name      string
+	headers   http.Header
+	objects   map[string]*object
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type object struct {
+	name    string
+	headers http.Header
+	content []byte
+	// for static large objects, `content` is empty and `segments` contains the
+	// manifest
+	segments     []sloSegment
+	sloSizeBytes int
+	createdAt    time.Time
+}
+
+func newCluster() *cluster {
+	return &cluster{accounts: make(map[string]*account)}
+}
+
+// now returns the current time, but ensures that successive timestamps are
+// distinct. Must be called with c.mutex locked.
+func (c *cluster) now() time.Time {
+	t := time.Now().UTC().Truncate(10 * time.Microsecond)
+	if !t.After(c.lastTimestamp) {
+		t = c.lastTimestamp.Add(10 * time.Microsecond)
+	}
+	c.lastTimestamp = t
+	return t
+}
+
+// getAccount returns the account with this name, creating it if necessary
+// (i.e. account_autocreate is enabled). Must be called with c.mutex locked.
+func (c *cluster) getAccount(name string) *account {
+	a := c.accounts[name]
+	if a == nil {
+		a = &account{
+			name:       name,
+			headers:    make(http.Header),
+			containers: make(map[string]*container),
+			createdAt:  c.now(),
+		}
+		c.accounts[name] = a
+	}
+	return a
+}
+
+// ServeHTTP is like http.Handler.ServeHTTP.
+func (c *cluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// like Swift, generate a transaction ID and append the client-supplied
+	// X-Trans-Id-Extra to it
+	c.requestCount++
+	transID := fmt.Sprintf("tx%021x-%010x", c.requestCount, time.Now().Unix())
+	if extra := r.Header.Get("X-Trans-Id-Extra"); extra != "" {
+		transID += "-" + extra[:min(len(extra), 32)]
+	}
+	w.Header().Set("X-Trans-Id", transID)
+	w.Header().Set("X-Openstack-Request-Id", transID)
+
+	if r.URL.Path == "/info" {
+		c.serveInfo(w, r)
+		return
+	}
+	if r.URL.Path == "/healthcheck" {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK")) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+		return
+	}
+
+	fields := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 4)
+	if len(fields) < 2 || fields[0] != "v1" || fields[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	a := c.getAccount(fields[1])
+	switch {
+	case len(fields) == 2 || (len(fields) == 3 && fields[2] == ""):
+		c.serveAccount(w, r, a)
+	case len(fields) == 3 || (len(fields) == 4 && fields[3] == ""):
+		c.serveContainer(w, r, a, fields[2])
+	default:
+		c.serveObject(w, r, a, fields[2], fields[3])
+	}
+}
+
+func (c *cluster) serveInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, capabilities)
+}
+
+var capabilities = map[string]any{
+	"bulk_delete": map[string]any{
+		"max_deletes_per_request": 10000,
+		"max_failed_deletes":      1000,
+	},
+	"bulk_upload": map[string]any{
+		"max_containers_per_extraction": 10000,
+		"max_failed_extractions":        1000,
+	},
+	"slo": map[string]any{
+		"max_manifest_segments": 1000,
+		"max_manifest_size":     8388608,
+		"min_segment_size":      1,
+	},
+	"swift": map[string]any{
+		"account_autocreate":      true,
+		"account_listing_limit":   listingLimit,
+		"container_listing_limit": listingLimit,
+		"max_file_size":           5368709122,
+		"max_meta_count":          90,
+		"max_meta_name_length":    128,
+		"max_meta_value_length":   256,
+		"max_object_name_length":  maxObjectNameLength,
+		"policies":                []map[string]any{{"name": "default", "default": true}},
+		"version":                 "fakeswift",
+	},
+	"symlink": map[string]any{
+		"symloop_max": 2,
+	},
+	"tempurl": map[string]any{
+		"allowed_digests": []string{"sha1", "sha256", "sha512"},
+		"methods":         []string{"GET", "HEAD", "PUT", "POST", "DELETE"},
+	},
+}
+
+const (
+	// listingLimit is the maximum number of items returned in a single listing.
+	listingLimit = 10000
+	// maxObjectNameLength is the maximum length of an object name in bytes.
+	maxObjectNameLength = 1024
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// helper functions
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(status)
+	w.Write(buf) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+}
+
+func writeError(w http.ResponseWriter, status int) {
+	var body string
+	switch status {
+	case http.StatusNotFound:
+		body = "<html><h1>Not Found</h1><p>The resource could not be found.</p></html>"
+	default:
+		body = "<html><h1>" + http.StatusText(status) + "</h1></html>"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	io.WriteString(w, body) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+}
+
+func etagOf(buf []byte) string {
+	sum := md5.Sum(buf) //nolint:gosec // Etag uses md5
+	return hex.EncodeToString(sum[:])
+}
+
+func formatTimestamp(t time.Time) string {
+	return fmt.Sprintf("%d.%05d", t.Unix(), t.Nanosecond()/10000)
+}
+
+// formatLastModified formats the Last-Modified header. Like Swift, we round
+// up to the next full second.
+func formatLastModified(t time.Time) string {
+	return ceilToSecond(t).Format(http.TimeFormat)
+}
+
+func ceilToSecond(t time.Time) time.Time {
+	truncated := t.Truncate(time.Second)
+	if truncated.Equal(t) {
+		return t
+	}
+	return truncated.Add(time.Second)
+}
+
+func formatListingTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000")
+}
+
+// Applies the metadata-like headers from the request to the stored headers.
+// Headers with an empty value are deleted, and "X-Remove-$PREFIX-Foo" headers
+// cause "X-$PREFIX-Foo" to be deleted.
+func applyHeaders(stored, req http.Header, isSettable func(string) bool) {
+	for key, values := range req {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+		if strings.HasPrefix(key, "X-Remove-") {
+			key = "X-" + strings.TrimPrefix(key, "X-Remove-")
+			value = ""
+		}
+		if !isSettable(key) {
+			continue
+		}
+		if value == "" {
+			stored.Del(key)
+		} else {
+			stored.Set(key, value)
+		}
+	}
+}
+
+// listingEntry is an entry in a container or object listing. For listings
+// with a delimiter, pseudo-directories are reported with isSubdir = true.
+type listingEntry struct {
+	name     string
+	isSubdir bool
+}
+
+// listNames returns those of the given names that match the listing query in
+// the given request.
+func listNames(names []string, r *http.Request) []listingEntry {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	marker := query.Get("marker")
+	endMarker := query.Get("end_marker")
+	reverse := query.Get("reverse") == "true"
+	limit := listingLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		val, err := strconv.Atoi(limitStr)
+		if err == nil && val >= 0 && val < limit {
+			limit = val
+		}
+	}
+
+	sort.Strings(names)
+	if reverse {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+	}
+
+	var result []listingEntry
+	for _, name := range names {
+		if len(result) >= limit {
+			break
+		}
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if marker != "" && ((!reverse && name <= marker) || (reverse && name >= marker)) {
+			continue
+		}
+		if endMarker != "" && ((!reverse && name >= endMarker) || (reverse && name <= endMarker)) {
+			continue
+		}
+		if delimiter != "" {
+			idx := strings.Index(name[len(prefix):], delimiter)
+			if idx >= 0 {
+				subdir := name[:len(prefix)+idx+len(delimiter)]
+				if subdir == marker {
+					continue
+				}
+				if len(result) > 0 && result[len(result)-1].name == subdir {
+					continue
+				}
+				result = append(result, listingEntry{subdir, true})
+				continue
+			}
+		}
+		result = append(result, listingEntry{name, false})
+	}
+	return result
+}
+
+// listingFormat returns "json", "xml" or "plain", depending on which format
+// the client requested for a listing.
+func listingFormat(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case "json", "xml":
+		return format
+	case "":
+		accept := r.Header.Get("Accept")
+		switch {
+		case strings.Contains(accept, "application/json"):
+			return "json"
+		case strings.Contains(accept, "application/xml"), strings.Contains(accept, "text/xml"):
+			return "xml"
+		}
+	}
+	return "plain"
+}
+
+func writePlainListing(w http.ResponseWriter, entries []listingEntry) {
+	if len(entries) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	var buf strings.Builder
+	for _, e := range entries {
+		buf.WriteString(e.name)
+		buf.WriteByte('\n')
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, buf.String()) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+}
+
+// xmlListingEntry is an entry in a listing in XML format. It is rendered as an
+// element of the given kind with one child element per field. Like Swift,
+// pseudo-directories are rendered as <subdir name="..."><name>...</name></subdir>.
+type xmlListingEntry struct {
+	kind   string
+	fields [][2]string
+}
+
+func xmlSubdirEntry(name string) xmlListingEntry {
+	return xmlListingEntry{kind: "subdir", fields: [][2]string{{"name", name}}}
+}
+
+func writeXMLListing(w http.ResponseWriter, rootKind, rootName string, entries []xmlListingEntry) {
+	escape := func(s string) string {
+		var buf strings.Builder
+		xml.EscapeText(&buf, []byte(s)) //nolint:errcheck // cannot fail on strings.Builder
+		return buf.String()
+	}
+
+	var buf strings.Builder
+	buf.WriteString(xml.Header)
+	fmt.Fprintf(&buf, "<%s name=\"%s\">", rootKind, escape(rootName))
+	for _, e := range entries {
+		if e.kind == "subdir" {
+			fmt.Fprintf(&buf, "<subdir name=\"%s\">", escape(e.fields[0][1]))
+		} else {
+			fmt.Fprintf(&buf, "<%s>", e.kind)
+		}
+		for _, f := range e.fields {
+			fmt.Fprintf(&buf, "<%s>%s</%s>", f[0], escape(f[1]), f[0])
+		}
+		fmt.Fprintf(&buf, "</%s>", e.kind)
+	}
+	fmt.Fprintf(&buf, "</%s>", rootKind)
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, buf.String()) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+}
@@ -0,0 +1,201 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package fakeswift
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func isSettableContainerHeader(key string) bool {
+	switch key {
+	case "X-Container-Read", "X-Container-Write", "X-Container-Sync-To", "X-Container-Sync-Key",
+		"X-Versions-Location", "X-History-Location":
+		return true
+	default:
+		return strings.HasPrefix(key, "X-Container-Meta-")
+	}
+}
+
+func (cont *container) counts() (objectCount, bytesUsed uint64) {
+	for _, o := range cont.objects {
+		objectCount++
+		bytesUsed += uint64(o.sizeBytes())
+	}
+	return
+}
+
+// newContainer creates a container in the given account. Must be called with
+// c.mutex locked.
+func (c *cluster) newContainer(a *account, name string) *container {
+	now := c.now()
+	cont := &container{
+		account:   a,
+		name:      name,
+		headers:   make(http.Header),
+		objects:   make(map[string]*object),
+		createdAt: now,
+		updatedAt: now,
+	}
+	a.containers[name] = cont
+	return cont
+}
+
+func (c *cluster) serveContainer(w http.ResponseWriter, r *http.Request, a *account, containerName string) {
+	if r.Method == http.MethodPut && r.URL.Query().Has("extract-archive") {
+		c.serveExtractArchive(w, r, a, containerName, "")
+		return
+	}
+
+	cont := a.containers[containerName]
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		if cont == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		hdr := w.Header()
+		for key, values := range cont.headers {
+			hdr[key] = values
+		}
+		objectCount, bytesUsed := cont.counts()
+		hdr.Set("X-Container-Object-Count", strconv.FormatUint(objectCount, 10))
+		hdr.Set("X-Container-Bytes-Used", strconv.FormatUint(bytesUsed, 10))
+		hdr.Set("X-Storage-Policy", "default")
+		hdr.Set("X-Timestamp", formatTimestamp(cont.createdAt))
+		hdr.Set("Last-Modified", formatLastModified(cont.createdAt))
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		c.serveObjectListing(w, r, cont)
+
+	case http.MethodPut:
+		status := http.StatusAccepted
+		if cont == nil {
+			cont = c.newContainer(a, containerName)
+			status = http.StatusCreated
+		}
+		applyHeaders(cont.headers, r.Header, isSettableContainerHeader)
+		w.WriteHeader(status)
+
+	case http.MethodPost:
+		if cont == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		applyHeaders(cont.headers, r.Header, isSettableContainerHeader)
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodDelete:
+		switch {
+		case cont == nil:
+			writeError(w, http.StatusNotFound)
+		case len(cont.objects) > 0:
+			writeError(w, http.StatusConflict)
+		default:
+			delete(a.containers, containerName)
+			w.WriteHeader(http.StatusNoContent)
+		}
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *cluster) serveObjectListing(w http.ResponseWriter, r *http.Request, cont *container) {
+	now := c.now()
+	names := make([]string, 0, len(cont.objects))
+	for name, o := range cont.objects {
+		if !o.isExpired(now) {
+			names = append(names, name)
+		}
+	}
+	entries := listNames(names, r)
+
+	format := listingFormat(r)
+	if format == "plain" {
+		writePlainListing(w, entries)
+		return
+	}
+	type objectInfo struct {
+		Name         string `json:"name,omitempty"`
+		Bytes        int    `json:"bytes"`
+		Hash         string `json:"hash,omitempty"`
+		ContentType  string `json:"content_type,omitempty"`
+		LastModified string `json:"last_modified,omitempty"`
+		SymlinkPath  string `json:"symlink_path,omitempty"`
+		SLOEtag      string `json:"slo_etag,omitempty"`
+		Subdir       string `json:"subdir,omitempty"`
+	}
+	result := make([]objectInfo, len(entries))
+	for idx, e := range entries {
+		if e.isSubdir {
+			result[idx] = objectInfo{Subdir: e.name}
+			continue
+		}
+		o := cont.objects[e.name]
+		info := objectInfo{
+			Name:         e.name,
+			Bytes:        o.sizeBytes(),
+			Hash:         strings.Trim(o.headers.Get("Etag"), `"`),
+			ContentType:  o.headers.Get("Content-Type"),
+			LastModified: formatListingTimestamp(o.createdAt),
+		}
+		if target := o.headers.Get("X-Symlink-Target"); target != "" {
+			account := o.headers.Get("X-Symlink-Target-Account")
+			if account == "" {
+				account = cont.account.name
+			}
+			info.SymlinkPath = "/v1/" + account + "/" + target
+		}
+		if o.segments != nil {
+			info.SLOEtag = o.headers.Get("Etag") // like Swift, with quotes
+		}
+		result[idx] = info
+	}
+	if format == "json" {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	xmlEntries := make([]xmlListingEntry, len(result))
+	for idx, info := range result {
+		if info.Subdir != "" {
+			xmlEntries[idx] = xmlSubdirEntry(info.Subdir)
+			continue
+		}
+		fields := [][2]string{
+			{"name", info.Name},
+			{"hash", info.Hash},
+			{"bytes", strconv.Itoa(info.Bytes)},
+			{"content_type", info.ContentType},
+			{"last_modified", info.LastModified},
+		}
+		if info.SymlinkPath != "" {
+			fields = append(fields, [2]string{"symlink_path", info.SymlinkPath})
+		}
+		if info.SLOEtag != "" {
+			fields = append(fields, [2]string{"slo_etag", info.SLOEtag})
+		}
+		xmlEntries[idx] = xmlListingEntry{kind: "object", fields: fields}
+	}
+	writeXMLListing(w, "container", cont.name, xmlEntries)
+}
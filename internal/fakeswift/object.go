@@ -0,0 +1,713 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package fakeswift
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sloSegment is an entry in an SLO manifest, as it appears in the request
+// body of a "PUT ?multipart-manifest=put" and in the response body of a
+// "GET ?multipart-manifest=get&format=raw".
+type sloSegment struct {
+	Path       string `json:"path,omitempty"`
+	Etag       string `json:"etag,omitempty"`
+	SizeBytes  uint64 `json:"size_bytes,omitempty"`
+	Range      string `json:"range,omitempty"`
+	DataBase64 string `json:"data,omitempty"`
+}
+
+// symloopMax is the maximum number of symlinks that are followed in a row.
+const symloopMax = 2
+
+func isSettableObjectHeader(key string) bool {
+	switch key {
+	case "Content-Type", "Content-Disposition", "Content-Encoding", "X-Delete-At":
+		return true
+	default:
+		return strings.HasPrefix(key, "X-Object-Meta-")
+	}
+}
+
+// sizeBytes returns the size of the object's content. For static large
+// objects, this is the sum of the sizes of the (ranges of the) segments.
+func (o *object) sizeBytes() int {
+	if o.segments == nil {
+		return len(o.content)
+	}
+	return o.sloSizeBytes
+}
+
+// isExpired checks whether the object has an X-Delete-At in the past.
+func (o *object) isExpired(now time.Time) bool {
+	deleteAt := o.headers.Get("X-Delete-At")
+	if deleteAt == "" {
+		return false
+	}
+	ts, err := strconv.ParseInt(deleteAt, 10, 64)
+	return err == nil && !now.Before(time.Unix(ts, 0))
+}
+
+// findObject returns the object with the given name, or nil if it does not
+// exist (or has expired). Must be called with c.mutex locked.
+func (c *cluster) findObject(a *account, containerName, objectName string) *object {
+	cont := a.containers[containerName]
+	if cont == nil {
+		return nil
+	}
+	o := cont.objects[objectName]
+	if o == nil {
+		return nil
+	}
+	if o.isExpired(c.now()) {
+		delete(cont.objects, objectName)
+		cont.updatedAt = c.now()
+		return nil
+	}
+	return o
+}
+
+// findByPath is like findObject, but takes a path of the form
+// "container/object" (with or without leading slash) as used in manifests and
+// symlink targets.
+func (c *cluster) findByPath(a *account, path string) *object {
+	fields := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(fields) < 2 {
+		return nil
+	}
+	return c.findObject(a, fields[0], fields[1])
+}
+
+// resolveSymlinks follows symlinks starting at the given object. Returns nil
+// if a symlink target does not exist or if there are too many symlinks.
+func (c *cluster) resolveSymlinks(a *account, o *object) *object {
+	for range symloopMax + 1 {
+		target := o.headers.Get("X-Symlink-Target")
+		if target == "" {
+			return o
+		}
+		if accountName := o.headers.Get("X-Symlink-Target-Account"); accountName != "" {
+			a = c.getAccount(accountName)
+		}
+		o = c.findByPath(a, target)
+		if o == nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// contentOf returns the content of the given object. For large objects, the
+// segments are assembled. Returns false if a segment is missing.
+func (c *cluster) contentOf(a *account, o *object) ([]byte, string, bool) {
+	if o.segments != nil {
+		var (
+			buf   bytes.Buffer
+			etags strings.Builder
+		)
+		for _, s := range o.segments {
+			if s.DataBase64 != "" {
+				data, err := base64.StdEncoding.DecodeString(s.DataBase64)
+				if err != nil {
+					return nil, "", false
+				}
+				buf.Write(data)
+				etags.WriteString(etagOf(data))
+				continue
+			}
+			segment := c.findByPath(a, s.Path)
+			if segment == nil {
+				return nil, "", false
+			}
+			data, _, ok := c.contentOf(a, segment)
+			if !ok {
+				return nil, "", false
+			}
+			data, ok = applySegmentRange(data, s.Range)
+			if !ok {
+				return nil, "", false
+			}
+			buf.Write(data)
+			etags.WriteString(sloSegmentEtag(s.Etag, s.Range))
+		}
+		return buf.Bytes(), `"` + etagOf([]byte(etags.String())) + `"`, true
+	}
+
+	if manifest := o.headers.Get("X-Object-Manifest"); manifest != "" {
+		fields := strings.SplitN(manifest, "/", 2)
+		if len(fields) < 2 {
+			return nil, "", false
+		}
+		cont := a.containers[fields[0]]
+		if cont == nil {
+			return nil, `"` + etagOf(nil) + `"`, true
+		}
+		var names []string
+		for name := range cont.objects {
+			if strings.HasPrefix(name, fields[1]) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var (
+			buf   bytes.Buffer
+			etags strings.Builder
+		)
+		for _, name := range names {
+			buf.Write(cont.objects[name].content)
+			etags.WriteString(cont.objects[name].headers.Get("Etag"))
+		}
+		return buf.Bytes(), `"` + etagOf([]byte(etags.String())) + `"`, true
+	}
+
+	return o.content, o.headers.Get("Etag"), true
+}
+
+// applySegmentRange applies the "range" attribute of an SLO segment.
+func applySegmentRange(data []byte, rangeStr string) ([]byte, bool) {
+	if rangeStr == "" {
+		return data, true
+	}
+	start, end, ok := parseRange(rangeStr, len(data))
+	if !ok {
+		return nil, false
+	}
+	return data[start:end], true
+}
+
+// parseRange parses a byte range like "10-20", "10-" or "-20" for content of
+// the given length. The returned range is half-open.
+func parseRange(rangeStr string, length int) (start, end int, ok bool) {
+	startStr, endStr, found := strings.Cut(rangeStr, "-")
+	if !found {
+		return 0, 0, false
+	}
+	if startStr == "" {
+		// suffix range, e.g. "-20"
+		n, err := strconv.Atoi(endStr)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		return max(length-n, 0), length, true
+	}
+
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 0 || start >= length {
+		return 0, 0, false
+	}
+	if endStr == "" {
+		return start, length, true
+	}
+	// the last byte position may exceed the content length (and even the
+	// range of int)
+	last, err := strconv.ParseUint(endStr, 10, 64)
+	if err != nil || last < uint64(start) {
+		return 0, 0, false
+	}
+	if last >= uint64(length) {
+		return start, length, true
+	}
+	return start, int(last) + 1, true
+}
+
+func (c *cluster) serveObject(w http.ResponseWriter, r *http.Request, a *account, containerName, objectName string) {
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		c.serveObjectGet(w, r, a, containerName, objectName)
+	case http.MethodPut:
+		if r.URL.Query().Has("extract-archive") {
+			c.serveExtractArchive(w, r, a, containerName, objectName)
+			return
+		}
+		c.serveObjectPut(w, r, a, containerName, objectName)
+	case http.MethodPost:
+		c.serveObjectPost(w, r, a, containerName, objectName)
+	case http.MethodDelete:
+		o := c.findObject(a, containerName, objectName)
+		if o == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		cont := a.containers[containerName]
+		delete(cont.objects, objectName)
+		cont.updatedAt = c.now()
+		w.WriteHeader(http.StatusNoContent)
+	case "COPY":
+		c.serveObjectCopy(w, r, a, containerName, objectName)
+	default:
+		writeError(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *cluster) serveObjectGet(w http.ResponseWriter, r *http.Request, a *account, containerName, objectName string) {
+	query := r.URL.Query()
+	o := c.findObject(a, containerName, objectName)
+	if o == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// collect headers from the symlink itself, then follow the symlink
+	hdr := w.Header()
+	for key, values := range o.headers {
+		hdr[key] = values
+	}
+	if query.Get("symlink") != "get" {
+		o = c.resolveSymlinks(a, o)
+		if o == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		for key, values := range o.headers {
+			if key != "X-Symlink-Target" && key != "X-Symlink-Target-Account" {
+				hdr[key] = values
+			}
+		}
+	}
+
+	var (
+		content []byte
+		etag    string
+	)
+	if o.segments != nil && query.Get("multipart-manifest") == "get" {
+		var err error
+		content, err = json.Marshal(o.segments)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		etag = etagOf(content)
+		hdr.Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		var ok bool
+		content, etag, ok = c.contentOf(a, o)
+		if !ok {
+			writeError(w, http.StatusConflict)
+			return
+		}
+	}
+	hdr.Set("Etag", etag)
+	hdr.Set("Last-Modified", formatLastModified(o.createdAt))
+	hdr.Set("X-Timestamp", formatTimestamp(o.createdAt))
+	hdr.Set("Accept-Ranges", "bytes")
+
+	// check conditional request headers
+	if status := checkPreconditions(r, etag, o.createdAt); status != 0 {
+		if status == http.StatusNotModified {
+			w.WriteHeader(status)
+		} else {
+			writeError(w, status)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		rangeStr, isBytes := strings.CutPrefix(rangeHeader, "bytes=")
+		if isBytes && !strings.Contains(rangeStr, ",") {
+			start, end, ok := parseRange(rangeStr, len(content))
+			if !ok {
+				hdr.Set("Content-Range", "bytes */"+strconv.Itoa(len(content)))
+				writeError(w, http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			hdr.Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(end-1)+"/"+strconv.Itoa(len(content)))
+			content = content[start:end]
+			status = http.StatusPartialContent
+		}
+	}
+
+	hdr.Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(content) //nolint:errcheck // cannot fail on httptest.ResponseRecorder
+	}
+}
+
+// checkPreconditions evaluates the conditional request headers. Returns 0 if
+// the request shall proceed, or the status code to respond with otherwise.
+func checkPreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	matchesEtag := func(header string) bool {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.Trim(strings.TrimSpace(candidate), `"`)
+			if candidate == "*" || candidate == strings.Trim(etag, `"`) {
+				return true
+			}
+		}
+		return false
+	}
+	lastModified = ceilToSecond(lastModified)
+
+	if header := r.Header.Get("If-Match"); header != "" && !matchesEtag(header) {
+		return http.StatusPreconditionFailed
+	}
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" {
+		t, err := http.ParseTime(header)
+		if err == nil && lastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if matchesEtag(header) {
+			return http.StatusNotModified
+		}
+	} else if header := r.Header.Get("If-Modified-Since"); header != "" {
+		t, err := http.ParseTime(header)
+		if err == nil && !lastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+func (c *cluster) serveObjectPut(w http.ResponseWriter, r *http.Request, a *account, containerName, objectName string) {
+	cont := a.containers[containerName]
+	if cont == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if len(objectName) > maxObjectNameLength {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("If-None-Match") == "*" && c.findObject(a, containerName, objectName) != nil {
+		writeError(w, http.StatusPreconditionFailed)
+		return
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest)
+		return
+	}
+	o := &object{
+		name:    objectName,
+		headers: make(http.Header),
+		content: content,
+	}
+	applyHeaders(o.headers, r.Header, isSettableObjectHeader)
+	if deleteAfter := r.Header.Get("X-Delete-After"); deleteAfter != "" {
+		seconds, err := strconv.ParseInt(deleteAfter, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		o.headers.Set("X-Delete-At", strconv.FormatInt(c.now().Unix()+seconds, 10))
+	}
+	if o.headers.Get("Content-Type") == "" {
+		o.headers.Set("Content-Type", "application/octet-stream")
+	}
+
+	switch {
+	case r.URL.Query().Get("multipart-manifest") == "put":
+		status, segmentError := c.prepareSLOManifest(a, o)
+		if r.URL.Query().Get("heartbeat") == "on" {
+			c.finishSLOHeartbeat(w, cont, o, status, segmentError)
+			return
+		}
+		if status != 0 {
+			writeError(w, status)
+			return
+		}
+	case r.Header.Get("X-Symlink-Target") != "":
+		if len(content) > 0 {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		o.headers.Set("X-Symlink-Target", r.Header.Get("X-Symlink-Target"))
+		if accountName := r.Header.Get("X-Symlink-Target-Account"); accountName != "" {
+			o.headers.Set("X-Symlink-Target-Account", accountName)
+		}
+		o.headers.Set("Etag", etagOf(nil))
+	default:
+		if manifest := r.Header.Get("X-Object-Manifest"); manifest != "" {
+			o.headers.Set("X-Object-Manifest", manifest)
+		}
+		etag := etagOf(content)
+		if expected := strings.Trim(r.Header.Get("Etag"), `"`); expected != "" && expected != etag {
+			writeError(w, http.StatusUnprocessableEntity)
+			return
+		}
+		o.headers.Set("Etag", etag)
+	}
+
+	o.createdAt = c.now()
+	cont.objects[objectName] = o
+	cont.updatedAt = o.createdAt
+
+	w.Header().Set("Etag", o.headers.Get("Etag"))
+	w.Header().Set("Last-Modified", formatLastModified(o.createdAt))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// finishSLOHeartbeat is the remainder of serveObjectPut() for SLO manifest
+// uploads with "heartbeat=on". Like Swift, it responds with 202 and some
+// whitespace before knowing the outcome, and then reports the outcome in the
+// response body.
+func (c *cluster) finishSLOHeartbeat(w http.ResponseWriter, cont *container, o *object, status int, segmentError []string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte(" \n ")) //nolint:errcheck // client will notice a broken response body
+
+	var result sloHeartbeatResponse
+	if status != 0 {
+		result.ResponseStatus = formatStatus(status)
+		result.Errors = [][]string{}
+		if segmentError != nil {
+			result.Errors = append(result.Errors, segmentError)
+		}
+	} else {
+		o.createdAt = c.now()
+		cont.objects[o.name] = o
+		cont.updatedAt = o.createdAt
+		result.ResponseStatus = formatStatus(http.StatusCreated)
+		result.Etag = o.headers.Get("Etag")
+		result.LastModified = formatLastModified(o.createdAt)
+		result.Errors = [][]string{}
+	}
+	buf, err := json.Marshal(result)
+	if err == nil {
+		_, _ = w.Write(buf) //nolint:errcheck // client will notice a broken response body
+	}
+}
+
+// sloHeartbeatResponse is the response body for SLO manifest uploads with
+// "heartbeat=on".
+type sloHeartbeatResponse struct {
+	ResponseStatus string     `json:"Response Status"`
+	ResponseBody   string     `json:"Response Body"`
+	Etag           string     `json:"Etag,omitempty"`
+	LastModified   string     `json:"Last Modified,omitempty"`
+	Errors         [][]string `json:"Errors"`
+}
+
+// sloSegmentEtag returns the contribution of a segment to the Etag of an SLO.
+func sloSegmentEtag(etag, rangeStr string) string {
+	if rangeStr == "" {
+		return etag
+	}
+	return etag + ":" + rangeStr + ";"
+}
+
+// prepareSLOManifest parses the SLO manifest in o.content and validates it
+// against the referenced segments. Returns 0 on success, or an error status
+// code otherwise. For errors relating to a specific segment, the second return
+// value contains the segment path and a reason, like in the "Errors" field of
+// a heartbeat response.
+func (c *cluster) prepareSLOManifest(a *account, o *object) (int, []string) {
+	var segments []sloSegment
+	err := json.Unmarshal(o.content, &segments)
+	if err != nil || len(segments) == 0 {
+		return http.StatusBadRequest, nil
+	}
+
+	var (
+		etags     strings.Builder
+		sizeBytes int
+	)
+	for idx, s := range segments {
+		if s.DataBase64 != "" {
+			data, err := base64.StdEncoding.DecodeString(s.DataBase64)
+			if err != nil {
+				return http.StatusBadRequest, nil
+			}
+			segments[idx].SizeBytes = uint64(len(data))
+			sizeBytes += len(data)
+			etags.WriteString(etagOf(data))
+			continue
+		}
+
+		segment := c.findByPath(a, s.Path)
+		if segment == nil {
+			return http.StatusBadRequest, []string{s.Path, formatStatus(http.StatusNotFound)}
+		}
+		data, etag, ok := c.contentOf(a, segment)
+		if !ok {
+			return http.StatusBadRequest, []string{s.Path, formatStatus(http.StatusConflict)}
+		}
+		etag = strings.Trim(etag, `"`)
+		if s.Etag != "" && s.Etag != etag {
+			return http.StatusBadRequest, []string{s.Path, "Etag Mismatch"}
+		}
+		segments[idx].Etag = etag
+		if s.SizeBytes != 0 && s.SizeBytes != uint64(len(data)) {
+			return http.StatusBadRequest, []string{s.Path, "Size Mismatch"}
+		}
+		segments[idx].SizeBytes = uint64(len(data))
+		if s.Range != "" {
+			// like Swift, normalize the range into absolute byte positions, and drop
+			// it if it covers the entire segment
+			start, end, ok := parseRange(s.Range, len(data))
+			if !ok {
+				return http.StatusBadRequest, []string{s.Path, "Unsatisfiable Range"}
+			}
+			if start == 0 && end == len(data) {
+				segments[idx].Range = ""
+			} else {
+				segments[idx].Range = fmt.Sprintf("%d-%d", start, end-1)
+			}
+		}
+		data, ok = applySegmentRange(data, segments[idx].Range)
+		if !ok {
+			return http.StatusBadRequest, []string{s.Path, "Unsatisfiable Range"}
+		}
+		sizeBytes += len(data)
+		etags.WriteString(sloSegmentEtag(etag, segments[idx].Range))
+	}
+
+	o.segments = segments
+	o.sloSizeBytes = sizeBytes
+	o.content = nil
+	o.headers.Set("X-Static-Large-Object", "True")
+	o.headers.Set("Etag", `"`+etagOf([]byte(etags.String()))+`"`)
+	return 0, nil
+}
+
+func (c *cluster) serveObjectPost(w http.ResponseWriter, r *http.Request, a *account, containerName, objectName string) {
+	o := c.findObject(a, containerName, objectName)
+	if o == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+
+	// POST replaces all user metadata (and some other headers, but not
+	// Content-Type)
+	for key := range o.headers {
+		if strings.HasPrefix(key, "X-Object-Meta-") || (key != "Content-Type" && isSettableObjectHeader(key)) {
+			o.headers.Del(key)
+		}
+	}
+	applyHeaders(o.headers, r.Header, isSettableObjectHeader)
+	if deleteAfter := r.Header.Get("X-Delete-After"); deleteAfter != "" {
+		seconds, err := strconv.ParseInt(deleteAfter, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		o.headers.Set("X-Delete-At", strconv.FormatInt(c.now().Unix()+seconds, 10))
+	}
+	if manifest := r.Header.Get("X-Object-Manifest"); manifest != "" {
+		o.headers.Set("X-Object-Manifest", manifest)
+	} else {
+		o.headers.Del("X-Object-Manifest")
+	}
+	o.createdAt = c.now()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// copySourceHeaders copies the headers of a COPY source into the target for
+// a shallow copy. With fresh metadata, user-settable headers are not copied,
+// except for Content-Type.
+func copySourceHeaders(dst, src http.Header, freshMetadata bool) {
+	for key, values := range src {
+		if freshMetadata && key != "Content-Type" && isSettableObjectHeader(key) {
+			continue
+		}
+		dst[key] = slices.Clone(values)
+	}
+}
+
+func (c *cluster) serveObjectCopy(w http.ResponseWriter, r *http.Request, a *account, containerName, objectName string) {
+	src := c.findObject(a, containerName, objectName)
+	if src == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	srcLastModified := formatLastModified(src.createdAt)
+
+	targetAccount := a
+	if accountName := r.Header.Get("Destination-Account"); accountName != "" {
+		targetAccount = c.getAccount(accountName)
+	}
+	fields := strings.SplitN(strings.TrimPrefix(r.Header.Get("Destination"), "/"), "/", 2)
+	if len(fields) < 2 || fields[1] == "" {
+		writeError(w, http.StatusPreconditionFailed)
+		return
+	}
+	targetContainer := targetAccount.containers[fields[0]]
+	if targetContainer == nil {
+		writeError(w, http.StatusNotFound)
+		return
+	}
+	if r.Header.Get("If-None-Match") == "*" && c.findObject(targetAccount, fields[0], fields[1]) != nil {
+		writeError(w, http.StatusPreconditionFailed)
+		return
+	}
+
+	query := r.URL.Query()
+	freshMetadata := r.Header.Get("X-Fresh-Metadata") == "true"
+	o := &object{name: fields[1], headers: make(http.Header)}
+	switch {
+	case query.Get("symlink") == "get" && src.headers.Get("X-Symlink-Target") != "":
+		// shallow copy of the symlink itself
+		copySourceHeaders(o.headers, src.headers, freshMetadata)
+	case query.Get("multipart-manifest") == "get" && (src.segments != nil || src.headers.Get("X-Object-Manifest") != ""):
+		// shallow copy of the large object manifest
+		o.content = bytes.Clone(src.content)
+		o.segments = slices.Clone(src.segments)
+		o.sloSizeBytes = src.sloSizeBytes
+		copySourceHeaders(o.headers, src.headers, freshMetadata)
+	default:
+		src = c.resolveSymlinks(a, src)
+		if src == nil {
+			writeError(w, http.StatusNotFound)
+			return
+		}
+		content, _, ok := c.contentOf(a, src)
+		if !ok {
+			writeError(w, http.StatusConflict)
+			return
+		}
+		o.content = bytes.Clone(content)
+		o.headers.Set("Content-Type", src.headers.Get("Content-Type"))
+		o.headers.Set("Etag", etagOf(content))
+		if !freshMetadata {
+			for key, values := range src.headers {
+				if isSettableObjectHeader(key) {
+					o.headers[key] = values
+				}
+			}
+		}
+	}
+	applyHeaders(o.headers, r.Header, isSettableObjectHeader)
+	if deleteAfter := r.Header.Get("X-Delete-After"); deleteAfter != "" {
+		seconds, err := strconv.ParseInt(deleteAfter, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest)
+			return
+		}
+		o.headers.Set("X-Delete-At", strconv.FormatInt(c.now().Unix()+seconds, 10))
+	}
+
+	o.createdAt = c.now()
+	targetContainer.objects[o.name] = o
+	targetContainer.updatedAt = o.createdAt
+	w.Header().Set("X-Copied-From-Last-Modified", srcLastModified)
+	w.WriteHeader(http.StatusCreated)
+}
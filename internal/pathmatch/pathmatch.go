@@ -0,0 +1,35 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+// Package pathmatch contains helpers for name patterns in the syntax of
+// path.Match(), like the container patterns accepted by
+// Account.DeleteByPrefix() and lifecycle.Rule.
+package pathmatch
+
+import "strings"
+
+// LiteralPrefix returns the longest prefix of the given pattern that does not
+// contain any special characters. All names matching the pattern start with
+// this prefix, so it can be used to restrict a listing before matching each
+// name against the pattern.
+func LiteralPrefix(pattern string) string {
+	if idx := strings.IndexAny(pattern, `*?[\`); idx >= 0 {
+		return pattern[:idx]
+	}
+	return pattern
+}
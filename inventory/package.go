@@ -0,0 +1,181 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package inventory exports detailed object listings of Swift containers or
+accounts into CSV or JSON-lines streams, for consumption by inventory
+pipelines and similar tools. For example:
+
+	import "github.com/majewsky/schwift/v2/inventory"
+
+	err := inventory.ExportAccount(ctx, os.Stdout, account, &inventory.Options{
+		Format: inventory.FormatJSONLines,
+		Gzip:   true,
+	})
+
+Listings are streamed page by page, so arbitrarily large inventories can be
+exported without holding them in memory.
+
+Each record describes one object with the following fields, in this order:
+container name, object name, size in bytes, Etag, Content-Type and the
+last-modified timestamp (in RFC 3339 format with UTC timezone). In CSV
+format, the first line is a header line containing the field names
+"container", "name", "bytes", "hash", "content_type" and "last_modified".
+In JSON-lines format, each line is a JSON object with these keys.
+*/
+package inventory
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// Format enumerates the output formats supported by this package.
+type Format int
+
+const (
+	// FormatCSV produces comma-separated values with a header line.
+	FormatCSV Format = iota
+	// FormatJSONLines produces one JSON object per line.
+	FormatJSONLines
+)
+
+// Options contains optional settings for ExportAccount() and ExportContainer().
+type Options struct {
+	// Format selects the output format. The default is FormatCSV.
+	Format Format
+	// When ContainerPrefix is set, only containers whose name starts with this
+	// string are exported. This is ignored by ExportContainer().
+	ContainerPrefix string
+	// When ObjectPrefix is set, only objects whose name starts with this string
+	// are exported.
+	ObjectPrefix string
+	// When Gzip is set, the output is compressed with gzip.
+	Gzip bool
+}
+
+// ExportContainer writes a record for each object in the given container into
+// the given writer. The opts argument may be nil.
+func ExportContainer(ctx context.Context, w io.Writer, c *schwift.Container, opts *Options) error {
+	return export(w, opts, func(e *exporter) error {
+		return e.exportContainer(ctx, c)
+	})
+}
+
+// ExportAccount writes a record for each object in each container of the
+// given account into the given writer. Containers are visited in the order in
+// which the account listing returns them. The opts argument may be nil.
+func ExportAccount(ctx context.Context, w io.Writer, a *schwift.Account, opts *Options) error {
+	return export(w, opts, func(e *exporter) error {
+		iter := a.Containers()
+		iter.Prefix = e.opts.ContainerPrefix
+		return iter.Foreach(ctx, func(c *schwift.Container) error {
+			return e.exportContainer(ctx, c)
+		})
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// implementation
+
+var csvHeader = []string{"container", "name", "bytes", "hash", "content_type", "last_modified"}
+
+type record struct {
+	Container    string `json:"container"`
+	Name         string `json:"name"`
+	SizeBytes    uint64 `json:"bytes"`
+	Etag         string `json:"hash"`
+	ContentType  string `json:"content_type"`
+	LastModified string `json:"last_modified"`
+}
+
+type exporter struct {
+	opts       Options
+	csvWriter  *csv.Writer
+	jsonWriter *json.Encoder
+}
+
+func export(w io.Writer, opts *Options, action func(*exporter) error) (returnedErr error) {
+	e := exporter{}
+	if opts != nil {
+		e.opts = *opts
+	}
+
+	if e.opts.Gzip {
+		gzw := gzip.NewWriter(w)
+		defer func() {
+			returnedErr = errors.Join(returnedErr, gzw.Close())
+		}()
+		w = gzw
+	}
+
+	switch e.opts.Format {
+	case FormatCSV:
+		e.csvWriter = csv.NewWriter(w)
+		err := e.csvWriter.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			e.csvWriter.Flush()
+			returnedErr = errors.Join(returnedErr, e.csvWriter.Error())
+		}()
+	case FormatJSONLines:
+		e.jsonWriter = json.NewEncoder(w)
+	default:
+		return errors.New("inventory: unknown output format " + strconv.Itoa(int(e.opts.Format)))
+	}
+
+	return action(&e)
+}
+
+func (e *exporter) exportContainer(ctx context.Context, c *schwift.Container) error {
+	iter := c.Objects()
+	iter.Prefix = e.opts.ObjectPrefix
+	return iter.ForeachDetailed(ctx, func(info schwift.ObjectInfo) error {
+		if info.SubDirectory != "" {
+			return nil
+		}
+		return e.write(record{
+			Container:    c.Name(),
+			Name:         info.Object.Name(),
+			SizeBytes:    info.SizeBytes,
+			Etag:         info.Etag,
+			ContentType:  info.ContentType,
+			LastModified: info.LastModified.UTC().Format(time.RFC3339Nano),
+		})
+	})
+}
+
+func (e *exporter) write(r record) error {
+	if e.jsonWriter != nil {
+		return e.jsonWriter.Encode(r)
+	}
+	return e.csvWriter.Write([]string{
+		r.Container, r.Name, strconv.FormatUint(r.SizeBytes, 10),
+		r.Etag, r.ContentType, r.LastModified,
+	})
+}
@@ -19,13 +19,41 @@
 package schwift
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+// ListingFormat is an enum for the Format field of ContainerIterator and
+// ObjectIterator. It selects the response format that is requested by the
+// "Detailed" listing methods. Methods without the "Detailed" suffix always
+// request plain-text listings.
+//
+// The default is JSON. The other formats are provided as fallbacks for when
+// JSON listings do not work, e.g. because a proxy or middleware mangles them.
+type ListingFormat int
+
+const (
+	// ListingFormatJSON is the default ListingFormat.
+	ListingFormatJSON ListingFormat = iota
+	// ListingFormatXML requests listings in XML format ("?format=xml"). The
+	// same information is returned as for ListingFormatJSON.
+	ListingFormatXML
+	// ListingFormatPlain requests listings in plain-text format, which only
+	// contains names. In ContainerInfo and ObjectInfo, all fields except for the
+	// Container/Object and SubDirectory are left empty. Pseudo-directories are
+	// recognized by their names ending in the Delimiter.
+	ListingFormatPlain
+)
+
 // iteratorInterface allows iteratorBase to access public attributes of
 // ContainerIterator/ObjectIterator.
 type iteratorInterface interface {
@@ -34,6 +62,10 @@ type iteratorInterface interface {
 	getDelimiter() string
 	getPrefix() string
 	getOptions() *RequestOptions
+	getFormat() ListingFormat
+	// getListingCache returns the ListingCache to use (if any), and the
+	// container that the listing pertains to.
+	getListingCache() (*ListingCache, *Container)
 	// putHeader initializes the AccountHeaders/ContainerHeaders field of the
 	// Account/Container using the response headers from the GET request.
 	putHeader(http.Header) error
@@ -44,13 +76,17 @@ func (i ContainerIterator) getContainerName() string    { return "" }
 func (i ContainerIterator) getDelimiter() string        { return "" }
 func (i ContainerIterator) getPrefix() string           { return i.Prefix }
 func (i ContainerIterator) getOptions() *RequestOptions { return i.Options }
+func (i ContainerIterator) getFormat() ListingFormat    { return i.Format }
+
+func (i ContainerIterator) getListingCache() (*ListingCache, *Container) { return nil, nil }
 
 func (i ContainerIterator) putHeader(hdr http.Header) error {
-	headers := AccountHeaders{headersFromHTTP(hdr)}
+	headers := newAccountHeadersFromHTTP(hdr)
 	if err := headers.Validate(); err != nil {
 		return err
 	}
 	i.Account.headers = &headers
+	i.Account.rawHeaders = multiValuedHeaders(hdr)
 	return nil
 }
 
@@ -59,24 +95,68 @@ func (i ObjectIterator) getContainerName() string    { return i.Container.Name()
 func (i ObjectIterator) getDelimiter() string        { return i.Delimiter }
 func (i ObjectIterator) getPrefix() string           { return i.Prefix }
 func (i ObjectIterator) getOptions() *RequestOptions { return i.Options }
+func (i ObjectIterator) getFormat() ListingFormat    { return i.Format }
+
+func (i ObjectIterator) getListingCache() (*ListingCache, *Container) { return i.Cache, i.Container }
 
 func (i ObjectIterator) putHeader(hdr http.Header) error {
-	headers := ContainerHeaders{headersFromHTTP(hdr)}
+	headers := newContainerHeadersFromHTTP(hdr)
 	if err := headers.Validate(); err != nil {
 		return err
 	}
 	i.Container.headers = &headers
+	i.Container.rawHeaders = multiValuedHeaders(hdr)
 	return nil
 }
 
+// ListingStats contains pagination metadata for a ContainerIterator or
+// ObjectIterator. It is returned by the Stats() method of the iterator, and
+// covers all pages that have been fetched through the iterator so far. This is
+// useful for monitoring and debugging code that makes heavy use of listings,
+// for example:
+//
+//	iter := container.Objects()
+//	objects, err := iter.CollectDetailed(ctx)
+//	stats := iter.Stats()
+//	log.Printf("listed %d objects in %d requests", len(objects), stats.RequestCount)
+type ListingStats struct {
+	// The name of the last entry on the last non-empty page, i.e. the marker
+	// for the next page. This is retained when the end of the listing has been
+	// reached, so that it can be used as a marker to look for new entries later.
+	LastMarker string
+	// Truncated is false once the end of the listing has been reached.
+	Truncated bool
+	// The number of listing pages that were fetched (including the empty page
+	// that indicates the end of the listing), regardless of whether they were
+	// served by the server or from a ListingCache.
+	PagesFetched int
+	// The number of GET requests that were sent to the server. This can be lower
+	// than PagesFetched when pages are served from a ListingCache, or higher
+	// when a request failed.
+	RequestCount int
+}
+
 // iteratorBase provides shared behavior for ContainerIterator and ObjectIterator.
 type iteratorBase struct {
 	i      iteratorInterface
 	marker string
 	eof    bool
+	// see ListingStats
+	lastMarker   string
+	pagesFetched int
+	requestCount int
 }
 
-func (b *iteratorBase) request(limit int, detailed bool) Request {
+func (b *iteratorBase) stats() ListingStats {
+	return ListingStats{
+		LastMarker:   b.lastMarker,
+		Truncated:    !b.eof,
+		PagesFetched: b.pagesFetched,
+		RequestCount: b.requestCount,
+	}
+}
+
+func (b *iteratorBase) request(limit int, format ListingFormat) Request {
 	r := Request{
 		Method:        "GET",
 		ContainerName: b.i.getContainerName(),
@@ -90,9 +170,10 @@ func (b *iteratorBase) request(limit int, detailed bool) Request {
 		r.Options.Values.Set("prefix", prefix)
 	}
 
-	if b.marker == "" {
-		r.Options.Values.Del("marker")
-	} else {
+	// b.marker is only empty before the first page (at EOF, no further
+	// requests are made), so a marker given in the options is retained for the
+	// first page only
+	if b.marker != "" {
 		r.Options.Values.Set("marker", b.marker)
 	}
 
@@ -102,11 +183,16 @@ func (b *iteratorBase) request(limit int, detailed bool) Request {
 		r.Options.Values.Set("limit", strconv.FormatUint(uint64(limit), 10))
 	}
 
-	if detailed {
+	switch format {
+	case ListingFormatJSON:
 		r.Options.Headers.Set("Accept", "application/json")
 		r.Options.Values.Set("format", "json")
 		r.ExpectStatusCodes = []int{200}
-	} else {
+	case ListingFormatXML:
+		r.Options.Headers.Set("Accept", "application/xml")
+		r.Options.Values.Set("format", "xml")
+		r.ExpectStatusCodes = []int{200}
+	default:
 		r.Options.Headers.Set("Accept", "text/plain")
 		r.Options.Values.Set("format", "plain")
 		r.ExpectStatusCodes = []int{200, 204}
@@ -115,16 +201,55 @@ func (b *iteratorBase) request(limit int, detailed bool) Request {
 	return r
 }
 
-func (b *iteratorBase) nextPage(ctx context.Context, limit int) ([]string, error) {
-	if b.eof {
-		return nil, nil
+// fetch executes a listing request and returns the response body. If the
+// iterator has a ListingCache, the response may be served from there.
+func (b *iteratorBase) fetch(ctx context.Context, limit int, format ListingFormat) ([]byte, error) {
+	r := b.request(limit, format)
+	fetchFromServer := func() ([]byte, error) {
+		resp, err := b.doRequest(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return collectResponseBody(resp)
 	}
-	resp, err := b.request(limit, false).Do(ctx, b.i.getAccount().backend)
+
+	var (
+		buf []byte
+		err error
+	)
+	if cache, c := b.i.getListingCache(); cache != nil {
+		buf, err = cache.fetch(ctx, c, r, fetchFromServer)
+	} else {
+		buf, err = fetchFromServer()
+	}
+	if err == nil {
+		b.pagesFetched++
+	}
+	return buf, err
+}
+
+// doRequest executes a listing request against the server and stores the
+// response headers in the Account or Container. The response body is not
+// consumed.
+func (b *iteratorBase) doRequest(ctx context.Context, r Request) (*http.Response, error) {
+	b.requestCount++
+	resp, err := r.Do(ctx, b.i.getAccount().backend)
+	if err != nil {
+		return nil, err
+	}
+	err = b.i.putHeader(resp.Header)
 	if err != nil {
+		resp.Body.Close()
 		return nil, err
 	}
+	return resp, nil
+}
 
-	buf, err := collectResponseBody(resp)
+func (b *iteratorBase) nextPage(ctx context.Context, limit int) ([]string, error) {
+	if b.eof {
+		return nil, nil
+	}
+	buf, err := b.fetch(ctx, limit, ListingFormatPlain)
 	if err != nil {
 		return nil, err
 	}
@@ -135,36 +260,166 @@ func (b *iteratorBase) nextPage(ctx context.Context, limit int) ([]string, error
 	}
 
 	if len(result) == 0 {
-		b.eof = true
-		b.marker = ""
+		b.setMarker("")
 	} else {
-		b.eof = false
-		b.marker = result[len(result)-1]
+		b.setMarker(result[len(result)-1])
 	}
-	return result, b.i.putHeader(resp.Header)
+	return result, nil
 }
 
-func (b *iteratorBase) nextPageDetailed(ctx context.Context, limit int, data interface{}) error {
+// nextFilteredPage is like nextPage, but only returns names that are accepted
+// by filterByName(). Pages where all names are filtered out are skipped, since
+// an empty page indicates EOF to the caller.
+func (b *iteratorBase) nextFilteredPage(ctx context.Context, limit int, match, exclude *regexp.Regexp) ([]string, error) {
+	for {
+		page, err := b.nextPage(ctx, limit)
+		if err != nil || len(page) == 0 {
+			return page, err
+		}
+		names := filterByName(page, match, exclude, func(name string) string { return name })
+		if len(names) > 0 {
+			return names, nil
+		}
+	}
+}
+
+// filterByName implements the Match and Exclude fields of ContainerIterator
+// and ObjectIterator. The given slice is filtered in place.
+func filterByName[T any](items []T, match, exclude *regexp.Regexp, nameOf func(T) string) []T {
+	if match == nil && exclude == nil {
+		return items
+	}
+	result := items[:0]
+	for _, item := range items {
+		name := nameOf(item)
+		if match != nil && !match.MatchString(name) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(name) {
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// nextPageDetailed fetches the next page of a detailed listing in the
+// iterator's Format, which must be ListingFormatJSON or ListingFormatXML.
+// The returned body must be given to decodeListing(). Returns nil at EOF.
+//
+// Unless the page is served from a ListingCache (which needs the full body),
+// the response body is streamed into the decoder instead of being buffered.
+func (b *iteratorBase) nextPageDetailed(ctx context.Context, limit int) (io.ReadCloser, error) {
 	if b.eof {
-		return nil
+		return nil, nil
+	}
+	format := b.i.getFormat()
+	if cache, _ := b.i.getListingCache(); cache != nil {
+		buf, err := b.fetch(ctx, limit, format)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(buf)), nil
 	}
-	resp, err := b.request(limit, true).Do(ctx, b.i.getAccount().backend)
+
+	resp, err := b.doRequest(ctx, b.request(limit, format))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	b.pagesFetched++
+	return resp.Body, nil
+}
 
-	err = json.NewDecoder(resp.Body).Decode(&data)
-	closeErr := resp.Body.Close()
+// decodeListing decodes a detailed listing in JSON or XML format, calls the
+// callback once for every entry, and closes the body. The entries are decoded
+// one at a time while streaming, so that the whole page does not need to be
+// held in memory twice. For XML, T must be able to represent every kind of
+// element below the root element (including <subdir>).
+func decodeListing[T any](body io.ReadCloser, format ListingFormat, callback func(T) error) error {
+	if body == nil {
+		return nil
+	}
+	var err error
+	if format == ListingFormatXML {
+		err = decodeListingXML(body, callback)
+	} else {
+		err = decodeListingJSON(body, callback)
+	}
+	closeErr := body.Close()
 	if err == nil {
 		err = closeErr
 	}
-	if err == nil {
-		err = b.i.putHeader(resp.Header)
+	return err
+}
+
+func decodeListingJSON[T any](r io.Reader, callback func(T) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil // empty body
+	}
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("invalid listing: expected JSON array, got %v", tok)
 	}
+
+	for dec.More() {
+		var entry T
+		err := dec.Decode(&entry)
+		if err != nil {
+			return err
+		}
+		err = callback(entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token() // consume closing ']'
 	return err
 }
 
+func decodeListingXML[T any](r io.Reader, callback func(T) error) error {
+	dec := xml.NewDecoder(r)
+	insideRoot := false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil // empty body
+		}
+		if err != nil {
+			return err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if !insideRoot {
+				insideRoot = true
+				continue
+			}
+			var entry T
+			err := dec.DecodeElement(&entry, &tok)
+			if err != nil {
+				return err
+			}
+			err = callback(entry)
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			// entries are consumed entirely by DecodeElement(), so this can only be
+			// the end of the root element
+			return nil
+		}
+	}
+}
+
 func (b *iteratorBase) setMarker(marker string) {
 	b.marker = marker
 	b.eof = marker == ""
+	if marker != "" {
+		b.lastMarker = marker
+	}
 }
@@ -0,0 +1,60 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecodeListing(t *testing.T) {
+	type entry struct {
+		Name string `json:"name" xml:"name"`
+	}
+	errStop := errors.New("stop")
+
+	bodies := map[ListingFormat]string{
+		ListingFormatJSON: `[{"name":"a"},{"name":"b"},{"name":"c"}]`,
+		ListingFormatXML:  `<?xml version="1.0" encoding="UTF-8"?><container name="foo"><object><name>a</name></object><object><name>b</name></object><object><name>c</name></object></container>`,
+	}
+	for format, body := range bodies {
+		// entries are reported one by one, and decoding stops at the first error
+		var names []string
+		err := decodeListing(io.NopCloser(strings.NewReader(body)), format, func(e entry) error {
+			names = append(names, e.Name)
+			if e.Name == "b" {
+				return errStop
+			}
+			return nil
+		})
+		if !errors.Is(err, errStop) {
+			t.Errorf("expected errStop for format %v, got %v", format, err)
+		}
+		expectString(t, "a,b", strings.Join(names, ","))
+
+		// empty bodies yield no entries
+		err = decodeListing(io.NopCloser(strings.NewReader("")), format, func(e entry) error {
+			t.Errorf("unexpected entry for format %v: %#v", format, e)
+			return nil
+		})
+		must(t, err)
+	}
+}
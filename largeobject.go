@@ -37,8 +37,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	"github.com/jpillora/longestcommon"
+	"unicode/utf8"
 )
 
 // SegmentInfo describes a segment of a large object.
@@ -155,6 +154,9 @@ type LargeObject struct {
 	segmentPrefix    string
 	strategy         LargeObjectStrategy
 	segments         []SegmentInfo
+	policy           SegmentPolicy
+	// if not nil, the segments have not been loaded yet (see AsLargeObjectOptions.Lazy)
+	fetchSegments func(ctx context.Context) ([]SegmentInfo, error)
 }
 
 // Object returns the location of this large object (where its manifest is stored).
@@ -182,12 +184,44 @@ func (lo *LargeObject) Strategy() LargeObjectStrategy {
 }
 
 // Segments returns a list of all segments for this object, in order.
+//
+// If the large object was opened with AsLargeObjectOptions.Lazy,
+// ErrSegmentsNotLoaded is returned until LoadSegments() has been called.
 func (lo *LargeObject) Segments() ([]SegmentInfo, error) {
-	//NOTE: This method has an error return value because we might later switch
-	// to loading segments lazily inside this method.
+	if lo.fetchSegments != nil {
+		return nil, ErrSegmentsNotLoaded
+	}
 	return lo.segments, nil
 }
 
+// LoadSegments loads the list of segments from the server if that has not
+// happened yet. This is only necessary for large objects that were opened with
+// AsLargeObjectOptions.Lazy; otherwise, this is a no-op. Methods that take a
+// context argument (e.g. Append() or WriteManifest()) call this implicitly,
+// but methods that do not (e.g. Segments() or AddSegment()) require it to be
+// called beforehand.
+func (lo *LargeObject) LoadSegments(ctx context.Context) error {
+	if lo.fetchSegments == nil {
+		return nil
+	}
+	segments, err := lo.fetchSegments(ctx)
+	if err != nil {
+		return err
+	}
+	lo.segments = segments
+	lo.fetchSegments = nil
+	return nil
+}
+
+// requireSegments is used by methods that can neither take a context nor
+// return an error. Since their result would be wrong without the segments,
+// calling them before LoadSegments() is a programming error.
+func (lo *LargeObject) requireSegments(method string) {
+	if lo.fetchSegments != nil {
+		panic("LargeObject." + method + "() called before LoadSegments() on a lazily opened large object")
+	}
+}
+
 // SegmentObjects returns a list of all segment objects referenced by this large
 // object. Note that, in general,
 //
@@ -196,7 +230,11 @@ func (lo *LargeObject) Segments() ([]SegmentInfo, error) {
 // since one object may be backing multiple segments, and data segments are not
 // backed by any object at all. No guarantee is made about the order in which
 // objects appear in this list.
+//
+// If the large object was opened with AsLargeObjectOptions.Lazy, this method
+// panics unless LoadSegments() has been called.
 func (lo *LargeObject) SegmentObjects() []*Object {
+	lo.requireSegments("SegmentObjects")
 	seen := make(map[string]bool)
 	result := make([]*Object, 0, len(lo.segments))
 	for _, segment := range lo.segments {
@@ -215,7 +253,53 @@ func (lo *LargeObject) SegmentObjects() []*Object {
 // AsLargeObject opens an existing large object. If the given object does not
 // exist, or if it is not a large object, ErrNotLarge will be returned. In this
 // case, Object.AsNewLargeObject() needs to be used instead.
+//
+// For static large objects, the SegmentContainer and SegmentPrefix are
+// inferred from the segments using InferSegmentLocation(). Use
+// AsLargeObjectWithOptions() to control this behavior.
 func (o *Object) AsLargeObject(ctx context.Context) (*LargeObject, error) {
+	return o.AsLargeObjectWithOptions(ctx, nil)
+}
+
+// AsLargeObjectOptions contains options for Object.AsLargeObjectWithOptions().
+type AsLargeObjectOptions struct {
+	// If not nil, the SegmentContainer and SegmentPrefix of the large object
+	// are set to this container and SegmentPrefix, instead of being inferred
+	// from the segments (for static large objects) or taken from the manifest
+	// (for dynamic large objects). The container must be in the same account as
+	// the large object.
+	SegmentContainer *Container
+	SegmentPrefix    string
+	// Configures the inference of SegmentContainer and SegmentPrefix when
+	// SegmentContainer is nil.
+	Inference SegmentLocationOptions
+	// If true, a SegmentLocationError is returned if any segment is located
+	// outside of the SegmentContainer and SegmentPrefix (regardless of whether
+	// they were given explicitly or inferred). Data segments are not affected.
+	Strict bool
+	// If true, the list of segments is not loaded until it is first needed, e.g.
+	// by Segments(), Append() or WriteManifest(). This saves requests when the
+	// segment list is never used, e.g. when the large object is only truncated
+	// without deleting its segments.
+	//
+	// For static large objects, this only takes effect if SegmentContainer is
+	// given and Strict is false, since the segments are needed to infer the
+	// segment location or to validate it.
+	//
+	// Methods that do not take a context do not load the segments. Until
+	// LoadSegments() has been called, Segments() and AddSegment() return
+	// ErrSegmentsNotLoaded, and SegmentObjects() and NextSegmentObject() panic.
+	Lazy bool
+}
+
+// AsLargeObjectWithOptions is like AsLargeObject, but provides control over
+// how the SegmentContainer and SegmentPrefix are determined, for use by
+// automated tooling that requires deterministic behavior instead of
+// heuristics. The opts argument may be nil.
+func (o *Object) AsLargeObjectWithOptions(ctx context.Context, opts *AsLargeObjectOptions) (*LargeObject, error) {
+	if opts == nil {
+		opts = &AsLargeObjectOptions{}
+	}
 	exists, err := o.Exists(ctx)
 	if err != nil {
 		return nil, err
@@ -224,17 +308,49 @@ func (o *Object) AsLargeObject(ctx context.Context) (*LargeObject, error) {
 		return nil, ErrNotLarge
 	}
 
+	var lo *LargeObject
 	h := o.headers
-	if h.IsDynamicLargeObject() {
-		return o.asDLO(ctx, h.Get("X-Object-Manifest"))
+	switch {
+	case h.IsDynamicLargeObject():
+		lo, err = o.asDLO(ctx, h.Get("X-Object-Manifest"), opts.Lazy)
+	case h.IsStaticLargeObject():
+		if opts.Lazy && opts.SegmentContainer != nil && !opts.Strict {
+			lo = &LargeObject{
+				object:        o,
+				strategy:      StaticLargeObject,
+				fetchSegments: o.fetchSLOSegments,
+			}
+		} else {
+			lo, err = o.asSLO(ctx, opts.SegmentContainer == nil, opts.Inference)
+		}
+	default:
+		return nil, ErrNotLarge
 	}
-	if h.IsStaticLargeObject() {
-		return o.asSLO(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return nil, ErrNotLarge
+
+	if opts.SegmentContainer != nil {
+		err = lo.SetSegmentLocation(opts.SegmentContainer, opts.SegmentPrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.Strict {
+		for _, s := range lo.segments {
+			if s.Object != nil && !lo.isOwnSegment(s.Object) {
+				return nil, SegmentLocationError{
+					SegmentName:      s.Object.FullName(),
+					SegmentContainer: lo.segmentContainer.Name(),
+					SegmentPrefix:    lo.segmentPrefix,
+				}
+			}
+		}
+	}
+	return lo, nil
 }
 
-func (o *Object) asDLO(ctx context.Context, manifestStr string) (*LargeObject, error) {
+func (o *Object) asDLO(ctx context.Context, manifestStr string, lazy bool) (*LargeObject, error) {
 	manifest := strings.SplitN(manifestStr, "/", 2)
 	if len(manifest) < 2 {
 		return nil, ErrNotLarge
@@ -247,159 +363,285 @@ func (o *Object) asDLO(ctx context.Context, manifestStr string) (*LargeObject, e
 		strategy:         DynamicLargeObject,
 	}
 
-	iter := lo.segmentContainer.Objects()
-	iter.Prefix = lo.segmentPrefix
-	segmentInfos, err := iter.CollectDetailed(ctx)
-	if err != nil {
-		return nil, err
-	}
-	lo.segments = make([]SegmentInfo, 0, len(segmentInfos))
-	for _, info := range segmentInfos {
-		lo.segments = append(lo.segments, SegmentInfo{
-			Object:    info.Object,
-			SizeBytes: info.SizeBytes,
-			Etag:      info.Etag,
+	// the segment location is known from the manifest, so listing the segments
+	// can be deferred until they are needed
+	segmentContainer, segmentPrefix := lo.segmentContainer, lo.segmentPrefix
+	lo.fetchSegments = func(ctx context.Context) ([]SegmentInfo, error) {
+		// convert each listing page right away instead of collecting the entire
+		// listing first, to avoid holding two copies of huge segment lists
+		iter := segmentContainer.Objects()
+		iter.Prefix = segmentPrefix
+		var segments []SegmentInfo
+		err := iter.ForeachDetailed(ctx, func(info ObjectInfo) error {
+			segments = append(segments, dloSegmentInfo(info))
+			return nil
 		})
+		if err != nil {
+			return nil, err
+		}
+		return segments, nil
 	}
 
-	return lo, nil
+	if lazy {
+		return lo, nil
+	}
+	return lo, lo.LoadSegments(ctx)
 }
 
-func (o *Object) asSLO(ctx context.Context) (*LargeObject, error) {
-	opts := RequestOptions{
-		Values: make(url.Values),
+func (o *Object) asSLO(ctx context.Context, inferLocation bool, inferOpts SegmentLocationOptions) (*LargeObject, error) {
+	lo := &LargeObject{
+		object:   o,
+		strategy: StaticLargeObject,
 	}
-	opts.Values.Set("multipart-manifest", "get")
-	opts.Values.Set("format", "raw")
-	buf, err := o.Download(ctx, &opts).AsByteSlice()
+
+	// read the segments first, then deduce the SegmentContainer/SegmentPrefix from these
+	var err error
+	lo.segments, err = o.fetchSLOSegments(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	var data []sloSegmentInfo
-	err = json.Unmarshal(buf, &data)
-	if err != nil {
-		return nil, errors.New("invalid SLO manifest: " + err.Error())
+	if len(lo.segments) == 0 || !inferLocation {
+		return lo, nil
 	}
 
-	lo := &LargeObject{
-		object:   o,
-		strategy: StaticLargeObject,
-	}
-	if len(data) == 0 {
-		return lo, nil
+	lo.segmentContainer, lo.segmentPrefix = InferSegmentLocation(lo.segments, inferOpts)
+	if lo.segmentContainer == nil {
+		// only data segments, so any location is as good as any other
+		lo.segmentContainer = o.c
+		lo.segmentPrefix = defaultSegmentPrefix(o, lo.strategy)
 	}
+	return lo, nil
+}
 
-	// read the segments first, then deduce the SegmentContainer/SegmentPrefix from these
-	lo.segments = make([]SegmentInfo, 0, len(data))
-	for _, info := range data {
-		// option 1: data segment
-		if info.DataBase64 != "" {
-			data, err := base64.StdEncoding.DecodeString(info.DataBase64)
-			if err != nil {
-				return nil, errors.New("invalid SLO data segment: " + err.Error())
-			}
-			lo.segments = append(lo.segments, SegmentInfo{Data: data})
-			continue
-		}
+func (o *Object) fetchSLOSegments(ctx context.Context) ([]SegmentInfo, error) {
+	var segments []SegmentInfo
+	err := o.foreachSLOSegment(ctx, func(s SegmentInfo) error {
+		segments = append(segments, s)
+		return nil
+	})
+	return segments, err
+}
 
-		// option 2: segment backed by object
-		pathElements := strings.SplitN(strings.TrimPrefix(info.Path, "/"), "/", 2)
-		if len(pathElements) != 2 {
-			return nil, errors.New("invalid SLO segment: malformed path: " + info.Path)
-		}
-		s := SegmentInfo{
-			Object:    o.c.a.Container(pathElements[0]).Object(pathElements[1]),
-			SizeBytes: info.SizeBytes,
-			Etag:      info.Etag,
-		}
-		if info.Range != "" {
-			var ok bool
-			s.RangeOffset, s.RangeLength, ok = parseHTTPRange(info.Range)
-			if !ok {
-				return nil, errors.New("invalid SLO segment: malformed range: " + info.Range)
-			}
-		}
-		lo.segments = append(lo.segments, s)
-	}
+// SegmentLocationOptions contains options for InferSegmentLocation().
+type SegmentLocationOptions struct {
+	// By default, if the longest common prefix of the segment names contains a
+	// slash, the part after the last slash is discarded. For example, for the
+	// segments "foo/bar/0001" and "foo/bar/0002", the longest common prefix is
+	// "foo/bar/000", but the inferred SegmentPrefix is "foo/bar/". If this
+	// option is set, the longest common prefix is used as is.
+	KeepPartialPathElement bool
+}
 
-	// choose the SegmentContainer by majority vote (in the spirit of "be liberal
-	// in what you accept")
-	containerNames := make(map[string]uint)
-	for _, s := range lo.segments {
+// InferSegmentLocation guesses the SegmentContainer and SegmentPrefix of a
+// large object from its segments. This is used by Object.AsLargeObject() for
+// static large objects, since their manifest does not record these values.
+//
+// The SegmentContainer is chosen by majority vote among the segments (ties
+// are broken in favor of the container that appears first). The SegmentPrefix
+// is the longest common prefix of the names of all segments in that
+// container, modified as described in the documentation of type
+// SegmentLocationOptions.
+//
+// If there are no segments backed by objects (i.e. only data segments), nil
+// and "" are returned. When the guess is not appropriate, it can be overridden
+// with LargeObject.SetSegmentLocation().
+func InferSegmentLocation(segments []SegmentInfo, opts SegmentLocationOptions) (*Container, string) {
+	var (
+		containers []*Container
+		votes      = make(map[string]int)
+	)
+	for _, s := range segments {
 		if s.Object == nil { // can happen for data segments
 			continue
 		}
-		containerNames[s.Object.c.Name()]++
+		name := s.Object.c.Name()
+		if votes[name] == 0 {
+			containers = append(containers, s.Object.c)
+		}
+		votes[name]++
 	}
-	maxName := ""
-	maxVotes := uint(0)
-	for name, votes := range containerNames {
-		if votes > maxVotes {
-			maxName = name
-			maxVotes = votes
+	var winner *Container
+	for _, c := range containers {
+		if winner == nil || votes[c.Name()] > votes[winner.Name()] {
+			winner = c
 		}
 	}
-	lo.segmentContainer = lo.object.c.a.Container(maxName)
+	if winner == nil {
+		return nil, ""
+	}
 
-	// choose the SegmentPrefix as the longest common prefix of all segments in
-	// the chosen SegmentContainer...
-	names := make([]string, 0, len(lo.segments))
-	for _, s := range lo.segments {
-		if s.Object == nil { // can happen for data segments
-			continue
-		}
-		name := s.Object.c.Name()
-		if name == maxName {
+	names := make([]string, 0, votes[winner.Name()])
+	for _, s := range segments {
+		if s.Object != nil && s.Object.c.Name() == winner.Name() {
 			names = append(names, s.Object.Name())
 		}
 	}
-	lo.segmentPrefix = longestcommon.Prefix(names)
+	prefix := longestCommonPrefix(names)
+	if !opts.KeepPartialPathElement && strings.Contains(prefix, "/") {
+		prefix = path.Dir(prefix) + "/"
+	}
+	return winner.a.Container(winner.Name()), prefix
+}
 
-	// ..BUT if the prefix is a path with slashes, do not consider the part after
-	// the last slash; e.g. if we have segments "foo/bar/0001" and "foo/bar/0002",
-	// the longest common prefix is "foo/bar/000", but we actually want "foo/bar/"
-	if strings.Contains(lo.segmentPrefix, "/") {
-		lo.segmentPrefix = path.Dir(lo.segmentPrefix) + "/"
+// longestCommonPrefix returns the longest string that is a prefix of all the
+// given strings. The result is cut back as necessary to not end in the middle
+// of a multi-byte character.
+func longestCommonPrefix(strs []string) string {
+	if len(strs) == 0 {
+		return ""
 	}
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		length := min(len(prefix), len(s))
+		idx := 0
+		for idx < length && prefix[idx] == s[idx] {
+			idx++
+		}
+		prefix = prefix[:idx]
+	}
+	for len(prefix) > 0 && !utf8.ValidString(prefix) {
+		prefix = prefix[:len(prefix)-1]
+	}
+	return prefix
+}
 
-	return lo, nil
+// SetSegmentLocation overrides the SegmentContainer and SegmentPrefix of this
+// large object. This is useful when the location inferred by
+// Object.AsLargeObject() (see InferSegmentLocation()) is not appropriate. The
+// location determines where new segments are created by Append() and
+// NextSegmentObject(). For dynamic large objects, it also determines which
+// segments are referenced by the manifest that is written by WriteManifest().
+//
+// The container must be in the same account as the large object, otherwise
+// ErrAccountMismatch is returned.
+func (lo *LargeObject) SetSegmentLocation(c *Container, prefix string) error {
+	if !c.a.IsEqualTo(lo.object.c.a) {
+		return ErrAccountMismatch
+	}
+	lo.segmentContainer = c
+	lo.segmentPrefix = prefix
+	return nil
 }
 
-func parseHTTPRange(str string) (offsetVal int64, lengthVal uint64, ok bool) {
-	fields := strings.SplitN(str, "-", 2)
-	if len(fields) != 2 {
-		return 0, 0, false
+// ForeachSegment calls the callback once for every segment of this large
+// object, in order. Iteration is aborted when a request fails, or when the
+// callback returns a non-nil error. If the object does not exist, or if it is
+// not a large object, ErrNotLarge will be returned.
+//
+// Unlike Object.AsLargeObject(), this method does not hold all segments in
+// memory at the same time. For static large objects, the manifest is parsed
+// while it is being downloaded, so this method is preferable for very large
+// manifests when the segments only need to be inspected once. To page through
+// the segments instead, use Object.Segments().
+func (o *Object) ForeachSegment(ctx context.Context, callback func(SegmentInfo) error) error {
+	exists, err := o.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotLarge
 	}
 
-	if fields[0] == "" {
-		// case 1: "-"
-		if fields[1] == "" {
-			return 0, 0, true
+	h := o.headers
+	if h.IsDynamicLargeObject() {
+		manifest := strings.SplitN(h.Get("X-Object-Manifest"), "/", 2)
+		if len(manifest) < 2 {
+			return ErrNotLarge
 		}
+		iter := o.c.a.Container(manifest[0]).Objects()
+		iter.Prefix = manifest[1]
+		return iter.ForeachDetailed(ctx, func(info ObjectInfo) error {
+			return callback(dloSegmentInfo(info))
+		})
+	}
+	if h.IsStaticLargeObject() {
+		return o.foreachSLOSegment(ctx, callback)
+	}
+	return ErrNotLarge
+}
 
-		// case 2: "-N"
-		numBytes, err := strconv.ParseUint(fields[1], 10, 64)
+// Downloads the SLO manifest of this object and calls the callback once for
+// every segment. The manifest is decoded while streaming, so that huge
+// manifests do not need to be held in memory at once.
+func (o *Object) foreachSLOSegment(ctx context.Context, callback func(SegmentInfo) error) error {
+	opts := RequestOptions{
+		Values: make(url.Values),
+	}
+	opts.Values.Set("multipart-manifest", "get")
+	opts.Values.Set("format", "raw")
+	body, err := o.Download(ctx, &opts).AsReadCloser()
+	if err != nil {
+		return err
+	}
+	err = o.decodeSLOManifest(body, callback)
+	closeErr := body.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (o *Object) decodeSLOManifest(r io.Reader, callback func(SegmentInfo) error) error {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return errors.New("invalid SLO manifest: " + err.Error())
+	}
+	if tok != json.Delim('[') {
+		return fmt.Errorf("invalid SLO manifest: expected JSON array, got %v", tok)
+	}
+
+	for dec.More() {
+		var info sloSegmentInfo
+		err := dec.Decode(&info)
+		if err != nil {
+			return errors.New("invalid SLO manifest: " + err.Error())
+		}
+		s, err := o.parseSLOSegment(info)
 		if err != nil {
-			return 0, 0, false
+			return err
+		}
+		err = callback(s)
+		if err != nil {
+			return err
 		}
-		return -1, numBytes, true
 	}
 
-	firstByte, err := strconv.ParseUint(fields[0], 10, 63) // not 64; needs to be unsigned, but also fit into int64
+	_, err = dec.Token() // consume closing ']'
 	if err != nil {
-		return 0, 0, false
+		return errors.New("invalid SLO manifest: " + err.Error())
 	}
-	if fields[1] == "" {
-		// case 3: "N-"
-		return int64(firstByte), 0, true
+	return nil
+}
+
+func (o *Object) parseSLOSegment(info sloSegmentInfo) (SegmentInfo, error) {
+	// option 1: data segment
+	if info.DataBase64 != "" {
+		data, err := base64.StdEncoding.DecodeString(info.DataBase64)
+		if err != nil {
+			return SegmentInfo{}, errors.New("invalid SLO data segment: " + err.Error())
+		}
+		return SegmentInfo{Data: data}, nil
 	}
-	// case 4: "M-N"
-	lastByte, err := strconv.ParseUint(fields[1], 10, 64)
-	if err != nil || lastByte < firstByte {
-		return 0, 0, false
+
+	// option 2: segment backed by object
+	pathElements := strings.SplitN(strings.TrimPrefix(info.Path, "/"), "/", 2)
+	if len(pathElements) != 2 {
+		return SegmentInfo{}, errors.New("invalid SLO segment: malformed path: " + info.Path)
+	}
+	s := SegmentInfo{
+		Object:    o.c.a.Container(pathElements[0]).Object(pathElements[1]),
+		SizeBytes: info.SizeBytes,
+		Etag:      info.Etag,
+	}
+	if info.Range != "" {
+		r, err := ParseByteRange(info.Range)
+		if err != nil {
+			return SegmentInfo{}, errors.New("invalid SLO segment: malformed range: " + info.Range)
+		}
+		s.RangeOffset, s.RangeLength = r.Offset, r.Length
 	}
-	return int64(firstByte), lastByte - firstByte + 1, true
+	return s, nil
 }
 
 // AsNewLargeObject opens an object as a large object. SegmentingOptions are
@@ -449,20 +691,25 @@ func (o *Object) AsNewLargeObject(ctx context.Context, sopts SegmentingOptions,
 	// apply default value for segmenting prefix
 	lo.segmentPrefix = sopts.SegmentPrefix
 	if lo.segmentPrefix == "" {
-		now := time.Now()
-		strategyStr := "slo"
-		if lo.strategy == DynamicLargeObject {
-			strategyStr = "dlo"
-		}
-
-		lo.segmentPrefix = fmt.Sprintf("%s/%s/%d.%09d",
-			o.Name(), strategyStr, now.Unix(), now.Nanosecond(),
-		)
+		lo.segmentPrefix = defaultSegmentPrefix(o, lo.strategy)
 	}
 
 	return lo, nil
 }
 
+// defaultSegmentPrefix computes the default for SegmentingOptions.SegmentPrefix.
+func defaultSegmentPrefix(o *Object, strategy LargeObjectStrategy) string {
+	now := time.Now()
+	strategyStr := "slo"
+	if strategy == DynamicLargeObject {
+		strategyStr = "dlo"
+	}
+
+	return fmt.Sprintf("%s/%s/%d.%09d",
+		o.Name(), strategyStr, now.Unix(), now.Nanosecond(),
+	)
+}
+
 // TruncateOptions contains options that can be passed to LargeObject.Truncate()
 // and Object.AsNewLargeObject().
 type TruncateOptions struct {
@@ -476,12 +723,24 @@ type TruncateOptions struct {
 // Truncate removes all segments from a large object's manifest. The manifest is
 // not written by this call, so WriteManifest() usually needs to be called
 // afterwards.
+//
+// Unless opts.DeleteSegments is set, this does not issue any requests. In
+// particular, if the segments have not been loaded yet (see
+// AsLargeObjectOptions.Lazy), they will not be loaded at all.
 func (lo *LargeObject) Truncate(ctx context.Context, opts *TruncateOptions) error {
-	_, _, err := lo.object.c.a.BulkDelete(ctx, lo.SegmentObjects(), nil, nil)
-	if err == nil {
-		lo.segments = nil
+	if opts != nil && opts.DeleteSegments {
+		err := lo.LoadSegments(ctx)
+		if err != nil {
+			return err
+		}
+		_, _, err = lo.object.c.a.BulkDelete(ctx, lo.SegmentObjects(), nil, nil)
+		if err != nil {
+			return err
+		}
 	}
-	return err
+	lo.segments = nil
+	lo.fetchSegments = nil
+	return nil
 }
 
 // NextSegmentObject suggests where to upload the next segment.
@@ -511,7 +770,12 @@ func (lo *LargeObject) Truncate(ctx context.Context, opts *TruncateOptions) erro
 // If there are no segments yet, or if all segments are located outside the
 // lo.segmentContainer and lo.segmentPrefix, the first segment name is chosen as
 // lo.segmentPrefix + "0000000000000001".
+//
+// If the large object was opened with AsLargeObjectOptions.Lazy, this method
+// panics unless LoadSegments() has been called.
 func (lo *LargeObject) NextSegmentObject() *Object {
+	lo.requireSegments("NextSegmentObject")
+
 	// find the name of the last-most segment that is within the designated
 	// segment container and prefix
 	var prevSegmentName string
@@ -520,7 +784,7 @@ func (lo *LargeObject) NextSegmentObject() *Object {
 		if o == nil { // can happen for data segments
 			continue
 		}
-		if lo.segmentContainer.IsEqualTo(o.c) && strings.HasPrefix(o.Name(), lo.segmentPrefix) {
+		if lo.isOwnSegment(o) {
 			prevSegmentName = s.Object.Name()
 			// keep going, we want to find the last such segment
 		}
@@ -537,6 +801,12 @@ func (lo *LargeObject) NextSegmentObject() *Object {
 	return lo.segmentContainer.Object(segmentName)
 }
 
+// isOwnSegment checks whether the given segment object is located in
+// lo.segmentContainer below lo.segmentPrefix.
+func (lo *LargeObject) isOwnSegment(o *Object) bool {
+	return lo.segmentContainer.IsEqualTo(o.c) && strings.HasPrefix(o.Name(), lo.segmentPrefix)
+}
+
 var splitSegmentIndexRx = regexp.MustCompile(`^(.*?)([0-9]+$)`)
 var initialIndex = "0000000000000001"
 
@@ -587,8 +857,20 @@ func nextSegmentName(segmentName string) string {
 // time), or
 //
 // - the SegmentInfo's Data attribute is set, but the LargeObject is a dynamic
-// large objects (DLOs do not support data segments).
+// large objects (DLOs do not support data segments), or
+//
+// - a SegmentPolicy is attached to this LargeObject, and adding the segment
+// would violate it. In this case, the error wraps ErrSegmentInvalid with a
+// more specific message.
+//
+// If the large object was opened with AsLargeObjectOptions.Lazy, this method
+// returns ErrSegmentsNotLoaded unless LoadSegments() has been called.
 func (lo *LargeObject) AddSegment(segment SegmentInfo) error {
+	// new segments are appended to the existing ones, so those need to be known
+	if lo.fetchSegments != nil {
+		return ErrSegmentsNotLoaded
+	}
+
 	if len(segment.Data) == 0 {
 		// validate segments backed by objects
 		o := segment.Object
@@ -632,6 +914,10 @@ func (lo *LargeObject) AddSegment(segment SegmentInfo) error {
 		}
 	}
 
+	err := lo.checkSegmentPolicy()
+	if err != nil {
+		return err
+	}
 	lo.segments = append(lo.segments, segment)
 	return nil
 }
@@ -651,8 +937,19 @@ func (lo *LargeObject) AddSegment(segment SegmentInfo) error {
 //	var buffer string
 //	lo.Append(bytes.NewReader([]byte(buffer)), segmentSizeBytes)
 //
-// If segmentSizeBytes is zero, Append() defaults to the maximum file size
-// reported by Account.Capabilities().
+// If segmentSizeBytes is zero, Append() defaults to the target segment size of
+// the attached SegmentPolicy (see SetSegmentPolicy), or to the maximum file
+// size reported by Account.Capabilities() if there is no such policy. If the
+// policy limits the number of segments and the size of the contents is known
+// in advance (see documentation on Object.Upload), the segment size is
+// increased if necessary to stay within that limit.
+//
+// If the attached SegmentPolicy has InlineTailSize set, a short final segment
+// may be stored in the manifest as a data segment instead of being uploaded.
+//
+// The opts argument applies to each segment upload, so it can be used to set
+// headers on the segments (e.g. Content-Type, X-Delete-At or metadata). The
+// headers of the manifest are given to WriteManifest() instead.
 //
 // Calls to Append() and its low-level counterpart, AddSegment(), can be freely
 // intermixed. AddSegment() is useful when you want to control the segments'
@@ -665,16 +962,19 @@ func (lo *LargeObject) Append(ctx context.Context, contents io.Reader, segmentSi
 	if segmentSizeBytes < 0 {
 		panic("segmentSizeBytes may not be negative")
 	}
+	err := lo.LoadSegments(ctx)
+	if err != nil {
+		return err
+	}
 	if segmentSizeBytes == 0 {
-		// apply default value for segmenting size
-		caps, err := lo.object.c.a.Capabilities(ctx)
+		segmentSizeBytes, err = lo.chooseSegmentSize(ctx, contents)
 		if err != nil {
 			return err
 		}
-		segmentSizeBytes = int64(caps.Swift.MaximumFileSize)
-		if segmentSizeBytes <= 0 {
-			return errors.New("cannot infer SegmentSizeBytes from Swift /info")
-		}
+	}
+	if lo.strategy == StaticLargeObject && uint64(segmentSizeBytes) < lo.policy.MinimumSegmentSize {
+		return fmt.Errorf("%w: segment size %d is below the minimum segment size of %d bytes",
+			ErrSegmentInvalid, segmentSizeBytes, lo.policy.MinimumSegmentSize)
 	}
 
 	sr := segmentingReader{contents, segmentSizeBytes}
@@ -684,20 +984,34 @@ func (lo *LargeObject) Append(ctx context.Context, contents io.Reader, segmentSi
 			break
 		}
 
+		// check the policy before uploading, to avoid leaving behind an orphaned
+		// segment in case of a violation
+		err := lo.checkSegmentPolicy()
+		if err != nil {
+			return err
+		}
+
+		segment, inlined, err := lo.tryInlineTail(segment, segmentSizeBytes)
+		if err != nil || inlined {
+			return err
+		}
+
 		tracker := lengthAndEtagTrackingReader{
 			Reader: segment,
-			Hasher: md5.New(), //nolint:gosec // Etag uses md5
+			Hasher: getMD5(),
 		}
 
 		obj := lo.NextSegmentObject()
-		err := obj.Upload(ctx, &tracker, nil, opts)
+		err = obj.Upload(ctx, &tracker, nil, opts)
 		if err != nil {
 			return err
 		}
+		etag := hex.EncodeToString(tracker.Hasher.Sum(nil))
+		putMD5(tracker.Hasher)
 		err = lo.AddSegment(SegmentInfo{
 			Object:    obj,
 			SizeBytes: tracker.BytesRead,
-			Etag:      hex.EncodeToString(tracker.Hasher.Sum(nil)),
+			Etag:      etag,
 		})
 		if err != nil {
 			return err
@@ -707,6 +1021,69 @@ func (lo *LargeObject) Append(ctx context.Context, contents io.Reader, segmentSi
 	return nil
 }
 
+// tryInlineTail implements SegmentPolicy.InlineTailSize for Append(). If the
+// given segment is small enough, it is added as a data segment and true is
+// returned. Otherwise, a reader for the entire segment is returned.
+func (lo *LargeObject) tryInlineTail(segment io.Reader, segmentSizeBytes int64) (io.Reader, bool, error) {
+	limit := lo.policy.InlineTailSize
+	if lo.strategy != StaticLargeObject || limit == 0 || limit >= uint64(segmentSizeBytes) {
+		return segment, false, nil
+	}
+
+	// since all segments except for the last one are exactly segmentSizeBytes
+	// long, a segment that is shorter than the limit must be the last one
+	buf := make([]byte, limit+1)
+	n, err := io.ReadFull(segment, buf)
+	switch {
+	case err == nil:
+		// segment is too long -> upload as usual
+		return io.MultiReader(bytes.NewReader(buf), segment), false, nil
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		// segment fits within the limit
+	default:
+		return nil, false, err
+	}
+
+	// only inline if the manifest does not grow too large (this is only an
+	// estimate since we do not know the segments that will be added afterwards)
+	buf = buf[:n]
+	dataSegment := SegmentInfo{Data: buf}
+	if maxSize := lo.policy.MaximumManifestSize; maxSize > 0 {
+		size := uint64(len(lo.sloManifest())) + uint64(len(`,{"data":""}`)+base64.StdEncoding.EncodedLen(n))
+		if size > maxSize {
+			return bytes.NewReader(buf), false, nil
+		}
+	}
+	return nil, true, lo.AddSegment(dataSegment)
+}
+
+// chooseSegmentSize implements the default value for Append's segmentSizeBytes.
+func (lo *LargeObject) chooseSegmentSize(ctx context.Context, contents io.Reader) (int64, error) {
+	segmentSizeBytes := int64(lo.policy.TargetSegmentSize)
+	if segmentSizeBytes <= 0 {
+		caps, err := lo.object.c.a.Capabilities(ctx)
+		if err != nil {
+			return 0, err
+		}
+		segmentSizeBytes = int64(caps.Swift.MaximumFileSize)
+		if segmentSizeBytes <= 0 {
+			return 0, errors.New("cannot infer SegmentSizeBytes from Swift /info")
+		}
+	}
+
+	// if we know how much data is coming, make sure that we do not exceed the
+	// maximum segment count
+	maxCount := lo.policy.MaximumSegmentCount
+	length := tryComputeContentLength(contents)
+	if lo.strategy == StaticLargeObject && maxCount > 0 && length != nil && uint(len(lo.segments)) < maxCount {
+		remainingCount := uint64(maxCount) - uint64(len(lo.segments))
+		minSize := int64((*length + remainingCount - 1) / remainingCount) // division rounding upwards
+		segmentSizeBytes = max(segmentSizeBytes, minSize)
+	}
+
+	return segmentSizeBytes, nil
+}
+
 type segmentingReader struct {
 	Reader           io.Reader
 	SegmentSizeBytes int64 // must be >0 // TODO: in Schwift 3, change field type to uint64 and clamp values to math.MaxInt64 internally
@@ -757,7 +1134,21 @@ func (r *lengthAndEtagTrackingReader) Read(buf []byte) (int, error) {
 // For dynamic large objects, this method does not generate a PUT request
 // if the object already exists and has the correct manifest (i.e.
 // SegmentContainer and SegmentPrefix have not been changed).
+//
+// For static large objects, Swift validates all segments before responding,
+// which can take longer than proxies and load balancers allow for huge
+// manifests. To avoid timeouts, set the "heartbeat" parameter:
+//
+//	err := lo.WriteManifest(ctx, opts.WithValue("heartbeat", "on"))
+//
+// Swift will then keep the connection alive while validating the segments. If
+// the manifest is rejected in this mode, the error is a BulkError whose
+// ObjectErrors identify the offending segments.
 func (lo *LargeObject) WriteManifest(ctx context.Context, opts *RequestOptions) error {
+	err := lo.LoadSegments(ctx)
+	if err != nil {
+		return err
+	}
 	switch lo.strategy {
 	case StaticLargeObject:
 		return lo.writeSLOManifest(ctx, opts)
@@ -787,6 +1178,43 @@ func (lo *LargeObject) writeDLOManifest(ctx context.Context, opts *RequestOption
 }
 
 func (lo *LargeObject) writeSLOManifest(ctx context.Context, opts *RequestOptions) error {
+	manifest := lo.sloManifest()
+
+	// if we know enough about the segments, we can check the Etag that Swift
+	// computes for the SLO (if not, verification is skipped)
+	expectedEtag, err := ComputeSLOEtag(lo.segments)
+	if err != nil {
+		expectedEtag = ""
+	}
+
+	opts = cloneRequestOptions(opts, nil)
+	opts.Headers.Del("X-Object-Manifest") // ensure sanity :)
+	opts.Values.Set("multipart-manifest", "put")
+
+	var actualEtag string
+	if opts.Values.Get("heartbeat") == "on" {
+		actualEtag, err = lo.writeSLOManifestWithHeartbeat(ctx, manifest, opts)
+		if err != nil {
+			return err
+		}
+	} else {
+		var result UploadResult
+		err = lo.object.Upload(ctx, bytes.NewReader(manifest), &UploadOptions{Result: &result}, opts)
+		if err != nil {
+			return err
+		}
+		actualEtag = result.Etag
+	}
+
+	actualEtag = strings.Trim(actualEtag, `"`)
+	if expectedEtag != "" && actualEtag != "" && expectedEtag != actualEtag {
+		return SLOEtagMismatchError{Expected: expectedEtag, Actual: actualEtag}
+	}
+	return nil
+}
+
+// sloManifest renders the SLO manifest for the current list of segments.
+func (lo *LargeObject) sloManifest() []byte {
 	sloSegments := make([]sloSegmentInfo, len(lo.segments))
 	for idx, s := range lo.segments {
 		if len(s.Data) > 0 {
@@ -794,21 +1222,12 @@ func (lo *LargeObject) writeSLOManifest(ctx context.Context, opts *RequestOption
 				DataBase64: base64.StdEncoding.EncodeToString(s.Data),
 			}
 		} else {
-			si := sloSegmentInfo{
+			sloSegments[idx] = sloSegmentInfo{
 				Path:      "/" + s.Object.FullName(),
 				SizeBytes: s.SizeBytes,
 				Etag:      s.Etag,
+				Range:     s.rangeString(),
 			}
-
-			if s.RangeOffset < 0 {
-				si.Range = "-" + strconv.FormatUint(s.RangeLength, 10)
-			} else {
-				firstByteStr := strconv.FormatUint(uint64(s.RangeOffset), 10)
-				lastByteStr := strconv.FormatUint(uint64(s.RangeOffset)+s.RangeLength-1, 10)
-				si.Range = firstByteStr + "-" + lastByteStr
-			}
-
-			sloSegments[idx] = si
 		}
 	}
 
@@ -817,9 +1236,100 @@ func (lo *LargeObject) writeSLOManifest(ctx context.Context, opts *RequestOption
 		// failing json.Marshal() on such a trivial data structure is alarming
 		panic(err.Error())
 	}
+	return manifest
+}
 
-	opts = cloneRequestOptions(opts, nil)
-	opts.Headers.Del("X-Object-Manifest") // ensure sanity :)
-	opts.Values.Set("multipart-manifest", "put")
-	return lo.object.Upload(ctx, bytes.NewReader(manifest), nil, opts)
+// rangeString formats the range of this segment in the syntax of an HTTP Range
+// header (without the "bytes=" prefix). The empty string is returned if the
+// segment covers its entire object.
+func (s SegmentInfo) rangeString() string {
+	if s.RangeLength == 0 && s.RangeOffset == 0 {
+		return "" // entire segment -> no range needed
+	}
+	return s.ByteRange().String()
+}
+
+// ByteRange returns the RangeOffset and RangeLength of this segment as a
+// ByteRange.
+func (s SegmentInfo) ByteRange() ByteRange {
+	return ByteRange{Offset: s.RangeOffset, Length: s.RangeLength}
+}
+
+// writeSLOManifestWithHeartbeat implements writeSLOManifest for
+// "heartbeat=on". In this mode, Swift responds with 202 immediately and sends
+// whitespace periodically while validating the segments, followed by a JSON
+// document with the actual result. Returns the Etag of the new SLO.
+func (lo *LargeObject) writeSLOManifestWithHeartbeat(ctx context.Context, manifest []byte, opts *RequestOptions) (string, error) {
+	opts.Headers.Set("Accept", "application/json")
+	o := lo.object
+	resp, err := Request{
+		Method:        "PUT",
+		ContainerName: o.c.name,
+		ObjectName:    o.name,
+		Options:       opts,
+		Body:          bytes.NewReader(manifest),
+		// 201 is accepted in case the server ignores the heartbeat parameter
+		ExpectStatusCodes: []int{http.StatusCreated, http.StatusAccepted},
+	}.Do(ctx, o.c.a.backend) //nolint:bodyclose // parseBulkResponse does the close
+	if err != nil {
+		return "", err
+	}
+	o.Invalidate()
+
+	if resp.StatusCode == http.StatusCreated {
+		etag := resp.Header.Get("Etag")
+		return etag, resp.Body.Close()
+	}
+	result, err := parseBulkResponse(resp.Body)
+	return result.Etag, err
+}
+
+// ComputeSLOEtag computes the Etag that Swift will report for a static large
+// object consisting of the given segments, without downloading any of them.
+// Like Swift, it computes the MD5 checksum of the concatenated Etags of all
+// segments (with range specifications appended for range segments). The
+// result is hex-encoded and not enclosed in quotes.
+//
+// An error is returned if the Etag cannot be computed because a segment backed
+// by an object does not have its Etag set, or because its SizeBytes is not set
+// even though it is needed to resolve the segment's range.
+//
+// LargeObject.WriteManifest() uses this function to verify the Etag reported
+// by Swift after writing an SLO manifest.
+func ComputeSLOEtag(segments []SegmentInfo) (string, error) {
+	h := getMD5()
+	defer putMD5(h)
+
+	for idx, s := range segments {
+		if len(s.Data) > 0 {
+			sum := md5.Sum(s.Data) //nolint:gosec // Etag uses md5
+			h.Write([]byte(hex.EncodeToString(sum[:])))
+			continue
+		}
+
+		if s.Etag == "" {
+			return "", fmt.Errorf("cannot compute SLO Etag: segment %d has no Etag", idx)
+		}
+		if s.RangeOffset == 0 && s.RangeLength == 0 {
+			h.Write([]byte(s.Etag))
+			continue
+		}
+		if s.SizeBytes == 0 {
+			return "", fmt.Errorf("cannot compute SLO Etag: segment %d has a range, but no SizeBytes", idx)
+		}
+
+		start, end, ok := s.ByteRange().Resolve(s.SizeBytes)
+		if !ok {
+			return "", fmt.Errorf("cannot compute SLO Etag: segment %d has an unsatisfiable range", idx)
+		}
+
+		if start == 0 && end == s.SizeBytes {
+			// Swift drops ranges that cover the entire segment
+			h.Write([]byte(s.Etag))
+		} else {
+			h.Write([]byte(fmt.Sprintf("%s:%d-%d;", s.Etag, start, end-1)))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
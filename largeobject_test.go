@@ -20,11 +20,17 @@ package schwift
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 )
 
-func TestParseHTTPRange(t *testing.T) {
+func TestParseByteRange(t *testing.T) {
 	testCases := []struct {
 		input  string
 		ok     bool
@@ -51,7 +57,8 @@ func TestParseHTTPRange(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		o, l, ok := parseHTTPRange(tc.input)
+		r, err := ParseByteRange(tc.input)
+		o, l, ok := r.Offset, r.Length, err == nil
 
 		if tc.ok && !ok {
 			t.Errorf("expected %q to parse, but did not", tc.input)
@@ -67,6 +74,47 @@ func TestParseHTTPRange(t *testing.T) {
 	}
 }
 
+func TestRangeSpec(t *testing.T) {
+	spec, err := ParseRangeSpec("bytes=0-499, -500,9500-")
+	must(t, err)
+	expectString(t, "bytes=0-499,-500,9500-", spec.String())
+
+	_, err = ParseRangeSpec("items=0-499")
+	if err == nil {
+		t.Error("expected error for unsupported range unit")
+	}
+	_, err = ParseRangeSpec("bytes=0-499,what")
+	if err == nil {
+		t.Error("expected error for malformed range")
+	}
+
+	// Resolve() handles all types of ranges
+	testCases := []struct {
+		r          ByteRange
+		start, end uint64
+		ok         bool
+	}{
+		{ByteRange{}, 0, 100, true},
+		{ByteRange{Offset: 10}, 10, 100, true},
+		{ByteRange{Offset: 10, Length: 20}, 10, 30, true},
+		{ByteRange{Offset: 90, Length: 20}, 90, 100, true},
+		{ByteRange{Offset: -1, Length: 20}, 80, 100, true},
+		{ByteRange{Offset: -1, Length: 200}, 0, 100, true},
+		{ByteRange{Offset: 100}, 0, 0, false},
+		{ByteRange{Offset: -1, Length: 0}, 0, 0, false},
+	}
+	for _, tc := range testCases {
+		start, end, ok := tc.r.Resolve(100)
+		if start != tc.start || end != tc.end || ok != tc.ok {
+			t.Errorf("expected %#v to resolve into (%d, %d, %t), but got (%d, %d, %t)",
+				tc.r, tc.start, tc.end, tc.ok, start, end, ok)
+		}
+	}
+
+	opts := (*RequestOptions)(nil).WithRange(ByteRange{Offset: 100, Length: 50}, ByteRange{Offset: -1, Length: 10})
+	expectString(t, "bytes=100-149,-10", opts.Headers.Get("Range"))
+}
+
 func TestSegmentingReader(t *testing.T) {
 	testCases := []struct {
 		input    string
@@ -110,3 +158,226 @@ func TestSegmentingReader(t *testing.T) {
 		}
 	}
 }
+
+func TestDecodeSLOManifest(t *testing.T) {
+	o := (&Account{}).Container("foo").Object("bar")
+	manifest := `[
+		{"path":"/segments/bar/0001","size_bytes":1024,"etag":"abc"},
+		{"data":"SGVsbG8="},
+		{"path":"/segments/bar/0002","size_bytes":2048,"etag":"def","range":"-100"}
+	]`
+
+	var segments []SegmentInfo
+	err := o.decodeSLOManifest(strings.NewReader(manifest), func(s SegmentInfo) error {
+		segments = append(segments, s)
+		return nil
+	})
+	must(t, err)
+
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments, got %d", len(segments))
+	}
+	if name := segments[0].Object.FullName(); name != "segments/bar/0001" {
+		t.Errorf("expected segment 0 at %q, got %q", "segments/bar/0001", name)
+	}
+	if string(segments[1].Data) != "Hello" {
+		t.Errorf("expected segment 1 to contain %q, got %q", "Hello", string(segments[1].Data))
+	}
+	if segments[2].RangeOffset != -1 || segments[2].RangeLength != 100 {
+		t.Errorf("expected segment 2 to have range (-1, 100), got (%d, %d)",
+			segments[2].RangeOffset, segments[2].RangeLength)
+	}
+
+	err = o.decodeSLOManifest(strings.NewReader(`{"path":"/foo/bar"}`), func(SegmentInfo) error { return nil })
+	if err == nil {
+		t.Error("expected error for non-array manifest, but got none")
+	}
+}
+
+func TestComputeSLOEtag(t *testing.T) {
+	o := &Object{name: "segment"}
+	etagA := etagOfString("aaaa")
+	etagB := etagOfString("bbbbbbbb")
+
+	testCases := []struct {
+		segments []SegmentInfo
+		input    string // the string whose MD5 is the expected SLO Etag
+	}{
+		// plain segments
+		{[]SegmentInfo{{Object: o, Etag: etagA}, {Object: o, Etag: etagB}}, etagA + etagB},
+		// data segment
+		{[]SegmentInfo{{Data: []byte("hello")}, {Object: o, Etag: etagA}}, etagOfString("hello") + etagA},
+		// range segments are normalized into absolute byte positions
+		{[]SegmentInfo{{Object: o, Etag: etagB, SizeBytes: 8, RangeOffset: 2, RangeLength: 3}}, etagB + ":2-4;"},
+		{[]SegmentInfo{{Object: o, Etag: etagB, SizeBytes: 8, RangeOffset: 2}}, etagB + ":2-7;"},
+		{[]SegmentInfo{{Object: o, Etag: etagB, SizeBytes: 8, RangeOffset: -1, RangeLength: 3}}, etagB + ":5-7;"},
+		// ranges covering the entire segment are dropped
+		{[]SegmentInfo{{Object: o, Etag: etagB, SizeBytes: 8, RangeOffset: 0, RangeLength: 100}}, etagB},
+	}
+
+	for idx, tc := range testCases {
+		actual, err := ComputeSLOEtag(tc.segments)
+		must(t, err)
+		if actual != etagOfString(tc.input) {
+			t.Errorf("testcase %d: expected Etag to be MD5 of %q, got %q", idx, tc.input, actual)
+		}
+	}
+
+	// missing information leads to errors
+	_, err := ComputeSLOEtag([]SegmentInfo{{Object: o}})
+	if err == nil {
+		t.Error("expected error for segment without Etag")
+	}
+	_, err = ComputeSLOEtag([]SegmentInfo{{Object: o, Etag: etagA, RangeOffset: 2}})
+	if err == nil {
+		t.Error("expected error for range segment without SizeBytes")
+	}
+}
+
+func TestSegmentPolicy(t *testing.T) {
+	a, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusOK, "hello")})
+	must(t, err)
+	c := a.Container("segments")
+	lo, err := a.Container("foo").Object("bar").AsNewLargeObject(context.Background(), SegmentingOptions{
+		SegmentContainer: c,
+		SegmentPrefix:    "bar/",
+	}, nil)
+	must(t, err)
+	lo.SetSegmentPolicy(SegmentPolicy{
+		MinimumSegmentSize:  100,
+		TargetSegmentSize:   1000,
+		MaximumSegmentCount: 3,
+	})
+
+	// the last segment may be small...
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("bar/1"), SizeBytes: 100}))
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("bar/2"), SizeBytes: 50}))
+	// ...but not once another segment is added after it
+	err = lo.AddSegment(SegmentInfo{Object: c.Object("bar/3"), SizeBytes: 100})
+	if !errors.Is(err, ErrSegmentInvalid) || !strings.Contains(err.Error(), "segment 1 is 50 bytes long") {
+		t.Errorf("expected minimum size violation, got %v", err)
+	}
+
+	// the segment count is limited
+	lo.segments = lo.segments[:1]
+	must(t, lo.AddSegment(SegmentInfo{Data: bytes.Repeat([]byte("x"), 100)}))
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("bar/3"), SizeBytes: 300, RangeOffset: 100, RangeLength: 100}))
+	err = lo.AddSegment(SegmentInfo{Object: c.Object("bar/4"), SizeBytes: 100})
+	if !errors.Is(err, ErrSegmentInvalid) || !strings.Contains(err.Error(), "at most 3 segments") {
+		t.Errorf("expected segment count violation, got %v", err)
+	}
+
+	// the segment size for Append() is chosen to fit within the segment count
+	lo.segments = nil
+	size, err := lo.chooseSegmentSize(context.Background(), bytes.NewReader(make([]byte, 2000)))
+	must(t, err)
+	expectString(t, "1000", strconv.FormatInt(size, 10))
+	size, err = lo.chooseSegmentSize(context.Background(), bytes.NewReader(make([]byte, 4000)))
+	must(t, err)
+	expectString(t, "1334", strconv.FormatInt(size, 10))
+
+	// segment sizes below the minimum are rejected before uploading anything
+	err = lo.Append(context.Background(), bytes.NewReader(make([]byte, 2000)), 50, nil)
+	if !errors.Is(err, ErrSegmentInvalid) {
+		t.Errorf("expected ErrSegmentInvalid, got %v", err)
+	}
+}
+
+func TestLargeObjectStat(t *testing.T) {
+	a, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusOK, "hello")})
+	must(t, err)
+	c := a.Container("segments")
+	lo, err := a.Container("foo").Object("bar").AsNewLargeObject(context.Background(), SegmentingOptions{
+		SegmentContainer: c,
+		SegmentPrefix:    "bar/",
+	}, nil)
+	must(t, err)
+
+	stat, err := lo.Stat(context.Background())
+	must(t, err)
+	expectString(t, "0 0 0", fmt.Sprintf("%d %d %d", stat.SizeBytes, stat.SegmentCount, stat.MaximumSegmentSize))
+
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("bar/1"), SizeBytes: 100}))
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("bar/2"), SizeBytes: 300, RangeOffset: 100, RangeLength: 50}))
+	must(t, lo.AddSegment(SegmentInfo{Data: []byte("hello")}))
+	must(t, lo.AddSegment(SegmentInfo{Object: c.Object("other/3"), SizeBytes: 200}))
+	must(t, lo.AddSegment(SegmentInfo{Object: a.Container("elsewhere").Object("bar/4"), SizeBytes: 20}))
+
+	stat, err = lo.Stat(context.Background())
+	must(t, err)
+	expectString(t, "375", strconv.FormatUint(stat.SizeBytes, 10))
+	expectString(t, "[100 50 5 200 20]", fmt.Sprint(stat.SegmentSizes))
+	expectString(t, "5 50 200", fmt.Sprintf("%d %d %d", stat.MinimumSegmentSize, stat.MedianSegmentSize, stat.MaximumSegmentSize))
+	expectString(t, "5 1 1 2", fmt.Sprintf("%d %d %d %d", stat.SegmentCount, stat.RangeSegmentCount, stat.DataSegmentCount, stat.ForeignSegmentCount))
+}
+
+func TestLargeObjectWithUnloadedSegments(t *testing.T) {
+	a, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusOK, "hello")})
+	must(t, err)
+	c := a.Container("segments")
+	lo := &LargeObject{
+		object:           a.Container("foo").Object("bar"),
+		segmentContainer: c,
+		segmentPrefix:    "bar/",
+		strategy:         DynamicLargeObject,
+		fetchSegments: func(ctx context.Context) ([]SegmentInfo, error) {
+			return []SegmentInfo{{Object: c.Object("bar/0000000000000001"), SizeBytes: 5}}, nil
+		},
+	}
+
+	// the existing segments must not be guessed
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NextSegmentObject() to panic")
+		}
+		must(t, lo.LoadSegments(context.Background()))
+		expectString(t, "bar/0000000000000002", lo.NextSegmentObject().Name())
+	}()
+	lo.NextSegmentObject()
+}
+
+func TestInferSegmentLocation(t *testing.T) {
+	a, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusOK, "hello")})
+	must(t, err)
+	c1 := a.Container("segments")
+	c2 := a.Container("other")
+
+	check := func(segments []SegmentInfo, opts SegmentLocationOptions, expected string) {
+		t.Helper()
+		c, prefix := InferSegmentLocation(segments, opts)
+		actual := "<none>"
+		if c != nil {
+			actual = c.Name() + " " + prefix
+		}
+		expectString(t, expected, actual)
+	}
+
+	segments := []SegmentInfo{
+		{Object: c1.Object("foo/bar/0001")},
+		{Data: []byte("hello")},
+		{Object: c2.Object("unrelated")},
+		{Object: c1.Object("foo/bar/0002")},
+	}
+	check(segments, SegmentLocationOptions{}, "segments foo/bar/")
+	check(segments, SegmentLocationOptions{KeepPartialPathElement: true}, "segments foo/bar/000")
+	check(segments[:3], SegmentLocationOptions{}, "segments foo/bar/") // tie is broken by order
+	check(segments[1:2], SegmentLocationOptions{}, "<none>")
+
+	// prefix does not end in the middle of a multi-byte character
+	check([]SegmentInfo{{Object: c1.Object("tür")}, {Object: c1.Object("tüx")}}, SegmentLocationOptions{}, "segments tü")
+	check([]SegmentInfo{{Object: c1.Object("aä")}, {Object: c1.Object("aö")}}, SegmentLocationOptions{}, "segments a")
+
+	// inferred location can be overridden
+	lo, err := a.Container("foo").Object("bar").AsNewLargeObject(context.Background(), SegmentingOptions{
+		SegmentContainer: c1,
+		SegmentPrefix:    "bar/",
+	}, nil)
+	must(t, err)
+	must(t, lo.SetSegmentLocation(c2, "baz/"))
+	expectString(t, "other/baz/0000000000000001", lo.NextSegmentObject().FullName())
+	b, err := InitializeAccount(&testBackend{handle: respondWith(http.StatusNoContent, "")})
+	must(t, err)
+	if err := lo.SetSegmentLocation(b.SwitchAccount("AUTH_other").Container("x"), ""); !errors.Is(err, ErrAccountMismatch) {
+		t.Errorf("expected ErrAccountMismatch, got %v", err)
+	}
+}
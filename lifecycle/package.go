@@ -0,0 +1,400 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package lifecycle applies lifecycle policies to the objects in a Swift
+account. Swift has no server-side equivalent to S3's lifecycle configuration,
+so this package evaluates the policies on the client side, either on demand
+or periodically. For example:
+
+	import "github.com/majewsky/schwift/v2/lifecycle"
+
+	engine := lifecycle.Engine{
+		Account: account,
+		Rules: []lifecycle.Rule{{
+			Name:             "expire-logs",
+			ContainerPattern: "logs-*",
+			Action:           lifecycle.ActionExpire,
+			ExpireAfter:      30 * 24 * time.Hour,
+		}, {
+			Name:             "archive-reports",
+			ContainerPattern: "reports",
+			ObjectPrefix:     "monthly/",
+			MinAge:           90 * 24 * time.Hour,
+			Action:           lifecycle.ActionSetMetadata,
+			Metadata:         map[string]string{"Tier": "archive"},
+		}},
+	}
+	report, err := engine.Run(ctx)
+
+Objects are found by listing the matching containers, and all filters are
+evaluated on the detailed object listing, so no HEAD requests are needed to
+decide whether a rule applies to an object. Objects are deleted with
+Account.BulkDelete(), except for those that may be large object manifests
+(see documentation on type Rule).
+*/
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/internal/pathmatch"
+)
+
+// Action is an enum of things that a Rule can do to the objects that it
+// applies to.
+type Action int
+
+const (
+	// ActionDelete deletes the object immediately.
+	ActionDelete Action = iota
+	// ActionExpire schedules the object for deletion by Swift by setting its
+	// X-Delete-At to Rule.ExpireAfter after its last modification. Objects
+	// whose expiry date is already in the past are deleted immediately.
+	ActionExpire
+	// ActionSetMetadata sets the metadata from Rule.Metadata on the object,
+	// while retaining all other metadata. This can be used to mark objects for
+	// transition to a different tier, for example.
+	ActionSetMetadata
+)
+
+// Rule describes a lifecycle policy: which objects it applies to, and what
+// to do with them.
+//
+// Large objects need special care. When a rule deletes or expires a large
+// object manifest, its segments are deleted or expired along with it. To
+// recognize manifests, the listing must report them as such: static large
+// objects are only recognized if the server reports SLO Etags in listings
+// (see ObjectInfo.IsStaticLargeObject), and dynamic large objects are only
+// recognized because their manifests are empty objects. Objects that may be
+// manifests are checked with a HEAD request before acting on them.
+//
+// Conversely, a rule must not delete segments that are still referenced by a
+// manifest. Containers whose name ends in "_segments" (the naming convention
+// for segment containers used by most Swift clients) are skipped unless
+// IncludeSegmentContainers is set. However, segments that are stored in the
+// same container as other objects (as done by Object.Append(), for example)
+// cannot be recognized, so choose ObjectPrefix accordingly.
+type Rule struct {
+	// Name identifies the rule in the Report.
+	Name string
+	// The rule applies to all containers whose name matches this pattern. The
+	// syntax is that of path.Match(), so use "*" to match all containers.
+	ContainerPattern string
+	// If IncludeSegmentContainers is set, containers whose name ends in
+	// "_segments" are not skipped.
+	IncludeSegmentContainers bool
+	// If ObjectPrefix is set, the rule only applies to objects whose name
+	// starts with this string.
+	ObjectPrefix string
+	// If MinAge is set, the rule only applies to objects that were last
+	// modified at least this long ago.
+	MinAge time.Duration
+	// If MinSizeBytes or MaxSizeBytes is set, the rule only applies to objects
+	// whose size is within these bounds (inclusive).
+	MinSizeBytes uint64
+	MaxSizeBytes uint64
+
+	Action Action
+	// ExpireAfter is required for ActionExpire.
+	ExpireAfter time.Duration
+	// Metadata is required for ActionSetMetadata. Keys are given without the
+	// "X-Object-Meta-" prefix.
+	Metadata map[string]string
+}
+
+func (r Rule) validate() error {
+	_, err := path.Match(r.ContainerPattern, "")
+	switch {
+	case err != nil:
+		return fmt.Errorf("invalid ContainerPattern: %w", err)
+	case r.MaxSizeBytes != 0 && r.MaxSizeBytes < r.MinSizeBytes:
+		return errors.New("value of MaxSizeBytes is smaller than MinSizeBytes")
+	case r.Action == ActionExpire && r.ExpireAfter <= 0:
+		return errors.New("value of ExpireAfter must be positive for ActionExpire")
+	case r.Action == ActionSetMetadata && len(r.Metadata) == 0:
+		return errors.New("value of Metadata must not be empty for ActionSetMetadata")
+	case r.Action < ActionDelete || r.Action > ActionSetMetadata:
+		return fmt.Errorf("unknown action: %d", r.Action)
+	}
+	return nil
+}
+
+func (r Rule) appliesTo(info schwift.ObjectInfo, now time.Time) bool {
+	switch {
+	case info.SubDirectory != "":
+		return false
+	case now.Sub(info.LastModified) < r.MinAge:
+		return false
+	case info.SizeBytes < r.MinSizeBytes:
+		return false
+	case r.MaxSizeBytes != 0 && info.SizeBytes > r.MaxSizeBytes:
+		return false
+	default:
+		return true
+	}
+}
+
+// Engine applies a set of Rules to the objects in an account.
+type Engine struct {
+	Account *schwift.Account
+	Rules   []Rule
+	// If DryRun is set, the engine only reports which objects the rules apply
+	// to, without changing them.
+	DryRun bool
+	// Now returns the current time. If nil, time.Now() is used. This can be
+	// overridden in tests.
+	Now func() time.Time
+}
+
+// Report contains the results of Engine.Run().
+type Report struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// One entry per rule, in the same order as in Engine.Rules.
+	Rules []RuleReport
+}
+
+// RuleReport contains the results of applying a single rule.
+type RuleReport struct {
+	Name              string
+	ContainersScanned int
+	// ObjectsScanned counts the objects that matched the ObjectPrefix.
+	ObjectsScanned int
+	// ObjectsMatched counts the objects that matched all filters.
+	ObjectsMatched int
+	ObjectsDeleted int
+	// ObjectsUpdated counts objects whose X-Delete-At or metadata was set.
+	ObjectsUpdated int
+	// Failures contains errors that occurred while acting on individual
+	// objects. Such errors do not abort the run.
+	Failures []Failure
+}
+
+// Failure appears in RuleReport when a rule could not be applied to an object.
+type Failure struct {
+	Object *schwift.Object
+	Err    error
+}
+
+// Run applies all rules once. Rules are applied one after the other. If a
+// rule is invalid, no rules are applied and an error is returned. If listing
+// containers or objects fails, the run is aborted and the error is returned
+// together with the report up to that point. Errors while acting on
+// individual objects are only recorded in the report.
+func (e *Engine) Run(ctx context.Context) (Report, error) {
+	for _, rule := range e.Rules {
+		err := rule.validate()
+		if err != nil {
+			return Report{}, fmt.Errorf("invalid lifecycle rule %q: %w", rule.Name, err)
+		}
+	}
+
+	report := Report{StartedAt: e.now()}
+	for _, rule := range e.Rules {
+		rr, err := e.applyRule(ctx, rule)
+		report.Rules = append(report.Rules, rr)
+		if err != nil {
+			report.FinishedAt = e.now()
+			return report, err
+		}
+	}
+	report.FinishedAt = e.now()
+	return report, nil
+}
+
+// RunEvery calls Run() immediately, and then once per interval until the
+// context expires. After each run, the callback is called with the results.
+// Returns the context's error.
+func (e *Engine) RunEvery(ctx context.Context, interval time.Duration, callback func(Report, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := e.Run(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		callback(report, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+	return e.Now()
+}
+
+func (e *Engine) applyRule(ctx context.Context, rule Rule) (RuleReport, error) {
+	rr := RuleReport{Name: rule.Name}
+
+	// restrict the container listing to the literal prefix of the pattern
+	iter := e.Account.Containers()
+	iter.Prefix = pathmatch.LiteralPrefix(rule.ContainerPattern)
+
+	err := iter.Foreach(ctx, func(c *schwift.Container) error {
+		if ok, _ := path.Match(rule.ContainerPattern, c.Name()); !ok {
+			return nil
+		}
+		if !rule.IncludeSegmentContainers && strings.HasSuffix(c.Name(), "_segments") {
+			return nil
+		}
+		rr.ContainersScanned++
+		return e.applyRuleToContainer(ctx, rule, c, &rr)
+	})
+	return rr, err
+}
+
+func (e *Engine) applyRuleToContainer(ctx context.Context, rule Rule, c *schwift.Container, rr *RuleReport) error {
+	iter := c.Objects()
+	iter.Prefix = rule.ObjectPrefix
+	for {
+		infos, err := iter.NextPageDetailed(ctx, -1)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			return nil
+		}
+
+		now := e.now()
+		var toDelete []*schwift.Object
+		for _, info := range infos {
+			if info.SubDirectory != "" {
+				continue
+			}
+			rr.ObjectsScanned++
+			if !rule.appliesTo(info, now) {
+				continue
+			}
+			rr.ObjectsMatched++
+			if e.DryRun {
+				continue
+			}
+
+			switch rule.Action {
+			case ActionDelete:
+				if mayBeLargeObject(info) {
+					e.deleteWithSegments(ctx, info.Object, rr)
+				} else {
+					toDelete = append(toDelete, info.Object)
+				}
+			case ActionExpire:
+				expiresAt := info.LastModified.Add(rule.ExpireAfter)
+				if !expiresAt.After(now) {
+					if mayBeLargeObject(info) {
+						e.deleteWithSegments(ctx, info.Object, rr)
+					} else {
+						toDelete = append(toDelete, info.Object)
+					}
+					continue
+				}
+				hdr := schwift.NewObjectHeaders()
+				hdr.ExpiresAt().Set(expiresAt)
+				if e.update(ctx, info.Object, hdr, rr) && mayBeLargeObject(info) {
+					e.expireSegments(ctx, info.Object, rr)
+				}
+			case ActionSetMetadata:
+				hdr := schwift.NewObjectHeaders()
+				for key, value := range rule.Metadata {
+					hdr.Metadata().Set(key, value)
+				}
+				e.update(ctx, info.Object, hdr, rr)
+			}
+		}
+
+		if len(toDelete) > 0 {
+			err := e.delete(ctx, toDelete, rr)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mayBeLargeObject returns whether the listed object may be a large object
+// manifest. See documentation on type Rule for the limitations of this check.
+func mayBeLargeObject(info schwift.ObjectInfo) bool {
+	return info.IsStaticLargeObject() || info.SizeBytes == 0
+}
+
+func (e *Engine) update(ctx context.Context, obj *schwift.Object, hdr schwift.ObjectHeaders, rr *RuleReport) bool {
+	err := obj.UpdatePreservingMetadata(ctx, hdr, nil)
+	if err != nil {
+		rr.Failures = append(rr.Failures, Failure{obj, err})
+		return false
+	}
+	rr.ObjectsUpdated++
+	return true
+}
+
+// deleteWithSegments deletes an object that may be a large object manifest,
+// including its segments.
+func (e *Engine) deleteWithSegments(ctx context.Context, obj *schwift.Object, rr *RuleReport) {
+	err := obj.Delete(ctx, &schwift.DeleteOptions{DeleteSegments: true}, nil)
+	if err != nil {
+		rr.Failures = append(rr.Failures, Failure{obj, err})
+		return
+	}
+	rr.ObjectsDeleted++
+}
+
+// expireSegments gives the segments of a large object manifest the same
+// X-Delete-At as the manifest.
+func (e *Engine) expireSegments(ctx context.Context, obj *schwift.Object, rr *RuleReport) {
+	lo, err := obj.AsLargeObject(ctx)
+	if errors.Is(err, schwift.ErrNotLarge) {
+		return
+	}
+	if err == nil {
+		_, err = lo.AlignSegmentExpiry(ctx, nil)
+	}
+	if err != nil {
+		rr.Failures = append(rr.Failures, Failure{obj, err})
+	}
+}
+
+func (e *Engine) delete(ctx context.Context, objects []*schwift.Object, rr *RuleReport) error {
+	numDeleted, _, err := e.Account.BulkDelete(ctx, objects, nil, nil)
+	rr.ObjectsDeleted += numDeleted
+
+	// errors for individual objects are reported as failures, but errors that
+	// concern the bulk request as a whole are fatal
+	var bulkErr schwift.BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr.ObjectErrors) == 0 {
+		return err
+	}
+	for _, oe := range bulkErr.ObjectErrors {
+		if obj := oe.Object(e.Account); obj != nil {
+			rr.Failures = append(rr.Failures, Failure{obj, oe})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ListingCache caches the pages returned by ObjectIterator, to speed up
+// applications (such as UIs) that repeatedly list the same container. Caching
+// is strictly opt-in: a ListingCache is only used by iterators whose Cache
+// field refers to it. For example:
+//
+//	cache := schwift.NewListingCache(time.Minute)
+//	iter := container.Objects()
+//	iter.Prefix = "photos/"
+//	iter.Cache = cache
+//	infos, err := iter.NextPageDetailed(ctx, 100)
+//
+// Pages are keyed by container and by all query parameters of the listing
+// request (most importantly, prefix and marker). Before a cached page is
+// reused, the container is revalidated with a HEAD request: The page is only
+// reused if the container's Last-Modified, X-Timestamp, object count and bytes
+// used are unchanged since the page was fetched. This HEAD request is
+// considerably cheaper than a listing, but since Swift updates the object
+// count and bytes used asynchronously, and since some changes (e.g.
+// overwriting an object with one of the same size) do not affect these values
+// at all, revalidation cannot catch every change. The TTL bounds how long such
+// changes can go unnoticed.
+//
+// A ListingCache is safe for concurrent use by multiple iterators.
+type ListingCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]listingCacheEntry
+}
+
+type listingCacheEntry struct {
+	validator string
+	fetchedAt time.Time
+	body      []byte
+}
+
+// NewListingCache creates a new ListingCache. Cached pages are never reused
+// once they are older than the given TTL, regardless of revalidation.
+func NewListingCache(ttl time.Duration) *ListingCache {
+	return &ListingCache{
+		ttl:     ttl,
+		entries: make(map[string]listingCacheEntry),
+	}
+}
+
+// Clear removes all pages from this cache.
+func (lc *ListingCache) Clear() {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+	clear(lc.entries)
+}
+
+// get returns the cached page for the given key if it has not expired yet.
+func (lc *ListingCache) get(key string) (listingCacheEntry, bool) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	entry, exists := lc.entries[key]
+	if !exists {
+		return listingCacheEntry{}, false
+	}
+	if time.Since(entry.fetchedAt) > lc.ttl {
+		delete(lc.entries, key)
+		return listingCacheEntry{}, false
+	}
+	return entry, true
+}
+
+func (lc *ListingCache) put(key string, entry listingCacheEntry) {
+	lc.mutex.Lock()
+	defer lc.mutex.Unlock()
+
+	// opportunistically clean up expired entries to bound memory usage
+	for k, e := range lc.entries {
+		if time.Since(e.fetchedAt) > lc.ttl {
+			delete(lc.entries, k)
+		}
+	}
+	lc.entries[key] = entry
+}
+
+// listingValidator returns a string that changes whenever the container
+// changes in a way that is observable by HEAD requests.
+func listingValidator(hdr ContainerHeaders) string {
+	return strings.Join([]string{
+		hdr.Get("Last-Modified"),
+		hdr.Get("X-Timestamp"),
+		hdr.Get("X-Container-Object-Count"),
+		hdr.Get("X-Container-Bytes-Used"),
+	}, "|")
+}
+
+// fetch executes the given listing request, or serves its response from this
+// cache if possible.
+func (lc *ListingCache) fetch(ctx context.Context, c *Container, r Request, fetchFromServer func() ([]byte, error)) ([]byte, error) {
+	key := c.a.Name() + "/" + c.name + "?" + r.Options.Values.Encode()
+	entry, exists := lc.get(key)
+	if exists {
+		// revalidate with a HEAD request
+		c.Invalidate()
+		hdr, err := c.Headers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if entry.validator == listingValidator(hdr) {
+			return entry.body, nil
+		}
+	}
+
+	body, err := fetchFromServer()
+	if err != nil {
+		return nil, err
+	}
+	hdr, err := c.Headers(ctx) // this was filled by the listing request
+	if err != nil {
+		return nil, err
+	}
+	lc.put(key, listingCacheEntry{listingValidator(hdr), time.Now(), body})
+	return body, nil
+}
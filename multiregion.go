@@ -0,0 +1,244 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegionEndpoint describes the endpoint of a Swift account in a specific
+// region, e.g. as listed in the Keystone catalog. It appears in
+// NewMultiRegionBackend().
+type RegionEndpoint struct {
+	Region string
+	// URL has the same format as the return value of Backend.EndpointURL().
+	URL string
+}
+
+// ReadPreference selects which endpoint is used for GET and HEAD requests by a
+// backend created with NewMultiRegionBackend().
+type ReadPreference struct {
+	// If Region is set, reads are directed to the endpoint of this region.
+	Region string
+	// If Nearest is set (and Region is not), reads are directed to the endpoint
+	// with the lowest latency. Before the first read, each endpoint is probed
+	// with a HEAD request on the account, and the fastest endpoint that
+	// responds successfully is used from then on. Reads that are made while the
+	// probe is running are directed to the write endpoint instead of waiting
+	// for the probe. If no endpoint responds successfully, reads are directed
+	// to the write endpoint, and the probe is repeated before a later read,
+	// with exponential backoff between 1 second and 5 minutes.
+	Nearest bool
+}
+
+// NewMultiRegionBackend wraps a Backend to direct GET and HEAD requests to a
+// different endpoint than all other requests. Writes always go to the
+// endpoint of the inner backend, whereas reads go to one of the given
+// endpoints as selected by the ReadPreference. If the preference is empty,
+// all requests go to the inner backend's endpoint.
+//
+// All endpoints must refer to the same account (i.e. with the same auth
+// token), which is usually the case for the object-store endpoints of
+// different regions in the Keystone catalog. The objects in the other regions
+// are typically replicated by container-sync, so reads may return stale data
+// until replication has caught up.
+//
+// To use the new backend, pass it to InitializeAccount(). Since Clone()
+// replaces the account name in all endpoints, Account.SwitchAccount() works as
+// expected.
+func NewMultiRegionBackend(inner Backend, endpoints []RegionEndpoint, pref ReadPreference) (Backend, error) {
+	for _, ep := range endpoints {
+		_, err := url.Parse(ep.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint URL for region %q: %w", ep.Region, err)
+		}
+	}
+
+	b := &multiRegionBackend{inner: inner, endpoints: endpoints, pref: pref}
+	if pref.Region != "" {
+		for _, ep := range endpoints {
+			if ep.Region == pref.Region {
+				b.readEndpointURL = ep.URL
+			}
+		}
+		if b.readEndpointURL == "" {
+			return nil, fmt.Errorf("no endpoint given for preferred region %q", pref.Region)
+		}
+	}
+	return b, nil
+}
+
+type multiRegionBackend struct {
+	inner     Backend
+	endpoints []RegionEndpoint
+	pref      ReadPreference
+
+	mutex           sync.Mutex
+	readEndpointURL string
+	// state of probeNearestEndpoint()
+	probing      bool
+	nextProbeAt  time.Time
+	probeBackoff time.Duration
+}
+
+const (
+	minProbeBackoff = time.Second
+	maxProbeBackoff = 5 * time.Minute
+	probeTimeout    = 10 * time.Second
+)
+
+func (b *multiRegionBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b *multiRegionBackend) Clone(newEndpointURL string) Backend {
+	accountName := path.Base(strings.TrimSuffix(newEndpointURL, "/"))
+	endpoints := make([]RegionEndpoint, len(b.endpoints))
+	for idx, ep := range b.endpoints {
+		endpoints[idx] = RegionEndpoint{ep.Region, replaceAccountInEndpointURL(ep.URL, accountName)}
+	}
+
+	// the selected read endpoint (including the result of a previous probe)
+	// carries over to the new account
+	b.mutex.Lock()
+	readEndpointURL := b.readEndpointURL
+	b.mutex.Unlock()
+	if readEndpointURL != "" {
+		readEndpointURL = replaceAccountInEndpointURL(readEndpointURL, accountName)
+	}
+
+	return &multiRegionBackend{
+		inner:           b.inner.Clone(newEndpointURL),
+		endpoints:       endpoints,
+		pref:            b.pref,
+		readEndpointURL: readEndpointURL,
+	}
+}
+
+func replaceAccountInEndpointURL(endpointURL, accountName string) string {
+	trimmed := strings.TrimSuffix(endpointURL, "/")
+	return trimmed[:strings.LastIndex(trimmed, "/")+1] + accountName + "/"
+}
+
+func (b *multiRegionBackend) Do(req *http.Request) (*http.Response, error) {
+	if !isReadMethod(req.Method) {
+		return b.inner.Do(req)
+	}
+	readEndpointURL := b.getReadEndpointURL(req.Context())
+	if readEndpointURL != "" {
+		err := redirectToEndpoint(req, b.inner.EndpointURL(), readEndpointURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.inner.Do(req)
+}
+
+func (b *multiRegionBackend) getReadEndpointURL(ctx context.Context) string {
+	b.mutex.Lock()
+	if b.readEndpointURL != "" || !b.pref.Nearest || b.probing || time.Now().Before(b.nextProbeAt) {
+		defer b.mutex.Unlock()
+		return b.readEndpointURL
+	}
+	b.probing = true
+	b.mutex.Unlock()
+
+	// the probe benefits all later reads, so it shall not be aborted just
+	// because the request that triggered it is canceled
+	probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), probeTimeout)
+	defer cancel()
+	readEndpointURL := b.probeNearestEndpoint(probeCtx)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.probing = false
+	b.readEndpointURL = readEndpointURL
+	if readEndpointURL == "" {
+		b.probeBackoff = min(max(2*b.probeBackoff, minProbeBackoff), maxProbeBackoff)
+		b.nextProbeAt = time.Now().Add(b.probeBackoff)
+	} else {
+		b.probeBackoff = 0
+	}
+	return readEndpointURL
+}
+
+// probeNearestEndpoint returns the URL of the endpoint that responds fastest
+// to a HEAD request on the account, or "" if none responds successfully.
+func (b *multiRegionBackend) probeNearestEndpoint(ctx context.Context) string {
+	var (
+		bestURL      string
+		bestDuration time.Duration
+	)
+	for _, ep := range b.endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, ep.URL, http.NoBody)
+		if err != nil {
+			continue
+		}
+		start := time.Now()
+		resp, err := b.inner.Do(req)
+		if err != nil {
+			continue
+		}
+		duration := time.Since(start)
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			continue
+		}
+		if bestURL == "" || duration < bestDuration {
+			bestURL = ep.URL
+			bestDuration = duration
+		}
+	}
+	return bestURL
+}
+
+// redirectToEndpoint rewrites the URL of a request directed at the account at
+// `fromURL` into the same request directed at `toURL`. Requests not directed at
+// that account (e.g. GET /info) are not changed.
+func redirectToEndpoint(req *http.Request, fromURL, toURL string) error {
+	from, err := url.Parse(fromURL)
+	if err != nil {
+		return err
+	}
+	to, err := url.Parse(toURL)
+	if err != nil {
+		return err
+	}
+	if req.URL.Host != from.Host || !strings.HasPrefix(req.URL.Path, from.Path) {
+		return nil
+	}
+
+	newURL := *req.URL
+	newURL.Scheme = to.Scheme
+	newURL.Host = to.Host
+	newURL.Path = to.Path + strings.TrimPrefix(req.URL.Path, from.Path)
+	if req.URL.RawPath != "" {
+		newURL.RawPath = to.EscapedPath() + strings.TrimPrefix(req.URL.RawPath, from.EscapedPath())
+	}
+	req.URL = &newURL
+	req.Host = to.Host
+	return nil
+}
@@ -0,0 +1,120 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultiRegionBackend(t *testing.T) {
+	ctx := context.Background()
+
+	// the backend responds to all requests after a delay that depends on the
+	// host, and records the URLs of all requests; requests to hosts without a
+	// delay entry fail with 503
+	delays := map[string]time.Duration{
+		"home.example.com": 50 * time.Millisecond,
+		"far.example.com":  20 * time.Millisecond,
+		"near.example.com": 0,
+	}
+	var requests []string
+	inner := &testBackend{
+		endpointURL: "https://home.example.com/v1/AUTH_example/",
+		handle: func(req *http.Request) (*http.Response, error) {
+			delay, ok := delays[req.URL.Host]
+			time.Sleep(delay)
+			requests = append(requests, req.Method+" "+req.URL.String())
+			if !ok {
+				return testResponse(http.StatusServiceUnavailable, ""), nil
+			}
+			return testResponse(http.StatusNoContent, ""), nil
+		},
+	}
+	endpoints := []RegionEndpoint{
+		{"home", "https://home.example.com/v1/AUTH_example/"},
+		{"far", "https://far.example.com/v1/AUTH_example/"},
+		{"near", "https://near.example.com/v1/AUTH_example/"},
+	}
+
+	// with a specific region, reads go there and writes go to the home region
+	b, err := NewMultiRegionBackend(inner, endpoints, ReadPreference{Region: "far"})
+	must(t, err)
+	a, err := InitializeAccount(b)
+	must(t, err)
+	c := a.Container("foo bar")
+	_, err = c.Headers(ctx)
+	must(t, err)
+	must(t, c.Update(ctx, NewContainerHeaders(), nil))
+	expectString(t, strings.Join([]string{
+		"HEAD https://far.example.com/v1/AUTH_example/foo%20bar/",
+		"POST https://home.example.com/v1/AUTH_example/foo%20bar/",
+	}, "\n"), strings.Join(requests, "\n"))
+
+	// the preference carries over to other accounts
+	requests = nil
+	other := a.SwitchAccount("AUTH_other")
+	_, err = other.Headers(ctx)
+	must(t, err)
+	expectString(t, "HEAD https://far.example.com/v1/AUTH_other/", strings.Join(requests, "\n"))
+
+	// with the nearest region, reads go to the fastest endpoint after probing
+	requests = nil
+	b, err = NewMultiRegionBackend(inner, endpoints, ReadPreference{Nearest: true})
+	must(t, err)
+	a, err = InitializeAccount(b)
+	must(t, err)
+	_, err = a.Container("foo").Headers(ctx)
+	must(t, err)
+	expectString(t, strings.Join([]string{
+		"HEAD https://home.example.com/v1/AUTH_example/",
+		"HEAD https://far.example.com/v1/AUTH_example/",
+		"HEAD https://near.example.com/v1/AUTH_example/",
+		"HEAD https://near.example.com/v1/AUTH_example/foo/",
+	}, "\n"), strings.Join(requests, "\n"))
+
+	// when no endpoint responds, reads go to the home region, and the probe is
+	// not repeated for every read
+	requests = nil
+	delays = map[string]time.Duration{"home.example.com": 0}
+	b, err = NewMultiRegionBackend(inner, endpoints[1:], ReadPreference{Nearest: true})
+	must(t, err)
+	a, err = InitializeAccount(b)
+	must(t, err)
+	for range 2 {
+		a.Invalidate()
+		_, err = a.Headers(ctx)
+		must(t, err)
+	}
+	expectString(t, strings.Join([]string{
+		"HEAD https://far.example.com/v1/AUTH_example/",
+		"HEAD https://near.example.com/v1/AUTH_example/",
+		"HEAD https://home.example.com/v1/AUTH_example/",
+		"HEAD https://home.example.com/v1/AUTH_example/",
+	}, "\n"), strings.Join(requests, "\n"))
+
+	// unknown regions are rejected
+	_, err = NewMultiRegionBackend(inner, endpoints, ReadPreference{Region: "moon"})
+	if err == nil || err.Error() != `no endpoint given for preferred region "moon"` {
+		t.Errorf("expected error for unknown region, got %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// NameSharder distributes object names across multiple shards by prepending a
+// shard prefix to them. Under heavy PUT loads, this avoids hot spots in the
+// container database that can occur when many objects with similar names
+// (e.g. names starting with a timestamp) are written at the same time. It is
+// used by ShardedNamespace.
+type NameSharder interface {
+	// ShardPrefix returns the prefix that is prepended to the given logical
+	// name to obtain the physical object name. It must be one of the values
+	// returned by Shards().
+	ShardPrefix(logicalName string) string
+	// Shards returns all possible return values of ShardPrefix(). No element
+	// may be a prefix of another element.
+	Shards() []string
+}
+
+// HashPrefixSharder is a NameSharder that prepends a hex-encoded prefix of the
+// SHA-256 hash of the logical name, followed by a slash. For example, with
+// Digits = 2, the logical name "invoice.pdf" is stored as "41/invoice.pdf".
+// The number of shards is 16 to the power of Digits.
+type HashPrefixSharder struct {
+	// Digits must be between 1 and 6 (inclusive). Values outside of this range
+	// are treated like the nearest value within the range.
+	Digits int
+}
+
+func (s HashPrefixSharder) digits() int {
+	return min(max(s.Digits, 1), 6)
+}
+
+// ShardPrefix implements the NameSharder interface.
+func (s HashPrefixSharder) ShardPrefix(logicalName string) string {
+	sum := sha256.Sum256([]byte(logicalName))
+	return hex.EncodeToString(sum[:])[:s.digits()] + "/"
+}
+
+// Shards implements the NameSharder interface.
+func (s HashPrefixSharder) Shards() []string {
+	result := []string{""}
+	for range s.digits() {
+		next := make([]string, 0, len(result)*16)
+		for _, prefix := range result {
+			for _, digit := range "0123456789abcdef" {
+				next = append(next, prefix+string(digit))
+			}
+		}
+		result = next
+	}
+	for idx := range result {
+		result[idx] += "/"
+	}
+	return result
+}
+
+// ShardedNamespace is a handle for a set of objects whose names are sharded
+// with a NameSharder. Instances are obtained with Container.Sharded() or
+// Directory.Sharded(). For example:
+//
+//	ns := container.Dir("uploads").Sharded(schwift.HashPrefixSharder{Digits: 2})
+//	ns.Object("invoice.pdf").Name()           //returns "uploads/41/invoice.pdf"
+//	ns.LogicalName("uploads/41/invoice.pdf")  //returns "invoice.pdf", true
+//
+// Since the physical object names are not in the same order as the logical
+// names, Walk() and Collect() list each shard separately and merge the
+// results.
+//
+// Like Container.Object(), none of the methods that return handles issue any
+// HTTP requests.
+type ShardedNamespace struct {
+	c       *Container
+	prefix  string
+	sharder NameSharder
+}
+
+// Sharded returns a handle to the sharded namespace covering this entire
+// container. See documentation on type ShardedNamespace for details.
+func (c *Container) Sharded(sharder NameSharder) *ShardedNamespace {
+	return &ShardedNamespace{c: c, sharder: sharder}
+}
+
+// Sharded returns a handle to the sharded namespace within this directory.
+// See documentation on type ShardedNamespace for details.
+func (d *Directory) Sharded(sharder NameSharder) *ShardedNamespace {
+	return &ShardedNamespace{c: d.c, prefix: d.prefix, sharder: sharder}
+}
+
+// Container returns a handle to the container this namespace is located in.
+func (n *ShardedNamespace) Container() *Container {
+	return n.c
+}
+
+// PhysicalName returns the object name under which the given logical name is
+// stored.
+func (n *ShardedNamespace) PhysicalName(logicalName string) string {
+	return n.prefix + n.sharder.ShardPrefix(logicalName) + logicalName
+}
+
+// LogicalName is the inverse of PhysicalName(). If the given object name does
+// not belong to this namespace, false is returned.
+func (n *ShardedNamespace) LogicalName(physicalName string) (string, bool) {
+	rest, ok := strings.CutPrefix(physicalName, n.prefix)
+	if !ok {
+		return "", false
+	}
+	// Since no shard prefix is a prefix of another, at most one way of
+	// splitting `rest` into shard prefix and logical name can be consistent with
+	// the sharder. Shard prefixes are usually short, so trying the shortest
+	// splits first finds it quickly without enumerating all shards.
+	for idx := 1; idx <= len(rest); idx++ {
+		shard, logicalName := rest[:idx], rest[idx:]
+		if n.sharder.ShardPrefix(logicalName) == shard {
+			return logicalName, true
+		}
+	}
+	return "", false
+}
+
+// Object returns a handle to the object with the given logical name.
+func (n *ShardedNamespace) Object(logicalName string) *Object {
+	return n.c.Object(n.PhysicalName(logicalName))
+}
+
+// Walk calls the callback once for every object in this namespace whose
+// logical name starts with the given prefix, in the lexical order of the
+// logical names. Objects in the container that do not belong to this namespace
+// are skipped. Iteration is aborted when a GET request fails, or when the
+// callback returns a non-nil error.
+//
+// This issues at least one listing request for each shard. Since the first
+// object of every shard is needed to determine which object comes first, the
+// first page of each shard is fetched before the first callback. These
+// requests are sent concurrently (up to 8 at a time).
+func (n *ShardedNamespace) Walk(ctx context.Context, prefix string, callback func(logicalName string, info ObjectInfo) error) error {
+	shards := n.sharder.Shards()
+	allCursors := make([]*shardCursor, len(shards))
+	for idx, shard := range shards {
+		// each cursor gets its own container handle, since the iterators write
+		// into the container's header cache concurrently
+		c := n.c.a.Container(n.c.name)
+		allCursors[idx] = &shardCursor{
+			iter:        &ObjectIterator{Container: c, Prefix: n.prefix + shard + prefix},
+			namePrefix:  n.prefix + shard,
+			shardPrefix: shard,
+			sharder:     n.sharder,
+		}
+	}
+	err := forEachConcurrently(ctx, 8, allCursors, func(ctx context.Context, cur *shardCursor) error {
+		return cur.fill(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	var cursors shardCursorHeap
+	for _, cur := range allCursors {
+		if len(cur.page) > 0 {
+			cursors = append(cursors, cur)
+		}
+	}
+	heap.Init(&cursors)
+
+	for len(cursors) > 0 {
+		cur := cursors[0]
+		info := cur.page[0]
+		err := callback(cur.logicalName(), info)
+		if err != nil {
+			return err
+		}
+		cur.page = cur.page[1:]
+		err = cur.fill(ctx)
+		if err != nil {
+			return err
+		}
+		if len(cur.page) == 0 {
+			heap.Pop(&cursors)
+		} else {
+			heap.Fix(&cursors, 0)
+		}
+	}
+	return nil
+}
+
+// Collect returns the logical names of all objects in this namespace whose
+// logical name starts with the given prefix, in lexical order. See Walk() for
+// details.
+func (n *ShardedNamespace) Collect(ctx context.Context, prefix string) ([]string, error) {
+	var result []string
+	err := n.Walk(ctx, prefix, func(logicalName string, _ ObjectInfo) error {
+		result = append(result, logicalName)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// shardCursor is used by ShardedNamespace.Walk() to traverse a single shard.
+type shardCursor struct {
+	iter        *ObjectIterator
+	namePrefix  string
+	shardPrefix string
+	sharder     NameSharder
+	page        []ObjectInfo
+	eof         bool
+}
+
+// fill ensures that the page is not empty, unless the end of the shard has
+// been reached. Objects that do not belong to the shard are skipped.
+func (c *shardCursor) fill(ctx context.Context) error {
+	for len(c.page) == 0 && !c.eof {
+		infos, err := c.iter.NextPageDetailed(ctx, -1)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			c.eof = true
+		}
+		for _, info := range infos {
+			logicalName := strings.TrimPrefix(info.Object.Name(), c.namePrefix)
+			if c.sharder.ShardPrefix(logicalName) == c.shardPrefix {
+				c.page = append(c.page, info)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *shardCursor) logicalName() string {
+	return strings.TrimPrefix(c.page[0].Object.Name(), c.namePrefix)
+}
+
+// shardCursorHeap implements heap.Interface, ordering the cursors by the
+// logical name of their next object.
+type shardCursorHeap []*shardCursor
+
+func (h shardCursorHeap) Len() int           { return len(h) }
+func (h shardCursorHeap) Less(i, j int) bool { return h[i].logicalName() < h[j].logicalName() }
+func (h shardCursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *shardCursorHeap) Push(x any) {
+	if cur, ok := x.(*shardCursor); ok {
+		*h = append(*h, cur)
+	}
+}
+
+func (h *shardCursorHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,69 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestShardedNamespace(t *testing.T) {
+	sharder := HashPrefixSharder{Digits: 2}
+	shards := sharder.Shards()
+	if len(shards) != 256 || shards[0] != "00/" || shards[255] != "ff/" {
+		t.Errorf("unexpected shards: %v", shards)
+	}
+
+	ns := (&Account{}).Container("foo").Dir("uploads").Sharded(sharder)
+	expectString(t, "uploads/41/invoice.pdf", ns.Object("invoice.pdf").Name())
+
+	testCases := []struct {
+		physicalName string
+		logicalName  string
+		ok           bool
+	}{
+		{"uploads/41/invoice.pdf", "invoice.pdf", true},
+		{"uploads/42/invoice.pdf", "", false}, // wrong shard
+		{"uploads/invoice.pdf", "", false},
+		{"other/41/invoice.pdf", "", false},
+		{"uploads/", "", false},
+	}
+	for _, tc := range testCases {
+		logicalName, ok := ns.LogicalName(tc.physicalName)
+		if logicalName != tc.logicalName || ok != tc.ok {
+			t.Errorf("expected LogicalName(%q) = (%q, %t), got (%q, %t)",
+				tc.physicalName, tc.logicalName, tc.ok, logicalName, ok)
+		}
+	}
+}
+
+func TestHashPrefixSharderDigits(t *testing.T) {
+	// out-of-range values are clamped instead of causing a panic
+	expectString(t, "4/", HashPrefixSharder{Digits: 0}.ShardPrefix("invoice.pdf"))
+	expectString(t, "41/", HashPrefixSharder{Digits: 2}.ShardPrefix("invoice.pdf"))
+	long := HashPrefixSharder{Digits: 100}.ShardPrefix("invoice.pdf")
+	expectString(t, "7", strconv.Itoa(len(long)))
+
+	// LogicalName() does not enumerate all 16^6 shards
+	ns := (&Account{}).Container("foo").Sharded(HashPrefixSharder{Digits: 6})
+	logicalName, ok := ns.LogicalName(ns.PhysicalName("invoice.pdf"))
+	if logicalName != "invoice.pdf" || !ok {
+		t.Errorf("expected LogicalName() to invert PhysicalName(), got (%q, %t)", logicalName, ok)
+	}
+}
@@ -21,9 +21,6 @@ package schwift
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/md5"  //nolint:gosec // Etag uses md5
-	"crypto/sha1" //nolint:gosec // Used by swift
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -45,6 +42,9 @@ type Object struct {
 	// cache
 	headers        *ObjectHeaders // from HEAD/GET without ?symlink=get
 	symlinkHeaders *ObjectHeaders // from HEAD/GET with ?symlink=get
+	// multi-valued headers from the responses that filled the above (see RawAll())
+	rawHeaders        http.Header
+	symlinkRawHeaders http.Header
 }
 
 // IsEqualTo returns true if both Object instances refer to the same object.
@@ -115,15 +115,35 @@ func (o *Object) Headers(ctx context.Context) (ObjectHeaders, error) {
 		return *o.headers, nil
 	}
 
-	hdr, err := o.fetchHeaders(ctx, nil)
+	hdr, raw, err := o.fetchHeadersWithRaw(ctx, nil)
 	if err != nil {
 		return ObjectHeaders{}, err
 	}
 	o.headers = hdr
+	o.rawHeaders = raw
 	return *hdr, nil
 }
 
+// RawAll returns all values of the given header in the response that the
+// cached ObjectHeaders were obtained from. This works like Account.RawAll().
+//
+// Only the ObjectHeaders returned by Headers() are considered here, not those
+// returned by SymlinkHeaders().
+func (o *Object) RawAll(key string) []string {
+	if o.headers == nil {
+		return nil
+	}
+	return rawAll(o.headers.Headers, o.rawHeaders, key)
+}
+
 func (o *Object) fetchHeaders(ctx context.Context, opts *RequestOptions) (*ObjectHeaders, error) {
+	hdr, _, err := o.fetchHeadersWithRaw(ctx, opts)
+	return hdr, err
+}
+
+// fetchHeadersWithRaw is like fetchHeaders, but also returns the multi-valued
+// headers from the response.
+func (o *Object) fetchHeadersWithRaw(ctx context.Context, opts *RequestOptions) (*ObjectHeaders, http.Header, error) {
 	resp, err := Request{
 		Method:        "HEAD",
 		ContainerName: o.c.name,
@@ -135,12 +155,12 @@ func (o *Object) fetchHeaders(ctx context.Context, opts *RequestOptions) (*Objec
 		DrainResponseBody: true,
 	}.Do(ctx, o.c.a.backend)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	headers := ObjectHeaders{headersFromHTTP(resp.Header)}
-	return &headers, headers.Validate()
+	headers := newObjectHeadersFromHTTP(resp.Header)
+	return &headers, multiValuedHeaders(resp.Header), headers.Validate()
 }
 
 // Update updates the object's headers using a POST request. To add URL
@@ -164,11 +184,111 @@ func (o *Object) Update(ctx context.Context, headers ObjectHeaders, opts *Reques
 	return err
 }
 
+// Headers besides X-Object-Meta-* that a POST request removes from an object
+// unless they are given again. Content-Type is not removed by current Swift
+// versions, but older versions (and those with object_post_as_copy enabled)
+// reset it to a default value.
+var postResetObjectHeaders = []string{
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Type",
+	"X-Delete-At",
+	"X-Object-Manifest",
+}
+
+// UpdatePreservingMetadata is like Update, but retains all existing metadata
+// that is not overwritten by the given headers. (A plain POST request replaces
+// all of the object's metadata with the metadata given in the request.)
+// As with Update(), a header with an empty value in the given headers will
+// cause this header to be removed on the server.
+//
+// To compute the full set of metadata, a HEAD request is sent before the POST
+// request, so concurrent updates to the same object may be lost.
+//
+// This operation fails with http.StatusNotFound if the object does not exist.
+//
+// A successful POST request implies Invalidate() since it may change metadata.
+func (o *Object) UpdatePreservingMetadata(ctx context.Context, headers ObjectHeaders, opts *RequestOptions) error {
+	current, err := o.fetchHeaders(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	merged := NewObjectHeaders()
+	for k, v := range current.Headers {
+		if strings.HasPrefix(k, "X-Object-Meta-") || contains(postResetObjectHeaders, k) {
+			merged.Headers[k] = v
+		}
+	}
+	for k, v := range headers.Headers {
+		if v == "" {
+			// omitting the header in the POST request removes it
+			delete(merged.Headers, k)
+		} else {
+			merged.Headers[k] = v
+		}
+	}
+	return o.Update(ctx, merged, opts)
+}
+
+// Touch updates the object's timestamp (as reported by
+// ObjectHeaders.UpdatedAt() and ObjectHeaders.CreatedAt()) without changing
+// its contents or metadata, using a metadata-preserving POST request. This
+// causes container sync to pick up the object again. To reset the object's
+// expiry at the same time, use UpdatePreservingMetadata() instead:
+//
+//	hdr := schwift.NewObjectHeaders()
+//	hdr.Set("X-Delete-After", "3600")
+//	err := obj.UpdatePreservingMetadata(ctx, hdr, nil)
+//
+// This operation fails with http.StatusNotFound if the object does not exist.
+//
+// A successful POST request implies Invalidate() since it may change metadata.
+func (o *Object) Touch(ctx context.Context, opts *RequestOptions) error {
+	return o.UpdatePreservingMetadata(ctx, NewObjectHeaders(), opts)
+}
+
 // UploadOptions invokes advanced behavior in the Object.Upload() method.
 type UploadOptions struct {
 	// When overwriting a large object, delete its segments. This will cause
 	// Upload() to call into BulkDelete(), so a BulkError may be returned.
 	DeleteSegments bool
+	// When Result is not nil, it will be filled with checksums and the size of
+	// the uploaded content after a successful upload, so that the caller does
+	// not have to read the content a second time to obtain them.
+	Result *UploadResult
+	// When ComputeSHA256 is set, Result.SHA256 will be filled in as well. This
+	// incurs additional CPU time during the upload.
+	ComputeSHA256 bool
+	// When QuotaCheck is not QuotaCheckOff, the quotas of the account and
+	// container are checked before the upload. See documentation on type
+	// QuotaCheckPolicy for details.
+	QuotaCheck QuotaCheckPolicy
+	// EtagCheck controls how the Etag reported by Swift is verified when it
+	// could not be computed in advance. See documentation on type
+	// EtagCheckPolicy for details.
+	EtagCheck EtagCheckPolicy
+}
+
+// UploadResult is filled by Object.Upload() when UploadOptions.Result is set.
+type UploadResult struct {
+	// SizeBytes is the number of bytes that were uploaded.
+	SizeBytes uint64
+	// MD5 is the hex-encoded MD5 checksum of the uploaded content. This is
+	// identical to the Etag of the object. For large object manifests, this is
+	// empty since their Etag does not refer to the uploaded content.
+	MD5 string
+	// SHA256 is the hex-encoded SHA-256 checksum of the uploaded content. It is
+	// only filled if UploadOptions.ComputeSHA256 was set.
+	SHA256 string
+	// Etag is the Etag reported by Swift for the new object. For plain objects,
+	// this is identical to MD5. For large object manifests, this is the Etag of
+	// the large object.
+	Etag string
+	// EtagUnverified is set if the Etag reported by Swift could not be
+	// verified against the uploaded content. See documentation on type
+	// EtagCheckPolicy for details.
+	EtagUnverified bool
 }
 
 // Upload creates the object using a PUT request.
@@ -194,14 +314,18 @@ type UploadOptions struct {
 // If content is a *bytes.Reader or a *bytes.Buffer instance, the Content-Length
 // and Etag request headers will be computed automatically. Otherwise, it is
 // highly recommended that the caller set these headers (if possible) to allow
-// the server to check the integrity of the uploaded file.
+// the server to check the integrity of the uploaded file. If the content is
+// composed from several such readers, use UploadMultiReader instead of
+// wrapping them in io.MultiReader, so that these headers can still be
+// computed.
 //
 // If Etag and/or Content-Length is supplied and the content does not match
 // these parameters, http.StatusUnprocessableEntity is returned. If Etag is not
 // supplied and cannot be computed in advance, Upload() will compute the Etag as
 // data is read from the io.Reader, and compare the result to the Etag returned
-// by Swift, returning ErrChecksumMismatch in case of mismatch. The object will
-// have been uploaded at that point, so you will usually want to Delete() it.
+// by Swift, returning ErrChecksumMismatch in case of mismatch (see
+// UploadOptions.EtagCheck for how to relax this check). The object will have
+// been uploaded at that point, so you will usually want to Delete() it.
 //
 // This function can be used regardless of whether the object exists or not.
 //
@@ -212,7 +336,8 @@ func (o *Object) Upload(ctx context.Context, content io.Reader, opts *UploadOpti
 	}
 
 	ropts = cloneRequestOptions(ropts, nil)
-	hdr := ObjectHeaders{ropts.Headers}
+	o.c.applyDefaultObjectHeaders(ropts.Headers)
+	hdr := ObjectHeaders{Headers: ropts.Headers}
 
 	if !hdr.SizeBytes().Exists() {
 		value := tryComputeContentLength(content)
@@ -224,24 +349,45 @@ func (o *Object) Upload(ctx context.Context, content io.Reader, opts *UploadOpti
 	// do not attempt to add the Etag header when we're writing a large object
 	// manifest; the header refers to the content, but we would be computing the
 	// manifest's hash instead
-	isManifestUpload := ropts.Values.Get("multipart-manifest") == "put" || hdr.IsDynamicLargeObject()
-
 	var hasher hash.Hash
-	if !isManifestUpload {
+	if !isManifestUpload(ropts, hdr) {
 		err := tryComputeEtag(content, hdr)
 		if err != nil {
 			return err
 		}
 
-		// could not compute Etag in advance -> need to check on the fly
-		if !hdr.Etag().Exists() {
-			hasher = md5.New() //nolint:gosec // Etag uses md5
+		// could not compute Etag in advance -> need to check on the fly (or at
+		// least compute it for the UploadResult)
+		if !hdr.Etag().Exists() && (opts.EtagCheck != EtagCheckOff || opts.Result != nil) {
+			hasher = getMD5()
 			if content != nil {
 				content = io.TeeReader(content, hasher)
 			}
 		}
 	}
 
+	if opts.QuotaCheck != QuotaCheckOff {
+		err := o.checkQuota(ctx, hdr.SizeBytes().Get(), opts.QuotaCheck)
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		counter      countingWriter
+		sha256Hasher hash.Hash
+	)
+	if opts.Result != nil {
+		var w io.Writer = &counter
+		if opts.ComputeSHA256 {
+			sha256Hasher = sha256.New()
+			w = io.MultiWriter(&counter, sha256Hasher)
+		}
+		if content != nil {
+			content = io.TeeReader(content, w)
+		}
+	}
+
 	var lo *LargeObject
 	if opts.DeleteSegments {
 		// enumerate segments in large object before overwriting it, but only delete
@@ -276,10 +422,35 @@ func (o *Object) Upload(ctx context.Context, content io.Reader, opts *UploadOpti
 	o.Invalidate()
 	defer resp.Body.Close()
 
+	var (
+		md5Digest    string
+		etagVerified bool
+	)
+	if hdr.Etag().Exists() {
+		// Swift has verified the precomputed Etag, but an intermediary might
+		// still have reported a different one
+		md5Digest = hdr.Etag().Get()
+		etagVerified = md5Digest == resp.Header.Get("Etag")
+	}
 	if hasher != nil {
-		expectedEtag := hex.EncodeToString(hasher.Sum(nil))
-		if expectedEtag != resp.Header.Get("Etag") {
-			return ErrChecksumMismatch
+		md5Digest = hex.EncodeToString(hasher.Sum(nil))
+		putMD5(hasher)
+		if opts.EtagCheck != EtagCheckOff {
+			etagVerified, err = o.checkUploadEtag(ctx, md5Digest, resp.Header.Get("Etag"), opts.EtagCheck)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.Result != nil {
+		*opts.Result = UploadResult{SizeBytes: counter.n, Etag: resp.Header.Get("Etag")}
+		if !isManifestUpload(ropts, hdr) {
+			opts.Result.MD5 = md5Digest
+			opts.Result.EtagUnverified = !etagVerified
+		}
+		if sha256Hasher != nil {
+			opts.Result.SHA256 = hex.EncodeToString(sha256Hasher.Sum(nil))
 		}
 	}
 
@@ -293,6 +464,42 @@ func (o *Object) Upload(ctx context.Context, content io.Reader, opts *UploadOpti
 	return nil
 }
 
+// Create is like Upload, but only creates the object if it does not exist
+// yet. To this end, the request carries the header "If-None-Match: *", so
+// Swift rejects the upload if the object exists, in which case
+// ErrAlreadyExists is returned. This can be used to implement "claim"
+// semantics between multiple workers, e.g. for distributed locking or for
+// deduplicating work items:
+//
+//	err := container.Object("locks/job-42").Create(ctx, nil, nil)
+//	if errors.Is(err, schwift.ErrAlreadyExists) {
+//		//another worker has claimed this job already
+//	}
+//
+// Since the object does not exist yet, there are no segments to clean up,
+// so this method does not take UploadOptions.
+//
+// A successful PUT request implies Invalidate() since it may change metadata.
+func (o *Object) Create(ctx context.Context, content io.Reader, ropts *RequestOptions) error {
+	ropts = cloneRequestOptions(ropts, nil)
+	ropts.Headers.Set("If-None-Match", "*")
+	err := o.Upload(ctx, content, nil, ropts)
+	if Is(err, http.StatusPreconditionFailed) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// countingWriter counts the bytes written into it.
+type countingWriter struct {
+	n uint64
+}
+
+func (w *countingWriter) Write(buf []byte) (int, error) {
+	w.n += uint64(len(buf))
+	return len(buf), nil
+}
+
 type readerWithLen interface {
 	// Returns the number of bytes in the unread portion of the buffer.
 	// Implemented by bytes.Reader, bytes.Buffer and strings.Reader.
@@ -310,37 +517,152 @@ func tryComputeContentLength(content io.Reader) *uint64 {
 	return nil
 }
 
-//nolint:gosec // Etag uses md5
+func isManifestUpload(ropts *RequestOptions, hdr ObjectHeaders) bool {
+	return ropts.Values.Get("multipart-manifest") == "put" || hdr.IsDynamicLargeObject()
+}
+
 func tryComputeEtag(content io.Reader, headers ObjectHeaders) error {
+	return tryComputeEtagMulti([]io.Reader{content}, headers)
+}
+
+// tryComputeEtagMulti is like tryComputeEtag, but computes the Etag of the
+// concatenation of all given readers. If any of them cannot be hashed in
+// advance, no Etag is set.
+func tryComputeEtagMulti(contents []io.Reader, headers ObjectHeaders) error {
 	h := headers.Etag()
 	if h.Exists() {
 		return nil
 	}
+	md5Hash := getMD5()
+	defer putMD5(md5Hash)
+	for _, content := range contents {
+		ok, err := tryHashContent(content, md5Hash)
+		if !ok || err != nil {
+			return err
+		}
+	}
+	h.Set(hex.EncodeToString(md5Hash.Sum(nil)))
+	return nil
+}
+
+// tryHashContent writes the content into the given hash without consuming it,
+// if this is possible for the type of reader. Returns false otherwise.
+func tryHashContent(content io.Reader, h hash.Hash) (bool, error) {
 	switch r := content.(type) {
 	case nil:
-		sum := md5.Sum(nil)
-		h.Set(hex.EncodeToString(sum[:]))
+		return true, nil
 	case *bytes.Buffer:
 		// bytes.Buffer has a method that returns the unread portion of the buffer,
 		// so this one is easy
-		sum := md5.Sum(r.Bytes())
-		h.Set(hex.EncodeToString(sum[:]))
+		h.Write(r.Bytes())
+		return true, nil
 	case io.ReadSeeker:
 		// bytes.Reader does not have such a method, but it is an io.Seeker, so we
 		// can read the entire thing and then seek back to where we started
-		md5Hash := md5.New()
-		n, err := io.Copy(md5Hash, r)
+		n, err := io.Copy(h, r)
 		if err != nil {
-			return err
+			return false, err
 		}
 		_, err = r.Seek(-n, io.SeekCurrent)
+		return err == nil, err
+	default:
+		return false, nil
+	}
+}
+
+// UploadIfChanged is like Upload, but skips the upload if the object already
+// has the same size and Etag as the given content. Returns whether the upload
+// took place (which is false if the upload fails). This is intended for sync
+// jobs that would otherwise re-upload unchanged content over and over again.
+//
+// The content is read once to compute its MD5 checksum, then rewound. The
+// object's metadata is obtained with Headers(), so if it is cached already,
+// no HEAD request is made. (Call Invalidate() beforehand if the cached
+// metadata may be outdated.) Large objects are always overwritten since their
+// Etag cannot be compared with the content's checksum.
+//
+// When the upload is skipped, UploadOptions.Result (if given) is filled with
+// the size and checksum of the content, except for the SHA256 field.
+func (o *Object) UploadIfChanged(ctx context.Context, content io.ReadSeeker, opts *UploadOptions, ropts *RequestOptions) (uploaded bool, err error) {
+	h := getMD5()
+	size, err := io.Copy(h, content)
+	etag := hex.EncodeToString(h.Sum(nil))
+	putMD5(h)
+	if err != nil {
+		return false, err
+	}
+	_, err = content.Seek(-size, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+
+	hdr, err := o.Headers(ctx)
+	switch {
+	case Is(err, http.StatusNotFound):
+		// object does not exist yet
+	case err != nil:
+		return false, err
+	case !hdr.IsLargeObject() && hdr.SizeBytes().Get() == uint64(size) && strings.Trim(hdr.Etag().Get(), `"`) == etag:
+		if opts != nil && opts.Result != nil {
+			*opts.Result = UploadResult{SizeBytes: uint64(size), MD5: etag, Etag: etag}
+		}
+		return false, nil
+	}
+
+	ropts = cloneRequestOptions(ropts, nil)
+	if ropts.Headers.Get("Etag") == "" {
+		ropts.Headers.Set("Etag", etag)
+	}
+	err = o.Upload(ctx, content, opts, ropts)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UploadMultiReader is like Upload, but the object's content is the
+// concatenation of the given readers, similar to io.MultiReader. Unlike with
+// io.MultiReader, the Content-Length and Etag request headers will be computed
+// automatically if all readers qualify for this (see documentation on Upload).
+//
+// This is intended for composing objects from a few parts that are already in
+// memory. To upload large amounts of data in segments, use LargeObject instead.
+func (o *Object) UploadMultiReader(ctx context.Context, opts *UploadOptions, ropts *RequestOptions, contents ...io.Reader) error {
+	ropts = cloneRequestOptions(ropts, nil)
+	o.c.applyDefaultObjectHeaders(ropts.Headers)
+	hdr := ObjectHeaders{Headers: ropts.Headers}
+
+	readers := make([]io.Reader, 0, len(contents))
+	for _, content := range contents {
+		if content != nil {
+			readers = append(readers, content)
+		}
+	}
+
+	if !hdr.SizeBytes().Exists() {
+		total := uint64(0)
+		known := true
+		for _, r := range readers {
+			value := tryComputeContentLength(r)
+			if value == nil {
+				known = false
+				break
+			}
+			total += *value
+		}
+		if known {
+			hdr.SizeBytes().Set(total)
+		}
+	}
+
+	if !isManifestUpload(ropts, hdr) {
+		err := tryComputeEtagMulti(readers, hdr)
 		if err != nil {
 			return err
 		}
-		h.Set(hex.EncodeToString(md5Hash.Sum(nil)))
 	}
 
-	return nil
+	return o.Upload(ctx, io.MultiReader(readers...), opts, ropts)
 }
 
 // UploadFromWriter is a variant of Upload that can be used when the object's
@@ -361,7 +683,9 @@ func tryComputeEtag(content io.Reader, headers ObjectHeaders) error {
 //	    return err
 //	})
 //
-// If you do not need an io.Writer, always use Upload instead.
+// If you do not need an io.Writer, always use Upload instead. If the callback
+// performs many small writes or produces data in bursts, consider
+// UploadFromBufferedWriter instead.
 func (o *Object) UploadFromWriter(ctx context.Context, opts *UploadOptions, ropts *RequestOptions, callback func(io.Writer) error) error {
 	reader, writer := io.Pipe()
 	errChan := make(chan error)
@@ -374,6 +698,23 @@ func (o *Object) UploadFromWriter(ctx context.Context, opts *UploadOptions, ropt
 	return <-errChan
 }
 
+// UploadFromBufferedWriter is like UploadFromWriter, but places a buffer
+// between the callback and the upload. Small writes by the callback are
+// coalesced into larger reads by the upload, and the callback only blocks when
+// the buffer is full. This is useful when the callback produces data in
+// bursts. With bopts.SpoolToDisk, the callback never blocks; excess data is
+// spooled into a temporary file instead. The bopts argument may be nil.
+func (o *Object) UploadFromBufferedWriter(ctx context.Context, opts *UploadOptions, ropts *RequestOptions, bopts *WriterBufferOptions, callback func(io.Writer) error) error {
+	p := newBufferedPipe(bopts)
+	errChan := make(chan error)
+	go func() {
+		err := o.Upload(ctx, bufferedPipeReader{p}, opts, ropts)
+		errChan <- errors.Join(err, p.closeRead(err)) // stop the writer if it is still writing
+	}()
+	p.closeWrite(callback(bufferedPipeWriter{p})) // stop the reader if it is still reading
+	return <-errChan
+}
+
 // DeleteOptions invokes advanced behavior in the Object.Delete() method.
 type DeleteOptions struct {
 	// When deleting a large object, also delete its segments. This will cause
@@ -435,6 +776,8 @@ func (o *Object) Delete(ctx context.Context, opts *DeleteOptions, ropts *Request
 func (o *Object) Invalidate() {
 	o.headers = nil
 	o.symlinkHeaders = nil
+	o.rawHeaders = nil
+	o.symlinkRawHeaders = nil
 }
 
 // Download retrieves the object's contents using a GET request. This returns a
@@ -450,6 +793,10 @@ func (o *Object) Invalidate() {
 //
 // See documentation on type DownloadedObject for details.
 //
+// If a Range header is given in the RequestOptions, the server may respond with
+// 206 (Partial Content), in which case only the requested range of the object
+// contents will be returned.
+//
 // WARNING: This method is not thread-safe. Calling it concurrently on the same
 // object results in undefined behavior.
 func (o *Object) Download(ctx context.Context, opts *RequestOptions) DownloadedObject {
@@ -458,22 +805,35 @@ func (o *Object) Download(ctx context.Context, opts *RequestOptions) DownloadedO
 		ContainerName:     o.c.name,
 		ObjectName:        o.name,
 		Options:           opts,
-		ExpectStatusCodes: []int{http.StatusOK},
+		ExpectStatusCodes: []int{http.StatusOK, http.StatusPartialContent},
 	}.Do(ctx, o.c.a.backend) //nolint:bodyclose // body is returned and must be closed by the user
-	var body io.ReadCloser
+	var result DownloadedObject
 	if err == nil {
-		newHeaders := ObjectHeaders{headersFromHTTP(resp.Header)}
+		newHeaders := newObjectHeadersFromHTTP(resp.Header)
 		err = newHeaders.Validate()
-		if err == nil {
-			if opts != nil && opts.Values != nil && opts.Values.Get("symlink") == "get" {
+		// for partial content, Content-Length etc. refer to the requested range,
+		// so we cannot put those headers in the cache
+		if err == nil && resp.StatusCode == http.StatusOK {
+			switch {
+			case opts != nil && opts.Values != nil && opts.Values.Get("multipart-manifest") == "get":
+				// the headers describe the manifest itself (e.g. Content-Type is JSON)
+				// instead of the large object, so they must not be cached either
+			case opts != nil && opts.Values != nil && opts.Values.Get("symlink") == "get":
 				o.symlinkHeaders = &newHeaders
-			} else {
+				o.symlinkRawHeaders = multiValuedHeaders(resp.Header)
+			default:
 				o.headers = &newHeaders
+				o.rawHeaders = multiValuedHeaders(resp.Header)
 			}
 		}
-		body = resp.Body
+		result.r = resp.Body
+		result.sizeBytes = resp.ContentLength
+		result.sizeKnown = resp.ContentLength >= 0
+		result.partial = resp.StatusCode == http.StatusPartialContent
+		result.header = resp.Header
 	}
-	return DownloadedObject{body, err}
+	result.err = err
+	return result
 }
 
 // CopyOptions invokes advanced behavior in the Object.Copy() method.
@@ -483,13 +843,37 @@ type CopyOptions struct {
 	FreshMetadata bool
 	// When the source is a symlink, copy the symlink instead of the target object.
 	ShallowCopySymlinks bool
+	// When the source is a large object, copy its manifest instead of its
+	// content. The copy will then refer to the same segments as the source.
+	ShallowCopyLargeObjects bool
 }
 
 // CopyTo copies the object on the server side using a COPY request.
 //
+// The metadata of the target is assembled as follows: Unless
+// CopyOptions.FreshMetadata is set, the target starts out with the source's
+// metadata (i.e. X-Object-Meta-* headers, Content-Disposition,
+// Content-Encoding and X-Delete-At). Then the headers given in the
+// RequestOptions are applied on top, so that they override source metadata of
+// the same name, but all other source metadata is retained. The Content-Type is
+// always inherited from the source, unless it is overridden in the
+// RequestOptions.
+//
+// When the source is a large object or a symlink, its content is copied into
+// a plain object by default. Set CopyOptions.ShallowCopyLargeObjects or
+// CopyOptions.ShallowCopySymlinks to copy the manifest or the symlink instead.
+// In this case, the metadata is assembled in the same way as above, and the
+// large object or symlink properties of the source are always retained.
+//
 // A successful COPY implies target.Invalidate() since it may change the
 // target's metadata.
 func (o *Object) CopyTo(ctx context.Context, target *Object, opts *CopyOptions, ropts *RequestOptions) error {
+	_, err := o.copyTo(ctx, target, opts, ropts)
+	return err
+}
+
+// copyTo implements CopyTo(), and additionally returns the response headers.
+func (o *Object) copyTo(ctx context.Context, target *Object, opts *CopyOptions, ropts *RequestOptions) (http.Header, error) {
 	ropts = cloneRequestOptions(ropts, nil)
 	ropts.Headers.Set("Destination", target.FullName())
 	if o.c.a.name != target.c.a.name {
@@ -502,6 +886,9 @@ func (o *Object) CopyTo(ctx context.Context, target *Object, opts *CopyOptions,
 		if opts.ShallowCopySymlinks {
 			ropts.Values.Set("symlink", "get")
 		}
+		if opts.ShallowCopyLargeObjects {
+			ropts.Values.Set("multipart-manifest", "get")
+		}
 	}
 
 	resp, err := Request{
@@ -512,11 +899,12 @@ func (o *Object) CopyTo(ctx context.Context, target *Object, opts *CopyOptions,
 		ExpectStatusCodes: []int{http.StatusCreated},
 		DrainResponseBody: true,
 	}.Do(ctx, o.c.a.backend)
-	if err == nil {
-		target.Invalidate()
-		resp.Body.Close()
+	if err != nil {
+		return nil, err
 	}
-	return err
+	target.Invalidate()
+	resp.Body.Close()
+	return resp.Header, nil
 }
 
 // SymlinkOptions invokes advanced behavior in the Object.SymlinkTo() method.
@@ -574,7 +962,7 @@ func (o *Object) SymlinkTo(ctx context.Context, target *Object, opts *SymlinkOpt
 // object results in undefined behavior.
 func (o *Object) SymlinkHeaders(ctx context.Context) (headers ObjectHeaders, target *Object, err error) {
 	if o.symlinkHeaders == nil {
-		o.symlinkHeaders, err = o.fetchHeaders(ctx, &RequestOptions{
+		o.symlinkHeaders, o.symlinkRawHeaders, err = o.fetchHeadersWithRaw(ctx, &RequestOptions{
 			Values: url.Values{"symlink": []string{"get"}},
 		})
 		if err != nil {
@@ -587,6 +975,7 @@ func (o *Object) SymlinkHeaders(ctx context.Context) (headers ObjectHeaders, tar
 	if targetFullName == "" {
 		// not a symlink - the o.symlinkHeaders are just the regular headers
 		o.headers = o.symlinkHeaders
+		o.rawHeaders = o.symlinkRawHeaders
 		return *o.headers, nil, nil
 	}
 	fields := strings.SplitN(targetFullName, "/", 2)
@@ -607,6 +996,12 @@ func (o *Object) SymlinkHeaders(ctx context.Context) (headers ObjectHeaders, tar
 	return *o.symlinkHeaders, target, nil
 }
 
+// NewRequest prepares a request on this object for use with Request.Do().
+// See documentation on type Request for details. The opts argument may be nil.
+func (o *Object) NewRequest(method string, opts *RequestOptions) Request {
+	return Request{Method: method, ContainerName: o.c.name, ObjectName: o.name, Options: opts}
+}
+
 // URL returns the canonical URL for the object on the server. This is
 // particularly useful when the ReadACL on the account or container is set to
 // allow anonymous read access.
@@ -617,6 +1012,20 @@ func (o *Object) URL() (string, error) {
 	}.URL(o.c.a.backend, nil)
 }
 
+// PublicURL returns the URL for this object that unauthenticated clients can
+// use when the ReadACL on its container permits anonymous access. Unlike
+// URL(), this honors the public endpoint configured with
+// Account.WithPublicEndpoint(), and escapes each element of the object name
+// separately, so that slashes in the object name appear as such in the URL.
+// Elements that are "." or ".." are percent-encoded, so that they are not
+// removed when the URL is normalized. For example:
+//
+//	url, err := account.Container("assets").Object("img/Logo 2.png").PublicURL()
+//	//url == "https://swift.example.com/v1/AUTH_example/assets/img/Logo%202.png"
+func (o *Object) PublicURL() (string, error) {
+	return o.c.a.publicURL(o.c.name, o.name)
+}
+
 // Returns true if string is contained in slice
 func contains(s []string, e string) bool {
 	for _, a := range s {
@@ -653,6 +1062,9 @@ func contains(s []string, e string) bool {
 //	url := o.TempURL(ctx, key, "GET", time.Now().Add(10 * time.Minute))
 //	resp, err := http.Get(url)
 //	//This time, resp.StatusCode == 200 because the URL includes a token.
+//
+// To hand out access to multiple objects at once, see Container.PrefixTempURL()
+// and Container.FormPost().
 func (o *Object) TempURL(ctx context.Context, key, method string, expires time.Time) (string, error) {
 	urlStr, err := o.URL()
 	if err != nil {
@@ -663,25 +1075,10 @@ func (o *Object) TempURL(ctx context.Context, key, method string, expires time.T
 		return "", err
 	}
 
-	capabilities, err := o.c.a.Capabilities(ctx)
+	signature, err := o.c.a.signTempURL(ctx, key, method, u.Path, expires)
 	if err != nil {
 		return "", err
 	}
-	allowedDigest := capabilities.TempURL.AllowedDigests
-
-	var mac hash.Hash
-	switch {
-	case contains(allowedDigest, "sha256"):
-		mac = hmac.New(sha256.New, []byte(key))
-	case contains(allowedDigest, "sha1"):
-		mac = hmac.New(sha1.New, []byte(key))
-	default:
-		return "", fmt.Errorf("schwift supports sha1 and sha256 digests but the Swift server only supports: %s", strings.Join(allowedDigest, ", "))
-	}
-
-	payload := fmt.Sprintf("%s\n%d\n%s", method, expires.Unix(), u.Path)
-	mac.Write([]byte(payload))
-	signature := hex.EncodeToString(mac.Sum(nil))
 
 	u.RawQuery = fmt.Sprintf("temp_url_sig=%s&temp_url_expires=%d",
 		signature, expires.Unix())
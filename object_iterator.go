@@ -20,9 +20,14 @@ package schwift
 
 import (
 	"context"
+	"encoding/xml"
 	"fmt"
+	"net/http"
 	"regexp"
+	"strings"
 	"time"
+
+	"github.com/majewsky/schwift/v2/internal/errext"
 )
 
 // ObjectInfo is a result type returned by ObjectIterator for detailed
@@ -37,6 +42,17 @@ type ObjectInfo struct {
 	LastModified time.Time
 	// SymlinkTarget is only set for symlinks.
 	SymlinkTarget *Object
+	// SymlinkEtag and SymlinkSizeBytes are only set for static symlinks (i.e.
+	// symlinks that were created with an X-Symlink-Target-Etag). They describe
+	// the symlink's target object.
+	SymlinkEtag      string
+	SymlinkSizeBytes uint64
+	// SLOEtag is only set for static large objects, and only if the server
+	// reports it in the listing (this requires Swift 2.22 or newer). It contains
+	// the Etag of the large object's content (like the Etag header returned by
+	// Object.Headers(), but without quotes), whereas Etag contains the MD5
+	// checksum of the manifest.
+	SLOEtag string
 	// If the ObjectInfo refers to an actual object, then SubDirectory is empty.
 	// If the ObjectInfo refers to a pseudo-directory, then SubDirectory contains
 	// the path of the pseudo-directory and all other fields are nil/zero/empty.
@@ -44,6 +60,19 @@ type ObjectInfo struct {
 	SubDirectory string
 }
 
+// IsSymlink returns whether this ObjectInfo describes a symlink.
+func (i ObjectInfo) IsSymlink() bool {
+	return i.SymlinkTarget != nil
+}
+
+// IsStaticLargeObject returns whether this ObjectInfo describes the manifest of
+// a static large object. Since this relies on SLOEtag, this may return false
+// negatives when the server does not report SLOEtag in listings. Dynamic large
+// objects cannot be recognized from listings at all.
+func (i ObjectInfo) IsStaticLargeObject() bool {
+	return i.SLOEtag != ""
+}
+
 // ObjectIterator iterates over the objects in a container. It is typically
 // constructed with the Container.Objects() method. For example:
 //
@@ -82,8 +111,35 @@ type ObjectIterator struct {
 	// prefix, if any) will be condensed into pseudo-directories in the result.
 	// See documentation for Swift for details.
 	Delimiter string
+	// When Match is set, only objects (and pseudo-directories) whose name
+	// matches this regular expression are returned. When Exclude is set, objects
+	// whose name matches that regular expression are not returned. Unlike Prefix
+	// and Delimiter, these filters are applied on the client side, so the server
+	// still returns (and the client still downloads) the entire listing below
+	// the Prefix.
+	Match   *regexp.Regexp
+	Exclude *regexp.Regexp
 	// Options may contain additional headers and query parameters for the GET request.
+	// If Options contains a "marker" query parameter, the first page starts
+	// after the object with that name. This can be used to resume a listing.
 	Options *RequestOptions
+	// Format selects the response format for the "Detailed" methods. See
+	// documentation on type ListingFormat for details.
+	Format ListingFormat
+	// When Cache is set, listing pages may be served from this cache. See
+	// documentation on type ListingCache for details.
+	Cache *ListingCache
+	// When SkipVanished is set, Foreach(), ForeachDetailed() and
+	// ForeachWithHeaders() do not abort when the callback returns an error that
+	// matches http.StatusNotFound (see documentation on Is()). In busy
+	// containers, such an error usually means that the object was deleted
+	// between the listing and the callback's HEAD or GET request. The object is
+	// skipped instead, and OnVanished is called with it (if set). Only errors
+	// concerning the listed object itself are treated this way; 404 errors for
+	// other targets still abort the iteration. This includes COPY requests on
+	// the listed object, since their 404 may concern the destination.
+	SkipVanished bool
+	OnVanished   func(*Object)
 
 	base *iteratorBase
 }
@@ -95,6 +151,13 @@ func (i *ObjectIterator) getBase() *iteratorBase {
 	return i.base
 }
 
+// Stats returns pagination metadata for all pages that have been fetched
+// through this iterator so far. See documentation on type ListingStats for
+// details.
+func (i *ObjectIterator) Stats() ListingStats {
+	return i.getBase().stats()
+}
+
 // NextPage queries Swift for the next page of object names. If limit is
 // >= 0, not more than that many object names will be returned at once. Note
 // that the server also has a limit for how many objects to list in one
@@ -105,7 +168,7 @@ func (i *ObjectIterator) getBase() *iteratorBase {
 // This method offers maximal flexibility, but most users will prefer the
 // simpler interfaces offered by Collect() and Foreach().
 func (i *ObjectIterator) NextPage(ctx context.Context, limit int) ([]*Object, error) {
-	names, err := i.getBase().nextPage(ctx, limit)
+	names, err := i.getBase().nextFilteredPage(ctx, limit, i.Match, i.Exclude)
 	if err != nil {
 		return nil, err
 	}
@@ -122,61 +185,123 @@ var symlinkPathRx = regexp.MustCompile(`^/v1/([^/]+)/([^/]+)/(.+)$`)
 
 // NextPageDetailed is like NextPage, but includes basic metadata.
 func (i *ObjectIterator) NextPageDetailed(ctx context.Context, limit int) ([]ObjectInfo, error) {
-	b := i.getBase()
+	for {
+		infos, err := i.nextPageDetailedUnfiltered(ctx, limit)
+		if err != nil || len(infos) == 0 {
+			return infos, err
+		}
+		infos = filterByName(infos, i.Match, i.Exclude, func(info ObjectInfo) string {
+			if info.SubDirectory != "" {
+				return info.SubDirectory
+			}
+			return info.Object.Name()
+		})
+		if len(infos) > 0 {
+			return infos, nil
+		}
+		// all entries on this page were filtered out, but the listing is not
+		// finished yet
+	}
+}
 
-	var document []struct {
+func (i *ObjectIterator) nextPageDetailedUnfiltered(ctx context.Context, limit int) ([]ObjectInfo, error) {
+	if i.Format == ListingFormatPlain {
+		return i.nextPagePlain(ctx, limit)
+	}
+
+	b := i.getBase()
+	body, err := b.nextPageDetailed(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		result []ObjectInfo
+		marker string
+	)
+	err = decodeListing(body, i.Format, func(data struct {
+		// for XML only: either <object> or <subdir>
+		XMLName xml.Name `json:"-"`
 		// either all of this:
-		SizeBytes       uint64 `json:"bytes"`
-		ContentType     string `json:"content_type"`
-		Etag            string `json:"hash"`
-		LastModifiedStr string `json:"last_modified"`
-		Name            string `json:"name"`
-		SymlinkPath     string `json:"symlink_path"`
-		// or just this:
-		Subdir string `json:"subdir"`
-	}
-	err := b.nextPageDetailed(ctx, limit, &document)
+		SizeBytes       uint64 `json:"bytes" xml:"bytes"`
+		ContentType     string `json:"content_type" xml:"content_type"`
+		Etag            string `json:"hash" xml:"hash"`
+		LastModifiedStr string `json:"last_modified" xml:"last_modified"`
+		Name            string `json:"name" xml:"name"`
+		SymlinkPath     string `json:"symlink_path" xml:"symlink_path"`
+		SymlinkEtag     string `json:"symlink_etag" xml:"symlink_etag"`
+		SymlinkBytes    uint64 `json:"symlink_bytes" xml:"symlink_bytes"`
+		SLOEtag         string `json:"slo_etag" xml:"slo_etag"`
+		// or just this (in XML, subdirs are <subdir name="..."><name>...</name></subdir>):
+		Subdir string `json:"subdir" xml:"-"`
+	}) error {
+		idx := len(result)
+		if data.XMLName.Local == "subdir" {
+			data.Subdir = data.Name
+		}
+		if data.Subdir != "" {
+			marker = data.Subdir
+			result = append(result, ObjectInfo{SubDirectory: data.Subdir})
+			return nil
+		}
+
+		marker = data.Name
+		info := ObjectInfo{
+			Object:      i.Container.Object(data.Name),
+			ContentType: data.ContentType,
+			Etag:        data.Etag,
+			SizeBytes:   data.SizeBytes,
+			// Swift reports the SLO Etag with quotes, like in the Etag header
+			SLOEtag: strings.Trim(data.SLOEtag, `"`),
+		}
+		var err error
+		info.LastModified, err = time.Parse(time.RFC3339Nano, data.LastModifiedStr+"Z")
+		if err != nil {
+			// this error is sufficiently obscure that we don't need to expose a type for it
+			return fmt.Errorf("bad field objects[%d].last_modified: %s", idx, err.Error())
+		}
+		if data.SymlinkPath != "" {
+			match := symlinkPathRx.FindStringSubmatch(data.SymlinkPath)
+			if match == nil {
+				// like above
+				return fmt.Errorf("bad field objects[%d].symlink_path: %q", idx, data.SymlinkPath)
+			}
+			a := i.Container.a
+			if a.Name() != match[1] {
+				a = a.SwitchAccount(match[1])
+			}
+			info.SymlinkTarget = a.Container(match[2]).Object(match[3])
+			info.SymlinkEtag = data.SymlinkEtag
+			info.SymlinkSizeBytes = data.SymlinkBytes
+		}
+		result = append(result, info)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	if len(document) == 0 {
+	if len(result) == 0 {
 		b.setMarker("") // indicate EOF to iteratorBase
 		return nil, nil
 	}
 
-	result := make([]ObjectInfo, len(document))
-	marker := ""
-	for idx, data := range document {
-		if data.Subdir == "" {
-			marker = data.Name
-			result[idx].Object = i.Container.Object(data.Name)
-			result[idx].ContentType = data.ContentType
-			result[idx].Etag = data.Etag
-			result[idx].SizeBytes = data.SizeBytes
-			result[idx].LastModified, err = time.Parse(time.RFC3339Nano, data.LastModifiedStr+"Z")
-			if err != nil {
-				// this error is sufficiently obscure that we don't need to expose a type for it
-				return nil, fmt.Errorf("bad field objects[%d].last_modified: %s", idx, err.Error())
-			}
-			if data.SymlinkPath != "" {
-				match := symlinkPathRx.FindStringSubmatch(data.SymlinkPath)
-				if match == nil {
-					// like above
-					return nil, fmt.Errorf("bad field objects[%d].symlink_path: %q", idx, data.SymlinkPath)
-				}
-				a := i.Container.a
-				if a.Name() != match[1] {
-					a = a.SwitchAccount(match[1])
-				}
-				result[idx].SymlinkTarget = a.Container(match[2]).Object(match[3])
-			}
+	b.setMarker(marker)
+	return result, nil
+}
+
+// nextPagePlain implements NextPageDetailed for ListingFormatPlain.
+func (i *ObjectIterator) nextPagePlain(ctx context.Context, limit int) ([]ObjectInfo, error) {
+	objects, err := i.NextPage(ctx, limit)
+	if err != nil || len(objects) == 0 {
+		return nil, err
+	}
+	result := make([]ObjectInfo, len(objects))
+	for idx, o := range objects {
+		if i.Delimiter != "" && strings.HasSuffix(o.Name(), i.Delimiter) {
+			result[idx].SubDirectory = o.Name()
 		} else {
-			marker = data.Subdir
-			result[idx].SubDirectory = data.Subdir
+			result[idx].Object = o
 		}
 	}
-
-	b.setMarker(marker)
 	return result, nil
 }
 
@@ -193,7 +318,7 @@ func (i *ObjectIterator) Foreach(ctx context.Context, callback func(*Object) err
 			return nil // EOF
 		}
 		for _, o := range objects {
-			err := callback(o)
+			err := i.checkCallbackError(o, callback(o))
 			if err != nil {
 				return err
 			}
@@ -212,7 +337,7 @@ func (i *ObjectIterator) ForeachDetailed(ctx context.Context, callback func(Obje
 			return nil // EOF
 		}
 		for _, ci := range infos {
-			err := callback(ci)
+			err := i.checkCallbackError(ci.Object, callback(ci))
 			if err != nil {
 				return err
 			}
@@ -220,6 +345,43 @@ func (i *ObjectIterator) ForeachDetailed(ctx context.Context, callback func(Obje
 	}
 }
 
+// ForeachWithHeaders is like Foreach, but issues a HEAD request for each object
+// and passes the resulting headers to the callback. This is usually combined
+// with SkipVanished, so that objects that are deleted between the listing and
+// the HEAD request are skipped instead of aborting the iteration:
+//
+//	iter := container.Objects()
+//	iter.SkipVanished = true
+//	err := iter.ForeachWithHeaders(ctx, func(obj *schwift.Object, hdr schwift.ObjectHeaders) error {
+//		...
+//	})
+func (i *ObjectIterator) ForeachWithHeaders(ctx context.Context, callback func(*Object, ObjectHeaders) error) error {
+	return i.Foreach(ctx, func(o *Object) error {
+		hdr, err := o.Headers(ctx)
+		if err != nil {
+			return err
+		}
+		return callback(o, hdr)
+	})
+}
+
+// checkCallbackError implements SkipVanished for the Foreach methods.
+func (i *ObjectIterator) checkCallbackError(o *Object, err error) error {
+	if err == nil || !i.SkipVanished {
+		return err
+	}
+	// for COPY requests, the target is the source object, but a 404 may also
+	// refer to a missing destination
+	e, ok := errext.As[UnexpectedStatusCodeError](err)
+	if !ok || e.ActualResponse.StatusCode != http.StatusNotFound || e.Target != o.FullName() || e.Method == "COPY" {
+		return err
+	}
+	if i.OnVanished != nil {
+		i.OnVanished(o)
+	}
+	return nil
+}
+
 // Collect lists all object names matching this iterator. For large sets of
 // objects that cannot be retrieved at once, Collect handles paging behind
 // the scenes. The return value is always the complete set of objects.
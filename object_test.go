@@ -19,9 +19,13 @@
 package schwift
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"encoding/hex"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -88,3 +92,142 @@ func TestObjectTempURL(t *testing.T) {
 	expectedURL := "https://example.com/v1/AUTH_example/foo/bar?temp_url_sig=5fc94a988b502d83e88863774812636ef0133b8aae04b20366fd906bff41189f&temp_url_expires=1000000000"
 	expectString(t, expectedURL, actualURL)
 }
+
+func TestPublicURL(t *testing.T) {
+	account, err := InitializeAccount(tempurlBogusBackend{})
+	must(t, err)
+
+	c := account.Container("assets")
+	actualURL, err := c.PublicURL()
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/assets", actualURL)
+	actualURL, err = c.Object("img/Logo 2.png").PublicURL()
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/assets/img/Logo%202.png", actualURL)
+	actualURL, err = c.Object("../a//b?.txt").PublicURL()
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/assets/%2E%2E/a//b%3F.txt", actualURL)
+	actualURL, err = c.Object("a/./b/..c/.").PublicURL()
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/assets/a/%2E/b/..c/%2E", actualURL)
+
+	for _, endpointURL := range []string{"https://cdn.example.com", "https://cdn.example.com/"} {
+		c := account.WithPublicEndpoint(endpointURL).ReadOnly().Container("assets")
+		actualURL, err := c.Object("img/Ünïcödé.png").PublicURL()
+		must(t, err)
+		expectString(t, "https://cdn.example.com/assets/img/%C3%9Cn%C3%AFc%C3%B6d%C3%A9.png", actualURL)
+	}
+}
+
+// uploadTestBackend returns a testBackend that accepts all PUT requests and
+// stores their content in the given buffer.
+func uploadTestBackend(lastBody *[]byte) *testBackend {
+	return &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		*lastBody = nil
+		if req.Body != nil {
+			var err error
+			*lastBody, err = io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp := testResponse(http.StatusCreated, "")
+		resp.Header.Set("Etag", etagOfString(string(*lastBody)))
+		return resp, nil
+	}}
+}
+
+func TestUploadMultiReader(t *testing.T) {
+	var lastBody []byte
+	b := uploadTestBackend(&lastBody)
+	a, err := InitializeAccount(b)
+	must(t, err)
+	obj := a.Container("foo").Object("bar")
+	ctx := context.Background()
+
+	// when all parts have known sizes, Content-Length and Etag are precomputed
+	must(t, obj.UploadMultiReader(ctx, nil, nil,
+		strings.NewReader("hello "), nil, bytes.NewBufferString("multi"), bytes.NewReader([]byte("reader")),
+	))
+	expectString(t, "hello multireader", string(lastBody))
+	expectString(t, "17", b.lastReq.Header.Get("Content-Length"))
+	expectString(t, etagOfString("hello multireader"), b.lastReq.Header.Get("Etag"))
+
+	// when one part has an unknown size, those headers are left out (but the
+	// upload still works)
+	must(t, obj.UploadMultiReader(ctx, nil, nil,
+		strings.NewReader("hello "), io.LimitReader(strings.NewReader("opaque"), 100),
+	))
+	expectString(t, "hello opaque", string(lastBody))
+	expectString(t, "", b.lastReq.Header.Get("Content-Length"))
+	expectString(t, "", b.lastReq.Header.Get("Etag"))
+
+	// without any parts, an empty object is uploaded
+	must(t, obj.UploadMultiReader(ctx, nil, nil))
+	expectString(t, "", string(lastBody))
+	expectString(t, "0", b.lastReq.Header.Get("Content-Length"))
+	expectString(t, etagOfString(""), b.lastReq.Header.Get("Etag"))
+}
+
+func TestUploadResult(t *testing.T) {
+	var lastBody []byte
+	b := uploadTestBackend(&lastBody)
+	a, err := InitializeAccount(b)
+	must(t, err)
+	obj := a.Container("foo").Object("bar")
+	ctx := context.Background()
+
+	// with a reader that can be hashed in advance
+	var result UploadResult
+	must(t, obj.Upload(ctx, strings.NewReader("hello"), &UploadOptions{Result: &result}, nil))
+	expectString(t, "5", strconv.FormatUint(result.SizeBytes, 10))
+	expectString(t, etagOfString("hello"), result.MD5)
+	expectString(t, "", result.SHA256)
+
+	// with a reader that is hashed on the fly
+	content := io.LimitReader(strings.NewReader("hello world"), 100)
+	must(t, obj.Upload(ctx, content, &UploadOptions{Result: &result, ComputeSHA256: true}, nil))
+	expectString(t, "11", strconv.FormatUint(result.SizeBytes, 10))
+	expectString(t, etagOfString("hello world"), result.MD5)
+	expectString(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", result.SHA256)
+
+	// without content
+	must(t, obj.Upload(ctx, nil, &UploadOptions{Result: &result, ComputeSHA256: true}, nil))
+	expectString(t, "0", strconv.FormatUint(result.SizeBytes, 10))
+	expectString(t, etagOfString(""), result.MD5)
+	expectString(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", result.SHA256)
+
+	// MD5 is the checksum of the uploaded content even if the Etag reported by
+	// Swift is not an MD5 digest and the Etag check is disabled
+	a, err = InitializeAccount(&testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		_, err := io.Copy(io.Discard, req.Body)
+		if err != nil {
+			return nil, err
+		}
+		resp := testResponse(http.StatusCreated, "")
+		resp.Header.Set("Etag", "opaque")
+		return resp, nil
+	}})
+	must(t, err)
+	content = io.LimitReader(strings.NewReader("hello world"), 100)
+	opts := &UploadOptions{Result: &result, EtagCheck: EtagCheckOff}
+	must(t, a.Container("foo").Object("bar").Upload(ctx, content, opts, nil))
+	expectString(t, etagOfString("hello world"), result.MD5)
+	expectString(t, "opaque", result.Etag)
+	if !result.EtagUnverified {
+		t.Error("expected EtagUnverified to be set")
+	}
+
+	// same with a precomputed Etag
+	must(t, a.Container("foo").Object("bar").Upload(ctx, strings.NewReader("hello"), opts, nil))
+	expectString(t, etagOfString("hello"), result.MD5)
+	expectString(t, "opaque", result.Etag)
+	if !result.EtagUnverified {
+		t.Error("expected EtagUnverified to be set")
+	}
+}
+
+func etagOfString(s string) string {
+	sum := md5.Sum([]byte(s)) //nolint:gosec // Etag uses md5
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+)
+
+// ContainerOwnership describes a convention for sharing an account between
+// multiple applications. Each application marks the containers that it
+// manages with the following container metadata:
+//
+//   - X-Container-Meta-Managed-By identifies the application that manages the
+//     container, e.g. "billing-exporter". This is the only required field.
+//   - X-Container-Meta-Owner identifies the party that the container belongs to
+//     within the application, e.g. a team or tenant.
+//   - X-Container-Meta-Created-By identifies the process that created the
+//     container, e.g. a hostname or job name. It is only set when the
+//     container is created, and never updated afterwards.
+//
+// Use Container.EnsureManagedBy() instead of EnsureExists() to create
+// containers with this metadata, Account.ContainersManagedBy() to find them,
+// and Container.CheckManagedBy() before destructive operations to ensure that
+// the container was not created by a different application. For example:
+//
+//	ownership := schwift.ContainerOwnership{ManagedBy: "billing-exporter"}
+//	container, err := account.Container("reports").EnsureManagedBy(ctx, ownership, nil)
+//
+//	//later on...
+//	err := container.CheckManagedBy(ctx, "billing-exporter")
+//	if err == nil {
+//		err = container.Delete(ctx, nil)
+//	}
+//
+// This convention is purely advisory. Swift does not enforce it, so other
+// applications can still modify or delete the container.
+type ContainerOwnership struct {
+	ManagedBy string
+	Owner     string
+	CreatedBy string
+}
+
+// Ownership returns the ContainerOwnership described by these headers. Fields
+// are empty if the respective header is not set.
+func (h ContainerHeaders) Ownership() ContainerOwnership {
+	return ContainerOwnership{
+		ManagedBy: h.ManagedBy().Get(),
+		Owner:     h.Owner().Get(),
+		CreatedBy: h.CreatedBy().Get(),
+	}
+}
+
+// EnsureManagedBy is like EnsureExists, but additionally sets the metadata
+// described by the given ContainerOwnership (see documentation over there).
+// If the container exists already and is managed by a different application,
+// a ContainerOwnershipError is returned. If the container exists already, but
+// is not managed by any application, it is claimed by setting the
+// ManagedBy and Owner fields.
+//
+// A successful PUT or POST request implies Invalidate() since it may change
+// metadata.
+func (c *Container) EnsureManagedBy(ctx context.Context, ownership ContainerOwnership, opts *RequestOptions) (*Container, error) {
+	hdr := NewContainerHeaders()
+	hdr.ManagedBy().Set(ownership.ManagedBy)
+	if ownership.Owner != "" {
+		hdr.Owner().Set(ownership.Owner)
+	}
+
+	current, err := c.Headers(ctx)
+	switch {
+	case Is(err, http.StatusNotFound):
+		if ownership.CreatedBy != "" {
+			hdr.CreatedBy().Set(ownership.CreatedBy)
+		}
+	case err != nil:
+		return c, err
+	default:
+		err = checkManagedBy(c.name, current, ownership.ManagedBy, true)
+		if err != nil {
+			return c, err
+		}
+	}
+
+	_, err = c.CreateIfNotExists(ctx, hdr, opts)
+	return c, err
+}
+
+// CheckManagedBy returns a ContainerOwnershipError if this container is not
+// managed by the given application (see documentation on type
+// ContainerOwnership). This is intended to be called before destructive
+// operations on the container. To ensure that the check is made against the
+// current metadata, this always issues a HEAD request on the container.
+//
+// This operation fails with http.StatusNotFound if the container does not
+// exist.
+func (c *Container) CheckManagedBy(ctx context.Context, managedBy string) error {
+	c.Invalidate()
+	hdr, err := c.Headers(ctx)
+	if err != nil {
+		return err
+	}
+	return checkManagedBy(c.name, hdr, managedBy, false)
+}
+
+func checkManagedBy(containerName string, hdr ContainerHeaders, managedBy string, allowUnmanaged bool) error {
+	actual := hdr.ManagedBy().Get()
+	if actual == managedBy || (actual == "" && allowUnmanaged) {
+		return nil
+	}
+	return ContainerOwnershipError{
+		ContainerName:     containerName,
+		ExpectedManagedBy: managedBy,
+		ActualManagedBy:   actual,
+	}
+}
+
+// ContainersManagedBy returns all containers in this account that are managed
+// by the given application (see documentation on type ContainerOwnership).
+// Since container listings do not include metadata, this issues a HEAD
+// request for each container in the account (see documentation on
+// ContainerIterator.CollectWhere() for details), so it can be slow on accounts
+// with many containers. Containers that are deleted while this method is
+// running are skipped.
+func (a *Account) ContainersManagedBy(ctx context.Context, managedBy string) ([]*Container, error) {
+	return a.Containers().CollectWhere(ctx, func(hdr ContainerHeaders) bool {
+		return hdr.ManagedBy().Get() == managedBy
+	}, nil)
+}
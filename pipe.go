@@ -0,0 +1,196 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// defaultWriterBufferSize is the default for WriterBufferOptions.BufferSize.
+const defaultWriterBufferSize = 1 << 20
+
+// WriterBufferOptions controls the buffering in
+// Object.UploadFromBufferedWriter().
+type WriterBufferOptions struct {
+	// BufferSize is the maximum number of bytes that are held in memory between
+	// the producer and the upload. When the buffer is full, writes block until
+	// the upload has caught up (unless SpoolToDisk is set). The default is 1 MiB.
+	BufferSize int
+	// When SpoolToDisk is set, data that does not fit into the buffer is written
+	// into a temporary file instead of blocking the producer. The temporary file
+	// is removed when the upload is done.
+	SpoolToDisk bool
+	// SpoolDirectory is the directory in which temporary files are created when
+	// SpoolToDisk is set. The default is os.TempDir().
+	SpoolDirectory string
+}
+
+// bufferedPipe is like io.Pipe, but with a bounded buffer between the writing
+// and reading side, and optionally an unbounded overflow into a temporary
+// file. Data is always read from memory first, and writes only go to memory
+// while the spool file is empty, so the order of bytes is preserved.
+type bufferedPipe struct {
+	opts  WriterBufferOptions
+	mutex sync.Mutex
+	cond  *sync.Cond
+	mem   bytes.Buffer
+	// spool file (only used if opts.SpoolToDisk is set)
+	spool       *os.File
+	spoolReadAt int64
+	spoolSize   int64
+	// state after closing
+	readClosed  bool
+	readErr     error // returned to the writer
+	writeClosed bool
+	writeErr    error // returned to the reader
+}
+
+func newBufferedPipe(opts *WriterBufferOptions) *bufferedPipe {
+	p := &bufferedPipe{}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.BufferSize <= 0 {
+		p.opts.BufferSize = defaultWriterBufferSize
+	}
+	p.cond = sync.NewCond(&p.mutex)
+	return p
+}
+
+func (p *bufferedPipe) write(buf []byte) (n int, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for len(buf) > 0 {
+		switch {
+		case p.readClosed:
+			if p.readErr != nil {
+				return n, p.readErr
+			}
+			return n, io.ErrClosedPipe
+		case p.writeClosed:
+			return n, io.ErrClosedPipe
+		case p.spoolSize > p.spoolReadAt:
+			// spool is in use, so everything needs to go there until the reader has caught up
+			k, err := p.writeSpool(buf)
+			return n + k, err
+		case p.mem.Len() < p.opts.BufferSize:
+			k := min(p.opts.BufferSize-p.mem.Len(), len(buf))
+			p.mem.Write(buf[:k])
+			buf = buf[k:]
+			n += k
+			p.cond.Broadcast()
+		case p.opts.SpoolToDisk:
+			k, err := p.writeSpool(buf)
+			return n + k, err
+		default:
+			p.cond.Wait()
+		}
+	}
+	return n, nil
+}
+
+func (p *bufferedPipe) writeSpool(buf []byte) (int, error) {
+	if p.spool == nil {
+		f, err := os.CreateTemp(p.opts.SpoolDirectory, "schwift-spool-")
+		if err != nil {
+			return 0, err
+		}
+		p.spool = f
+	}
+	n, err := p.spool.WriteAt(buf, p.spoolSize)
+	p.spoolSize += int64(n)
+	p.cond.Broadcast()
+	return n, err
+}
+
+func (p *bufferedPipe) read(buf []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for {
+		switch {
+		case p.readClosed:
+			return 0, io.ErrClosedPipe
+		case p.mem.Len() > 0:
+			n, _ := p.mem.Read(buf) //nolint:errcheck // cannot fail when Len() > 0
+			p.cond.Broadcast()
+			return n, nil
+		case p.spoolSize > p.spoolReadAt:
+			k := min(int64(len(buf)), p.spoolSize-p.spoolReadAt)
+			n, err := p.spool.ReadAt(buf[:k], p.spoolReadAt)
+			p.spoolReadAt += int64(n)
+			if err == nil && p.spoolReadAt == p.spoolSize {
+				// spool is drained -> start over to keep the file small
+				p.spoolReadAt, p.spoolSize = 0, 0
+				err = p.spool.Truncate(0)
+			}
+			p.cond.Broadcast()
+			return n, err
+		case p.writeClosed:
+			if p.writeErr != nil {
+				return 0, p.writeErr
+			}
+			return 0, io.EOF
+		default:
+			p.cond.Wait()
+		}
+	}
+}
+
+// closeWrite is like io.PipeWriter.CloseWithError.
+func (p *bufferedPipe) closeWrite(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if !p.writeClosed {
+		p.writeClosed = true
+		p.writeErr = err
+		p.cond.Broadcast()
+	}
+}
+
+// closeRead is like io.PipeReader.CloseWithError, but additionally removes the
+// spool file, if any.
+func (p *bufferedPipe) closeRead(err error) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.readClosed {
+		return nil
+	}
+	p.readClosed = true
+	p.readErr = err
+	p.cond.Broadcast()
+
+	if p.spool == nil {
+		return nil
+	}
+	return errors.Join(p.spool.Close(), os.Remove(p.spool.Name()))
+}
+
+// bufferedPipeReader and bufferedPipeWriter expose the respective sides of a
+// bufferedPipe, so that neither side can be type-asserted into the other.
+type bufferedPipeReader struct{ p *bufferedPipe }
+type bufferedPipeWriter struct{ p *bufferedPipe }
+
+func (r bufferedPipeReader) Read(buf []byte) (int, error)  { return r.p.read(buf) }
+func (w bufferedPipeWriter) Write(buf []byte) (int, error) { return w.p.write(buf) }
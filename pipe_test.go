@@ -0,0 +1,76 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestBufferedPipe(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 1000)
+
+	for _, spool := range []bool{false, true} {
+		dir := t.TempDir()
+		p := newBufferedPipe(&WriterBufferOptions{
+			BufferSize:     100,
+			SpoolToDisk:    spool,
+			SpoolDirectory: dir,
+		})
+
+		// write in small chunks from a separate goroutine
+		go func() {
+			w := bufferedPipeWriter{p}
+			for offset := 0; offset < len(content); offset += 7 {
+				_, err := w.Write(content[offset:min(offset+7, len(content))])
+				if err != nil {
+					p.closeWrite(err)
+					return
+				}
+			}
+			p.closeWrite(nil)
+		}()
+
+		buf, err := io.ReadAll(bufferedPipeReader{p})
+		must(t, err)
+		if !bytes.Equal(buf, content) {
+			t.Errorf("spool = %t: content mismatch after reading %d bytes", spool, len(buf))
+		}
+
+		// closing the read side removes the spool file
+		must(t, p.closeRead(nil))
+		entries, err := os.ReadDir(dir)
+		must(t, err)
+		if len(entries) != 0 {
+			t.Errorf("spool = %t: expected spool directory to be empty, but found %d entries", spool, len(entries))
+		}
+	}
+
+	// when the read side is closed with an error, the writer receives it
+	errTest := errors.New("upload failed")
+	p := newBufferedPipe(&WriterBufferOptions{BufferSize: 4})
+	must(t, p.closeRead(errTest))
+	_, err := bufferedPipeWriter{p}.Write([]byte("hello"))
+	if !errors.Is(err, errTest) {
+		t.Errorf("expected %q, got %v", errTest.Error(), err)
+	}
+}
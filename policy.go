@@ -0,0 +1,176 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// AccessPolicy restricts which requests can be made on an Account obtained
+// from Account.WithPolicy(), and on containers and objects below it. A request
+// is permitted if at least one of the rules matches it. Requests that are not
+// permitted fail with a PolicyViolationError before they are sent to the
+// server.
+//
+// For example, the following policy permits reading everything, but writing
+// only into containers whose name starts with "staging-":
+//
+//	policy := schwift.AccessPolicy{
+//		{Methods: []string{"GET", "HEAD"}, ContainerPattern: "*"},
+//		{ContainerPattern: "staging-*"},
+//	}
+//	scoped, err := account.WithPolicy(policy)
+//
+// Requests on the account itself (e.g. listing containers, updating account
+// metadata, or bulk operations) are matched with an empty container name, so
+// only rules with an empty ContainerPattern or with the pattern "*" permit
+// them. Note that this means that bulk operations cannot be restricted to
+// certain containers.
+//
+// For a COPY request, the destination container must also be permitted for a
+// PUT request. Likewise, for a PUT request with an X-Copy-From header, the
+// source container must also be permitted for a GET request. Copies from or to
+// other accounts are never permitted.
+//
+// Requests that are not directed at the account or anything below it (i.e.
+// the GET /info request made by Account.Capabilities()) are not subject to
+// the policy.
+type AccessPolicy []AccessRule
+
+// AccessRule is a single rule in an AccessPolicy.
+type AccessRule struct {
+	// If Methods is not empty, the rule only matches requests with one of these
+	// HTTP methods (e.g. "GET", "PUT", "COPY").
+	Methods []string
+	// If ContainerPattern is not empty, the rule only matches requests on
+	// containers (and objects therein) whose name matches this pattern. The
+	// syntax is that of path.Match(). Since container names cannot contain
+	// slashes, "*" matches all container names.
+	ContainerPattern string
+}
+
+func (r AccessRule) matches(method, containerName string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.ContainerPattern == "" {
+		return true
+	}
+	matched, _ := path.Match(r.ContainerPattern, containerName) //nolint:errcheck // pattern was validated by Account.WithPolicy()
+	return matched
+}
+
+func (p AccessPolicy) permits(method, containerName string) bool {
+	for _, rule := range p {
+		if rule.matches(method, containerName) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyViolationError is returned by all operations on an Account obtained
+// from Account.WithPolicy(), or on containers and objects below it, if the
+// request that would be sent is not permitted by the AccessPolicy.
+type PolicyViolationError struct {
+	Method string // e.g. http.MethodPut
+	Target string // either "<account>" or "$CONTAINER_NAME" or "$CONTAINER_NAME/$OBJECT_NAME"
+}
+
+// Error implements the builtin/error interface.
+func (e PolicyViolationError) Error() string {
+	return fmt.Sprintf("could not %s %q in Swift: not permitted by access policy", e.Method, e.Target)
+}
+
+// policyBackend wraps a Backend to reject requests that are not permitted by
+// an AccessPolicy. It is used by Account.WithPolicy().
+type policyBackend struct {
+	inner  Backend
+	policy AccessPolicy
+}
+
+func (b policyBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b policyBackend) Clone(newEndpointURL string) Backend {
+	return policyBackend{b.inner.Clone(newEndpointURL), b.policy}
+}
+
+func (b policyBackend) Do(req *http.Request) (*http.Response, error) {
+	err := b.check(req)
+	if err != nil {
+		// like http.Client.Do(), we are responsible for closing the request body
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return b.inner.Do(req)
+}
+
+func (b policyBackend) check(req *http.Request) error {
+	endpointURL, err := url.Parse(b.inner.EndpointURL())
+	if err != nil {
+		return err
+	}
+	rest, ok := strings.CutPrefix(req.URL.Path, endpointURL.Path)
+	if !ok {
+		// request is not directed at this account (e.g. GET /info)
+		return nil
+	}
+	containerName, objectName, _ := strings.Cut(rest, "/")
+	if !b.policy.permits(req.Method, containerName) {
+		return PolicyViolationError{req.Method, describeTarget(containerName, objectName)}
+	}
+
+	// check the other side of copy operations
+	accountName := path.Base(endpointURL.Path)
+	switch {
+	case req.Method == "COPY":
+		return b.checkCopyPartner(req, http.MethodPut, accountName, "Destination", "Destination-Account")
+	case req.Method == http.MethodPut && req.Header.Get("X-Copy-From") != "":
+		return b.checkCopyPartner(req, http.MethodGet, accountName, "X-Copy-From", "X-Copy-From-Account")
+	default:
+		return nil
+	}
+}
+
+func (b policyBackend) checkCopyPartner(req *http.Request, method, accountName, pathHeader, accountHeader string) error {
+	fullName := strings.TrimPrefix(req.Header.Get(pathHeader), "/")
+	containerName, objectName, _ := strings.Cut(fullName, "/")
+	otherAccountName := req.Header.Get(accountHeader)
+	if (otherAccountName != "" && otherAccountName != accountName) || !b.policy.permits(method, containerName) {
+		return PolicyViolationError{method, describeTarget(containerName, objectName)}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	b := &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	a, err := InitializeAccount(b)
+	must(t, err)
+
+	_, err = a.WithPolicy(AccessPolicy{{ContainerPattern: "[staging"}})
+	if err == nil {
+		t.Error("expected error for malformed container pattern")
+	}
+
+	a, err = a.WithPolicy(AccessPolicy{
+		{Methods: []string{http.MethodGet, http.MethodHead}, ContainerPattern: "*"},
+		{ContainerPattern: "staging-*"},
+	})
+	must(t, err)
+
+	expectViolation := func(err error, method, target string) {
+		t.Helper()
+		var pve PolicyViolationError
+		if !errors.As(err, &pve) {
+			t.Errorf("expected PolicyViolationError, got %v", err)
+			return
+		}
+		expectString(t, method, pve.Method)
+		expectString(t, target, pve.Target)
+	}
+
+	// (the test backend responds with 200 to everything, so permitted writes
+	// fail with an UnexpectedStatusCodeError instead)
+	expectPermitted := func(err error) {
+		t.Helper()
+		if err != nil && !Is(err, http.StatusOK) {
+			t.Errorf("expected request to be permitted, got %v", err)
+		}
+	}
+
+	// reads are permitted everywhere
+	_, err = a.Container("prod").Object("foo").Download(context.Background(), nil).AsByteSlice()
+	must(t, err)
+	_, err = a.Containers().NextPage(context.Background(), -1)
+	must(t, err)
+
+	// writes are only permitted in staging containers
+	err = a.Container("staging-1").Object("foo").Upload(context.Background(), strings.NewReader("hello"), nil, nil)
+	expectPermitted(err)
+	b.lastReq = nil
+	err = a.Container("prod").Object("foo/bar").Upload(context.Background(), strings.NewReader("hello"), nil, nil)
+	expectViolation(err, http.MethodPut, "prod/foo/bar")
+	err = a.Update(context.Background(), NewAccountHeaders(), nil)
+	expectViolation(err, http.MethodPost, "<account>")
+	if b.lastReq != nil {
+		t.Errorf("expected no request to be sent, but got %s %s", b.lastReq.Method, b.lastReq.URL)
+	}
+
+	// copies are checked on both sides
+	err = a.Container("staging-1").Object("foo").CopyTo(context.Background(), a.Container("prod").Object("foo"), nil, nil)
+	expectViolation(err, http.MethodPut, "prod/foo")
+}
@@ -0,0 +1,70 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"hash"
+	"sync"
+)
+
+// This file contains pools for objects that are allocated in hot paths, to
+// reduce GC pressure for applications with high request rates.
+
+var md5Pool = sync.Pool{
+	New: func() any { return md5.New() }, //nolint:gosec // Etag uses md5
+}
+
+// getMD5 returns a fresh MD5 hasher from the pool. When the hasher is not
+// needed anymore, it should be returned with putMD5().
+func getMD5() hash.Hash {
+	h, ok := md5Pool.Get().(hash.Hash)
+	if !ok {
+		return md5.New() //nolint:gosec // Etag uses md5
+	}
+	h.Reset()
+	return h
+}
+
+func putMD5(h hash.Hash) {
+	md5Pool.Put(h)
+}
+
+var downloadBufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, downloadBufferSize)
+		return &buf
+	},
+}
+
+// getDownloadBuffer returns a buffer of size downloadBufferSize from the pool.
+// When the buffer is not needed anymore, it should be returned with
+// putDownloadBuffer().
+func getDownloadBuffer() *[]byte {
+	buf, ok := downloadBufferPool.Get().(*[]byte)
+	if !ok {
+		slice := make([]byte, downloadBufferSize)
+		return &slice
+	}
+	return buf
+}
+
+func putDownloadBuffer(buf *[]byte) {
+	downloadBufferPool.Put(buf)
+}
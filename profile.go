@@ -0,0 +1,268 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Profile contains defaults for all requests made through an Account obtained
+// from Account.WithProfile(), and through containers and objects below it.
+// This avoids passing the same RequestOptions on every call. For example:
+//
+//	profile := schwift.Profile{
+//		Timeout: 5 * time.Minute,
+//		Retry:   schwift.RetryPolicy{MaxAttempts: 3},
+//		Headers: schwift.Headers{"X-Openstack-Request-Id": "nightly-backup"},
+//	}
+//	account = account.WithProfile(profile)
+//
+// Overrides for specific parts of the code base can be derived from the
+// account's profile:
+//
+//	profile := account.Profile()
+//	profile.Timeout = time.Hour
+//	container := account.Container("huge-files").WithProfile(profile)
+type Profile struct {
+	// Headers that are added to every request, unless the request already has
+	// a value for the respective header.
+	Headers Headers
+	// If not zero, every request is aborted if it takes longer than this,
+	// including the time for reading the response body. This applies in
+	// addition to RequestOptions.Timeout, i.e. the shorter timeout wins.
+	Timeout time.Duration
+	// If not zero, every request is aborted with an IdleTimeoutError if no data
+	// has been received for this long. Like with Timeout, this applies in
+	// addition to RequestOptions.IdleTimeout.
+	IdleTimeout time.Duration
+	// If true, "X-Newest: true" is sent on all GET and HEAD requests. See
+	// documentation on Account.WithNewest() for details.
+	Newest bool
+	// Controls whether failed requests are retried.
+	Retry RetryPolicy
+	// If not nil, this is called after every request (including each retry
+	// attempt) once the response headers have been received or the request has
+	// failed. This can be used to report progress, or to collect metrics.
+	OnRequest func(RequestEvent)
+}
+
+// RetryPolicy appears in type Profile. A request is retried if it fails
+// without a response (except when its context was canceled), or if the
+// response has one of the status codes 429, 500, 502, 503 or 504. Requests
+// with a body are only retried if the body can be replayed, i.e. if it is a
+// *bytes.Buffer, *bytes.Reader or *strings.Reader.
+//
+// Since Swift implements all object and container operations idempotently,
+// this is safe for all request methods, but note that e.g. a DELETE that is
+// retried after succeeding on the server may fail with 404.
+type RetryPolicy struct {
+	// The maximum number of attempts for each request. Values <= 1 disable
+	// retries.
+	MaxAttempts int
+	// The delay before the first retry. Each further retry doubles the delay.
+	// Defaults to 100 milliseconds.
+	InitialBackoff time.Duration
+	// The upper bound for the delay between retries. Defaults to 10 seconds.
+	MaxBackoff time.Duration
+}
+
+// RequestEvent is passed to Profile.OnRequest.
+type RequestEvent struct {
+	Method string // e.g. http.MethodGet
+	Target string // either "<account>" or "$CONTAINER_NAME" or "$CONTAINER_NAME/$OBJECT_NAME"
+	// Attempt is 1 for the first attempt, and counts upwards for retries.
+	Attempt int
+	// StatusCode is 0 if no response was received. In this case, Err contains
+	// the error.
+	StatusCode int
+	Err        error
+	// The time until the response headers were received (or the request failed).
+	Duration time.Duration
+}
+
+// WithProfile returns a handle to the same account that applies the given
+// Profile to all requests, including those for containers and objects below
+// it. When called on an Account that was itself obtained from WithProfile(),
+// the previous profile is replaced instead of being stacked.
+//
+// The profile carries over to accounts obtained from the returned Account via
+// SwitchAccount(), WithNewest(), ReadOnly() etc.
+func (a *Account) WithProfile(profile Profile) *Account {
+	inner := a.backend
+	if pb, ok := inner.(profileBackend); ok {
+		inner = pb.inner
+	}
+	headers := make(Headers, len(profile.Headers))
+	for k, v := range profile.Headers {
+		headers.Set(k, v)
+	}
+	profile.Headers = headers
+
+	return a.withBackend(profileBackend{inner, profile})
+}
+
+// Profile returns the profile that was given to WithProfile() when this
+// Account was obtained from it, or the zero value otherwise.
+func (a *Account) Profile() Profile {
+	if pb, ok := a.backend.(profileBackend); ok {
+		return pb.profile
+	}
+	return Profile{}
+}
+
+// WithProfile returns a handle to the same container, whose requests (and
+// those for objects below it) use the given Profile. This is a shorthand for
+// calling Account.WithProfile() and then retrieving the container from the
+// resulting account. Default object headers set with WithDefaultObjectHeaders()
+// carry over to the returned Container.
+//
+// The returned Container does not share any caches with this Container.
+func (c *Container) WithProfile(profile Profile) *Container {
+	return &Container{
+		a:                    c.a.WithProfile(profile),
+		name:                 c.name,
+		defaultObjectHeaders: c.defaultObjectHeaders,
+	}
+}
+
+// profileBackend wraps a Backend to apply a Profile. It is used by
+// Account.WithProfile().
+type profileBackend struct {
+	inner   Backend
+	profile Profile
+}
+
+func (b profileBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b profileBackend) Clone(newEndpointURL string) Backend {
+	return profileBackend{b.inner.Clone(newEndpointURL), b.profile}
+}
+
+func (b profileBackend) Do(req *http.Request) (*http.Response, error) {
+	p := b.profile
+	for k, v := range p.Headers {
+		if req.Header.Get(k) == "" {
+			req.Header.Set(k, v)
+		}
+	}
+	if p.Newest && isReadMethod(req.Method) && req.Header.Get("X-Newest") == "" {
+		req.Header.Set("X-Newest", "true")
+	}
+
+	target := describeRequestTarget(b.inner.EndpointURL(), req.URL)
+	canReplay := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	maxAttempts := max(p.Retry.MaxAttempts, 1)
+	backoff := durationOrDefault(p.Retry.InitialBackoff, 100*time.Millisecond)
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		start := time.Now()
+		idleErr := IdleTimeoutError{req.Method, target, p.IdleTimeout}
+		resp, err := doWithTimeouts(req.Context(), p.Timeout, idleErr, func(ctx context.Context) (*http.Response, error) {
+			return b.inner.Do(req.WithContext(ctx))
+		})
+		if p.OnRequest != nil {
+			event := RequestEvent{
+				Method:   req.Method,
+				Target:   target,
+				Attempt:  attempt,
+				Err:      err,
+				Duration: time.Since(start),
+			}
+			if resp != nil {
+				event.StatusCode = resp.StatusCode
+			}
+			p.OnRequest(event)
+		}
+
+		if attempt >= maxAttempts || !canReplay || !isRetryable(req.Context(), resp, err) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body) //nolint:errcheck // we are going to retry anyway
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, durationOrDefault(p.Retry.MaxBackoff, 10*time.Second))
+	}
+}
+
+func isRetryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	return isRetryableStatus(resp.StatusCode)
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// describeRequestTarget is like describeTarget, but works on the URL of a
+// request to the given endpoint. For requests that are not directed at the
+// account or anything below it (e.g. GET /info), the URL path is returned.
+func describeRequestTarget(endpointURL string, u *url.URL) string {
+	containerName, objectName, ok := splitRequestURL(endpointURL, u)
+	if !ok {
+		return u.Path
+	}
+	return describeTarget(containerName, objectName)
+}
+
+// splitRequestURL extracts the container name and object name from the URL of
+// a request to the given endpoint. If the request is not directed at the
+// account or anything below it, false is returned.
+func splitRequestURL(endpointURL string, u *url.URL) (containerName, objectName string, ok bool) {
+	parsed, err := url.Parse(endpointURL)
+	if err != nil {
+		return "", "", false
+	}
+	rest, ok := strings.CutPrefix(u.Path, parsed.Path)
+	if !ok {
+		return "", "", false
+	}
+	containerName, objectName, _ = strings.Cut(rest, "/")
+	return containerName, objectName, true
+}
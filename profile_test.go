@@ -0,0 +1,96 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	ctx := context.Background()
+	statusCode := http.StatusServiceUnavailable
+	b := &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		return testResponse(statusCode, ""), nil
+	}}
+	a, err := InitializeAccount(b)
+	must(t, err)
+
+	var events []RequestEvent
+	a = a.WithProfile(Profile{
+		Headers: Headers{"x-openstack-request-id": "test"},
+		Newest:  true,
+		Retry:   RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		OnRequest: func(e RequestEvent) {
+			events = append(events, e)
+		},
+	})
+
+	// failing requests are retried, and each attempt is reported
+	_, err = a.Container("foo").Headers(ctx)
+	if !Is(err, http.StatusServiceUnavailable) {
+		t.Errorf("expected 503 error, got %v", err)
+	}
+	if b.requests != 3 || len(events) != 3 {
+		t.Errorf("expected 3 attempts, but got %d requests and %d events", b.requests, len(events))
+	}
+	for idx, e := range events {
+		if e.Attempt != idx+1 || e.StatusCode != http.StatusServiceUnavailable || e.Target != "foo" {
+			t.Errorf("unexpected event: %#v", e)
+		}
+	}
+
+	// default headers and X-Newest are applied, but explicit headers win
+	statusCode = http.StatusNoContent
+	b.requests = 0
+	_, err = a.Container("foo").Headers(ctx)
+	must(t, err)
+	expectString(t, "test", b.lastReq.Header.Get("X-Openstack-Request-Id"))
+	expectString(t, "true", b.lastReq.Header.Get("X-Newest"))
+	must(t, a.Container("foo").Update(ctx, NewContainerHeaders(), &RequestOptions{RequestID: "explicit"}))
+	expectString(t, "explicit", b.lastReq.Header.Get("X-Openstack-Request-Id"))
+	expectString(t, "", b.lastReq.Header.Get("X-Newest"))
+	if b.requests != 2 {
+		t.Errorf("expected 2 requests, but got %d", b.requests)
+	}
+
+	// overrides replace the profile instead of stacking
+	profile := a.Profile()
+	profile.Headers = nil
+	c := a.Container("foo").WithProfile(profile)
+	_, err = c.Headers(ctx)
+	must(t, err)
+	expectString(t, "", b.lastReq.Header.Get("X-Openstack-Request-Id"))
+	expectString(t, "true", b.lastReq.Header.Get("X-Newest"))
+	if _, ok := c.Account().Backend().(profileBackend).inner.(profileBackend); ok {
+		t.Error("expected profiles to not be stacked")
+	}
+
+	// timeouts apply to each request
+	tb := &testBackend{handle: hangUntilCanceled}
+	a, err = InitializeAccount(tb)
+	must(t, err)
+	_, err = a.WithProfile(Profile{Timeout: 10 * time.Millisecond}).Container("foo").Headers(ctx)
+	if err == nil || !strings.Contains(err.Error(), "deadline exceeded") {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+}
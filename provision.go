@@ -0,0 +1,153 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+)
+
+// AccountSpec describes the desired state of an account and its containers.
+// It is passed to Account.Provision().
+//
+// All settings are expressed as headers, so the type-safe API on
+// AccountHeaders and ContainerHeaders can be used to describe ACLs, quotas,
+// versioning, container sync etc. Settings without a type-safe accessor (e.g.
+// those for the staticweb middleware) can be given as metadata. For example:
+//
+//	hdr := schwift.NewContainerHeaders()
+//	hdr.ReadACLEntries().Set([]string{".r:*", ".rlistings"})
+//	hdr.BytesUsedQuota().Set(1 << 30)
+//	hdr.Metadata().Set("Web-Index", "index.html")
+//
+//	spec := schwift.AccountSpec{
+//		Containers: []schwift.ContainerSpec{{Name: "www", Headers: hdr}},
+//	}
+//	changes, err := account.Provision(ctx, spec, nil)
+//
+// Headers that are not mentioned in the spec are left unchanged on the server.
+// To remove a header on the server, set it to the empty string (see
+// Headers.Clear()).
+type AccountSpec struct {
+	// Headers may be nil if the account itself shall not be changed.
+	Headers    AccountHeaders
+	Containers []ContainerSpec
+}
+
+// ContainerSpec describes the desired state of a container. It appears in
+// struct AccountSpec.
+type ContainerSpec struct {
+	Name string
+	// Headers may be nil if the container only needs to exist.
+	Headers ContainerHeaders
+}
+
+// ProvisionOptions invokes advanced behavior in the Account.Provision() method.
+type ProvisionOptions struct {
+	// When DryRun is set, Provision() only reports the changes that it would
+	// make, without making them. Only HEAD requests will be sent.
+	DryRun bool
+}
+
+// ProvisionChange describes a change made (or planned, for
+// ProvisionOptions.DryRun) by Account.Provision().
+type ProvisionChange struct {
+	// ContainerName is empty for changes to the account itself.
+	ContainerName string
+	// Created is true if the container did not exist before.
+	Created bool
+	// Headers contains the headers that were sent (or would have been sent) to
+	// the server. For created containers, these are all the headers from the
+	// ContainerSpec.
+	Headers Headers
+}
+
+// Provision reconciles the account and its containers with the given spec,
+// and returns the list of changes that were made. Containers that do not exist
+// are created, and existing accounts and containers are updated if their
+// headers differ from those in the spec. See documentation on type AccountSpec
+// for details.
+//
+// Containers that exist on the server, but are not mentioned in the spec, are
+// never touched.
+//
+// If an error occurs, the changes made up to that point are returned along
+// with the error.
+func (a *Account) Provision(ctx context.Context, spec AccountSpec, opts *ProvisionOptions) ([]ProvisionChange, error) {
+	if opts == nil {
+		opts = &ProvisionOptions{}
+	}
+	var changes []ProvisionChange
+
+	// reconcile account
+	if len(spec.Headers.Headers) > 0 {
+		current, err := a.Headers(ctx)
+		if err != nil {
+			return changes, err
+		}
+		diff := diffHeaders(current.Headers, spec.Headers.Headers)
+		if len(diff) > 0 {
+			if !opts.DryRun {
+				err := a.Update(ctx, AccountHeaders{Headers: diff}, nil)
+				if err != nil {
+					return changes, err
+				}
+			}
+			changes = append(changes, ProvisionChange{Headers: diff})
+		}
+	}
+
+	// reconcile containers
+	for _, cs := range spec.Containers {
+		c := a.Container(cs.Name)
+		exists, err := c.Exists(ctx)
+		if err != nil {
+			return changes, err
+		}
+
+		var diff Headers
+		if opts.DryRun {
+			// compute the same diff as CreateIfNotExists(), but do not apply it
+			current := make(Headers)
+			if exists {
+				currentHdr, err := c.Headers(ctx)
+				if err != nil {
+					return changes, err
+				}
+				current = currentHdr.Headers
+			}
+			diff = diffHeaders(current, cs.Headers.Headers)
+		} else {
+			changed, err := c.CreateIfNotExists(ctx, cs.Headers, nil)
+			if err != nil {
+				return changes, err
+			}
+			diff = changed.Headers
+		}
+
+		if !exists || len(diff) > 0 {
+			changes = append(changes, ProvisionChange{
+				ContainerName: cs.Name,
+				Created:       !exists,
+				Headers:       diff,
+			})
+		}
+	}
+
+	return changes, nil
+}
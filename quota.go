@@ -0,0 +1,90 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+)
+
+// QuotaCheckPolicy is an enum that appears in type UploadOptions. It controls
+// whether Object.Upload() checks the quotas of the account and container
+// before uploading, in order to fail fast with a QuotaExceededError instead of
+// having Swift reject the upload after the entire content has been sent.
+//
+// The check uses the Content-Length of the upload if it is known (see
+// documentation on Object.Upload() for when it is computed automatically).
+// Otherwise, the check only fails if the quota is exhausted already. Since the
+// check does not know whether an existing object is being overwritten, it
+// always assumes that the upload adds a new object. Also, since quotas are
+// checked before the upload starts, concurrent uploads by other clients can
+// still cause Swift to reject the upload.
+type QuotaCheckPolicy int
+
+const (
+	// QuotaCheckOff disables the quota check. This is the default.
+	QuotaCheckOff QuotaCheckPolicy = iota
+	// QuotaCheckBestEffort checks the quotas, but proceeds with the upload if
+	// the quotas cannot be determined (e.g. because the HEAD requests fail).
+	QuotaCheckBestEffort
+	// QuotaCheckStrict checks the quotas, and fails the upload if the quotas
+	// cannot be determined.
+	QuotaCheckStrict
+)
+
+// checkQuota implements UploadOptions.QuotaCheck. The statistics are always
+// obtained with fresh HEAD requests, without using or filling the header
+// caches of the Account and Container instances, since the cached values
+// would go stale with each upload.
+func (o *Object) checkQuota(ctx context.Context, sizeBytes uint64, policy QuotaCheckPolicy) error {
+	a := o.c.a.withBackend(o.c.a.backend)
+	accountHdr, err := a.Headers(ctx)
+	if err != nil {
+		if policy == QuotaCheckStrict {
+			return err
+		}
+	} else if missing := missingQuota(accountHdr.BytesUsed().Get(), sizeBytes, accountHdr.BytesUsedQuota()); missing > 0 {
+		return QuotaExceededError{Target: describeTarget("", ""), MissingBytes: missing}
+	}
+
+	containerHdr, err := a.Container(o.c.name).Headers(ctx)
+	if err != nil {
+		if policy == QuotaCheckStrict {
+			return err
+		}
+		return nil
+	}
+	target := describeTarget(o.c.name, "")
+	if missing := missingQuota(containerHdr.BytesUsed().Get(), sizeBytes, containerHdr.BytesUsedQuota()); missing > 0 {
+		return QuotaExceededError{Target: target, MissingBytes: missing}
+	}
+	if missing := missingQuota(containerHdr.ObjectCount().Get(), 1, containerHdr.ObjectCountQuota()); missing > 0 {
+		return QuotaExceededError{Target: target, MissingObjects: missing}
+	}
+	return nil
+}
+
+// missingQuota returns by how much `used + requested` exceeds `quota`, or 0 if
+// it does not exceed the quota or no quota is set.
+func missingQuota(used, requested uint64, quota FieldUint64) uint64 {
+	limit := quota.Get()
+	if !quota.Exists() || used+requested <= limit {
+		return 0
+	}
+	return used + requested - limit
+}
@@ -0,0 +1,157 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteRange describes a range of bytes within an object, following the same
+// conventions as the RangeOffset and RangeLength fields of SegmentInfo:
+//
+// For .Length == 0, the range consists of all bytes after skipping the first
+// .Offset bytes. The zero value therefore describes the entire object.
+//
+// For .Length > 0, the range consists of that many bytes, again after skipping
+// the first .Offset bytes.
+//
+// For .Offset < 0, the range consists of the last .Length bytes of the object.
+// (The concrete value for .Offset is disregarded.)
+type ByteRange struct {
+	Offset int64
+	Length uint64
+}
+
+// ParseByteRange parses a single byte range in the syntax of RFC 7233, section
+// 2.1 (without the "bytes=" prefix), e.g. "0-499", "9500-" or "-500". As an
+// extension, "-" is accepted as the entire object.
+func ParseByteRange(str string) (ByteRange, error) {
+	first, last, ok := strings.Cut(str, "-")
+	if !ok {
+		return ByteRange{}, fmt.Errorf("malformed byte range: %q", str)
+	}
+
+	if first == "" {
+		// case 1: "-"
+		if last == "" {
+			return ByteRange{}, nil
+		}
+
+		// case 2: "-N"
+		numBytes, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return ByteRange{}, fmt.Errorf("malformed byte range: %q", str)
+		}
+		return ByteRange{Offset: -1, Length: numBytes}, nil
+	}
+
+	firstByte, err := strconv.ParseUint(first, 10, 63) // not 64; needs to be unsigned, but also fit into int64
+	if err != nil {
+		return ByteRange{}, fmt.Errorf("malformed byte range: %q", str)
+	}
+	if last == "" {
+		// case 3: "N-"
+		return ByteRange{Offset: int64(firstByte)}, nil
+	}
+	// case 4: "M-N"
+	lastByte, err := strconv.ParseUint(last, 10, 64)
+	if err != nil || lastByte < firstByte {
+		return ByteRange{}, fmt.Errorf("malformed byte range: %q", str)
+	}
+	return ByteRange{Offset: int64(firstByte), Length: lastByte - firstByte + 1}, nil
+}
+
+// String formats this range in the syntax of RFC 7233, section 2.1 (without
+// the "bytes=" prefix). This is the inverse of ParseByteRange(), except that
+// the entire object is formatted as "0-".
+func (r ByteRange) String() string {
+	switch {
+	case r.Offset < 0:
+		return "-" + strconv.FormatUint(r.Length, 10)
+	case r.Length == 0:
+		return strconv.FormatInt(r.Offset, 10) + "-"
+	default:
+		firstByteStr := strconv.FormatUint(uint64(r.Offset), 10)
+		lastByteStr := strconv.FormatUint(uint64(r.Offset)+r.Length-1, 10)
+		return firstByteStr + "-" + lastByteStr
+	}
+}
+
+// Resolve converts this range into absolute byte positions within an object
+// of the given size. The result is a half-open interval, i.e. the range
+// covers the bytes with indexes start <= i < end. If the range is
+// unsatisfiable (i.e. it does not cover any bytes of the object), false is
+// returned.
+func (r ByteRange) Resolve(sizeBytes uint64) (start, end uint64, ok bool) {
+	switch {
+	case r.Offset < 0:
+		start, end = sizeBytes-min(r.Length, sizeBytes), sizeBytes
+	case r.Length == 0:
+		start, end = uint64(r.Offset), sizeBytes
+	default:
+		start, end = uint64(r.Offset), min(uint64(r.Offset)+r.Length, sizeBytes)
+	}
+	if start >= end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// RangeSpec is a list of byte ranges, as used in the HTTP Range header.
+type RangeSpec []ByteRange
+
+// ParseRangeSpec parses the value of an HTTP Range header like
+// "bytes=0-499,-500". Only the "bytes" unit is supported.
+func ParseRangeSpec(header string) (RangeSpec, error) {
+	rangesStr, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok {
+		return nil, fmt.Errorf("malformed Range header: %q", header)
+	}
+	var result RangeSpec
+	for _, field := range strings.Split(rangesStr, ",") {
+		r, err := ParseByteRange(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("malformed Range header: %q", header)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// String formats this RangeSpec as the value of an HTTP Range header, e.g.
+// "bytes=0-499,-500". This is the inverse of ParseRangeSpec().
+func (s RangeSpec) String() string {
+	fields := make([]string, len(s))
+	for idx, r := range s {
+		fields[idx] = r.String()
+	}
+	return "bytes=" + strings.Join(fields, ",")
+}
+
+// WithRange returns a copy of this RequestOptions instance with the Range
+// header set to the given ranges. Like WithHeaders, this method can be called
+// on a nil receiver and does not modify the receiver. For example:
+//
+//	opts := (*schwift.RequestOptions)(nil).WithRange(schwift.ByteRange{Offset: 100, Length: 50})
+//	buf, err := obj.Download(ctx, opts).AsByteSlice()
+func (o *RequestOptions) WithRange(ranges ...ByteRange) *RequestOptions {
+	return o.WithHeader("Range", RangeSpec(ranges).String())
+}
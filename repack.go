@@ -0,0 +1,285 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// RepackOptions contains options for LargeObject.Repack().
+type RepackOptions struct {
+	// Runs of consecutive segments that are smaller than this size are merged
+	// into new segments of at most this size. If zero, the TargetSegmentSize of
+	// the attached SegmentPolicy is used, or 1 GiB if that is not set either.
+	TargetSegmentSize uint64
+	// If true, Repack() only reports what it would do, without uploading any
+	// segments or changing the manifest.
+	DryRun bool
+	// If true, segment objects that are no longer referenced after repacking
+	// are deleted (but only if they are located in SegmentContainer() below
+	// SegmentPrefix()). This will cause Repack() to call into BulkDelete(), so
+	// a BulkError may be returned.
+	DeleteOldSegments bool
+}
+
+// RepackReport is returned by LargeObject.Repack() and describes the changes
+// that were made (or, with RepackOptions.DryRun, that would be made).
+type RepackReport struct {
+	SegmentCountBefore int
+	SegmentCountAfter  int
+	// The number of existing segments that were merged into new segments.
+	MergedSegmentCount int
+	// The number of new segments that were uploaded.
+	NewSegmentCount int
+	// The total size of all new segments.
+	BytesRewritten uint64
+}
+
+// Repack merges runs of consecutive small segments of a static large object
+// into fewer, larger segments. Large objects that were built from many small
+// appends are slow to read and may hit the limits on the number of segments
+// per manifest, so it makes sense to repack them from time to time.
+//
+// The contents of each run are downloaded and re-uploaded as new segments in
+// the SegmentContainer() below the SegmentPrefix(). Once all new segments
+// have been uploaded, the manifest is written with a single request, while
+// keeping the object's existing metadata. Segments that are at least as large
+// as the target segment size, and segments whose size is not known, are left
+// alone.
+//
+// For dynamic large objects, ErrSegmentInvalid is returned since the order of
+// their segments is determined by the segment names and cannot be changed
+// freely.
+//
+// This function uploads segment objects and writes the manifest, so it may
+// return any error that Object.Upload() or LargeObject.WriteManifest()
+// returns, see documentation over there.
+func (lo *LargeObject) Repack(ctx context.Context, opts *RepackOptions, ropts *RequestOptions) (RepackReport, error) {
+	if opts == nil {
+		opts = &RepackOptions{}
+	}
+	if lo.strategy != StaticLargeObject {
+		return RepackReport{}, fmt.Errorf("%w: only static large objects can be repacked", ErrSegmentInvalid)
+	}
+	err := lo.LoadSegments(ctx)
+	if err != nil {
+		return RepackReport{}, err
+	}
+	targetSize := opts.TargetSegmentSize
+	if targetSize == 0 {
+		targetSize = lo.policy.TargetSegmentSize
+	}
+	if targetSize == 0 {
+		targetSize = 1 << 30
+	}
+
+	runs := planRepack(lo.segments, targetSize)
+	report := RepackReport{
+		SegmentCountBefore: len(lo.segments),
+		SegmentCountAfter:  len(lo.segments),
+	}
+	for _, run := range runs {
+		report.SegmentCountAfter -= run.end - run.start - 1
+		report.MergedSegmentCount += run.end - run.start
+		report.NewSegmentCount++
+		report.BytesRewritten += run.sizeBytes
+	}
+	if opts.DryRun || len(runs) == 0 {
+		return report, nil
+	}
+
+	manifestOpts, err := lo.object.manifestRewriteOptions(ctx, ropts)
+	if err != nil {
+		return report, err
+	}
+
+	// new segments must not overwrite any existing segments (segment names are
+	// not necessarily ascending, e.g. after a previous Repack())
+	existingNames := make(map[string]bool)
+	for _, o := range lo.SegmentObjects() {
+		existingNames[o.FullName()] = true
+	}
+	obj := lo.NextSegmentObject()
+	nextObject := func() *Object {
+		for existingNames[obj.FullName()] {
+			obj = lo.segmentContainer.Object(nextSegmentName(obj.Name()))
+		}
+		result := obj
+		obj = lo.segmentContainer.Object(nextSegmentName(obj.Name()))
+		return result
+	}
+
+	// upload the new segments
+	newSegments := make([]SegmentInfo, 0, report.SegmentCountAfter)
+	var obsoleteSegments []SegmentInfo
+	offset := 0
+	for _, run := range runs {
+		newSegments = append(newSegments, lo.segments[offset:run.start]...)
+		offset = run.end
+
+		reader := &segmentSequenceReader{ctx: ctx, segments: lo.segments[run.start:run.end]}
+		tracker := lengthAndEtagTrackingReader{
+			Reader: reader,
+			Hasher: getMD5(),
+		}
+		segmentObj := nextObject()
+		err := segmentObj.Upload(ctx, &tracker, nil, ropts)
+		closeErr := reader.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return report, err
+		}
+		newSegments = append(newSegments, SegmentInfo{
+			Object:    segmentObj,
+			SizeBytes: tracker.BytesRead,
+			Etag:      hex.EncodeToString(tracker.Hasher.Sum(nil)),
+		})
+		putMD5(tracker.Hasher)
+		obsoleteSegments = append(obsoleteSegments, lo.segments[run.start:run.end]...)
+	}
+	newSegments = append(newSegments, lo.segments[offset:]...)
+
+	// replace the manifest
+	lo.segments = newSegments
+	err = lo.WriteManifest(ctx, manifestOpts)
+	if err != nil || !opts.DeleteOldSegments {
+		return report, err
+	}
+
+	// delete segment objects that are not referenced anymore
+	stillReferenced := make(map[string]bool)
+	for _, o := range lo.SegmentObjects() {
+		stillReferenced[o.FullName()] = true
+	}
+	var unreferenced []*Object
+	for _, o := range (&LargeObject{segments: obsoleteSegments}).SegmentObjects() {
+		if lo.isOwnSegment(o) && !stillReferenced[o.FullName()] {
+			unreferenced = append(unreferenced, o)
+		}
+	}
+	if len(unreferenced) > 0 {
+		_, _, err = lo.object.c.a.BulkDelete(ctx, unreferenced, nil, nil)
+	}
+	return report, err
+}
+
+// repackRun is a sequence of segments (from index start to index end-1) that
+// will be merged into one segment by Repack().
+type repackRun struct {
+	start, end int
+	sizeBytes  uint64
+}
+
+// planRepack finds the runs of segments that will be merged by Repack(). Only
+// runs of at least two segments are returned.
+func planRepack(segments []SegmentInfo, targetSize uint64) []repackRun {
+	var (
+		result  []repackRun
+		current repackRun
+	)
+	flush := func(idx int) {
+		if current.end-current.start >= 2 {
+			result = append(result, current)
+		}
+		current = repackRun{start: idx, end: idx}
+	}
+
+	for idx, s := range segments {
+		size, known := s.effectiveSize()
+		if !known || size >= targetSize {
+			flush(idx + 1)
+			continue
+		}
+		if current.sizeBytes+size > targetSize {
+			flush(idx)
+		}
+		current.end = idx + 1
+		current.sizeBytes += size
+	}
+	flush(len(segments))
+	return result
+}
+
+// segmentSequenceReader is an io.Reader that yields the contents of a list of
+// segments, downloading one segment at a time.
+type segmentSequenceReader struct {
+	ctx      context.Context //nolint:containedctx // only lives for the duration of one Upload()
+	segments []SegmentInfo
+	current  io.ReadCloser
+}
+
+// Read implements the io.Reader interface.
+func (r *segmentSequenceReader) Read(buf []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.segments) == 0 {
+				return 0, io.EOF
+			}
+			err := r.openNextSegment()
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.current.Read(buf)
+		if err == io.EOF {
+			err = r.current.Close()
+			r.current = nil
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close releases the segment download that is currently in progress, if any.
+func (r *segmentSequenceReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
+
+func (r *segmentSequenceReader) openNextSegment() error {
+	s := r.segments[0]
+	r.segments = r.segments[1:]
+
+	if len(s.Data) > 0 {
+		r.current = io.NopCloser(bytes.NewReader(s.Data))
+		return nil
+	}
+
+	var opts *RequestOptions
+	if s.RangeOffset != 0 || s.RangeLength != 0 {
+		opts = opts.WithRange(s.ByteRange())
+	}
+	var err error
+	r.current, err = s.Object.Download(r.ctx, opts).AsReadCloser()
+	return err
+}
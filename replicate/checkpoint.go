@@ -0,0 +1,107 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package replicate
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint records the progress of a replication, so that an interrupted
+// replication can be resumed. Progress is recorded per source container as a
+// marker: All objects in that container whose name sorts before or equal to
+// the marker have been replicated.
+//
+// Implementations must be safe for concurrent use.
+type Checkpoint interface {
+	// Load returns the marker for the given source container, or the empty
+	// string if no progress has been recorded for that container.
+	Load(containerName string) (string, error)
+	// Save records a new marker for the given source container.
+	Save(containerName, marker string) error
+}
+
+// FileCheckpoint is a Checkpoint that persists its state in a JSON file at the
+// given path. The file is created on the first call to Save(), and is replaced
+// atomically on each subsequent call. The zero value is not usable; Path must
+// be set.
+type FileCheckpoint struct {
+	Path string
+
+	mutex   sync.Mutex
+	markers map[string]string
+}
+
+// Load implements the Checkpoint interface.
+func (c *FileCheckpoint) Load(containerName string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err := c.loadIfNecessary()
+	return c.markers[containerName], err
+}
+
+// Save implements the Checkpoint interface.
+func (c *FileCheckpoint) Save(containerName, marker string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	err := c.loadIfNecessary()
+	if err != nil {
+		return err
+	}
+	c.markers[containerName] = marker
+
+	buf, err := json.Marshal(c.markers)
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(filepath.Dir(c.Path), "."+filepath.Base(c.Path)+".tmp")
+	err = os.WriteFile(tmpPath, buf, 0o666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.Path)
+}
+
+func (c *FileCheckpoint) loadIfNecessary() error {
+	if c.markers != nil {
+		return nil
+	}
+	buf, err := os.ReadFile(c.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.markers = make(map[string]string)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var markers map[string]string
+	err = json.Unmarshal(buf, &markers)
+	if err != nil {
+		return err
+	}
+	if markers == nil {
+		markers = make(map[string]string)
+	}
+	c.markers = markers
+	return nil
+}
@@ -0,0 +1,453 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package replicate copies objects from one Swift container or account into
+another, which may be located in a different account or on a different Swift
+cluster. For example:
+
+	import "github.com/majewsky/schwift/v2/replicate"
+
+	result, err := replicate.Container(ctx,
+		sourceAccount.Container("assets"),
+		targetAccount.Container("assets"),
+		&replicate.Options{Concurrency: 4},
+	)
+
+Object contents are streamed from the source to the target without buffering,
+so the source and target Account instances may use entirely unrelated backends.
+Object metadata (Content-Type, Content-Disposition, Content-Encoding,
+X-Delete-At and all X-Object-Meta-* headers) is carried over.
+
+Objects that already exist in the target with the same Etag and size are
+skipped, so replication can be repeated to bring the target up to date with
+the source. Additionally, a Checkpoint can be supplied to persist progress, so
+that an interrupted replication can be resumed without checking every object
+again. Objects are never deleted from the target, even if they do not exist
+in the source.
+
+Symlinks are replicated as symlinks. If a symlink points to an object in the
+source account, the replicated symlink points to the object of the same name
+in the target account.
+
+Large objects are replicated as large objects using the same segmenting
+strategy: Each segment is replicated into the target account (into a container
+with the same name as the segment's source container, which is created if
+necessary), and then a new manifest referring to those segments is written.
+*/
+package replicate
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// Options contains optional settings for Container() and Account().
+type Options struct {
+	// Concurrency is the number of objects that are replicated in parallel.
+	// Values below 1 are treated as 1.
+	Concurrency int
+	// If Prefix is set, only objects whose name starts with this string are
+	// replicated.
+	Prefix string
+	// If Checkpoint is set, progress is recorded there after each replicated
+	// object, and objects that have already been recorded as done are skipped
+	// without checking them again.
+	Checkpoint Checkpoint
+	// If Overwrite is set, objects are replicated even if the target object
+	// already has the same Etag and size as the source object.
+	Overwrite bool
+}
+
+// Result contains statistics about a replication run.
+type Result struct {
+	// ObjectsCopied counts objects (including symlinks and large object
+	// manifests) that were written into the target.
+	ObjectsCopied uint64
+	// ObjectsSkipped counts objects that were not written because they were
+	// already up to date in the target, or because they were recorded as done
+	// in the checkpoint.
+	ObjectsSkipped uint64
+	// BytesCopied counts the object contents that were streamed from source to
+	// target, including the contents of large object segments.
+	BytesCopied uint64
+}
+
+func (r *Result) add(other Result) {
+	r.ObjectsCopied += other.ObjectsCopied
+	r.ObjectsSkipped += other.ObjectsSkipped
+	r.BytesCopied += other.BytesCopied
+}
+
+// Container replicates all objects from the source container into the target
+// container. The target container is created if it does not exist yet, with
+// the metadata (X-Container-Meta-* headers) of the source container.
+//
+// If an error occurs, replication stops and the error is returned together
+// with the statistics collected up to that point. Objects that were
+// replicated successfully before the error will be recorded in the
+// checkpoint, if any.
+func Container(ctx context.Context, source, target *schwift.Container, opts *Options) (Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := newReplicator(source.Account(), target.Account(), opts)
+	return r.replicateContainer(ctx, source, target)
+}
+
+// Account replicates all containers of the source account into containers of
+// the same name in the target account. See Container() for details on how
+// each container is replicated. Containers are replicated one after the
+// other; opts.Concurrency applies to the objects within each container.
+//
+// The checkpoint (if any) records progress separately for each container.
+func Account(ctx context.Context, source, target *schwift.Account, opts *Options) (Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	r := newReplicator(source, target, opts)
+
+	var total Result
+	err := source.Containers().Foreach(ctx, func(c *schwift.Container) error {
+		result, err := r.replicateContainer(ctx, c, target.Container(c.Name()))
+		total.add(result)
+		return err
+	})
+	return total, err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// type replicator
+
+type replicator struct {
+	source *schwift.Account
+	target *schwift.Account
+	opts   Options
+
+	// names of containers in the target account that are known to exist
+	containersMutex sync.Mutex
+	containers      map[string]bool
+}
+
+func newReplicator(source, target *schwift.Account, opts *Options) *replicator {
+	return &replicator{
+		source:     source,
+		target:     target,
+		opts:       *opts,
+		containers: make(map[string]bool),
+	}
+}
+
+// ensureContainer creates the target container if necessary, using the
+// metadata of the source container.
+func (r *replicator) ensureContainer(ctx context.Context, source, target *schwift.Container) error {
+	r.containersMutex.Lock()
+	defer r.containersMutex.Unlock()
+	if r.containers[target.Name()] {
+		return nil
+	}
+
+	srcHeaders, err := source.Headers(ctx)
+	if err != nil {
+		return err
+	}
+	hdr := schwift.NewContainerHeaders()
+	for key, value := range srcHeaders.Headers {
+		if strings.HasPrefix(key, "X-Container-Meta-") {
+			hdr.Set(key, value)
+		}
+	}
+	_, err = target.CreateIfNotExists(ctx, hdr, nil)
+	if err != nil {
+		return err
+	}
+	r.containers[target.Name()] = true
+	return nil
+}
+
+func (r *replicator) replicateContainer(ctx context.Context, source, target *schwift.Container) (Result, error) {
+	err := r.ensureContainer(ctx, source, target)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var startAfter string
+	if r.opts.Checkpoint != nil {
+		startAfter, err = r.opts.Checkpoint.Load(source.Name())
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the listing feeds jobs to the workers, which report back on the results
+	// channel; the checkpoint is only advanced over a contiguous range of
+	// finished jobs, so that it never skips an object that failed or that has
+	// not been finished yet
+	type job struct {
+		index  int
+		source *schwift.Object
+	}
+	type jobResult struct {
+		index  int
+		name   string
+		result Result
+		err    error
+	}
+	jobs := make(chan job)
+	results := make(chan jobResult)
+
+	var wg sync.WaitGroup
+	for range max(r.opts.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result, err := r.replicateObject(ctx, j.source, target.Object(j.source.Name()))
+				results <- jobResult{j.index, j.source.Name(), result, err}
+			}
+		}()
+	}
+
+	var (
+		listErr        error
+		objectsSkipped uint64
+	)
+	go func() {
+		defer close(jobs)
+		iter := source.Objects()
+		iter.Prefix = r.opts.Prefix
+		index := 0
+		listErr = iter.Foreach(ctx, func(o *schwift.Object) error {
+			if o.Name() <= startAfter {
+				objectsSkipped++
+				return nil
+			}
+			select {
+			case jobs <- job{index, o}:
+				index++
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		total     Result
+		firstErr  error
+		finished  = make(map[int]string)
+		nextIndex = 0
+	)
+	for res := range results {
+		total.add(res.result)
+		if res.err != nil {
+			if firstErr == nil {
+				firstErr = res.err
+				cancel()
+			}
+			continue
+		}
+		if r.opts.Checkpoint == nil || firstErr != nil {
+			continue
+		}
+
+		finished[res.index] = res.name
+		marker := ""
+		for {
+			name, ok := finished[nextIndex]
+			if !ok {
+				break
+			}
+			delete(finished, nextIndex)
+			marker = name
+			nextIndex++
+		}
+		if marker != "" {
+			err := r.opts.Checkpoint.Save(source.Name(), marker)
+			if err != nil {
+				firstErr = err
+				cancel()
+			}
+		}
+	}
+
+	total.ObjectsSkipped += objectsSkipped
+	if firstErr == nil && listErr != nil {
+		firstErr = listErr
+	}
+	return total, firstErr
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// replication of individual objects
+
+// these headers are carried over from source objects to target objects, in
+// addition to all X-Object-Meta-* headers
+var replicatedObjectHeaders = []string{
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Type",
+	"X-Delete-At",
+}
+
+func (r *replicator) replicateObject(ctx context.Context, source, target *schwift.Object) (Result, error) {
+	srcHeaders, srcSymlinkTarget, err := source.SymlinkHeaders(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if !r.opts.Overwrite {
+		upToDate, err := isUpToDate(ctx, srcHeaders, target)
+		if err != nil {
+			return Result{}, err
+		}
+		if upToDate {
+			return Result{ObjectsSkipped: 1}, nil
+		}
+	}
+
+	hdr := schwift.NewObjectHeaders()
+	for key, value := range srcHeaders.Headers {
+		if strings.HasPrefix(key, "X-Object-Meta-") {
+			hdr.Set(key, value)
+		}
+	}
+	for _, key := range replicatedObjectHeaders {
+		if value := srcHeaders.Get(key); value != "" {
+			hdr.Set(key, value)
+		}
+	}
+
+	switch {
+	case srcSymlinkTarget != nil:
+		err := target.SymlinkTo(ctx, r.mapObject(srcSymlinkTarget), nil, hdr.ToOpts())
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{ObjectsCopied: 1}, nil
+	case srcHeaders.IsLargeObject():
+		return r.replicateLargeObject(ctx, source, target, hdr)
+	default:
+		return r.replicatePlainObject(ctx, source, target, srcHeaders, hdr)
+	}
+}
+
+// isUpToDate checks whether the target object already has the same content as
+// the source object.
+func isUpToDate(ctx context.Context, srcHeaders schwift.ObjectHeaders, target *schwift.Object) (bool, error) {
+	tgtHeaders, _, err := target.SymlinkHeaders(ctx)
+	if err != nil {
+		if schwift.Is(err, http.StatusNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return srcHeaders.Etag().Get() == tgtHeaders.Etag().Get() &&
+		srcHeaders.SizeBytes().Get() == tgtHeaders.SizeBytes().Get() &&
+		srcHeaders.Get("X-Symlink-Target") == tgtHeaders.Get("X-Symlink-Target"), nil
+}
+
+// mapObject returns the object in the target account that corresponds to the
+// given object. Objects outside the source account are not mapped.
+func (r *replicator) mapObject(o *schwift.Object) *schwift.Object {
+	if !o.Container().Account().IsEqualTo(r.source) {
+		return o
+	}
+	return r.target.Container(o.Container().Name()).Object(o.Name())
+}
+
+func (r *replicator) replicatePlainObject(ctx context.Context, source, target *schwift.Object, srcHeaders, hdr schwift.ObjectHeaders) (Result, error) {
+	// let Swift verify the integrity of the streamed content
+	hdr.Etag().Set(srcHeaders.Etag().Get())
+	hdr.SizeBytes().Set(srcHeaders.SizeBytes().Get())
+
+	reader, err := source.Download(ctx, nil).AsReadCloser()
+	if err != nil {
+		return Result{}, err
+	}
+	defer reader.Close()
+
+	err = target.Upload(ctx, reader, nil, hdr.ToOpts())
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{ObjectsCopied: 1, BytesCopied: srcHeaders.SizeBytes().Get()}, nil
+}
+
+func (r *replicator) replicateLargeObject(ctx context.Context, source, target *schwift.Object, hdr schwift.ObjectHeaders) (Result, error) {
+	srcLO, err := source.AsLargeObject(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	tgtLO, err := target.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+		Strategy:         srcLO.Strategy(),
+		SegmentContainer: r.target.Container(srcLO.SegmentContainer().Name()),
+		SegmentPrefix:    srcLO.SegmentPrefix(),
+	}, nil)
+	if err != nil {
+		return Result{}, err
+	}
+
+	segments, err := srcLO.Segments()
+	if err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	for _, segment := range segments {
+		if segment.Object != nil {
+			if !segment.Object.Container().Account().IsEqualTo(r.source) {
+				return result, schwift.ErrAccountMismatch
+			}
+			tgtSegment := r.mapObject(segment.Object)
+			err := r.ensureContainer(ctx, segment.Object.Container(), tgtSegment.Container())
+			if err != nil {
+				return result, err
+			}
+			segmentResult, err := r.replicateObject(ctx, segment.Object, tgtSegment)
+			result.BytesCopied += segmentResult.BytesCopied
+			if err != nil {
+				return result, err
+			}
+			segment.Object = tgtSegment
+		}
+		err := tgtLO.AddSegment(segment)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	err = tgtLO.WriteManifest(ctx, hdr.ToOpts())
+	if err != nil {
+		return result, err
+	}
+	result.ObjectsCopied++
+	return result, nil
+}
@@ -20,10 +20,12 @@ package schwift
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // RequestOptions is used to pass additional headers and values to a request.
@@ -40,6 +42,71 @@ import (
 type RequestOptions struct {
 	Headers Headers
 	Values  url.Values
+	// If not empty, RequestID is sent in the X-Openstack-Request-Id header, and
+	// also in the X-Trans-Id-Extra header (which Swift appends to the
+	// transaction ID that appears in its logs). This can be used to correlate
+	// client-side logs with server-side logs.
+	RequestID string
+	// If not empty, IdempotencyKey is recorded in the metadata of objects
+	// created by PUT requests (in the X-Object-Meta-Idempotency-Key header). When
+	// retrying an upload after an ambiguous failure (e.g. a timeout), the
+	// application can compare ObjectHeaders.IdempotencyKey() to find out whether
+	// the previous attempt has succeeded after all.
+	IdempotencyKey string
+	// If not zero, Timeout limits the duration of each individual request that
+	// is made with these options. Like http.Client.Timeout, this includes
+	// reading the response body, so when a method returns a response body to
+	// the caller (e.g. Object.Download()), the body must be read completely
+	// before the timeout expires. For operations that consist of multiple
+	// requests (e.g. LargeObject.Append()), the timeout applies separately to
+	// each request; use the context.Context argument to limit the duration of
+	// the entire operation.
+	Timeout time.Duration
+	// If not zero, IdleTimeout aborts each individual request that is made with
+	// these options when no data has been received for this long, either while
+	// waiting for the response or while reading the response body. Unlike
+	// Timeout, this does not limit the total duration of the request, so it is
+	// suitable for protecting long downloads against stalled connections. When
+	// the idle timeout expires, the request fails with an IdleTimeoutError.
+	IdleTimeout time.Duration
+	// If Newest is true, GET and HEAD requests include the header
+	// "X-Newest: true". See documentation on Account.WithNewest() for details.
+	Newest bool
+	// If OverrideHold is true, the request is not checked against object holds
+	// on an Account obtained from Account.WithHoldEnforcement(). See
+	// documentation over there for details.
+	OverrideHold bool
+}
+
+// WithHeaders returns a copy of this RequestOptions instance with the given
+// headers added to it. Headers that already exist are overwritten. It is safe
+// to call this method on a nil receiver, so that request options can be built
+// in a single expression, e.g. starting from the headers for an update:
+//
+//	hdr := NewObjectHeaders()
+//	hdr.Metadata().Set("color", "blue")
+//	opts := hdr.ToOpts().WithValue("multipart-manifest", "get")
+//
+// The receiver is not modified, so a RequestOptions instance can be shared
+// between multiple requests and extended as needed for each.
+func (o *RequestOptions) WithHeaders(headers Headers) *RequestOptions {
+	return cloneRequestOptions(o, headers)
+}
+
+// WithHeader is like WithHeaders, but adds a single header.
+func (o *RequestOptions) WithHeader(key, value string) *RequestOptions {
+	result := cloneRequestOptions(o, nil)
+	result.Headers.Set(key, value)
+	return result
+}
+
+// WithValue returns a copy of this RequestOptions instance with the given URL
+// query parameter set. Like WithHeaders, this method can be called on a nil
+// receiver and does not modify the receiver.
+func (o *RequestOptions) WithValue(key, value string) *RequestOptions {
+	result := cloneRequestOptions(o, nil)
+	result.Values.Set(key, value)
+	return result
 }
 
 func cloneRequestOptions(orig *RequestOptions, additional Headers) *RequestOptions {
@@ -48,6 +115,12 @@ func cloneRequestOptions(orig *RequestOptions, additional Headers) *RequestOptio
 		Values:  make(url.Values),
 	}
 	if orig != nil {
+		result.RequestID = orig.RequestID
+		result.IdempotencyKey = orig.IdempotencyKey
+		result.Timeout = orig.Timeout
+		result.IdleTimeout = orig.IdleTimeout
+		result.Newest = orig.Newest
+		result.OverrideHold = orig.OverrideHold
 		for k, v := range orig.Headers {
 			result.Headers[k] = v
 		}
@@ -62,8 +135,23 @@ func cloneRequestOptions(orig *RequestOptions, additional Headers) *RequestOptio
 }
 
 // Request contains the parameters that can be set in a request to the Swift API.
+//
+// Schwift uses this type internally to implement all its methods, but it is
+// also a public extension point for calling Swift APIs that Schwift does not
+// model (yet), e.g. those provided by custom middlewares. The fields and the
+// behavior of Do() are covered by the same compatibility guarantees as the
+// rest of the public API. To make a request on an existing Account,
+// Container or Object instance, start from its NewRequest() method:
+//
+//	resp, err := obj.NewRequest(http.MethodGet, nil).
+//		WithValue("my-middleware", "true").
+//		Do(ctx, obj.Container().Account().Backend())
+//
+// Unlike the methods on Account, Container and Object, requests executed this
+// way do not affect any cached headers. If the request modifies the target,
+// call Invalidate() on the respective instance afterwards.
 type Request struct {
-	Method        string // "GET", "HEAD", "PUT", "POST" or "DELETE"
+	Method        string // any HTTP method, e.g. "GET" or "COPY"
 	ContainerName string // empty for requests on accounts
 	ObjectName    string // empty for requests on accounts/containers
 	Options       *RequestOptions
@@ -76,6 +164,33 @@ type Request struct {
 	DrainResponseBody bool
 }
 
+// WithValue returns a copy of this request with the given URL query parameter
+// set in its Options. The original request's Options are not modified.
+func (r Request) WithValue(key, value string) Request {
+	r.Options = r.Options.WithValue(key, value)
+	return r
+}
+
+// WithHeader returns a copy of this request with the given header set in its
+// Options. The original request's Options are not modified.
+func (r Request) WithHeader(key, value string) Request {
+	r.Options = r.Options.WithHeader(key, value)
+	return r
+}
+
+// WithExpectedStatusCodes returns a copy of this request that expects the
+// given status codes. See documentation on field ExpectStatusCodes.
+func (r Request) WithExpectedStatusCodes(codes ...int) Request {
+	r.ExpectStatusCodes = codes
+	return r
+}
+
+// WithBody returns a copy of this request with the given request body.
+func (r Request) WithBody(body io.Reader) Request {
+	r.Body = body
+	return r
+}
+
 // URL returns the full URL for this request.
 func (r Request) URL(backend Backend, values url.Values) (string, error) {
 	uri, err := url.Parse(backend.EndpointURL())
@@ -115,13 +230,82 @@ func (r Request) Do(ctx context.Context, backend Backend) (*http.Response, error
 		return nil, err
 	}
 
+	if r.Options == nil || (r.Options.Timeout <= 0 && r.Options.IdleTimeout <= 0) {
+		resp, err := r.do(ctx, backend, uri)
+		if err != nil {
+			return nil, err
+		}
+		return r.checkResponse(resp)
+	}
+
+	idleErr := IdleTimeoutError{r.Method, describeTarget(r.ContainerName, r.ObjectName), r.Options.IdleTimeout}
+	resp, err := doWithTimeouts(ctx, r.Options.Timeout, idleErr, func(ctx context.Context) (*http.Response, error) {
+		return r.do(ctx, backend, uri)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.checkResponse(resp)
+}
+
+// doWithTimeouts calls `do` with a context that expires after `timeout`, or
+// when no data has been received for `idleErr.IdleTimeout`. Either of these
+// may be zero to disable the respective timeout. The context will be canceled
+// once the response body is closed, or immediately if an error is returned.
+func doWithTimeouts(ctx context.Context, timeout time.Duration, idleErr IdleTimeoutError, do func(context.Context) (*http.Response, error)) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	release := func() { cancel(nil) }
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		release = func() {
+			cancelTimeout()
+			cancel(nil)
+		}
+	}
+	var idleTimer *time.Timer
+	if idleErr.IdleTimeout > 0 {
+		idleTimer = time.AfterFunc(idleErr.IdleTimeout, func() { cancel(idleErr) })
+	}
+
+	resp, err := do(ctx)
+	if err != nil {
+		if idleTimer != nil {
+			idleTimer.Stop()
+		}
+		release()
+		return nil, idleTimeoutCause(ctx, err)
+	}
+	if idleTimer != nil {
+		idleTimer.Reset(idleErr.IdleTimeout)
+		resp.Body = idleTimeoutBody{resp.Body, ctx, idleTimer, idleErr.IdleTimeout}
+	}
+	resp.Body = cancelOnClose{resp.Body, release}
+	return resp, nil
+}
+
+func (r Request) do(ctx context.Context, backend Backend, uri string) (*http.Response, error) {
 	// build request
+	if r.Options != nil && r.Options.OverrideHold {
+		ctx = context.WithValue(ctx, overrideHoldKey{}, true)
+	}
 	req, err := http.NewRequestWithContext(ctx, r.Method, uri, r.Body)
 	if err != nil {
 		return nil, err
 	}
 
 	if r.Options != nil {
+		if r.Options.RequestID != "" {
+			req.Header.Set("X-Openstack-Request-Id", r.Options.RequestID)
+			req.Header.Set("X-Trans-Id-Extra", r.Options.RequestID)
+		}
+		if r.Options.Newest && isReadMethod(r.Method) {
+			req.Header.Set("X-Newest", "true")
+		}
+		if r.Options.IdempotencyKey != "" && r.Method == http.MethodPut && r.ObjectName != "" {
+			req.Header.Set("X-Object-Meta-Idempotency-Key", r.Options.IdempotencyKey)
+		}
+		// explicitly given headers take precedence over the ones generated above
 		for k, v := range r.Options.Headers {
 			req.Header[k] = []string{v}
 		}
@@ -130,11 +314,10 @@ func (r Request) Do(ctx context.Context, backend Backend) (*http.Response, error
 		req.Header.Set("Expect", "100-continue")
 	}
 
-	resp, err := backend.Do(req)
-	if err != nil {
-		return nil, err
-	}
+	return backend.Do(req)
+}
 
+func (r Request) checkResponse(resp *http.Response) (*http.Response, error) {
 	// return success if error code matches expectation
 	if len(r.ExpectStatusCodes) == 0 {
 		// check disabled -> return response unaltered
@@ -176,6 +359,54 @@ func describeTarget(containerName, objectName string) string {
 	}
 }
 
+// cancelOnClose wraps a response body to release the resources of its
+// request's context when the body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// idleTimeoutBody wraps a response body to restart the idle timer whenever
+// data is received.
+type idleTimeoutBody struct {
+	io.ReadCloser
+	ctx     context.Context //nolint:containedctx // only used to report the cancellation cause
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (b idleTimeoutBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = idleTimeoutCause(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.ReadCloser.Close()
+}
+
+// idleTimeoutCause replaces an error caused by the expiry of an idle timeout
+// with the respective IdleTimeoutError.
+func idleTimeoutCause(ctx context.Context, err error) error {
+	var idleErr IdleTimeoutError
+	if errors.As(context.Cause(ctx), &idleErr) {
+		return idleErr
+	}
+	return err
+}
+
 func drainResponseBody(r *http.Response) error {
 	_, err := io.Copy(io.Discard, r.Body)
 	if err != nil {
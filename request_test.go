@@ -0,0 +1,130 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestOptionsBuilders(t *testing.T) {
+	// builders must work on nil receivers
+	var opts *RequestOptions
+	opts = opts.WithHeader("content-type", "text/plain").WithValue("symlink", "get")
+	expectString(t, "text/plain", opts.Headers.Get("Content-Type"))
+	expectString(t, "get", opts.Values.Get("symlink"))
+
+	// builders must not modify the receiver
+	opts.RequestID = "foo"
+	extended := opts.WithHeaders(Headers{"X-Object-Meta-Color": "blue"}).WithValue("symlink", "")
+	expectString(t, "", opts.Headers.Get("X-Object-Meta-Color"))
+	expectString(t, "get", opts.Values.Get("symlink"))
+	expectString(t, "blue", extended.Headers.Get("X-Object-Meta-Color"))
+	expectString(t, "text/plain", extended.Headers.Get("Content-Type"))
+	expectString(t, "", extended.Values.Get("symlink"))
+	expectString(t, "foo", extended.RequestID)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	// a request that does not complete in time fails
+	b := &testBackend{handle: hangUntilCanceled}
+	_, err := Request{
+		Method:        http.MethodHead,
+		ContainerName: "foo",
+		Options:       &RequestOptions{Timeout: 10 * time.Millisecond},
+	}.Do(context.Background(), b)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// for a request that completes in time, the context remains alive until the
+	// response body is closed
+	b = &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	resp, err := Request{
+		Method:            http.MethodGet,
+		ContainerName:     "foo",
+		Options:           &RequestOptions{Timeout: time.Minute},
+		ExpectStatusCodes: []int{http.StatusOK},
+	}.Do(context.Background(), b)
+	must(t, err)
+	must(t, b.lastReq.Context().Err())
+	buf, err := io.ReadAll(resp.Body)
+	must(t, err)
+	expectString(t, "hello", string(buf))
+	must(t, resp.Body.Close())
+	if b.lastReq.Context().Err() == nil {
+		t.Error("expected request context to be canceled after closing the response body")
+	}
+}
+
+func TestNewest(t *testing.T) {
+	b := &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	a, err := InitializeAccount(b)
+	must(t, err)
+	obj := a.Container("foo").Object("bar")
+
+	// per-request option
+	_, err = obj.Download(context.Background(), &RequestOptions{Newest: true}).AsByteSlice()
+	must(t, err)
+	expectString(t, "true", b.lastReq.Header.Get("X-Newest"))
+	_, err = obj.Download(context.Background(), nil).AsByteSlice()
+	must(t, err)
+	expectString(t, "", b.lastReq.Header.Get("X-Newest"))
+
+	// per-account option applies only to reads
+	obj = a.WithNewest().Container("foo").Object("bar")
+	_, err = obj.Download(context.Background(), nil).AsByteSlice()
+	must(t, err)
+	expectString(t, "true", b.lastReq.Header.Get("X-Newest"))
+	resp, err := obj.NewRequest(http.MethodPut, nil).Do(context.Background(), obj.Container().Account().Backend())
+	must(t, err)
+	must(t, resp.Body.Close())
+	expectString(t, "", b.lastReq.Header.Get("X-Newest"))
+}
+
+func TestReadOnly(t *testing.T) {
+	b := &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	a, err := InitializeAccount(b)
+	must(t, err)
+	obj := a.ReadOnly().Container("foo").Object("bar")
+
+	// reads go through
+	_, err = obj.Download(context.Background(), nil).AsByteSlice()
+	must(t, err)
+	expectString(t, http.MethodGet, b.lastReq.Method)
+
+	// writes are rejected without sending a request
+	b.lastReq = nil
+	err = obj.Upload(context.Background(), strings.NewReader("hello"), nil, nil)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	err = obj.Container().Delete(context.Background(), nil)
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if b.lastReq != nil {
+		t.Errorf("expected no request to be sent, but got %s %s", b.lastReq.Method, b.lastReq.URL)
+	}
+}
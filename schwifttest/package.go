@@ -0,0 +1,252 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package schwifttest contains helpers for writing tests against Swift with
+Schwift. It provides the same helpers that Schwift uses for its own tests, so
+that downstream projects do not need to copy them. For example:
+
+	func TestArchive(t *testing.T) {
+		a, err := testaccount.Connect(context.Background())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		c := schwifttest.TemporaryContainer(t, a)
+
+		obj := c.Object("hello.txt")
+		err = archive.Store(obj, "hello")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		schwifttest.ExpectObjectContent(t, obj, []byte("hello"))
+	}
+
+The helpers in this package work with any Account. To obtain an Account for
+a test, use package schwifttest/testaccount, which can connect to a real Swift
+(when credentials are given in the environment) or fall back to an in-memory
+fake. It lives in a separate package so that importing schwifttest does not
+pull Gophercloud and the fake into the test binary.
+
+All assertion functions report failures with t.Errorf(), so the test
+continues after a failed assertion.
+*/
+package schwifttest
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec // Etag uses md5
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// setup
+
+// TemporaryContainer creates a container with a random name in the given
+// account. When the test completes, the container is deleted including all
+// objects in it.
+func TemporaryContainer(t testing.TB, a *schwift.Account) *schwift.Container {
+	t.Helper()
+	ctx := context.Background()
+	c, err := a.Container(RandomName()).EnsureExists(ctx)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	t.Cleanup(func() {
+		exists, err := c.Exists(ctx)
+		if err != nil {
+			t.Error(err.Error())
+			return
+		}
+		if !exists {
+			return
+		}
+		err = c.Objects().Foreach(ctx, func(o *schwift.Object) error {
+			return o.Delete(ctx, nil, nil)
+		})
+		if err == nil {
+			err = c.Delete(ctx, nil)
+		}
+		if err != nil {
+			t.Error(err.Error())
+		}
+	})
+	return c
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// generators
+
+// RandomName returns a random string of 32 hex digits, which is suitable as a
+// container or object name.
+func RandomName() string {
+	var buf [16]byte
+	_, err := rand.Read(buf[:])
+	if err != nil {
+		panic(err.Error())
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// RandomContent returns a random string of the given length (consisting of hex
+// digits), which is suitable as object content. The length should be even.
+func RandomContent(length int) string {
+	buf := make([]byte, length/2)
+	_, err := rand.Read(buf)
+	if err != nil {
+		panic(err.Error())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// Etag returns the Etag that Swift computes for an object with the given
+// content.
+func Etag(buf []byte) string {
+	hash := md5.Sum(buf) //nolint:gosec // Etag uses md5
+	return hex.EncodeToString(hash[:])
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// assertions
+
+// ExpectObjectExistence checks whether the given object exists. The object's
+// header cache is cleared beforehand.
+func ExpectObjectExistence(t testing.TB, obj *schwift.Object, expectedExists bool) {
+	t.Helper()
+	obj.Invalidate()
+	actualExists, err := obj.Exists(context.Background())
+	if expectSuccess(t, err) && actualExists != expectedExists {
+		t.Errorf("expected value %#v, got %#v instead\n", expectedExists, actualExists)
+	}
+}
+
+// ExpectObjectContent downloads the given object and checks its content. For
+// objects that are not large objects, the Etag is checked as well.
+func ExpectObjectContent(t testing.TB, obj *schwift.Object, expected []byte) {
+	t.Helper()
+	str, err := obj.Download(context.Background(), nil).AsString()
+	if expectSuccess(t, err) {
+		expectString(t, str, string(expected))
+	}
+	obj.Invalidate()
+	hdr, err := obj.Headers(context.Background())
+	if expectSuccess(t, err) && !hdr.IsLargeObject() {
+		expectString(t, hdr.Etag().Get(), Etag(expected))
+	}
+}
+
+// ExpectHeaders compares two sets of headers, e.g. the Headers field of
+// schwift.ObjectHeaders with a set of expected headers. Every difference is
+// reported separately.
+func ExpectHeaders(t testing.TB, actual, expected map[string]string) {
+	t.Helper()
+	reported := make(map[string]bool)
+
+	for k, av := range actual {
+		ev, exists := expected[k]
+		if !exists {
+			ev = "<not set>"
+		}
+		if av != ev {
+			t.Errorf(`expected "%s: %s", got "%s: %s" instead`, k, ev, k, av)
+			reported[k] = true
+		}
+	}
+
+	for k, ev := range expected {
+		av, exists := actual[k]
+		if !exists {
+			av = "<not set>"
+		}
+		if av != ev && !reported[k] {
+			t.Errorf(`expected "%s: %s", got "%s: %s" instead`, k, ev, k, av)
+		}
+	}
+}
+
+// ExpectLargeObject checks that the given object is a large object with the
+// given segments. For segments backed by objects, the SizeBytes and Etag
+// attributes are only checked if they are set in the expected SegmentInfo.
+func ExpectLargeObject(t testing.TB, obj *schwift.Object, expected []schwift.SegmentInfo) {
+	t.Helper()
+	ExpectObjectExistence(t, obj, true)
+	lo, err := obj.AsLargeObject(context.Background())
+	if !expectSuccess(t, err) {
+		return
+	}
+
+	actual, err := lo.Segments()
+	if !expectSuccess(t, err) {
+		return
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("expected %s to have %d segments, got %d segments",
+			obj.FullName(), len(expected), len(actual))
+		return
+	}
+
+	for idx, as := range actual {
+		es := expected[idx]
+		if len(es.Data) > 0 {
+			// expecting data segment
+			if string(es.Data) != string(as.Data) {
+				t.Errorf("expected segments[%d].Data == %q, got %q",
+					idx, string(es.Data), string(as.Data))
+			}
+		} else {
+			// expecting segment backed by object
+			if as.Object == nil {
+				t.Errorf("expected segments[%d] to be backed by %q, got data segment",
+					idx, es.Object.FullName())
+				continue
+			}
+			if as.Object.FullName() != es.Object.FullName() {
+				t.Errorf("expected segments[%d].Object.FullName() == %q, got %q",
+					idx, es.Object.FullName(), as.Object.FullName())
+			}
+			if es.SizeBytes != 0 && as.SizeBytes != es.SizeBytes {
+				t.Errorf("expected segments[%d].SizeBytes == %d, got %d",
+					idx, es.SizeBytes, as.SizeBytes)
+			}
+			if es.Etag != "" && as.Etag != es.Etag {
+				t.Errorf("expected segments[%d].Etag == %q, got %q",
+					idx, es.Etag, as.Etag)
+			}
+		}
+	}
+}
+
+func expectSuccess(t testing.TB, actual error) bool {
+	t.Helper()
+	if actual != nil {
+		t.Errorf("expected success, got error %q instead\n", actual.Error())
+		return false
+	}
+	return true
+}
+
+func expectString(t testing.TB, actual, expected string) {
+	t.Helper()
+	if actual != expected {
+		t.Errorf("expected value %q, got %q instead\n", expected, actual)
+	}
+}
@@ -0,0 +1,68 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package testaccount provides an Account for tests that use package
+schwifttest. For example:
+
+	a, err := testaccount.Connect(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	c := schwifttest.TemporaryContainer(t, a)
+
+Tests can run against a real Swift (when credentials are given in the
+environment) or against an in-memory fake.
+
+The fake is not a stable API: It only implements those parts of the Swift API
+that Schwift uses, and its behavior may change in any release of Schwift
+(including patch releases) to follow Schwift's own testing needs. When in
+doubt, test against a real Swift.
+*/
+package testaccount
+
+import (
+	"context"
+	"os"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/config"
+	"github.com/majewsky/schwift/v2/internal/fakeswift"
+)
+
+// Connect returns an account to run tests against. If Swift or Keystone
+// credentials are given in the environment (i.e. if any of ST_AUTH, ST_USER,
+// ST_KEY, OS_AUTH_URL or OS_CLOUD is set), it connects to that Swift using
+// config.FromEnv().Connect(). Otherwise, an in-memory fake Swift is used (see
+// NewFake).
+func Connect(ctx context.Context) (*schwift.Account, error) {
+	for _, key := range []string{"ST_AUTH", "ST_USER", "ST_KEY", "OS_AUTH_URL", "OS_CLOUD"} {
+		if os.Getenv(key) != "" {
+			return config.FromEnv().Connect(ctx)
+		}
+	}
+	return NewFake(), nil
+}
+
+// NewFake returns an account in a fresh in-memory fake Swift cluster. No
+// requests leave the process. Each call returns an account in a separate
+// cluster, so tests using different fake accounts do not see each other's
+// containers. See the package documentation for the limitations of the fake.
+func NewFake() *schwift.Account {
+	return fakeswift.NewAccount()
+}
@@ -0,0 +1,46 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package testaccount
+
+import (
+	"context"
+	"testing"
+
+	"github.com/majewsky/schwift/v2/schwifttest"
+)
+
+func TestConnect(t *testing.T) {
+	// without credentials, the fake is used
+	for _, key := range []string{"ST_AUTH", "ST_USER", "ST_KEY", "OS_AUTH_URL", "OS_CLOUD"} {
+		t.Setenv(key, "")
+	}
+	a, err := Connect(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	schwifttest.TemporaryContainer(t, a)
+
+	// incomplete credentials are rejected instead of being used
+	t.Setenv("ST_USER", "test:tester")
+	_, err = Connect(context.Background())
+	expected := "incomplete Swift v1 credentials (need all of ST_AUTH, ST_USER and ST_KEY)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,85 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import "context"
+
+// SegmentExpiryOptions contains options for LargeObject.AlignSegmentExpiry().
+type SegmentExpiryOptions struct {
+	// By default, only segments located in SegmentContainer() below
+	// SegmentPrefix() are updated, since segments outside of this location may
+	// be shared with other large objects. If this is true, all segments are
+	// updated.
+	IncludeForeignSegments bool
+	// If not nil, these options are used for each POST request.
+	RequestOptions *RequestOptions
+}
+
+// AlignSegmentExpiry sets the X-Delete-At header of each segment object to
+// the value of the X-Delete-At header of the manifest. Otherwise, when the
+// manifest expires, its segments remain in place forever. If the manifest does
+// not have an X-Delete-At header, the header is removed from the segments
+// instead. Segments that already have the correct X-Delete-At are not updated.
+//
+// This is useful for large objects whose expiry was set or changed after the
+// segments were uploaded. To set the expiry while uploading, give the same
+// X-Delete-At header to Append() and WriteManifest(), or use
+// AppendOptions.SegmentsExpireWithManifest with Object.Append().
+//
+// The existing metadata of the segments is retained (see
+// Object.UpdatePreservingMetadata). Returns the number of segment objects that
+// were updated. When an update fails, the remaining segments are not updated.
+func (lo *LargeObject) AlignSegmentExpiry(ctx context.Context, opts *SegmentExpiryOptions) (int, error) {
+	if opts == nil {
+		opts = &SegmentExpiryOptions{}
+	}
+	err := lo.LoadSegments(ctx)
+	if err != nil {
+		return 0, err
+	}
+	hdr, err := lo.object.Headers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	expiresAt := hdr.Get("X-Delete-At")
+
+	updated := 0
+	for _, segment := range lo.SegmentObjects() {
+		if !opts.IncludeForeignSegments && !lo.isOwnSegment(segment) {
+			continue
+		}
+		segmentHdr, err := segment.Headers(ctx)
+		if err != nil {
+			return updated, err
+		}
+		if segmentHdr.Get("X-Delete-At") == expiresAt {
+			continue
+		}
+
+		// an empty value causes the header to be removed
+		change := NewObjectHeaders()
+		change.Set("X-Delete-At", expiresAt)
+		err = segment.UpdatePreservingMetadata(ctx, change, opts.RequestOptions)
+		if err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
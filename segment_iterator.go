@@ -0,0 +1,176 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"strings"
+)
+
+// SegmentIterator iterates over the segments of a large object, in order.
+// Instances are usually obtained with Object.Segments(). Unlike
+// Object.AsLargeObject(), which holds all segments in memory, this is suitable
+// for dynamic large objects with very large numbers of segments:
+//
+//	iter := obj.Segments()
+//	for {
+//	    segments, err := iter.NextPage(ctx, 1000)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if len(segments) == 0 {
+//	        break // EOF
+//	    }
+//	    // process segments...
+//	}
+//
+// For dynamic large objects, the segments are listed from the segment
+// container page by page. For static large objects, the entire manifest is
+// downloaded on the first call. This is acceptable because Swift limits the
+// number of segments per manifest (see
+// Capabilities.StaticLargeObject.MaximumManifestSegments).
+//
+// If the object does not exist, or if it is not a large object, the first
+// call returns ErrNotLarge.
+type SegmentIterator struct {
+	Object *Object
+	// Options may contain additional headers and query parameters for the
+	// listing requests (for dynamic large objects only).
+	Options *RequestOptions
+
+	initialized bool
+	listing     *ObjectIterator // for DLO
+	remaining   []SegmentInfo   // for SLO
+}
+
+// Segments returns a SegmentIterator for the segments of this large object.
+// This function does not issue any HTTP requests.
+func (o *Object) Segments() *SegmentIterator {
+	return &SegmentIterator{Object: o}
+}
+
+func (i *SegmentIterator) init(ctx context.Context) error {
+	if i.initialized {
+		return nil
+	}
+	o := i.Object
+	exists, err := o.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotLarge
+	}
+
+	h := o.headers
+	switch {
+	case h.IsDynamicLargeObject():
+		manifest := strings.SplitN(h.Get("X-Object-Manifest"), "/", 2)
+		if len(manifest) < 2 {
+			return ErrNotLarge
+		}
+		i.listing = o.c.a.Container(manifest[0]).Objects()
+		i.listing.Prefix = manifest[1]
+		i.listing.Options = i.Options
+	case h.IsStaticLargeObject():
+		i.remaining, err = o.fetchSLOSegments(ctx)
+		if err != nil {
+			return err
+		}
+	default:
+		return ErrNotLarge
+	}
+	i.initialized = true
+	return nil
+}
+
+// NextPage returns the next page of segments. If limit is >= 0, not more than
+// that many segments will be returned at once. For dynamic large objects, the
+// server also has a limit for how many objects to list in one request; the
+// lower limit wins.
+//
+// The end of the segment list is reached when an empty list is returned.
+func (i *SegmentIterator) NextPage(ctx context.Context, limit int) ([]SegmentInfo, error) {
+	err := i.init(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.listing == nil {
+		count := len(i.remaining)
+		if limit >= 0 {
+			count = min(count, limit)
+		}
+		result := i.remaining[:count:count]
+		i.remaining = i.remaining[count:]
+		return result, nil
+	}
+
+	infos, err := i.listing.NextPageDetailed(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SegmentInfo, len(infos))
+	for idx, info := range infos {
+		result[idx] = dloSegmentInfo(info)
+	}
+	return result, nil
+}
+
+// Foreach calls the callback once for every segment. Iteration is aborted
+// when a request fails, or when the callback returns a non-nil error.
+func (i *SegmentIterator) Foreach(ctx context.Context, callback func(SegmentInfo) error) error {
+	for {
+		segments, err := i.NextPage(ctx, -1)
+		if err != nil {
+			return err
+		}
+		if len(segments) == 0 {
+			return nil // EOF
+		}
+		for _, s := range segments {
+			err := callback(s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Collect returns all remaining segments at once.
+func (i *SegmentIterator) Collect(ctx context.Context) ([]SegmentInfo, error) {
+	var result []SegmentInfo
+	err := i.Foreach(ctx, func(s SegmentInfo) error {
+		result = append(result, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// dloSegmentInfo converts an entry of a DLO's segment listing into a SegmentInfo.
+func dloSegmentInfo(info ObjectInfo) SegmentInfo {
+	return SegmentInfo{
+		Object:    info.Object,
+		SizeBytes: info.SizeBytes,
+		Etag:      info.Etag,
+	}
+}
@@ -0,0 +1,139 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"fmt"
+)
+
+// SegmentPolicy describes the constraints that a Swift cluster places on the
+// segments of static large objects. When a SegmentPolicy is attached to a
+// LargeObject with SetSegmentPolicy(), AddSegment() and Append() check
+// segments against it, and return descriptive errors instead of having the
+// manifest upload fail with an opaque 400 (Bad Request) later on.
+//
+// The policy for a particular cluster can be obtained from
+// Account.SegmentPolicy(). All fields may be zero to disable the respective
+// check or behavior.
+type SegmentPolicy struct {
+	// All segments except for the last one must be at least this large.
+	MinimumSegmentSize uint64
+	// The segment size used by Append() if none is given explicitly.
+	TargetSegmentSize uint64
+	// The maximum number of segments in a single manifest.
+	MaximumSegmentCount uint
+	// The maximum size of a manifest in bytes (after encoding as JSON).
+	MaximumManifestSize uint64
+	// When the last segment written by Append() is at most this many bytes
+	// long, it is stored in the manifest as a data segment instead of being
+	// uploaded as a separate segment object, as long as the manifest does not
+	// exceed MaximumManifestSize. This avoids cluttering the segment container
+	// with tiny objects. Note that, if the large object is appended to later on,
+	// this data segment is subject to MinimumSegmentSize like all other
+	// segments.
+	//
+	// This is a client-side preference, so Account.SegmentPolicy() leaves it at
+	// zero.
+	InlineTailSize uint64
+}
+
+// SegmentPolicy returns the SegmentPolicy for static large objects in this
+// account, as reported by Account.Capabilities(). The target segment size is
+// set to the maximum object size.
+//
+// If the server does not support static large objects, ErrNotSupported is
+// returned.
+func (a *Account) SegmentPolicy(ctx context.Context) (SegmentPolicy, error) {
+	caps, err := a.Capabilities(ctx)
+	if err != nil {
+		return SegmentPolicy{}, err
+	}
+	if caps.StaticLargeObject == nil {
+		return SegmentPolicy{}, ErrNotSupported
+	}
+	return SegmentPolicy{
+		MinimumSegmentSize:  uint64(caps.StaticLargeObject.MinimumSegmentSize),
+		TargetSegmentSize:   uint64(caps.Swift.MaximumFileSize),
+		MaximumSegmentCount: caps.StaticLargeObject.MaximumManifestSegments,
+		MaximumManifestSize: uint64(caps.StaticLargeObject.MaximumManifestSize),
+	}, nil
+}
+
+// SetSegmentPolicy attaches a SegmentPolicy to this large object. The policy
+// only applies to segments that are added afterwards, and is ignored for
+// dynamic large objects.
+func (lo *LargeObject) SetSegmentPolicy(policy SegmentPolicy) {
+	lo.policy = policy
+}
+
+// SegmentPolicy returns the SegmentPolicy that was attached to this large
+// object with SetSegmentPolicy(), or the zero value if none was attached.
+func (lo *LargeObject) SegmentPolicy() SegmentPolicy {
+	return lo.policy
+}
+
+// checkSegmentPolicy checks whether another segment can be added to this large
+// object without violating the policy. This is called by AddSegment(), but also
+// by Append() before uploading each segment.
+func (lo *LargeObject) checkSegmentPolicy() error {
+	if lo.strategy != StaticLargeObject {
+		return nil
+	}
+	p := lo.policy
+
+	if p.MaximumSegmentCount > 0 && uint(len(lo.segments)) >= p.MaximumSegmentCount {
+		return fmt.Errorf("%w: cannot add segment %d because this cluster allows at most %d segments per manifest",
+			ErrSegmentInvalid, len(lo.segments), p.MaximumSegmentCount)
+	}
+
+	// once a segment is added, the previous last segment is not the last one
+	// anymore, so the minimum size applies to it
+	if p.MinimumSegmentSize > 0 && len(lo.segments) > 0 {
+		idx := len(lo.segments) - 1
+		size, known := lo.segments[idx].effectiveSize()
+		if known && size < p.MinimumSegmentSize {
+			return fmt.Errorf("%w: segment %d is %d bytes long, but this cluster requires all segments except the last one to be at least %d bytes long",
+				ErrSegmentInvalid, idx, size, p.MinimumSegmentSize)
+		}
+	}
+
+	return nil
+}
+
+// effectiveSize returns how many bytes this segment contributes to the large
+// object, or false if that cannot be determined without asking the server.
+func (s SegmentInfo) effectiveSize() (uint64, bool) {
+	switch {
+	case len(s.Data) > 0:
+		return uint64(len(s.Data)), true
+	case s.RangeOffset < 0 && s.SizeBytes == 0:
+		return s.RangeLength, true // suffix range (might be shorter if the object is shorter)
+	case s.RangeOffset < 0:
+		return min(s.RangeLength, s.SizeBytes), true
+	case s.SizeBytes == 0:
+		return 0, false
+	case s.RangeOffset > 0 && uint64(s.RangeOffset) >= s.SizeBytes:
+		return 0, true
+	case s.RangeLength > 0:
+		return min(s.RangeLength, s.SizeBytes-uint64(max(s.RangeOffset, 0))), true
+	default:
+		return s.SizeBytes - uint64(max(s.RangeOffset, 0)), true
+	}
+}
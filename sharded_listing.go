@@ -0,0 +1,236 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// ShardStrategy splits an object listing into shards that can be listed
+// concurrently. It is used by the "Sharded" methods of ObjectIterator.
+type ShardStrategy interface {
+	// Shards returns the prefixes of the shards for a listing with the given
+	// prefix. Each returned prefix must start with the given prefix, and no
+	// returned prefix may start with another returned prefix. The prefixes
+	// must be sorted in ascending order, so that results from the shards can
+	// be concatenated in order. Objects that do not match any of the returned
+	// prefixes will not be listed.
+	Shards(prefix string) []string
+}
+
+// CharacterShards is a ShardStrategy that creates one shard for each
+// character in the string, by appending that character to the listing's
+// prefix. For example, when listing with Prefix = "logs/",
+// CharacterShards("abc") results in the shards "logs/a", "logs/b" and
+// "logs/c".
+//
+// This only lists objects whose name (after the prefix) starts with one of the
+// characters, so it is only suitable for containers with a known naming
+// scheme. See also HexShards.
+type CharacterShards string
+
+// HexShards is a ShardStrategy for containers where object names (after the
+// prefix) start with a lower-case hex digit, e.g. because they are derived
+// from checksums.
+const HexShards CharacterShards = "0123456789abcdef"
+
+// Shards implements the ShardStrategy interface.
+func (s CharacterShards) Shards(prefix string) []string {
+	var result []string
+	for _, r := range string(s) {
+		shard := prefix + string(r)
+		if !slices.Contains(result, shard) {
+			result = append(result, shard)
+		}
+	}
+	// sort in byte order, which is how Swift sorts object names
+	slices.SortFunc(result, strings.Compare)
+	return result
+}
+
+// ShardingOptions appears in the "Sharded" methods of ObjectIterator.
+type ShardingOptions struct {
+	// Strategy determines how the listing is split into shards. If nil, the
+	// listing is not split, and the "Sharded" methods behave like their
+	// non-sharded counterparts.
+	Strategy ShardStrategy
+	// Concurrency is the maximum number of shards that are listed at the same
+	// time. The default is 4.
+	Concurrency int
+}
+
+// ForeachSharded is like Foreach, but lists multiple shards of the listing
+// concurrently (see documentation on type ShardStrategy). This can reduce the
+// wall-clock time for listing very large containers considerably. The callback
+// is still called sequentially, and in the same order as with Foreach, as long
+// as the ShardStrategy returns its shards in ascending order.
+//
+// Unlike Foreach, this method does not use the iterator's paging state, so it
+// can be called multiple times on the same iterator. The iterator's Container
+// will not receive cached headers from the listing responses.
+func (i *ObjectIterator) ForeachSharded(ctx context.Context, opts *ShardingOptions, callback func(*Object) error) error {
+	return foreachSharded(ctx, i, opts, func(ctx context.Context, shard *ObjectIterator) ([]*Object, error) {
+		objects, err := shard.NextPage(ctx, -1)
+		for idx, o := range objects {
+			objects[idx] = i.Container.Object(o.name)
+		}
+		return objects, err
+	}, callback)
+}
+
+// ForeachDetailedSharded is like ForeachSharded, but includes basic metadata.
+func (i *ObjectIterator) ForeachDetailedSharded(ctx context.Context, opts *ShardingOptions, callback func(ObjectInfo) error) error {
+	return foreachSharded(ctx, i, opts, func(ctx context.Context, shard *ObjectIterator) ([]ObjectInfo, error) {
+		infos, err := shard.NextPageDetailed(ctx, -1)
+		for idx, info := range infos {
+			if info.Object != nil {
+				infos[idx].Object = i.Container.Object(info.Object.name)
+			}
+		}
+		return infos, err
+	}, callback)
+}
+
+// CollectSharded is like Collect, but lists multiple shards of the listing
+// concurrently. See documentation on ForeachSharded for details.
+func (i *ObjectIterator) CollectSharded(ctx context.Context, opts *ShardingOptions) ([]*Object, error) {
+	var result []*Object
+	err := i.ForeachSharded(ctx, opts, func(o *Object) error {
+		result = append(result, o)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CollectDetailedSharded is like CollectSharded, but includes basic metadata.
+func (i *ObjectIterator) CollectDetailedSharded(ctx context.Context, opts *ShardingOptions) ([]ObjectInfo, error) {
+	var result []ObjectInfo
+	err := i.ForeachDetailedSharded(ctx, opts, func(info ObjectInfo) error {
+		result = append(result, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// shardPage is sent from the listing goroutines to the consumer in foreachSharded().
+type shardPage[T any] struct {
+	items []T
+	err   error
+}
+
+func foreachSharded[T any](ctx context.Context, i *ObjectIterator, opts *ShardingOptions, nextPage func(context.Context, *ObjectIterator) ([]T, error), callback func(T) error) error {
+	if opts == nil {
+		opts = &ShardingOptions{}
+	}
+	shards := []string{i.Prefix}
+	if opts.Strategy != nil {
+		shards = opts.Strategy.Shards(i.Prefix)
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each shard is listed by its own goroutine into its own channel. The
+	// channels are consumed in order, so only the first unfinished shard is
+	// being consumed at any time; the other goroutines can only list a few
+	// pages ahead before blocking, which bounds the memory usage.
+	pages := make([]chan shardPage[T], len(shards))
+	for idx := range pages {
+		pages[idx] = make(chan shardPage[T], 4)
+	}
+	go func() {
+		semaphore := make(chan struct{}, concurrency)
+		for idx, prefix := range shards {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			shard := &ObjectIterator{
+				// use a separate Container instance since the listing responses are
+				// written into its header cache, which is not thread-safe
+				Container: i.Container.a.Container(i.Container.name),
+				Prefix:    prefix,
+				Delimiter: i.Delimiter,
+				Match:     i.Match,
+				Exclude:   i.Exclude,
+				Options:   i.Options,
+				Format:    i.Format,
+				Cache:     i.Cache,
+			}
+			go func(out chan<- shardPage[T]) {
+				defer func() { <-semaphore }()
+				defer close(out)
+				for {
+					items, err := nextPage(ctx, shard)
+					if len(items) == 0 && err == nil {
+						return // EOF
+					}
+					select {
+					case out <- shardPage[T]{items, err}:
+					case <-ctx.Done():
+						return
+					}
+					if err != nil {
+						return
+					}
+				}
+			}(pages[idx])
+		}
+	}()
+
+	for _, ch := range pages {
+		for {
+			var (
+				page shardPage[T]
+				ok   bool
+			)
+			select {
+			case page, ok = <-ch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if !ok {
+				break // shard is done
+			}
+			if page.err != nil {
+				return page.err
+			}
+			for _, item := range page.items {
+				err := callback(item)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
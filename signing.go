@@ -0,0 +1,133 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SignableRequest describes a request that is about to be sent to Swift
+// through an Account obtained from Account.WithRequestSigner(). It is passed
+// to RequestSigner.SignRequest().
+type SignableRequest struct {
+	Method string // e.g. http.MethodGet
+	// Path is the escaped URL path as it will be sent on the wire, e.g.
+	// "/v1/AUTH_example/foo/bar%20baz".
+	Path  string
+	Query url.Values
+	// Time is when the signature is computed. Expires is Time plus the validity
+	// given to Account.WithRequestSigner().
+	Time    time.Time
+	Expires time.Time
+	// Header refers to the headers of the actual request. The signer adds its
+	// signature headers here. It must not remove or change other headers.
+	Header http.Header
+}
+
+// CanonicalString returns the method, the expiry time (as a Unix timestamp)
+// and the path, separated by newlines. This is the same format that Swift uses
+// for the signatures in temp URLs, so gateways that reuse that format can be
+// served with:
+//
+//	mac := hmac.New(sha256.New, key)
+//	mac.Write([]byte(req.CanonicalString()))
+//	req.Header.Set("X-Gateway-Signature", hex.EncodeToString(mac.Sum(nil)))
+func (r SignableRequest) CanonicalString() string {
+	return fmt.Sprintf("%s\n%d\n%s", r.Method, r.Expires.Unix(), r.Path)
+}
+
+// RequestSigner computes per-request signatures for Account.WithRequestSigner().
+// Since requests can be made concurrently, SignRequest() may be called
+// concurrently as well. If it returns an error, the request is not sent, and
+// the error is returned to the caller.
+type RequestSigner interface {
+	SignRequest(req *SignableRequest) error
+}
+
+// RequestSignerFunc is a RequestSigner that calls itself for each request.
+type RequestSignerFunc func(req *SignableRequest) error
+
+// SignRequest implements the RequestSigner interface.
+func (f RequestSignerFunc) SignRequest(req *SignableRequest) error {
+	return f(req)
+}
+
+// WithRequestSigner returns a handle to the same account that calls the given
+// signer for every request before it is sent, including retries and requests
+// to the /info endpoint. This is intended for gateways in front of Swift that
+// require custom signature headers, so that they can be supported without
+// implementing a custom Backend. For example:
+//
+//	account = account.WithRequestSigner(schwift.RequestSignerFunc(func(req *schwift.SignableRequest) error {
+//		mac := hmac.New(sha256.New, gatewayKey)
+//		mac.Write([]byte(req.CanonicalString()))
+//		req.Header.Set("X-Gateway-Expires", strconv.FormatInt(req.Expires.Unix(), 10))
+//		req.Header.Set("X-Gateway-Signature", hex.EncodeToString(mac.Sum(nil)))
+//		return nil
+//	}), 5*time.Minute)
+//
+// The validity determines SignableRequest.Expires. If it is not positive, a
+// default of 5 minutes is used. Signers can be stacked by calling this method
+// multiple times; the signer added last sees the request first. The signer
+// carries over to accounts obtained from the returned Account via
+// SwitchAccount(), WithNewest(), ReadOnly() or WithPolicy().
+func (a *Account) WithRequestSigner(signer RequestSigner, validity time.Duration) *Account {
+	if validity <= 0 {
+		validity = 5 * time.Minute
+	}
+	return a.withBackend(signingBackend{a.backend, signer, validity})
+}
+
+// signingBackend wraps a Backend to call a RequestSigner for each request. It
+// is used by Account.WithRequestSigner().
+type signingBackend struct {
+	inner    Backend
+	signer   RequestSigner
+	validity time.Duration
+}
+
+func (b signingBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b signingBackend) Clone(newEndpointURL string) Backend {
+	return signingBackend{b.inner.Clone(newEndpointURL), b.signer, b.validity}
+}
+
+func (b signingBackend) Do(req *http.Request) (*http.Response, error) {
+	now := time.Now()
+	err := b.signer.SignRequest(&SignableRequest{
+		Method:  req.Method,
+		Path:    req.URL.EscapedPath(),
+		Query:   req.URL.Query(),
+		Time:    now,
+		Expires: now.Add(b.validity),
+		Header:  req.Header,
+	})
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("cannot sign %s request: %w", req.Method, err)
+	}
+	return b.inner.Do(req)
+}
@@ -0,0 +1,75 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestSigner(t *testing.T) {
+	b := &testBackend{handle: respondWith(http.StatusOK, "hello")}
+	account, err := InitializeAccount(b)
+	must(t, err)
+
+	var signed []SignableRequest
+	signer := RequestSignerFunc(func(req *SignableRequest) error {
+		signed = append(signed, *req)
+		req.Header.Set("X-Gateway-Signature", req.CanonicalString())
+		return nil
+	})
+	account = account.WithRequestSigner(signer, time.Minute)
+
+	resp, err := Request{
+		Method:        http.MethodGet,
+		ContainerName: "foo",
+		ObjectName:    "bar baz",
+		Options:       (*RequestOptions)(nil).WithValue("format", "json"),
+	}.Do(context.TODO(), account.Backend())
+	must(t, err)
+	resp.Body.Close()
+
+	if len(signed) != 1 {
+		t.Fatalf("expected 1 signed request, got %d", len(signed))
+	}
+	req := signed[0]
+	expectString(t, "GET", req.Method)
+	expectString(t, "/v1/AUTH_example/foo/bar%20baz", req.Path)
+	expectString(t, "json", req.Query.Get("format"))
+	if req.Expires.Sub(req.Time) != time.Minute {
+		t.Errorf("expected validity of 1 minute, got %s", req.Expires.Sub(req.Time))
+	}
+	expectString(t, req.CanonicalString(), b.lastReq.Header.Get("X-Gateway-Signature"))
+
+	// the signer carries over to derived accounts, and errors abort the request
+	errSigner := errors.New("no key available")
+	failing := account.WithRequestSigner(RequestSignerFunc(func(*SignableRequest) error {
+		return errSigner
+	}), 0).ReadOnly()
+	_, err = failing.RawCapabilities(context.TODO())
+	if !errors.Is(err, errSigner) {
+		t.Errorf("expected signer error, got %v", err)
+	}
+	if len(signed) != 1 {
+		t.Errorf("expected the inner signer not to be called after the outer signer failed")
+	}
+}
@@ -0,0 +1,170 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SnapshotOptions contains options for Container.SnapshotTo().
+type SnapshotOptions struct {
+	// The prefix for the object names in the snapshot. Defaults to the time at
+	// which the snapshot is started, e.g. "2006-01-02T15:04:05Z/".
+	Prefix string
+	// The maximum number of objects that are copied at the same time. Defaults
+	// to 4.
+	Concurrency int
+	// By default, large objects and symlinks are copied shallowly, i.e. only
+	// the manifest or symlink is copied, and the copy refers to the same
+	// segments or symlink target as the original. If this is set, their
+	// content is copied into plain objects instead. This is much slower, but
+	// makes the snapshot independent from the segments.
+	DeepCopy bool
+	// If true, no marker object is written (see documentation on
+	// Container.SnapshotTo()).
+	SkipMarker bool
+	// If not nil, these options are used for each COPY request.
+	RequestOptions *RequestOptions
+}
+
+// SnapshotResult is returned by Container.SnapshotTo(). It is also stored in
+// the snapshot's marker object in JSON format.
+type SnapshotResult struct {
+	Source      string    `json:"source"` // the name of the source container
+	Prefix      string    `json:"prefix"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	// The number of objects in the snapshot.
+	ObjectCount int `json:"object_count"`
+	// The names of objects that were modified after the snapshot was started,
+	// so the snapshot contains a version that is newer than StartedAt.
+	ChangedObjects []string `json:"changed_objects,omitempty"`
+	// The names of objects that were deleted after the snapshot was started,
+	// and are therefore missing from the snapshot.
+	VanishedObjects []string `json:"vanished_objects,omitempty"`
+}
+
+// SnapshotMarkerName returns the name of the marker object that
+// Container.SnapshotTo() writes for a snapshot with the given prefix.
+func SnapshotMarkerName(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + ".snapshot.json"
+}
+
+// SnapshotTo copies all objects in this container into the destination
+// container using server-side copies, with SnapshotOptions.Prefix prepended
+// to each object name. The destination should be a different container,
+// otherwise previous snapshots will be included in the next one. For example:
+//
+//	result, err := container.SnapshotTo(ctx, account.Container("backups"), nil)
+//	// result.Prefix is e.g. "2006-01-02T15:04:05Z/"
+//
+// Swift cannot take atomic snapshots of a container. When objects are
+// modified while the snapshot is being taken, the snapshot contains whatever
+// version exists when the respective object is copied. Such objects are listed
+// in SnapshotResult.ChangedObjects. (Since Swift timestamps have a resolution
+// of one second, objects that were modified shortly before the snapshot was
+// started may also be listed.) Objects that are deleted while the snapshot is
+// being taken are not an error; they are listed in
+// SnapshotResult.VanishedObjects.
+//
+// Once all objects have been copied, a marker object containing the
+// SnapshotResult in JSON format is written into the destination container.
+// Its name is given by SnapshotMarkerName(), i.e. it is placed next to the
+// snapshot rather than inside it. Tools that consume snapshots should only
+// consider snapshots complete if the marker object exists.
+//
+// If a copy fails, no further copies are started, and the first error is
+// returned after all copies in progress have completed. In this case, the
+// marker object is not written.
+func (c *Container) SnapshotTo(ctx context.Context, dest *Container, opts *SnapshotOptions) (SnapshotResult, error) {
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+	result := SnapshotResult{
+		Source:    c.name,
+		Prefix:    opts.Prefix,
+		StartedAt: time.Now().UTC(),
+	}
+	if result.Prefix == "" {
+		result.Prefix = result.StartedAt.Format(time.RFC3339) + "/"
+	}
+	copyOpts := &CopyOptions{
+		ShallowCopyLargeObjects: !opts.DeepCopy,
+		ShallowCopySymlinks:     !opts.DeepCopy,
+	}
+	// Last-Modified is rounded to full seconds, so objects from the same second
+	// as StartedAt may or may not have been modified after it
+	changedThreshold := result.StartedAt.Truncate(time.Second)
+
+	objects, err := c.Objects().Collect(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	var mutex sync.Mutex
+	err = forEachConcurrently(ctx, opts.Concurrency, objects, func(ctx context.Context, obj *Object) error {
+		target := dest.Object(result.Prefix + obj.Name())
+		hdr, err := obj.copyTo(ctx, target, copyOpts, opts.RequestOptions)
+		if err != nil && !Is(err, http.StatusNotFound) {
+			return err
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			result.VanishedObjects = append(result.VanishedObjects, obj.Name())
+			return nil
+		}
+		result.ObjectCount++
+		// if the timestamp is missing, we cannot rule out a modification
+		lastModified, err := http.ParseTime(hdr.Get("X-Copied-From-Last-Modified"))
+		if err != nil || !lastModified.Before(changedThreshold) {
+			result.ChangedObjects = append(result.ChangedObjects, obj.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	slices.Sort(result.ChangedObjects)
+	slices.Sort(result.VanishedObjects)
+	result.CompletedAt = time.Now().UTC()
+
+	if !opts.SkipMarker {
+		buf, err := json.Marshal(result)
+		if err != nil {
+			return result, err
+		}
+		hdr := NewObjectHeaders()
+		hdr.ContentType().Set("application/json")
+		marker := dest.Object(SnapshotMarkerName(result.Prefix))
+		err = marker.Upload(ctx, bytes.NewReader(buf), nil, hdr.ToOpts())
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
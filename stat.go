@@ -0,0 +1,204 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AccountStat contains usage statistics for an account, as returned by
+// Account.Stat().
+type AccountStat struct {
+	ContainerCount uint64
+	ObjectCount    uint64
+	BytesUsed      uint64
+	// QuotaBytes is 0 if no quota is set.
+	QuotaBytes uint64
+	CreatedAt  time.Time
+	// UpdatedAt is the zero value if the server does not report it.
+	UpdatedAt time.Time
+	// Policies contains the usage statistics for each storage policy that is in
+	// use in this account. The map keys are the policy names. Since Swift treats
+	// policy names case-insensitively and Go canonicalizes header names, the
+	// keys may differ in case from the names reported in Capabilities, so use
+	// strings.EqualFold() when comparing them.
+	Policies map[string]StoragePolicyStat
+}
+
+// StoragePolicyStat contains usage statistics for a single storage policy
+// within an account. It appears in type AccountStat.
+type StoragePolicyStat struct {
+	ContainerCount uint64
+	ObjectCount    uint64
+	BytesUsed      uint64
+}
+
+// ContainerStat contains usage statistics for a container, as returned by
+// Container.Stat().
+type ContainerStat struct {
+	ObjectCount uint64
+	BytesUsed   uint64
+	// QuotaBytes and QuotaCount are 0 if the respective quota is not set.
+	QuotaBytes    uint64
+	QuotaCount    uint64
+	StoragePolicy string
+	CreatedAt     time.Time
+	// UpdatedAt is the zero value if the server does not report it.
+	UpdatedAt time.Time
+}
+
+// Stat returns the usage statistics for this account. This is a convenience
+// wrapper around Account.Headers(), so the statistics come from a single HEAD
+// request (or from the cached headers, if any).
+func (a *Account) Stat(ctx context.Context) (AccountStat, error) {
+	hdr, err := a.Headers(ctx)
+	if err != nil {
+		return AccountStat{}, err
+	}
+
+	stat := AccountStat{
+		ContainerCount: hdr.ContainerCount().Get(),
+		ObjectCount:    hdr.ObjectCount().Get(),
+		BytesUsed:      hdr.BytesUsed().Get(),
+		QuotaBytes:     hdr.BytesUsedQuota().Get(),
+		CreatedAt:      hdr.CreatedAt().Get(),
+		UpdatedAt:      hdr.UpdatedAt().Get(),
+		Policies:       make(map[string]StoragePolicyStat),
+	}
+
+	// collect per-policy statistics from headers like
+	// "X-Account-Storage-Policy-Gold-Bytes-Used"
+	for key := range hdr.Headers {
+		name, isPolicyHeader := strings.CutPrefix(key, "X-Account-Storage-Policy-")
+		if !isPolicyHeader {
+			continue
+		}
+		value := FieldUint64Readonly{hdr.Headers, key}.Get()
+		for _, suffix := range []string{"-Container-Count", "-Object-Count", "-Bytes-Used"} {
+			policyName, ok := strings.CutSuffix(name, suffix)
+			if !ok {
+				continue
+			}
+			ps := stat.Policies[policyName]
+			switch suffix {
+			case "-Container-Count":
+				ps.ContainerCount = value
+			case "-Object-Count":
+				ps.ObjectCount = value
+			case "-Bytes-Used":
+				ps.BytesUsed = value
+			}
+			stat.Policies[policyName] = ps
+		}
+	}
+
+	return stat, nil
+}
+
+// Stat returns the usage statistics for this container. This is a
+// convenience wrapper around Container.Headers(), so the statistics come from
+// a single HEAD request (or from the cached headers, if any).
+//
+// This operation fails with http.StatusNotFound if the container does not exist.
+func (c *Container) Stat(ctx context.Context) (ContainerStat, error) {
+	hdr, err := c.Headers(ctx)
+	if err != nil {
+		return ContainerStat{}, err
+	}
+	return ContainerStat{
+		ObjectCount:   hdr.ObjectCount().Get(),
+		BytesUsed:     hdr.BytesUsed().Get(),
+		QuotaBytes:    hdr.BytesUsedQuota().Get(),
+		QuotaCount:    hdr.ObjectCountQuota().Get(),
+		StoragePolicy: hdr.StoragePolicy().Get(),
+		CreatedAt:     hdr.CreatedAt().Get(),
+		UpdatedAt:     hdr.UpdatedAt().Get(),
+	}, nil
+}
+
+// LargeObjectStat contains statistics about the segments of a large object, as
+// returned by LargeObject.Stat().
+type LargeObjectStat struct {
+	// SizeBytes is the logical size of the large object, i.e. the sum of
+	// SegmentSizes.
+	SizeBytes    uint64
+	SegmentCount int
+	// SegmentSizes contains the number of bytes that each segment contributes to
+	// the large object (for range segments, this is the length of the range), in
+	// the order of the segments in the manifest.
+	SegmentSizes []uint64
+	// MinimumSegmentSize, MedianSegmentSize and MaximumSegmentSize summarize the
+	// distribution of SegmentSizes. They are 0 if there are no segments.
+	MinimumSegmentSize uint64
+	MedianSegmentSize  uint64
+	MaximumSegmentSize uint64
+	// RangeSegmentCount and DataSegmentCount count the segments that are
+	// range-limited or data segments, respectively.
+	RangeSegmentCount int
+	DataSegmentCount  int
+	// ForeignSegmentCount counts the segments that are backed by objects outside
+	// of SegmentContainer() below SegmentPrefix().
+	ForeignSegmentCount int
+}
+
+// Stat returns statistics about the segments of this large object. The
+// statistics are computed from the segments that are already known to this
+// LargeObject instance, so no requests are issued unless the large object was
+// opened with AsLargeObjectOptions.Lazy and its segments need to be loaded.
+// For segments that do not have their SizeBytes set (which can only happen for
+// segments added through AddSegment()), the size is assumed to be 0.
+func (lo *LargeObject) Stat(ctx context.Context) (LargeObjectStat, error) {
+	err := lo.LoadSegments(ctx)
+	if err != nil {
+		return LargeObjectStat{}, err
+	}
+	stat := LargeObjectStat{
+		SegmentCount: len(lo.segments),
+		SegmentSizes: make([]uint64, len(lo.segments)),
+	}
+
+	for idx, s := range lo.segments {
+		size, _ := s.effectiveSize()
+		stat.SegmentSizes[idx] = size
+		stat.SizeBytes += size
+
+		switch {
+		case len(s.Data) > 0:
+			stat.DataSegmentCount++
+		case s.RangeOffset != 0 || s.RangeLength != 0:
+			stat.RangeSegmentCount++
+		}
+		if s.Object != nil && !lo.isOwnSegment(s.Object) {
+			stat.ForeignSegmentCount++
+		}
+	}
+
+	if len(stat.SegmentSizes) > 0 {
+		sorted := slices.Clone(stat.SegmentSizes)
+		slices.Sort(sorted)
+		stat.MinimumSegmentSize = sorted[0]
+		stat.MedianSegmentSize = sorted[len(sorted)/2]
+		stat.MaximumSegmentSize = sorted[len(sorted)-1]
+	}
+
+	return stat, nil
+}
@@ -0,0 +1,376 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+/*
+Package swauth contains helpers for Swift clusters that use the swauth
+middleware (https://github.com/openstack/swauth) instead of Keystone for
+authentication. It can manage accounts and users through the swauth admin API,
+and connect to accounts as one of their users. This allows test environments
+to be bootstrapped entirely from Go. For example:
+
+	import "github.com/majewsky/schwift/v2/swauth"
+
+	admin := &swauth.Client{
+		AuthURL:   "http://127.0.0.1:8080/auth/",
+		AdminUser: ".super_admin",
+		AdminKey:  "swauthkey",
+	}
+	err := admin.CreateAccount(ctx, "test")
+	err = admin.CreateUser(ctx, "test", "tester", "testing", &swauth.UserOptions{Admin: true})
+	account, err := admin.Connect(ctx, "test", "tester", "testing")
+
+Using this schwift.Account instance, you have access to all of schwift's API.
+Refer to the documentation in the parent package for details.
+*/
+package swauth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+// Client talks to the swauth middleware of a Swift cluster. For the admin API
+// (all methods except Connect), AdminUser and AdminKey must identify a swauth
+// user with sufficient privileges, e.g. the ".super_admin" user.
+type Client struct {
+	// The URL where swauth is mounted, e.g. "http://127.0.0.1:8080/auth/".
+	AuthURL   string
+	AdminUser string
+	AdminKey  string
+	// If set, this client is used instead of http.DefaultClient.
+	HTTPClient *http.Client
+	// If set (and HTTPClient is not), an HTTP client with these TLS settings is
+	// used instead of http.DefaultClient.
+	TLS *schwift.TLSOptions
+	// If set (and HTTPClient is not), connections are established with this
+	// function, e.g. schwift.UnixSocketDialer().
+	Dial schwift.DialFunc
+	// If set, this User-Agent will be reported in HTTP requests instead of
+	// schwift.DefaultUserAgent.
+	UserAgent string
+
+	clientOnce      sync.Once
+	customClient    *http.Client
+	customClientErr error
+}
+
+func (c *Client) httpClient() (*http.Client, error) {
+	if c.HTTPClient != nil {
+		return c.HTTPClient, nil
+	}
+	if c.TLS != nil || c.Dial != nil {
+		c.clientOnce.Do(func() {
+			c.customClient, c.customClientErr = schwift.NewHTTPClient(c.TLS, c.Dial)
+		})
+		return c.customClient, c.customClientErr
+	}
+	return http.DefaultClient, nil
+}
+
+func (c *Client) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return schwift.DefaultUserAgent
+}
+
+// do executes an admin API request on the given path below "v2/", and returns
+// the response body. Errors are reported as schwift.UnexpectedStatusCodeError,
+// so that schwift.Is() can be used on them.
+func (c *Client) do(ctx context.Context, method string, hdr http.Header, pathElements []string, expectStatusCodes ...int) ([]byte, error) {
+	escapedElements := make([]string, len(pathElements))
+	for idx, elem := range pathElements {
+		escapedElements[idx] = url.PathEscape(elem)
+	}
+	uri := strings.TrimSuffix(c.AuthURL, "/") + "/v2/" + strings.Join(escapedElements, "/")
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range hdr {
+		req.Header[key] = values
+	}
+	req.Header.Set("X-Auth-Admin-User", c.AdminUser)
+	req.Header.Set("X-Auth-Admin-Key", c.AdminKey)
+	req.Header.Set("User-Agent", c.userAgent())
+
+	httpClient, err := c.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, code := range expectStatusCodes {
+		if resp.StatusCode == code {
+			return buf, nil
+		}
+	}
+	return nil, schwift.UnexpectedStatusCodeError{
+		Method:              method,
+		Target:              "swauth:" + strings.Join(pathElements, "/"),
+		ExpectedStatusCodes: expectStatusCodes,
+		ActualResponse:      resp,
+		ResponseBody:        buf,
+	}
+}
+
+// ListAccounts returns the names of all accounts known to swauth.
+func (c *Client) ListAccounts(ctx context.Context) ([]string, error) {
+	buf, err := c.do(ctx, http.MethodGet, nil, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Accounts []struct {
+			Name string `json:"name"`
+		} `json:"accounts"`
+	}
+	err = json.Unmarshal(buf, &data)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(data.Accounts))
+	for idx, a := range data.Accounts {
+		result[idx] = a.Name
+	}
+	return result, nil
+}
+
+// CreateAccount creates an account in swauth, which also creates the
+// corresponding Swift account. This function can be used regardless of whether
+// the account exists or not.
+func (c *Client) CreateAccount(ctx context.Context, accountName string) error {
+	_, err := c.do(ctx, http.MethodPut, nil, []string{accountName}, http.StatusCreated, http.StatusAccepted)
+	return err
+}
+
+// DeleteAccount deletes an account in swauth. This only works if the account
+// does not have any users anymore, and if the Swift account is empty.
+func (c *Client) DeleteAccount(ctx context.Context, accountName string) error {
+	_, err := c.do(ctx, http.MethodDelete, nil, []string{accountName}, http.StatusNoContent)
+	return err
+}
+
+// ListUsers returns the names of all users in the given account.
+func (c *Client) ListUsers(ctx context.Context, accountName string) ([]string, error) {
+	buf, err := c.do(ctx, http.MethodGet, nil, []string{accountName}, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Users []struct {
+			Name string `json:"name"`
+		} `json:"users"`
+	}
+	err = json.Unmarshal(buf, &data)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(data.Users))
+	for idx, u := range data.Users {
+		result[idx] = u.Name
+	}
+	return result, nil
+}
+
+// UserOptions contains optional settings for Client.CreateUser().
+type UserOptions struct {
+	// If set, the user can manage the other users of their account.
+	Admin bool
+	// If set, the user can manage all accounts. This implies Admin.
+	ResellerAdmin bool
+}
+
+// CreateUser creates a user in the given account with the given key
+// (password). If the user exists already, it is overwritten.
+func (c *Client) CreateUser(ctx context.Context, accountName, userName, key string, opts *UserOptions) error {
+	hdr := http.Header{"X-Auth-User-Key": []string{key}}
+	if opts != nil {
+		if opts.Admin {
+			hdr.Set("X-Auth-User-Admin", "true")
+		}
+		if opts.ResellerAdmin {
+			hdr.Set("X-Auth-User-Reseller-Admin", "true")
+		}
+	}
+	_, err := c.do(ctx, http.MethodPut, hdr, []string{accountName, userName}, http.StatusCreated)
+	return err
+}
+
+// DeleteUser deletes a user from the given account.
+func (c *Client) DeleteUser(ctx context.Context, accountName, userName string) error {
+	_, err := c.do(ctx, http.MethodDelete, nil, []string{accountName, userName}, http.StatusNoContent)
+	return err
+}
+
+// Connect authenticates as the given user using swauth's v1.0 auth endpoint,
+// and returns a schwift.Account for the user's account. When the token
+// expires, the backend of the returned account authenticates again.
+//
+// This does not use AdminUser and AdminKey.
+func (c *Client) Connect(ctx context.Context, accountName, userName, key string) (*schwift.Account, error) {
+	b := &backend{
+		client: c,
+		user:   accountName + ":" + userName,
+		key:    key,
+	}
+	err := b.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schwift.InitializeAccount(b)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// type backend
+
+type backend struct {
+	client *Client
+	user   string
+	key    string
+
+	mutex       sync.Mutex
+	endpointURL string
+	token       string
+}
+
+func (b *backend) authenticate(ctx context.Context) error {
+	uri := strings.TrimSuffix(b.client.AuthURL, "/") + "/v1.0"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-User", b.user)
+	req.Header.Set("X-Auth-Key", b.key)
+	req.Header.Set("User-Agent", b.client.userAgent())
+
+	httpClient, err := b.client.httpClient()
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return schwift.UnexpectedStatusCodeError{
+			Method:              http.MethodGet,
+			Target:              "swauth:v1.0",
+			ExpectedStatusCodes: []int{http.StatusOK},
+			ActualResponse:      resp,
+			ResponseBody:        buf,
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.endpointURL == "" {
+		b.endpointURL = resp.Header.Get("X-Storage-Url")
+		if !strings.HasSuffix(b.endpointURL, "/") {
+			b.endpointURL += "/"
+		}
+	}
+	b.token = resp.Header.Get("X-Auth-Token")
+	return nil
+}
+
+func (b *backend) EndpointURL() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.endpointURL
+}
+
+func (b *backend) Clone(newEndpointURL string) schwift.Backend {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return &backend{
+		client:      b.client,
+		user:        b.user,
+		key:         b.key,
+		endpointURL: newEndpointURL,
+		token:       b.token,
+	}
+}
+
+func (b *backend) Do(req *http.Request) (*http.Response, error) {
+	return b.do(req, false)
+}
+
+func (b *backend) do(req *http.Request, afterReauth bool) (*http.Response, error) {
+	b.mutex.Lock()
+	req.Header.Set("X-Auth-Token", b.token)
+	b.mutex.Unlock()
+	req.Header.Set("User-Agent", b.client.userAgent())
+
+	httpClient, err := b.client.httpClient()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// detect expired token (the request can only be restarted if its body can be
+	// replayed)
+	canReplay := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if resp.StatusCode == http.StatusUnauthorized && !afterReauth && canReplay {
+		_, err := io.Copy(io.Discard, resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		err = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		err = b.authenticate(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		if req.GetBody != nil {
+			req.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b.do(req, true)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,232 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // Used by swift
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Temp URLs can only authorize requests on object paths, so they cannot be
+// used for Account.BulkDelete() (which POSTs to the account) or for
+// Account.BulkUpload() (whose archive members are written through
+// subrequests that are not covered by the signature of the original request).
+// To hand out the ability to upload many files, use Container.FormPost(). To
+// hand out the ability to delete a known set of objects, use
+// Container.PrefixTempURL() with method "DELETE" and let the client issue one
+// DELETE per object.
+
+// signTempURL computes the signature for a temp URL on the given path, which
+// must be the unescaped URL path (e.g. "/v1/AUTH_example/foo/bar"), or
+// "prefix:" followed by such a path for prefix-based temp URLs.
+func (a *Account) signTempURL(ctx context.Context, key, method, path string, expires time.Time) (string, error) {
+	capabilities, err := a.Capabilities(ctx)
+	if err != nil {
+		return "", err
+	}
+	if capabilities.TempURL == nil {
+		return "", ErrNotSupported
+	}
+	allowedMethods := capabilities.TempURL.Methods
+	if len(allowedMethods) > 0 && !contains(allowedMethods, method) {
+		return "", fmt.Errorf("temp URLs for method %s are not supported by the Swift server, only for: %s", method, strings.Join(allowedMethods, ", "))
+	}
+	allowedDigest := capabilities.TempURL.AllowedDigests
+
+	var mac hash.Hash
+	switch {
+	case contains(allowedDigest, "sha256"):
+		mac = hmac.New(sha256.New, []byte(key))
+	case contains(allowedDigest, "sha1"):
+		mac = hmac.New(sha1.New, []byte(key))
+	default:
+		return "", fmt.Errorf("schwift supports sha1 and sha256 digests but the Swift server only supports: %s", strings.Join(allowedDigest, ", "))
+	}
+
+	payload := fmt.Sprintf("%s\n%d\n%s", method, expires.Unix(), path)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// PrefixTempURL is a temp URL that permits access to all objects in a
+// container whose names start with a certain prefix. It is obtained from
+// Container.PrefixTempURL().
+type PrefixTempURL struct {
+	c      *Container
+	prefix string
+	query  string
+}
+
+// PrefixTempURL is like Object.TempURL, but the resulting token permits access
+// to all objects in this container whose names start with the given prefix,
+// using the given HTTP method. This is useful for handing out a signed,
+// time-limited ability to upload (method "PUT") or delete (method "DELETE") a
+// set of objects to an untrusted client. For example:
+//
+//	tu, err := container.PrefixTempURL(ctx, key, "DELETE", "uploads/1234/", time.Now().Add(time.Hour))
+//	for _, name := range names {
+//		url, err := tu.ObjectURL(name) //e.g. name == "uploads/1234/photo.jpg"
+//		...
+//	}
+//
+// The same requirements as for Object.TempURL apply.
+func (c *Container) PrefixTempURL(ctx context.Context, key, method, prefix string, expires time.Time) (PrefixTempURL, error) {
+	urlStr, err := c.URL()
+	if err != nil {
+		return PrefixTempURL{}, err
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return PrefixTempURL{}, err
+	}
+
+	path := "prefix:" + strings.TrimSuffix(u.Path, "/") + "/" + prefix
+	signature, err := c.a.signTempURL(ctx, key, method, path, expires)
+	if err != nil {
+		return PrefixTempURL{}, err
+	}
+
+	query := fmt.Sprintf("temp_url_sig=%s&temp_url_expires=%d&temp_url_prefix=%s",
+		signature, expires.Unix(), url.QueryEscape(prefix))
+	return PrefixTempURL{c, prefix, query}, nil
+}
+
+// Prefix returns the object name prefix that this temp URL applies to.
+func (tu PrefixTempURL) Prefix() string {
+	return tu.prefix
+}
+
+// ObjectURL returns the temp URL for the object with the given name. An error
+// is returned if the object name does not start with the prefix of this temp
+// URL, since Swift would reject the token for such an object.
+func (tu PrefixTempURL) ObjectURL(objectName string) (string, error) {
+	if !strings.HasPrefix(objectName, tu.prefix) {
+		return "", fmt.Errorf("object name %q does not start with temp URL prefix %q", objectName, tu.prefix)
+	}
+	urlStr, err := tu.c.Object(objectName).URL()
+	if err != nil {
+		return "", err
+	}
+	return urlStr + "?" + tu.query, nil
+}
+
+// FormPostOptions contains the parameters for Container.FormPost().
+type FormPostOptions struct {
+	// Uploaded files are stored as objects whose name is this prefix followed by
+	// the file name given by the client.
+	ObjectPrefix string
+	// If not empty, the client is redirected to this URL after the upload.
+	RedirectURL string
+	// The maximum size of each uploaded file in bytes. Must not be zero.
+	MaxFileSize uint64
+	// The maximum number of files that can be uploaded in one request. Must not
+	// be zero.
+	MaxFileCount uint
+	// The form is rejected by Swift after this time.
+	Expires time.Time
+}
+
+// FormPost contains what is needed to render an HTML form that uploads files
+// directly into Swift through the formpost middleware. It is obtained from
+// Container.FormPost().
+type FormPost struct {
+	// The URL that the form must be POSTed to, with enctype "multipart/form-data".
+	URL string
+	// These fields must be included in the form (usually as hidden inputs)
+	// before any file fields.
+	Fields map[string]string
+}
+
+// FormPost generates the signed parameters for an HTML form that permits
+// uploading files into this container below the given object prefix without
+// authentication. This works only when the formpost middleware is set up on
+// the server, and if the given `key` matches one of the tempurl keys for this
+// container or its account. For example:
+//
+//	fp, err := container.FormPost(ctx, key, schwift.FormPostOptions{
+//		ObjectPrefix: "uploads/1234/",
+//		MaxFileSize:  10 << 20,
+//		MaxFileCount: 5,
+//		Expires:      time.Now().Add(time.Hour),
+//	})
+//	//render <form action="{{fp.URL}}" method="POST" enctype="multipart/form-data">
+//	//with a hidden <input> for each entry in fp.Fields, followed by file inputs
+func (c *Container) FormPost(ctx context.Context, key string, opts FormPostOptions) (FormPost, error) {
+	if opts.MaxFileSize == 0 || opts.MaxFileCount == 0 {
+		return FormPost{}, errors.New("invalid FormPostOptions: MaxFileSize and MaxFileCount must not be zero")
+	}
+	capabilities, err := c.a.Capabilities(ctx)
+	if err != nil {
+		return FormPost{}, err
+	}
+	if capabilities.FormPost == nil {
+		return FormPost{}, ErrNotSupported
+	}
+
+	var urlStr string
+	if opts.ObjectPrefix == "" {
+		urlStr, err = c.URL()
+		urlStr = strings.TrimSuffix(urlStr, "/")
+	} else {
+		urlStr, err = c.Object(opts.ObjectPrefix).URL()
+	}
+	if err != nil {
+		return FormPost{}, err
+	}
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return FormPost{}, err
+	}
+
+	// formpost accepts hex-encoded signatures of any allowed digest; servers
+	// that do not report allowed digests only support sha1
+	var mac hash.Hash
+	if contains(capabilities.FormPost.AllowedDigests, "sha256") {
+		mac = hmac.New(sha256.New, []byte(key))
+	} else {
+		mac = hmac.New(sha1.New, []byte(key))
+	}
+
+	maxFileSize := strconv.FormatUint(opts.MaxFileSize, 10)
+	maxFileCount := strconv.FormatUint(uint64(opts.MaxFileCount), 10)
+	expires := strconv.FormatInt(opts.Expires.Unix(), 10)
+	payload := strings.Join([]string{u.Path, opts.RedirectURL, maxFileSize, maxFileCount, expires}, "\n")
+	mac.Write([]byte(payload))
+
+	return FormPost{
+		URL: urlStr,
+		Fields: map[string]string{
+			"redirect":       opts.RedirectURL,
+			"max_file_size":  maxFileSize,
+			"max_file_count": maxFileCount,
+			"expires":        expires,
+			"signature":      hex.EncodeToString(mac.Sum(nil)),
+		},
+	}, nil
+}
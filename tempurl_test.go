@@ -0,0 +1,100 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContainerPrefixTempURL(t *testing.T) {
+	account, err := InitializeAccount(tempurlBogusBackend{
+		mockInfoText: `{ "tempurl": { "allowed_digests": [ "sha1", "sha256" ], "methods": [ "GET", "PUT", "DELETE" ]}}`,
+	})
+	must(t, err)
+	c := account.Container("foo")
+
+	tu, err := c.PrefixTempURL(context.TODO(), "supersecretkey", "DELETE", "uploads/", time.Unix(1e9, 0))
+	must(t, err)
+	actualURL, err := tu.ObjectURL("uploads/a b.jpg")
+	must(t, err)
+	expectedURL := "https://example.com/v1/AUTH_example/foo/uploads%2Fa%20b.jpg?temp_url_sig=50e438b6060458e7d1650b2224e216aef00c449684869d2f5907a17ef5dc423f&temp_url_expires=1000000000&temp_url_prefix=uploads%2F"
+	expectString(t, expectedURL, actualURL)
+
+	_, err = tu.ObjectURL("other/file.jpg")
+	if err == nil {
+		t.Error("expected ObjectURL() to fail for object outside of prefix")
+	}
+
+	// methods that are not advertised by the server are rejected
+	_, err = c.PrefixTempURL(context.TODO(), "supersecretkey", "POST", "uploads/", time.Unix(1e9, 0))
+	if err == nil {
+		t.Error("expected PrefixTempURL() to fail for unsupported method")
+	}
+}
+
+func TestContainerFormPost(t *testing.T) {
+	account, err := InitializeAccount(tempurlBogusBackend{
+		mockInfoText: `{ "formpost": { "allowed_digests": [ "sha1", "sha256" ]}}`,
+	})
+	must(t, err)
+
+	fp, err := account.Container("foo").FormPost(context.TODO(), "supersecretkey", FormPostOptions{
+		ObjectPrefix: "uploads/",
+		RedirectURL:  "https://example.org/done",
+		MaxFileSize:  1 << 20,
+		MaxFileCount: 5,
+		Expires:      time.Unix(1e9, 0),
+	})
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/foo/uploads%2F", fp.URL)
+	expectString(t, "https://example.org/done", fp.Fields["redirect"])
+	expectString(t, "1048576", fp.Fields["max_file_size"])
+	expectString(t, "5", fp.Fields["max_file_count"])
+	expectString(t, "1000000000", fp.Fields["expires"])
+	expectString(t, "cd0ef66e8229953aa23924bac1b1beda9bf90b82ee1b84855bc30cae8b90fa32", fp.Fields["signature"])
+
+	// servers that do not report allowed digests only support sha1
+	account, err = InitializeAccount(tempurlBogusBackend{
+		mockInfoText: `{ "formpost": {}}`,
+	})
+	must(t, err)
+	fp, err = account.Container("foo").FormPost(context.TODO(), "supersecretkey", FormPostOptions{
+		MaxFileSize:  1 << 20,
+		MaxFileCount: 5,
+		Expires:      time.Unix(1e9, 0),
+	})
+	must(t, err)
+	expectString(t, "https://example.com/v1/AUTH_example/foo", fp.URL)
+	expectString(t, "26dc136495e9e973dda45f822d7702e9d544771d", fp.Fields["signature"])
+
+	// servers without formpost are detected
+	account, err = InitializeAccount(tempurlBogusBackend{mockInfoText: `{}`})
+	must(t, err)
+	_, err = account.Container("foo").FormPost(context.TODO(), "supersecretkey", FormPostOptions{
+		MaxFileSize:  1 << 20,
+		MaxFileCount: 5,
+		Expires:      time.Unix(1e9, 0),
+	})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -20,6 +20,10 @@ package tests
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/majewsky/schwift/v2"
@@ -92,3 +96,33 @@ func TestAccountMetadata(t *testing.T) {
 		expectString(t, hdr.Metadata().Get("schwift-test2"), "changed")
 	})
 }
+
+func TestAccountHealthcheck(t *testing.T) {
+	testWithAccount(t, func(a *schwift.Account) {
+		ctx := context.TODO()
+		status, err := a.Healthcheck(ctx, 0)
+		expectSuccess(t, err)
+		expectBool(t, status.Healthy(), true)
+		expectInt(t, status.HealthcheckStatusCode, http.StatusOK)
+		expectString(t, status.HealthcheckMessage, "OK")
+		expectInt(t, status.InfoStatusCode, http.StatusOK)
+		expectBool(t, status.Version != "", true)
+
+		// simulate a proxy that was taken out of rotation
+		hb := &HookBackend{Inner: a.Backend()}
+		hb.AfterResponse = func(req *http.Request, resp *http.Response) {
+			if req.URL.Path == "/healthcheck" {
+				resp.Body.Close()
+				resp.StatusCode = http.StatusServiceUnavailable
+				resp.Body = io.NopCloser(strings.NewReader("DISABLED BY FILE"))
+			}
+		}
+		a2, err := schwift.InitializeAccount(hb)
+		expectSuccess(t, err)
+		status, err = a2.Healthcheck(ctx, 0)
+		expectError(t, err, "cluster is not healthy: GET /healthcheck returned 503 DISABLED BY FILE")
+		expectBool(t, errors.Is(err, schwift.ErrUnhealthy), true)
+		expectBool(t, status.Healthy(), false)
+		expectString(t, status.HealthcheckMessage, "DISABLED BY FILE")
+	})
+}
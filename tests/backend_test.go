@@ -19,7 +19,9 @@
 package tests
 
 import (
+	"io"
 	"net/http"
+	"sync"
 
 	"github.com/majewsky/schwift/v2"
 )
@@ -27,6 +29,7 @@ import (
 type RequestCountingBackend struct {
 	Inner schwift.Backend
 	Count int
+	mutex sync.Mutex
 }
 
 func (b *RequestCountingBackend) EndpointURL() string {
@@ -38,6 +41,62 @@ func (b *RequestCountingBackend) Clone(newEndpointURL string) schwift.Backend {
 }
 
 func (b *RequestCountingBackend) Do(req *http.Request) (*http.Response, error) {
+	b.mutex.Lock()
 	b.Count++
+	b.mutex.Unlock()
 	return b.Inner.Do(req)
 }
+
+// HookBackend calls BeforeRequest (if set) before passing each request on to
+// the Inner backend. This is used to interleave other operations with the
+// requests made by a method under test.
+type HookBackend struct {
+	Inner         schwift.Backend
+	BeforeRequest func(req *http.Request)
+	// If not nil, AfterResponse is called with each successful response, and
+	// may modify it.
+	AfterResponse func(req *http.Request, resp *http.Response)
+}
+
+func (b *HookBackend) EndpointURL() string {
+	return b.Inner.EndpointURL()
+}
+
+func (b *HookBackend) Clone(newEndpointURL string) schwift.Backend {
+	return &HookBackend{Inner: b.Inner.Clone(newEndpointURL), BeforeRequest: b.BeforeRequest, AfterResponse: b.AfterResponse}
+}
+
+func (b *HookBackend) Do(req *http.Request) (*http.Response, error) {
+	if b.BeforeRequest != nil {
+		b.BeforeRequest(req)
+	}
+	resp, err := b.Inner.Do(req)
+	if err == nil && b.AfterResponse != nil {
+		b.AfterResponse(req, resp)
+	}
+	return resp, err
+}
+
+// interruptedBody cuts off the inner response body with io.ErrUnexpectedEOF
+// after a certain number of bytes. This is used to simulate interrupted
+// downloads.
+type interruptedBody struct {
+	inner     io.ReadCloser
+	remaining int64
+}
+
+func (b *interruptedBody) Read(buf []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(buf)) > b.remaining {
+		buf = buf[:b.remaining]
+	}
+	n, err := b.inner.Read(buf)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *interruptedBody) Close() error {
+	return b.inner.Close()
+}
@@ -0,0 +1,95 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/backup"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("text/plain")
+		hdr.Metadata().Set("Color", "blue")
+		expectSuccess(t, c.Object("first").Upload(ctx, strings.NewReader("hello"), nil, hdr.ToOpts()))
+		expectSuccess(t, c.Object("second").Upload(ctx, strings.NewReader("world"), nil, nil))
+
+		// full backup
+		var full bytes.Buffer
+		manifest, err := backup.Create(ctx, &full, c, nil)
+		expectSuccess(t, err)
+		expectInt(t, len(manifest.Changed), 2)
+		expectString(t, strings.Join(manifest.Objects, ","), "first,second")
+
+		// use the server-side timestamps as the cutoff to be robust against clock skew
+		infos, err := c.Objects().CollectDetailed(ctx)
+		expectSuccess(t, err)
+		var since time.Time
+		for _, info := range infos {
+			if info.LastModified.After(since) {
+				since = info.LastModified
+			}
+		}
+		since = since.Add(time.Microsecond)
+		time.Sleep(10 * time.Millisecond)
+
+		// incremental backup after some changes
+		expectSuccess(t, c.Object("second").Delete(ctx, nil, nil))
+		expectSuccess(t, c.Object("third").Upload(ctx, strings.NewReader("!"), nil, nil))
+		var incremental bytes.Buffer
+		manifest, err = backup.Create(ctx, &incremental, c, &backup.Options{Since: since})
+		expectSuccess(t, err)
+		expectInt(t, len(manifest.Changed), 1)
+		expectString(t, manifest.Changed[0].Name, "third")
+		expectString(t, manifest.Changed[0].Etag, etagOfString("!"))
+		expectString(t, strings.Join(manifest.Objects, ","), "first,third")
+
+		// restore both into a fresh container
+		target, err := c.Account().Container(c.Name() + "-restore").EnsureExists(ctx)
+		expectSuccess(t, err)
+		defer func() {
+			expectSuccess(t, target.Object("first").Delete(ctx, nil, nil))
+			expectSuccess(t, target.Object("third").Delete(ctx, nil, nil))
+			expectSuccess(t, target.Delete(ctx, nil))
+		}()
+		for _, buf := range []*bytes.Buffer{&full, &incremental} {
+			_, err := backup.Restore(ctx, buf, target, &backup.RestoreOptions{DeleteMissing: true})
+			expectSuccess(t, err)
+		}
+
+		objects, err := target.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectObjectNames(t, objects, "first", "third")
+		expectObjectContent(t, target.Object("first"), []byte("hello"))
+		expectObjectContent(t, target.Object("third"), []byte("!"))
+		restored, err := target.Object("first").Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, restored.ContentType().Get(), "text/plain")
+		expectString(t, restored.Metadata().Get("Color"), "blue")
+	})
+}
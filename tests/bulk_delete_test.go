@@ -103,3 +103,60 @@ func testWithAndWithoutBulkDeleteSupport(action func()) {
 	capabilities.AllowBulkDelete = true
 	action()
 }
+
+func TestDeleteByPrefix(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		a := c.Account()
+
+		// setup: objects in several containers, only some of which match
+		containers := []*schwift.Container{c}
+		for _, suffix := range []string{"-tenant1", "-tenant2", "-other"} {
+			cc, err := a.Container(c.Name() + suffix).EnsureExists(ctx)
+			expectSuccess(t, err)
+			containers = append(containers, cc)
+		}
+		for _, cc := range containers {
+			for _, name := range []string{"tmp/1", "tmp/2", "keep/1"} {
+				expectSuccess(t, cc.Object(name).Upload(ctx, strings.NewReader("example"), nil, nil))
+			}
+		}
+
+		// dry run only counts
+		pattern := c.Name() + "-tenant*"
+		result, err := a.DeleteByPrefix(ctx, pattern, "tmp/", &schwift.DeleteByPrefixOptions{DryRun: true})
+		expectSuccess(t, err)
+		expectInt(t, result.ContainersMatched, 2)
+		expectInt(t, result.ObjectsMatched, 4)
+		expectInt(t, result.ObjectsDeleted, 0)
+		expectObjectExistence(t, containers[1].Object("tmp/1"), true)
+
+		// actual run deletes
+		var progress []int
+		result, err = a.DeleteByPrefix(ctx, pattern, "tmp/", &schwift.DeleteByPrefixOptions{
+			Progress: func(r schwift.DeleteByPrefixResult) { progress = append(progress, r.ObjectsDeleted) },
+		})
+		expectSuccess(t, err)
+		expectInt(t, result.ContainersMatched, 2)
+		expectInt(t, result.ObjectsDeleted, 4)
+		expectString(t, fmt.Sprint(progress), "[2 4]")
+		for idx, cc := range containers {
+			isTenant := idx == 1 || idx == 2
+			expectObjectExistence(t, cc.Object("tmp/1"), !isTenant)
+			expectObjectExistence(t, cc.Object("tmp/2"), !isTenant)
+			expectObjectExistence(t, cc.Object("keep/1"), true)
+		}
+
+		// malformed patterns are rejected
+		_, err = a.DeleteByPrefix(ctx, "[", "", nil)
+		expectError(t, err, "syntax error in pattern")
+
+		// cleanup
+		for _, cc := range containers[1:] {
+			objects, err := cc.Objects().Collect(ctx)
+			expectSuccess(t, err)
+			_, _, err = a.BulkDelete(ctx, objects, []*schwift.Container{cc}, nil)
+			expectSuccess(t, err)
+		}
+	})
+}
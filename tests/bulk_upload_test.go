@@ -20,12 +20,16 @@ package tests
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"io"
 	"strings"
 	"testing"
 
 	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/capabilities"
 	"github.com/majewsky/schwift/v2/internal/errext"
 )
 
@@ -138,3 +142,101 @@ func buildInvalidObjectName() string {
 	}
 	return string(buf)
 }
+
+func TestBulkUploadFallback(t *testing.T) {
+	capabilities.AllowBulkUpload = false
+	defer func() { capabilities.AllowBulkUpload = true }()
+
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		// upload to account level (including a container that does not exist yet),
+		// with a file that is too large to be buffered
+		newContainer := c.Account().Container(c.Name() + "-new")
+		largeContent := bytes.Repeat([]byte("x"), 5<<20)
+		archive := buildTarArchive(map[string][]byte{
+			c.Object("file1").FullName():            []byte("hello"),
+			c.Object("dir/file2").FullName():        []byte("world"),
+			c.Object("large").FullName():            largeContent,
+			newContainer.Object("file3").FullName(): []byte("!"),
+		})
+		var buf bytes.Buffer
+		gzw := gzip.NewWriter(&buf)
+		_, err := gzw.Write(archive)
+		expectSuccess(t, err)
+		expectSuccess(t, gzw.Close())
+
+		n, err := c.Account().BulkUpload(ctx, "", schwift.BulkUploadTarGzip, &buf, nil)
+		expectSuccess(t, err)
+		expectInt(t, n, 4)
+		expectObjectContent(t, c.Object("file1"), []byte("hello"))
+		expectObjectContent(t, c.Object("dir/file2"), []byte("world"))
+		expectObjectContent(t, c.Object("large"), largeContent)
+		expectObjectContent(t, newContainer.Object("file3"), []byte("!"))
+		_, _, err = c.Account().BulkDelete(ctx, []*schwift.Object{newContainer.Object("file3")}, []*schwift.Container{newContainer}, nil)
+		expectSuccess(t, err)
+
+		// upload below a pseudo-directory in a container
+		archive = buildTarArchive(map[string][]byte{"file4": []byte("foo")})
+		n, err = c.Account().BulkUpload(ctx, c.Name()+"/sub/dir", schwift.BulkUploadTar, bytes.NewReader(archive), nil)
+		expectSuccess(t, err)
+		expectInt(t, n, 1)
+		expectObjectContent(t, c.Object("sub/dir/file4"), []byte("foo"))
+
+		// errors are reported like by the server-side implementation
+		n, err = c.Account().BulkUpload(ctx, c.Name(), schwift.BulkUploadTar,
+			strings.NewReader("This is not the TAR archive you're looking for."), nil)
+		expectInt(t, n, 0)
+		expectError(t, err, "400 Bad Request: Invalid Tar File: truncated header")
+
+		archive = buildTarArchive(map[string][]byte{
+			buildInvalidObjectName(): []byte("hello"),
+			"file5":                  []byte("world"),
+		})
+		n, err = c.Account().BulkUpload(ctx, c.Name(), schwift.BulkUploadTar, bytes.NewReader(archive), nil)
+		expectInt(t, n, 1)
+		expectError(t, err, "400 Bad Request (+1 object errors)")
+		bulkErr, _ := errext.As[schwift.BulkError](err)
+		expectInt(t, len(bulkErr.ObjectErrors), 1)
+		expectString(t, bulkErr.ObjectErrors[0].ContainerName, c.Name())
+		expectInt(t, bulkErr.ObjectErrors[0].StatusCode, 400)
+		expectObjectContent(t, c.Object("file5"), []byte("world"))
+	})
+}
+
+func TestBulkUploadZip(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		_, err := zw.Create("dir/")
+		expectSuccess(t, err)
+		for name, content := range map[string]string{"dir/file1": "hello", "file2": "world"} {
+			w, err := zw.Create(name)
+			expectSuccess(t, err)
+			_, err = w.Write([]byte(content))
+			expectSuccess(t, err)
+		}
+		expectSuccess(t, zw.Close())
+
+		// with random access to the archive
+		n, err := c.Account().BulkUpload(ctx, c.Name()+"/a", schwift.BulkUploadZip, bytes.NewReader(buf.Bytes()), nil)
+		expectSuccess(t, err)
+		expectInt(t, n, 2)
+		expectObjectContent(t, c.Object("a/dir/file1"), []byte("hello"))
+		expectObjectContent(t, c.Object("a/file2"), []byte("world"))
+
+		// without random access (the archive is spooled to disk)
+		n, err = c.Account().BulkUpload(ctx, c.Name()+"/b", schwift.BulkUploadZip, io.MultiReader(bytes.NewReader(buf.Bytes())), nil)
+		expectSuccess(t, err)
+		expectInt(t, n, 2)
+		expectObjectContent(t, c.Object("b/dir/file1"), []byte("hello"))
+		expectObjectContent(t, c.Object("b/file2"), []byte("world"))
+
+		// invalid archive
+		n, err = c.Account().BulkUpload(ctx, c.Name(), schwift.BulkUploadZip, strings.NewReader("not a zip file"), nil)
+		expectInt(t, n, 0)
+		expectError(t, err, "400 Bad Request: Invalid Zip File: zip: not a valid zip file")
+	})
+}
@@ -21,6 +21,7 @@ package tests
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/majewsky/schwift/v2"
@@ -123,6 +124,58 @@ func TestContainerIterator(t *testing.T) {
 		expectSuccess(t, err)
 		expectContainerInfos(t, cis, cname(1), cname(2), cname(3), cname(4))
 
+		// if the server reports storage policies in the listing, they can be
+		// resolved through the capabilities
+		caps, err := a.Capabilities(context.TODO())
+		expectSuccess(t, err)
+		for _, ci := range cis {
+			if ci.StoragePolicy == "" {
+				continue
+			}
+			_, ok := caps.StoragePolicy(ci.StoragePolicy)
+			expectBool(t, ok, true)
+		}
+
+		// test detailed iteration with fallback formats
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-listing"
+		iter.Format = schwift.ListingFormatXML
+		cis, err = iter.NextPageDetailed(context.TODO(), 3)
+		expectSuccess(t, err)
+		expectContainerInfos(t, cis, cname(1), cname(2), cname(3))
+		cis, err = iter.CollectDetailed(context.TODO())
+		expectSuccess(t, err)
+		expectContainerInfos(t, cis, cname(4))
+
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-listing"
+		iter.Format = schwift.ListingFormatPlain
+		cis, err = iter.CollectDetailed(context.TODO())
+		expectSuccess(t, err)
+		expectInt(t, len(cis), 4)
+		for idx, ci := range cis {
+			expectString(t, ci.Container.Name(), cname(idx+1))
+			expectBool(t, ci.LastModified.IsZero(), true)
+		}
+
+		// test client-side filtering (the first page is filtered out entirely)
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-listing"
+		iter.Match = regexp.MustCompile(`[34]$`)
+		iter.Exclude = regexp.MustCompile(`4$`)
+		cs, err = iter.NextPage(context.TODO(), 2)
+		expectSuccess(t, err)
+		expectContainerNames(t, cs, cname(3))
+		cs, err = iter.NextPage(context.TODO(), 2)
+		expectSuccess(t, err)
+		expectContainerNames(t, cs)
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-listing"
+		iter.Exclude = regexp.MustCompile(`[12]$`)
+		cis, err = iter.CollectDetailed(context.TODO())
+		expectSuccess(t, err)
+		expectContainerInfos(t, cis, cname(3), cname(4))
+
 		// cleanup
 		iter = a.Containers()
 		iter.Prefix = "schwift-test-listing"
@@ -132,6 +185,67 @@ func TestContainerIterator(t *testing.T) {
 	})
 }
 
+func TestContainerIteratorCollectWhere(t *testing.T) {
+	testWithAccount(t, func(a *schwift.Account) {
+		ctx := context.TODO()
+		cname := func(idx int) string {
+			return fmt.Sprintf("schwift-test-filter%d", idx)
+		}
+
+		// create test containers, of which the odd ones are managed by "app1"
+		for idx := 1; idx <= 4; idx++ {
+			hdr := schwift.NewContainerHeaders()
+			if idx%2 == 1 {
+				hdr.ManagedBy().Set("app1")
+			}
+			expectSuccess(t, a.Container(cname(idx)).Create(ctx, hdr.ToOpts()))
+		}
+
+		iter := a.Containers()
+		iter.Prefix = "schwift-test-filter"
+		cache := schwift.NewContainerHeaderCache()
+		opts := &schwift.ContainerFilterOptions{Concurrency: 2, Cache: cache}
+		cs, err := iter.CollectWhere(ctx, schwift.MetadataEquals("Managed-By", "app1"), opts)
+		expectSuccess(t, err)
+		expectContainerNames(t, cs, cname(1), cname(3))
+
+		// the returned containers have their headers loaded already
+		requestCountBefore := a.Backend().(*RequestCountingBackend).Count
+		hdr, err := cs[0].Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.ManagedBy().Get(), "app1")
+
+		// a second scan with the same cache only needs the listing requests
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-filter"
+		_, err = iter.Collect(ctx)
+		expectSuccess(t, err)
+		listingRequestCount := a.Backend().(*RequestCountingBackend).Count - requestCountBefore
+		requestCountBefore = a.Backend().(*RequestCountingBackend).Count
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-filter"
+		cs, err = iter.CollectWhere(ctx, schwift.MetadataEquals("Managed-By", ""), opts)
+		expectSuccess(t, err)
+		expectContainerNames(t, cs, cname(2), cname(4))
+		expectInt(t, a.Backend().(*RequestCountingBackend).Count-requestCountBefore, listingRequestCount)
+
+		// filters on the iterator are applied before HEADing
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-filter"
+		iter.Exclude = regexp.MustCompile(`1$`)
+		cs, err = iter.CollectWhere(ctx, schwift.MetadataEquals("Managed-By", "app1"), nil)
+		expectSuccess(t, err)
+		expectContainerNames(t, cs, cname(3))
+
+		// cleanup
+		iter = a.Containers()
+		iter.Prefix = "schwift-test-filter"
+		expectSuccess(t, iter.Foreach(ctx, func(c *schwift.Container) error {
+			return c.Delete(ctx, nil)
+		}))
+	})
+}
+
 func expectAccountHeadersCached(t *testing.T, a *schwift.Account) {
 	requestCountBefore := a.Backend().(*RequestCountingBackend).Count
 	_, err := a.Headers(context.TODO())
@@ -20,9 +20,12 @@ package tests
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/majewsky/schwift/v2"
 )
@@ -84,6 +87,62 @@ func TestContainerUpdate(t *testing.T) {
 	})
 }
 
+func TestContainerRemoveHeaders(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		hdr := schwift.NewContainerHeaders()
+		hdr.Metadata().Set("Foo", "bar")
+		hdr.Metadata().Set("Qux", "zap")
+		hdr.ObjectCountQuota().Set(23)
+		expectSuccess(t, c.Update(context.TODO(), hdr, nil))
+
+		hdr = schwift.NewContainerHeaders()
+		hdr.Metadata().Remove("Foo")
+		hdr.ObjectCountQuota().Remove()
+		expectSuccess(t, c.Update(context.TODO(), hdr, nil))
+
+		hdr, err := c.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.Metadata().Get("Foo"), "")
+		expectString(t, hdr.Metadata().Get("Qux"), "zap")
+		expectBool(t, hdr.ObjectCountQuota().Exists(), false)
+	})
+}
+
+func TestContainerCORS(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		err := c.ConfigureCORS(context.TODO(),
+			[]string{"https://example.com", "https://example.org"},
+			10*time.Minute,
+			[]string{"X-Object-Meta-Color"},
+			nil,
+		)
+		expectSuccess(t, err)
+
+		hdr, err := c.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.CORSAllowedOrigins().Get(), "https://example.com https://example.org")
+		expectString(t, hdr.CORSExposedHeaders().Get(), "X-Object-Meta-Color")
+		expectInt64(t, int64(hdr.CORSMaxAge().Get()), int64(10*time.Minute))
+
+		result := hdr.SimulateCORSPreflight("https://example.org", http.MethodPut, []string{"Content-Type", "X-Object-Meta-Color"})
+		expectBool(t, result.Allowed, true)
+		expectString(t, result.AllowOrigin, "https://example.org")
+		expectString(t, strings.Join(result.AllowHeaders, ","), "Content-Type,X-Object-Meta-Color")
+		expectInt64(t, int64(result.MaxAge), int64(10*time.Minute))
+		expectBool(t, hdr.SimulateCORSPreflight("https://example.net", http.MethodGet, nil).Allowed, false)
+		expectBool(t, hdr.SimulateCORSPreflight("https://example.org", "PATCH", nil).Allowed, false)
+
+		// empty values remove the respective settings
+		expectSuccess(t, c.ConfigureCORS(context.TODO(), []string{"*"}, 0, nil, nil))
+		hdr, err = c.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.CORSAllowedOrigins().Get(), "*")
+		expectBool(t, hdr.CORSExposedHeaders().Exists(), false)
+		expectBool(t, hdr.CORSMaxAge().Exists(), false)
+		expectString(t, hdr.SimulateCORSPreflight("https://example.net", http.MethodGet, nil).AllowOrigin, "*")
+	})
+}
+
 func expectContainerExistence(t *testing.T, c *schwift.Container, expectedExists bool) {
 	t.Helper()
 	c.Invalidate()
@@ -91,3 +150,191 @@ func expectContainerExistence(t *testing.T, c *schwift.Container, expectedExists
 	expectSuccess(t, err)
 	expectBool(t, actualExists, expectedExists)
 }
+
+func TestContainerCreateIfNotExists(t *testing.T) {
+	testWithAccount(t, func(a *schwift.Account) {
+		c := a.Container(getRandomName())
+
+		hdr := schwift.NewContainerHeaders()
+		hdr.ObjectCountQuota().Set(23)
+		hdr.Metadata().Set("Owner", "alice")
+
+		// first call creates the container with all headers
+		changed, err := c.CreateIfNotExists(context.TODO(), hdr, nil)
+		expectSuccess(t, err)
+		expectHeaders(t, changed.Headers, hdr.Headers)
+		expectContainerExistence(t, c, true)
+
+		// second call does not change anything
+		changed, err = c.CreateIfNotExists(context.TODO(), hdr, nil)
+		expectSuccess(t, err)
+		expectHeaders(t, changed.Headers, map[string]string{})
+
+		// third call only sends the differing headers
+		hdr.Metadata().Set("Owner", "bob")
+		changed, err = c.CreateIfNotExists(context.TODO(), hdr, nil)
+		expectSuccess(t, err)
+		expectHeaders(t, changed.Headers, map[string]string{"X-Container-Meta-Owner": "bob"})
+
+		newHdr, err := c.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, newHdr.Metadata().Get("Owner"), "bob")
+		expectUint64(t, newHdr.ObjectCountQuota().Get(), 23)
+
+		expectSuccess(t, c.Delete(context.TODO(), nil))
+	})
+}
+
+func TestContainerOwnership(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		a := c.Account()
+
+		// a new container gets all ownership metadata
+		c2 := a.Container(c.Name() + "-managed")
+		ownership := schwift.ContainerOwnership{ManagedBy: "app1", Owner: "team1", CreatedBy: "job1"}
+		_, err := c2.EnsureManagedBy(ctx, ownership, nil)
+		expectSuccess(t, err)
+		hdr, err := c2.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, fmt.Sprint(hdr.Ownership()), "{app1 team1 job1}")
+
+		// the existing unmanaged container is claimed, but CreatedBy is not set
+		ownership.Owner = "team2"
+		_, err = c.EnsureManagedBy(ctx, ownership, nil)
+		expectSuccess(t, err)
+		hdr, err = c.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, fmt.Sprint(hdr.Ownership()), "{app1 team2 }")
+
+		// other applications cannot claim these containers
+		_, err = c2.EnsureManagedBy(ctx, schwift.ContainerOwnership{ManagedBy: "app2"}, nil)
+		expectError(t, err, fmt.Sprintf(`container %q is managed by "app1", not by "app2"`, c2.Name()))
+		expectSuccess(t, c2.CheckManagedBy(ctx, "app1"))
+		expectError(t, c2.CheckManagedBy(ctx, "app2"), fmt.Sprintf(`container %q is managed by "app1", not by "app2"`, c2.Name()))
+
+		// listing
+		containers, err := a.ContainersManagedBy(ctx, "app1")
+		expectSuccess(t, err)
+		expectContainerNames(t, containers, c.Name(), c2.Name())
+		containers, err = a.ContainersManagedBy(ctx, "app2")
+		expectSuccess(t, err)
+		expectContainerNames(t, containers)
+
+		expectSuccess(t, c2.Delete(ctx, nil))
+	})
+}
+
+func TestContainerSnapshot(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		// populate source container with a plain object, a large object and an
+		// object that will vanish during the snapshot
+		expectSuccess(t, c.Object("plain").Upload(ctx, strings.NewReader("hello"), nil, nil))
+		expectSuccess(t, c.Object("doomed").Upload(ctx, strings.NewReader("bye"), nil, nil))
+		lo, err := c.Object("large").AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader("abcdefgh"), 4, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+		hb := &HookBackend{Inner: c.Account().Backend()}
+		hb.BeforeRequest = func(req *http.Request) {
+			if req.Method == "COPY" && strings.HasSuffix(req.URL.Path, "/doomed") {
+				expectSuccess(t, c.Object("doomed").Delete(ctx, nil, nil))
+			}
+		}
+		a, err := schwift.InitializeAccount(hb)
+		expectSuccess(t, err)
+		target := c.Account().Container(getRandomName())
+		_, err = target.EnsureExists(ctx)
+		expectSuccess(t, err)
+
+		result, err := a.Container(c.Name()).SnapshotTo(ctx, target, &schwift.SnapshotOptions{
+			Prefix:      "snap/",
+			Concurrency: 1,
+		})
+		expectSuccess(t, err)
+		expectInt(t, result.ObjectCount, 4) // plain, large and two segments
+		expectString(t, strings.Join(result.VanishedObjects, ","), "doomed")
+
+		expectObjectContent(t, target.Object("snap/plain"), []byte("hello"))
+		expectObjectContent(t, target.Object("snap/large"), []byte("abcdefgh"))
+		hdr, err := target.Object("snap/large").Headers(ctx)
+		expectSuccess(t, err)
+		expectBool(t, hdr.IsStaticLargeObject(), true)
+		expectObjectExistence(t, target.Object("snap/doomed"), false)
+
+		// the marker object contains the result
+		buf, err := target.Object(schwift.SnapshotMarkerName("snap/")).Download(ctx, nil).AsByteSlice()
+		expectSuccess(t, err)
+		var marker schwift.SnapshotResult
+		expectSuccess(t, json.Unmarshal(buf, &marker))
+		expectString(t, marker.Source, c.Name())
+		expectInt(t, marker.ObjectCount, 4)
+		expectString(t, strings.Join(marker.VanishedObjects, ","), "doomed")
+
+		// cleanup
+		expectSuccess(t, target.Objects().Foreach(ctx, func(o *schwift.Object) error {
+			return o.Delete(ctx, nil, nil)
+		}))
+		expectSuccess(t, target.Delete(ctx, nil))
+	})
+}
+
+func TestContainerCloneShallow(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		expectSuccess(t, c.Object("a").Upload(ctx, strings.NewReader("alpha"), nil, nil))
+		expectSuccess(t, c.Object("b").Upload(ctx, strings.NewReader("beta"), nil, nil))
+		expectSuccess(t, c.Object("link").SymlinkTo(ctx, c.Object("a"), nil, nil))
+
+		branch := c.Account().Container(getRandomName())
+		_, err := branch.EnsureExists(ctx)
+		expectSuccess(t, err)
+
+		_, err = c.CloneShallow(ctx, c, nil)
+		expectError(t, err, "cannot clone a container into itself")
+
+		count, err := c.CloneShallow(ctx, branch, &schwift.CloneOptions{Concurrency: 2})
+		expectSuccess(t, err)
+		expectInt(t, count, 3)
+		expectObjectContent(t, branch.Object("a"), []byte("alpha"))
+		expectObjectContent(t, branch.Object("link"), []byte("alpha"))
+
+		// the clone of a symlink points to the original target, not to the symlink
+		_, target, err := branch.Object("link").SymlinkHeaders(ctx)
+		expectSuccess(t, err)
+		expectString(t, target.FullName(), c.Name()+"/a")
+
+		// changes to the original are visible through the clone until it is materialized
+		expectSuccess(t, c.Object("a").Upload(ctx, strings.NewReader("alpha2"), nil, nil))
+		expectObjectContent(t, branch.Object("a"), []byte("alpha2"))
+		ok, err := branch.Object("a").Materialize(ctx, nil)
+		expectSuccess(t, err)
+		expectBool(t, ok, true)
+		ok, err = branch.Object("a").Materialize(ctx, nil)
+		expectSuccess(t, err)
+		expectBool(t, ok, false)
+		expectSuccess(t, c.Object("a").Upload(ctx, strings.NewReader("alpha3"), nil, nil))
+		expectObjectContent(t, branch.Object("a"), []byte("alpha2"))
+
+		// materialize the rest of the branch, with one dangling symlink
+		expectSuccess(t, c.Object("b").Delete(ctx, nil, nil))
+		result, err := branch.Materialize(ctx, "", nil)
+		expectSuccess(t, err)
+		expectInt(t, result.ObjectCount, 1)
+		expectString(t, strings.Join(result.DanglingSymlinks, ","), "b")
+		expectSuccess(t, c.Object("a").Upload(ctx, strings.NewReader("alpha4"), nil, nil))
+		expectObjectContent(t, branch.Object("link"), []byte("alpha3"))
+
+		// cleanup
+		expectSuccess(t, branch.Objects().Foreach(ctx, func(o *schwift.Object) error {
+			return o.Delete(ctx, nil, nil)
+		}))
+		expectSuccess(t, branch.Delete(ctx, nil))
+	})
+}
@@ -0,0 +1,192 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/crawler"
+	"github.com/majewsky/schwift/v2/replicate"
+)
+
+func TestAccountCrawler(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		a := c.Account()
+		c2, err := a.Container(c.Name() + "-2").EnsureExists(ctx)
+		expectSuccess(t, err)
+		for _, name := range []string{"a/1", "a/2", "b/1"} {
+			expectSuccess(t, c.Object(name).Upload(ctx, strings.NewReader("example"), nil, nil))
+			expectSuccess(t, c2.Object(name).Upload(ctx, strings.NewReader("example content"), nil, nil))
+		}
+
+		// count and verify all objects in one crawl
+		counter := &crawler.Counter{}
+		verifier := &crawler.Verifier{}
+		var exported bytes.Buffer
+		ac := crawler.AccountCrawler{
+			Account:          a,
+			Visitor:          crawler.Chain(counter, verifier, crawler.NewExporter(&exported)),
+			ContainerPrefix:  c.Name(),
+			Concurrency:      2,
+			ObjectsPerSecond: 1000,
+		}
+		result, err := ac.Run(ctx)
+		expectSuccess(t, err)
+		expectString(t, fmt.Sprintf("%#v", result), "crawler.Result{ContainersVisited:0x2, ObjectsVisited:0x6, BytesVisited:0x42, Complete:true}")
+		expectString(t, fmt.Sprint(counter.Usage()), fmt.Sprintf("map[%s:{3 21} %s:{3 45}]", c.Name(), c2.Name()))
+		expectInt(t, len(verifier.Corrupted()), 0)
+		expectInt(t, strings.Count(exported.String(), "\n"), 6)
+		expectBool(t, strings.Contains(exported.String(), fmt.Sprintf(`{"container":%q,"name":"b/1","bytes":15,`, c2.Name())), true)
+
+		// a time-bounded crawl stops early, and resumes from the checkpoint
+		ac.Visitor = crawler.Deleter{}
+		ac.ObjectPrefix = "a/"
+		ac.Checkpoint = &replicate.FileCheckpoint{Path: filepath.Join(t.TempDir(), "checkpoint.json")}
+		ac.MaxDuration = time.Nanosecond
+		result, err = ac.Run(ctx)
+		expectSuccess(t, err)
+		expectBool(t, result.Complete, false)
+		expectObjectExistence(t, c.Object("a/1"), true)
+
+		ac.MaxDuration = 0
+		result, err = ac.Run(ctx)
+		expectSuccess(t, err)
+		expectBool(t, result.Complete, true)
+		expectInt(t, int(result.ObjectsVisited), 4)
+		expectObjectExistence(t, c.Object("a/1"), false)
+		expectObjectExistence(t, c2.Object("a/2"), false)
+		expectObjectExistence(t, c2.Object("b/1"), true)
+
+		// objects recorded in the checkpoint are not visited again
+		expectSuccess(t, c.Object("a/1").Upload(ctx, strings.NewReader("example"), nil, nil))
+		result, err = ac.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, int(result.ObjectsVisited), 0)
+		expectObjectExistence(t, c.Object("a/1"), true)
+
+		// visitor errors abort the crawl
+		ac.Checkpoint = nil
+		ac.Concurrency = 1
+		ac.ObjectPrefix = ""
+		ac.Visitor = crawler.VisitorFunc(func(ctx context.Context, info schwift.ObjectInfo) error {
+			return fmt.Errorf("cannot visit %s", info.Object.FullName())
+		})
+		_, err = ac.Run(ctx)
+		expectError(t, err, fmt.Sprintf("cannot visit %s/a/1", c.Name()))
+
+		expectSuccess(t, c2.Object("b/1").Delete(ctx, nil, nil))
+		expectSuccess(t, c2.Delete(ctx, nil))
+	})
+}
+
+func TestScrub(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		for _, name := range []string{"healthy", "corrupted", "unreadable", "large"} {
+			expectSuccess(t, c.Object(name).Upload(ctx, strings.NewReader("example content"), nil, nil))
+		}
+
+		// simulate corruption on the way from the disk to the client
+		a, err := schwift.InitializeAccount(&HookBackend{
+			Inner: c.Account().Backend(),
+			AfterResponse: func(req *http.Request, resp *http.Response) {
+				switch {
+				case strings.HasSuffix(req.URL.Path, "/corrupted"):
+					resp.Body = io.NopCloser(strings.NewReader("exampl3 content"))
+				case strings.HasSuffix(req.URL.Path, "/unreadable"):
+					resp.Body = io.NopCloser(io.MultiReader(strings.NewReader("exam"), iotest.ErrReader(errors.New("simulated read error"))))
+				}
+			},
+		})
+		expectSuccess(t, err)
+
+		result, err := crawler.Scrub(ctx, a.Container(c.Name()), "", &crawler.ScrubOptions{
+			BytesPerSecond: 1 << 20,
+		})
+		expectSuccess(t, err)
+		expectBool(t, result.Complete, true)
+		expectUint64(t, result.ObjectsVisited, 4)
+		expectUint64(t, result.BytesRead, 15*3+4)
+		expectInt(t, len(result.Findings), 2)
+		for _, finding := range result.Findings {
+			expectBool(t, finding.TransactionID != "", true)
+			switch finding.Object.Object.Name() {
+			case "corrupted":
+				expectString(t, finding.ExpectedEtag, etagOfString("example content"))
+				expectString(t, finding.ActualEtag, etagOfString("exampl3 content"))
+			case "unreadable":
+				expectError(t, finding.Err, "simulated read error")
+			default:
+				t.Errorf("unexpected finding: %s", finding.String())
+			}
+		}
+
+		// sampling only reads a range of each object
+		result, err = crawler.Scrub(ctx, c, "h", &crawler.ScrubOptions{SampleBytes: 4})
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsVisited, 1)
+		expectUint64(t, result.ObjectsSampled, 1)
+		expectUint64(t, result.BytesRead, 4)
+		expectInt(t, len(result.Findings), 0)
+
+		// when the server ignores the Range header, the entire object is checked
+		a, err = schwift.InitializeAccount(&HookBackend{
+			Inner: c.Account().Backend(),
+			BeforeRequest: func(req *http.Request) {
+				req.Header.Del("Range")
+			},
+		})
+		expectSuccess(t, err)
+		result, err = crawler.Scrub(ctx, a.Container(c.Name()), "h", &crawler.ScrubOptions{SampleBytes: 4})
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsVisited, 1)
+		expectUint64(t, result.ObjectsSampled, 0)
+		expectUint64(t, result.BytesRead, 15)
+		expectInt(t, len(result.Findings), 0)
+
+		// static large objects are skipped (their segments are scrubbed as
+		// regular objects)
+		lo, err := c.Object("slo").AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "slo-segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader("example content"), 8, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+		result, err = crawler.Scrub(ctx, c, "slo", &crawler.ScrubOptions{SampleBytes: 4})
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsVisited, 3)
+		expectUint64(t, result.ObjectsSampled, 2)
+		expectUint64(t, result.BytesRead, 8)
+		expectInt(t, len(result.Findings), 0)
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/majewsky/schwift/v2"
 )
@@ -57,6 +58,29 @@ func TestFieldString(t *testing.T) {
 	expectHeaders(t, hdr.Headers, map[string]string{
 		"X-Account-Meta-Temp-Url-Key": "",
 	})
+
+	// Remove() uses the X-Remove-* form where available...
+	hdr.TempURLKey().Remove()
+	expectHeaders(t, hdr.Headers, map[string]string{
+		"X-Remove-Account-Meta-Temp-Url-Key": "1",
+	})
+	expectBool(t, hdr.TempURLKey().Exists(), false)
+	hdr.TempURLKey().Set("baz")
+	expectHeaders(t, hdr.Headers, map[string]string{
+		"X-Account-Meta-Temp-Url-Key": "baz",
+	})
+	hdr.TempURLKey().Remove()
+	hdr.TempURLKey().Del()
+	expectHeaders(t, hdr.Headers, nil)
+
+	// ...and falls back to Clear() otherwise
+	ohdr := schwift.NewObjectHeaders()
+	ohdr.ContentDisposition().Remove()
+	ohdr.Metadata().Remove("Foo")
+	expectHeaders(t, ohdr.Headers, map[string]string{
+		"Content-Disposition": "",
+		"X-Object-Meta-Foo":   "",
+	})
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -166,3 +190,55 @@ func TestFieldUint64Readonly(t *testing.T) {
 	expectUint64(t, hdr.BytesUsed().Get(), 0)
 	expectError(t, hdr.Validate(), `Bad header X-Account-Bytes-Used: strconv.ParseUint: parsing "-23": invalid syntax`)
 }
+
+func TestFieldBool(t *testing.T) {
+	hdr := schwift.NewContainerHeaders()
+	expectBool(t, hdr.VersionsEnabled().Exists(), false)
+	expectBool(t, hdr.VersionsEnabled().Get(), false)
+	expectSuccess(t, hdr.Validate())
+
+	hdr.Headers["X-Versions-Enabled"] = "True"
+	expectBool(t, hdr.VersionsEnabled().Exists(), true)
+	expectBool(t, hdr.VersionsEnabled().Get(), true)
+	expectSuccess(t, hdr.Validate())
+
+	hdr.Headers["X-Versions-Enabled"] = "maybe"
+	expectBool(t, hdr.VersionsEnabled().Exists(), true)
+	expectBool(t, hdr.VersionsEnabled().Get(), false)
+	expectError(t, hdr.Validate(), `Bad header X-Versions-Enabled: strconv.ParseBool: parsing "maybe": invalid syntax`)
+
+	hdr.VersionsEnabled().Set(false)
+	expectHeaders(t, hdr.Headers, map[string]string{
+		"X-Versions-Enabled": "false",
+	})
+	hdr.VersionsEnabled().Clear()
+	expectHeaders(t, hdr.Headers, map[string]string{
+		"X-Versions-Enabled": "",
+	})
+	hdr.VersionsEnabled().Del()
+	expectHeaders(t, hdr.Headers, nil)
+}
+
+func TestFieldDuration(t *testing.T) {
+	hdr := schwift.NewObjectHeaders()
+	expectBool(t, hdr.ExpiresAfter().Exists(), false)
+	expectInt64(t, int64(hdr.ExpiresAfter().Get()), 0)
+	expectSuccess(t, hdr.Validate())
+
+	hdr.Headers["X-Delete-After"] = "3600"
+	expectBool(t, hdr.ExpiresAfter().Exists(), true)
+	expectInt64(t, int64(hdr.ExpiresAfter().Get()), int64(time.Hour))
+	expectSuccess(t, hdr.Validate())
+
+	hdr.Headers["X-Delete-After"] = "1.5"
+	expectBool(t, hdr.ExpiresAfter().Exists(), true)
+	expectInt64(t, int64(hdr.ExpiresAfter().Get()), 0)
+	expectError(t, hdr.Validate(), `Bad header X-Delete-After: strconv.ParseInt: parsing "1.5": invalid syntax`)
+
+	hdr.ExpiresAfter().Set(90*time.Second + 500*time.Millisecond)
+	expectHeaders(t, hdr.Headers, map[string]string{
+		"X-Delete-After": "90",
+	})
+	hdr.ExpiresAfter().Del()
+	expectHeaders(t, hdr.Headers, nil)
+}
@@ -0,0 +1,119 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestDirectoryFS(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		for _, name := range []string{"a.txt", "sub/b.txt", "sub/deeper/c.txt", "other/d.txt"} {
+			err := c.Object(name).Upload(context.TODO(), bytes.NewReader([]byte("content of "+name)), nil, nil)
+			expectSuccess(t, err)
+		}
+		expectSuccess(t, c.Dir("empty").EnsureMarker(context.TODO()))
+
+		fsys := c.Dir("").FS(context.TODO())
+		expectSuccess(t, fstest.TestFS(fsys, "a.txt", "sub/b.txt", "sub/deeper/c.txt", "other/d.txt", "empty"))
+
+		entries, err := fsys.ReadDir("sub")
+		expectSuccess(t, err)
+		expectInt(t, len(entries), 2)
+		expectString(t, entries[0].Name(), "b.txt")
+		expectBool(t, entries[0].IsDir(), false)
+		expectString(t, entries[1].Name(), "deeper")
+		expectBool(t, entries[1].IsDir(), true)
+
+		// test ranged reads
+		f, err := c.Dir("sub").FS(context.TODO()).Open("deeper/c.txt")
+		expectSuccess(t, err)
+		buf := make([]byte, 7)
+		n, err := f.(io.ReaderAt).ReadAt(buf, 11)
+		expectSuccess(t, err)
+		expectString(t, string(buf[:n]), "sub/dee")
+		expectSuccess(t, f.Close())
+
+		_, err = fsys.Stat("does/not/exist")
+		expectBool(t, errors.Is(err, fs.ErrNotExist), true)
+
+		// ranged reads also work when the server ignores the Range header
+		hb := &HookBackend{Inner: c.Account().Backend()}
+		hb.BeforeRequest = func(req *http.Request) {
+			req.Header.Del("Range")
+		}
+		a, err := schwift.InitializeAccount(hb)
+		expectSuccess(t, err)
+		f, err = a.Container(c.Name()).Dir("sub").FS(context.TODO()).Open("deeper/c.txt")
+		expectSuccess(t, err)
+		n, err = f.(io.ReaderAt).ReadAt(buf, 11)
+		expectSuccess(t, err)
+		expectString(t, string(buf[:n]), "sub/dee")
+		expectSuccess(t, f.Close())
+	})
+}
+
+func TestUploadFS(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		fsys := fstest.MapFS{
+			"index.html":   {Data: []byte("<html></html>")},
+			"css/site.css": {Data: []byte("body { color: red; }")},
+			"README":       {Data: []byte("hello world")},
+		}
+
+		// initial upload
+		result, err := c.UploadFS(context.TODO(), fsys, "static/", nil)
+		expectSuccess(t, err)
+		expectInt(t, result.FilesUploaded, 3)
+		expectObjectContent(t, c.Object("static/css/site.css"), []byte("body { color: red; }"))
+		for name, contentType := range map[string]string{
+			"static/index.html":   "text/html; charset=utf-8",
+			"static/css/site.css": "text/css; charset=utf-8",
+			"static/README":       "text/plain; charset=utf-8",
+		} {
+			hdr, err := c.Object(name).Headers(context.TODO())
+			expectSuccess(t, err)
+			expectString(t, hdr.ContentType().Get(), contentType)
+		}
+
+		// unchanged files are skipped
+		fsys["index.html"] = &fstest.MapFile{Data: []byte("<html>v2</html>")}
+		opts := &schwift.UploadFSOptions{SkipUnchanged: true, DeleteMissing: true}
+		expectSuccess(t, c.Object("static/obsolete.js").Upload(context.TODO(), strings.NewReader("//"), nil, nil))
+		expectSuccess(t, c.Object("other/file.txt").Upload(context.TODO(), strings.NewReader("foo"), nil, nil))
+		result, err = c.UploadFS(context.TODO(), fsys, "static/", opts)
+		expectSuccess(t, err)
+		expectInt(t, result.FilesUploaded, 1)
+		expectInt(t, result.FilesSkipped, 2)
+		expectInt(t, result.ObjectsDeleted, 1)
+		expectObjectContent(t, c.Object("static/index.html"), []byte("<html>v2</html>"))
+		expectObjectExistence(t, c.Object("static/obsolete.js"), false)
+		expectObjectExistence(t, c.Object("other/file.txt"), true)
+	})
+}
@@ -0,0 +1,87 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestObjectHold(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		enforcing := c.Account().WithHoldEnforcement().Container(c.Name())
+
+		obj := enforcing.Object("evidence")
+		expectSuccess(t, obj.Upload(ctx, strings.NewReader("hello"), nil, nil))
+		expectSuccess(t, obj.SetHold(ctx, "case 42", nil))
+		hdr, err := obj.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.LegalHold().Get(), "case 42")
+
+		expectOnHold := func(err error) {
+			t.Helper()
+			var ohe schwift.ObjectOnHoldError
+			if !errors.As(err, &ohe) {
+				t.Errorf("expected ObjectOnHoldError, got %v", err)
+				return
+			}
+			expectString(t, ohe.Target, c.Name()+"/evidence")
+			expectString(t, ohe.Reason, "case 42")
+			expectBool(t, errors.Is(err, schwift.ErrObjectOnHold), true)
+		}
+
+		// modifications of the held object are refused
+		expectOnHold(obj.Upload(ctx, strings.NewReader("world"), nil, nil))
+		expectOnHold(obj.Delete(ctx, nil, nil))
+		expectOnHold(obj.Update(ctx, schwift.NewObjectHeaders(), nil))
+		expectOnHold(enforcing.Object("other").CopyTo(ctx, obj, nil, nil))
+		expectObjectContent(t, obj, []byte("hello"))
+
+		// metadata updates that keep the hold are permitted
+		hdr = schwift.NewObjectHeaders()
+		hdr.Metadata().Set("Color", "blue")
+		expectSuccess(t, obj.UpdatePreservingMetadata(ctx, hdr, nil))
+
+		// copies inherit the hold along with the other metadata
+		expectSuccess(t, obj.CopyTo(ctx, enforcing.Object("copy"), nil, nil))
+		err = enforcing.Object("copy").Delete(ctx, nil, nil)
+		expectBool(t, errors.Is(err, schwift.ErrObjectOnHold), true)
+		expectSuccess(t, enforcing.Object("copy").Delete(ctx, nil, &schwift.RequestOptions{OverrideHold: true}))
+
+		// accounts without hold enforcement do not check holds (and since a PUT
+		// replaces all metadata, this also removes the hold)
+		plainObj := c.Object("evidence")
+		expectSuccess(t, plainObj.Upload(ctx, strings.NewReader("world"), nil, nil))
+
+		// holds can be overridden or cleared
+		expectSuccess(t, obj.SetHold(ctx, "case 42", nil))
+		expectSuccess(t, obj.Upload(ctx, strings.NewReader("hello"), nil, &schwift.RequestOptions{OverrideHold: true}))
+		expectSuccess(t, obj.SetHold(ctx, "case 42", nil))
+		expectSuccess(t, obj.ClearHold(ctx, nil))
+		hdr, err = obj.Headers(ctx)
+		expectSuccess(t, err)
+		expectBool(t, hdr.LegalHold().Exists(), false)
+		expectSuccess(t, obj.Delete(ctx, nil, nil))
+	})
+}
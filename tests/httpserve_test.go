@@ -0,0 +1,71 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/httpserve"
+)
+
+func TestHTTPServe(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		obj := c.Object("public/hello.txt")
+		expectSuccess(t, obj.Upload(context.TODO(), bytes.NewReader([]byte("Hello World")), nil, nil))
+		hdr, err := obj.Headers(context.TODO())
+		expectSuccess(t, err)
+
+		h := &httpserve.Handler{Container: c, Prefix: "public/"}
+		serve := func(method, path string, reqHeaders map[string]string) (int, http.Header, string) {
+			req := httptest.NewRequest(method, path, http.NoBody)
+			for k, v := range reqHeaders {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			body, err := io.ReadAll(rec.Result().Body)
+			expectSuccess(t, err)
+			return rec.Code, rec.Result().Header, string(body)
+		}
+
+		code, respHeaders, body := serve(http.MethodGet, "/hello.txt", nil)
+		expectInt(t, code, http.StatusOK)
+		expectString(t, body, "Hello World")
+		expectString(t, respHeaders.Get("Etag"), hdr.Etag().Get())
+
+		code, _, body = serve(http.MethodGet, "/hello.txt", map[string]string{"Range": "bytes=6-"})
+		expectInt(t, code, http.StatusPartialContent)
+		expectString(t, body, "World")
+
+		code, _, _ = serve(http.MethodGet, "/hello.txt", map[string]string{"If-None-Match": hdr.Etag().Get()})
+		expectInt(t, code, http.StatusNotModified)
+
+		code, _, _ = serve(http.MethodGet, "/missing.txt", nil)
+		expectInt(t, code, http.StatusNotFound)
+
+		code, _, _ = serve(http.MethodPost, "/hello.txt", nil)
+		expectInt(t, code, http.StatusMethodNotAllowed)
+	})
+}
@@ -0,0 +1,76 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/inventory"
+)
+
+func TestInventoryExport(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("text/plain")
+		expectSuccess(t, c.Object("a/first").Upload(ctx, strings.NewReader("hello"), nil, hdr.ToOpts()))
+		expectSuccess(t, c.Object("b/second").Upload(ctx, strings.NewReader("world!"), nil, hdr.ToOpts()))
+
+		// CSV export of the whole container
+		var buf bytes.Buffer
+		expectSuccess(t, inventory.ExportContainer(ctx, &buf, c, nil))
+		rows, err := csv.NewReader(&buf).ReadAll()
+		expectSuccess(t, err)
+		expectInt(t, len(rows), 3)
+		expectString(t, strings.Join(rows[0], ","), "container,name,bytes,hash,content_type,last_modified")
+		expectString(t, strings.Join(rows[1][:5], ","), c.Name()+",a/first,5,"+etagOfString("hello")+",text/plain")
+		expectString(t, strings.Join(rows[2][:5], ","), c.Name()+",b/second,6,"+etagOfString("world!")+",text/plain")
+
+		// gzipped JSON-lines export of the account, filtered by prefix
+		buf.Reset()
+		expectSuccess(t, inventory.ExportAccount(ctx, &buf, c.Account(), &inventory.Options{
+			Format:          inventory.FormatJSONLines,
+			ContainerPrefix: c.Name(),
+			ObjectPrefix:    "b/",
+			Gzip:            true,
+		}))
+		gzr, err := gzip.NewReader(&buf)
+		expectSuccess(t, err)
+		dec := json.NewDecoder(gzr)
+		var records []map[string]any
+		for dec.More() {
+			var record map[string]any
+			expectSuccess(t, dec.Decode(&record))
+			records = append(records, record)
+		}
+		expectInt(t, len(records), 1)
+		expectString(t, records[0]["container"].(string), c.Name())
+		expectString(t, records[0]["name"].(string), "b/second")
+		expectFloat64(t, records[0]["bytes"].(float64), 6)
+		expectString(t, records[0]["hash"].(string), etagOfString("world!"))
+	})
+}
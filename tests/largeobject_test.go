@@ -21,12 +21,19 @@ package tests
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/internal/errext"
+	"github.com/majewsky/schwift/v2/schwifttest"
 )
 
 func foreachLargeObjectStrategy(action func(schwift.LargeObjectStrategy, string)) {
@@ -349,6 +356,173 @@ func TestSLOGuessSegmentPrefix(t *testing.T) {
 	})
 }
 
+func TestSLOExplicitSegmentLocation(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		obj := c.Object("largeobject")
+
+		// setup phase: create an SLO with two segments in "segments/" and one
+		// segment elsewhere
+		lo, err := obj.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(ctx, bytes.NewReader([]byte(getRandomSegmentContent(128))), 0, nil))
+		expectSuccess(t, lo.Append(ctx, bytes.NewReader([]byte(getRandomSegmentContent(128))), 0, nil))
+		foreign := c.Object("elsewhere/segment")
+		expectSuccess(t, foreign.Upload(ctx, bytes.NewReader([]byte(getRandomSegmentContent(128))), nil, nil))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Object: foreign}))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+		// explicit location skips the inference
+		lo, err = obj.AsLargeObjectWithOptions(ctx, &schwift.AsLargeObjectOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "explicit/",
+		})
+		expectSuccess(t, err)
+		expectString(t, lo.SegmentPrefix(), "explicit/")
+		expectString(t, lo.NextSegmentObject().Name(), "explicit/0000000000000001")
+
+		// in strict mode, segments outside the location are an error
+		_, err = obj.AsLargeObjectWithOptions(ctx, &schwift.AsLargeObjectOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+			Strict:           true,
+		})
+		expectError(t, err, `segment "`+c.Name()+`/elsewhere/segment" is located outside of the segment location "`+c.Name()+`/segments/"`)
+		if !errors.Is(err, schwift.ErrSegmentInvalid) {
+			t.Errorf("expected ErrSegmentInvalid, got %v", err)
+		}
+		lo, err = obj.AsLargeObjectWithOptions(ctx, &schwift.AsLargeObjectOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "",
+			Strict:           true,
+		})
+		expectSuccess(t, err)
+		expectInt(t, len(lo.SegmentObjects()), 3)
+	})
+}
+
+func TestLargeObjectLazySegments(t *testing.T) {
+	foreachLargeObjectStrategy(func(strategy schwift.LargeObjectStrategy, strategyStr string) {
+		testWithContainer(t, func(c *schwift.Container) {
+			ctx := context.TODO()
+
+			// setup phase: create a large object with two segments
+			lo, err := c.Object("largeobject").AsNewLargeObject(ctx, schwift.SegmentingOptions{
+				SegmentContainer: c,
+				SegmentPrefix:    "segments/",
+				Strategy:         strategy,
+			}, nil)
+			expectSuccess(t, err)
+			content := getRandomSegmentContent(128) + getRandomSegmentContent(128)
+			expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, nil))
+			expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+			// count the requests that enumerate the segments (the first page of the
+			// container listing for DLO, the manifest download for SLO)
+			fetchCount := 0
+			hb := &HookBackend{Inner: c.Account().Backend()}
+			hb.BeforeRequest = func(req *http.Request) {
+				query := req.URL.Query()
+				isListing := query.Get("prefix") == "segments/" && query.Get("marker") == ""
+				if req.Method == http.MethodGet && (isListing || query.Get("multipart-manifest") == "get") {
+					fetchCount++
+				}
+			}
+			a, err := schwift.InitializeAccount(hb)
+			expectSuccess(t, err)
+			obj := a.Container(c.Name()).Object("largeobject")
+			opts := &schwift.AsLargeObjectOptions{
+				SegmentContainer: a.Container(c.Name()),
+				SegmentPrefix:    "segments/",
+				Lazy:             true,
+			}
+
+			// truncating without deleting segments does not need the segments at all
+			lo, err = obj.AsLargeObjectWithOptions(ctx, opts)
+			expectSuccess(t, err)
+			expectSuccess(t, lo.Truncate(ctx, nil))
+			expectInt(t, fetchCount, 0)
+
+			// methods without a context do not fetch the segments on their own
+			lo, err = obj.AsLargeObjectWithOptions(ctx, opts)
+			expectSuccess(t, err)
+			_, err = lo.Segments()
+			expectError(t, err, schwift.ErrSegmentsNotLoaded.Error())
+			err = lo.AddSegment(schwift.SegmentInfo{Object: lo.SegmentContainer().Object("segments/extra")})
+			expectError(t, err, schwift.ErrSegmentsNotLoaded.Error())
+			expectInt(t, fetchCount, 0)
+
+			// the segments are fetched only once
+			expectSuccess(t, lo.LoadSegments(ctx))
+			expectSuccess(t, lo.LoadSegments(ctx))
+			segments, err := lo.Segments()
+			expectSuccess(t, err)
+			expectInt(t, len(segments), 2)
+			expectInt(t, len(lo.SegmentObjects()), 2)
+			expectInt(t, fetchCount, 1)
+
+			// appending to a lazily opened object continues after the existing segments
+			lo, err = obj.AsLargeObjectWithOptions(ctx, opts)
+			expectSuccess(t, err)
+			tail := getRandomSegmentContent(128)
+			expectSuccess(t, lo.Append(ctx, strings.NewReader(tail), 128, nil))
+			expectSuccess(t, lo.WriteManifest(ctx, nil))
+			expectInt(t, fetchCount, 2)
+			expectObjectContent(t, obj, []byte(content+tail))
+		})
+	})
+}
+
+func TestSegmentIterator(t *testing.T) {
+	foreachLargeObjectStrategy(func(strategy schwift.LargeObjectStrategy, strategyStr string) {
+		testWithContainer(t, func(c *schwift.Container) {
+			ctx := context.TODO()
+			obj := c.Object("largeobject")
+
+			// not a large object
+			expectSuccess(t, obj.Upload(ctx, strings.NewReader("hello"), nil, nil))
+			_, err := obj.Segments().NextPage(ctx, -1)
+			if !errors.Is(err, schwift.ErrNotLarge) {
+				t.Errorf("expected ErrNotLarge, got %v", err)
+			}
+
+			// setup phase: create a large object with three segments
+			lo, err := obj.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+				SegmentContainer: c,
+				SegmentPrefix:    "segments/",
+				Strategy:         strategy,
+			}, nil)
+			expectSuccess(t, err)
+			content := getRandomSegmentContent(128) + getRandomSegmentContent(128) + getRandomSegmentContent(128)
+			expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, nil))
+			expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+			// page through the segments
+			iter := obj.Segments()
+			var names []string
+			for _, expectedCount := range []int{2, 1, 0} {
+				segments, err := iter.NextPage(ctx, 2)
+				expectSuccess(t, err)
+				expectInt(t, len(segments), expectedCount)
+				for _, s := range segments {
+					expectUint64(t, s.SizeBytes, 128)
+					names = append(names, s.Object.Name())
+				}
+			}
+			expectString(t, strings.Join(names, ","),
+				"segments/0000000000000001,segments/0000000000000002,segments/0000000000000003")
+
+			segments, err := obj.Segments().Collect(ctx)
+			expectSuccess(t, err)
+			expectInt(t, len(segments), 3)
+		})
+	})
+}
+
 func TestDeleteLargeObjectAndKeepSegments(t *testing.T) {
 	foreachLargeObjectStrategy(func(strategy schwift.LargeObjectStrategy, strategyStr string) {
 		testWithContainer(t, func(c *schwift.Container) {
@@ -569,45 +743,7 @@ func TestAddInvalidSegments(t *testing.T) {
 
 func expectLargeObject(t *testing.T, obj *schwift.Object, expected []schwift.SegmentInfo) {
 	t.Helper()
-	expectObjectExistence(t, obj, true)
-	lo, err := obj.AsLargeObject(context.TODO())
-	expectSuccess(t, err)
-	if lo == nil {
-		t.FailNow()
-	}
-
-	actual, err := lo.Segments()
-	expectSuccess(t, err)
-	if len(actual) != len(expected) {
-		t.Errorf("expected %s to have %d segments, got %d segments",
-			obj.FullName(), len(expected), len(actual))
-		return
-	}
-
-	for idx, as := range actual {
-		es := expected[idx]
-		if len(es.Data) > 0 {
-			// expecting data segment
-			if string(es.Data) != string(as.Data) {
-				t.Errorf("expected segments[%d].Data == %q, got %q",
-					idx, string(es.Data), string(as.Data))
-			}
-		} else {
-			// expecting segment backed by object
-			if as.Object.FullName() != es.Object.FullName() {
-				t.Errorf("expected segments[%d].Object.FullName() == %q, got %q",
-					idx, es.Object.FullName(), as.Object.FullName())
-			}
-			if es.SizeBytes != 0 && as.SizeBytes != es.SizeBytes {
-				t.Errorf("expected segments[%d].SizeBytes == %d, got %d",
-					idx, es.SizeBytes, as.SizeBytes)
-			}
-			if es.Etag != "" && as.Etag != es.Etag {
-				t.Errorf("expected segments[%d].Etag == %q, got %q",
-					idx, es.Etag, as.Etag)
-			}
-		}
-	}
+	schwifttest.ExpectLargeObject(t, obj, expected)
 }
 
 func expectLargeObjectSetup(t *testing.T, lo *schwift.LargeObject, strategy schwift.LargeObjectStrategy, segmentFullPrefix string) {
@@ -627,3 +763,532 @@ func expectLargeObjectSetup(t *testing.T, lo *schwift.LargeObject, strategy schw
 		}
 	}
 }
+
+func TestObjectAppend(t *testing.T) {
+	foreachLargeObjectStrategy(func(strategy schwift.LargeObjectStrategy, strategyStr string) {
+		testWithContainer(t, func(c *schwift.Container) {
+			ctx := context.TODO()
+			opts := &schwift.AppendOptions{
+				Segmenting: schwift.SegmentingOptions{Strategy: strategy},
+			}
+
+			// appending to a plain object converts it into a large object, but
+			// retains its metadata
+			obj := c.Object("plain-" + strategyStr)
+			hdr := schwift.NewObjectHeaders()
+			hdr.ContentType().Set("text/plain")
+			hdr.Metadata().Set("Foo", "bar")
+			expectSuccess(t, obj.Upload(ctx, strings.NewReader("first\n"), nil, hdr.ToOpts()))
+			expectSuccess(t, obj.Append(ctx, strings.NewReader("second\n"), opts, nil))
+			expectSuccess(t, obj.Append(ctx, strings.NewReader("third\n"), opts, nil))
+			expectObjectContent(t, obj, []byte("first\nsecond\nthird\n"))
+
+			hdr, err := obj.Headers(ctx)
+			expectSuccess(t, err)
+			expectBool(t, hdr.IsLargeObject(), true)
+			expectString(t, hdr.ContentType().Get(), "text/plain")
+			expectString(t, hdr.Metadata().Get("Foo"), "bar")
+
+			// appending to a nonexistent object creates it
+			obj = c.Object("new-" + strategyStr)
+			expectSuccess(t, obj.Append(ctx, strings.NewReader("hello"), opts, nil))
+			expectSuccess(t, obj.Append(ctx, strings.NewReader(" world"), opts, nil))
+			expectObjectContent(t, obj, []byte("hello world"))
+		})
+	})
+}
+
+func TestObjectAppendSegmentHeaders(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		// segment options apply to the segments, ropts applies to the manifest
+		obj := c.Object("log")
+		segmentHdr := schwift.NewObjectHeaders()
+		segmentHdr.ContentType().Set("application/octet-stream")
+		segmentHdr.Metadata().Set("Role", "segment")
+		manifestHdr := schwift.NewObjectHeaders()
+		manifestHdr.ContentType().Set("text/plain")
+		manifestHdr.ExpiresAt().Set(expiresAt)
+		opts := &schwift.AppendOptions{
+			SegmentOptions:             segmentHdr.ToOpts(),
+			SegmentsExpireWithManifest: true,
+		}
+		expectSuccess(t, obj.Append(ctx, strings.NewReader("first\n"), opts, manifestHdr.ToOpts()))
+		expectSuccess(t, obj.Append(ctx, strings.NewReader("second\n"), opts, nil))
+		expectObjectContent(t, obj, []byte("first\nsecond\n"))
+
+		hdr, err := obj.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "text/plain")
+		expectString(t, hdr.Metadata().Get("Role"), "")
+
+		segments, err := obj.Segments().Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 2)
+		for _, s := range segments {
+			hdr, err := s.Object.Headers(ctx)
+			expectSuccess(t, err)
+			expectString(t, hdr.ContentType().Get(), "application/octet-stream")
+			expectString(t, hdr.Metadata().Get("Role"), "segment")
+			// the manifest's expiry is also retained for the second Append()
+			expectBool(t, hdr.ExpiresAt().Get().Equal(expiresAt), true)
+		}
+
+		// a relative expiry is converted into an absolute one, so that the
+		// segments do not expire before the manifest
+		obj = c.Object("log2")
+		manifestHdr = schwift.NewObjectHeaders()
+		manifestHdr.ExpiresAfter().Set(time.Hour)
+		expectSuccess(t, obj.Append(ctx, strings.NewReader("first\n"), opts, manifestHdr.ToOpts()))
+		hdr, err = obj.Headers(ctx)
+		expectSuccess(t, err)
+		manifestExpiresAt := hdr.ExpiresAt().Get()
+		expectBool(t, manifestExpiresAt.After(time.Now()), true)
+		segments, err = obj.Segments().Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 1)
+		hdr, err = segments[0].Object.Headers(ctx)
+		expectSuccess(t, err)
+		expectBool(t, hdr.ExpiresAt().Get().Equal(manifestExpiresAt), true)
+	})
+}
+
+func TestAlignSegmentExpiry(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+
+		// setup phase: create an SLO with two own segments and one foreign segment
+		obj := c.Object("largeobject")
+		lo, err := obj.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+		}, nil)
+		expectSuccess(t, err)
+		segmentHdr := schwift.NewObjectHeaders()
+		segmentHdr.Metadata().Set("Foo", "bar")
+		content := getRandomSegmentContent(128) + getRandomSegmentContent(128)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, segmentHdr.ToOpts()))
+		foreign := c.Object("shared/segment")
+		expectSuccess(t, foreign.Upload(ctx, strings.NewReader(getRandomSegmentContent(128)), nil, nil))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Object: foreign}))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+		// set an expiry on the manifest, then propagate it to the own segments
+		manifestHdr := schwift.NewObjectHeaders()
+		manifestHdr.ExpiresAt().Set(expiresAt)
+		expectSuccess(t, obj.UpdatePreservingMetadata(ctx, manifestHdr, nil))
+		lo, err = obj.AsLargeObjectWithOptions(ctx, &schwift.AsLargeObjectOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+		})
+		expectSuccess(t, err)
+		updated, err := lo.AlignSegmentExpiry(ctx, nil)
+		expectSuccess(t, err)
+		expectInt(t, updated, 2)
+
+		for _, segment := range lo.SegmentObjects() {
+			segment.Invalidate()
+			hdr, err := segment.Headers(ctx)
+			expectSuccess(t, err)
+			if segment.IsEqualTo(foreign) {
+				expectBool(t, hdr.ExpiresAt().Exists(), false)
+			} else {
+				expectBool(t, hdr.ExpiresAt().Get().Equal(expiresAt), true)
+				expectString(t, hdr.Metadata().Get("Foo"), "bar")
+			}
+		}
+
+		// segments that are already aligned are not updated again
+		updated, err = lo.AlignSegmentExpiry(ctx, nil)
+		expectSuccess(t, err)
+		expectInt(t, updated, 0)
+
+		// removing the expiry from the manifest also removes it from the segments
+		manifestHdr.ExpiresAt().Clear()
+		expectSuccess(t, obj.UpdatePreservingMetadata(ctx, manifestHdr, nil))
+		updated, err = lo.AlignSegmentExpiry(ctx, &schwift.SegmentExpiryOptions{IncludeForeignSegments: true})
+		expectSuccess(t, err)
+		expectInt(t, updated, 2)
+		for _, segment := range lo.SegmentObjects() {
+			hdr, err := segment.Headers(ctx)
+			expectSuccess(t, err)
+			expectBool(t, hdr.ExpiresAt().Exists(), false)
+		}
+	})
+}
+
+func TestObjectAppendConcurrently(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		// setup: an existing SLO with one segment
+		obj := c.Object("log")
+		expectSuccess(t, obj.Append(ctx, strings.NewReader("first\n"), nil, nil))
+		expectObjectContent(t, obj, []byte("first\n"))
+
+		interleavedObject := func(content string) *schwift.Object {
+			return interleavedAppendObject(t, c, "log", content)
+		}
+
+		// with the default conflict resolution, concurrent appends are merged
+		err := interleavedObject("second\n").Append(ctx, strings.NewReader("third\n"), nil, nil)
+		expectSuccess(t, err)
+		expectObjectContent(t, obj, []byte("first\nsecond\nthird\n"))
+
+		// with AppendConflictFail, the concurrent append wins
+		err = interleavedObject("fourth\n").Append(ctx, strings.NewReader("fifth\n"), &schwift.AppendOptions{
+			OnConflict: schwift.AppendConflictFail,
+		}, nil)
+		expectError(t, err, schwift.ErrConcurrentModification.Error())
+		expectObjectContent(t, obj, []byte("first\nsecond\nthird\nfourth\n"))
+
+		// with AppendConflictOverwrite, the concurrent append is lost
+		err = interleavedObject("sixth\n").Append(ctx, strings.NewReader("seventh\n"), &schwift.AppendOptions{
+			OnConflict: schwift.AppendConflictOverwrite,
+		}, nil)
+		expectSuccess(t, err)
+		expectObjectContent(t, obj, []byte("first\nsecond\nthird\nfourth\nseventh\n"))
+	})
+}
+
+func TestObjectAppendConcurrentlyToPlainObject(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		// setup: a plain object
+		obj := c.Object("log")
+		expectSuccess(t, obj.Upload(ctx, strings.NewReader("first\n"), nil, nil))
+
+		// both appends convert the plain object into a large object; since the
+		// first segment of the later one would be a copy of content that was
+		// replaced in the meantime, the appends cannot be merged
+		err := interleavedAppendObject(t, c, "log", "second\n").Append(ctx, strings.NewReader("third\n"), nil, nil)
+		expectError(t, err, schwift.ErrConcurrentModification.Error())
+		expectObjectContent(t, obj, []byte("first\nsecond\n"))
+
+		// the segments of the failed append were cleaned up
+		lo, err := obj.AsLargeObject(ctx)
+		expectSuccess(t, err)
+		iter := c.Objects()
+		iter.Prefix = "log/slo/"
+		segments, err := iter.Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(segments), len(lo.SegmentObjects()))
+	})
+}
+
+// interleavedAppendObject returns an Object handle for the given object that,
+// during the next Append() on it, interleaves another Append() of the given
+// content right before its first segment upload.
+func interleavedAppendObject(t *testing.T, c *schwift.Container, objectName, content string) *schwift.Object {
+	t.Helper()
+	triggered := false
+	hb := &HookBackend{Inner: c.Account().Backend()}
+	hb.BeforeRequest = func(req *http.Request) {
+		if !triggered && req.Method == http.MethodPut && strings.Contains(req.URL.Path, "/"+objectName+"/slo/") {
+			triggered = true
+			expectSuccess(t, c.Object(objectName).Append(context.TODO(), strings.NewReader(content), nil, nil))
+		}
+	}
+	a, err := schwift.InitializeAccount(hb)
+	expectSuccess(t, err)
+	return a.Container(c.Name()).Object(objectName)
+}
+
+func TestSLOManifestWithHeartbeat(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		o := c.Object("foo")
+		lo, err := o.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+
+		segment := getRandomSegmentContent(128)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader(segment), 0, nil))
+		var opts *schwift.RequestOptions
+		expectSuccess(t, lo.WriteManifest(ctx, opts.WithValue("heartbeat", "on")))
+		expectObjectContent(t, o, []byte(segment))
+
+		// errors concerning specific segments are reported as BulkError
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{
+			Object: c.Object("segments/missing"),
+		}))
+		err = lo.WriteManifest(ctx, opts.WithValue("heartbeat", "on"))
+		bulkErr, ok := errext.As[schwift.BulkError](err)
+		if !ok {
+			t.Fatalf("expected BulkError, got %#v", err)
+		}
+		expectInt(t, bulkErr.StatusCode, http.StatusBadRequest)
+		expectInt(t, len(bulkErr.ObjectErrors), 1)
+		expectString(t, bulkErr.ObjectErrors[0].ContainerName, c.Name())
+		expectString(t, bulkErr.ObjectErrors[0].ObjectName, "segments/missing")
+		expectInt(t, bulkErr.ObjectErrors[0].StatusCode, http.StatusNotFound)
+	})
+}
+
+func TestSLOWithInlineTail(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		o := c.Object("foo")
+		lo, err := o.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		policy, err := c.Account().SegmentPolicy(ctx)
+		expectSuccess(t, err)
+		policy.InlineTailSize = 16
+		lo.SetSegmentPolicy(policy)
+
+		// the short final segment becomes a data segment
+		content := getRandomSegmentContent(128) + getRandomSegmentContent(128) + "tail"
+		expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+		expectObjectContent(t, o, []byte(content))
+
+		segments, err := lo.Segments()
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 3)
+		expectString(t, string(segments[2].Data), "tail")
+		objects, err := c.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(objects), 3) // manifest and 2 segment objects
+
+		// a final segment that exceeds the limit is uploaded as usual
+		expectSuccess(t, lo.Truncate(ctx, nil))
+		content = getRandomSegmentContent(128) + getRandomSegmentContent(64)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, nil))
+		segments, err = lo.Segments()
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 2)
+		expectInt(t, len(segments[1].Data), 0)
+	})
+}
+
+func TestLargeObjectRepack(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		o := c.Object("foo")
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("text/plain")
+		hdr.Metadata().Set("Foo", "bar")
+		lo, err := o.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+
+		// setup: three small segments, one large segment, then a range segment and
+		// a data segment (the last two are also small)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader("aaaabbbbcccc"), 4, nil))
+		large := c.Object("segments/large")
+		expectSuccess(t, large.Upload(ctx, strings.NewReader("0123456789abcdef"), nil, nil))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Object: large, SizeBytes: 16, Etag: etagOfString("0123456789abcdef")}))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Object: large, SizeBytes: 16, RangeOffset: 2, RangeLength: 2}))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Data: []byte("xy")}))
+		expectSuccess(t, lo.WriteManifest(ctx, hdr.ToOpts()))
+		content := "aaaabbbbcccc0123456789abcdef23xy"
+		expectObjectContent(t, o, []byte(content))
+
+		// dry run does not change anything
+		lo, err = o.AsLargeObject(ctx)
+		expectSuccess(t, err)
+		report, err := lo.Repack(ctx, &schwift.RepackOptions{TargetSegmentSize: 10, DryRun: true}, nil)
+		expectSuccess(t, err)
+		expectInt(t, report.SegmentCountBefore, 6)
+		expectInt(t, report.SegmentCountAfter, 4)
+		expectInt(t, report.MergedSegmentCount, 4)
+		expectInt(t, report.NewSegmentCount, 2)
+		expectInt(t, int(report.BytesRewritten), 12)
+		segments, err := lo.Segments()
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 6)
+
+		// actual repack merges "aaaa"+"bbbb" and "23"+"xy"
+		report2, err := lo.Repack(ctx, &schwift.RepackOptions{TargetSegmentSize: 10, DeleteOldSegments: true}, nil)
+		expectSuccess(t, err)
+		if report2 != report {
+			t.Errorf("expected report %#v, got %#v", report, report2)
+		}
+		expectObjectContent(t, o, []byte(content))
+		lo, err = o.AsLargeObject(ctx)
+		expectSuccess(t, err)
+		segments, err = lo.Segments()
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 4)
+		expectInt(t, int(segments[0].SizeBytes), 8)
+		expectInt(t, int(segments[3].SizeBytes), 4)
+
+		// metadata is retained
+		hdr, err = o.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "text/plain")
+		expectString(t, hdr.Metadata().Get("Foo"), "bar")
+
+		// the segments for "aaaa" and "bbbb" were deleted, but the large segment
+		// is still referenced
+		objects, err := c.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(objects), 5) // manifest, "cccc", large, 2 new segments
+
+		// nothing more to do
+		report, err = lo.Repack(ctx, &schwift.RepackOptions{TargetSegmentSize: 10}, nil)
+		expectSuccess(t, err)
+		expectInt(t, report.NewSegmentCount, 0)
+	})
+}
+
+func TestDownloadVerified(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "download")
+		expectFileContent := func(expected string) {
+			t.Helper()
+			buf, err := os.ReadFile(path)
+			expectSuccess(t, err)
+			expectString(t, string(buf), expected)
+		}
+
+		// regular object
+		plain := c.Object("plain")
+		expectSuccess(t, plain.Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, nil))
+		expectSuccess(t, plain.DownloadVerified(context.TODO(), path, nil))
+		expectFileContent(string(objectExampleContent))
+
+		// static large object with all types of segments
+		segment1 := getRandomSegmentContent(128)
+		rangeSegmentStr := "<aaa>X<bbb>"
+		rangeSegmentObj := c.Object("range-segment")
+		expectSuccess(t, rangeSegmentObj.Upload(context.TODO(), strings.NewReader(rangeSegmentStr), nil, nil))
+
+		slo := c.Object("slo")
+		lo, err := slo.AsNewLargeObject(context.TODO(), schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "slo-segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(context.TODO(), strings.NewReader(segment1), 0, nil))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Data: []byte("---")}))
+		expectSuccess(t, lo.AddSegment(schwift.SegmentInfo{Object: rangeSegmentObj, RangeOffset: 6, RangeLength: 5}))
+		expectSuccess(t, lo.WriteManifest(context.TODO(), nil))
+		expectSuccess(t, slo.DownloadVerified(context.TODO(), path, nil))
+		expectFileContent(segment1 + "---<bbb>")
+
+		// dynamic large object (cannot be verified, but can be downloaded)
+		dlo := c.Object("dlo")
+		lo, err = dlo.AsNewLargeObject(context.TODO(), schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "dlo-segments/",
+			Strategy:         schwift.DynamicLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(context.TODO(), strings.NewReader(segment1), 64, nil))
+		expectSuccess(t, lo.WriteManifest(context.TODO(), nil))
+		expectSuccess(t, dlo.DownloadVerified(context.TODO(), path, nil))
+		expectFileContent(segment1)
+
+		// when a segment of the SLO is replaced, the download fails and the
+		// existing file is left untouched
+		corrupted := getRandomSegmentContent(128)
+		expectSuccess(t, c.Object("slo-segments/0000000000000001").Upload(context.TODO(), strings.NewReader(corrupted), nil, nil))
+		err = slo.DownloadVerified(context.TODO(), path, nil)
+		var checksumErr schwift.DownloadChecksumError
+		if !errors.As(err, &checksumErr) || !errors.Is(err, schwift.ErrChecksumMismatch) {
+			t.Fatalf("expected DownloadChecksumError, got %v", err)
+		}
+		expectString(t, checksumErr.Segment, "segment 0")
+		expectString(t, checksumErr.Expected, etagOfString(segment1))
+		expectString(t, checksumErr.Actual, etagOfString(corrupted))
+		expectFileContent(segment1)
+
+		entries, err := os.ReadDir(dir)
+		expectSuccess(t, err)
+		expectInt(t, len(entries), 1)
+	})
+}
+
+func TestDownloadResumable(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		dir := t.TempDir()
+		path := filepath.Join(dir, "download")
+
+		slo := c.Object("slo")
+		lo, err := slo.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "slo-segments/",
+			Strategy:         schwift.StaticLargeObject,
+		}, nil)
+		expectSuccess(t, err)
+		content := getRandomSegmentContent(384)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader(content), 128, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+		// interrupt the download in the middle of the second segment
+		// (downloads of the manifest itself are not affected)
+		interrupting, err := schwift.InitializeAccount(&HookBackend{
+			Inner: c.Account().Backend(),
+			AfterResponse: func(req *http.Request, resp *http.Response) {
+				if req.Method == http.MethodGet && req.URL.Query().Get("multipart-manifest") == "" {
+					resp.Body = &interruptedBody{resp.Body, 200}
+				}
+			},
+		})
+		expectSuccess(t, err)
+		err = interrupting.Container(c.Name()).Object("slo").DownloadResumable(ctx, path, nil)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+		}
+		expectObjectFileExistence := func(path string, expected bool) {
+			t.Helper()
+			_, err := os.Stat(path)
+			expectBool(t, err == nil, expected)
+		}
+		expectObjectFileExistence(path, false)
+		expectObjectFileExistence(path+".partial.resume", true)
+
+		// the next attempt resumes after the first segment
+		var ranges []string
+		hooked, err := schwift.InitializeAccount(&HookBackend{
+			Inner: c.Account().Backend(),
+			BeforeRequest: func(req *http.Request) {
+				if req.Method == http.MethodGet && req.URL.Query().Get("multipart-manifest") == "" {
+					ranges = append(ranges, req.Header.Get("Range"))
+				}
+			},
+		})
+		expectSuccess(t, err)
+		expectSuccess(t, hooked.Container(c.Name()).Object("slo").DownloadResumable(ctx, path, nil))
+		expectString(t, strings.Join(ranges, ","), "bytes=128-")
+		buf, err := os.ReadFile(path)
+		expectSuccess(t, err)
+		expectString(t, string(buf), content)
+		expectObjectFileExistence(path+".partial", false)
+		expectObjectFileExistence(path+".partial.resume", false)
+
+		// when the object changes after an interruption, the download starts over
+		err = interrupting.Container(c.Name()).Object("slo").DownloadResumable(ctx, path, nil)
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected ErrUnexpectedEOF, got %v", err)
+		}
+		lo, err = slo.AsLargeObject(ctx)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(ctx, strings.NewReader("more"), 0, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+		ranges = nil
+		expectSuccess(t, hooked.Container(c.Name()).Object("slo").DownloadResumable(ctx, path, nil))
+		expectString(t, strings.Join(ranges, ","), "")
+		buf, err = os.ReadFile(path)
+		expectSuccess(t, err)
+		expectString(t, string(buf), content+"more")
+	})
+}
@@ -0,0 +1,221 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/lifecycle"
+)
+
+func TestLifecycleEngine(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		a := c.Account()
+
+		for _, name := range []string{"logs/1", "logs/2", "reports/small", "reports/large"} {
+			content := "example"
+			if name == "reports/large" {
+				content = strings.Repeat("example", 10)
+			}
+			expectSuccess(t, c.Object(name).Upload(ctx, strings.NewReader(content), nil, nil))
+		}
+
+		// objects were just created, so MinAge filters them all out
+		engine := lifecycle.Engine{
+			Account: a,
+			Rules: []lifecycle.Rule{{
+				Name:             "delete-old-logs",
+				ContainerPattern: c.Name(),
+				ObjectPrefix:     "logs/",
+				MinAge:           time.Hour,
+				Action:           lifecycle.ActionDelete,
+			}},
+		}
+		report, err := engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(report.Rules), 1)
+		expectInt(t, report.Rules[0].ContainersScanned, 1)
+		expectInt(t, report.Rules[0].ObjectsScanned, 2)
+		expectInt(t, report.Rules[0].ObjectsMatched, 0)
+
+		// dry run from the future reports matches without deleting
+		engine.Now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+		engine.DryRun = true
+		report, err = engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, report.Rules[0].ObjectsMatched, 2)
+		expectInt(t, report.Rules[0].ObjectsDeleted, 0)
+		expectObjectExistence(t, c.Object("logs/1"), true)
+
+		// actual run deletes
+		engine.DryRun = false
+		report, err = engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, report.Rules[0].ObjectsDeleted, 2)
+		expectInt(t, len(report.Rules[0].Failures), 0)
+		objects, err := c.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectObjectNames(t, objects, "reports/large", "reports/small")
+
+		// size filter and metadata transition
+		engine.Rules = []lifecycle.Rule{{
+			Name:             "archive-large-reports",
+			ContainerPattern: c.Name(),
+			ObjectPrefix:     "reports/",
+			MinSizeBytes:     20,
+			Action:           lifecycle.ActionSetMetadata,
+			Metadata:         map[string]string{"Tier": "archive"},
+		}, {
+			Name:             "expire-reports",
+			ContainerPattern: c.Name(),
+			ObjectPrefix:     "reports/",
+			Action:           lifecycle.ActionExpire,
+			ExpireAfter:      24 * time.Hour,
+		}}
+		engine.Now = nil
+		report, err = engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, report.Rules[0].ObjectsMatched, 1)
+		expectInt(t, report.Rules[0].ObjectsUpdated, 1)
+		expectInt(t, report.Rules[1].ObjectsUpdated, 2)
+
+		hdr, err := c.Object("reports/large").Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.Metadata().Get("Tier"), "archive")
+		expectBool(t, hdr.ExpiresAt().Exists(), true)
+		hdr, err = c.Object("reports/small").Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.Metadata().Get("Tier"), "")
+		expectBool(t, hdr.ExpiresAt().Exists(), true)
+
+		// invalid rules are rejected before anything is done
+		engine.Rules = []lifecycle.Rule{{Name: "broken", ContainerPattern: "*", Action: lifecycle.ActionExpire}}
+		_, err = engine.Run(ctx)
+		expectError(t, err, `invalid lifecycle rule "broken": value of ExpireAfter must be positive for ActionExpire`)
+
+		// errors that concern the bulk request as a whole are not mistaken for success
+		hooked, err := schwift.InitializeAccount(&HookBackend{
+			Inner: a.Backend(),
+			AfterResponse: func(req *http.Request, resp *http.Response) {
+				if req.URL.Query().Has("bulk-delete") {
+					resp.Body.Close()
+					resp.Body = io.NopCloser(strings.NewReader(`{"Response Status":"502 Bad Gateway","Response Body":"","Errors":[]}`))
+				}
+			},
+		})
+		expectSuccess(t, err)
+		engine = lifecycle.Engine{
+			Account: hooked,
+			Rules: []lifecycle.Rule{{
+				Name:             "delete-reports",
+				ContainerPattern: c.Name(),
+				ObjectPrefix:     "reports/",
+				Action:           lifecycle.ActionDelete,
+			}},
+		}
+		_, err = engine.Run(ctx)
+		var bulkErr schwift.BulkError
+		expectBool(t, errors.As(err, &bulkErr), true)
+		expectInt(t, bulkErr.StatusCode, http.StatusBadGateway)
+
+		// cleanup (the expiry date is far enough in the future)
+		objects, err = c.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		_, _, err = a.BulkDelete(ctx, objects, nil, nil)
+		expectSuccess(t, err)
+	})
+}
+
+func TestLifecycleLargeObjects(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		a := c.Account()
+		segmentContainer, err := a.Container(c.Name() + "_segments").EnsureExists(ctx)
+		expectSuccess(t, err)
+
+		for _, strategy := range []schwift.LargeObjectStrategy{schwift.StaticLargeObject, schwift.DynamicLargeObject} {
+			name := "slo"
+			if strategy == schwift.DynamicLargeObject {
+				name = "dlo"
+			}
+			lo, err := c.Object(name).AsNewLargeObject(ctx, schwift.SegmentingOptions{
+				SegmentContainer: segmentContainer,
+				SegmentPrefix:    name + "/",
+				Strategy:         strategy,
+			}, nil)
+			expectSuccess(t, err)
+			expectSuccess(t, lo.Append(ctx, strings.NewReader(getRandomSegmentContent(256)), 128, nil))
+			expectSuccess(t, lo.WriteManifest(ctx, nil))
+		}
+
+		// expiring a manifest also expires its segments
+		engine := lifecycle.Engine{
+			Account: a,
+			Rules: []lifecycle.Rule{{
+				Name:             "expire-everything",
+				ContainerPattern: c.Name(),
+				Action:           lifecycle.ActionExpire,
+				ExpireAfter:      time.Hour,
+			}},
+		}
+		report, err := engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, report.Rules[0].ObjectsUpdated, 2)
+		expectInt(t, len(report.Rules[0].Failures), 0)
+		segments, err := segmentContainer.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(segments), 4)
+		for _, segment := range segments {
+			hdr, err := segment.Headers(ctx)
+			expectSuccess(t, err)
+			expectBool(t, hdr.ExpiresAt().Exists(), true)
+		}
+
+		// the segment container matches the pattern, but is skipped, and the
+		// segments are deleted together with their manifests instead
+		engine = lifecycle.Engine{
+			Account: a,
+			Rules: []lifecycle.Rule{{
+				Name:             "delete-everything",
+				ContainerPattern: c.Name() + "*",
+				Action:           lifecycle.ActionDelete,
+			}},
+		}
+		report, err = engine.Run(ctx)
+		expectSuccess(t, err)
+		expectInt(t, report.Rules[0].ContainersScanned, 1)
+		expectInt(t, report.Rules[0].ObjectsDeleted, 2)
+		expectInt(t, len(report.Rules[0].Failures), 0)
+		for _, container := range []*schwift.Container{c, segmentContainer} {
+			objects, err := container.Objects().Collect(ctx)
+			expectSuccess(t, err)
+			expectObjectNames(t, objects)
+		}
+
+		expectSuccess(t, segmentContainer.Delete(ctx, nil))
+	})
+}
@@ -22,8 +22,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/majewsky/schwift/v2"
 )
@@ -54,12 +57,16 @@ func TestObjectIterator(t *testing.T) {
 		os, err = iter.NextPage(context.TODO(), 2)
 		expectSuccess(t, err)
 		expectObjectNames(t, os, oname(3), oname(4))
+		expectString(t, fmt.Sprintf("%+v", iter.Stats()),
+			fmt.Sprintf("{LastMarker:%s Truncated:true PagesFetched:2 RequestCount:2}", oname(4)))
 		os, err = iter.NextPage(context.TODO(), 2)
 		expectSuccess(t, err)
 		expectObjectNames(t, os)
 		os, err = iter.NextPage(context.TODO(), 2)
 		expectSuccess(t, err)
 		expectObjectNames(t, os)
+		expectString(t, fmt.Sprintf("%+v", iter.Stats()),
+			fmt.Sprintf("{LastMarker:%s Truncated:false PagesFetched:3 RequestCount:3}", oname(4)))
 
 		// test iteration with partial last page
 		iter = c.Objects()
@@ -132,6 +139,132 @@ func TestObjectIterator(t *testing.T) {
 	})
 }
 
+func TestObjectIteratorWithFilter(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("application/json")
+		for _, name := range []string{"a.jpg", "b.jpg", "c.txt", "d.txt", "e.jpg", "f.jpg.bak"} {
+			expectSuccess(t, c.Object(name).Upload(ctx, bytes.NewReader(objectExampleContent), nil, hdr.ToOpts()))
+		}
+
+		// the second page ("c.txt", "d.txt") is filtered out entirely, which must
+		// not be mistaken for the end of the listing
+		iter := c.Objects()
+		iter.Match = regexp.MustCompile(`\.jpg$`)
+		os, err := iter.NextPage(ctx, 2)
+		expectSuccess(t, err)
+		expectObjectNames(t, os, "a.jpg", "b.jpg")
+		os, err = iter.NextPage(ctx, 2)
+		expectSuccess(t, err)
+		expectObjectNames(t, os, "e.jpg")
+		os, err = iter.NextPage(ctx, 2)
+		expectSuccess(t, err)
+		expectObjectNames(t, os)
+
+		// inverse match, combined with Prefix
+		iter = c.Objects()
+		iter.Prefix = "f"
+		iter.Exclude = regexp.MustCompile(`\.bak$`)
+		os, err = iter.Collect(ctx)
+		expectSuccess(t, err)
+		expectObjectNames(t, os)
+
+		// both filters at once, with detailed listing
+		iter = c.Objects()
+		iter.Match = regexp.MustCompile(`\.jpg`)
+		iter.Exclude = regexp.MustCompile(`^[ab]`)
+		ois, err := iter.CollectDetailed(ctx)
+		expectSuccess(t, err)
+		expectObjectInfos(t, ois, "e.jpg", "f.jpg.bak")
+
+		// sharded listings apply the filters within each shard
+		iter = c.Objects()
+		iter.Exclude = regexp.MustCompile(`\.jpg`)
+		os, err = iter.CollectSharded(ctx, &schwift.ShardingOptions{Strategy: schwift.CharacterShards("abcdef")})
+		expectSuccess(t, err)
+		expectObjectNames(t, os, "c.txt", "d.txt")
+
+		// a marker in the options is used for the first page only
+		iter = c.Objects()
+		iter.Options = (*schwift.RequestOptions)(nil).WithValue("marker", "c.txt")
+		os, err = iter.NextPage(ctx, 2)
+		expectSuccess(t, err)
+		expectObjectNames(t, os, "d.txt", "e.jpg")
+		os, err = iter.NextPage(ctx, 2)
+		expectSuccess(t, err)
+		expectObjectNames(t, os, "f.jpg.bak")
+	})
+}
+
+func TestObjectIteratorSkipVanished(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		upload := func() {
+			for _, name := range []string{"a", "b", "c"} {
+				expectSuccess(t, c.Object(name).Upload(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+			}
+		}
+		// while visiting "a", delete "b" to simulate a concurrent deletion
+		// between listing and HEAD
+		var visited []string
+		callback := func(obj *schwift.Object, hdr schwift.ObjectHeaders) error {
+			visited = append(visited, obj.Name())
+			expectUint64(t, hdr.SizeBytes().Get(), uint64(len(objectExampleContent)))
+			if obj.Name() == "a" {
+				return c.Object("b").Delete(ctx, nil, nil)
+			}
+			return nil
+		}
+
+		// by default, the iteration is aborted
+		upload()
+		err := c.Objects().ForeachWithHeaders(ctx, callback)
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+		expectString(t, strings.Join(visited, ","), "a")
+
+		// with SkipVanished, the object is skipped and reported
+		upload()
+		visited = nil
+		var vanished []string
+		iter := c.Objects()
+		iter.SkipVanished = true
+		iter.OnVanished = func(obj *schwift.Object) {
+			vanished = append(vanished, obj.Name())
+		}
+		expectSuccess(t, iter.ForeachWithHeaders(ctx, callback))
+		expectString(t, strings.Join(visited, ","), "a,c")
+		expectString(t, strings.Join(vanished, ","), "b")
+
+		// 404 errors concerning other objects still abort the iteration
+		missingContainer := c.Account().Container(c.Name() + "-missing")
+		iter = c.Objects()
+		iter.SkipVanished = true
+		err = iter.Foreach(ctx, func(obj *schwift.Object) error {
+			_, err := missingContainer.Object(obj.Name()).Headers(ctx)
+			return err
+		})
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+
+		// this includes copying into a missing container, even though the 404
+		// response is reported for the source object
+		iter = c.Objects()
+		iter.SkipVanished = true
+		err = iter.Foreach(ctx, func(obj *schwift.Object) error {
+			return obj.CopyTo(ctx, missingContainer.Object(obj.Name()), nil, nil)
+		})
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+
+		// other errors still abort the iteration
+		iter = c.Objects()
+		iter.SkipVanished = true
+		err = iter.Foreach(ctx, func(obj *schwift.Object) error {
+			return fmt.Errorf("cannot process %s", obj.Name())
+		})
+		expectError(t, err, "cannot process a")
+	})
+}
+
 func TestPseudoDirectories(t *testing.T) {
 	testWithContainer(t, func(c *schwift.Container) {
 		// create test objects that can be listed
@@ -178,6 +311,26 @@ func TestPseudoDirectories(t *testing.T) {
 		ois, err = iter.CollectDetailed(context.TODO())
 		expectSuccess(t, err)
 		expectObjectInfos(t, ois, "foo/1", "foo/2", "foo/3", "foo/bar", "subdir:foo/bar/")
+
+		// test detailed iteration with fallback formats
+		iter = c.Objects()
+		iter.Prefix = "foo/"
+		iter.Delimiter = "/"
+		iter.Format = schwift.ListingFormatXML
+		ois, err = iter.CollectDetailed(context.TODO())
+		expectSuccess(t, err)
+		expectObjectInfos(t, ois, "foo/1", "foo/2", "foo/3", "foo/bar", "subdir:foo/bar/")
+
+		iter = c.Objects()
+		iter.Prefix = "foo/"
+		iter.Delimiter = "/"
+		iter.Format = schwift.ListingFormatPlain
+		ois, err = iter.CollectDetailed(context.TODO())
+		expectSuccess(t, err)
+		expectInt(t, len(ois), 5)
+		expectString(t, ois[3].Object.Name(), "foo/bar")
+		expectString(t, ois[3].Etag, "")
+		expectString(t, ois[4].SubDirectory, "foo/bar/")
 	})
 }
 
@@ -207,6 +360,37 @@ func TestObjectIteratorWithSymlinks(t *testing.T) {
 		ois, err := iter.CollectDetailed(context.TODO())
 		expectSuccess(t, err)
 		expectObjectInfos(t, ois, "foo/1", "symlink:foo/2>foo/1", "foo/3")
+		expectBool(t, ois[0].IsSymlink(), false)
+		expectBool(t, ois[1].IsSymlink(), true)
+	})
+}
+
+func TestObjectIteratorWithLargeObjects(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		expectSuccess(t, c.Object("plain").Upload(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		slo := c.Object("slo")
+		expectSuccess(t, slo.Append(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		expectSuccess(t, slo.Append(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		hdr, err := slo.Headers(ctx)
+		expectSuccess(t, err)
+
+		for _, format := range []schwift.ListingFormat{schwift.ListingFormatJSON, schwift.ListingFormatXML} {
+			iter := c.Objects()
+			iter.Delimiter = "/" // hide the segments
+			iter.Format = format
+			ois, err := iter.CollectDetailed(ctx)
+			expectSuccess(t, err)
+			expectInt(t, len(ois), 3)
+
+			expectString(t, ois[0].Object.Name(), "plain")
+			expectBool(t, ois[0].IsStaticLargeObject(), false)
+			expectString(t, ois[1].Object.Name(), "slo")
+			expectBool(t, ois[1].IsStaticLargeObject(), true)
+			expectString(t, ois[1].SLOEtag, strings.Trim(hdr.Etag().Get(), `"`))
+			expectInt(t, int(ois[1].SizeBytes), 2*len(objectExampleContent))
+			expectString(t, ois[2].SubDirectory, "slo/")
+		}
 	})
 }
 
@@ -328,3 +512,139 @@ func expectObjectInfos(t *testing.T, actualInfos []schwift.ObjectInfo, expectedN
 		}
 	}
 }
+
+func TestObjectIteratorWithCache(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		for idx := 1; idx <= 3; idx++ {
+			expectSuccess(t, c.Object(fmt.Sprintf("object%d", idx)).Upload(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		}
+
+		// record the methods of all requests made through this container
+		var methods []string
+		hb := &HookBackend{Inner: c.Account().Backend()}
+		hb.BeforeRequest = func(req *http.Request) {
+			methods = append(methods, req.Method)
+		}
+		a, err := schwift.InitializeAccount(hb)
+		expectSuccess(t, err)
+		cc := a.Container(c.Name())
+
+		listFirstPage := func(cache *schwift.ListingCache) {
+			t.Helper()
+			methods = nil
+			iter := cc.Objects()
+			iter.Cache = cache
+			os, err := iter.NextPage(ctx, 2)
+			expectSuccess(t, err)
+			expectObjectNames(t, os, "object1", "object2")
+		}
+
+		// the first listing fills the cache, the second one only needs to revalidate
+		cache := schwift.NewListingCache(time.Hour)
+		listFirstPage(cache)
+		expectString(t, strings.Join(methods, ","), "GET")
+		listFirstPage(cache)
+		expectString(t, strings.Join(methods, ","), "HEAD")
+
+		// after the container changes, the page is fetched again
+		expectSuccess(t, c.Object("object4").Upload(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		listFirstPage(cache)
+		expectString(t, strings.Join(methods, ","), "HEAD,GET")
+		listFirstPage(cache)
+		expectString(t, strings.Join(methods, ","), "HEAD")
+
+		// detailed listings are cached separately
+		methods = nil
+		iter := cc.Objects()
+		iter.Cache = cache
+		infos, err := iter.CollectDetailed(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(infos), 4)
+		expectString(t, strings.Join(methods, ","), "GET,GET")
+		methods = nil
+		iter = cc.Objects()
+		iter.Cache = cache
+		infos, err = iter.CollectDetailed(ctx)
+		expectSuccess(t, err)
+		expectInt(t, len(infos), 4)
+		expectString(t, infos[3].Object.Name(), "object4")
+		expectString(t, strings.Join(methods, ","), "HEAD,HEAD")
+		expectString(t, fmt.Sprintf("%+v", iter.Stats()), "{LastMarker:object4 Truncated:false PagesFetched:2 RequestCount:0}")
+
+		// expired pages are not reused
+		cache = schwift.NewListingCache(0)
+		listFirstPage(cache)
+		listFirstPage(cache)
+		expectString(t, strings.Join(methods, ","), "GET")
+	})
+}
+
+func TestObjectIteratorSharded(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		names := []string{"data/0a", "data/0b", "data/3c/1", "data/3c/2", "data/a0", "data/f0", "data/ff", "other"}
+		for _, name := range names {
+			expectSuccess(t, c.Object(name).Upload(ctx, bytes.NewReader(objectExampleContent), nil, nil))
+		}
+
+		// results are merged in order, even when pages are small
+		iter := c.Objects()
+		iter.Prefix = "data/"
+		iter.Options = (&schwift.RequestOptions{}).WithValue("limit", "1")
+		opts := &schwift.ShardingOptions{Strategy: schwift.HexShards, Concurrency: 3}
+		objects, err := iter.CollectSharded(ctx, opts)
+		expectSuccess(t, err)
+		expectObjectNames(t, objects, names[:7]...)
+		expectBool(t, objects[0].Container() == c, true)
+
+		// same with detailed listing and pseudo-directories
+		iter.Delimiter = "/"
+		iter.Prefix = "data/3"
+		infos, err := iter.CollectDetailedSharded(ctx, &schwift.ShardingOptions{Strategy: schwift.CharacterShards("c3")})
+		expectSuccess(t, err)
+		expectObjectInfos(t, infos, "subdir:data/3c/")
+
+		// without a strategy, the listing is not split
+		objects, err = c.Objects().CollectSharded(ctx, nil)
+		expectSuccess(t, err)
+		expectObjectNames(t, objects, names...)
+
+		// errors from the callback abort the listing
+		count := 0
+		err = c.Objects().ForeachSharded(ctx, &schwift.ShardingOptions{Strategy: schwift.CharacterShards("do")}, func(o *schwift.Object) error {
+			count++
+			return fmt.Errorf("stop at %s", o.Name())
+		})
+		expectError(t, err, "stop at data/0a")
+		expectInt(t, count, 1)
+	})
+}
+
+func TestShardedNamespaceWalk(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		ns := c.Dir("data").Sharded(schwift.HashPrefixSharder{Digits: 1})
+		logicalNames := []string{"2018/a", "2018/b", "2019/a", "2019/b", "2019/c", "2020/a"}
+		for _, name := range logicalNames {
+			expectSuccess(t, ns.Object(name).Upload(ctx, strings.NewReader(name), nil, nil))
+		}
+		// objects outside the namespace are ignored
+		expectSuccess(t, c.Object("data/0/unsharded").Upload(ctx, strings.NewReader("x"), nil, nil))
+
+		// listing reassembles the logical order
+		names, err := ns.Collect(ctx, "")
+		expectSuccess(t, err)
+		expectString(t, strings.Join(names, ","), strings.Join(logicalNames, ","))
+		names, err = ns.Collect(ctx, "2019/")
+		expectSuccess(t, err)
+		expectString(t, strings.Join(names, ","), "2019/a,2019/b,2019/c")
+
+		err = ns.Walk(ctx, "2020/", func(logicalName string, info schwift.ObjectInfo) error {
+			expectString(t, info.Object.Name(), ns.PhysicalName(logicalName))
+			expectUint64(t, info.SizeBytes, uint64(len(logicalName)))
+			return nil
+		})
+		expectSuccess(t, err)
+	})
+}
@@ -21,6 +21,7 @@ package tests
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,6 +29,7 @@ import (
 	"testing"
 
 	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/schwifttest"
 )
 
 func TestObjectLifecycle(t *testing.T) {
@@ -97,6 +99,25 @@ func TestObjectUpload(t *testing.T) {
 		expectSuccess(t, err)
 		expectObjectContent(t, obj, objectExampleContent)
 
+		// test upload with buffered io.Writer (with a buffer small enough to
+		// force spooling to disk)
+		obj = c.Object("upload5b")
+		err = obj.UploadFromBufferedWriter(context.TODO(), nil, nil, &schwift.WriterBufferOptions{
+			BufferSize:     4,
+			SpoolToDisk:    true,
+			SpoolDirectory: t.TempDir(),
+		}, func(w io.Writer) error {
+			for _, b := range objectExampleContent {
+				_, err := w.Write([]byte{b})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		expectSuccess(t, err)
+		expectObjectContent(t, obj, objectExampleContent)
+
 		// test upload with empty reader (should create zero-byte-sized object)
 		obj = c.Object("upload6")
 		err = obj.Upload(context.TODO(), eofReader{}, nil, nil)
@@ -111,6 +132,77 @@ func TestObjectUpload(t *testing.T) {
 	})
 }
 
+func TestObjectUploadWithIdempotencyKey(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		obj := c.Object("upload")
+		opts := schwift.RequestOptions{
+			RequestID:      "schwift-test-" + getRandomName(),
+			IdempotencyKey: getRandomName(),
+		}
+		err := obj.Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, &opts)
+		expectSuccess(t, err)
+
+		hdr, err := obj.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.IdempotencyKey().Get(), opts.IdempotencyKey)
+
+		// the key is only recorded on PUT, so an update via POST (which replaces
+		// all metadata) needs to supply it explicitly to retain it
+		newHdr := schwift.NewObjectHeaders()
+		newHdr.Metadata().Set("Foo", "bar")
+		err = obj.Update(context.TODO(), newHdr, &opts)
+		expectSuccess(t, err)
+		hdr, err = obj.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.IdempotencyKey().Get(), "")
+	})
+}
+
+func TestObjectUploadIfChanged(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		obj := c.Object("upload")
+
+		// object does not exist yet
+		uploaded, err := obj.UploadIfChanged(ctx, bytes.NewReader(objectExampleContent), nil, nil)
+		expectSuccess(t, err)
+		expectBool(t, uploaded, true)
+		expectObjectContent(t, obj, objectExampleContent)
+
+		// same content is skipped
+		var result schwift.UploadResult
+		uploaded, err = c.Object("upload").UploadIfChanged(ctx, bytes.NewReader(objectExampleContent), &schwift.UploadOptions{Result: &result}, nil)
+		expectSuccess(t, err)
+		expectBool(t, uploaded, false)
+		expectString(t, result.MD5, etagOfString(string(objectExampleContent)))
+		expectUint64(t, result.SizeBytes, uint64(len(objectExampleContent)))
+
+		// different content is uploaded
+		uploaded, err = c.Object("upload").UploadIfChanged(ctx, strings.NewReader("changed"), nil, nil)
+		expectSuccess(t, err)
+		expectBool(t, uploaded, true)
+		expectObjectContent(t, obj, []byte("changed"))
+
+		// failed uploads are not reported as uploaded
+		missing := c.Account().Container(getRandomName()).Object("upload")
+		uploaded, err = missing.UploadIfChanged(ctx, strings.NewReader("changed"), nil, nil)
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+		expectBool(t, uploaded, false)
+	})
+}
+
+func TestObjectCreate(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		obj := c.Object("claim")
+		err := obj.Create(context.TODO(), bytes.NewReader([]byte("first")), nil)
+		expectSuccess(t, err)
+
+		err = c.Object("claim").Create(context.TODO(), bytes.NewReader([]byte("second")), nil)
+		expectBool(t, errors.Is(err, schwift.ErrAlreadyExists), true)
+		expectObjectContent(t, obj, []byte("first"))
+	})
+}
+
 type eofReader struct{}
 
 func (r eofReader) Read([]byte) (int, error) {
@@ -155,6 +247,28 @@ func TestObjectDownload(t *testing.T) {
 		buf, err = io.ReadAll(reader)
 		expectSuccess(t, err)
 		expectString(t, string(buf), string(objectExampleContent[8:]))
+
+		// test metadata of full download
+		downloaded := obj.Download(context.TODO(), nil)
+		expectBool(t, downloaded.IsPartial(), false)
+		expectBool(t, downloaded.AcceptsRanges(), true)
+		r, total, ok := downloaded.ContentRange()
+		expectBool(t, ok, true)
+		expectString(t, r.String(), fmt.Sprintf("0-%d", len(objectExampleContent)-1))
+		expectUint64(t, total, uint64(len(objectExampleContent)))
+		_, err = downloaded.AsByteSlice()
+		expectSuccess(t, err)
+
+		// test metadata of partial download
+		downloaded = obj.Download(context.TODO(), (*schwift.RequestOptions)(nil).WithRange(schwift.ByteRange{Offset: 4, Length: 4}))
+		expectBool(t, downloaded.IsPartial(), true)
+		r, total, ok = downloaded.ContentRange()
+		expectBool(t, ok, true)
+		expectString(t, r.String(), "4-7")
+		expectUint64(t, total, uint64(len(objectExampleContent)))
+		buf, err = downloaded.AsByteSlice()
+		expectSuccess(t, err)
+		expectString(t, string(buf), string(objectExampleContent[4:8]))
 	})
 }
 
@@ -187,6 +301,108 @@ func TestObjectUpdate(t *testing.T) {
 	})
 }
 
+func TestObjectUpdatePreservingMetadata(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		obj := c.Object("example")
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("application/json")
+		hdr.ContentDisposition().Set(`attachment; filename="example.json"`)
+		hdr.Metadata().Set("Foo", "1")
+		hdr.Metadata().Set("Bar", "2")
+		err := obj.Upload(context.TODO(), bytes.NewReader([]byte("{}")), nil, hdr.ToOpts())
+		expectSuccess(t, err)
+
+		// only the given metadata is changed or removed
+		newHeaders := schwift.NewObjectHeaders()
+		newHeaders.Metadata().Set("Foo", "3")
+		newHeaders.Metadata().Clear("Bar")
+		err = obj.UpdatePreservingMetadata(context.TODO(), newHeaders, nil)
+		expectSuccess(t, err)
+
+		hdr, err = obj.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "application/json")
+		expectString(t, hdr.ContentDisposition().Get(), `attachment; filename="example.json"`)
+		expectString(t, hdr.Metadata().Get("Foo"), "3")
+		expectString(t, hdr.Metadata().Get("Bar"), "")
+
+		// Touch() changes the timestamp, but nothing else
+		createdAt := hdr.CreatedAt().Get()
+		expectSuccess(t, obj.Touch(context.TODO(), nil))
+		newHdr, err := obj.Headers(context.TODO())
+		expectSuccess(t, err)
+		expectBool(t, newHdr.CreatedAt().Get().After(createdAt), true)
+		expectString(t, newHdr.ContentType().Get(), "application/json")
+		expectString(t, newHdr.Metadata().Get("Foo"), "3")
+		expectObjectContent(t, obj, []byte("{}"))
+	})
+}
+
+func TestUploadQuotaCheck(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		hdr := schwift.NewContainerHeaders()
+		hdr.BytesUsedQuota().Set(10)
+		expectSuccess(t, c.Update(ctx, hdr, nil))
+
+		// upload within the quota succeeds
+		opts := &schwift.UploadOptions{QuotaCheck: schwift.QuotaCheckBestEffort}
+		expectSuccess(t, c.Object("first").Upload(ctx, strings.NewReader("123456"), opts, nil))
+
+		// upload exceeding the quota fails without creating the object
+		err := c.Object("second").Upload(ctx, strings.NewReader("123456"), opts, nil)
+		expectError(t, err, fmt.Sprintf("upload would exceed byte quota of %q by 2 bytes", c.Name()))
+		expectBool(t, errors.Is(err, schwift.ErrQuotaExceeded), true)
+		expectObjectExistence(t, c.Object("second"), false)
+
+		// same for the object count quota
+		hdr = schwift.NewContainerHeaders()
+		hdr.ObjectCountQuota().Set(1)
+		expectSuccess(t, c.Update(ctx, hdr, nil))
+		err = c.Object("second").Upload(ctx, strings.NewReader("1"), opts, nil)
+		expectError(t, err, fmt.Sprintf("upload would exceed object count quota of %q by 1 objects", c.Name()))
+
+		// when the quota cannot be determined, only the strict check fails
+		missing := c.Account().Container(c.Name() + "-missing").Object("foo")
+		err = missing.Upload(ctx, strings.NewReader("1"), opts, nil)
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+		opts.QuotaCheck = schwift.QuotaCheckStrict
+		err = missing.Upload(ctx, strings.NewReader("1"), opts, nil)
+		expectError(t, err, fmt.Sprintf("could not HEAD %q in Swift: expected 204 response, got 404 instead", c.Name()+"-missing"))
+	})
+}
+
+func TestContainerDefaultObjectHeaders(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		defaults := schwift.NewObjectHeaders()
+		defaults.ContentType().Set("text/plain")
+		defaults.Metadata().Set("origin", "test")
+		dc := c.WithDefaultObjectHeaders(defaults)
+
+		// defaults are applied on upload through the container handle...
+		obj := dc.Object("with-defaults")
+		expectSuccess(t, obj.Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, nil))
+		hdr, err := c.Object("with-defaults").Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "text/plain")
+		expectString(t, hdr.Metadata().Get("origin"), "test")
+
+		// ...but explicitly given headers take precedence
+		ropts := &schwift.RequestOptions{Headers: schwift.Headers{"Content-Type": "application/json"}}
+		expectSuccess(t, obj.Upload(context.TODO(), strings.NewReader("{}"), nil, ropts))
+		hdr, err = c.Object("with-defaults").Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "application/json")
+		expectString(t, hdr.Metadata().Get("origin"), "test")
+
+		// defaults do not apply through other handles for the same container
+		expectSuccess(t, c.Object("without-defaults").Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, nil))
+		hdr, err = c.Object("without-defaults").Headers(context.TODO())
+		expectSuccess(t, err)
+		expectString(t, hdr.Metadata().Get("origin"), "")
+	})
+}
+
 func TestObjectCopy(t *testing.T) {
 	testWithContainer(t, func(c *schwift.Container) {
 		obj1 := c.Object("location1")
@@ -202,6 +418,91 @@ func TestObjectCopy(t *testing.T) {
 	})
 }
 
+func TestObjectCopyMetadata(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		sourceHdr := schwift.NewObjectHeaders()
+		sourceHdr.ContentType().Set("text/plain")
+		sourceHdr.Metadata().Set("Color", "blue")
+		sourceHdr.Metadata().Set("Shape", "round")
+		overrideHdr := schwift.NewObjectHeaders()
+		overrideHdr.Metadata().Set("Color", "red")
+
+		// expectCopyMetadata checks the headers of a copy made with `overrideHdr`
+		expectCopyMetadata := func(obj *schwift.Object, freshMetadata bool) {
+			t.Helper()
+			hdr, err := obj.Headers(ctx)
+			expectSuccess(t, err)
+			expectString(t, hdr.ContentType().Get(), "text/plain")
+			expectString(t, hdr.Metadata().Get("Color"), "red")
+			if freshMetadata {
+				expectString(t, hdr.Metadata().Get("Shape"), "")
+			} else {
+				expectString(t, hdr.Metadata().Get("Shape"), "round")
+			}
+		}
+
+		// plain objects
+		src := c.Object("plain")
+		expectSuccess(t, src.Upload(ctx, bytes.NewReader(objectExampleContent), nil, sourceHdr.ToOpts()))
+		for _, fresh := range []bool{false, true} {
+			dst := c.Object(fmt.Sprintf("plain-copy-%t", fresh))
+			opts := &schwift.CopyOptions{FreshMetadata: fresh}
+			expectSuccess(t, src.CopyTo(ctx, dst, opts, overrideHdr.ToOpts()))
+			expectCopyMetadata(dst, fresh)
+			expectObjectContent(t, dst, objectExampleContent)
+		}
+
+		// large objects are flattened, unless a shallow copy is requested
+		for _, strategy := range []schwift.LargeObjectStrategy{schwift.StaticLargeObject, schwift.DynamicLargeObject} {
+			strategyStr := "slo"
+			if strategy == schwift.DynamicLargeObject {
+				strategyStr = "dlo"
+			}
+			src := c.Object(strategyStr)
+			lo, err := src.AsNewLargeObject(ctx, schwift.SegmentingOptions{
+				SegmentContainer: c,
+				SegmentPrefix:    strategyStr + "-segments/",
+				Strategy:         strategy,
+			}, nil)
+			expectSuccess(t, err)
+			expectSuccess(t, lo.Append(ctx, bytes.NewReader(objectExampleContent), 4, nil))
+			expectSuccess(t, lo.WriteManifest(ctx, sourceHdr.ToOpts()))
+
+			for _, shallow := range []bool{false, true} {
+				for _, fresh := range []bool{false, true} {
+					dst := c.Object(fmt.Sprintf("%s-copy-%t-%t", strategyStr, shallow, fresh))
+					opts := &schwift.CopyOptions{FreshMetadata: fresh, ShallowCopyLargeObjects: shallow}
+					expectSuccess(t, src.CopyTo(ctx, dst, opts, overrideHdr.ToOpts()))
+					expectCopyMetadata(dst, fresh)
+					expectObjectContent(t, dst, objectExampleContent)
+					hdr, err := dst.Headers(ctx)
+					expectSuccess(t, err)
+					expectBool(t, hdr.IsLargeObject(), shallow)
+				}
+			}
+		}
+
+		// symlinks are followed, unless a shallow copy is requested
+		link := c.Object("symlink")
+		expectSuccess(t, link.SymlinkTo(ctx, src, nil, sourceHdr.ToOpts()))
+		for _, fresh := range []bool{false, true} {
+			dst := c.Object(fmt.Sprintf("symlink-copy-%t", fresh))
+			opts := &schwift.CopyOptions{FreshMetadata: fresh, ShallowCopySymlinks: true}
+			expectSuccess(t, link.CopyTo(ctx, dst, opts, overrideHdr.ToOpts()))
+			expectObjectSymlink(t, dst, src)
+			hdr, _, err := dst.SymlinkHeaders(ctx)
+			expectSuccess(t, err)
+			expectString(t, hdr.Metadata().Get("Color"), "red")
+			if fresh {
+				expectString(t, hdr.Metadata().Get("Shape"), "")
+			} else {
+				expectString(t, hdr.Metadata().Get("Shape"), "round")
+			}
+		}
+	})
+}
+
 func TestSymlinkOperations(t *testing.T) {
 	testWithContainer(t, func(c *schwift.Container) {
 		// create a test object that we can link to
@@ -256,23 +557,12 @@ func TestSymlinkOperations(t *testing.T) {
 
 func expectObjectExistence(t *testing.T, obj *schwift.Object, expectedExists bool) {
 	t.Helper()
-	obj.Invalidate()
-	actualExists, err := obj.Exists(context.TODO())
-	expectSuccess(t, err)
-	expectBool(t, actualExists, expectedExists)
+	schwifttest.ExpectObjectExistence(t, obj, expectedExists)
 }
 
 func expectObjectContent(t *testing.T, obj *schwift.Object, expected []byte) {
 	t.Helper()
-	str, err := obj.Download(context.TODO(), nil).AsString()
-	expectSuccess(t, err)
-	expectString(t, str, string(expected))
-	obj.Invalidate()
-	hdr, err := obj.Headers(context.TODO())
-	expectSuccess(t, err)
-	if !hdr.IsLargeObject() {
-		expectString(t, hdr.Etag().Get(), etagOf(expected))
-	}
+	schwifttest.ExpectObjectContent(t, obj, expected)
 }
 
 func expectObjectSymlink(t *testing.T, source, expectedTarget *schwift.Object) {
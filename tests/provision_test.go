@@ -0,0 +1,68 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestAccountProvision(t *testing.T) {
+	testWithAccount(t, func(a *schwift.Account) {
+		containerName := getRandomName()
+		hdr := schwift.NewContainerHeaders()
+		hdr.ReadACL().Set(".r:*")
+		hdr.ObjectCountQuota().Set(42)
+		spec := schwift.AccountSpec{
+			Containers: []schwift.ContainerSpec{{Name: containerName, Headers: hdr}},
+		}
+
+		// dry run does not create the container
+		changes, err := a.Provision(context.TODO(), spec, &schwift.ProvisionOptions{DryRun: true})
+		expectSuccess(t, err)
+		expectInt(t, len(changes), 1)
+		expectBool(t, changes[0].Created, true)
+		expectContainerExistence(t, a.Container(containerName), false)
+
+		// actual run creates the container
+		changes, err = a.Provision(context.TODO(), spec, nil)
+		expectSuccess(t, err)
+		expectInt(t, len(changes), 1)
+		expectBool(t, changes[0].Created, true)
+		expectHeaders(t, changes[0].Headers, hdr.Headers)
+		expectContainerExistence(t, a.Container(containerName), true)
+
+		// second run is a no-op
+		changes, err = a.Provision(context.TODO(), spec, nil)
+		expectSuccess(t, err)
+		expectInt(t, len(changes), 0)
+
+		// changing the spec yields a minimal diff
+		hdr.ObjectCountQuota().Set(23)
+		changes, err = a.Provision(context.TODO(), spec, nil)
+		expectSuccess(t, err)
+		expectInt(t, len(changes), 1)
+		expectBool(t, changes[0].Created, false)
+		expectHeaders(t, changes[0].Headers, map[string]string{"X-Container-Meta-Quota-Count": "23"})
+
+		expectSuccess(t, a.Container(containerName).Delete(context.TODO(), nil))
+	})
+}
@@ -0,0 +1,106 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/replicate"
+)
+
+func TestReplicateContainer(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+
+		// populate source container with a plain object, a symlink and a large object
+		plain := c.Object("plain")
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("text/plain")
+		hdr.Metadata().Set("Foo", "bar")
+		expectSuccess(t, plain.Upload(ctx, bytes.NewReader([]byte("hello")), nil, hdr.ToOpts()))
+		expectSuccess(t, c.Object("link").SymlinkTo(ctx, plain, nil, nil))
+
+		segment1 := getRandomSegmentContent(128)
+		segment2 := getRandomSegmentContent(128)
+		lo, err := c.Object("large").AsNewLargeObject(ctx, schwift.SegmentingOptions{
+			SegmentContainer: c,
+			SegmentPrefix:    "segments/",
+		}, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, lo.Append(ctx, bytes.NewReader([]byte(segment1+segment2)), 128, nil))
+		expectSuccess(t, lo.WriteManifest(ctx, nil))
+
+		target := c.Account().Container(getRandomName())
+		checkpoint := &replicate.FileCheckpoint{Path: filepath.Join(t.TempDir(), "checkpoint.json")}
+
+		// first run copies everything: plain, link, large and both segments
+		result, err := replicate.Container(ctx, c, target, &replicate.Options{
+			Concurrency: 3,
+			Checkpoint:  checkpoint,
+		})
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsCopied, 5)
+		expectUint64(t, result.ObjectsSkipped, 0)
+
+		expectObjectContent(t, target.Object("plain"), []byte("hello"))
+		hdr, err = target.Object("plain").Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, hdr.ContentType().Get(), "text/plain")
+		expectString(t, hdr.Metadata().Get("Foo"), "bar")
+
+		expectObjectContent(t, target.Object("large"), []byte(segment1+segment2))
+		hdr, err = target.Object("large").Headers(ctx)
+		expectSuccess(t, err)
+		expectBool(t, hdr.IsStaticLargeObject(), true)
+
+		_, linkTarget, err := target.Object("link").SymlinkHeaders(ctx)
+		expectSuccess(t, err)
+		if linkTarget == nil {
+			t.Error("expected replicated link to be a symlink")
+		}
+
+		marker, err := checkpoint.Load(c.Name())
+		expectSuccess(t, err)
+		expectString(t, marker, "segments/0000000000000002")
+
+		// second run is resumed from the checkpoint and does not look at any objects
+		result, err = replicate.Container(ctx, c, target, &replicate.Options{Checkpoint: checkpoint})
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsCopied, 0)
+		expectUint64(t, result.ObjectsSkipped, 5)
+
+		// without checkpoint, objects are skipped because they are up to date
+		expectSuccess(t, plain.Upload(ctx, bytes.NewReader([]byte("hello world")), nil, nil))
+		result, err = replicate.Container(ctx, c, target, nil)
+		expectSuccess(t, err)
+		expectUint64(t, result.ObjectsCopied, 1)
+		expectUint64(t, result.ObjectsSkipped, 4)
+		expectObjectContent(t, target.Object("plain"), []byte("hello world"))
+
+		// cleanup
+		expectSuccess(t, target.Objects().Foreach(ctx, func(o *schwift.Object) error {
+			return o.Delete(ctx, nil, nil)
+		}))
+		expectSuccess(t, target.Delete(ctx, nil))
+	})
+}
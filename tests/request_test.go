@@ -0,0 +1,78 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestRawRequests(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		backend := c.Account().Backend()
+		obj := c.Object("raw")
+
+		// PUT with custom body and headers
+		resp, err := obj.NewRequest(http.MethodPut, nil).
+			WithBody(bytes.NewReader(objectExampleContent)).
+			WithHeader("Content-Type", "text/plain").
+			WithExpectedStatusCodes(http.StatusCreated).
+			Do(context.TODO(), backend)
+		if !expectSuccess(t, err) {
+			return
+		}
+		expectString(t, resp.Header.Get("Etag"), etagOf(objectExampleContent))
+		expectSuccess(t, resp.Body.Close())
+
+		// HEAD with custom accepted status codes
+		resp, err = obj.NewRequest(http.MethodHead, nil).
+			WithExpectedStatusCodes(http.StatusOK, http.StatusNotFound).
+			Do(context.TODO(), backend)
+		if !expectSuccess(t, err) {
+			return
+		}
+		expectInt(t, resp.StatusCode, http.StatusOK)
+		expectString(t, resp.Header.Get("Content-Type"), "text/plain")
+		expectSuccess(t, resp.Body.Close())
+
+		// GET on container with custom query parameters
+		resp, err = c.NewRequest(http.MethodGet, nil).
+			WithValue("prefix", "ra").
+			WithExpectedStatusCodes(http.StatusOK).
+			Do(context.TODO(), backend)
+		if !expectSuccess(t, err) {
+			return
+		}
+		var buf bytes.Buffer
+		_, err = buf.ReadFrom(resp.Body)
+		expectSuccess(t, err)
+		expectString(t, buf.String(), "raw\n")
+		expectSuccess(t, resp.Body.Close())
+
+		// unexpected status codes are reported as usual
+		_, err = c.Object("missing").NewRequest(http.MethodHead, nil).
+			WithExpectedStatusCodes(http.StatusOK).
+			Do(context.TODO(), backend)
+		expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+	})
+}
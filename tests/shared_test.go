@@ -20,56 +20,17 @@ package tests
 
 import (
 	"context"
-	"crypto/md5" //nolint:gosec // Etag uses md5
-	"crypto/rand"
-	"encoding/hex"
 	"math"
-	"os"
 	"testing"
 
-	"github.com/gophercloud/gophercloud/v2"
-	"github.com/gophercloud/gophercloud/v2/openstack"
-	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/swauth"
-	"github.com/gophercloud/utils/v2/openstack/clientconfig"
-
 	"github.com/majewsky/schwift/v2"
-	"github.com/majewsky/schwift/v2/gopherschwift"
+	"github.com/majewsky/schwift/v2/config"
+	"github.com/majewsky/schwift/v2/schwifttest"
 )
 
 func testWithAccount(t *testing.T, testCode func(a *schwift.Account)) {
-	stAuth := os.Getenv("ST_AUTH")
-	stUser := os.Getenv("ST_USER")
-	stKey := os.Getenv("ST_KEY")
-	var client *gophercloud.ServiceClient
-
-	if stAuth == "" && stUser == "" && stKey == "" {
-		// option 1: Keystone authentication
-		provider, err := clientconfig.AuthenticatedClient(context.TODO(), nil)
-		if err != nil {
-			t.Errorf("clientconfig.AuthenticatedClient returned: " + err.Error())
-			t.Error("probably missing Swift credentials (need either ST_AUTH, ST_USER, ST_KEY or OS_* variables)")
-			return
-		}
-		client, err = openstack.NewObjectStorageV1(provider, gophercloud.EndpointOpts{})
-		if err != nil {
-			t.Errorf("openstack.NewObjectStorageV1 returned: " + err.Error())
-			return
-		}
-	} else {
-		// option 2: Swift authentication v1
-		provider, err := openstack.NewClient(stAuth)
-		if err != nil {
-			t.Errorf("openstack.NewClient returned: " + err.Error())
-			return
-		}
-		client, err = swauth.NewObjectStorageV1(context.TODO(), provider, swauth.AuthOpts{User: stUser, Key: stKey})
-		if err != nil {
-			t.Errorf("swauth.NewObjectStorageV1 returned: " + err.Error())
-			return
-		}
-	}
-
-	account, err := gopherschwift.Wrap(client, nil)
+	// credentials are taken from either ST_AUTH, ST_USER, ST_KEY or OS_* variables
+	account, err := config.FromEnv().Connect(context.TODO())
 	if err != nil {
 		t.Error(err.Error())
 		return
@@ -108,8 +69,7 @@ func testWithContainer(t *testing.T, testCode func(c *schwift.Container)) {
 ////////////////////////////////////////////////////////////////////////////////
 
 func etagOf(buf []byte) string {
-	hash := md5.Sum(buf) //nolint:gosec // Etag uses md5
-	return hex.EncodeToString(hash[:])
+	return schwifttest.Etag(buf)
 }
 
 func etagOfString(buf string) string {
@@ -117,21 +77,11 @@ func etagOfString(buf string) string {
 }
 
 func getRandomName() string {
-	var buf [16]byte
-	_, err := rand.Read(buf[:])
-	if err != nil {
-		panic(err.Error())
-	}
-	return hex.EncodeToString(buf[:])
+	return schwifttest.RandomName()
 }
 
 func getRandomSegmentContent(length int) string { //nolint:unparam
-	buf := make([]byte, length/2)
-	_, err := rand.Read(buf)
-	if err != nil {
-		panic(err.Error())
-	}
-	return hex.EncodeToString(buf)
+	return schwifttest.RandomContent(length)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -198,26 +148,5 @@ func expectSuccess(t *testing.T, actual error) (ok bool) {
 
 func expectHeaders(t *testing.T, actual, expected map[string]string) {
 	t.Helper()
-	reported := make(map[string]bool)
-
-	for k, av := range actual {
-		ev, exists := expected[k]
-		if !exists {
-			ev = "<not set>"
-		}
-		if av != ev {
-			t.Errorf(`expected "%s: %s", got "%s: %s" instead`, k, ev, k, av)
-			reported[k] = true
-		}
-	}
-
-	for k, ev := range expected {
-		av, exists := actual[k]
-		if !exists {
-			av = "<not set>"
-		}
-		if av != ev && !reported[k] {
-			t.Errorf(`expected "%s: %s", got "%s: %s" instead`, k, ev, k, av)
-		}
-	}
+	schwifttest.ExpectHeaders(t, actual, expected)
 }
@@ -0,0 +1,75 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestContainerStat(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		hdr := schwift.NewContainerHeaders()
+		hdr.BytesUsedQuota().Set(1 << 20)
+		expectSuccess(t, c.Update(context.TODO(), hdr, nil))
+		expectSuccess(t, c.Object("foo").Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, nil))
+
+		c.Invalidate()
+		stat, err := c.Stat(context.TODO())
+		if !expectSuccess(t, err) {
+			return
+		}
+		expectUint64(t, stat.ObjectCount, 1)
+		expectUint64(t, stat.BytesUsed, uint64(len(objectExampleContent)))
+		expectUint64(t, stat.QuotaBytes, 1<<20)
+		expectUint64(t, stat.QuotaCount, 0)
+		expectBool(t, stat.StoragePolicy != "", true)
+		expectBool(t, stat.CreatedAt.IsZero(), false)
+	})
+}
+
+func TestAccountStat(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		expectSuccess(t, c.Object("foo").Upload(context.TODO(), bytes.NewReader(objectExampleContent), nil, nil))
+
+		a := c.Account()
+		a.Invalidate()
+		stat, err := a.Stat(context.TODO())
+		if !expectSuccess(t, err) {
+			return
+		}
+		// we cannot assume that the test account is otherwise empty
+		expectBool(t, stat.ContainerCount >= 1, true)
+		expectBool(t, stat.ObjectCount >= 1, true)
+		expectBool(t, len(stat.Policies) >= 1, true)
+
+		var total schwift.StoragePolicyStat
+		for _, ps := range stat.Policies {
+			total.ContainerCount += ps.ContainerCount
+			total.ObjectCount += ps.ObjectCount
+			total.BytesUsed += ps.BytesUsed
+		}
+		expectUint64(t, total.ContainerCount, stat.ContainerCount)
+		expectUint64(t, total.ObjectCount, stat.ObjectCount)
+		expectUint64(t, total.BytesUsed, stat.BytesUsed)
+	})
+}
@@ -0,0 +1,195 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+	"github.com/majewsky/schwift/v2/internal/fakeswift"
+	"github.com/majewsky/schwift/v2/swauth"
+)
+
+// fakeSwauth implements just enough of the swauth API to test package swauth.
+// Storage requests are forwarded into a fakeswift cluster.
+type fakeSwauth struct {
+	storage    *fakeswift.Backend
+	serverURL  string
+	mutex      sync.Mutex
+	users      map[string]map[string]string // account -> user -> key
+	tokenCount int
+}
+
+func (s *fakeSwauth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case r.URL.Path == "/auth/v1.0":
+		accountName, userName, _ := strings.Cut(r.Header.Get("X-Auth-User"), ":")
+		key, exists := s.users[accountName][userName]
+		if !exists || key != r.Header.Get("X-Auth-Key") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.tokenCount++
+		w.Header().Set("X-Storage-Url", s.serverURL+"/v1/AUTH_"+accountName)
+		w.Header().Set("X-Auth-Token", fmt.Sprintf("token%d", s.tokenCount))
+		w.WriteHeader(http.StatusOK)
+
+	case strings.HasPrefix(r.URL.Path, "/auth/v2/"):
+		if r.Header.Get("X-Auth-Admin-User") != ".super_admin" || r.Header.Get("X-Auth-Admin-Key") != "swauthkey" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.serveAdmin(w, r, strings.TrimPrefix(r.URL.Path, "/auth/v2/"))
+
+	case strings.HasPrefix(r.URL.Path, "/v1/"):
+		// only the most recent token is valid
+		if r.Header.Get("X-Auth-Token") != fmt.Sprintf("token%d", s.tokenCount) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		resp, err := s.storage.Do(r.Clone(r.Context()))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer resp.Body.Close()
+		for key, values := range resp.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body) //nolint:errcheck // test server
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeSwauth) serveAdmin(w http.ResponseWriter, r *http.Request, path string) {
+	accountName, userName, _ := strings.Cut(path, "/")
+	switch {
+	case r.Method == http.MethodGet && accountName == "":
+		var data struct {
+			Accounts []map[string]string `json:"accounts"`
+		}
+		for name := range s.users {
+			data.Accounts = append(data.Accounts, map[string]string{"name": name})
+		}
+		slices.SortFunc(data.Accounts, func(a, b map[string]string) int { return strings.Compare(a["name"], b["name"]) })
+		_ = json.NewEncoder(w).Encode(data) //nolint:errcheck // test server
+	case r.Method == http.MethodPut && userName == "":
+		if s.users[accountName] == nil {
+			s.users[accountName] = make(map[string]string)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && userName == "":
+		if len(s.users[accountName]) > 0 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		delete(s.users, accountName)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && userName == "":
+		var data struct {
+			Users []map[string]string `json:"users"`
+		}
+		for name := range s.users[accountName] {
+			data.Users = append(data.Users, map[string]string{"name": name})
+		}
+		_ = json.NewEncoder(w).Encode(data) //nolint:errcheck // test server
+	case r.Method == http.MethodPut:
+		if s.users[accountName] == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.users[accountName][userName] = r.Header.Get("X-Auth-User-Key")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		delete(s.users[accountName], userName)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestSwauth(t *testing.T) {
+	fake := &fakeSwauth{
+		storage: fakeswift.New(),
+		users:   make(map[string]map[string]string),
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	fake.serverURL = server.URL
+
+	ctx := context.TODO()
+	client := &swauth.Client{
+		AuthURL:   server.URL + "/auth/",
+		AdminUser: ".super_admin",
+		AdminKey:  "swauthkey",
+	}
+
+	// admin API
+	expectSuccess(t, client.CreateAccount(ctx, "test"))
+	expectSuccess(t, client.CreateUser(ctx, "test", "tester", "testing", &swauth.UserOptions{Admin: true}))
+	accountNames, err := client.ListAccounts(ctx)
+	expectSuccess(t, err)
+	expectString(t, strings.Join(accountNames, ","), "test")
+	userNames, err := client.ListUsers(ctx, "test")
+	expectSuccess(t, err)
+	expectString(t, strings.Join(userNames, ","), "tester")
+	err = client.CreateUser(ctx, "missing", "tester", "testing", nil)
+	expectBool(t, schwift.Is(err, http.StatusNotFound), true)
+
+	// connecting as a user
+	account, err := client.Connect(ctx, "test", "tester", "testing")
+	expectSuccess(t, err)
+	expectString(t, account.Name(), "AUTH_test")
+	_, err = account.Container("foo").EnsureExists(ctx)
+	expectSuccess(t, err)
+
+	// when the token is invalidated, the backend authenticates again
+	_, err = client.Connect(ctx, "test", "tester", "testing")
+	expectSuccess(t, err)
+	account.Container("foo").Invalidate()
+	exists, err := account.Container("foo").Exists(ctx)
+	expectSuccess(t, err)
+	expectBool(t, exists, true)
+
+	_, err = client.Connect(ctx, "test", "tester", "wrong")
+	expectBool(t, schwift.Is(err, http.StatusUnauthorized), true)
+
+	// cleanup
+	expectSuccess(t, account.Container("foo").Delete(ctx, nil))
+	expectSuccess(t, client.DeleteUser(ctx, "test", "tester"))
+	expectSuccess(t, client.DeleteAccount(ctx, "test"))
+	accountNames, err = client.ListAccounts(ctx)
+	expectSuccess(t, err)
+	expectInt(t, len(accountNames), 0)
+}
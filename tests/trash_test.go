@@ -0,0 +1,93 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestDeleteToTrash(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		trash, err := c.Account().Container(c.Name() + "-trash").EnsureExists(ctx)
+		expectSuccess(t, err)
+		defer func() {
+			_, err := trash.PurgeTrash(ctx, time.Time{}, nil)
+			expectSuccess(t, err)
+			expectSuccess(t, trash.Delete(ctx, nil))
+		}()
+
+		hdr := schwift.NewObjectHeaders()
+		hdr.ContentType().Set("text/plain")
+		hdr.Metadata().Set("Color", "blue")
+		obj := c.Object("foo/bar")
+		expectSuccess(t, obj.Upload(ctx, strings.NewReader("hello"), nil, hdr.ToOpts()))
+
+		// move to trash
+		trashed, err := obj.DeleteToTrash(ctx, trash, 24*time.Hour, nil)
+		expectSuccess(t, err)
+		expectString(t, trashed.Name(), c.Name()+"/foo/bar")
+		expectObjectExistence(t, obj, false)
+		expectObjectContent(t, trashed, []byte("hello"))
+		trashedHdr, err := trashed.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, trashedHdr.Metadata().Get("Trashed-From"), obj.FullName())
+		expectString(t, trashedHdr.Metadata().Get("Trashed-From-Account"), c.Account().Name())
+		expectString(t, trashedHdr.Metadata().Get("Color"), "blue")
+		expectBool(t, trashedHdr.ExpiresAt().Exists(), true)
+
+		// restore
+		restored, err := trashed.RestoreFromTrash(ctx, nil)
+		expectSuccess(t, err)
+		expectString(t, restored.FullName(), obj.FullName())
+		expectObjectExistence(t, trashed, false)
+		expectObjectContent(t, obj, []byte("hello"))
+		restoredHdr, err := obj.Headers(ctx)
+		expectSuccess(t, err)
+		expectString(t, restoredHdr.ContentType().Get(), "text/plain")
+		expectString(t, restoredHdr.Metadata().Get("Color"), "blue")
+		expectBool(t, restoredHdr.Metadata().Get("Trashed-From") == "", true)
+		expectBool(t, restoredHdr.Metadata().Get("Trashed-From-Account") == "", true)
+		expectBool(t, restoredHdr.ExpiresAt().Exists(), false)
+
+		// restoring does not overwrite existing objects
+		trashed, err = obj.DeleteToTrash(ctx, trash, 0, nil)
+		expectSuccess(t, err)
+		expectSuccess(t, obj.Upload(ctx, strings.NewReader("world"), nil, nil))
+		_, err = trashed.RestoreFromTrash(ctx, nil)
+		expectBool(t, errors.Is(err, schwift.ErrAlreadyExists), true)
+		expectObjectContent(t, obj, []byte("world"))
+
+		// purge
+		numDeleted, err := trash.PurgeTrash(ctx, time.Now().Add(-time.Hour), nil)
+		expectSuccess(t, err)
+		expectInt(t, numDeleted, 0)
+		numDeleted, err = trash.PurgeTrash(ctx, time.Time{}, nil)
+		expectSuccess(t, err)
+		expectInt(t, numDeleted, 1)
+		expectObjectExistence(t, trashed, false)
+		expectSuccess(t, obj.Delete(ctx, nil, nil))
+	})
+}
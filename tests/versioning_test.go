@@ -0,0 +1,84 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/majewsky/schwift/v2"
+)
+
+func TestObjectUndelete(t *testing.T) {
+	testWithContainer(t, func(c *schwift.Container) {
+		ctx := context.TODO()
+		obj := c.Object("foo")
+
+		// without legacy versioning, there is nothing to undelete
+		err := obj.Undelete(ctx, nil)
+		expectBool(t, errors.Is(err, schwift.ErrNotVersioned), true)
+
+		archive, err := c.Account().Container(c.Name() + "-versions").EnsureExists(ctx)
+		expectSuccess(t, err)
+		defer func() {
+			expectSuccess(t, archive.Object("003foo/1700000000.00000").Delete(ctx, nil, nil))
+			expectSuccess(t, archive.Delete(ctx, nil))
+		}()
+		hdr := schwift.NewContainerHeaders()
+		hdr.HistoryLocation().Set(archive.Name())
+		expectSuccess(t, c.Update(ctx, hdr, nil))
+
+		// populate the archive like Swift would when "foo" is overwritten and then
+		// deleted (the "foobar" version belongs to a different object)
+		upload := func(name, content, contentType string) {
+			t.Helper()
+			hdr := schwift.NewObjectHeaders()
+			hdr.ContentType().Set(contentType)
+			expectSuccess(t, archive.Object(name).Upload(ctx, strings.NewReader(content), nil, hdr.ToOpts()))
+		}
+		upload("003foo/1700000000.00000", "first", "text/plain")
+		upload("003foo/1700000001.00000", "second", "text/plain")
+		upload("003foo/1700000002.00000", "", "application/x-deleted;swift_versions_deleted=1")
+		upload("006foobar/1700000003.00000", "other", "text/plain")
+
+		// the newest version is restored and removed from the archive
+		expectSuccess(t, obj.Undelete(ctx, nil))
+		expectObjectContent(t, obj, []byte("second"))
+		objects, err := archive.Objects().Collect(ctx)
+		expectSuccess(t, err)
+		expectObjectNames(t, objects, "003foo/1700000000.00000", "006foobar/1700000003.00000")
+
+		// existing objects are not overwritten
+		err = obj.Undelete(ctx, nil)
+		expectBool(t, errors.Is(err, schwift.ErrAlreadyExists), true)
+
+		// without archived versions, nothing can be restored
+		expectSuccess(t, archive.Object("006foobar/1700000003.00000").Delete(ctx, nil, nil))
+		err = c.Object("foobar").Undelete(ctx, nil)
+		expectBool(t, errors.Is(err, schwift.ErrNoPreviousVersion), true)
+
+		// disable versioning before cleanup, so that nothing else gets archived
+		hdr = schwift.NewContainerHeaders()
+		hdr.HistoryLocation().Clear()
+		expectSuccess(t, c.Update(ctx, hdr, nil))
+		expectSuccess(t, obj.Delete(ctx, nil, nil))
+	})
+}
@@ -0,0 +1,216 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// TrafficStats contains the traffic counted by a TrafficCounter.
+//
+// Only the payload (i.e. the request and response bodies) is counted. Headers
+// and the overhead of the underlying protocols are not included.
+type TrafficStats struct {
+	Requests      uint64
+	BytesSent     uint64
+	BytesReceived uint64
+}
+
+// TrafficCounter counts the number of bytes sent to and received from Swift,
+// e.g. to attribute ingress and egress costs to the parts of an application
+// that cause them. It is attached to an account with
+// Account.WithTrafficCounter().
+//
+// Bytes are counted when they are actually transferred, i.e. response bodies
+// that are closed without being read fully count only as far as they were
+// read. The zero value is ready to use. All methods are safe for concurrent
+// use.
+type TrafficCounter struct {
+	// If true, traffic is also counted separately for each container, see
+	// ContainerSnapshot(). This must not be changed after the TrafficCounter has
+	// been attached to an account.
+	PerContainer bool
+
+	total      trafficCells
+	mutex      sync.Mutex
+	containers map[string]*trafficCells
+}
+
+// trafficCells is the atomic counterpart of TrafficStats.
+type trafficCells struct {
+	requests      atomic.Uint64
+	bytesSent     atomic.Uint64
+	bytesReceived atomic.Uint64
+}
+
+func (c *trafficCells) snapshot() TrafficStats {
+	return TrafficStats{
+		Requests:      c.requests.Load(),
+		BytesSent:     c.bytesSent.Load(),
+		BytesReceived: c.bytesReceived.Load(),
+	}
+}
+
+func (c *trafficCells) reset() TrafficStats {
+	return TrafficStats{
+		Requests:      c.requests.Swap(0),
+		BytesSent:     c.bytesSent.Swap(0),
+		BytesReceived: c.bytesReceived.Swap(0),
+	}
+}
+
+// Snapshot returns the traffic counted so far across all containers.
+func (tc *TrafficCounter) Snapshot() TrafficStats {
+	return tc.total.snapshot()
+}
+
+// ContainerSnapshot returns the traffic counted so far for each container.
+// Requests on the account itself (e.g. container listings) are counted under
+// the empty container name. If PerContainer is not set, the result is empty.
+//
+// Containers are identified by name only. If the TrafficCounter is attached to
+// multiple accounts, containers with the same name are counted together.
+func (tc *TrafficCounter) ContainerSnapshot() map[string]TrafficStats {
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	result := make(map[string]TrafficStats, len(tc.containers))
+	for name, cells := range tc.containers {
+		result[name] = cells.snapshot()
+	}
+	return result
+}
+
+// Reset sets all counters back to zero, and returns the traffic counted before
+// the reset (like Snapshot()). Traffic that is counted while the reset is in
+// progress is attributed either to the returned value or to the next
+// snapshot, but never lost or counted twice.
+func (tc *TrafficCounter) Reset() TrafficStats {
+	tc.mutex.Lock()
+	for _, cells := range tc.containers {
+		cells.reset()
+	}
+	tc.mutex.Unlock()
+	return tc.total.reset()
+}
+
+// cellsForContainer returns the counters for the given container, or nil if
+// per-container counting is disabled.
+func (tc *TrafficCounter) cellsForContainer(containerName string) *trafficCells {
+	if !tc.PerContainer {
+		return nil
+	}
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	cells := tc.containers[containerName]
+	if cells == nil {
+		if tc.containers == nil {
+			tc.containers = make(map[string]*trafficCells)
+		}
+		cells = &trafficCells{}
+		tc.containers[containerName] = cells
+	}
+	return cells
+}
+
+// WithTrafficCounter returns a handle to the same account that counts all
+// traffic in the given TrafficCounter. The counter carries over to accounts
+// obtained from the returned Account via SwitchAccount() etc., and the same
+// counter may be attached to multiple accounts, in which case their traffic
+// is counted together.
+func (a *Account) WithTrafficCounter(tc *TrafficCounter) *Account {
+	return a.withBackend(trafficBackend{a.backend, tc})
+}
+
+// trafficBackend wraps a Backend to count traffic in a TrafficCounter. It is
+// used by Account.WithTrafficCounter().
+type trafficBackend struct {
+	inner   Backend
+	counter *TrafficCounter
+}
+
+func (b trafficBackend) EndpointURL() string {
+	return b.inner.EndpointURL()
+}
+
+func (b trafficBackend) Clone(newEndpointURL string) Backend {
+	return trafficBackend{b.inner.Clone(newEndpointURL), b.counter}
+}
+
+func (b trafficBackend) Do(req *http.Request) (*http.Response, error) {
+	var cells *trafficCells
+	if b.counter.PerContainer {
+		containerName, _, ok := splitRequestURL(b.inner.EndpointURL(), req.URL)
+		if ok {
+			cells = b.counter.cellsForContainer(containerName)
+		}
+	}
+
+	b.counter.total.requests.Add(1)
+	if cells != nil {
+		cells.requests.Add(1)
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &trafficCountingBody{req.Body, &b.counter.total.bytesSent, cellOrNil(cells, true)}
+	}
+
+	resp, err := b.inner.Do(req)
+	if err == nil && resp.Body != nil {
+		resp.Body = &trafficCountingBody{resp.Body, &b.counter.total.bytesReceived, cellOrNil(cells, false)}
+	}
+	return resp, err
+}
+
+// cellOrNil returns the bytesSent or bytesReceived cell of the given
+// trafficCells, or nil if there are none.
+func cellOrNil(cells *trafficCells, sent bool) *atomic.Uint64 {
+	switch {
+	case cells == nil:
+		return nil
+	case sent:
+		return &cells.bytesSent
+	default:
+		return &cells.bytesReceived
+	}
+}
+
+// trafficCountingBody wraps a request or response body to count the bytes
+// that are read from it.
+type trafficCountingBody struct {
+	inner     io.ReadCloser
+	total     *atomic.Uint64
+	container *atomic.Uint64 // may be nil
+}
+
+func (b *trafficCountingBody) Read(buf []byte) (int, error) {
+	n, err := b.inner.Read(buf)
+	if n > 0 {
+		b.total.Add(uint64(n))
+		if b.container != nil {
+			b.container.Add(uint64(n))
+		}
+	}
+	return n, err
+}
+
+func (b *trafficCountingBody) Close() error {
+	return b.inner.Close()
+}
@@ -0,0 +1,74 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTrafficCounter(t *testing.T) {
+	ctx := context.Background()
+
+	// the backend stores the body of each PUT request, and serves the last
+	// stored body in response to every GET request
+	var stored []byte
+	a, err := InitializeAccount(&testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodGet {
+			return testResponse(http.StatusOK, string(stored)), nil
+		}
+		if req.Body != nil {
+			var err error
+			stored, err = io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			req.Body.Close()
+		}
+		return testResponse(http.StatusCreated, ""), nil
+	}})
+	must(t, err)
+	tc := &TrafficCounter{PerContainer: true}
+	a = a.WithTrafficCounter(tc)
+
+	must(t, a.Container("foo").Object("bar").Upload(ctx, strings.NewReader("hello world"), nil, nil))
+	buf, err := a.Container("qux").Object("bar").Download(ctx, nil).AsByteSlice()
+	must(t, err)
+	expectString(t, "hello world", string(buf))
+
+	expectTrafficStats(t, "total", TrafficStats{Requests: 2, BytesSent: 11, BytesReceived: 11}, tc.Snapshot())
+	perContainer := tc.ContainerSnapshot()
+	expectTrafficStats(t, "foo", TrafficStats{Requests: 1, BytesSent: 11, BytesReceived: 0}, perContainer["foo"])
+	expectTrafficStats(t, "qux", TrafficStats{Requests: 1, BytesSent: 0, BytesReceived: 11}, perContainer["qux"])
+
+	// Reset() returns the previous values
+	expectTrafficStats(t, "reset", TrafficStats{Requests: 2, BytesSent: 11, BytesReceived: 11}, tc.Reset())
+	expectTrafficStats(t, "total after reset", TrafficStats{}, tc.Snapshot())
+	expectTrafficStats(t, "foo after reset", TrafficStats{}, tc.ContainerSnapshot()["foo"])
+}
+
+func expectTrafficStats(t *testing.T, label string, expected, actual TrafficStats) {
+	t.Helper()
+	if expected != actual {
+		t.Errorf("expected %s traffic to be %#v, but got %#v", label, expected, actual)
+	}
+}
@@ -0,0 +1,192 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// TLSOptions contains TLS settings for the HTTP client of a Backend. The
+// built-in backends in the gopherschwift and swauth packages accept this type,
+// so that callers do not need to construct their own http.Client just to
+// adjust TLS settings.
+type TLSOptions struct {
+	// PEM-encoded CA certificates that are trusted in addition to the system's
+	// root CAs.
+	CACertificatesPEM []byte
+	// If set, the system's root CAs are not trusted, only the ones given in
+	// CACertificatesPEM.
+	IgnoreSystemRoots bool
+	// Client certificates that are presented to the server, e.g. as obtained
+	// from tls.LoadX509KeyPair().
+	ClientCertificates []tls.Certificate
+	// The minimum TLS version that is accepted. Defaults to tls.VersionTLS12.
+	MinVersion uint16
+	// Whether the server may request renegotiation. Defaults to
+	// tls.RenegotiateNever.
+	Renegotiation tls.RenegotiationSupport
+	// If not empty, the server's certificate is accepted if and only if it is
+	// identical to one of these certificates. The certificate chain and the
+	// hostname are not verified in this case. This is intended only for
+	// development environments; see TrustSelfSignedEndpoint().
+	PinnedCertificates []*x509.Certificate
+}
+
+// Config builds a tls.Config from these options.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		Certificates:  o.ClientCertificates,
+		MinVersion:    o.MinVersion,
+		Renegotiation: o.Renegotiation,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if len(o.CACertificatesPEM) > 0 || o.IgnoreSystemRoots {
+		pool := x509.NewCertPool()
+		if !o.IgnoreSystemRoots {
+			systemPool, err := x509.SystemCertPool()
+			if err != nil {
+				return nil, fmt.Errorf("cannot load system root CAs: %w", err)
+			}
+			pool = systemPool
+		}
+		if len(o.CACertificatesPEM) > 0 && !pool.AppendCertsFromPEM(o.CACertificatesPEM) {
+			return nil, errors.New("no valid certificates found in CACertificatesPEM")
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(o.PinnedCertificates) > 0 {
+		pinned := o.PinnedCertificates
+		cfg.InsecureSkipVerify = true //nolint:gosec // verification is done by VerifyPeerCertificate below
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("server did not present a certificate")
+			}
+			for _, cert := range pinned {
+				if bytes.Equal(cert.Raw, rawCerts[0]) {
+					return nil
+				}
+			}
+			return errors.New("server certificate does not match any of the pinned certificates")
+		}
+	}
+
+	return cfg, nil
+}
+
+// HTTPClient builds an http.Client that uses these TLS options. This is a
+// shorthand for NewHTTPClient(&o, nil).
+func (o TLSOptions) HTTPClient() (*http.Client, error) {
+	return NewHTTPClient(&o, nil)
+}
+
+// DialFunc is the signature of http.Transport.DialContext. It can be given to
+// the built-in backends in the gopherschwift and swauth packages to control
+// how connections to Swift are established.
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// UnixSocketDialer returns a DialFunc that connects to the Unix domain socket
+// at the given path, regardless of which address is requested. This is useful
+// to reach a Swift proxy running on the same host or in a sidecar container.
+// The endpoint URL of the backend still needs to be a "http://" or "https://"
+// URL; its host part is only used for the Host header (and, for https, for
+// certificate verification).
+func UnixSocketDialer(path string) DialFunc {
+	var d net.Dialer
+	return func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "unix", path)
+	}
+}
+
+// NewHTTPClient builds an http.Client whose transport uses the given TLS
+// options and dial function. Both arguments may be nil. Apart from that, the
+// client's transport behaves like http.DefaultTransport.
+func NewHTTPClient(tlsOpts *TLSOptions, dial DialFunc) (*http.Client, error) {
+	defaultTransport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("http.DefaultTransport is not a *http.Transport")
+	}
+	transport := defaultTransport.Clone()
+	if tlsOpts != nil {
+		cfg, err := tlsOpts.Config()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = cfg
+	}
+	if dial != nil {
+		transport.DialContext = dial
+		transport.Proxy = nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// TrustSelfSignedEndpoint connects to the given HTTPS URL, and returns
+// TLSOptions that pin the certificate that the server presents. This is
+// intended for development environments like a Swift-all-in-one, whose
+// self-signed certificate would otherwise be rejected:
+//
+//	tlsOpts, err := schwift.TrustSelfSignedEndpoint(ctx, "https://127.0.0.1:8080/")
+//	account, err := gopherschwift.Wrap(client, &gopherschwift.Options{TLS: &tlsOpts})
+//
+// Since the certificate is trusted on first use without any verification,
+// this function MUST NOT be used in production.
+func TrustSelfSignedEndpoint(ctx context.Context, endpointURL string) (TLSOptions, error) {
+	u, err := url.Parse(endpointURL)
+	if err != nil {
+		return TLSOptions{}, err
+	}
+	if u.Scheme != "https" {
+		return TLSOptions{}, fmt.Errorf("expected https:// URL, got %q", endpointURL)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	dialer := &tls.Dialer{Config: &tls.Config{
+		InsecureSkipVerify: true, //nolint:gosec // we only fetch the certificate to pin it
+		MinVersion:         tls.VersionTLS12,
+	}}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return TLSOptions{}, err
+	}
+	defer conn.Close()
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return TLSOptions{}, errors.New("tls.Dialer did not return a *tls.Conn")
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return TLSOptions{}, fmt.Errorf("%s did not present a certificate", host)
+	}
+	return TLSOptions{PinnedCertificates: certs[:1]}, nil
+}
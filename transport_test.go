@@ -0,0 +1,100 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTLSOptions(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tryRequest := func(opts TLSOptions) error {
+		client, err := opts.HTTPClient()
+		if err != nil {
+			return err
+		}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	// the test server's certificate is not trusted by default
+	if tryRequest(TLSOptions{}) == nil {
+		t.Error("expected request with default TLSOptions to fail")
+	}
+
+	// it can be trusted by adding its CA...
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	must(t, tryRequest(TLSOptions{CACertificatesPEM: caPEM, IgnoreSystemRoots: true}))
+
+	// ...or by pinning it
+	opts, err := TrustSelfSignedEndpoint(context.Background(), srv.URL)
+	must(t, err)
+	must(t, tryRequest(opts))
+
+	// pinning a different certificate does not work
+	opts = TLSOptions{PinnedCertificates: []*x509.Certificate{{Raw: []byte("something else")}}}
+	if tryRequest(opts) == nil {
+		t.Error("expected request with mismatching pinned certificate to fail")
+	}
+
+	// malformed CA certificates are rejected
+	_, err = TLSOptions{CACertificatesPEM: []byte("garbage")}.Config()
+	if err == nil {
+		t.Error("expected error for malformed CACertificatesPEM")
+	}
+}
+
+func TestUnixSocketDialer(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "swift.sock")
+	listener, err := net.Listen("unix", socketPath)
+	must(t, err)
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("hello from " + r.Host))
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+	go srv.Serve(listener) //nolint:errcheck // returns when the server is closed
+	defer srv.Close()
+
+	client, err := NewHTTPClient(nil, UnixSocketDialer(socketPath))
+	must(t, err)
+	resp, err := client.Get("http://swift.local/v1/AUTH_example/")
+	must(t, err)
+	buf, err := io.ReadAll(resp.Body)
+	must(t, err)
+	must(t, resp.Body.Close())
+	expectString(t, "hello from swift.local", string(buf))
+}
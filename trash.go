@@ -0,0 +1,174 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Metadata headers that DeleteToTrash() sets on the copies in the trash
+// container.
+const (
+	trashedFromHeader        = "X-Object-Meta-Trashed-From"
+	trashedFromAccountHeader = "X-Object-Meta-Trashed-From-Account"
+	trashedAtHeader          = "X-Object-Meta-Trashed-At"
+)
+
+// DeleteToTrash deletes this object in a way that can be undone: It is first
+// copied into the given trash container (using a server-side COPY request),
+// and then deleted. The copy is named "$CONTAINER_NAME/$OBJECT_NAME" after the
+// original object, and remembers its original location in the
+// X-Object-Meta-Trashed-From and X-Object-Meta-Trashed-From-Account headers,
+// so that it can be put back with RestoreFromTrash(). If an object with the
+// same name is deleted to the same trash container again, the previous copy is
+// overwritten.
+//
+// If ttl is not zero, the copy in the trash container is set to expire after
+// this duration (using X-Delete-After), so that Swift removes it eventually.
+// Otherwise, use Container.PurgeTrash() to clean up the trash container. The
+// trash container must exist already, and may be located in a different
+// account.
+//
+// Large objects and symlinks are moved to the trash by copying their manifest
+// or the symlink itself. The segments of large objects are not deleted, and
+// since they are shared between the original and the copy in the trash, they
+// do not expire with the copy either.
+//
+// The given RequestOptions are used for both the COPY and the DELETE request.
+// The returned Object is the copy in the trash container.
+func (o *Object) DeleteToTrash(ctx context.Context, trash *Container, ttl time.Duration, opts *RequestOptions) (*Object, error) {
+	trashed := trash.Object(o.FullName())
+	hdr := NewObjectHeaders()
+	hdr.Set(trashedFromHeader, o.FullName())
+	hdr.Set(trashedFromAccountHeader, o.c.a.Name())
+	hdr.Set(trashedAtHeader, time.Now().UTC().Format(time.RFC3339))
+	if ttl != 0 {
+		hdr.ExpiresAfter().Set(ttl)
+	}
+
+	copyOpts := &CopyOptions{
+		ShallowCopySymlinks:     true,
+		ShallowCopyLargeObjects: true,
+	}
+	err := o.CopyTo(ctx, trashed, copyOpts, cloneRequestOptions(opts, hdr.Headers))
+	if err != nil {
+		return nil, err
+	}
+	return trashed, o.Delete(ctx, nil, opts)
+}
+
+// RestoreFromTrash can be called on an object in a trash container that was
+// placed there by DeleteToTrash(). It copies the object back to its original
+// location, removes the metadata and expiry that was added by
+// DeleteToTrash(), and deletes the copy in the trash container. The returned
+// Object is the restored object.
+//
+// If this object was not placed in the trash by DeleteToTrash(), an error is
+// returned. If an object exists at the original location already,
+// ErrAlreadyExists is returned and nothing is changed. If the trash container
+// is in a different account than the original object, the object is restored
+// into its original account (see Account.SwitchAccount()). For objects that
+// were trashed without recording their account, the original container is
+// assumed to be in the same account as the trash container.
+//
+// This operation fails with http.StatusNotFound if this object does not exist.
+func (o *Object) RestoreFromTrash(ctx context.Context, opts *RequestOptions) (*Object, error) {
+	hdr, err := o.fetchHeaders(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	containerName, objectName, ok := strings.Cut(hdr.Get(trashedFromHeader), "/")
+	if !ok || containerName == "" || objectName == "" {
+		return nil, fmt.Errorf("cannot restore %s: missing or malformed %s header", o.FullName(), trashedFromHeader)
+	}
+	account := o.c.a
+	if accountName := hdr.Get(trashedFromAccountHeader); accountName != "" && accountName != account.Name() {
+		account = account.SwitchAccount(accountName)
+	}
+	restored := account.Container(containerName).Object(objectName)
+
+	// like in Object.Create(), the copy is rejected if the target exists already
+	copyOpts := &CopyOptions{
+		ShallowCopySymlinks:     true,
+		ShallowCopyLargeObjects: true,
+	}
+	copyReqOpts := cloneRequestOptions(opts, nil)
+	copyReqOpts.Headers.Set("If-None-Match", "*")
+	err = o.CopyTo(ctx, restored, copyOpts, copyReqOpts)
+	if Is(err, http.StatusPreconditionFailed) {
+		return nil, ErrAlreadyExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the copy inherits our metadata, including what DeleteToTrash() added
+	cleanup := NewObjectHeaders()
+	cleanup.Clear(trashedFromHeader)
+	cleanup.Clear(trashedFromAccountHeader)
+	cleanup.Clear(trashedAtHeader)
+	cleanup.ExpiresAt().Clear()
+	err = restored.UpdatePreservingMetadata(ctx, cleanup, opts)
+	if err != nil {
+		return restored, err
+	}
+	return restored, o.Delete(ctx, nil, opts)
+}
+
+// PurgeTrash deletes all objects from this trash container (see
+// Object.DeleteToTrash()) that were placed there before the given time. If
+// the time is zero, all objects are deleted. The container itself is not
+// deleted.
+//
+// Objects are deleted one listing page at a time using BulkDelete(), so a
+// BulkError may be returned. The return value counts the objects that were
+// deleted before an error occurred.
+func (c *Container) PurgeTrash(ctx context.Context, trashedBefore time.Time, opts *RequestOptions) (int, error) {
+	var result int
+	iter := c.Objects()
+	for {
+		infos, err := iter.NextPageDetailed(ctx, -1)
+		if err != nil {
+			return result, err
+		}
+		if len(infos) == 0 {
+			return result, nil
+		}
+
+		var objects []*Object
+		for _, info := range infos {
+			// the last modification is the COPY into the trash
+			if info.Object != nil && (trashedBefore.IsZero() || info.LastModified.Before(trashedBefore)) {
+				objects = append(objects, info.Object)
+			}
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		numDeleted, _, err := c.a.BulkDelete(ctx, objects, nil, opts)
+		result += numDeleted
+		if err != nil {
+			return result, err
+		}
+	}
+}
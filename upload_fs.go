@@ -0,0 +1,205 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"sync"
+)
+
+// UploadFSOptions contains options for Container.UploadFS().
+type UploadFSOptions struct {
+	// The maximum number of files that are uploaded at the same time. Defaults
+	// to 4.
+	Concurrency int
+	// If true, files are not uploaded if an object with the same size and Etag
+	// exists already. This requires reading each such file twice (once for
+	// computing its checksum, and once for uploading it if it has changed).
+	SkipUnchanged bool
+	// If true, objects below the prefix that do not correspond to any file in
+	// the FS are deleted after all files have been uploaded.
+	DeleteMissing bool
+	// If not nil, these options are used for each upload. If they contain a
+	// Content-Type header, content-type detection is disabled.
+	RequestOptions *RequestOptions
+}
+
+// UploadFSResult is returned by Container.UploadFS().
+type UploadFSResult struct {
+	FilesUploaded  int
+	BytesUploaded  uint64
+	FilesSkipped   int // only with UploadFSOptions.SkipUnchanged
+	ObjectsDeleted int // only with UploadFSOptions.DeleteMissing
+}
+
+// UploadFS uploads all regular files in the given FS into this container.
+// Each file is uploaded into an object whose name is the concatenation of the
+// given prefix and the file's path within the FS. This can be used with any
+// implementation of fs.FS, e.g. to ship embedded static assets to Swift when
+// an application starts:
+//
+//	//go:embed static
+//	var assets embed.FS
+//
+//	result, err := container.UploadFS(ctx, assets, "", nil)
+//
+// The Content-Type of each object is derived from the file extension, or
+// from the file's content if the extension is not known.
+//
+// If an upload fails, no further uploads are started, and the first error is
+// returned after all uploads in progress have completed. In this case, the
+// deletion of missing objects (if requested) is skipped.
+func (c *Container) UploadFS(ctx context.Context, fsys fs.FS, prefix string, opts *UploadFSOptions) (UploadFSResult, error) {
+	if opts == nil {
+		opts = &UploadFSOptions{}
+	}
+	var result UploadFSResult
+
+	// list existing objects if necessary
+	existing := make(map[string]ObjectInfo)
+	if opts.SkipUnchanged || opts.DeleteMissing {
+		iter := c.Objects()
+		iter.Prefix = prefix
+		err := iter.ForeachDetailed(ctx, func(info ObjectInfo) error {
+			existing[info.Object.Name()] = info
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+
+	var (
+		mutex sync.Mutex
+		seen  = make(map[string]bool)
+	)
+	err := streamConcurrently(ctx, opts.Concurrency, func(ctx context.Context, submit func(string) bool) error {
+		return fs.WalkDir(fsys, ".", func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			seen[prefix+filePath] = true
+			if !submit(filePath) {
+				return ctx.Err()
+			}
+			return nil
+		})
+	}, func(ctx context.Context, filePath string) error {
+		uploaded, size, err := c.uploadFromFS(ctx, fsys, filePath, prefix+filePath, existing, opts)
+		if err != nil {
+			return err
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if uploaded {
+			result.FilesUploaded++
+			result.BytesUploaded += size
+		} else {
+			result.FilesSkipped++
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if opts.DeleteMissing {
+		var toDelete []*Object
+		for name, info := range existing {
+			if !seen[name] {
+				toDelete = append(toDelete, info.Object)
+			}
+		}
+		if len(toDelete) > 0 {
+			numDeleted, _, err := c.a.BulkDelete(ctx, toDelete, nil, nil)
+			result.ObjectsDeleted = numDeleted
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// uploadFromFS implements the upload of a single file for UploadFS().
+func (c *Container) uploadFromFS(ctx context.Context, fsys fs.FS, filePath, objectName string, existing map[string]ObjectInfo, opts *UploadFSOptions) (uploaded bool, size uint64, err error) {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return false, 0, err
+	}
+	defer func() { file.Close() }() // not `defer file.Close()` since `file` may be reopened below
+	fi, err := file.Stat()
+	if err != nil {
+		return false, 0, err
+	}
+	size = uint64(fi.Size())
+
+	if info, exists := existing[objectName]; opts.SkipUnchanged && exists && info.SizeBytes == size {
+		h := getMD5()
+		_, err := io.Copy(h, file)
+		etag := hex.EncodeToString(h.Sum(nil))
+		putMD5(h)
+		if err != nil {
+			return false, 0, err
+		}
+		if etag == info.Etag {
+			return false, size, nil
+		}
+		// reopen the file for the upload
+		file.Close()
+		file, err = fsys.Open(filePath)
+		if err != nil {
+			return false, 0, err
+		}
+	}
+
+	ropts := cloneRequestOptions(opts.RequestOptions, nil)
+	hdr := ObjectHeaders{Headers: ropts.Headers}
+	hdr.SizeBytes().Set(size)
+	var content io.Reader = file
+	if !hdr.ContentType().Exists() {
+		contentType := mime.TypeByExtension(path.Ext(filePath))
+		if contentType == "" {
+			// sniff the content type from the first 512 bytes
+			head := make([]byte, 512)
+			n, err := io.ReadFull(file, head)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+				return false, 0, err
+			}
+			head = head[:n]
+			contentType = http.DetectContentType(head)
+			content = io.MultiReader(bytes.NewReader(head), file)
+		}
+		hdr.ContentType().Set(contentType)
+	}
+
+	err = c.Object(objectName).Upload(ctx, content, nil, ropts)
+	return err == nil, size, err
+}
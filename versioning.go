@@ -0,0 +1,346 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ObjectVersionInfo describes a version of an object in a container with
+// object versioning enabled (i.e. with X-Versions-Enabled: true). It is
+// returned by Container.ObjectVersions().
+type ObjectVersionInfo struct {
+	Object    *Object
+	VersionID string
+	// IsLatest is true for the version that is currently visible (or, if
+	// IsDeleteMarker is also true, for the delete marker that currently hides
+	// all older versions).
+	IsLatest bool
+	// IsDeleteMarker is true if this version records a deletion of the object.
+	// All other fields except for LastModified are empty in this case.
+	IsDeleteMarker bool
+	SizeBytes      uint64
+	ContentType    string
+	Etag           string
+	LastModified   time.Time
+}
+
+// The content type that Swift uses for delete markers in version listings.
+const deleteMarkerContentType = "application/x-deleted;swift_versions_deleted=1"
+
+// ObjectVersions lists all versions of all objects in this container whose
+// names start with the given prefix, using a GET request with the ?versions
+// query parameter. The result is sorted by object name, and the versions of
+// each object are sorted from newest to oldest.
+//
+// This only works for the object versioning mode that is enabled by the
+// X-Versions-Enabled header. The legacy modes using the X-Versions-Location
+// or X-History-Location headers store versions as regular objects in a
+// different container.
+func (c *Container) ObjectVersions(ctx context.Context, prefix string) ([]ObjectVersionInfo, error) {
+	var (
+		result        []ObjectVersionInfo
+		marker        string
+		versionMarker string
+	)
+	for {
+		opts := cloneRequestOptions(nil, nil)
+		opts.Headers.Set("Accept", "application/json")
+		opts.Values.Set("format", "json")
+		opts.Values.Set("versions", "")
+		if prefix != "" {
+			opts.Values.Set("prefix", prefix)
+		}
+		if marker != "" {
+			opts.Values.Set("marker", marker)
+			opts.Values.Set("version_marker", versionMarker)
+		}
+		resp, err := Request{
+			Method:            "GET",
+			ContainerName:     c.name,
+			Options:           opts,
+			ExpectStatusCodes: []int{200, 204},
+		}.Do(ctx, c.a.backend)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := collectResponseBody(resp)
+		if err != nil {
+			return nil, err
+		}
+
+		var document []struct {
+			Name            string `json:"name"`
+			VersionID       string `json:"version_id"`
+			IsLatest        bool   `json:"is_latest"`
+			SizeBytes       uint64 `json:"bytes"`
+			ContentType     string `json:"content_type"`
+			Etag            string `json:"hash"`
+			LastModifiedStr string `json:"last_modified"`
+		}
+		if len(buf) > 0 {
+			err = json.Unmarshal(buf, &document)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if len(document) == 0 {
+			return result, nil
+		}
+
+		for idx, data := range document {
+			info := ObjectVersionInfo{
+				Object:    c.Object(data.Name),
+				VersionID: data.VersionID,
+				IsLatest:  data.IsLatest,
+			}
+			info.LastModified, err = time.Parse(time.RFC3339Nano, data.LastModifiedStr+"Z")
+			if err != nil {
+				// this error is sufficiently obscure that we don't need to expose a type for it
+				return nil, fmt.Errorf("bad field versions[%d].last_modified: %s", idx, err.Error())
+			}
+			if data.ContentType == deleteMarkerContentType {
+				info.IsDeleteMarker = true
+			} else {
+				info.SizeBytes = data.SizeBytes
+				info.ContentType = data.ContentType
+				info.Etag = data.Etag
+			}
+			result = append(result, info)
+		}
+		last := document[len(document)-1]
+		marker, versionMarker = last.Name, last.VersionID
+	}
+}
+
+// RestoreOptions contains options for Container.RestoreToTime().
+type RestoreOptions struct {
+	// If not empty, only objects whose names start with this prefix are
+	// restored.
+	Prefix string
+	// If true, no changes are made. OnProgress is still called for each
+	// action that would have been taken.
+	DryRun bool
+	// If not nil, this is called for each restored or deleted object after the
+	// respective request has completed successfully.
+	OnProgress func(RestoreAction)
+}
+
+// RestoreAction describes a change made by Container.RestoreToTime().
+type RestoreAction struct {
+	Object *Object
+	// The version that the object was restored to, or nil if the object was
+	// deleted because it did not exist at the restore time.
+	Version *ObjectVersionInfo
+}
+
+// RestoreResult is returned by Container.RestoreToTime().
+type RestoreResult struct {
+	ObjectsRestored  int
+	ObjectsDeleted   int
+	ObjectsUnchanged int
+}
+
+// RestoreToTime reverts all objects in this container to the state that they
+// had at the given point in time. The container must have object versioning
+// enabled (with the X-Versions-Enabled header), otherwise ErrNotVersioned is
+// returned.
+//
+// For each object, the latest version that is not newer than the given time is
+// determined using ObjectVersions(). If it differs from the current version
+// (in terms of its Etag), it is restored by copying it onto the object, which
+// creates a new version. If the object did not exist at the given time, or if
+// it was deleted at that point, it is deleted, which creates a delete marker.
+// Since nothing is overwritten or removed for good, the restore can itself be
+// reverted with another RestoreToTime().
+//
+// Objects are processed one at a time. If a request fails, the restore is
+// aborted, and the result so far is returned together with the error.
+func (c *Container) RestoreToTime(ctx context.Context, t time.Time, opts *RestoreOptions) (RestoreResult, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+	var result RestoreResult
+
+	hdr, err := c.Headers(ctx)
+	if err != nil {
+		return result, err
+	}
+	if !hdr.VersionsEnabled().Get() {
+		return result, ErrNotVersioned
+	}
+	versions, err := c.ObjectVersions(ctx, opts.Prefix)
+	if err != nil {
+		return result, err
+	}
+
+	// versions are grouped by object name, from newest to oldest
+	for len(versions) > 0 {
+		name := versions[0].Object.Name()
+		count := 1
+		for count < len(versions) && versions[count].Object.Name() == name {
+			count++
+		}
+		err := c.restoreObjectToTime(ctx, t, versions[:count], opts, &result)
+		if err != nil {
+			return result, err
+		}
+		versions = versions[count:]
+	}
+	return result, nil
+}
+
+// restoreObjectToTime implements RestoreToTime() for a single object.
+func (c *Container) restoreObjectToTime(ctx context.Context, t time.Time, versions []ObjectVersionInfo, opts *RestoreOptions, result *RestoreResult) error {
+	var current, target *ObjectVersionInfo
+	for idx, v := range versions {
+		if v.IsLatest && !v.IsDeleteMarker {
+			current = &versions[idx]
+		}
+		if target == nil && !v.LastModified.After(t) {
+			target = &versions[idx]
+		}
+	}
+	if target != nil && target.IsDeleteMarker {
+		target = nil
+	}
+
+	obj := c.Object(versions[0].Object.Name())
+	switch {
+	case target == nil && current == nil:
+		result.ObjectsUnchanged++
+		return nil
+	case target != nil && current != nil && target.Etag == current.Etag:
+		result.ObjectsUnchanged++
+		return nil
+	case target == nil:
+		if !opts.DryRun {
+			err := obj.Delete(ctx, nil, nil)
+			if err != nil {
+				return err
+			}
+		}
+		result.ObjectsDeleted++
+	default:
+		if !opts.DryRun {
+			ropts := cloneRequestOptions(nil, nil)
+			ropts.Values.Set("version-id", target.VersionID)
+			err := obj.CopyTo(ctx, obj, &CopyOptions{
+				ShallowCopyLargeObjects: true,
+				ShallowCopySymlinks:     true,
+			}, ropts)
+			if err != nil {
+				return err
+			}
+		}
+		result.ObjectsRestored++
+	}
+
+	if opts.OnProgress != nil {
+		opts.OnProgress(RestoreAction{Object: obj, Version: target})
+	}
+	return nil
+}
+
+// Undelete restores this object after it was deleted in a container with
+// legacy object versioning (i.e. with the X-History-Location or
+// X-Versions-Location header). The newest archived version of the object is
+// copied from the versions container back into place, and then removed from
+// the versions container. Delete markers that were written into the versions
+// container after that version (in the X-History-Location mode) are removed
+// as well, since the deletion that they record has now been undone.
+//
+// The names of archived versions are derived from the object name in the same
+// way as Swift does (i.e. "$LENGTH$NAME/$TIMESTAMP", where $LENGTH is the
+// length of the object name as three hex digits), so callers do not need to
+// know about this mangling.
+//
+// If the container does not use legacy object versioning, ErrNotVersioned is
+// returned. If the object exists, ErrAlreadyExists is returned. If no archived
+// version is found, ErrNoPreviousVersion is returned. In all these cases,
+// nothing is changed. For containers with the newer X-Versions-Enabled mode,
+// use ObjectVersions() or Container.RestoreToTime() instead.
+//
+// The given RequestOptions are used for the COPY request that restores the
+// object.
+//
+// A successful COPY request implies Invalidate().
+func (o *Object) Undelete(ctx context.Context, opts *RequestOptions) error {
+	hdr, err := o.c.Headers(ctx)
+	if err != nil {
+		return err
+	}
+	archiveName := hdr.HistoryLocation().Get()
+	if archiveName == "" {
+		archiveName = hdr.VersionsLocation().Get()
+	}
+	if archiveName == "" {
+		return ErrNotVersioned
+	}
+
+	o.Invalidate()
+	exists, err := o.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAlreadyExists
+	}
+
+	// archived versions sort from oldest to newest since the timestamps in
+	// their names have a fixed width
+	iter := o.c.a.Container(archiveName).Objects()
+	iter.Prefix = legacyVersionPrefix(o.name)
+	infos, err := iter.CollectDetailed(ctx)
+	if err != nil {
+		return err
+	}
+	idx := len(infos) - 1
+	for idx >= 0 && infos[idx].ContentType == deleteMarkerContentType {
+		idx--
+	}
+	if idx < 0 {
+		return ErrNoPreviousVersion
+	}
+
+	err = infos[idx].Object.CopyTo(ctx, o, &CopyOptions{
+		ShallowCopyLargeObjects: true,
+		ShallowCopySymlinks:     true,
+	}, opts)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos[idx:] {
+		err := info.Object.Delete(ctx, nil, nil)
+		if err != nil && !Is(err, http.StatusNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyVersionPrefix returns the prefix of the names that legacy object
+// versioning uses for the archived versions of the object with the given name.
+func legacyVersionPrefix(objectName string) string {
+	return fmt.Sprintf("%03x%s/", len(objectName), objectName)
+}
@@ -0,0 +1,143 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const versionsListing = `[
+	{"name":"a","version_id":"a3","is_latest":true,"bytes":3,"content_type":"text/plain","hash":"e3","last_modified":"2024-01-01T03:00:00.000000"},
+	{"name":"a","version_id":"a2","is_latest":false,"bytes":2,"content_type":"text/plain","hash":"e2","last_modified":"2024-01-01T02:00:00.000000"},
+	{"name":"a","version_id":"a1","is_latest":false,"bytes":1,"content_type":"text/plain","hash":"e1","last_modified":"2024-01-01T01:00:00.000000"},
+	{"name":"b","version_id":"b2","is_latest":true,"bytes":2,"content_type":"text/plain","hash":"e2","last_modified":"2024-01-01T02:00:00.000000"},
+	{"name":"c","version_id":"c3","is_latest":true,"bytes":3,"content_type":"text/plain","hash":"e3","last_modified":"2024-01-01T03:00:00.000000"},
+	{"name":"d","version_id":"d3","is_latest":true,"bytes":0,"content_type":"application/x-deleted;swift_versions_deleted=1","hash":"d41d8cd98f00b204e9800998ecf8427e","last_modified":"2024-01-01T03:00:00.000000"},
+	{"name":"d","version_id":"d1","is_latest":false,"bytes":1,"content_type":"text/plain","hash":"e1","last_modified":"2024-01-01T01:00:00.000000"},
+	{"name":"e","version_id":"e2","is_latest":true,"bytes":0,"content_type":"application/x-deleted;swift_versions_deleted=1","hash":"d41d8cd98f00b204e9800998ecf8427e","last_modified":"2024-01-01T02:00:00.000000"},
+	{"name":"e","version_id":"e1","is_latest":false,"bytes":1,"content_type":"text/plain","hash":"e1","last_modified":"2024-01-01T01:00:00.000000"}
+]`
+
+// versionsTestBackend returns a testBackend that serves a container with
+// object versioning enabled (or disabled), and records all COPY and DELETE
+// requests in the given log.
+func versionsTestBackend(versioned bool, log *[]string) *testBackend {
+	return &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		resp := testResponse(http.StatusNoContent, "")
+		query := req.URL.Query()
+		switch req.Method {
+		case http.MethodHead:
+			if versioned {
+				resp.Header.Set("X-Versions-Enabled", "true")
+			}
+		case http.MethodGet:
+			resp = testResponse(http.StatusOK, "[]")
+			if query.Get("marker") == "" {
+				resp = testResponse(http.StatusOK, versionsListing)
+			}
+		case "COPY":
+			resp.StatusCode = http.StatusCreated
+			*log = append(*log, "COPY "+req.URL.Path+"?version-id="+query.Get("version-id"))
+		case http.MethodDelete:
+			*log = append(*log, "DELETE "+req.URL.Path)
+		}
+		return resp, nil
+	}}
+}
+
+func TestObjectVersions(t *testing.T) {
+	a, err := InitializeAccount(versionsTestBackend(true, new([]string)))
+	must(t, err)
+	versions, err := a.Container("foo").ObjectVersions(context.Background(), "")
+	must(t, err)
+
+	var descs []string
+	for _, v := range versions {
+		desc := v.Object.Name() + "@" + v.VersionID
+		if v.IsLatest {
+			desc += "(latest)"
+		}
+		if v.IsDeleteMarker {
+			desc += "(deleted)"
+		}
+		descs = append(descs, desc)
+	}
+	expectString(t, "a@a3(latest) a@a2 a@a1 b@b2(latest) c@c3(latest) d@d3(latest)(deleted) d@d1 e@e2(latest)(deleted) e@e1",
+		strings.Join(descs, " "))
+	expectString(t, "2024-01-01T02:00:00Z", versions[1].LastModified.Format(time.RFC3339))
+}
+
+func TestRestoreToTime(t *testing.T) {
+	ctx := context.Background()
+	restoreTime := time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC)
+
+	// dry run does not make any changes
+	var log []string
+	a, err := InitializeAccount(versionsTestBackend(true, &log))
+	must(t, err)
+	var actions []string
+	opts := &RestoreOptions{
+		DryRun: true,
+		OnProgress: func(action RestoreAction) {
+			desc := action.Object.Name() + ":deleted"
+			if action.Version != nil {
+				desc = action.Object.Name() + ":" + action.Version.VersionID
+			}
+			actions = append(actions, desc)
+		},
+	}
+	result, err := a.Container("foo").RestoreToTime(ctx, restoreTime, opts)
+	must(t, err)
+	expectString(t, "a:a2 c:deleted d:d1", strings.Join(actions, " "))
+	if result != (RestoreResult{ObjectsRestored: 2, ObjectsDeleted: 1, ObjectsUnchanged: 2}) {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	expectString(t, "", strings.Join(log, "\n"))
+
+	// actual run restores versions by copying them onto the object, and deletes
+	// objects that did not exist at the restore time
+	opts.DryRun = false
+	_, err = a.Container("foo").RestoreToTime(ctx, restoreTime, opts)
+	must(t, err)
+	expectString(t, strings.Join([]string{
+		"COPY /v1/AUTH_example/foo/a?version-id=a2",
+		"DELETE /v1/AUTH_example/foo/c",
+		"COPY /v1/AUTH_example/foo/d?version-id=d1",
+	}, "\n"), strings.Join(log, "\n"))
+
+	// restore requires object versioning
+	a, err = InitializeAccount(versionsTestBackend(false, &log))
+	must(t, err)
+	_, err = a.Container("foo").RestoreToTime(ctx, restoreTime, nil)
+	if !errors.Is(err, ErrNotVersioned) {
+		t.Errorf("expected ErrNotVersioned, got %v", err)
+	}
+}
+
+func TestLegacyVersionPrefix(t *testing.T) {
+	expectString(t, "003foo/", legacyVersionPrefix("foo"))
+	expectString(t, "00bfoo/bar.txt/", legacyVersionPrefix("foo/bar.txt"))
+	// the length is counted in bytes, not runes
+	expectString(t, "005über/", legacyVersionPrefix("über"))
+}
@@ -0,0 +1,393 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/majewsky/schwift/v2/internal/errext"
+)
+
+// WriteQueueOptions contains the configuration for NewWriteQueue().
+type WriteQueueOptions struct {
+	// The maximum number of writes that can be pending at the same time.
+	// Defaults to 1000.
+	MaxLength int
+	// The maximum total size of the content of all pending uploads, in bytes.
+	// Defaults to 64 MiB.
+	MaxBytes int64
+	// Controls how often failed writes are retried, and how long to wait
+	// between attempts. Unlike in type Profile, MaxAttempts == 0 means that
+	// writes are retried indefinitely (until the queue is closed). Use
+	// MaxAttempts == 1 to disable retries.
+	Retry RetryPolicy
+	// If not nil, this is called once for each write when it has been
+	// acknowledged by Swift (with err == nil), or when it has failed
+	// permanently. Calls are made from the queue's worker goroutine, in the same
+	// order in which the writes were enqueued. The callback should not block
+	// for long since it holds up the queue.
+	OnComplete func(w QueuedWrite, err error)
+}
+
+// QueuedWrite describes a write in a WriteQueue. It is passed to
+// WriteQueueOptions.OnComplete.
+type QueuedWrite struct {
+	// ID is the value that was returned by the Enqueue method.
+	ID     uint64
+	Method string // either http.MethodPut or http.MethodPost
+	Target string // either "$CONTAINER_NAME" or "$CONTAINER_NAME/$OBJECT_NAME"
+	// The number of attempts that were made to execute this write.
+	Attempts   int
+	EnqueuedAt time.Time
+}
+
+// WriteQueue is a write-behind queue for metadata updates and small uploads.
+// It is intended for edge deployments with flaky connectivity to the Swift
+// endpoint, where the application shall not block on writes that can just as
+// well be executed later. For example:
+//
+//	queue := schwift.NewWriteQueue(schwift.WriteQueueOptions{
+//		OnComplete: func(w schwift.QueuedWrite, err error) {
+//			if err != nil {
+//				log.Printf("write to %s failed permanently: %s", w.Target, err.Error())
+//			}
+//		},
+//	})
+//	defer queue.Drain(ctx)
+//
+//	_, err := queue.EnqueueUpload(container.Object("readings.json"), data, nil, nil)
+//
+// Writes are executed one at a time by a worker goroutine, in the order in
+// which they were enqueued. If a write fails, the queue waits for the next
+// retry instead of proceeding with later writes, so writes to the same object
+// cannot overtake each other. A write is only retried if it fails because of
+// a network error (e.g. because the endpoint is unreachable), because a
+// CircuitBreaker is open, or with one of the status codes 429, 500, 502, 503
+// or 504. Any other error (including errors that occur locally before the
+// request is sent) fails the write permanently.
+//
+// Writes are executed on separate handles, so the caches of the Object or
+// Container instances given to the Enqueue methods are not updated. Pending
+// writes only exist in memory, so they are lost when the process exits.
+// Use OnComplete to find out when a write is durable.
+//
+// All methods are safe for concurrent use.
+type WriteQueue struct {
+	opts WriteQueueOptions
+
+	mutex        sync.Mutex
+	pending      []*queueEntry // the first entry is in progress
+	pendingBytes int64
+	lastID       uint64 // ID of the last enqueued write
+	completedID  uint64 // ID of the last completed write
+	closed       bool
+	changed      chan struct{} // closed and replaced whenever a write completes
+
+	wake     chan struct{} // signals the worker that there is new work
+	retryNow chan struct{} // signals the worker to cut a retry backoff short (see Flush())
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{} // closed when the worker exits
+}
+
+type queueEntry struct {
+	info  QueuedWrite
+	bytes int64
+	do    func(ctx context.Context) error
+}
+
+// NewWriteQueue creates a WriteQueue and starts its worker goroutine. The
+// queue must be shut down with Drain() or Close() when it is not needed
+// anymore.
+func NewWriteQueue(opts WriteQueueOptions) *WriteQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &WriteQueue{
+		opts:     opts,
+		changed:  make(chan struct{}),
+		wake:     make(chan struct{}, 1),
+		retryNow: make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// EnqueueUpload enqueues an Object.Upload() with the given content. The
+// content is copied, so the caller may reuse the buffer afterwards. Returns
+// the ID of the write (see type QueuedWrite), or ErrQueueFull or
+// ErrQueueClosed.
+func (q *WriteQueue) EnqueueUpload(obj *Object, content []byte, opts *UploadOptions, ropts *RequestOptions) (uint64, error) {
+	content = bytes.Clone(content)
+	if opts != nil {
+		cloned := *opts
+		opts = &cloned
+	}
+	ropts = cloneRequestOptions(ropts, nil)
+	handle := obj.c.Object(obj.name)
+	return q.enqueue(http.MethodPut, obj.FullName(), int64(len(content)), func(ctx context.Context) error {
+		return handle.Upload(ctx, bytes.NewReader(content), opts, ropts)
+	})
+}
+
+// EnqueueObjectUpdate enqueues an Object.Update() with the given headers.
+// Returns the ID of the write (see type QueuedWrite), or ErrQueueFull or
+// ErrQueueClosed.
+func (q *WriteQueue) EnqueueObjectUpdate(obj *Object, headers ObjectHeaders, ropts *RequestOptions) (uint64, error) {
+	headers = ObjectHeaders{Headers: maps.Clone(headers.Headers)}
+	ropts = cloneRequestOptions(ropts, nil)
+	handle := obj.c.Object(obj.name)
+	return q.enqueue(http.MethodPost, obj.FullName(), 0, func(ctx context.Context) error {
+		return handle.Update(ctx, headers, ropts)
+	})
+}
+
+// EnqueueContainerUpdate enqueues a Container.Update() with the given headers.
+// Returns the ID of the write (see type QueuedWrite), or ErrQueueFull or
+// ErrQueueClosed.
+func (q *WriteQueue) EnqueueContainerUpdate(c *Container, headers ContainerHeaders, ropts *RequestOptions) (uint64, error) {
+	headers = ContainerHeaders{Headers: maps.Clone(headers.Headers)}
+	ropts = cloneRequestOptions(ropts, nil)
+	handle := c.a.Container(c.name)
+	return q.enqueue(http.MethodPost, c.name, 0, func(ctx context.Context) error {
+		return handle.Update(ctx, headers, ropts)
+	})
+}
+
+func (q *WriteQueue) enqueue(method, target string, size int64, do func(context.Context) error) (uint64, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.closed {
+		return 0, ErrQueueClosed
+	}
+	maxLength := q.opts.MaxLength
+	if maxLength <= 0 {
+		maxLength = 1000
+	}
+	maxBytes := q.opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 64 << 20
+	}
+	if len(q.pending) >= maxLength || q.pendingBytes+size > maxBytes {
+		return 0, ErrQueueFull
+	}
+
+	q.lastID++
+	q.pending = append(q.pending, &queueEntry{
+		info: QueuedWrite{
+			ID:         q.lastID,
+			Method:     method,
+			Target:     target,
+			EnqueuedAt: time.Now(),
+		},
+		bytes: size,
+		do:    do,
+	})
+	q.pendingBytes += size
+	signal(q.wake)
+	return q.lastID, nil
+}
+
+// Len returns the number of pending writes, including the one that is
+// currently in progress.
+func (q *WriteQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pending)
+}
+
+// Flush waits until all writes that were enqueued before this call have
+// completed (successfully or not). If the queue is waiting to retry a failed
+// write, the retry is attempted immediately. This is useful when the
+// application knows that connectivity has been restored.
+//
+// If the context expires first, its error is returned. The pending writes stay
+// in the queue in this case.
+func (q *WriteQueue) Flush(ctx context.Context) error {
+	q.mutex.Lock()
+	targetID := q.lastID
+	q.mutex.Unlock()
+	signal(q.retryNow)
+	return q.waitFor(ctx, targetID)
+}
+
+// Drain stops accepting new writes, waits until all pending writes have
+// completed, and then shuts down the queue. If the context expires first,
+// the queue is shut down with Close() and the context's error is returned.
+func (q *WriteQueue) Drain(ctx context.Context) error {
+	q.mutex.Lock()
+	q.closed = true
+	targetID := q.lastID
+	q.mutex.Unlock()
+	signal(q.retryNow)
+
+	err := q.waitFor(ctx, targetID)
+	if err != nil {
+		q.Close()
+		return err
+	}
+	q.cancel()
+	<-q.done
+	return nil
+}
+
+// Close stops accepting new writes, and shuts down the queue immediately.
+// Pending writes are discarded and reported to OnComplete with
+// ErrQueueClosed. If a write was in progress, it is aborted and also
+// reported with ErrQueueClosed, but it may or may not have been executed by
+// Swift.
+func (q *WriteQueue) Close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.mutex.Unlock()
+	q.cancel()
+	<-q.done
+
+	q.mutex.Lock()
+	discarded := q.pending
+	q.pending = nil
+	q.pendingBytes = 0
+	q.mutex.Unlock()
+	for _, entry := range discarded {
+		q.complete(entry, ErrQueueClosed)
+	}
+}
+
+// signal sends on the given channel without blocking. The channels have a
+// buffer of 1, so multiple signals before the worker gets to them collapse
+// into one.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+func (q *WriteQueue) waitFor(ctx context.Context, targetID uint64) error {
+	for {
+		q.mutex.Lock()
+		if q.completedID >= targetID {
+			q.mutex.Unlock()
+			return nil
+		}
+		changed := q.changed
+		q.mutex.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// run is the worker goroutine.
+func (q *WriteQueue) run() {
+	defer close(q.done)
+	for {
+		q.mutex.Lock()
+		var entry *queueEntry
+		if len(q.pending) > 0 {
+			entry = q.pending[0]
+		}
+		q.mutex.Unlock()
+
+		if entry == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-q.ctx.Done():
+				return
+			}
+		}
+
+		err := q.execute(entry)
+		if err != nil && q.ctx.Err() != nil {
+			// entry will be reported by Close()
+			return
+		}
+		q.mutex.Lock()
+		q.pending = q.pending[1:]
+		q.pendingBytes -= entry.bytes
+		q.mutex.Unlock()
+		q.complete(entry, err)
+	}
+}
+
+// execute runs a write, including all retries.
+func (q *WriteQueue) execute(entry *queueEntry) error {
+	backoff := durationOrDefault(q.opts.Retry.InitialBackoff, 100*time.Millisecond)
+	// discard a Flush() signal that arrived while no write was being retried
+	select {
+	case <-q.retryNow:
+	default:
+	}
+	for {
+		entry.info.Attempts++
+		err := entry.do(q.ctx)
+		if err == nil || !isRetryableWriteError(err) {
+			return err
+		}
+		if q.opts.Retry.MaxAttempts > 0 && entry.info.Attempts >= q.opts.Retry.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-q.retryNow:
+			timer.Stop()
+		case <-q.ctx.Done():
+			timer.Stop()
+			return ErrQueueClosed
+		}
+		backoff = min(2*backoff, durationOrDefault(q.opts.Retry.MaxBackoff, 10*time.Second))
+	}
+}
+
+func (q *WriteQueue) complete(entry *queueEntry, err error) {
+	if q.opts.OnComplete != nil {
+		q.opts.OnComplete(entry.info, err)
+	}
+	q.mutex.Lock()
+	q.completedID = max(q.completedID, entry.info.ID)
+	close(q.changed)
+	q.changed = make(chan struct{})
+	q.mutex.Unlock()
+}
+
+func isRetryableWriteError(err error) bool {
+	if e, ok := errext.As[UnexpectedStatusCodeError](err); ok {
+		return isRetryableStatus(e.ActualResponse.StatusCode)
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	_, isNetworkError := errext.As[net.Error](err)
+	return isNetworkError
+}
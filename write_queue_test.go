@@ -0,0 +1,217 @@
+/******************************************************************************
+*
+*  Copyright 2018 Stefan Majewsky <majewsky@gmx.net>
+*
+*  Licensed under the Apache License, Version 2.0 (the "License");
+*  you may not use this file except in compliance with the License.
+*  You may obtain a copy of the License at
+*
+*      http://www.apache.org/licenses/LICENSE-2.0
+*
+*  Unless required by applicable law or agreed to in writing, software
+*  distributed under the License is distributed on an "AS IS" BASIS,
+*  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+*  See the License for the specific language governing permissions and
+*  limitations under the License.
+*
+******************************************************************************/
+
+package schwift
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyTestBackend returns a testBackend that fails the first `failures`
+// requests with 503, and then responds with the status code that Swift would
+// return on success. Requests for objects named "missing" fail with 404, and
+// requests for objects named "broken" fail without a response with an error
+// that is not a network error. All other requests are recorded in the given
+// log.
+func flakyTestBackend(failures int, log *[]string) *testBackend {
+	var mutex sync.Mutex
+	return &testBackend{handle: func(req *http.Request) (*http.Response, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if strings.HasSuffix(req.URL.Path, "/broken") {
+			*log = append(*log, req.Method+" "+req.URL.Path+" failed")
+			return nil, errors.New("broken")
+		}
+
+		statusCode := http.StatusAccepted
+		if req.Method == http.MethodPut {
+			statusCode = http.StatusCreated
+		}
+		switch {
+		case failures > 0:
+			failures--
+			statusCode = http.StatusServiceUnavailable
+		case strings.HasSuffix(req.URL.Path, "/missing"):
+			statusCode = http.StatusNotFound
+		}
+		if req.Body != nil {
+			buf, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			req.Body.Close()
+			if statusCode < 300 {
+				*log = append(*log, req.Method+" "+req.URL.Path+" "+string(buf))
+			}
+		} else if statusCode < 300 {
+			*log = append(*log, req.Method+" "+req.URL.Path)
+		}
+
+		return testResponse(statusCode, ""), nil
+	}}
+}
+
+func TestWriteQueue(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	a, err := InitializeAccount(flakyTestBackend(3, &log))
+	must(t, err)
+	c := a.Container("foo")
+
+	var (
+		mutex     sync.Mutex
+		completed []string
+	)
+	q := NewWriteQueue(WriteQueueOptions{
+		MaxLength: 3,
+		Retry:     RetryPolicy{InitialBackoff: 10 * time.Millisecond},
+		OnComplete: func(w QueuedWrite, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			msg := "ok"
+			if err != nil {
+				msg = err.Error()
+			}
+			completed = append(completed, w.Method+" "+w.Target+": "+msg)
+		},
+	})
+
+	// writes are executed in order, and retried until they succeed
+	content := []byte("hello")
+	_, err = q.EnqueueUpload(c.Object("first"), content, nil, nil)
+	must(t, err)
+	content[0] = 'j' // the queue must have copied the content
+	hdr := NewObjectHeaders()
+	hdr.Metadata().Set("color", "blue")
+	_, err = q.EnqueueObjectUpdate(c.Object("first"), hdr, nil)
+	must(t, err)
+	_, err = q.EnqueueObjectUpdate(c.Object("missing"), hdr, nil)
+	must(t, err)
+
+	// the queue is bounded (the first write is still being retried at this
+	// point, so it still counts towards MaxLength)
+	_, err = q.EnqueueUpload(c.Object("second"), content, nil, nil)
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	must(t, q.Flush(ctx))
+	expectString(t, "0", strconv.Itoa(q.Len()))
+	expectString(t, strings.Join([]string{
+		"PUT /v1/AUTH_example/foo/first hello",
+		"POST /v1/AUTH_example/foo/first",
+	}, "\n"), strings.Join(log, "\n"))
+	mutex.Lock()
+	expectString(t, strings.Join([]string{
+		"PUT foo/first: ok",
+		"POST foo/first: ok",
+		`POST foo/missing: could not POST "foo/missing" in Swift: expected 202 response, got 404 instead`,
+	}, "\n"), strings.Join(completed[:3], "\n"))
+	mutex.Unlock()
+
+	// after Drain(), no further writes are accepted
+	must(t, q.Drain(ctx))
+	_, err = q.EnqueueUpload(c.Object("third"), content, nil, nil)
+	if !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestWriteQueueClose(t *testing.T) {
+	var log []string
+	a, err := InitializeAccount(flakyTestBackend(1000, &log))
+	must(t, err)
+
+	var completed []error
+	q := NewWriteQueue(WriteQueueOptions{
+		Retry:      RetryPolicy{InitialBackoff: time.Millisecond},
+		OnComplete: func(w QueuedWrite, err error) { completed = append(completed, err) },
+	})
+	_, err = q.EnqueueUpload(a.Container("foo").Object("bar"), []byte("hello"), nil, nil)
+	must(t, err)
+
+	// Drain() gives up when the context expires, and discards pending writes
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = q.Drain(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if len(completed) != 1 || !errors.Is(completed[0], ErrQueueClosed) {
+		t.Errorf("expected write to be reported with ErrQueueClosed, got %v", completed)
+	}
+	expectString(t, "0", strconv.Itoa(q.Len()))
+}
+
+func TestWriteQueueRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	a, err := InitializeAccount(flakyTestBackend(1, &log))
+	must(t, err)
+	c := a.Container("foo")
+
+	var (
+		mutex     sync.Mutex
+		completed []error
+	)
+	q := NewWriteQueue(WriteQueueOptions{
+		Retry: RetryPolicy{InitialBackoff: time.Hour},
+		OnComplete: func(w QueuedWrite, err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			completed = append(completed, err)
+		},
+	})
+	defer q.Close()
+
+	// errors that are not network errors or retryable status codes are not
+	// retried, even though retries are unlimited
+	_, err = q.EnqueueObjectUpdate(c.Object("broken"), NewObjectHeaders(), nil)
+	must(t, err)
+	must(t, q.Flush(ctx))
+	mutex.Lock()
+	if len(completed) != 1 || completed[0] == nil || completed[0].Error() != "broken" {
+		t.Errorf("expected write to fail permanently, got %v", completed)
+	}
+	mutex.Unlock()
+
+	// enqueueing further writes does not cut the retry backoff short...
+	_, err = q.EnqueueObjectUpdate(c.Object("first"), NewObjectHeaders(), nil)
+	must(t, err)
+	_, err = q.EnqueueObjectUpdate(c.Object("second"), NewObjectHeaders(), nil)
+	must(t, err)
+	time.Sleep(20 * time.Millisecond)
+	expectString(t, "2", strconv.Itoa(q.Len()))
+
+	// ...but Flush() does
+	must(t, q.Flush(ctx))
+	expectString(t, strings.Join([]string{
+		"POST /v1/AUTH_example/foo/broken failed",
+		"POST /v1/AUTH_example/foo/first",
+		"POST /v1/AUTH_example/foo/second",
+	}, "\n"), strings.Join(log, "\n"))
+}